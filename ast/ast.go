@@ -9,6 +9,7 @@
 //   - Statement: Interface for nodes that represent statements (e.g., let, return)
 //   - Expression: Interface for nodes that represent expressions (e.g., literals, function calls)
 //   - Program: The root node of the AST, containing a list of statements
+//   - Inspect: Traverses a node and its children in depth-first order
 package ast
 
 import (
@@ -24,6 +25,8 @@ type Node interface {
 	TokenLiteral() string
 	// String returns a string representation of the node for debugging and testing.
 	String() string
+	// Pos returns the position of the token associated with this node.
+	Pos() token.Position
 }
 
 // Statement is the interface for all statement nodes in the AST.
@@ -46,6 +49,7 @@ type Expression interface {
 // It represents a complete Monke program and contains a list of statements.
 type Program struct {
 	Statements []Statement // The list of statements in the program
+	Pragmas    []string    // The names given by "#pragma" lines at the top of the source
 }
 
 // TokenLiteral returns the literal value of the first token in the program.
@@ -57,6 +61,14 @@ func (p *Program) TokenLiteral() string {
 	return ""
 }
 
+// Pos returns the position of the first statement in the program.
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return token.Position{}
+}
+
 // String returns a string representation of the program.
 // It concatenates the string representations of all statements in the program.
 func (p *Program) String() string {
@@ -72,6 +84,10 @@ func (p *Program) String() string {
 type Identifier struct {
 	Token token.Token // The token containing the identifier
 	Value string      // The value (name) of the identifier
+
+	// Cache is reserved for the evaluator's inline lookup cache.
+	// It is not part of the syntax and is ignored by String.
+	Cache any
 }
 
 func (id *Identifier) expressionNode() {}
@@ -79,14 +95,24 @@ func (id *Identifier) expressionNode() {}
 // TokenLiteral returns the literal value of the identifier token.
 func (id *Identifier) TokenLiteral() string { return id.Token.Literal }
 
+// Pos returns the position of the token associated with this node.
+func (id *Identifier) Pos() token.Position { return id.Token.Position }
+
 // String returns the value (name) of the identifier.
 func (id *Identifier) String() string { return id.Value }
 
 // LetStatement represents a variable binding statement (e.g., "let x = 5;").
+// A statement binding several names destructures an array, binding each name
+// to an element in order (e.g., "let x, y = f();"), and so does one with an
+// array pattern (e.g., "let [head, ...tail] = xs;"); "_" binds nothing. One
+// with a hash pattern binds the values of its keys (e.g., "let {name} = user;").
 type LetStatement struct {
-	Token token.Token // The 'let' token
-	Name  *Identifier // The identifier being bound
-	Value Expression  // The expression that produces the value to bind
+	Token   token.Token   // The 'let' token
+	Name    *Identifier   // The identifier being bound, the first of Names, or nil with a Pattern
+	Names   []*Identifier // All the identifiers bound when destructuring without a pattern, or nil
+	Pattern Pattern       // The array or hash pattern destructuring the value, or nil
+	Type    *Identifier   // The optional type annotation (e.g., "int" in "let x: int = 5;")
+	Value   Expression    // The expression that produces the value to bind
 }
 
 func (ls *LetStatement) statementNode() {}
@@ -94,13 +120,61 @@ func (ls *LetStatement) statementNode() {}
 // TokenLiteral returns the literal value of the 'let' token.
 func (ls *LetStatement) TokenLiteral() string { return ls.Token.Literal }
 
+// Pos returns the position of the token associated with this node.
+func (ls *LetStatement) Pos() token.Position { return ls.Token.Position }
+
+// Destructures reports whether the statement binds the elements of an array
+// or the values of a hash.
+func (ls *LetStatement) Destructures() bool { return len(ls.Names) > 1 || ls.Pattern != nil }
+
+// Identifiers returns the identifiers the statement binds, in order. When
+// destructuring, "_" binds nothing and is left out.
+func (ls *LetStatement) Identifiers() []*Identifier {
+	switch {
+	case ls.Pattern != nil:
+		var names []*Identifier
+		Inspect(ls.Pattern, func(node Node) bool {
+			if binding, ok := node.(*BindingPattern); ok && !binding.Wildcard() {
+				names = append(names, binding.Name)
+			}
+			return true
+		})
+		return names
+	case ls.Destructures():
+		var names []*Identifier
+		for _, name := range ls.Names {
+			if name.Value != "_" {
+				names = append(names, name)
+			}
+		}
+		return names
+	default:
+		return []*Identifier{ls.Name}
+	}
+}
+
 // String returns a string representation of the let statement.
-// Format: "let <identifier> = <expression>;"
+// Format: "let <identifier>, ... = <expression>;" or "let <pattern> = <expression>;"
 func (ls *LetStatement) String() string {
 	var out strings.Builder
 
 	out.WriteString(ls.TokenLiteral() + " ")
-	out.WriteString(ls.Name.String())
+	switch {
+	case ls.Pattern != nil:
+		out.WriteString(ls.Pattern.String())
+	case ls.Names != nil:
+		for i, name := range ls.Names {
+			if i > 0 {
+				out.WriteString(", ")
+			}
+			out.WriteString(name.String())
+		}
+	default:
+		out.WriteString(ls.Name.String())
+	}
+	if ls.Type != nil {
+		out.WriteString(": " + ls.Type.String())
+	}
 	out.WriteString(" = ")
 
 	if ls.Value != nil {
@@ -110,10 +184,42 @@ func (ls *LetStatement) String() string {
 	return out.String()
 }
 
+// AssignStatement represents an assignment to an existing binding (e.g., "x = 5;").
+type AssignStatement struct {
+	Token token.Token // The identifier token
+	Name  *Identifier // The identifier being assigned
+	Value Expression  // The expression that produces the new value
+}
+
+func (as *AssignStatement) statementNode() {}
+
+// TokenLiteral returns the literal value of the identifier token.
+func (as *AssignStatement) TokenLiteral() string { return as.Token.Literal }
+
+// Pos returns the position of the token associated with this node.
+func (as *AssignStatement) Pos() token.Position { return as.Token.Position }
+
+// String returns a string representation of the assignment.
+// Format: "<identifier> = <expression>;"
+func (as *AssignStatement) String() string {
+	var out strings.Builder
+
+	out.WriteString(as.Name.String())
+	out.WriteString(" = ")
+	if as.Value != nil {
+		out.WriteString(as.Value.String())
+	}
+	out.WriteString(";")
+	return out.String()
+}
+
 // ReturnStatement represents a return statement (e.g., "return 5;").
+// A statement returning several values returns an array of them
+// (e.g., "return a, b;"), for the caller to destructure.
 type ReturnStatement struct {
 	Token       token.Token // The 'return' token
 	ReturnValue Expression  // The expression that produces the return value
+	Multiple    bool        // Whether ReturnValue is the *ArrayLiteral of several values
 }
 
 func (rs *ReturnStatement) statementNode() {}
@@ -121,19 +227,116 @@ func (rs *ReturnStatement) statementNode() {}
 // TokenLiteral returns the literal value of the 'return' token.
 func (rs *ReturnStatement) TokenLiteral() string { return rs.Token.Literal }
 
+// Pos returns the position of the token associated with this node.
+func (rs *ReturnStatement) Pos() token.Position { return rs.Token.Position }
+
 // String returns a string representation of the return statement.
-// Format: "return <expression>;"
+// Format: "return <expression>, ...;"
 func (rs *ReturnStatement) String() string {
 	var out strings.Builder
 	out.WriteString(rs.TokenLiteral() + " ")
 
-	if rs.ReturnValue != nil {
+	if values, ok := rs.ReturnValue.(*ArrayLiteral); ok && rs.Multiple {
+		elements := make([]string, 0, len(values.Elements))
+		for _, el := range values.Elements {
+			elements = append(elements, el.String())
+		}
+		out.WriteString(strings.Join(elements, ", "))
+	} else if rs.ReturnValue != nil {
 		out.WriteString(rs.ReturnValue.String())
 	}
 	out.WriteString(";")
 	return out.String()
 }
 
+// DeferStatement represents a deferred block (e.g., "defer { puts(x); }").
+// The block runs when the enclosing function returns or the program ends.
+type DeferStatement struct {
+	Token token.Token     // The 'defer' token
+	Body  *BlockStatement // The block to run on exit
+}
+
+func (ds *DeferStatement) statementNode() {}
+
+// TokenLiteral returns the literal value of the 'defer' token.
+func (ds *DeferStatement) TokenLiteral() string { return ds.Token.Literal }
+
+// Pos returns the position of the token associated with this node.
+func (ds *DeferStatement) Pos() token.Position { return ds.Token.Position }
+
+// String returns a string representation of the defer statement.
+// Format: "defer { <statements> }"
+func (ds *DeferStatement) String() string {
+	return ds.TokenLiteral() + " { " + ds.Body.String() + " }"
+}
+
+// ImportStatement binds the top-level names of a module in the current scope
+// (e.g., "import "std/list";"). Names starting with "_" are private to the module.
+type ImportStatement struct {
+	Token token.Token    // The 'import' token
+	Path  *StringLiteral // The path of the module, resolved by the module resolver
+}
+
+func (is *ImportStatement) statementNode() {}
+
+// TokenLiteral returns the literal value of the 'import' token.
+func (is *ImportStatement) TokenLiteral() string { return is.Token.Literal }
+
+// Pos returns the position of the token associated with this node.
+func (is *ImportStatement) Pos() token.Position { return is.Token.Position }
+
+// String returns a string representation of the import statement.
+// Format: "import "<path>";"
+func (is *ImportStatement) String() string {
+	return is.TokenLiteral() + " \"" + is.Path.Value + "\";"
+}
+
+// ClassStatement binds a class, a user-defined type with named fields and
+// methods, in the current scope
+// (e.g., "class Point(x, y) { fn norm() { self.x * self.x + self.y * self.y } }").
+// Calling the class with the values of its fields makes an instance, and the
+// methods are called on an instance bound to self.
+type ClassStatement struct {
+	Token   token.Token   // The 'class' token
+	Name    *Identifier   // The name of the class
+	Fields  []*Identifier // The fields, in the order the class is called with them
+	Methods []*Method     // The methods, in source order
+}
+
+// Method is a method of a class, written "fn name(params) { body }".
+type Method struct {
+	Name     *Identifier
+	Function *FunctionLiteral // The parameters and body, without self
+}
+
+func (cs *ClassStatement) statementNode() {}
+
+// TokenLiteral returns the literal value of the 'class' token.
+func (cs *ClassStatement) TokenLiteral() string { return cs.Token.Literal }
+
+// Pos returns the position of the token associated with this node.
+func (cs *ClassStatement) Pos() token.Position { return cs.Token.Position }
+
+// String returns a string representation of the class statement.
+// Format: "class <name>(<fields>) { fn <method>(<parameters>) <body> ... }"
+func (cs *ClassStatement) String() string {
+	var out strings.Builder
+
+	fields := make([]string, 0, len(cs.Fields))
+	for _, field := range cs.Fields {
+		fields = append(fields, field.String())
+	}
+	out.WriteString(cs.TokenLiteral() + " " + cs.Name.String())
+	out.WriteString("(" + strings.Join(fields, ", ") + ") {")
+	for _, method := range cs.Methods {
+		out.WriteString(" fn " + method.Name.String())
+		out.WriteString(strings.TrimPrefix(method.Function.String(), method.Function.TokenLiteral()))
+	}
+	out.WriteString(" }")
+
+	return out.String()
+}
+
 // ExpressionStatement represents a statement consisting of a single expression.
 // For example, function calls can be used as statements.
 type ExpressionStatement struct {
@@ -146,6 +349,9 @@ func (exp *ExpressionStatement) statementNode() {}
 // TokenLiteral returns the literal value of the token associated with this statement.
 func (exp *ExpressionStatement) TokenLiteral() string { return exp.Token.Literal }
 
+// Pos returns the position of the token associated with this node.
+func (exp *ExpressionStatement) Pos() token.Position { return exp.Token.Position }
+
 // String returns a string representation of the expression statement.
 // It delegates to the String method of the underlying expression.
 func (exp *ExpressionStatement) String() string {
@@ -167,15 +373,40 @@ func (il *IntegerLiteral) expressionNode() {}
 // TokenLiteral returns the literal value of the token associated with this integer.
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
 
+// Pos returns the position of the token associated with this node.
+func (il *IntegerLiteral) Pos() token.Position { return il.Token.Position }
+
 // String returns a string representation of the integer literal.
 func (il *IntegerLiteral) String() string { return il.Token.Literal }
 
+// FloatLiteral represents a floating-point literal in the AST.
+// For example, "3.14".
+type FloatLiteral struct {
+	Token token.Token // The token containing the float literal
+	Value float64     // The actual floating-point value
+}
+
+func (fl *FloatLiteral) expressionNode() {}
+
+// TokenLiteral returns the literal value of the token associated with this float.
+func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
+
+// Pos returns the position of the token associated with this node.
+func (fl *FloatLiteral) Pos() token.Position { return fl.Token.Position }
+
+// String returns a string representation of the float literal.
+func (fl *FloatLiteral) String() string { return fl.Token.Literal }
+
 // PrefixExpression represents a prefix operator expression in the AST.
 // For example, "-5" or "!true" where "-" and "!" are prefix operators.
 type PrefixExpression struct {
 	Token    token.Token // The prefix operator token (e.g., "!")
 	Operator string      // The operator (e.g., "!")
 	Right    Expression  // The expression to the right of the operator
+
+	// Cache is reserved for the evaluator's memo of constant values.
+	// It is not part of the syntax and is ignored by String.
+	Cache any
 }
 
 func (pe *PrefixExpression) expressionNode() {}
@@ -183,6 +414,9 @@ func (pe *PrefixExpression) expressionNode() {}
 // TokenLiteral returns the literal value of the token associated with this expression.
 func (pe *PrefixExpression) TokenLiteral() string { return pe.Token.Literal }
 
+// Pos returns the position of the token associated with this node.
+func (pe *PrefixExpression) Pos() token.Position { return pe.Token.Position }
+
 // String returns a string representation of the prefix expression.
 // Format: "(<operator><expression>)"
 func (pe *PrefixExpression) String() string {
@@ -203,6 +437,10 @@ type InfixExpression struct {
 	Left     Expression  // The expression to the left of the operator
 	Operator string      // The operator (e.g., "+")
 	Right    Expression  // The expression to the right of the operator
+
+	// Cache is reserved for the evaluator's memo of constant values.
+	// It is not part of the syntax and is ignored by String.
+	Cache any
 }
 
 func (ie *InfixExpression) expressionNode() {}
@@ -210,6 +448,9 @@ func (ie *InfixExpression) expressionNode() {}
 // TokenLiteral returns the literal value of the token associated with this expression.
 func (ie *InfixExpression) TokenLiteral() string { return ie.Token.Literal }
 
+// Pos returns the position of the token associated with this node.
+func (ie *InfixExpression) Pos() token.Position { return ie.Token.Position }
+
 // String returns a string representation of the infix expression.
 // Format: "(<left-expression> <operator> <right-expression>)"
 func (ie *InfixExpression) String() string {
@@ -236,16 +477,21 @@ func (b *Boolean) expressionNode() {}
 // TokenLiteral returns the literal value of the token associated with this boolean.
 func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
 
+// Pos returns the position of the token associated with this node.
+func (b *Boolean) Pos() token.Position { return b.Token.Position }
+
 // String returns a string representation of the boolean literal.
 func (b *Boolean) String() string { return b.Token.Literal }
 
 // IfExpression represents an if-else expression in the AST.
-// For example, "if (x > y) { x } else { y }".
+// For example, "if (x > y) { x } else { y }", or "if (a) { 1 } else if (b) { 2 }".
 type IfExpression struct {
 	Token       token.Token     // The 'if' token
 	Condition   Expression      // The condition expression
 	Consequence *BlockStatement // The block to execute if condition is true
-	Alternative *BlockStatement // The block to execute if condition is false (optional)
+	// Alternative is evaluated if the condition is false: a *BlockStatement,
+	// an *IfExpression for "else if", or nil if there is no else branch
+	Alternative Node
 }
 
 func (ie *IfExpression) expressionNode() {}
@@ -253,6 +499,9 @@ func (ie *IfExpression) expressionNode() {}
 // TokenLiteral returns the literal value of the token associated with this expression.
 func (ie *IfExpression) TokenLiteral() string { return ie.Token.Literal }
 
+// Pos returns the position of the token associated with this node.
+func (ie *IfExpression) Pos() token.Position { return ie.Token.Position }
+
 // String returns a string representation of the `if expression`.
 // Format: "if <condition> <consequence> else <alternative>"
 func (ie *IfExpression) String() string {
@@ -270,6 +519,135 @@ func (ie *IfExpression) String() string {
 	return out.String()
 }
 
+// ConditionalExpression selects one of two expressions by a condition.
+// For example, "x > y ? x : y".
+type ConditionalExpression struct {
+	Token       token.Token // The '?' token
+	Condition   Expression  // The condition expression
+	Consequence Expression  // The expression evaluated if the condition is true
+	Alternative Expression  // The expression evaluated if the condition is false
+}
+
+func (ce *ConditionalExpression) expressionNode() {}
+
+// TokenLiteral returns the literal value of the token associated with this expression.
+func (ce *ConditionalExpression) TokenLiteral() string { return ce.Token.Literal }
+
+// Pos returns the position of the token associated with this node.
+func (ce *ConditionalExpression) Pos() token.Position { return ce.Token.Position }
+
+// String returns a string representation of the conditional expression.
+// Format: "(<condition> ? <consequence> : <alternative>)"
+func (ce *ConditionalExpression) String() string {
+	return "(" + ce.Condition.String() + " ? " + ce.Consequence.String() + " : " + ce.Alternative.String() + ")"
+}
+
+// WhileExpression represents a loop that runs its body as long as a condition holds.
+// For example, "while (i < 10) { i = i + 1; }".
+type WhileExpression struct {
+	Token     token.Token     // The 'while' token
+	Condition Expression      // The condition checked before each iteration
+	Body      *BlockStatement // The block to execute on each iteration
+}
+
+func (we *WhileExpression) expressionNode() {}
+
+// TokenLiteral returns the literal value of the token associated with this expression.
+func (we *WhileExpression) TokenLiteral() string { return we.Token.Literal }
+
+// Pos returns the position of the token associated with this node.
+func (we *WhileExpression) Pos() token.Position { return we.Token.Position }
+
+// String returns a string representation of the while loop.
+// Format: "while<condition> <body>"
+func (we *WhileExpression) String() string {
+	var out strings.Builder
+
+	out.WriteString("while")
+	out.WriteString(we.Condition.String())
+	out.WriteString(" ")
+	out.WriteString(we.Body.String())
+	return out.String()
+}
+
+// ForExpression represents a C-style for loop.
+// For example, "for (let i = 0; i < 10; i = i + 1) { puts(i); }".
+// Init, Condition and Post are nil when omitted.
+type ForExpression struct {
+	Token     token.Token     // The 'for' token
+	Init      Statement       // The statement run once before the loop
+	Condition Expression      // The condition checked before each iteration
+	Post      Statement       // The statement run after each iteration
+	Body      *BlockStatement // The block to execute on each iteration
+}
+
+func (fe *ForExpression) expressionNode() {}
+
+// TokenLiteral returns the literal value of the token associated with this expression.
+func (fe *ForExpression) TokenLiteral() string { return fe.Token.Literal }
+
+// Pos returns the position of the token associated with this node.
+func (fe *ForExpression) Pos() token.Position { return fe.Token.Position }
+
+// String returns a string representation of the for loop.
+// Format: "for (<init>; <condition>; <post>) <body>"
+func (fe *ForExpression) String() string {
+	var out strings.Builder
+
+	out.WriteString("for (")
+	if fe.Init != nil {
+		out.WriteString(strings.TrimSuffix(fe.Init.String(), ";"))
+	}
+	out.WriteString("; ")
+	if fe.Condition != nil {
+		out.WriteString(fe.Condition.String())
+	}
+	out.WriteString("; ")
+	if fe.Post != nil {
+		out.WriteString(strings.TrimSuffix(fe.Post.String(), ";"))
+	}
+	out.WriteString(") ")
+	out.WriteString(fe.Body.String())
+	return out.String()
+}
+
+// ForInExpression represents a loop over the elements of an array or the pairs of a hash.
+// For example, "for (x in xs) { puts(x); }" or "for (k, v in h) { puts(k, v); }".
+// With a single name, it is bound to each array element or hash key; with two,
+// Key is bound to the index or key and Value to the element or value.
+type ForInExpression struct {
+	Token    token.Token     // The 'for' token
+	Key      *Identifier     // The name bound to the index or key, nil if only one name is given
+	Value    *Identifier     // The name bound to the element, value, or with a single name, hash key
+	Iterable Expression      // The collection to iterate over
+	Body     *BlockStatement // The block to execute on each iteration
+}
+
+func (fi *ForInExpression) expressionNode() {}
+
+// TokenLiteral returns the literal value of the token associated with this expression.
+func (fi *ForInExpression) TokenLiteral() string { return fi.Token.Literal }
+
+// Pos returns the position of the token associated with this node.
+func (fi *ForInExpression) Pos() token.Position { return fi.Token.Position }
+
+// String returns a string representation of the loop.
+// Format: "for (<key>, <value> in <iterable>) <body>"
+func (fi *ForInExpression) String() string {
+	var out strings.Builder
+
+	out.WriteString("for (")
+	if fi.Key != nil {
+		out.WriteString(fi.Key.String() + ", ")
+	}
+	out.WriteString(fi.Value.String())
+	out.WriteString(" in ")
+	out.WriteString(fi.Iterable.String())
+	out.WriteString(") ")
+	out.WriteString(fi.Body.String())
+	return out.String()
+}
+
 // BlockStatement represents a block of statements enclosed in braces.
 // For example, "{ statement1; statement2; }".
 type BlockStatement struct {
@@ -282,6 +660,9 @@ func (bs *BlockStatement) statementNode() {}
 // TokenLiteral returns the literal value of the token associated with this block.
 func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
 
+// Pos returns the position of the token associated with this node.
+func (bs *BlockStatement) Pos() token.Position { return bs.Token.Position }
+
 // String returns a string representation of the block statement.
 // It concatenates the string representations of all statements in the block.
 func (bs *BlockStatement) String() string {
@@ -298,6 +679,9 @@ func (bs *BlockStatement) String() string {
 type FunctionLiteral struct {
 	Token      token.Token     // The 'fn' token
 	Parameters []*Identifier   // The function parameters
+	ParamTypes []*Identifier   // The parameter type annotations, nil if none are annotated
+	Rest       bool            // The last parameter collects the remaining arguments into an array
+	ReturnType *Identifier     // The optional return type annotation
 	Body       *BlockStatement // The function body
 }
 
@@ -306,25 +690,46 @@ func (fl *FunctionLiteral) expressionNode() {}
 // TokenLiteral returns the literal value of the token associated with this function.
 func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
 
+// Pos returns the position of the token associated with this node.
+func (fl *FunctionLiteral) Pos() token.Position { return fl.Token.Position }
+
 // String returns a string representation of the function literal.
 // Format: "fn(<parameters>) <body>"
 func (fl *FunctionLiteral) String() string {
 	var out strings.Builder
 
 	params := make([]string, 0, len(fl.Parameters))
-	for _, p := range fl.Parameters {
-		params = append(params, p.String())
+	for i, p := range fl.Parameters {
+		param := p.String()
+		if fl.Rest && i == len(fl.Parameters)-1 {
+			param = "..." + param
+		}
+		if typ := fl.ParamType(i); typ != nil {
+			param += ": " + typ.String()
+		}
+		params = append(params, param)
 	}
 
 	out.WriteString(fl.TokenLiteral())
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(")")
+	if fl.ReturnType != nil {
+		out.WriteString(" -> " + fl.ReturnType.String() + " ")
+	}
 	out.WriteString(fl.Body.String())
 
 	return out.String()
 }
 
+// ParamType returns the type annotation of the i-th parameter, or nil if it has none.
+func (fl *FunctionLiteral) ParamType(i int) *Identifier {
+	if i < len(fl.ParamTypes) {
+		return fl.ParamTypes[i]
+	}
+	return nil
+}
+
 // CallExpression represents a function call in the AST.
 // For example, "add(1, 2)" or "fn(x, y){ x + y }(1, 2)".
 type CallExpression struct {
@@ -338,6 +743,9 @@ func (ce *CallExpression) expressionNode() {}
 // TokenLiteral returns the literal value of the token associated with this call.
 func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
 
+// Pos returns the position of the token associated with this node.
+func (ce *CallExpression) Pos() token.Position { return ce.Token.Position }
+
 // String returns a string representation of the function call.
 // Format: "<function>(<arguments>)"
 func (ce *CallExpression) String() string {
@@ -367,6 +775,9 @@ func (sl *StringLiteral) expressionNode() {}
 // TokenLiteral returns the literal value of the token associated with this string.
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
 
+// Pos returns the position of the token associated with this node.
+func (sl *StringLiteral) Pos() token.Position { return sl.Token.Position }
+
 // String returns a string representation of the string literal.
 func (sl *StringLiteral) String() string { return sl.Token.Literal }
 
@@ -382,6 +793,9 @@ func (al *ArrayLiteral) expressionNode() {}
 // TokenLiteral returns the literal value of the token associated with this array.
 func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
 
+// Pos returns the position of the token associated with this node.
+func (al *ArrayLiteral) Pos() token.Position { return al.Token.Position }
+
 // String returns a string representation of the array literal.
 // Format: "[<elements>]"
 func (al *ArrayLiteral) String() string {
@@ -398,12 +812,56 @@ func (al *ArrayLiteral) String() string {
 	return out.String()
 }
 
+// SpreadElement represents an element spread into an array, hash or argument list
+// (e.g., "...rest" in "[1, ...rest]").
+type SpreadElement struct {
+	Token token.Token // The '...' token
+	Value Expression  // The array or hash being spread
+}
+
+func (se *SpreadElement) expressionNode() {}
+
+// TokenLiteral returns the literal value of the '...' token.
+func (se *SpreadElement) TokenLiteral() string { return se.Token.Literal }
+
+// Pos returns the position of the token associated with this node.
+func (se *SpreadElement) Pos() token.Position { return se.Token.Position }
+
+// String returns a string representation of the spread element.
+// Format: "...<value>"
+func (se *SpreadElement) String() string { return "..." + se.Value.String() }
+
+// NamedArgument represents an argument passed by the name of the parameter it
+// binds (e.g., "age: 3" in "makeUser(name, age: 3)"). Named arguments follow
+// the positional ones.
+type NamedArgument struct {
+	Token token.Token // The name token
+	Name  *Identifier // The name of the parameter
+	Value Expression
+}
+
+func (na *NamedArgument) expressionNode() {}
+
+// TokenLiteral returns the literal value of the name token.
+func (na *NamedArgument) TokenLiteral() string { return na.Token.Literal }
+
+// Pos returns the position of the token associated with this node.
+func (na *NamedArgument) Pos() token.Position { return na.Token.Position }
+
+// String returns a string representation of the named argument.
+// Format: "<name>: <value>"
+func (na *NamedArgument) String() string { return na.Name.String() + ": " + na.Value.String() }
+
 // IndexExpression represents an index expression in the AST.
 // For example, "myArray[1]" or "myHash["key"]".
+// A field access, "h.field", is shorthand for "h["field"]". Optional index
+// expressions, written "h?.[index]" or "h?.field", evaluate to null instead
+// of failing when the indexed value is null.
 type IndexExpression struct {
-	Token token.Token // The '[' token
-	Left  Expression  // The expression being indexed (array or hash)
-	Index Expression  // The index expression
+	Token    token.Token // The '[', '.' or '?.' token
+	Left     Expression  // The expression being indexed (array or hash)
+	Index    Expression  // The index expression
+	Optional bool        // Whether the index uses the '?.' operator
 }
 
 func (ie *IndexExpression) expressionNode() {}
@@ -411,13 +869,38 @@ func (ie *IndexExpression) expressionNode() {}
 // TokenLiteral returns the literal value of the token associated with this expression.
 func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
 
+// Pos returns the position of the token associated with this node.
+func (ie *IndexExpression) Pos() token.Position { return ie.Token.Position }
+
+// Field returns the name of the field of an index expression written
+// "h.field" or "h?.field", and whether it is written that way.
+func (ie *IndexExpression) Field() (string, bool) {
+	field, ok := ie.Index.(*StringLiteral)
+	if !ok || field.Token.Type != token.IDENT {
+		return "", false
+	}
+	return field.Value, true
+}
+
 // String returns a string representation of the index expression.
-// Format: "(<left-expression>[<index-expression>])"
+// Format: "(<left-expression>[<index-expression>])", "(<left-expression>.<field>)",
+// "(<left-expression>?.[<index-expression>])" or "(<left-expression>?.<field>)".
 func (ie *IndexExpression) String() string {
 	var out strings.Builder
 
 	out.WriteString("(")
 	out.WriteString(ie.Left.String())
+	if ie.Optional {
+		out.WriteString("?.")
+	}
+	if field, ok := ie.Field(); ok {
+		if !ie.Optional {
+			out.WriteString(".")
+		}
+		out.WriteString(field)
+		out.WriteString(")")
+		return out.String()
+	}
 	out.WriteString("[")
 	out.WriteString(ie.Index.String())
 	out.WriteString("])")
@@ -425,11 +908,54 @@ func (ie *IndexExpression) String() string {
 	return out.String()
 }
 
+// SliceExpression represents a slice of an array or string in the AST.
+// For example, "arr[1:3]", "arr[:2]" or "str[2:]".
+type SliceExpression struct {
+	Token    token.Token // The '[' or '?.' token
+	Left     Expression  // The expression being sliced (array or string)
+	Start    Expression  // The first index of the slice, or nil for the start
+	End      Expression  // The index the slice stops before, or nil for the end
+	Optional bool        // Whether the slice uses the '?.' operator
+}
+
+func (se *SliceExpression) expressionNode() {}
+
+// TokenLiteral returns the literal value of the token associated with this expression.
+func (se *SliceExpression) TokenLiteral() string { return se.Token.Literal }
+
+// Pos returns the position of the token associated with this node.
+func (se *SliceExpression) Pos() token.Position { return se.Token.Position }
+
+// String returns a string representation of the slice expression.
+// Format: "(<left-expression>[<start>:<end>])" or "(<left-expression>?.[<start>:<end>])",
+// where either bound may be left out.
+func (se *SliceExpression) String() string {
+	var out strings.Builder
+
+	out.WriteString("(")
+	out.WriteString(se.Left.String())
+	if se.Optional {
+		out.WriteString("?.")
+	}
+	out.WriteString("[")
+	if se.Start != nil {
+		out.WriteString(se.Start.String())
+	}
+	out.WriteString(":")
+	if se.End != nil {
+		out.WriteString(se.End.String())
+	}
+	out.WriteString("])")
+
+	return out.String()
+}
+
 // HashLiteral represents a hash literal expression in the AST.
 // For example, "{key1: value1, key2: value2}".
 type HashLiteral struct {
 	Token token.Token               // The '{' token
 	Pairs map[Expression]Expression // The key-value pairs in the hash
+	Order []Expression              // The keys of Pairs and any spread elements, in source order
 }
 
 func (hl *HashLiteral) expressionNode() {}
@@ -437,14 +963,27 @@ func (hl *HashLiteral) expressionNode() {}
 // TokenLiteral returns the literal value of the token associated with this hash.
 func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
 
+// Pos returns the position of the token associated with this node.
+func (hl *HashLiteral) Pos() token.Position { return hl.Token.Position }
+
 // String returns a string representation of the hash literal.
 // Format: "{<key1>:<value1>, <key2>:<value2>, ...}"
 func (hl *HashLiteral) String() string {
 	var out strings.Builder
 
 	pairs := make([]string, 0, len(hl.Pairs))
-	for key, value := range hl.Pairs {
-		pairs = append(pairs, key.String()+":"+value.String())
+	if hl.Order != nil {
+		for _, key := range hl.Order {
+			if spread, ok := key.(*SpreadElement); ok {
+				pairs = append(pairs, spread.String())
+			} else {
+				pairs = append(pairs, key.String()+":"+hl.Pairs[key].String())
+			}
+		}
+	} else {
+		for key, value := range hl.Pairs {
+			pairs = append(pairs, key.String()+":"+value.String())
+		}
 	}
 	out.WriteString("{")
 	out.WriteString(strings.Join(pairs, ", "))
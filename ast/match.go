@@ -0,0 +1,162 @@
+package ast
+
+import (
+	"strings"
+
+	"github.com/dr8co/monke/token"
+)
+
+// MatchExpression compares a value against the patterns of its arms in order,
+// and evaluates the body of the first arm that matches.
+// For example, "match (x) { 0 => "zero", [first, ...rest] => first, _ => x }".
+type MatchExpression struct {
+	Token token.Token // The 'match' token
+	Value Expression  // The value to match
+	Arms  []*MatchArm // The arms, in the order they are tried
+}
+
+func (me *MatchExpression) expressionNode() {}
+
+// TokenLiteral returns the literal value of the token associated with this expression.
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+
+// Pos returns the position of the token associated with this node.
+func (me *MatchExpression) Pos() token.Position { return me.Token.Position }
+
+// String returns a string representation of the match expression.
+// Format: "match (<value>) { <pattern> => <body>, ... }"
+func (me *MatchExpression) String() string {
+	arms := make([]string, len(me.Arms))
+	for i, arm := range me.Arms {
+		arms[i] = arm.String()
+	}
+	return "match (" + me.Value.String() + ") { " + strings.Join(arms, ", ") + " }"
+}
+
+// MatchArm is an arm of a match expression. The names bound by its pattern
+// are only visible in its body.
+type MatchArm struct {
+	Token   token.Token // The '=>' token
+	Pattern Pattern
+	Body    Statement // A *BlockStatement, or an *ExpressionStatement for "pattern => expression"
+}
+
+// TokenLiteral returns the literal value of the token associated with this arm.
+func (ma *MatchArm) TokenLiteral() string { return ma.Token.Literal }
+
+// Pos returns the position of the pattern of the arm.
+func (ma *MatchArm) Pos() token.Position { return ma.Pattern.Pos() }
+
+// String returns a string representation of the arm.
+// Format: "<pattern> => <body>"
+func (ma *MatchArm) String() string {
+	return ma.Pattern.String() + " => " + ma.Body.String()
+}
+
+// Pattern is the interface for the patterns of match arms.
+type Pattern interface {
+	Node
+	patternNode() // Marker method to identify pattern nodes
+}
+
+// BindingPattern matches any value and binds it to a name.
+// The name "_" matches without binding anything.
+type BindingPattern struct {
+	Name *Identifier
+}
+
+func (bp *BindingPattern) patternNode() {}
+
+// TokenLiteral returns the literal value of the token associated with this pattern.
+func (bp *BindingPattern) TokenLiteral() string { return bp.Name.Token.Literal }
+
+// Pos returns the position of the name.
+func (bp *BindingPattern) Pos() token.Position { return bp.Name.Pos() }
+
+// String returns the name.
+func (bp *BindingPattern) String() string { return bp.Name.Value }
+
+// Wildcard reports whether the pattern is "_", which binds nothing.
+func (bp *BindingPattern) Wildcard() bool { return bp.Name.Value == "_" }
+
+// LiteralPattern matches values equal to a literal: a number, which may be
+// negative, a string or a boolean.
+type LiteralPattern struct {
+	Value Expression
+}
+
+func (lp *LiteralPattern) patternNode() {}
+
+// TokenLiteral returns the literal value of the token associated with this pattern.
+func (lp *LiteralPattern) TokenLiteral() string { return lp.Value.TokenLiteral() }
+
+// Pos returns the position of the literal.
+func (lp *LiteralPattern) Pos() token.Position { return lp.Value.Pos() }
+
+// String returns a string representation of the literal.
+func (lp *LiteralPattern) String() string { return lp.Value.String() }
+
+// ArrayPattern matches the arrays whose elements match its elements. Without
+// Rest, the array must have exactly as many elements; with it, Rest is
+// matched against an array of the elements left over.
+// For example, "[x, y]" or "[first, ...rest]".
+type ArrayPattern struct {
+	Token    token.Token // The '[' token
+	Elements []Pattern
+	Rest     *BindingPattern // The pattern after '...', or nil
+}
+
+func (ap *ArrayPattern) patternNode() {}
+
+// TokenLiteral returns the literal value of the token associated with this pattern.
+func (ap *ArrayPattern) TokenLiteral() string { return ap.Token.Literal }
+
+// Pos returns the position of the token associated with this node.
+func (ap *ArrayPattern) Pos() token.Position { return ap.Token.Position }
+
+// String returns a string representation of the pattern.
+// Format: "[<element>, ..., ...<rest>]"
+func (ap *ArrayPattern) String() string {
+	elements := make([]string, 0, len(ap.Elements)+1)
+	for _, el := range ap.Elements {
+		elements = append(elements, el.String())
+	}
+	if ap.Rest != nil {
+		elements = append(elements, "..."+ap.Rest.String())
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+// HashPattern matches the hashes that have all its keys, with values matching
+// the patterns of the keys; other keys are ignored. With a Tag, the hash must
+// also carry that tag. Keys written as bare names, as in "{name: n}", are
+// string keys, and "{name}" is short for "{name: name}".
+// For example, "{"id": 1, name}" or "Point {x, y}".
+type HashPattern struct {
+	Token  token.Token // The '{' token
+	Tag    string      // The type tag the hash must have, or "" for any hash
+	Keys   []Expression
+	Values []Pattern // The patterns of the values of Keys
+}
+
+func (hp *HashPattern) patternNode() {}
+
+// TokenLiteral returns the literal value of the token associated with this pattern.
+func (hp *HashPattern) TokenLiteral() string { return hp.Token.Literal }
+
+// Pos returns the position of the token associated with this node.
+func (hp *HashPattern) Pos() token.Position { return hp.Token.Position }
+
+// String returns a string representation of the pattern.
+// Format: "<tag> {<key>: <pattern>, ...}"
+func (hp *HashPattern) String() string {
+	pairs := make([]string, len(hp.Keys))
+	for i, key := range hp.Keys {
+		pairs[i] = key.String() + ": " + hp.Values[i].String()
+	}
+	out := "{" + strings.Join(pairs, ", ") + "}"
+	if hp.Tag != "" {
+		out = hp.Tag + " " + out
+	}
+	return out
+}
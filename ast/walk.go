@@ -0,0 +1,151 @@
+package ast
+
+import "reflect"
+
+// Inspect traverses the AST rooted at node in depth-first order. It starts by
+// calling f(node); if f returns true, Inspect visits each of the children of
+// node in source order, followed by a call of f(nil), so that f can keep track
+// of the depth. Nil children are skipped.
+func Inspect(node Node, f func(Node) bool) {
+	if isNilNode(node) || !f(node) {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		for _, s := range n.Statements {
+			Inspect(s, f)
+		}
+	case *LetStatement:
+		if n.Names == nil {
+			Inspect(n.Name, f)
+		}
+		for _, name := range n.Names {
+			Inspect(name, f)
+		}
+		Inspect(n.Pattern, f)
+		Inspect(n.Type, f)
+		Inspect(n.Value, f)
+	case *AssignStatement:
+		Inspect(n.Name, f)
+		Inspect(n.Value, f)
+	case *ReturnStatement:
+		Inspect(n.ReturnValue, f)
+	case *DeferStatement:
+		Inspect(n.Body, f)
+	case *ImportStatement:
+		Inspect(n.Path, f)
+	case *ClassStatement:
+		Inspect(n.Name, f)
+		for _, field := range n.Fields {
+			Inspect(field, f)
+		}
+		for _, method := range n.Methods {
+			Inspect(method.Name, f)
+			Inspect(method.Function, f)
+		}
+	case *ExpressionStatement:
+		Inspect(n.Expression, f)
+	case *BlockStatement:
+		for _, s := range n.Statements {
+			Inspect(s, f)
+		}
+	case *PrefixExpression:
+		Inspect(n.Right, f)
+	case *InfixExpression:
+		Inspect(n.Left, f)
+		Inspect(n.Right, f)
+	case *IfExpression:
+		Inspect(n.Condition, f)
+		Inspect(n.Consequence, f)
+		Inspect(n.Alternative, f)
+	case *ConditionalExpression:
+		Inspect(n.Condition, f)
+		Inspect(n.Consequence, f)
+		Inspect(n.Alternative, f)
+	case *WhileExpression:
+		Inspect(n.Condition, f)
+		Inspect(n.Body, f)
+	case *ForExpression:
+		Inspect(n.Init, f)
+		Inspect(n.Condition, f)
+		Inspect(n.Post, f)
+		Inspect(n.Body, f)
+	case *ForInExpression:
+		Inspect(n.Key, f)
+		Inspect(n.Value, f)
+		Inspect(n.Iterable, f)
+		Inspect(n.Body, f)
+	case *FunctionLiteral:
+		for i, param := range n.Parameters {
+			Inspect(param, f)
+			if i < len(n.ParamTypes) {
+				Inspect(n.ParamTypes[i], f)
+			}
+		}
+		Inspect(n.ReturnType, f)
+		Inspect(n.Body, f)
+	case *CallExpression:
+		Inspect(n.Function, f)
+		for _, arg := range n.Arguments {
+			Inspect(arg, f)
+		}
+	case *ArrayLiteral:
+		for _, el := range n.Elements {
+			Inspect(el, f)
+		}
+	case *NamedArgument:
+		Inspect(n.Name, f)
+		Inspect(n.Value, f)
+	case *SpreadElement:
+		Inspect(n.Value, f)
+	case *IndexExpression:
+		Inspect(n.Left, f)
+		Inspect(n.Index, f)
+	case *SliceExpression:
+		Inspect(n.Left, f)
+		Inspect(n.Start, f)
+		Inspect(n.End, f)
+	case *HashLiteral:
+		for _, key := range n.Order {
+			Inspect(key, f)
+			if _, ok := key.(*SpreadElement); !ok {
+				Inspect(n.Pairs[key], f)
+			}
+		}
+	case *MatchExpression:
+		Inspect(n.Value, f)
+		for _, arm := range n.Arms {
+			Inspect(arm, f)
+		}
+	case *MatchArm:
+		Inspect(n.Pattern, f)
+		Inspect(n.Body, f)
+	case *BindingPattern:
+		Inspect(n.Name, f)
+	case *LiteralPattern:
+		Inspect(n.Value, f)
+	case *ArrayPattern:
+		for _, el := range n.Elements {
+			Inspect(el, f)
+		}
+		Inspect(n.Rest, f)
+	case *HashPattern:
+		for i, key := range n.Keys {
+			Inspect(key, f)
+			Inspect(n.Values[i], f)
+		}
+	}
+	f(nil)
+}
+
+// isNilNode reports whether node is nil or a nil pointer, as the absent optional
+// children of a node and the statements the parser leaves behind after an
+// error are.
+func isNilNode(node Node) bool {
+	if node == nil {
+		return true
+	}
+	v := reflect.ValueOf(node)
+	return v.Kind() == reflect.Pointer && v.IsNil()
+}
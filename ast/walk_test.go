@@ -0,0 +1,66 @@
+package ast_test
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/dr8co/monke/ast"
+	"github.com/dr8co/monke/lexer"
+	"github.com/dr8co/monke/parser"
+)
+
+func TestInspect(t *testing.T) {
+	input := `let f = fn(a: int) { if (a <= 1) { a } else { [a, {"k": a}[0]] } };`
+	expected := []string{
+		"*ast.Program", "*ast.LetStatement", "*ast.Identifier", "*ast.FunctionLiteral",
+		"*ast.Identifier", "*ast.Identifier", "*ast.BlockStatement", "*ast.ExpressionStatement",
+		"*ast.IfExpression", "*ast.InfixExpression", "*ast.Identifier", "*ast.IntegerLiteral",
+		"*ast.BlockStatement", "*ast.ExpressionStatement", "*ast.Identifier",
+		"*ast.BlockStatement", "*ast.ExpressionStatement", "*ast.ArrayLiteral", "*ast.Identifier",
+		"*ast.IndexExpression", "*ast.HashLiteral", "*ast.StringLiteral", "*ast.Identifier",
+		"*ast.IntegerLiteral",
+	}
+
+	program := parser.New(lexer.New(input)).ParseProgram()
+	var visited []string
+	depth, maxDepth := 0, 0
+	ast.Inspect(program, func(node ast.Node) bool {
+		if node == nil {
+			depth--
+			return false
+		}
+		depth++
+		maxDepth = max(maxDepth, depth)
+		visited = append(visited, fmt.Sprintf("%T", node))
+		return true
+	})
+
+	if !slices.Equal(visited, expected) {
+		t.Errorf("wrong order of nodes.\nexpected=%q\ngot=%q", expected, visited)
+	}
+	if depth != 0 {
+		t.Errorf("f(nil) not called once per node. depth=%d", depth)
+	}
+	if maxDepth != 12 {
+		t.Errorf("wrong maximum depth. expected=12, got=%d", maxDepth)
+	}
+}
+
+func TestInspectSkipsChildren(t *testing.T) {
+	program := parser.New(lexer.New("fn(x) { x + 1 }; 2")).ParseProgram()
+	var count int
+	ast.Inspect(program, func(node ast.Node) bool {
+		if node == nil {
+			return false
+		}
+		count++
+		_, isFunction := node.(*ast.FunctionLiteral)
+		return !isFunction
+	})
+
+	// Program, two expression statements, the function and the integer
+	if count != 5 {
+		t.Errorf("wrong number of nodes visited. expected=5, got=%d", count)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dr8co/monke/bench"
+)
+
+// runBench implements "monke bench", which compares benchmark results.
+func runBench(args []string) int {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "Usage: %s bench diff old.json new.json\n\n", filepath.Base(os.Args[0]))
+		fmt.Fprintln(out, "Compares the results the profiler writes with -json, e.g. before and after a change:")
+		fmt.Fprintln(out, "  go run ./cmd/profile -program=all -json > old.json")
+		fmt.Fprintln(out, "For each program, it prints the mean times with their 95% confidence intervals, the")
+		fmt.Fprintln(out, "change and the p-value of Welch's t-test, and the change of the allocations per run.")
+		fmt.Fprintf(out, "Changes of the time with a p-value of %g or more may be noise, and are shown as ~.\n", bench.Alpha)
+	}
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 3 || fs.Arg(0) != "diff" {
+		fs.Usage()
+		return 2
+	}
+	before, err := bench.ReadFile(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return 1
+	}
+	after, err := bench.ReadFile(fs.Arg(2))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return 1
+	}
+	if err := bench.WriteDiff(os.Stdout, bench.Compare(before, after)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,202 @@
+// Package bench records and compares the costs of running Monke programs.
+//
+// The profiler (cmd/profile) writes a Result per program with -json, one JSON
+// object per line. Comparing the results of two versions of the interpreter
+// tells whether a change to it made programs faster or slower, beyond the
+// noise of the machine they ran on.
+//
+// Key components:
+//   - Result: What runs of a program cost, as written by the profiler
+//   - Summarize, Inliers: Describe the durations of runs, without outliers
+//   - WelchTest: Tells whether two sets of runs differ
+//   - Compare, WriteDiff: Compare the results of two versions per program
+package bench
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Result is what runs of a program cost. The durations leave out the runs
+// rejected as outliers; the other costs, which do not depend on how busy the
+// machine is, cover every measured run.
+type Result struct {
+	Program    string  `json:"program"`
+	Result     string  `json:"result"`
+	Warmup     int     `json:"warmup"`          // Runs before the measured ones, not measured
+	Iterations int     `json:"iterations"`      // Measured runs
+	Outliers   int     `json:"outliers"`        // Measured runs left out of the durations
+	Parse      Summary `json:"parse"`           // Lexing and parsing
+	Eval       Summary `json:"eval"`            // Evaluation
+	Wall       Summary `json:"wall"`            // Lexing, parsing and evaluation
+	Steps      int     `json:"steps"`           // Statements and loop iterations evaluated, per run
+	Allocs     uint64  `json:"allocs"`          // Go heap allocations, per run
+	AllocBytes uint64  `json:"alloc_bytes"`     // Bytes of the Go heap allocations, per run
+	GCCycles   uint32  `json:"gc_cycles"`       // Garbage collections completed during the measured runs
+	PeakHeap   uint64  `json:"peak_heap_bytes"` // Largest heap sampled during the measured runs
+}
+
+// Runs returns the number of runs the durations of r describe.
+func (r Result) Runs() int {
+	return r.Iterations - r.Outliers
+}
+
+// Read reads the results in r, a sequence of JSON objects such as the lines
+// the profiler writes with -json.
+func Read(r io.Reader) ([]Result, error) {
+	var results []Result
+	dec := json.NewDecoder(r)
+	for {
+		var result Result
+		err := dec.Decode(&result)
+		if errors.Is(err, io.EOF) {
+			return results, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if result.Program == "" {
+			return nil, fmt.Errorf("result %d has no program", len(results)+1)
+		}
+		results = append(results, result)
+	}
+}
+
+// ReadFile reads the results in the file name.
+func ReadFile(name string) ([]Result, error) {
+	f, err := os.Open(name) //nolint:gosec // The path is supplied by the user on purpose
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	results, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return results, nil
+}
+
+// Alpha is the p-value below which a difference of durations is significant.
+const Alpha = 0.05
+
+// Delta compares the results of a program in two versions. Old or New is nil
+// if the program has a result in only one of them.
+type Delta struct {
+	Program  string
+	Old, New *Result
+	P        float64 // The p-value of Welch's t-test of the wall times
+}
+
+// Time returns the relative change of the mean wall time, e.g. -0.1 for 10% faster.
+func (d Delta) Time() float64 {
+	return change(float64(d.Old.Wall.Mean), float64(d.New.Wall.Mean))
+}
+
+// Allocs returns the relative change of the allocations per run.
+func (d Delta) Allocs() float64 {
+	return change(float64(d.Old.Allocs), float64(d.New.Allocs))
+}
+
+// Significant reports whether the wall times differ beyond the noise.
+func (d Delta) Significant() bool {
+	return d.P < Alpha
+}
+
+// change returns the relative change from before to after.
+func change(before, after float64) float64 {
+	if before == 0 {
+		return 0
+	}
+	return (after - before) / before
+}
+
+// Compare compares the results of the programs in the old version, before,
+// with those in the new one, after, in the order the programs first appear.
+// A program with several results in a version is compared by the last.
+func Compare(before, after []Result) []Delta {
+	var deltas []Delta
+	index := make(map[string]int)
+	add := func(results []Result, isNew bool) {
+		for i := range results {
+			r := &results[i]
+			j, ok := index[r.Program]
+			if !ok {
+				j = len(deltas)
+				index[r.Program] = j
+				deltas = append(deltas, Delta{Program: r.Program})
+			}
+			if isNew {
+				deltas[j].New = r
+			} else {
+				deltas[j].Old = r
+			}
+		}
+	}
+	add(before, false)
+	add(after, true)
+
+	for i, d := range deltas {
+		deltas[i].P = 1
+		if d.Old != nil && d.New != nil {
+			deltas[i].P = WelchTest(d.Old.Wall, d.Old.Runs(), d.New.Wall, d.New.Runs())
+		}
+	}
+	return deltas
+}
+
+// WriteDiff writes deltas to w as an aligned table, with the mean wall times
+// of each program in both versions, their change and its p-value, and the
+// change of the allocations. Changes of the wall time that are not
+// significant are shown as "~".
+func WriteDiff(w io.Writer, deltas []Delta) error {
+	var s strings.Builder
+	tw := tabwriter.NewWriter(&s, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Program\tOld time\tNew time\tDelta\tP\tOld allocs\tNew allocs\tDelta")
+	for _, d := range deltas {
+		switch {
+		case d.Old == nil:
+			fmt.Fprintf(tw, "%s\t-\t%s\t\t\t-\t%d\t\n", d.Program, round(d.New.Wall.Mean), d.New.Allocs)
+		case d.New == nil:
+			fmt.Fprintf(tw, "%s\t%s\t-\t\t\t%d\t-\t\n", d.Program, round(d.Old.Wall.Mean), d.Old.Allocs)
+		default:
+			delta := "~"
+			if d.Significant() {
+				delta = percent(d.Time())
+			}
+			fmt.Fprintf(tw, "%s\t%s ± %s\t%s ± %s\t%s\t%.3f\t%d\t%d\t%s\n", d.Program,
+				round(d.Old.Wall.Mean), round(margin(d.Old.Wall)), round(d.New.Wall.Mean), round(margin(d.New.Wall)),
+				delta, d.P, d.Old.Allocs, d.New.Allocs, percent(d.Allocs()))
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, s.String())
+	return err
+}
+
+// margin returns the half-width of the confidence interval of s.
+func margin(s Summary) time.Duration {
+	return (s.High - s.Low) / 2
+}
+
+// round rounds d to three decimals of its unit, e.g. 16.354ms, as more are noise.
+func round(d time.Duration) time.Duration {
+	for _, unit := range []time.Duration{time.Second, time.Millisecond, time.Microsecond} {
+		if d >= unit {
+			return d.Round(unit / 1000)
+		}
+	}
+	return d
+}
+
+// percent formats a relative change as a signed percentage.
+func percent(change float64) string {
+	return fmt.Sprintf("%+.2f%%", change*100)
+}
@@ -0,0 +1,131 @@
+package bench
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSummarize(t *testing.T) {
+	s := Summarize([]time.Duration{5, 1, 3, 2, 4})
+	// The standard deviation is sqrt(2.5) and the margin 2.776 * sqrt(2.5) / sqrt(5),
+	// truncated to whole nanoseconds
+	expected := Summary{Mean: 3, Median: 3, StdDev: 1, Min: 1, Max: 5, Low: 1, High: 4}
+	if s != expected {
+		t.Errorf("Summarize wrong. expected=%+v, got=%+v", expected, s)
+	}
+
+	if s := Summarize([]time.Duration{4, 2}); s.Median != 3 {
+		t.Errorf("median of an even number of durations wrong. got=%d", s.Median)
+	}
+	if s := Summarize([]time.Duration{7}); s.Low != 7 || s.High != 7 || s.StdDev != 0 {
+		t.Errorf("a single duration has no spread. got=%+v", s)
+	}
+}
+
+func TestInliers(t *testing.T) {
+	tests := []struct {
+		durations []time.Duration
+		expected  []int
+	}{
+		{[]time.Duration{10, 11, 12, 11, 10, 12, 11, 100}, []int{0, 1, 2, 3, 4, 5, 6}},
+		{[]time.Duration{10, 11, 12, 11, 10, 1}, []int{0, 1, 2, 3, 4}},
+		{[]time.Duration{10, 20, 30, 40}, []int{0, 1, 2, 3}},
+		{[]time.Duration{1, 100, 1000}, []int{0, 1, 2}}, // Too few to tell
+	}
+	for _, tt := range tests {
+		got := Inliers(tt.durations)
+		if len(got) != len(tt.expected) {
+			t.Errorf("Inliers(%v) wrong. expected=%v, got=%v", tt.durations, tt.expected, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.expected[i] {
+				t.Errorf("Inliers(%v) wrong. expected=%v, got=%v", tt.durations, tt.expected, got)
+				break
+			}
+		}
+	}
+}
+
+func TestWelchTest(t *testing.T) {
+	// The critical values of the table are where the p-value is 0.05
+	for i, critical := range tTable {
+		df := float64(i + 1)
+		if p := incompleteBeta(df/2, 0.5, df/(df+critical*critical)); math.Abs(p-0.05) > 0.0005 {
+			t.Errorf("p-value of t=%g with %g degrees of freedom wrong. expected=0.05, got=%g", critical, df, p)
+		}
+	}
+
+	ms := time.Millisecond
+	a := Summarize([]time.Duration{1 * ms, 2 * ms, 3 * ms, 4 * ms, 5 * ms})
+	b := Summarize([]time.Duration{3 * ms, 4 * ms, 5 * ms, 6 * ms, 7 * ms})
+	// t = -2 with 8 degrees of freedom
+	if p := WelchTest(a, 5, b, 5); math.Abs(p-0.0805) > 0.0005 {
+		t.Errorf("WelchTest wrong. expected=0.0805, got=%g", p)
+	}
+	if p := WelchTest(a, 5, a, 5); p != 1 {
+		t.Errorf("WelchTest of the same samples wrong. expected=1, got=%g", p)
+	}
+	if p := WelchTest(a, 1, b, 5); p != 1 {
+		t.Errorf("WelchTest of a single run wrong. expected=1, got=%g", p)
+	}
+}
+
+const results = `{"program":"fib","iterations":10,"wall":{"mean_ns":1000000,"stddev_ns":10000,"ci95_low_ns":992846,"ci95_high_ns":1007154},"allocs":100}
+{"program":"hash","iterations":10,"wall":{"mean_ns":50000,"stddev_ns":5000},"allocs":10}
+`
+
+func TestCompare(t *testing.T) {
+	before, err := Read(strings.NewReader(results))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	after, err := Read(strings.NewReader(`{"program":"fib","iterations":10,"outliers":1,"wall":{"mean_ns":900000,"stddev_ns":10000},"allocs":90}
+{"program":"hash","iterations":10,"wall":{"mean_ns":50100,"stddev_ns":5000},"allocs":10}
+{"program":"new","iterations":10,"wall":{"mean_ns":1000},"allocs":1}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	deltas := Compare(before, after)
+	if len(deltas) != 3 {
+		t.Fatalf("wrong number of deltas. got=%d", len(deltas))
+	}
+	fib, hash, added := deltas[0], deltas[1], deltas[2]
+	if fib.Program != "fib" || !fib.Significant() || math.Abs(fib.Time()+0.1) > 1e-9 || math.Abs(fib.Allocs()+0.1) > 1e-9 {
+		t.Errorf("delta of fib wrong. got=%+v", fib)
+	}
+	if hash.Program != "hash" || hash.Significant() {
+		t.Errorf("delta of hash wrong, the change is noise. got=%+v", hash)
+	}
+	if added.Program != "new" || added.Old != nil || added.New == nil || added.Significant() {
+		t.Errorf("delta of a new program wrong. got=%+v", added)
+	}
+
+	var out strings.Builder
+	if err := WriteDiff(&out, deltas); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The columns are aligned with spaces, which are left out of the comparison
+	lines := strings.Split(out.String(), "\n")
+	for i, expected := range []string{
+		"Program Old time New time Delta P Old allocs New allocs Delta",
+		"fib 1ms ± 7.154µs 900µs ± 0s -10.00% 0.000 100 90 -10.00%",
+	} {
+		if got := strings.Join(strings.Fields(lines[i]), " "); got != expected {
+			t.Errorf("line %d of WriteDiff wrong.\nexpected=%q\ngot=     %q", i, expected, got)
+		}
+	}
+	if !strings.Contains(out.String(), "~") {
+		t.Errorf("WriteDiff does not mark the change of hash as noise. got=\n%s", out.String())
+	}
+
+	if _, err := Read(strings.NewReader(`{"iterations":1}`)); err == nil {
+		t.Errorf("expected an error for a result without a program")
+	}
+	if _, err := Read(strings.NewReader(`{"program":`)); err == nil {
+		t.Errorf("expected an error for invalid JSON")
+	}
+}
@@ -0,0 +1,175 @@
+package bench
+
+import (
+	"math"
+	"slices"
+	"time"
+)
+
+// Summary describes the durations of a stage over the measured runs.
+type Summary struct {
+	Mean   time.Duration `json:"mean_ns"`
+	Median time.Duration `json:"median_ns"`
+	StdDev time.Duration `json:"stddev_ns"`
+	Min    time.Duration `json:"min_ns"`
+	Max    time.Duration `json:"max_ns"`
+	Low    time.Duration `json:"ci95_low_ns"`  // The lower bound of the 95% confidence interval of the mean
+	High   time.Duration `json:"ci95_high_ns"` // The upper bound of the 95% confidence interval of the mean
+}
+
+// Summarize returns the summary of durations, which must not be empty.
+func Summarize(durations []time.Duration) Summary {
+	sorted := slices.Sorted(slices.Values(durations))
+	n := len(sorted)
+
+	var total float64
+	for _, d := range sorted {
+		total += float64(d)
+	}
+	mean := total / float64(n)
+	var squares float64
+	for _, d := range sorted {
+		squares += (float64(d) - mean) * (float64(d) - mean)
+	}
+	stddev := 0.0
+	if n > 1 {
+		stddev = math.Sqrt(squares / float64(n-1))
+	}
+	margin := tCritical(n-1) * stddev / math.Sqrt(float64(n))
+
+	return Summary{
+		Mean:   time.Duration(mean),
+		Median: median(sorted),
+		StdDev: time.Duration(stddev),
+		Min:    sorted[0],
+		Max:    sorted[n-1],
+		Low:    time.Duration(mean - margin),
+		High:   time.Duration(mean + margin),
+	}
+}
+
+// median returns the median of sorted, which must not be empty.
+func median(sorted []time.Duration) time.Duration {
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// Inliers returns the indices of the durations within Tukey's fences: those
+// no further than 1.5 interquartile ranges below the first quartile or above
+// the third. Runs outside them were most likely disturbed, e.g. by the
+// scheduler or a garbage collection, and would skew the summaries. Fewer than
+// four durations are all kept, as their quartiles say little.
+func Inliers(durations []time.Duration) []int {
+	kept := make([]int, 0, len(durations))
+	if len(durations) < 4 {
+		for i := range durations {
+			kept = append(kept, i)
+		}
+		return kept
+	}
+
+	sorted := slices.Sorted(slices.Values(durations))
+	half := len(sorted) / 2
+	q1, q3 := median(sorted[:half]), median(sorted[len(sorted)-half:])
+	iqr := q3 - q1
+	low, high := q1-iqr*3/2, q3+iqr*3/2
+	for i, d := range durations {
+		if d >= low && d <= high {
+			kept = append(kept, i)
+		}
+	}
+	return kept
+}
+
+// tTable holds the two-sided 95% critical values of Student's t-distribution
+// for 1 to 30 degrees of freedom.
+var tTable = [...]float64{
+	12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228,
+	2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086,
+	2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042,
+}
+
+// tCritical returns the critical value for a 95% confidence interval with df
+// degrees of freedom. Beyond the table, the normal approximation is close enough.
+func tCritical(df int) float64 {
+	switch {
+	case df < 1:
+		return 0 // A single run has no spread to estimate
+	case df <= len(tTable):
+		return tTable[df-1]
+	default:
+		return 1.960
+	}
+}
+
+// WelchTest returns the two-sided p-value of Welch's t-test of whether the
+// means of two samples differ, given their summaries and sizes: the chance of
+// seeing means at least this far apart if the true means were the same.
+// Samples of fewer than two durations, which have no spread, give 1.
+func WelchTest(a Summary, n int, b Summary, m int) float64 {
+	if n < 2 || m < 2 {
+		return 1
+	}
+	va := float64(a.StdDev) * float64(a.StdDev) / float64(n)
+	vb := float64(b.StdDev) * float64(b.StdDev) / float64(m)
+	if va+vb == 0 {
+		if a.Mean == b.Mean {
+			return 1
+		}
+		return 0 // Durations without noise differ for sure
+	}
+	t := (float64(a.Mean) - float64(b.Mean)) / math.Sqrt(va+vb)
+	df := (va + vb) * (va + vb) / (va*va/float64(n-1) + vb*vb/float64(m-1))
+	return incompleteBeta(df/2, 0.5, df/(df+t*t))
+}
+
+// incompleteBeta returns the regularized incomplete beta function I_x(a, b),
+// evaluated with its continued fraction.
+func incompleteBeta(a, b, x float64) float64 {
+	switch {
+	case x <= 0:
+		return 0
+	case x >= 1:
+		return 1
+	case x > (a+1)/(a+b+2):
+		// The continued fraction converges quickly only below this point
+		return 1 - incompleteBeta(b, a, 1-x)
+	}
+	la, _ := math.Lgamma(a)
+	lb, _ := math.Lgamma(b)
+	lab, _ := math.Lgamma(a + b)
+	front := math.Exp(lab-la-lb+a*math.Log(x)+b*math.Log(1-x)) / a
+
+	// Lentz's method
+	const tiny, epsilon = 1e-300, 1e-14
+	f, c, d := 1.0, 1.0, 0.0
+	for i := range 200 {
+		m := float64(i / 2)
+		var numerator float64
+		switch {
+		case i == 0:
+			numerator = 1
+		case i%2 == 0:
+			numerator = m * (b - m) * x / ((a + 2*m - 1) * (a + 2*m))
+		default:
+			numerator = -(a + m) * (a + b + m) * x / ((a + 2*m) * (a + 2*m + 1))
+		}
+		d = 1 + numerator*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		d = 1 / d
+		c = 1 + numerator/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		f *= c * d
+		if math.Abs(1-c*d) < epsilon {
+			break
+		}
+	}
+	return front * (f - 1)
+}
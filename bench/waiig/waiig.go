@@ -0,0 +1,66 @@
+// Package waiig vendors the interpreter of Thorsten Ball's book "Writing an
+// Interpreter in Go", which Monke started from, as it stands at the end of the
+// book. The profiler measures it with -reference, so that the costs of Monke
+// can be compared with those of the original on the same programs.
+//
+// The subpackages are the book's code, with the import paths rewritten and
+// one format string made constant for go vet; see README.md. They are kept as
+// they are rather than improved, since they are the baseline.
+//
+// Key components:
+//   - Parse: Lexes and parses a program
+//   - Eval: Evaluates a parsed program in a new environment
+//   - Programs: Lists the programs both interpreters run
+package waiig
+
+import (
+	"embed"
+	"path"
+	"strings"
+
+	"github.com/dr8co/monke/bench/waiig/ast"
+	"github.com/dr8co/monke/bench/waiig/evaluator"
+	"github.com/dr8co/monke/bench/waiig/lexer"
+	"github.com/dr8co/monke/bench/waiig/object"
+	"github.com/dr8co/monke/bench/waiig/parser"
+	"github.com/dr8co/monke/examples"
+)
+
+//go:embed programs/*.monkey
+var programs embed.FS
+
+// Parse lexes and parses source, returning the program with the syntax errors
+// found in it. The book's language lacks most of Monke's additions, such as
+// comments, loops and "else if", so many Monke programs do not parse.
+func Parse(source string) (*ast.Program, []string) {
+	p := parser.New(lexer.New(source))
+	program := p.ParseProgram()
+	return program, p.Errors()
+}
+
+// Eval evaluates program in a new environment and returns its value, which
+// is nil for programs that end with a let statement.
+func Eval(program *ast.Program) object.Object {
+	return evaluator.Eval(program, object.NewEnvironment())
+}
+
+// Programs returns the baseline programs, sorted by name. They are written in
+// the book's language, which Monke also runs, and each one ends with an
+// expression whose value is its result, like the samples of the examples
+// package.
+func Programs() []examples.Example {
+	entries, err := programs.ReadDir("programs")
+	if err != nil {
+		panic(err) // the directory is embedded, so it always exists
+	}
+	all := make([]examples.Example, 0, len(entries))
+	for _, entry := range entries {
+		src, err := programs.ReadFile("programs/" + entry.Name())
+		if err != nil {
+			panic(err)
+		}
+		name := strings.TrimSuffix(entry.Name(), path.Ext(entry.Name()))
+		all = append(all, examples.Example{Name: name, Source: string(src)})
+	}
+	return all
+}
@@ -0,0 +1,32 @@
+package waiig
+
+import (
+	"testing"
+
+	"github.com/dr8co/monke/pipeline"
+)
+
+// TestPrograms checks that both interpreters run every baseline program to
+// the same result, so comparing their costs compares the same work.
+func TestPrograms(t *testing.T) {
+	programs := Programs()
+	if len(programs) == 0 {
+		t.Fatal("no baseline programs")
+	}
+	for _, p := range programs {
+		program, errs := Parse(p.Source)
+		if len(errs) != 0 {
+			t.Errorf("%s: parser errors: %v", p.Name, errs)
+			continue
+		}
+		want := Eval(program)
+		got := pipeline.Run(p.Source, nil)
+		if got.Failed() || got.Value == nil || want == nil {
+			t.Errorf("%s: no result. monke=%v, reference=%v", p.Name, got.Value, want)
+			continue
+		}
+		if got.Value.Inspect() != want.Inspect() {
+			t.Errorf("%s: results differ. monke=%s, reference=%s", p.Name, got.Value.Inspect(), want.Inspect())
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dr8co/monke/parser"
+	"github.com/dr8co/monke/pipeline"
+	"github.com/dr8co/monke/typecheck"
+)
+
+// runCheck implements "monke check", which reports the problems in scripts without running them.
+func runCheck(args []string) int {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	types := fs.Bool("types", false, "Also check the values against the type annotations")
+	noColor := fs.Bool("no-color", false, "Disable colored output")
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "Usage: %s check [flags] script...\n\n", filepath.Base(os.Args[0]))
+		fmt.Fprintln(out, "Reports syntax errors and warnings in the scripts without running them.")
+		fmt.Fprintln(out, "\nFlags:")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	// The scripts are parsed all at once, in parallel, and reported in the
+	// order they were given
+	filenames := fs.Args()
+	sources := make([]string, len(filenames))
+	readErrs := make([]error, len(filenames))
+	for i, filename := range filenames {
+		//nolint:gosec // The path is supplied by the user on purpose
+		content, err := os.ReadFile(filename)
+		sources[i], readErrs[i] = string(content), err
+	}
+
+	status := 0
+	for i, file := range parser.ParseAll(sources) {
+		if readErrs[i] != nil {
+			fmt.Fprintf(os.Stderr, "Error reading file: %s\n", readErrs[i])
+			status = 1
+			continue
+		}
+		if !checkFile(filenames[i], sources[i], file, *types, !colorStderr(*noColor)) {
+			status = 1
+		}
+	}
+	return status
+}
+
+// checkFile reports the problems found in the named script, parsed from
+// source into file, on stderr. It returns false if the script has errors.
+func checkFile(filename, source string, file parser.File, types, noColor bool) bool {
+	if len(file.Errors) != 0 {
+		fmt.Fprint(os.Stderr, formatParseErrors(source, file.Errors, noColor))
+		return false
+	}
+
+	warnf := stderrWarnings(filename)
+	for _, w := range append(file.Warnings, pipeline.ShadowWarnings(file.Program, source)...) {
+		warnf(w.Pos, w.Message)
+	}
+	if !types {
+		return true
+	}
+
+	errs := typecheck.Check(file.Program)
+	for _, err := range errs {
+		fmt.Fprintf(os.Stderr, "%s:%s: %s\n", filename, err.Pos, err.Message)
+	}
+	return len(errs) == 0
+}
@@ -0,0 +1,97 @@
+// Command conformance runs a corpus of Monkey programs and compares their
+// output with the expected output stored next to each one.
+//
+// Every "name.monkey" file in the corpus directories is a test case, and
+// "name.out" holds what it must print: the lines written by puts, the runtime
+// warnings, and then the value of the program unless it is null. Parse and
+// runtime errors are part of the output too, so the corpus pins down error
+// messages as well as results. Cases run against every engine selected with
+// -engine, so that new execution engines can be held to the same semantics.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+var (
+	engineFlag = flag.String("engine", "all", "engine to run the cases with, one of "+strings.Join(engineNames(), ", ")+" or \"all\"")
+	runFlag    = flag.String("run", "", "only run the cases whose name contains this string")
+	updateFlag = flag.Bool("update", false, "write the actual output to the .out files instead of comparing")
+)
+
+func main() {
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+		fmt.Fprintf(out, "Usage: conformance [flags] [dir...]\n\n")
+		fmt.Fprintf(out, "Runs the .monkey programs in each directory (default %s) and compares\n", defaultDir)
+		fmt.Fprintln(out, "their output with the matching .out files.")
+		fmt.Fprintln(out, "\nFlags:")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	selected := engines
+	if *engineFlag != "all" {
+		i := slices.IndexFunc(engines, func(e engine) bool { return e.name == *engineFlag })
+		if i < 0 {
+			fmt.Fprintf(os.Stderr, "Error: unknown engine %q, want one of %s or \"all\"\n", *engineFlag, strings.Join(engineNames(), ", "))
+			os.Exit(2)
+		}
+		selected = engines[i : i+1]
+	}
+
+	dirs := flag.Args()
+	if len(dirs) == 0 {
+		dirs = []string{defaultDir}
+	}
+	var cases []testCase
+	for _, dir := range dirs {
+		found, err := loadCases(dir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			os.Exit(2)
+		}
+		for _, c := range found {
+			if strings.Contains(c.name, *runFlag) {
+				cases = append(cases, c)
+			}
+		}
+	}
+
+	if *updateFlag {
+		for _, c := range cases {
+			if err := os.WriteFile(c.outFile(), []byte(selected[0].run(c.source)), 0o600); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+				os.Exit(1)
+			}
+		}
+		fmt.Printf("updated %d cases with %s\n", len(cases), selected[0].name)
+		return
+	}
+
+	failed := 0
+	for _, e := range selected {
+		for _, c := range cases {
+			result := check(e, c)
+			if result == "" {
+				continue
+			}
+			failed++
+			fmt.Printf("--- FAIL: %s (%s)\n%s", c.name, e.name, result)
+		}
+	}
+	total := len(cases) * len(selected)
+	if failed > 0 {
+		fmt.Printf("FAIL: %d of %d cases failed\n", failed, total)
+		os.Exit(1)
+	}
+	fmt.Printf("ok: %d cases passed\n", total)
+}
+
+// defaultDir is the corpus run when no directory is given, relative to the repository root.
+var defaultDir = filepath.Join("cmd", "conformance", "testdata")
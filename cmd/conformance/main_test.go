@@ -0,0 +1,38 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCorpus(t *testing.T) {
+	cases, err := loadCases("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range engines {
+		for _, c := range cases {
+			if result := check(e, c); result != "" {
+				t.Errorf("%s (%s):\n%s", c.name, e.name, result)
+			}
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		want, got []string
+		expected  []string
+	}{
+		{[]string{"a", "b"}, []string{"a", "b"}, []string{"  a", "  b"}},
+		{[]string{"a", "b", "c"}, []string{"a", "x", "c"}, []string{"  a", "- b", "+ x", "  c"}},
+		{[]string{"a"}, []string{"a", "b"}, []string{"  a", "+ b"}},
+		{[]string{"a", "b"}, nil, []string{"- a", "- b"}},
+	}
+
+	for _, tt := range tests {
+		if got := diff(tt.want, tt.got); !slices.Equal(got, tt.expected) {
+			t.Errorf("diff(%q, %q): expected=%q, got=%q", tt.want, tt.got, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dr8co/monke/evaluator"
+	"github.com/dr8co/monke/modules"
+	"github.com/dr8co/monke/object"
+	"github.com/dr8co/monke/pipeline"
+	"github.com/dr8co/monke/token"
+)
+
+// engine runs Monkey programs.
+type engine struct {
+	name string
+	run  func(source string) string // returns the output of the program
+}
+
+// engines lists the engines the corpus can be run against.
+var engines = []engine{
+	{"eval", runEvaluator},
+}
+
+// engineNames returns the names of the engines.
+func engineNames() []string {
+	names := make([]string, len(engines))
+	for i, e := range engines {
+		names[i] = e.name
+	}
+	return names
+}
+
+// runEvaluator runs source with the tree-walking evaluator in a fresh environment,
+// where only the modules of the standard library can be imported.
+func runEvaluator(source string) string {
+	var out strings.Builder
+	evaluator.SetOutput(&out)
+	defer evaluator.SetOutput(os.Stdout)
+	evaluator.SetWarningHandler(func(pos token.Position, message string) {
+		fmt.Fprintf(&out, "warning: %s: %s\n", pos, message)
+	})
+	defer evaluator.SetWarningHandler(nil)
+	evaluator.SetModuleResolver(modules.Stdlib)
+	defer evaluator.SetModuleResolver(nil)
+
+	// The parser's warnings come first, before any output of the program
+	warnings := pipeline.Middleware{Program: func(result *pipeline.Result) bool {
+		for _, w := range result.Warnings {
+			fmt.Fprintf(&out, "warning: %s: %s\n", w.Pos, w.Message)
+		}
+		return true
+	}}
+	result := pipeline.Run(source, nil, warnings)
+	for _, err := range result.Errors {
+		fmt.Fprintf(&out, "parse error: %s: %s\n", err.Pos, err.Message)
+	}
+	if result.Value != nil && result.Value.Type() != object.NULL_OBJ {
+		out.WriteString(result.Value.Inspect())
+		out.WriteString("\n")
+	}
+	return out.String()
+}
+
+// testCase is a program of the corpus with its expected output.
+type testCase struct {
+	name     string // The path of the program without its extension
+	source   string
+	expected string
+	missing  bool // There is no .out file
+}
+
+// outFile returns the path of the file holding the expected output.
+func (c testCase) outFile() string { return c.name + ".out" }
+
+// loadCases reads the programs in dir, sorted by name.
+func loadCases(dir string) ([]testCase, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.monkey"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no .monkey files in %s", dir)
+	}
+	cases := make([]testCase, 0, len(files))
+	for _, file := range files {
+		//nolint:gosec // The corpus is chosen by the user on purpose
+		source, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		c := testCase{name: strings.TrimSuffix(file, ".monkey"), source: string(source)}
+		expected, err := os.ReadFile(c.outFile())
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			c.missing = true
+		case err != nil:
+			return nil, err
+		}
+		c.expected = string(expected)
+		cases = append(cases, c)
+	}
+	return cases, nil
+}
+
+// check runs c with e and describes how its output differs from the expected
+// one, or returns "" if it matches.
+func check(e engine, c testCase) string {
+	if c.missing {
+		return fmt.Sprintf("    missing %s (run with -update to create it)\n", c.outFile())
+	}
+	actual := e.run(c.source)
+	if actual == c.expected {
+		return ""
+	}
+	var s strings.Builder
+	for _, line := range diff(lines(c.expected), lines(actual)) {
+		s.WriteString("    ")
+		s.WriteString(line)
+		s.WriteString("\n")
+	}
+	return s.String()
+}
+
+// lines splits text into lines, without the final newline.
+func lines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diff returns a line diff turning want into got: common lines are prefixed
+// with "  ", removed lines with "- " and added lines with "+ ".
+func diff(want, got []string) []string {
+	// lcs[i][j] is the length of the longest common subsequence of want[i:] and got[j:]
+	lcs := make([][]int, len(want)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(got)+1)
+	}
+	for i := len(want) - 1; i >= 0; i-- {
+		for j := len(got) - 1; j >= 0; j-- {
+			if want[i] == got[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out []string
+	i, j := 0, 0
+	for i < len(want) || j < len(got) {
+		switch {
+		case i < len(want) && j < len(got) && want[i] == got[j]:
+			out = append(out, "  "+want[i])
+			i++
+			j++
+		case i < len(want) && (j == len(got) || lcs[i+1][j] >= lcs[i][j+1]):
+			out = append(out, "- "+want[i])
+			i++
+		default:
+			out = append(out, "+ "+got[j])
+			j++
+		}
+	}
+	return out
+}
@@ -1,4 +1,7 @@
-// Command profile runs a Monkey program and measures its execution time.
+// Command profile runs a Monkey program repeatedly and measures its parse and
+// evaluation times, allocations, garbage collections, peak heap and evaluation
+// steps. Warm-up runs come first, and runs disturbed enough to be outliers are
+// left out of the timings.
 package main
 
 import (
@@ -8,136 +11,26 @@ import (
 	"runtime"
 	"runtime/pprof"
 	"runtime/trace"
-	"time"
+	"slices"
+	"strings"
 
-	"github.com/dr8co/monke/evaluator"
-	"github.com/dr8co/monke/lexer"
+	"github.com/dr8co/monke/bench/waiig"
+	"github.com/dr8co/monke/examples"
 	"github.com/dr8co/monke/object"
-	"github.com/dr8co/monke/parser"
 )
 
 var (
 	cpuprofile   = flag.String("cpuprofile", "", "write cpu profile to file")
 	memprofile   = flag.String("memprofile", "", "write memory profile to file")
 	traceprofile = flag.String("trace", "", "write execution trace to file")
-	program      = flag.String("program", "fibonacci", "program to profile (fibonacci, factorial, array, hash, complex)")
+	program      = flag.String("program", "fibonacci", "program to profile, one of the samples listed by \"monke examples\" or, with -baseline, of the baseline programs, or \"all\"")
+	jsonOutput   = flag.Bool("json", false, "print the measurements as JSON")
+	warmup       = flag.Int("warmup", 1, "number of runs before the measured ones, to warm up caches and the heap")
+	iterations   = flag.Int("iterations", 10, "number of measured runs")
+	refImpl      = flag.Bool("reference", false, "measure the interpreter of the book Monke started from instead, skipping the programs it cannot parse")
+	baseline     = flag.Bool("baseline", false, "measure the programs written in the book's language, which both interpreters run, instead of the samples")
 )
 
-// Sample Monkey programs for profiling
-var programs = map[string]string{
-	"fibonacci": `
-		let fibonacci = fn(x) {
-			if (x == 0) {
-				return 0;
-			} else {
-				if (x == 1) {
-					return 1;
-				} else {
-					return fibonacci(x - 1) + fibonacci(x - 2);
-				}
-			}
-		};
-		fibonacci(20);
-	`,
-	"factorial": `
-		let factorial = fn(n) {
-			if (n == 0) {
-				return 1;
-			} else {
-				return n * factorial(n - 1);
-			}
-		};
-		factorial(10);
-	`,
-	"array": `
-		let map = fn(arr, f) {
-			let iter = fn(arr, accumulated) {
-				if (len(arr) == 0) {
-					return accumulated;
-				} else {
-					return iter(rest(arr), push(accumulated, f(first(arr))));
-				}
-			};
-			return iter(arr, []);
-		};
-
-		let reduce = fn(arr, initial, f) {
-			let iter = fn(arr, result) {
-				if (len(arr) == 0) {
-					return result;
-				} else {
-					return iter(rest(arr), f(result, first(arr)));
-				}
-			};
-			return iter(arr, initial);
-		};
-
-		let arr = [1, 2, 3, 4, 5, 6, 7, 8, 9, 10];
-		let double = fn(x) { return x * 2; };
-		let sum = fn(x, y) { return x + y; };
-
-		let doubled = map(arr, double);
-		reduce(doubled, 0, sum);
-	`,
-	"hash": `
-		let hash = {"one": 1, "two": 2, "three": 3, "four": 4, "five": 5};
-		let sum = 0;
-
-		let keys = ["one", "two", "three", "four", "five"];
-		let i = 0;
-
-		while (i < len(keys)) {
-			let key = keys[i];
-			sum = sum + hash[key];
-			i = i + 1;
-		}
-
-		sum;
-	`,
-	"complex": `
-		let fibonacci = fn(x) {
-			if (x == 0) {
-				return 0;
-			} else {
-				if (x == 1) {
-					return 1;
-				} else {
-					return fibonacci(x - 1) + fibonacci(x - 2);
-				}
-			}
-		};
-
-		let map = fn(arr, f) {
-			let iter = fn(arr, accumulated) {
-				if (len(arr) == 0) {
-					return accumulated;
-				} else {
-					return iter(rest(arr), push(accumulated, f(first(arr))));
-				}
-			};
-			return iter(arr, []);
-		};
-
-		let reduce = fn(arr, initial, f) {
-			let iter = fn(arr, result) {
-				if (len(arr) == 0) {
-					return result;
-				} else {
-					return iter(rest(arr), f(result, first(arr)));
-				}
-			};
-			return iter(arr, initial);
-		};
-
-		let numbers = [1, 2, 3, 4, 5, 6, 7, 8, 9, 10];
-		let compute = fn(x) { return fibonacci(x); };
-		let sum = fn(x, y) { return x + y; };
-
-		let results = map(numbers, compute);
-		reduce(results, 0, sum);
-	`,
-}
-
 // builtins is a map of built-in functions that are available to the Monkey program
 var builtins = map[string]*object.Builtin{
 	"len": {
@@ -147,7 +40,7 @@ var builtins = map[string]*object.Builtin{
 			}
 			switch arg := args[0].(type) {
 			case *object.String:
-				return &object.Integer{Value: int64(len(arg.Value))}
+				return &object.Integer{Value: int64(arg.Len())}
 			case *object.Array:
 				return &object.Integer{Value: int64(len(arg.Elements))}
 			default:
@@ -292,57 +185,86 @@ func main() {
 		defer trace.Stop()
 	}
 
-	// Get the program to profile
-	input, ok := programs[*program]
-	if !ok {
-		_, err := fmt.Fprintf(os.Stderr, "unknown program: %s\n", *program)
-		if err != nil {
-			return
+	// Get the programs to profile
+	programs := examples.All()
+	if *baseline {
+		programs = waiig.Programs()
+	}
+	if *program != "all" {
+		i := slices.IndexFunc(programs, func(ex examples.Example) bool { return ex.Name == *program })
+		if i < 0 {
+			_, err := fmt.Fprintf(os.Stderr, "unknown program: %s\n", *program)
+			if err != nil {
+				return
+			}
+			names := make([]string, len(programs))
+			for i, ex := range programs {
+				names[i] = ex.Name
+			}
+			_, err = fmt.Fprintf(os.Stderr, "available programs: %s\n", strings.Join(names, ", "))
+			if err != nil {
+				return
+			}
+			exit(1)
 		}
-		_, err = fmt.Fprintf(os.Stderr, "available programs: fibonacci, factorial, array, hash, complex\n")
+		programs = programs[i : i+1]
+	}
+
+	if *iterations < 1 || *warmup < 0 {
+		_, err := fmt.Fprintf(os.Stderr, "-iterations must be at least 1 and -warmup at least 0\n")
 		if err != nil {
 			return
 		}
 		exit(1)
 	}
 
-	// Create an environment with built-in functions
-	env := object.NewEnvironment()
-	for name, builtin := range builtins {
-		env.Set(name, builtin)
+	// Create an environment with built-in functions for each run
+	newEnv := func() *object.Environment {
+		env := object.NewEnvironment()
+		for name, builtin := range builtins {
+			env.Set(name, builtin)
+		}
+		return env
+	}
+	run := monke(newEnv)
+	if *refImpl {
+		run = reference
 	}
 
-	// Run the program and measure the time
-	start := time.Now()
-
-	// Lexing
-	l := lexer.New(input)
-
-	// Parsing
-	p := parser.New(l)
-	program := p.ParseProgram()
-	if len(p.Errors()) != 0 {
-		_, err := fmt.Fprintf(os.Stderr, "parser errors:\n")
-		if err != nil {
-			return
+	// Lexing, parsing and evaluation, measured
+	measured := 0
+	for _, example := range programs {
+		last, m := measure(example.Name, example.Source, run, *warmup, *iterations)
+		if len(last.errors) != 0 && *refImpl {
+			// The book's language lacks most of Monke's syntax
+			_, err := fmt.Fprintf(os.Stderr, "%s: skipped, the reference cannot parse it: %s\n", example.Name, last.errors[0])
+			if err != nil {
+				return
+			}
+			continue
 		}
-		for _, msg := range p.Errors() {
-			_, err := fmt.Fprintf(os.Stderr, "\t%s\n", msg)
+		if len(last.errors) != 0 {
+			_, err := fmt.Fprintf(os.Stderr, "%s: parser errors:\n", example.Name)
 			if err != nil {
 				return
 			}
+			for _, e := range last.errors {
+				_, err := fmt.Fprintf(os.Stderr, "\t%s\n", e)
+				if err != nil {
+					return
+				}
+			}
+			exit(1)
+		}
+		if measured > 0 && !*jsonOutput {
+			fmt.Println()
+		}
+		measured++
+		if err := write(os.Stdout, m, *jsonOutput); err != nil {
+			return
 		}
-		exit(1)
 	}
 
-	// Evaluation
-	result := evaluator.Eval(program, env)
-
-	elapsed := time.Since(start)
-	fmt.Printf("Program: %s\n", *program)
-	fmt.Printf("Result: %s\n", result.Inspect())
-	fmt.Printf("Time: %s\n", elapsed)
-
 	// Write the memory profile if requested
 	if *memprofile != "" {
 		f, err := os.Create(*memprofile)
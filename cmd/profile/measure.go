@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/metrics"
+	"sync"
+	"time"
+
+	"github.com/dr8co/monke/bench"
+	"github.com/dr8co/monke/bench/waiig"
+	"github.com/dr8co/monke/evaluator"
+	"github.com/dr8co/monke/object"
+	"github.com/dr8co/monke/pipeline"
+)
+
+// heapMetric is the runtime metric sampled for the peak heap: the bytes of
+// the heap occupied by objects, live or not yet collected.
+const heapMetric = "/memory/classes/heap/objects:bytes"
+
+// heapSampleInterval is how often the heap is sampled during a run.
+const heapSampleInterval = time.Millisecond
+
+// timing is how long the stages of a run took.
+type timing struct {
+	parse, eval time.Duration
+}
+
+// outcome is what a run of a program gave.
+type outcome struct {
+	errors []string // The syntax errors; the program is not evaluated if there are any
+	result string   // The value of the program, as printed, or "" if it has none
+	steps  int      // The evaluation steps, if the interpreter counts them
+}
+
+// runner runs source once in a new environment, timing lexing and parsing
+// apart from evaluation.
+type runner func(source string) (outcome, timing)
+
+// measure runs source warmup times, then iterations times measuring each
+// run, every time with run. It returns the outcome of the last run with what
+// the measured runs cost. A program with syntax errors is run only once, and
+// its outcome returned without measurements.
+func measure(name, source string, run runner, warmup, iterations int) (outcome, bench.Result) {
+	m := bench.Result{Program: name, Warmup: warmup, Iterations: iterations}
+	var last outcome
+	for range warmup {
+		if last, _ = run(source); len(last.errors) != 0 {
+			return last, m
+		}
+	}
+
+	var before, after runtime.MemStats
+	runtime.GC() // Start from a heap holding nothing of earlier runs
+	runtime.ReadMemStats(&before)
+	sampler := startHeapSampler()
+
+	timings := make([]timing, 0, iterations)
+	for range iterations {
+		var t timing
+		last, t = run(source)
+		if len(last.errors) != 0 {
+			sampler.stop()
+			return last, m
+		}
+		timings = append(timings, t)
+	}
+
+	m.PeakHeap = sampler.stop()
+	runtime.ReadMemStats(&after)
+	m.Steps = last.steps
+	m.Allocs = (after.Mallocs - before.Mallocs) / uint64(iterations)
+	m.AllocBytes = (after.TotalAlloc - before.TotalAlloc) / uint64(iterations)
+	m.GCCycles = after.NumGC - before.NumGC
+	m.Result = last.result
+
+	walls := make([]time.Duration, len(timings))
+	for i, t := range timings {
+		walls[i] = t.parse + t.eval
+	}
+	kept := bench.Inliers(walls)
+	m.Outliers = len(timings) - len(kept)
+	var parse, eval, wall []time.Duration
+	for _, i := range kept {
+		parse = append(parse, timings[i].parse)
+		eval = append(eval, timings[i].eval)
+		wall = append(wall, walls[i])
+	}
+	m.Parse, m.Eval, m.Wall = bench.Summarize(parse), bench.Summarize(eval), bench.Summarize(wall)
+	return last, m
+}
+
+// monke returns a runner of Monke, which runs programs in environments
+// from newEnv.
+func monke(newEnv func() *object.Environment) runner {
+	return func(source string) (outcome, timing) {
+		evaluator.SetStepLimit(0)
+		var parse time.Duration
+		start := time.Now()
+		run := pipeline.Run(source, newEnv(), pipeline.Middleware{
+			Program: func(*pipeline.Result) bool {
+				parse = time.Since(start)
+				return true
+			},
+		})
+		if len(run.Errors) != 0 {
+			parse = time.Since(start) // Not evaluated
+		}
+
+		// The evaluator measures the evaluation itself, as the REPL shows it
+		o := outcome{steps: run.Eval.Steps}
+		for _, err := range run.Errors {
+			o.errors = append(o.errors, err.Message)
+		}
+		if run.Value != nil {
+			o.result = run.Value.Inspect()
+		}
+		return o, timing{parse: parse, eval: run.Eval.Duration}
+	}
+}
+
+// reference runs programs with the interpreter of the book Monke started
+// from, vendored in bench/waiig, which does not count its steps.
+func reference(source string) (outcome, timing) {
+	start := time.Now()
+	program, errs := waiig.Parse(source)
+	parsed := time.Now()
+	if len(errs) != 0 {
+		return outcome{errors: errs}, timing{parse: parsed.Sub(start)}
+	}
+	value := waiig.Eval(program)
+	end := time.Now()
+
+	var o outcome
+	if value != nil {
+		o.result = value.Inspect()
+	}
+	return o, timing{parse: parsed.Sub(start), eval: end.Sub(parsed)}
+}
+
+// write writes m to w, as JSON if asJSON is set.
+func write(w io.Writer, m bench.Result, asJSON bool) error {
+	if asJSON {
+		return json.NewEncoder(w).Encode(m)
+	}
+	_, err := fmt.Fprintf(w, "Program: %s\nResult: %s\nRuns: %d measured (%d outliers rejected), %d warm-up\n",
+		m.Program, m.Result, m.Iterations, m.Outliers, m.Warmup)
+	if err != nil {
+		return err
+	}
+	for _, stage := range []struct {
+		name string
+		s    bench.Summary
+	}{{"Parse", m.Parse}, {"Eval", m.Eval}, {"Time", m.Wall}} {
+		_, err := fmt.Fprintf(w, "%s: %s (95%% CI %s to %s), median %s, stddev %s, min %s, max %s\n",
+			stage.name, stage.s.Mean, stage.s.Low, stage.s.High, stage.s.Median, stage.s.StdDev, stage.s.Min, stage.s.Max)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "Steps: %d\nAllocations: %d per run (%d bytes)\nGC cycles: %d\nPeak heap: %d bytes\n",
+		m.Steps, m.Allocs, m.AllocBytes, m.GCCycles, m.PeakHeap)
+	return err
+}
+
+// heapSampler records the largest heap size seen while a run is in progress.
+type heapSampler struct {
+	done chan struct{}
+	wg   sync.WaitGroup
+	peak uint64
+}
+
+// startHeapSampler starts sampling the heap until stop is called.
+func startHeapSampler() *heapSampler {
+	s := &heapSampler{done: make(chan struct{})}
+	s.sample()
+	s.wg.Go(func() {
+		ticker := time.NewTicker(heapSampleInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.done:
+				return
+			case <-ticker.C:
+				s.sample()
+			}
+		}
+	})
+	return s
+}
+
+// sample reads the size of the heap, keeping the largest.
+func (s *heapSampler) sample() {
+	sample := []metrics.Sample{{Name: heapMetric}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() == metrics.KindUint64 {
+		s.peak = max(s.peak, sample[0].Value.Uint64())
+	}
+}
+
+// stop stops sampling and returns the peak, including a last sample.
+func (s *heapSampler) stop() uint64 {
+	close(s.done)
+	s.wg.Wait()
+	s.sample()
+	return s.peak
+}
@@ -0,0 +1,301 @@
+// Package deps fetches Monke packages into a project for "monke get".
+//
+// A package is a git repository of ".monkey" files, named by its URL without
+// the scheme (e.g. "github.com/user/lib") and versioned by its git tags.
+// Getting "github.com/user/lib@v1" clones the tag and copies the package's
+// ".monkey" files into "vendor/github.com/user/lib" under the project root,
+// keeping their directory layout, so the project builds without network access.
+// The lockfile "monke.lock" next to the vendor directory records the commit and
+// a checksum of every vendored package. Fetching a locked version again fails
+// if it resolves to another commit or other contents, and Verify checks the
+// vendored files against the lockfile.
+//
+// Key components:
+//   - Ref: A package path and version, parsed by ParseRef
+//   - Lock: The contents of the lockfile
+//   - Get: Vendors a package and records it in the lockfile
+//   - Verify: Checks the vendored packages against the lockfile
+//   - Fetcher: Retrieves a package version; GitFetch uses git
+package deps
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/dr8co/monke/modules"
+)
+
+const (
+	// VendorDir is the directory of a project holding its vendored packages.
+	VendorDir = modules.VendorDir
+
+	// LockFile is the name of a project's lockfile.
+	LockFile = "monke.lock"
+)
+
+// ErrMismatch is reported when a package does not match its lockfile entry.
+var ErrMismatch = errors.New("does not match " + LockFile)
+
+// Ref names a version of a package, as in "github.com/user/lib@v1".
+type Ref struct {
+	Path    string
+	Version string
+}
+
+// String returns the reference in "path@version" form.
+func (r Ref) String() string { return r.Path + "@" + r.Version }
+
+// URL returns the URL of the package's git repository.
+func (r Ref) URL() string { return "https://" + r.Path }
+
+// ParseRef parses a "path@version" reference.
+// The path must start with a host name, like "github.com", followed by at least one element.
+func ParseRef(s string) (Ref, error) {
+	p, version, ok := strings.Cut(s, "@")
+	if !ok || version == "" {
+		return Ref{}, fmt.Errorf("missing version in %q: want path@version, e.g. github.com/user/lib@v1", s)
+	}
+	elems := strings.Split(p, "/")
+	if len(elems) < 2 || !strings.Contains(elems[0], ".") {
+		return Ref{}, fmt.Errorf("invalid package path %q: want a host and a repository, e.g. github.com/user/lib", p)
+	}
+	for _, elem := range elems {
+		if elem == "" || elem == "." || elem == ".." || strings.ContainsAny(elem, `\:`) {
+			return Ref{}, fmt.Errorf("invalid package path %q", p)
+		}
+	}
+	if strings.HasPrefix(version, "-") {
+		return Ref{}, fmt.Errorf("invalid version %q", version)
+	}
+	return Ref{Path: p, Version: version}, nil
+}
+
+// Fetcher retrieves the given version of a package into the empty directory dir
+// and returns the commit it resolved to.
+type Fetcher func(ref Ref, dir string) (commit string, err error)
+
+// GitFetch is the Fetcher that clones the version's tag or branch with git.
+func GitFetch(ref Ref, dir string) (string, error) {
+	//nolint:gosec // The reference is validated by ParseRef and passed after "--"
+	clone := exec.Command("git", "clone", "--quiet", "--depth", "1", "--branch", ref.Version, "--", ref.URL(), dir)
+	// Fail instead of asking for credentials when the repository does not exist
+	clone.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if out, err := clone.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("fetching %s: %s", ref, strings.TrimSpace(string(out)))
+	}
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return "", fmt.Errorf("resolving %s: %w", ref, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Package is a vendored package as recorded in the lockfile.
+type Package struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+	Commit  string `json:"commit"`
+	Sum     string `json:"sum"` // Checksum of the vendored files, see Checksum
+}
+
+// Lock is the contents of a lockfile.
+type Lock struct {
+	Packages []Package `json:"packages"`
+}
+
+// LoadLock reads a lockfile. A missing file is an empty lock.
+func LoadLock(file string) (*Lock, error) {
+	lock := &Lock{}
+	//nolint:gosec // The path is the lockfile of the project being modified
+	data, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return lock, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", file, err)
+	}
+	return lock, nil
+}
+
+// Save writes the lock to file, with the packages sorted by path.
+func (l *Lock) Save(file string) error {
+	slices.SortFunc(l.Packages, func(a, b Package) int { return cmp.Compare(a.Path, b.Path) })
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	//nolint:gosec // The lockfile is meant to be committed and shared
+	return os.WriteFile(file, append(data, '\n'), 0o644)
+}
+
+// Find returns the entry of the package at path.
+func (l *Lock) Find(path string) (Package, bool) {
+	for _, p := range l.Packages {
+		if p.Path == path {
+			return p, true
+		}
+	}
+	return Package{}, false
+}
+
+// Set records pkg, replacing any other version of the same package.
+func (l *Lock) Set(pkg Package) {
+	for i, p := range l.Packages {
+		if p.Path == pkg.Path {
+			l.Packages[i] = pkg
+			return
+		}
+	}
+	l.Packages = append(l.Packages, pkg)
+}
+
+// Get fetches ref, vendors its ".monkey" files into the project at root and
+// records it in the project's lockfile. Any previously vendored version is
+// replaced. If the lockfile already has ref's version, the fetched commit and
+// checksum must match it, otherwise Get fails with ErrMismatch and leaves the
+// project unchanged.
+func Get(root string, ref Ref, fetch Fetcher) (Package, error) {
+	lockFile := filepath.Join(root, LockFile)
+	lock, err := LoadLock(lockFile)
+	if err != nil {
+		return Package{}, err
+	}
+
+	tmp, err := os.MkdirTemp("", "monke-get-")
+	if err != nil {
+		return Package{}, err
+	}
+	defer func() { _ = os.RemoveAll(tmp) }()
+
+	src := filepath.Join(tmp, "src")
+	commit, err := fetch(ref, src)
+	if err != nil {
+		return Package{}, err
+	}
+	files, err := sources(src)
+	if err != nil {
+		return Package{}, err
+	}
+	if len(files) == 0 {
+		return Package{}, fmt.Errorf("%s has no .monkey files", ref)
+	}
+	// The checksum only covers the files that are vendored, so it is the same
+	// for the fetched directory and the vendored copy.
+	sum, err := Checksum(src)
+	if err != nil {
+		return Package{}, err
+	}
+	pkg := Package{Path: ref.Path, Version: ref.Version, Commit: commit, Sum: sum}
+	if locked, ok := lock.Find(ref.Path); ok && locked.Version == ref.Version && locked != pkg {
+		return Package{}, fmt.Errorf("%s %w: locked commit %.12s with %s, fetched commit %.12s with %s",
+			ref, ErrMismatch, locked.Commit, locked.Sum, commit, sum)
+	}
+
+	dest := filepath.Join(root, VendorDir, filepath.FromSlash(ref.Path))
+	if err := os.RemoveAll(dest); err != nil {
+		return Package{}, err
+	}
+	for _, name := range files {
+		if err := copyFile(filepath.Join(src, name), filepath.Join(dest, name)); err != nil {
+			return Package{}, err
+		}
+	}
+	lock.Set(pkg)
+	return pkg, lock.Save(lockFile)
+}
+
+// Verify checks that the vendored files of every package in the lockfile of the
+// project at root have the recorded checksum. The error lists each package that
+// is missing or was modified; those that do not match wrap ErrMismatch.
+func Verify(root string) error {
+	lock, err := LoadLock(filepath.Join(root, LockFile))
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, pkg := range lock.Packages {
+		dir := filepath.Join(root, VendorDir, filepath.FromSlash(pkg.Path))
+		sum, err := Checksum(dir)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			errs = append(errs, fmt.Errorf("%s@%s is not vendored", pkg.Path, pkg.Version))
+		case err != nil:
+			errs = append(errs, err)
+		case sum != pkg.Sum:
+			errs = append(errs, fmt.Errorf("%s@%s %w: vendored files have %s, locked %s",
+				pkg.Path, pkg.Version, ErrMismatch, sum, pkg.Sum))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Checksum returns a checksum of the ".monkey" files under dir: the SHA-256 of
+// their slash-separated relative paths and contents, in path order.
+func Checksum(dir string) (string, error) {
+	files, err := sources(dir)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	for _, name := range files {
+		//nolint:gosec // The file was found by walking dir
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s %d\n", filepath.ToSlash(name), len(data))
+		h.Write(data)
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// sources returns the relative paths of the ".monkey" files under dir, sorted,
+// skipping hidden directories such as ".git".
+func sources(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && p != dir && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if d.Type().IsRegular() && filepath.Ext(d.Name()) == ".monkey" {
+			rel, err := filepath.Rel(dir, p)
+			if err != nil {
+				return err
+			}
+			files = append(files, rel)
+		}
+		return nil
+	})
+	slices.Sort(files)
+	return files, err
+}
+
+// copyFile copies the regular file src to dst, creating the directories of dst.
+func copyFile(src, dst string) error {
+	//nolint:gosec // The file was found by walking the fetched package
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o750); err != nil {
+		return err
+	}
+	//nolint:gosec // Vendored sources are meant to be committed and shared
+	return os.WriteFile(dst, data, 0o644)
+}
@@ -0,0 +1,203 @@
+package deps
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseRef(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Ref
+		err      string // prefix of the error, if any
+	}{
+		{"github.com/user/lib@v1", Ref{"github.com/user/lib", "v1"}, ""},
+		{"example.org/a/b/c@v1.2.0", Ref{"example.org/a/b/c", "v1.2.0"}, ""},
+		{"github.com/user/lib", Ref{}, "missing version"},
+		{"github.com/user/lib@", Ref{}, "missing version"},
+		{"lib@v1", Ref{}, "invalid package path"},
+		{"user/lib@v1", Ref{}, "invalid package path"},
+		{"github.com/../lib@v1", Ref{}, "invalid package path"},
+		{"github.com//lib@v1", Ref{}, "invalid package path"},
+		{"github.com/user/lib@--upload-pack=x", Ref{}, "invalid version"},
+	}
+
+	for _, tt := range tests {
+		ref, err := ParseRef(tt.input)
+		if tt.err != "" {
+			if err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+				t.Errorf("%q: expected error %q, got=%v", tt.input, tt.err, err)
+			}
+			continue
+		}
+		if err != nil || ref != tt.expected {
+			t.Errorf("%q: expected=%+v, got=%+v (%v)", tt.input, tt.expected, ref, err)
+		}
+	}
+}
+
+// fakeFetch returns a Fetcher that writes files into the fetched directory.
+func fakeFetch(commit string, files map[string]string) Fetcher {
+	return func(_ Ref, dir string) (string, error) {
+		for name, content := range files {
+			file := filepath.Join(dir, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(file), 0o750); err != nil {
+				return "", err
+			}
+			if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+				return "", err
+			}
+		}
+		return commit, nil
+	}
+}
+
+func TestGet(t *testing.T) {
+	root := t.TempDir()
+	ref := Ref{Path: "github.com/user/lib", Version: "v1"}
+	files := map[string]string{
+		"lib.monkey":          "let double = fn(x) { x * 2 };",
+		"util/strings.monkey": `let shout = fn(s) { upper(s) };`,
+		"README.md":           "not vendored",
+		".git/config":         "not vendored",
+		".hidden/x.monkey":    "not vendored",
+	}
+
+	pkg, err := Get(root, ref, fakeFetch("abc123", files))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vendored := filepath.Join(root, "vendor", "github.com", "user", "lib")
+	got, err := sources(vendored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, " ") != "lib.monkey "+filepath.Join("util", "strings.monkey") {
+		t.Errorf("wrong vendored files. got=%q", got)
+	}
+	if pkg.Commit != "abc123" || !strings.HasPrefix(pkg.Sum, "sha256:") {
+		t.Errorf("wrong package. got=%+v", pkg)
+	}
+
+	lock, err := LoadLock(filepath.Join(root, "monke.lock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lock.Packages) != 1 || lock.Packages[0] != pkg {
+		t.Errorf("wrong lock. got=%+v", lock.Packages)
+	}
+
+	// Getting another version replaces the vendored files and the lock entry
+	ref.Version = "v2"
+	pkg2, err := Get(root, ref, fakeFetch("def456", map[string]string{"lib.monkey": "let triple = fn(x) { x * 3 };"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := sources(vendored); len(got) != 1 {
+		t.Errorf("stale files left in the vendor directory: %q", got)
+	}
+	if pkg2.Sum == pkg.Sum {
+		t.Error("checksum did not change with the contents")
+	}
+	lock, err = LoadLock(filepath.Join(root, "monke.lock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lock.Packages) != 1 || lock.Packages[0] != pkg2 {
+		t.Errorf("wrong lock after the update. got=%+v", lock.Packages)
+	}
+}
+
+func TestGetErrors(t *testing.T) {
+	root := t.TempDir()
+	ref := Ref{Path: "github.com/user/lib", Version: "v1"}
+
+	if _, err := Get(root, ref, fakeFetch("abc", map[string]string{"README.md": "docs only"})); err == nil ||
+		err.Error() != "github.com/user/lib@v1 has no .monkey files" {
+		t.Errorf("expected a no-sources error, got=%v", err)
+	}
+
+	failing := func(Ref, string) (string, error) { return "", errors.New("network down") }
+	if _, err := Get(root, ref, failing); err == nil || err.Error() != "network down" {
+		t.Errorf("expected the fetch error, got=%v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "monke.lock")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("lockfile written after failed fetches: %v", err)
+	}
+}
+
+func TestGetLockedVersion(t *testing.T) {
+	root := t.TempDir()
+	ref := Ref{Path: "github.com/user/lib", Version: "v1"}
+	files := map[string]string{"lib.monkey": "let double = fn(x) { x * 2 };"}
+	pkg, err := Get(root, ref, fakeFetch("abc123", files))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Fetching the same commit and files again is fine
+	if again, err := Get(root, ref, fakeFetch("abc123", files)); err != nil || again != pkg {
+		t.Errorf("refetching the locked version: got=%+v (%v)", again, err)
+	}
+
+	tests := []struct {
+		name   string
+		commit string
+		files  map[string]string
+	}{
+		{"moved tag", "def456", files},
+		{"changed files", "abc123", map[string]string{"lib.monkey": "let double = fn(x) { x + x };"}},
+	}
+	for _, tt := range tests {
+		if _, err := Get(root, ref, fakeFetch(tt.commit, tt.files)); !errors.Is(err, ErrMismatch) {
+			t.Errorf("%s: expected ErrMismatch, got=%v", tt.name, err)
+		}
+		lock, err := LoadLock(filepath.Join(root, "monke.lock"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(lock.Packages) != 1 || lock.Packages[0] != pkg {
+			t.Errorf("%s: lock changed. got=%+v", tt.name, lock.Packages)
+		}
+		if err := Verify(root); err != nil {
+			t.Errorf("%s: vendored files changed: %v", tt.name, err)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	root := t.TempDir()
+	if err := Verify(root); err != nil {
+		t.Errorf("project without a lockfile: %v", err)
+	}
+
+	for _, path := range []string{"github.com/user/a", "github.com/user/b"} {
+		ref := Ref{Path: path, Version: "v1"}
+		if _, err := Get(root, ref, fakeFetch("abc", map[string]string{"lib.monkey": "1;"})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := Verify(root); err != nil {
+		t.Errorf("freshly vendored packages: %v", err)
+	}
+
+	vendor := filepath.Join(root, "vendor", "github.com", "user")
+	if err := os.WriteFile(filepath.Join(vendor, "a", "lib.monkey"), []byte("2;"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	err := Verify(root)
+	if !errors.Is(err, ErrMismatch) || !strings.Contains(err.Error(), "github.com/user/a@v1") {
+		t.Errorf("expected a mismatch for the modified package, got=%v", err)
+	}
+
+	if err := os.RemoveAll(filepath.Join(vendor, "b")); err != nil {
+		t.Fatal(err)
+	}
+	err = Verify(root)
+	if err == nil || !strings.Contains(err.Error(), "github.com/user/b@v1 is not vendored") {
+		t.Errorf("expected the missing package to be reported, got=%v", err)
+	}
+}
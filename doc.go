@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dr8co/monke/doc"
+)
+
+// runDoc implements "monke doc", which prints the reference of builtins and keywords.
+func runDoc(args []string) int {
+	fs := flag.NewFlagSet("doc", flag.ExitOnError)
+	fs.Usage = func() {
+		out := fs.Output()
+		name := filepath.Base(os.Args[0])
+		fmt.Fprintf(out, "Usage: %s doc         List the builtins and keywords\n", name)
+		fmt.Fprintf(out, "       %s doc <name>  Show the reference of a builtin or keyword\n", name)
+	}
+	_ = fs.Parse(args)
+
+	switch fs.NArg() {
+	case 0:
+		fmt.Print(doc.Index())
+		return 0
+	case 1:
+		entry, ok := doc.Lookup(fs.Arg(0))
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: no reference for %q (see %s doc)\n", fs.Arg(0), filepath.Base(os.Args[0]))
+			return 1
+		}
+		fmt.Print(doc.Format(entry))
+		return 0
+	default:
+		fs.Usage()
+		return 2
+	}
+}
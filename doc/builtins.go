@@ -0,0 +1,330 @@
+package doc
+
+// builtinEntries is the reference of every builtin function registered by the evaluator.
+var builtinEntries = []Entry{
+	{
+		Name:      "len",
+		Kind:      Builtin,
+		Signature: "len(value) -> int",
+		Summary:   "Returns the number of characters in a string, of bytes in bytes, or of elements in an array.",
+		Examples: []Example{
+			{`len("hello")`, "5"},
+			{`len("héllo")`, "5"},
+			{`len([1, 2, 3])`, "3"},
+		},
+	},
+	{
+		Name:      "first",
+		Kind:      Builtin,
+		Signature: "first(array)",
+		Summary:   "Returns the first element of an array, or null if it is empty.",
+		Examples: []Example{
+			{`first([1, 2, 3])`, "1"},
+			{`first([])`, "null"},
+		},
+	},
+	{
+		Name:      "last",
+		Kind:      Builtin,
+		Signature: "last(array)",
+		Summary:   "Returns the last element of an array, or null if it is empty.",
+		Examples: []Example{
+			{`last([1, 2, 3])`, "3"},
+		},
+	},
+	{
+		Name:      "rest",
+		Kind:      Builtin,
+		Signature: "rest(array) -> array",
+		Summary:   "Returns a new array with every element but the first, or null if the array is empty.",
+		Examples: []Example{
+			{`rest([1, 2, 3])`, "[2, 3]"},
+			{`rest([])`, "null"},
+		},
+	},
+	{
+		Name:      "builder",
+		Kind:      Builtin,
+		Signature: "builder() -> builder",
+		Summary:   "Returns a new, empty string builder.",
+		Details: "Appending pieces to a builder with b_write and reading the result with b_string takes time linear " +
+			"in the length of the string. Unlike strings, a builder is changed in place.",
+		Examples: []Example{
+			{`type(builder())`, "BUILDER"},
+		},
+	},
+	{
+		Name:      "b_write",
+		Kind:      Builtin,
+		Signature: "b_write(builder, string) -> builder",
+		Summary:   "Appends the string to the builder in place and returns the builder.",
+		Examples: []Example{
+			{`b_string(b_write(b_write(builder(), "ab"), "c"))`, "abc"},
+		},
+	},
+	{
+		Name:      "b_string",
+		Kind:      Builtin,
+		Signature: "b_string(builder) -> string",
+		Summary:   "Returns the string written to the builder so far.",
+		Examples: []Example{
+			{`let b = builder(); b_write(b, "hi"); b_string(b)`, "hi"},
+		},
+	},
+	{
+		Name:      "bytes",
+		Kind:      Builtin,
+		Signature: "bytes(value) -> bytes",
+		Summary:   "Returns the bytes of a string, or bytes holding an array of integers from 0 to 255.",
+		Details: "Indexing bytes gives a byte as an integer, slicing them gives new bytes, and == compares their " +
+			"contents. Like strings, bytes are never changed in place; + concatenates them into new bytes.",
+		Examples: []Example{
+			{`bytes("hi")[0]`, "104"},
+			{`bytes_string(bytes([104, 105]))`, "hi"},
+			{`len(bytes("monke")[1:3])`, "2"},
+		},
+	},
+	{
+		Name:      "bytes_string",
+		Kind:      Builtin,
+		Signature: "bytes_string(bytes) -> string",
+		Summary:   "Returns a string of the bytes, which need not be valid UTF-8.",
+		Examples: []Example{
+			{`bytes_string(bytes("ab") + bytes("c"))`, "abc"},
+		},
+	},
+	{
+		Name:      "slice",
+		Kind:      Builtin,
+		Signature: "slice(array, start, end) -> array",
+		Summary:   "Returns a new array of the elements from start up to but not including end.",
+		Details: "end defaults to the length of the array. Negative indices count from the end of the array, " +
+			"and indices out of range are clamped to it. The slice expression array[start:end] does the same, " +
+			"and also slices strings.",
+		Examples: []Example{
+			{`slice([1, 2, 3, 4], 1, 3)`, "[2, 3]"},
+			{`slice([1, 2, 3, 4], -2)`, "[3, 4]"},
+			{`[1, 2, 3, 4][1:3]`, "[2, 3]"},
+		},
+	},
+	{
+		Name:      "splice",
+		Kind:      Builtin,
+		Signature: "splice(array, start, count, elements...) -> array",
+		Summary:   "Returns a new array with count elements removed from start and the elements inserted in their place.",
+		Details:   "start is an index as for slice, and the array itself is unchanged.",
+		Examples: []Example{
+			{`splice([1, 2, 3, 4], 1, 2)`, "[1, 4]"},
+			{`splice([1, 4], 1, 0, 2, 3)`, "[1, 2, 3, 4]"},
+		},
+	},
+	{
+		Name:      "push",
+		Kind:      Builtin,
+		Signature: "push(array, element) -> array",
+		Summary:   "Returns a new array with the element added to the end.",
+		Details:   "The original array is left unchanged.",
+		Examples: []Example{
+			{`push([1, 2], 3)`, "[1, 2, 3]"},
+		},
+	},
+	{
+		Name:      "puts",
+		Kind:      Builtin,
+		Signature: "puts(values...)",
+		Summary:   "Prints each value on its own line and returns null.",
+		Examples: []Example{
+			{`puts("hello")`, "null"},
+		},
+	},
+	{
+		Name:      "upper",
+		Kind:      Builtin,
+		Signature: "upper(string) -> string",
+		Summary:   "Returns the string converted to upper case.",
+		Examples: []Example{
+			{`upper("Monke")`, "MONKE"},
+		},
+	},
+	{
+		Name:      "lower",
+		Kind:      Builtin,
+		Signature: "lower(string) -> string",
+		Summary:   "Returns the string converted to lower case.",
+		Examples: []Example{
+			{`lower("Monke")`, "monke"},
+		},
+	},
+	{
+		Name:      "is_null",
+		Kind:      Builtin,
+		Signature: "is_null(value) -> bool",
+		Summary:   "Returns whether the value is null.",
+		Examples: []Example{
+			{`is_null(first([]))`, "true"},
+			{`is_null(0)`, "false"},
+		},
+	},
+	{
+		Name:      "is_empty",
+		Kind:      Builtin,
+		Signature: "is_empty(value) -> bool",
+		Summary:   "Returns whether a string, array or hash has no elements.",
+		Examples: []Example{
+			{`is_empty("")`, "true"},
+			{`is_empty({"a": 1})`, "false"},
+		},
+	},
+	{
+		Name:      "merge",
+		Kind:      Builtin,
+		Signature: "merge(a, b) -> hash",
+		Summary:   "Returns a new hash with the pairs of both hashes, those of b winning.",
+		Details:   "The result keeps the tag of a.",
+		Examples: []Example{
+			{`merge({"x": 1}, {"x": 2})`, "{x: 2}"},
+			{`merge(tag({"x": 1, "y": 1}, "Point"), {"y": 2}).y`, "2"},
+		},
+	},
+	{
+		Name:      "type",
+		Kind:      Builtin,
+		Signature: "type(value) -> string",
+		Summary:   "Returns the name of the value's type, or the tag of a tagged hash.",
+		Examples: []Example{
+			{`type(1)`, "INTEGER"},
+			{`type(tag({}, "Point"))`, "Point"},
+		},
+	},
+	{
+		Name:      "tag",
+		Kind:      Builtin,
+		Signature: "tag(hash, name) -> hash",
+		Summary:   "Returns a copy of the hash tagged with the type name.",
+		Details: "Tagged hashes are printed with their tag, and type() returns it.\n" +
+			"An empty name returns an untagged copy.",
+		Examples: []Example{
+			{`tag({"x": 1}, "Point")`, "Point{x: 1}"},
+		},
+	},
+	{
+		Name:      "source",
+		Kind:      Builtin,
+		Signature: "source(fn) -> string",
+		Summary:   "Returns the formatted source code of a function.",
+		Examples: []Example{
+			{`source(fn(x) { x * 2 })`, "fn(x) {\n    x * 2;\n}"},
+		},
+	},
+	{
+		Name:      "close",
+		Kind:      Builtin,
+		Signature: "close(resource) -> null",
+		Summary:   "Closes a resource, such as a file given to the program by a host builtin.",
+		Details: "Closing a resource again does nothing. Resources left open are closed once they are unreachable, " +
+			"and all of them when the run is interrupted.",
+		Examples: []Example{
+			{`close(1)`, "ERROR: argument to `close` must be RESOURCE, got INTEGER"},
+		},
+	},
+	{
+		Name:      "div",
+		Kind:      Builtin,
+		Signature: "div(a: int, b: int) -> int",
+		Summary:   "Returns the quotient of two integers, rounded towards negative infinity.",
+		Details:   "Unlike a / b, which rounds towards zero, div(a, b) * b + mod(a, b) is always a.",
+		Examples: []Example{
+			{`div(7, 2)`, "3"},
+			{`div(-7, 2)`, "-4"},
+		},
+	},
+	{
+		Name:      "mod",
+		Kind:      Builtin,
+		Signature: "mod(a: int, b: int) -> int",
+		Summary:   "Returns the remainder of div(a, b), which has the sign of b.",
+		Examples: []Example{
+			{`mod(7, 3)`, "1"},
+			{`mod(-7, 3)`, "2"},
+		},
+	},
+	{
+		Name:      "version",
+		Kind:      Builtin,
+		Signature: "version() -> hash",
+		Summary:   "Returns a hash with the interpreter version, its engine and the supported features.",
+		Details:   `The "features" array lets scripts detect optional capabilities, such as "defer".`,
+		Examples: []Example{
+			{`version()["engine"]`, "tree-walking"},
+		},
+	},
+	{
+		Name:      "runtime_stats",
+		Kind:      Builtin,
+		Signature: "runtime_stats() -> hash",
+		Summary:   "Returns the number of calls and cumulative time of every builtin called so far.",
+		Details:   `Each builtin name maps to a hash with its "calls" and "time_ns".`,
+		Examples: []Example{
+			{`type(runtime_stats())`, "HASH"},
+		},
+	},
+	{
+		Name:      "forall",
+		Kind:      Builtin,
+		Signature: "forall(gen, property, iterations) -> bool",
+		Summary:   "Checks a property against values drawn from a generator.",
+		Details: "Returns true if the property holds for every value (100 unless iterations is given).\n" +
+			"Otherwise the failing value is shrunk to a minimal counterexample and reported as an error.",
+		Examples: []Example{
+			{`forall(gen_int(-100, 100), fn(x) { x + 0 == x })`, "true"},
+		},
+	},
+	{
+		Name:      "gen_int",
+		Kind:      Builtin,
+		Signature: "gen_int(lo: int, hi: int)",
+		Summary:   "Returns a generator of integers in [lo, hi] that shrink towards zero.",
+		Examples: []Example{
+			{`gen_int(1, 6)`, "gen_int(1, 6)"},
+		},
+	},
+	{
+		Name:      "gen_string",
+		Kind:      Builtin,
+		Signature: "gen_string(n: int)",
+		Summary:   "Returns a generator of lowercase strings of up to n characters.",
+		Examples: []Example{
+			{`forall(gen_string(5), fn(s) { len(s) < 6 })`, "true"},
+		},
+	},
+	{
+		Name:      "gen_array",
+		Kind:      Builtin,
+		Signature: "gen_array(gen, n: int)",
+		Summary:   "Returns a generator of arrays of up to n elements drawn from gen.",
+		Examples: []Example{
+			{`forall(gen_array(gen_int(0, 9), 5), fn(a) { len(push(a, 1)) == len(a) + 1 })`, "true"},
+		},
+	},
+	{
+		Name:      "args",
+		Kind:      Builtin,
+		Signature: "args() -> array",
+		Summary:   "Returns the command-line arguments passed to the script as an array of strings.",
+		Examples: []Example{
+			{`args()`, "[]"},
+		},
+	},
+	{
+		Name:      "parse_flags",
+		Kind:      Builtin,
+		Signature: "parse_flags(spec: hash, argv: array) -> hash",
+		Summary:   "Parses command-line flags described by spec from argv, or from args() if it is omitted.",
+		Details: `Each key of spec is a flag name mapped to a hash with a "type" ("int", "string" or "bool"),` + "\n" +
+			`an optional "default" and an optional "help" text. The result maps each flag to its value,` + "\n" +
+			`"_" to the positional arguments and "_usage" to a usage message.`,
+		Examples: []Example{
+			{`parse_flags({"n": {"type": "int", "default": 1}}, ["--n", "3"])["n"]`, "3"},
+		},
+	},
+}
@@ -0,0 +1,116 @@
+// Package doc holds the reference entries for the builtins and keywords of Monke.
+//
+// The entries are shown by "monke doc <name>" and by ":help <name>" in the REPL.
+// Every builtin registered by the evaluator and every keyword of the lexer has
+// an entry, and the result of every example is checked by the tests.
+//
+// Key components:
+//   - Entry: The reference of one builtin or keyword
+//   - Lookup: Finds the entry for a name
+//   - Format: Renders an entry for the terminal
+//   - Index: Renders the list of entries with their summaries
+package doc
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// Kind tells builtins and keywords apart.
+type Kind string
+
+// The kinds of entries.
+const (
+	Builtin Kind = "builtin"
+	Keyword Kind = "keyword"
+)
+
+// Entry is the reference of a builtin or keyword.
+type Entry struct {
+	Name      string
+	Kind      Kind
+	Signature string // How a call or construct is written, e.g. "len(value) -> int"
+	Summary   string // One sentence
+	Details   string // Further paragraphs, if any
+	Examples  []Example
+}
+
+// Example is a snippet of code with the value it evaluates to.
+type Example struct {
+	Code   string
+	Result string // The Inspect form of the value
+}
+
+// All returns every entry, builtins first, each kind sorted by name.
+func All() []Entry {
+	all := slices.Concat(builtinEntries, keywordEntries)
+	slices.SortStableFunc(all, func(a, b Entry) int {
+		if a.Kind != b.Kind {
+			if a.Kind == Builtin {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return all
+}
+
+// Lookup returns the entry for name.
+func Lookup(name string) (Entry, bool) {
+	for _, e := range All() {
+		if e.Name == name {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Format renders e as plain text: the kind and name, the signature, the description
+// and the examples with their results.
+func Format(e Entry) string {
+	var s strings.Builder
+	fmt.Fprintf(&s, "%s %s\n\n", e.Kind, e.Name)
+	s.WriteString(indent(e.Signature))
+	s.WriteString("\n")
+	s.WriteString(indent(e.Summary))
+	if e.Details != "" {
+		s.WriteString("\n")
+		s.WriteString(indent(e.Details))
+	}
+	if len(e.Examples) > 0 {
+		s.WriteString("\nExamples:\n")
+		for _, ex := range e.Examples {
+			s.WriteString(indent(ex.Code))
+			s.WriteString(indent("// => " + strings.ReplaceAll(ex.Result, "\n", "\n// ")))
+		}
+	}
+	return s.String()
+}
+
+// Index renders the names of all entries with their summaries, grouped by kind.
+func Index() string {
+	var s strings.Builder
+	w := tabwriter.NewWriter(&s, 0, 0, 2, ' ', 0)
+	var kind Kind
+	for _, e := range All() {
+		if e.Kind != kind {
+			if kind != "" {
+				fmt.Fprintln(w)
+			}
+			kind = e.Kind
+			fmt.Fprintf(w, "%ss:\n", strings.ToUpper(string(kind[:1]))+string(kind[1:]))
+		}
+		fmt.Fprintf(w, "  %s\t%s\n", e.Name, e.Summary)
+	}
+	_ = w.Flush()
+	return s.String()
+}
+
+// indent indents every line of text by four spaces and ends it with a newline.
+func indent(text string) string {
+	return "    " + strings.ReplaceAll(strings.TrimRight(text, "\n"), "\n", "\n    ") + "\n"
+}
@@ -0,0 +1,88 @@
+package doc
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/dr8co/monke/evaluator"
+	"github.com/dr8co/monke/lexer"
+	"github.com/dr8co/monke/modules"
+	"github.com/dr8co/monke/object"
+	"github.com/dr8co/monke/parser"
+	"github.com/dr8co/monke/token"
+)
+
+func TestEveryNameHasAnEntry(t *testing.T) {
+	var builtins, keywords []string
+	for _, e := range All() {
+		switch e.Kind {
+		case Builtin:
+			builtins = append(builtins, e.Name)
+		case Keyword:
+			keywords = append(keywords, e.Name)
+		default:
+			t.Errorf("%s: unknown kind %q", e.Name, e.Kind)
+		}
+		if e.Signature == "" || e.Summary == "" || len(e.Examples) == 0 {
+			t.Errorf("%s: incomplete entry: %+v", e.Name, e)
+		}
+	}
+
+	if want := evaluator.Builtins(); !slices.Equal(builtins, want) {
+		t.Errorf("builtin entries differ from the registered builtins.\nentries=%v\nbuiltins=%v", builtins, want)
+	}
+	if want := token.Keywords(); !slices.Equal(keywords, want) {
+		t.Errorf("keyword entries differ from the keywords.\nentries=%v\nkeywords=%v", keywords, want)
+	}
+}
+
+func TestExamples(t *testing.T) {
+	evaluator.SetModuleResolver(modules.Stdlib)
+	defer evaluator.SetModuleResolver(nil)
+	for _, e := range All() {
+		for _, ex := range e.Examples {
+			p := parser.New(lexer.New(ex.Code))
+			program := p.ParseProgram()
+			if len(p.Errors()) != 0 {
+				t.Errorf("%s: %q: parser errors: %v", e.Name, ex.Code, p.Errors())
+				continue
+			}
+			got := evaluator.Eval(program, object.NewEnvironment())
+			if got == nil || got.Inspect() != ex.Result {
+				t.Errorf("%s: %q: wrong result. expected=%q, got=%v", e.Name, ex.Code, ex.Result, got)
+			}
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	e, ok := Lookup("len")
+	if !ok {
+		t.Fatal("no entry for len")
+	}
+	expected := `builtin len
+
+    len(value) -> int
+
+    Returns the number of characters in a string, of bytes in bytes, or of elements in an array.
+
+Examples:
+    len("hello")
+    // => 5
+    len("héllo")
+    // => 5
+    len([1, 2, 3])
+    // => 3
+`
+	if got := Format(e); got != expected {
+		t.Errorf("wrong format.\nexpected=%q\ngot=%q", expected, got)
+	}
+
+	if _, ok := Lookup("missing"); ok {
+		t.Error("found an entry for missing")
+	}
+	if index := Index(); !strings.HasPrefix(index, "Builtins:\n  args ") || !strings.Contains(index, "\nKeywords:\n") {
+		t.Errorf("wrong index. got=%q", index)
+	}
+}
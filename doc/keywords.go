@@ -0,0 +1,160 @@
+package doc
+
+// keywordEntries is the reference of every keyword of the language.
+var keywordEntries = []Entry{
+	{
+		Name:      "fn",
+		Kind:      Keyword,
+		Signature: "fn(parameters) { statements }\nfn(parameters, ...rest) { statements }",
+		Summary:   "Creates a function, which evaluates to the value of its last statement.",
+		Details: "Functions are values: they can be bound, passed around and close over their environment. " +
+			"A last parameter written `...rest` is bound to an array of the arguments left over. " +
+			"Calls may pass arguments by parameter name after the positional ones, as in `f(1, by: 2)`.",
+		Examples: []Example{
+			{`let add = fn(a, b) { a + b }; add(1, 2)`, "3"},
+			{`let count = fn(first, ...others) { len(others) }; count(1, 2, 3)`, "2"},
+			{`let scale = fn(x, by) { x * by }; scale(by: 3, x: 2)`, "6"},
+		},
+	},
+	{
+		Name:      "let",
+		Kind:      Keyword,
+		Signature: "let name = expression;",
+		Summary:   "Binds the value of an expression to a name in the current scope.",
+		Details:   "let a, b = expression; destructures an array, binding each name to an element in order; the array must have as many elements as there are names, and _ skips an element. let [head, ...tail] = expression; destructures it with an array pattern, as in match, where ...tail binds the elements left over. let {name, age: years} = expression; binds the values of the keys of a hash, or null for keys it does not have (an error in strict mode).",
+		Examples: []Example{
+			{`let x = 5; x * 2`, "10"},
+			{`let _, second = [1, 2]; second`, "2"},
+			{`let [head, ...tail] = [1, 2, 3]; tail`, "[2, 3]"},
+			{`let {name, email} = {"name": "Ann"}; [name, email]`, "[Ann, null]"},
+		},
+	},
+	{
+		Name:      "true",
+		Kind:      Keyword,
+		Signature: "true",
+		Summary:   "The boolean true value.",
+		Examples: []Example{
+			{`1 < 2 == true`, "true"},
+		},
+	},
+	{
+		Name:      "false",
+		Kind:      Keyword,
+		Signature: "false",
+		Summary:   "The boolean false value.",
+		Examples: []Example{
+			{`!true == false`, "true"},
+		},
+	},
+	{
+		Name:      "if",
+		Kind:      Keyword,
+		Signature: "if (condition) { statements } else { statements }",
+		Summary:   "Evaluates the first block if the condition is truthy, and the else block otherwise.",
+		Details:   `false and null are falsy, and in strict mode 0, 0.0, "", [] and {} too. Without an else block, the result is null.`,
+		Examples: []Example{
+			{`if (1 < 2) { "yes" } else { "no" }`, "yes"},
+		},
+	},
+	{
+		Name:      "else",
+		Kind:      Keyword,
+		Signature: "if (condition) { statements } else { statements }",
+		Summary:   "Introduces the block an if expression evaluates when its condition is falsy.",
+		Details:   "\"else if\" chains another if expression, which is evaluated in place of the block.",
+		Examples: []Example{
+			{`if (len("") > 0) { "yes" } else { "no" }`, "no"},
+			{`let n = 0; if (n < 0) { "negative" } else if (n == 0) { "zero" } else { "positive" }`, "zero"},
+		},
+	},
+	{
+		Name:      "return",
+		Kind:      Keyword,
+		Signature: "return expression;",
+		Summary:   "Returns the value of an expression from the enclosing function.",
+		Details:   "return a, b; returns several values as an array, which the caller can destructure with let.",
+		Examples: []Example{
+			{`let sign = fn(x) { if (x < 0) { return -1; } 1 }; sign(-5)`, "-1"},
+			{`let divide = fn(a, b) { if (b == 0) { return 0, "division by zero"; } return a / b, ""; }; let q, err = divide(7, 2); q`, "3"},
+		},
+	},
+	{
+		Name:      "defer",
+		Kind:      Keyword,
+		Signature: "defer { statements }",
+		Summary:   "Runs a block when the enclosing function returns, in reverse order of registration.",
+		Details:   "The value of a deferred block is discarded, so it cannot change the function's result.",
+		Examples: []Example{
+			{`let log = []; let f = fn() { defer { log = push(log, "done"); } log = push(log, "body"); 1 }; f(); log`, "[body, done]"},
+		},
+	},
+	{
+		Name:      "while",
+		Kind:      Keyword,
+		Signature: "while (condition) { statements }",
+		Summary:   "Evaluates the body for as long as the condition is truthy.",
+		Examples: []Example{
+			{`let i = 0; while (i < 3) { i = i + 1; } i`, "3"},
+		},
+	},
+	{
+		Name:      "for",
+		Kind:      Keyword,
+		Signature: "for (init; condition; post) { statements }\nfor (name in iterable) { statements }",
+		Summary:   "Loops with an init statement, a condition and a post statement, or over an array or hash.",
+		Examples: []Example{
+			{`let sum = 0; for (let i = 1; i < 4; i = i + 1) { sum = sum + i; } sum`, "6"},
+			{`let sum = 0; for (x in [1, 2, 3]) { sum = sum + x; } sum`, "6"},
+		},
+	},
+	{
+		Name:      "in",
+		Kind:      Keyword,
+		Signature: "for (value in iterable) { statements }\nfor (key, value in hash) { statements }",
+		Summary:   "Separates the loop variables of a for-in loop from what it iterates over.",
+		Details:   "Hashes are iterated in key order, with integers sorted numerically and strings alphabetically.",
+		Examples: []Example{
+			{`let keys = []; for (k, v in {"b": 2, "a": 1}) { keys = push(keys, k); } keys`, "[a, b]"},
+		},
+	},
+	{
+		Name:      "match",
+		Kind:      Keyword,
+		Signature: "match (value) { pattern => expression, pattern => { statements } }",
+		Summary:   "Evaluates the arm of the first pattern the value matches, or gives null if none does.",
+		Details: "Patterns are literals, names that bind the value (`_` binds nothing), arrays such as " +
+			"`[x, ...rest]`, and hashes such as `{name: n}` or `Point {x, y}`, which also require a tag. " +
+			"Hash patterns ignore the keys they do not list. The names a pattern binds are only visible in its arm.",
+		Examples: []Example{
+			{`match ([1, 2, 3]) { [] => 0, [first, ...rest] => first + len(rest) }`, "3"},
+			{`match (tag({"x": 1, "y": 2}, "Point")) { Point {x, y: 0} => x, Point {y} => y }`, "2"},
+		},
+	},
+	{
+		Name:      "import",
+		Kind:      Keyword,
+		Signature: `import "path";`,
+		Summary:   "Evaluates a module once and binds its top-level names in the current scope.",
+		Details: "The standard library is imported as `std/name`; other paths name `.monkey` files in the " +
+			"program's directory or its vendor directory, and paths starting with `./` or `../` are " +
+			"relative to the importing module. Names starting with `_` are private to the module.",
+		Examples: []Example{
+			{`import "std/list"; reduce(map([1, 2, 3], fn(x) { x * x }), 0, fn(a, b) { a + b })`, "14"},
+		},
+	},
+	{
+		Name:      "class",
+		Kind:      Keyword,
+		Signature: "class Name(field, ...) { fn method(parameters) { statements } ... }",
+		Summary:   "Binds a class: calling it with the values of its fields makes an instance.",
+		Details: "An instance is a hash tagged with the name of the class, so its fields are read as `p.x`, " +
+			"`type` returns the name and `match` patterns such as `Point {x, y}` match it. " +
+			"`p.method(args)` calls a method with `self` bound to the instance, and `merge(self, {...})` " +
+			"returns an updated instance that keeps its methods.",
+		Examples: []Example{
+			{`class Point(x, y) { fn norm() { self.x * self.x + self.y * self.y } }; Point(3, 4).norm()`, "25"},
+			{`class Counter(n) { fn next() { merge(self, {"n": self.n + 1}) } }; Counter(n: 1).next().next().n`, "3"},
+		},
+	},
+}
@@ -0,0 +1,98 @@
+package evaluator
+
+import (
+	"github.com/dr8co/monke/ast"
+	"github.com/dr8co/monke/object"
+)
+
+// hasNamedArguments reports whether a call passes any argument by name. The
+// parser puts named arguments after the positional ones, so only the last
+// argument needs to be looked at.
+func hasNamedArguments(arguments []ast.Expression) bool {
+	if len(arguments) == 0 {
+		return false
+	}
+	_, ok := arguments[len(arguments)-1].(*ast.NamedArgument)
+	return ok
+}
+
+// evalNamedCall calls function with arguments that include named ones. The
+// positional arguments bind the first parameters, and each named argument
+// binds the parameter of its name, which must not be bound already. Named
+// arguments cannot bind a rest parameter, which collects the positional
+// arguments left over. In strict mode every other parameter must be bound;
+// in legacy mode the parameters left out are null. The parameters of a class
+// are its fields, which must all be bound. The receiver of a method call is
+// passed before the positional arguments.
+func evalNamedCall(function object.Object, arguments []ast.Expression, env *object.Environment, receiver ...object.Object) object.Object {
+	var params []*ast.Identifier
+	rest, strict := false, true
+	switch fn := function.(type) {
+	case *object.Function:
+		params, rest, strict = fn.Parameters, fn.Rest, fn.Env.Strict()
+	case *object.Class:
+		params = fn.Fields
+	case *object.Builtin:
+		return newError("builtins do not take named arguments")
+	default:
+		return newError("not a function: %s", function.Type())
+	}
+
+	first := len(arguments)
+	for first > 0 {
+		if _, ok := arguments[first-1].(*ast.NamedArgument); !ok {
+			break
+		}
+		first--
+	}
+	positional := evalExpressions(arguments[:first], env)
+	if len(positional) == 1 && isError(positional[0]) {
+		return positional[0]
+	}
+	positional = append(receiver, positional...)
+
+	all := params
+	if rest {
+		params = params[:len(params)-1]
+	}
+	args := make([]object.Object, max(len(params), len(positional)))
+	copy(args, positional)
+	for _, arg := range arguments[first:] {
+		named := arg.(*ast.NamedArgument)
+		i := parameterIndex(all, named.Name.Value)
+		switch {
+		case i < 0:
+			return newError("unknown parameter: %s", named.Name.Value)
+		case i == len(params):
+			return newError("rest parameter cannot be passed by name: %s", named.Name.Value)
+		case args[i] != nil:
+			return newError("argument given twice for parameter: %s", named.Name.Value)
+		}
+		value := Eval(named.Value, env)
+		if isError(value) {
+			return value
+		}
+		args[i] = value
+	}
+
+	for i, arg := range args[:len(params)] {
+		if arg != nil {
+			continue
+		}
+		if strict {
+			return newError("missing argument for parameter: %s", params[i].Value)
+		}
+		args[i] = NULL
+	}
+	return applyFunction(function, args)
+}
+
+// parameterIndex returns the index of the parameter in params called name, or -1.
+func parameterIndex(params []*ast.Identifier, name string) int {
+	for i, param := range params {
+		if param.Value == name {
+			return i
+		}
+	}
+	return -1
+}
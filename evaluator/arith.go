@@ -0,0 +1,95 @@
+package evaluator
+
+import (
+	"math"
+
+	"github.com/dr8co/monke/object"
+)
+
+func init() {
+	builtins["div"] = &object.Builtin{Fn: divBuiltin}
+	builtins["mod"] = &object.Builtin{Fn: modBuiltin}
+}
+
+// checkedIntegerOp applies an arithmetic operator to a and b, reporting
+// int64 overflow as an error instead of wrapping around.
+// It returns nil for operators that are not arithmetic.
+func checkedIntegerOp(operator string, a, b int64) object.Object {
+	var r int64
+	overflow := false
+	switch operator {
+	case "+":
+		r = a + b
+		overflow = (a^r)&(b^r) < 0
+	case "-":
+		r = a - b
+		overflow = (a^b)&(a^r) < 0
+	case "*":
+		r = a * b
+		overflow = a != 0 && (r/a != b || a == -1 && b == math.MinInt64)
+	case "/":
+		if b == 0 {
+			return newError("division by zero: %d / %d", a, b)
+		}
+		overflow = a == math.MinInt64 && b == -1
+		r = a / b
+	default:
+		return nil
+	}
+	if overflow {
+		return newError("integer overflow: %d %s %d", a, operator, b)
+	}
+	return getIntegerObject(r)
+}
+
+// integerArgs returns the two INTEGER arguments of the builtin name.
+func integerArgs(name string, args []object.Object) (a, b int64, err *object.Error) {
+	if len(args) != 2 {
+		return 0, 0, newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	x, ok := args[0].(*object.Integer)
+	if !ok {
+		return 0, 0, newError("first argument to `%s` must be INTEGER, got %s", name, args[0].Type())
+	}
+	y, ok := args[1].(*object.Integer)
+	if !ok {
+		return 0, 0, newError("second argument to `%s` must be INTEGER, got %s", name, args[1].Type())
+	}
+	if y.Value == 0 {
+		return 0, 0, newError("division by zero: %s(%d, 0)", name, x.Value)
+	}
+	return x.Value, y.Value, nil
+}
+
+// divBuiltin implements div(a, b), the quotient of a and b rounded towards negative infinity.
+func divBuiltin(args ...object.Object) object.Object {
+	a, b, err := integerArgs("div", args)
+	if err != nil {
+		return err
+	}
+	if a == math.MinInt64 && b == -1 {
+		return newError("integer overflow: div(%d, %d)", a, b)
+	}
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return getIntegerObject(q)
+}
+
+// modBuiltin implements mod(a, b), the remainder of div(a, b), which has the sign of b.
+func modBuiltin(args ...object.Object) object.Object {
+	a, b, err := integerArgs("mod", args)
+	if err != nil {
+		return err
+	}
+	if b == -1 {
+		// Avoid the overflow of math.MinInt64 % -1 on some platforms
+		return getIntegerObject(0)
+	}
+	m := a % b
+	if m != 0 && (m < 0) != (b < 0) {
+		m += b
+	}
+	return getIntegerObject(m)
+}
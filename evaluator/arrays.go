@@ -0,0 +1,152 @@
+package evaluator
+
+import (
+	"slices"
+
+	"github.com/dr8co/monke/ast"
+	"github.com/dr8co/monke/object"
+)
+
+func init() {
+	builtins["slice"] = &object.Builtin{Fn: sliceBuiltin}
+	builtins["splice"] = &object.Builtin{Fn: spliceBuiltin}
+}
+
+// sliceBuiltin implements slice(array, start, end), returning a new array of
+// the elements from start up to but not including end, which defaults to the
+// length of the array. Negative indices count from the end of the array, and
+// indices out of range are clamped to it.
+func sliceBuiltin(args ...object.Object) object.Object {
+	if len(args) != 2 && len(args) != 3 {
+		return newError("wrong number of arguments. got=%d, want=2 or 3", len(args))
+	}
+	array, ok := args[0].(*object.Array)
+	if !ok {
+		return newError("first argument to `slice` must be ARRAY, got %s", args[0].Type())
+	}
+	start, err := arrayIndex("slice", "second", args[1], len(array.Elements))
+	if err != nil {
+		return err
+	}
+	end := len(array.Elements)
+	if len(args) == 3 {
+		if end, err = arrayIndex("slice", "third", args[2], len(array.Elements)); err != nil {
+			return err
+		}
+	}
+	end = max(start, end)
+	return allocate(&object.Array{Elements: slices.Clone(array.Elements[start:end])})
+}
+
+// spliceBuiltin implements splice(array, start, count, ...elements), returning
+// a new array with count elements removed from start and the given elements
+// inserted in their place. start is an index as for slice, and count is
+// clamped to the elements left after start. The array itself is unchanged.
+func spliceBuiltin(args ...object.Object) object.Object {
+	if len(args) < 3 {
+		return newError("wrong number of arguments. got=%d, want at least 3", len(args))
+	}
+	array, ok := args[0].(*object.Array)
+	if !ok {
+		return newError("first argument to `splice` must be ARRAY, got %s", args[0].Type())
+	}
+	start, err := arrayIndex("splice", "second", args[1], len(array.Elements))
+	if err != nil {
+		return err
+	}
+	count, ok := args[2].(*object.Integer)
+	if !ok {
+		return newError("third argument to `splice` must be INTEGER, got %s", args[2].Type())
+	}
+	if count.Value < 0 {
+		return newError("third argument to `splice` must not be negative, got %d", count.Value)
+	}
+	end := start + int(min(count.Value, int64(len(array.Elements)-start)))
+
+	elements := make([]object.Object, 0, len(array.Elements)-(end-start)+len(args)-3)
+	elements = append(elements, array.Elements[:start]...)
+	elements = append(elements, args[3:]...)
+	elements = append(elements, array.Elements[end:]...)
+	return allocate(&object.Array{Elements: elements})
+}
+
+// arrayIndex returns the index given by the argument arg of the builtin name,
+// the nth, into an array of the given length. Negative indices count from the
+// end, and the result is clamped to the range from 0 to length.
+func arrayIndex(name, nth string, arg object.Object, length int) (int, *object.Error) {
+	index, ok := arg.(*object.Integer)
+	if !ok {
+		return 0, newError("%s argument to `%s` must be INTEGER, got %s", nth, name, arg.Type())
+	}
+	return clampIndex(index.Value, length), nil
+}
+
+// clampIndex returns the index i into a sequence of the given length, with a
+// negative i counting from the end, clamped to the range from 0 to length.
+func clampIndex(i int64, length int) int {
+	if i < 0 {
+		i += int64(length)
+	}
+	return int(min(max(i, 0), int64(length)))
+}
+
+// evalSliceExpression evaluates left[start:end]: a new array of the elements,
+// string of the characters, or bytes of the bytes, of left from start up to
+// but not including end.
+// The bounds are clamped as they are by the `slice` builtin; a missing start
+// is 0 and a missing end is the length of left.
+func evalSliceExpression(se *ast.SliceExpression, env *object.Environment) object.Object {
+	left := Eval(se.Left, env)
+	if isError(left) {
+		return left
+	}
+	if se.Optional && left == NULL {
+		return NULL
+	}
+
+	var length int
+	switch left := left.(type) {
+	case *object.Array:
+		length = len(left.Elements)
+	case *object.String:
+		length = left.CharLen()
+	case *object.Bytes:
+		length = len(left.Value)
+	default:
+		return newError("slice operator not supported: %s", left.Type())
+	}
+	start, err := sliceBound(se.Start, 0, length, env)
+	if err != nil {
+		return err
+	}
+	end, err := sliceBound(se.End, length, length, env)
+	if err != nil {
+		return err
+	}
+	end = max(start, end)
+
+	switch left := left.(type) {
+	case *object.Array:
+		return allocate(&object.Array{Elements: slices.Clone(left.Elements[start:end])})
+	case *object.Bytes:
+		return allocate(&object.Bytes{Value: slices.Clone(left.Value[start:end])})
+	}
+	return allocate(&object.String{Value: left.(*object.String).CharSlice(start, end)})
+}
+
+// sliceBound evaluates the bound exp of a slice into a sequence of the given
+// length, or returns def if the bound is left out.
+func sliceBound(exp ast.Expression, def, length int, env *object.Environment) (int, object.Object) {
+	if exp == nil {
+		return def, nil
+	}
+	bound := Eval(exp, env)
+	if isError(bound) {
+		return 0, bound
+	}
+	index, ok := bound.(*object.Integer)
+	if !ok {
+		return 0, newError("slice index must be INTEGER, got %s", bound.Type())
+	}
+	return clampIndex(index.Value, length), nil
+}
@@ -0,0 +1,82 @@
+package evaluator
+
+import (
+	"github.com/dr8co/monke/ast"
+	"github.com/dr8co/monke/object"
+	"github.com/dr8co/monke/token"
+)
+
+// Frame is a function call in progress, as reported by Backtrace.
+type Frame struct {
+	Function string         // The name of the function, Anonymous, or "" for the top level
+	Pos      token.Position // The position of the statement being evaluated in the call
+}
+
+// Anonymous is the Function of the frames of functions without a name.
+const Anonymous = "(anonymous)"
+
+// call is a function call in progress.
+type call struct {
+	fn     *object.Function
+	caller ast.Statement // The statement of the caller that made the call
+}
+
+// calls holds the function calls in progress, innermost last, and current the
+// statement being evaluated by the innermost one.
+var (
+	calls   []call
+	current ast.Statement
+)
+
+// pushCall records the start of a call to fn.
+func pushCall(fn *object.Function) {
+	calls = append(calls, call{fn: fn, caller: current})
+}
+
+// popCall records the end of the innermost call.
+func popCall() {
+	current = calls[len(calls)-1].caller
+	calls = calls[:len(calls)-1]
+}
+
+// backtraceHandler receives the backtraces asked for by RequestBacktrace.
+var backtraceHandler func(frames []Frame)
+
+// SetBacktraceHandler installs h to receive the backtraces asked for by
+// RequestBacktrace; nil removes the current handler.
+func SetBacktraceHandler(h func(frames []Frame)) {
+	backtraceHandler = h
+}
+
+// RequestBacktrace asks the running evaluation to pass its Backtrace to the
+// handler installed by SetBacktraceHandler at its next step, and then carry
+// on. It is safe to call from another goroutine, e.g. on a signal to find out
+// where a script hangs.
+func RequestBacktrace() {
+	requests.Or(backtraceRequest)
+}
+
+// Backtrace returns the function calls in progress, most recent first, down to
+// the top level of the program. It must be called from the goroutine running
+// the evaluation, e.g. by a builtin or a Tracer.
+func Backtrace() []Frame {
+	frames := make([]Frame, 0, len(calls)+1)
+	stmt := current
+	for i := len(calls) - 1; i >= 0; i-- {
+		name := calls[i].fn.Name
+		if name == "" {
+			name = Anonymous
+		}
+		frames = append(frames, Frame{Function: name, Pos: position(stmt)})
+		stmt = calls[i].caller
+	}
+	return append(frames, Frame{Pos: position(stmt)})
+}
+
+// position returns the position of stmt, or the zero position if it is nil.
+func position(stmt ast.Statement) token.Position {
+	if stmt == nil {
+		return token.Position{}
+	}
+	return stmt.Pos()
+}
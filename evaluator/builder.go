@@ -0,0 +1,52 @@
+package evaluator
+
+import "github.com/dr8co/monke/object"
+
+func init() {
+	builtins["builder"] = &object.Builtin{Fn: builderBuiltin}
+	builtins["b_write"] = &object.Builtin{Fn: bWriteBuiltin}
+	builtins["b_string"] = &object.Builtin{Fn: bStringBuiltin}
+}
+
+// builderBuiltin implements builder(), returning a new, empty string builder.
+func builderBuiltin(args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return newError("wrong number of arguments. got=%d, want=0", len(args))
+	}
+	return allocate(&object.Builder{})
+}
+
+// bWriteBuiltin implements b_write(builder, string), appending the string to
+// the builder in place and returning the builder, so that writes can be chained.
+func bWriteBuiltin(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	b, ok := args[0].(*object.Builder)
+	if !ok {
+		return newError("first argument to `b_write` must be BUILDER, got %s", args[0].Type())
+	}
+	s, ok := args[1].(*object.String)
+	if !ok {
+		return newError("second argument to `b_write` must be STRING, got %s", args[1].Type())
+	}
+	before := b.Cap()
+	b.WriteString(s.Flat())
+	if err := grow(b, b.Cap()-before); err != nil {
+		return err
+	}
+	return b
+}
+
+// bStringBuiltin implements b_string(builder), returning the string written
+// to the builder so far.
+func bStringBuiltin(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	b, ok := args[0].(*object.Builder)
+	if !ok {
+		return newError("argument to `b_string` must be BUILDER, got %s", args[0].Type())
+	}
+	return allocate(&object.String{Value: b.String()})
+}
@@ -1,11 +1,24 @@
 package evaluator
 
 import (
-	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
 
 	"github.com/dr8co/monke/object"
 )
 
+// output is where `puts` prints.
+var output io.Writer = os.Stdout
+
+// SetOutput redirects the output of `puts` to w. It has no effect in builds
+// without `puts` (see puts.go).
+func SetOutput(w io.Writer) {
+	output = w
+}
+
 var builtins = map[string]*object.Builtin{
 	"len": {
 		Fn: func(args ...object.Object) object.Object {
@@ -14,11 +27,14 @@ var builtins = map[string]*object.Builtin{
 			}
 			switch arg := args[0].(type) {
 			case *object.String:
-				return &object.Integer{Value: int64(len(arg.Value))}
+				return &object.Integer{Value: int64(arg.CharLen())}
 
 			case *object.Array:
 				return &object.Integer{Value: int64(len(arg.Elements))}
 
+			case *object.Bytes:
+				return &object.Integer{Value: int64(len(arg.Value))}
+
 			default:
 				return newError("argument to `len` not supported, got %s", args[0].Type())
 			}
@@ -97,12 +113,58 @@ var builtins = map[string]*object.Builtin{
 			}
 		},
 	},
-	"puts": {
+	"upper": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			arg, ok := args[0].(*object.String)
+			if !ok {
+				return newError("argument to `upper` must be STRING, got %s", args[0].Type())
+			}
+			return &object.String{Value: strings.ToUpper(arg.Flat())}
+		},
+	},
+	"lower": {
 		Fn: func(args ...object.Object) object.Object {
-			for _, arg := range args {
-				fmt.Println(arg.Inspect())
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			arg, ok := args[0].(*object.String)
+			if !ok {
+				return newError("argument to `lower` must be STRING, got %s", args[0].Type())
 			}
-			return NULL
+			return &object.String{Value: strings.ToLower(arg.Flat())}
 		},
 	},
+	"is_null": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			return nativeBoolToBooleanObject(args[0] == NULL)
+		},
+	},
+	"is_empty": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			switch arg := args[0].(type) {
+			case *object.String:
+				return nativeBoolToBooleanObject(arg.Len() == 0)
+			case *object.Array:
+				return nativeBoolToBooleanObject(len(arg.Elements) == 0)
+			case *object.Hash:
+				return nativeBoolToBooleanObject(len(arg.Pairs) == 0)
+			default:
+				return newError("argument to `is_empty` not supported, got %s", args[0].Type())
+			}
+		},
+	},
+}
+
+// Builtins returns the names of the builtin functions, sorted.
+func Builtins() []string {
+	return slices.Sorted(maps.Keys(builtins))
 }
@@ -0,0 +1,84 @@
+package evaluator
+
+import (
+	"bytes"
+	"slices"
+
+	"github.com/dr8co/monke/object"
+)
+
+func init() {
+	builtins["bytes"] = &object.Builtin{Fn: bytesBuiltin}
+	builtins["bytes_string"] = &object.Builtin{Fn: bytesStringBuiltin}
+}
+
+// bytesBuiltin implements bytes(value), returning the bytes of a string, or
+// bytes holding the elements of an array of integers from 0 to 255. Bytes are
+// returned as they are.
+func bytesBuiltin(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	switch arg := args[0].(type) {
+	case *object.Bytes:
+		return arg
+	case *object.String:
+		return &object.Bytes{Value: []byte(arg.Flat())}
+	case *object.Array:
+		value := make([]byte, len(arg.Elements))
+		for i, el := range arg.Elements {
+			n, ok := el.(*object.Integer)
+			if !ok || n.Value < 0 || n.Value > 255 {
+				return newError("element %d of the argument to `bytes` must be an INTEGER from 0 to 255, got %s",
+					i, el.Inspect())
+			}
+			value[i] = byte(n.Value)
+		}
+		return &object.Bytes{Value: value}
+	default:
+		return newError("argument to `bytes` must be STRING or ARRAY, got %s", args[0].Type())
+	}
+}
+
+// bytesStringBuiltin implements bytes_string(bytes), returning a string of
+// the bytes. Strings hold any bytes, so the bytes need not be valid UTF-8.
+func bytesStringBuiltin(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	b, ok := args[0].(*object.Bytes)
+	if !ok {
+		return newError("argument to `bytes_string` must be BYTES, got %s", args[0].Type())
+	}
+	return getStringObject(string(b.Value))
+}
+
+// evalBytesIndexExpression returns the byte of b at index as an integer, or
+// null if the index is out of range, as it is for arrays.
+func evalBytesIndexExpression(b, index object.Object) object.Object {
+	value := b.(*object.Bytes).Value
+	idx := index.(*object.Integer).Value
+
+	if idx < 0 || idx >= int64(len(value)) {
+		return NULL
+	}
+	return getIntegerObject(int64(value[idx]))
+}
+
+// evalBytesInfixExpression compares bytes by their contents, or concatenates
+// them into new bytes.
+func evalBytesInfixExpression(operator string, left, right object.Object) object.Object {
+	l, r := left.(*object.Bytes).Value, right.(*object.Bytes).Value
+
+	switch operator {
+	case "+":
+		return allocate(&object.Bytes{Value: slices.Concat(l, r)})
+	case "==":
+		return nativeBoolToBooleanObject(bytes.Equal(l, r))
+	case "!=":
+		return nativeBoolToBooleanObject(!bytes.Equal(l, r))
+	default:
+		return newError("unknown operator: %s %s %s",
+			left.Type(), operator, right.Type())
+	}
+}
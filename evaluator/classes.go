@@ -0,0 +1,64 @@
+package evaluator
+
+import (
+	"github.com/dr8co/monke/ast"
+	"github.com/dr8co/monke/object"
+	"github.com/dr8co/monke/token"
+)
+
+// evalClassStatement binds the class defined by node in env. Its methods
+// close over env, as function literals evaluated there do, and take the
+// instance they are called on as a first parameter named self.
+func evalClassStatement(node *ast.ClassStatement, env *object.Environment) object.Object {
+	if env.Strict() && env.Defined(node.Name.Value) {
+		return newError("identifier already declared: %s", node.Name.Value)
+	}
+	class := &object.Class{
+		Name:    node.Name.Value,
+		Fields:  node.Fields,
+		Methods: make(map[string]*object.Function, len(node.Methods)),
+	}
+	for _, method := range node.Methods {
+		self := &ast.Identifier{
+			Token: token.Token{Type: token.IDENT, Literal: "self", Position: method.Name.Pos()},
+			Value: "self",
+		}
+		class.Methods[method.Name.Value] = &object.Function{
+			Name:       class.Name + "." + method.Name.Value,
+			Parameters: append([]*ast.Identifier{self}, method.Function.Parameters...),
+			Rest:       method.Function.Rest,
+			Body:       method.Function.Body,
+			Env:        env,
+		}
+	}
+	env.Set(node.Name.Value, class)
+	return nil
+}
+
+// construct makes an instance of class, a hash tagged with its name that
+// holds args as the values of its fields, in the order they are declared.
+func construct(class *object.Class, args []object.Object) object.Object {
+	if len(args) != len(class.Fields) {
+		return newError("wrong number of arguments to %s. got=%d, want=%d", class.Name, len(args), len(class.Fields))
+	}
+	pairs := make(map[object.HashKey]object.HashPair, len(args))
+	for i, field := range class.Fields {
+		key := getStringObject(field.Value)
+		pairs[key.HashKey()] = object.HashPair{Key: key, Value: args[i]}
+	}
+	return allocate(&object.Hash{Pairs: pairs, Tag: class.Name, Class: class})
+}
+
+// classMethod returns the method name of the class recv is an instance of,
+// or nil if recv has no such method.
+func classMethod(recv object.Object, name string) object.Object {
+	hash, ok := recv.(*object.Hash)
+	if !ok || hash.Class == nil {
+		return nil
+	}
+	method, ok := hash.Class.Methods[name]
+	if !ok {
+		return nil
+	}
+	return method
+}
@@ -0,0 +1,44 @@
+package evaluator
+
+import (
+	"math/rand/v2"
+	"time"
+
+	"github.com/dr8co/monke/object"
+)
+
+// now returns the time used to measure durations, such as the time spent in builtins.
+var now = time.Now
+
+// Now returns the current time as the evaluator sees it. Tracers measure
+// durations with it, so that they stand still in deterministic runs too.
+// Reading it is not an input of the program, so it is not recorded in traces.
+func Now() time.Time {
+	return now()
+}
+
+// timeInput passes on each duration the program reads, such as the time_ns
+// of runtime_stats.
+var timeInput = func(d time.Duration) time.Duration { return d }
+
+// SetTimeInput replaces the function every duration the program reads goes
+// through; nil restores the measured durations. Recording and replaying a run
+// go through it.
+func SetTimeInput(input func(time.Duration) time.Duration) {
+	if input == nil {
+		input = func(d time.Duration) time.Duration { return d }
+	}
+	timeInput = input
+}
+
+// SetDeterministic removes the sources of nondeterminism from the following
+// runs, so that a program prints the same output byte for byte every time:
+// random numbers are drawn from a source seeded with seed, hashes print their
+// pairs sorted by key, and the clock is stopped, so measured durations are 0.
+// It is meant to be called before evaluation starts.
+func SetDeterministic(seed uint64) {
+	SetRandSource(rand.NewPCG(seed, seed))
+	object.SetSortedHashes(true)
+	stopped := time.Unix(0, 0).UTC()
+	now = func() time.Time { return stopped }
+}
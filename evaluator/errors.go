@@ -0,0 +1,52 @@
+package evaluator
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dr8co/monke/object"
+)
+
+// The causes of the runtime errors raised by the limits of the evaluator,
+// which hosts can tell apart from the errors of programs with errors.Is.
+var (
+	ErrInterrupted = errors.New("interrupted")
+	ErrStepLimit   = errors.New("step limit exceeded")
+	ErrDepthLimit  = errors.New("call depth limit exceeded")
+	ErrMemoryLimit = errors.New("memory limit exceeded")
+)
+
+// RuntimeError is a runtime error of a program as a Go error, for hosts that
+// handle failures with the errors package rather than by the type of a value.
+// It unwraps to the cause of the error, if it has one. The *interp.RuntimeError
+// of an interpreter wraps it, so errors.As finds it in those as well.
+type RuntimeError struct {
+	Err *object.Error
+}
+
+// Error returns the message of the error, after its position if it is known.
+func (e *RuntimeError) Error() string {
+	if e.Err.Pos.Line == 0 {
+		return e.Err.Message
+	}
+	return e.Err.Pos.String() + ": " + e.Err.Message
+}
+
+// Unwrap returns the cause of the error, or nil.
+func (e *RuntimeError) Unwrap() error {
+	return e.Err.Cause
+}
+
+// AsError returns obj, the result of an evaluation, as a *RuntimeError if it
+// is an error, or nil otherwise.
+func AsError(obj object.Object) error {
+	if err, ok := obj.(*object.Error); ok {
+		return &RuntimeError{Err: err}
+	}
+	return nil
+}
+
+// wrapError returns an error with a formatted message, caused by cause.
+func wrapError(cause error, format string, a ...any) *object.Error {
+	return &object.Error{Message: fmt.Sprintf(format, a...), Cause: cause}
+}
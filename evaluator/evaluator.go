@@ -19,7 +19,11 @@
 package evaluator
 
 import (
+	"errors"
 	"fmt"
+	"maps"
+	"math"
+	"slices"
 
 	"github.com/dr8co/monke/ast"
 	"github.com/dr8co/monke/object"
@@ -79,12 +83,42 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return &object.ReturnValue{Value: val}
 
 	case *ast.LetStatement:
+		if node.Destructures() {
+			return evalDestructuringLet(node, env)
+		}
+		if env.Strict() && env.Defined(node.Name.Value) {
+			return newError("identifier already declared: %s", node.Name.Value)
+		}
 		val := Eval(node.Value, env)
 		if isError(val) {
 			return val
 		}
+		// Name function literals after their binding; "let g = f" keeps the name of f
+		if _, isLiteral := node.Value.(*ast.FunctionLiteral); isLiteral {
+			if fn, ok := val.(*object.Function); ok {
+				fn.Name = node.Name.Value
+			}
+		}
 		env.Set(node.Name.Value, val)
 
+	case *ast.AssignStatement:
+		val := Eval(node.Value, env)
+		if isError(val) {
+			return val
+		}
+		if !env.Assign(node.Name.Value, val) {
+			return newError("cannot assign to undeclared identifier: %s", node.Name.Value)
+		}
+
+	case *ast.DeferStatement:
+		env.Defer(node.Body)
+
+	case *ast.ImportStatement:
+		return evalImportStatement(node, env)
+
+	case *ast.ClassStatement:
+		return evalClassStatement(node, env)
+
 	// Expressions
 	case *ast.IntegerLiteral:
 		// Use cached integer if available
@@ -93,6 +127,9 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		}
 		return &object.Integer{Value: node.Value}
 
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
+
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
 
@@ -100,27 +137,56 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return getStringObject(node.Value)
 
 	case *ast.PrefixExpression:
+		m := memo(&node.Cache, node)
+		if val := m.lookup(env); val != nil {
+			return val
+		}
 		right := Eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
-		return evalPrefixExpression(node.Operator, right)
+		return m.keep(evalPrefixExpression(node.Operator, right, env.Strict()), env)
 
 	case *ast.InfixExpression:
+		m := memo(&node.Cache, node)
+		if val := m.lookup(env); val != nil {
+			return val
+		}
 		left := Eval(node.Left, env)
 		if isError(left) {
 			return left
 		}
+		if node.Operator == "??" {
+			// Only evaluate the fallback if it is needed
+			if left != NULL {
+				return left
+			}
+			return Eval(node.Right, env)
+		}
 
 		right := Eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
 
-		return evalInfixExpression(node.Operator, left, right)
+		return m.keep(evalInfixExpression(node.Operator, left, right, env.Strict()), env)
 
 	case *ast.IfExpression:
 		return evalIfExpression(node, env)
+	case *ast.ConditionalExpression:
+		return evalConditionalExpression(node, env)
+
+	case *ast.WhileExpression:
+		return evalWhileExpression(node, env)
+
+	case *ast.ForExpression:
+		return evalForExpression(node, env)
+
+	case *ast.ForInExpression:
+		return evalForInExpression(node, env)
+
+	case *ast.MatchExpression:
+		return evalMatchExpression(node, env)
 
 	case *ast.Identifier:
 		return evalIdentifier(node, env)
@@ -128,13 +194,21 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &object.Function{Parameters: params, Env: env, Body: body}
+		return &object.Function{Parameters: params, Rest: node.Rest, Env: env, Body: body}
 
 	case *ast.CallExpression:
+		if field, ok := node.Function.(*ast.IndexExpression); ok {
+			if name, ok := field.Field(); ok {
+				return evalMethodCall(node, field, name, env)
+			}
+		}
 		function := Eval(node.Function, env)
 		if isError(function) {
 			return function
 		}
+		if hasNamedArguments(node.Arguments) {
+			return evalNamedCall(function, node.Arguments, env)
+		}
 
 		args := evalExpressions(node.Arguments, env)
 		if len(args) == 1 && isError(args[0]) {
@@ -147,27 +221,103 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if len(elements) == 1 && isError(elements[0]) {
 			return elements[0]
 		}
-		return &object.Array{Elements: elements}
+		return allocate(&object.Array{Elements: elements})
 
 	case *ast.IndexExpression:
 		left := Eval(node.Left, env)
 		if isError(left) {
 			return left
 		}
+		if node.Optional && left == NULL {
+			return NULL
+		}
 
 		index := Eval(node.Index, env)
 		if isError(index) {
 			return index
 		}
-		return evalIndexExpression(left, index)
+		return evalIndexExpression(left, index, node.Index)
+
+	case *ast.SliceExpression:
+		return evalSliceExpression(node, env)
 
 	case *ast.HashLiteral:
-		return evalHashLiteral(node, env)
+		hash := evalHashLiteral(node, env)
+		if isError(hash) {
+			return hash
+		}
+		return allocate(hash)
 	}
 
 	return nil
 }
 
+// evalDestructuringLet binds the names of ls to the elements of the array its
+// value evaluates to. Without a pattern, the array must have as many elements
+// as there are names; with an array pattern, it must match the pattern. A hash
+// pattern binds the values of its keys in a hash, or null for keys the hash
+// does not have, which are errors in strict mode.
+func evalDestructuringLet(ls *ast.LetStatement, env *object.Environment) object.Object {
+	if env.Strict() {
+		for _, name := range ls.Identifiers() {
+			if env.Defined(name.Value) {
+				return newError("identifier already declared: %s", name.Value)
+			}
+		}
+	}
+	val := Eval(ls.Value, env)
+	if isError(val) {
+		return val
+	}
+	if pattern, ok := ls.Pattern.(*ast.HashPattern); ok {
+		if _, ok := val.(*object.Hash); !ok {
+			return newError("cannot destructure %s: not a hash", val.Type())
+		}
+		return bindPattern(pattern, val, env)
+	}
+	array, ok := val.(*object.Array)
+	if !ok {
+		return newError("cannot destructure %s: not an array", val.Type())
+	}
+	if pattern, ok := ls.Pattern.(*ast.ArrayPattern); ok {
+		switch got, want := len(array.Elements), len(pattern.Elements); {
+		case pattern.Rest == nil && got != want:
+			return newError("wrong number of values to destructure. got=%d, want=%d", got, want)
+		case got < want:
+			return newError("wrong number of values to destructure. got=%d, want at least %d", got, want)
+		}
+		return bindPattern(pattern, array, env)
+	}
+	if len(array.Elements) != len(ls.Names) {
+		return newError("wrong number of values to destructure. got=%d, want=%d", len(array.Elements), len(ls.Names))
+	}
+	for i, name := range ls.Names {
+		if name.Value != "_" {
+			env.Set(name.Value, array.Elements[i])
+		}
+	}
+	return nil
+}
+
+// bindPattern binds the names of pattern in env to the parts of val it
+// matches, failing if it does not match as match expressions would skip it.
+// Unlike in match expressions, the keys of hash patterns missing from a hash
+// match null.
+func bindPattern(pattern ast.Pattern, val object.Object, env *object.Environment) object.Object {
+	m := matcher{env: env, missingKeys: true}
+	matched := m.match(pattern, val)
+	if m.err != nil {
+		return m.err
+	}
+	if !matched {
+		return newError("cannot destructure %s: does not match %s", val.Inspect(), pattern.String())
+	}
+	for _, b := range m.bindings {
+		env.Set(b.name, b.value)
+	}
+	return nil
+}
+
 func newError(format string, a ...any) *object.Error {
 	return &object.Error{Message: fmt.Sprintf(format, a...)}
 }
@@ -179,12 +329,16 @@ func isError(obj object.Object) bool {
 	return false
 }
 
-func evalIndexExpression(left, index object.Object) object.Object {
+func evalIndexExpression(left, index object.Object, indexNode ast.Expression) object.Object {
 	switch {
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
 		return evalArrayIndexExpression(left, index)
+	case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalStringIndexExpression(left, index)
+	case left.Type() == object.BYTES_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalBytesIndexExpression(left, index)
 	case left.Type() == object.HASH_OBJ:
-		return evalHashIndexExpression(left, index)
+		return evalHashIndexExpression(left, index, indexNode)
 	default:
 		return newError("index operator not supported: %s", left.Type())
 	}
@@ -202,12 +356,26 @@ func evalArrayIndexExpression(array, index object.Object) object.Object {
 	return arrayObject.Elements[idx]
 }
 
-func evalHashIndexExpression(hash, index object.Object) object.Object {
+// evalStringIndexExpression returns the character of str at index as a string
+// of its own. It counts UTF-8 encoded characters, not bytes, so every result is
+// one whole character; a byte that is not valid UTF-8 counts as a character.
+// An index out of range gives null, as it does for arrays.
+func evalStringIndexExpression(str, index object.Object) object.Object {
+	value := str.(*object.String)
+	idx := index.(*object.Integer).Value
+
+	if idx < 0 || idx >= int64(value.CharLen()) {
+		return NULL
+	}
+	return getStringObject(value.CharAt(int(idx)))
+}
+
+func evalHashIndexExpression(hash, index object.Object, indexNode ast.Expression) object.Object {
 	hashObject := hash.(*object.Hash)
 
 	key, ok := index.(object.Hashable)
 	if !ok {
-		return newError("unusable as hash key: %s", index.Type())
+		return unusableKeyError(index, indexNode)
 	}
 
 	pair, ok := hashObject.Pairs[key.HashKey()]
@@ -223,6 +391,19 @@ func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Ob
 	result := make([]object.Object, 0, len(exps))
 
 	for _, e := range exps {
+		if spread, ok := e.(*ast.SpreadElement); ok {
+			evaluated := Eval(spread.Value, env)
+			if isError(evaluated) {
+				return []object.Object{evaluated}
+			}
+			arr, ok := evaluated.(*object.Array)
+			if !ok {
+				return []object.Object{spreadError(evaluated, spread, object.ARRAY_OBJ)}
+			}
+			result = append(result, arr.Elements...)
+			continue
+		}
+
 		evaluated := Eval(e, env)
 		if isError(evaluated) {
 			return []object.Object{evaluated}
@@ -232,28 +413,80 @@ func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Ob
 	return result
 }
 
+// spreadError reports that a spread element produced a value of the wrong type.
+func spreadError(value object.Object, spread *ast.SpreadElement, want object.Type) *object.Error {
+	err := newError("cannot spread %s from `%s`, want %s", value.Type(), spread.Value.String(), want)
+	err.Pos = spread.Pos()
+	return err
+}
+
+// Call applies fn, a function or builtin, to args as a call expression does,
+// and returns its result. It lets hosts call back into a program, from a
+// builtin while the program runs or once it has finished.
+func Call(fn object.Object, args ...object.Object) object.Object {
+	return applyFunction(fn, args)
+}
+
 func applyFunction(fn object.Object, args []object.Object) object.Object {
 	switch fn := fn.(type) {
 	case *object.Function:
-		extendedEnv := extendFunctionEnv(fn, args)
+		if fn.Env.Strict() && fn.Rest && len(args) < len(fn.Parameters)-1 {
+			return newError("wrong number of arguments. got=%d, want at least %d", len(args), len(fn.Parameters)-1)
+		}
+		if fn.Env.Strict() && !fn.Rest && len(args) != len(fn.Parameters) {
+			return newError("wrong number of arguments. got=%d, want=%d", len(args), len(fn.Parameters))
+		}
+		if depthLimit > 0 && len(calls) >= depthLimit {
+			return wrapError(ErrDepthLimit, "call depth limit exceeded: more than %d nested calls", depthLimit)
+		}
+		pushCall(fn)
+		defer popCall()
+		countCall(fn)
+
+		extendedEnv, err := extendFunctionEnv(fn, args)
+		if err != nil {
+			return err
+		}
+		pushFrame(extendedEnv)
+		defer popFrame()
 		evaluated := Eval(fn.Body, extendedEnv)
-		return unwrapReturnValue(evaluated)
+		return unwrapReturnValue(runDeferred(extendedEnv, evaluated))
 
 	case *object.Builtin:
-		return fn.Fn(args...)
+		return callBuiltin(fn, args)
+
+	case *object.Class:
+		return construct(fn, args)
 
 	default:
 		return newError("not a function: %s", fn.Type())
 	}
 }
 
-func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
+// extendFunctionEnv returns the environment of a call of fn, binding its
+// parameters to args. Parameters left without an argument, which only legacy
+// mode allows, are bound to null. A rest parameter is bound to a new array of the
+// arguments left after the other parameters, which may be empty.
+func extendFunctionEnv(fn *object.Function, args []object.Object) (*object.Environment, object.Object) {
 	env := object.NewEnclosedEnvironment(fn.Env)
 
-	for paramIdx, param := range fn.Parameters {
-		env.Set(param.Value, args[paramIdx])
+	params := fn.Parameters
+	if fn.Rest {
+		params = params[:len(params)-1]
+		rest := allocate(&object.Array{Elements: slices.Clone(args[min(len(params), len(args)):])})
+		if isError(rest) {
+			return nil, rest
+		}
+		env.Set(fn.Parameters[len(params)].Value, rest)
+	}
+	for paramIdx, param := range params {
+		if paramIdx < len(args) {
+			env.Set(param.Value, args[paramIdx])
+		} else {
+			env.Set(param.Value, NULL)
+		}
 	}
-	return env
+	return env, nil
 }
 
 func unwrapReturnValue(obj object.Object) object.Object {
@@ -263,12 +496,28 @@ func unwrapReturnValue(obj object.Object) object.Object {
 	return obj
 }
 
+// identCache is the inline cache attached to an *ast.Identifier after its first lookup.
+type identCache struct {
+	lookup  object.LookupCache
+	builtin *object.Builtin
+}
+
 func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
-	if val, ok := env.Get(node.Value); ok {
+	c, ok := node.Cache.(*identCache)
+	if !ok {
+		c = &identCache{}
+		node.Cache = c
+	}
+
+	if val, ok := env.GetCached(node.Value, &c.lookup); ok {
 		return val
 	}
 
+	if c.builtin != nil {
+		return c.builtin
+	}
 	if builtin, ok := builtins[node.Value]; ok {
+		c.builtin = builtin
 		return builtin
 	}
 
@@ -279,11 +528,15 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 	var result object.Object
 
 	for _, statement := range block.Statements {
-		result = Eval(statement, env)
+		result = evalStatement(statement, env)
 
 		if result != nil {
 			rt := result.Type()
-			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+			if rt == object.ERROR_OBJ {
+				setErrorPos(result.(*object.Error), statement)
+				return result
+			}
+			if rt == object.RETURN_VALUE_OBJ {
 				return result
 			}
 		}
@@ -292,36 +545,226 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 }
 
 func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
-	condition := Eval(ie.Condition, env)
+	condition := evalCondition(ie.Condition, env)
+	if isError(condition) {
+		return condition
+	}
+	if isTruthy(condition, env.Strict()) {
+		return evalScopedBlock(ie.Consequence, env)
+	}
+	switch alt := ie.Alternative.(type) {
+	case *ast.BlockStatement:
+		return evalScopedBlock(alt, env)
+	case nil:
+		return NULL
+	default:
+		return Eval(alt, env)
+	}
+}
+
+// evalScopedBlock evaluates the block of an if expression or a deferred
+// block in a scope of its own, so the names it binds with let do not outlive
+// it and may shadow those of the enclosing scopes.
+func evalScopedBlock(block *ast.BlockStatement, env *object.Environment) object.Object {
+	blockEnv := object.NewBlockEnvironment(env)
+	pushFrame(blockEnv)
+	defer popFrame()
+	return Eval(block, blockEnv)
+}
+
+// evalConditionalExpression evaluates only the branch selected by the condition.
+func evalConditionalExpression(ce *ast.ConditionalExpression, env *object.Environment) object.Object {
+	condition := evalCondition(ce.Condition, env)
 	if isError(condition) {
 		return condition
 	}
-	if isTruthy(condition) {
-		return Eval(ie.Consequence, env)
+	if isTruthy(condition, env.Strict()) {
+		return Eval(ce.Consequence, env)
+	}
+	return Eval(ce.Alternative, env)
+}
+
+// evalCondition evaluates the condition of an if, while or conditional expression.
+// In strict mode, conditions that are not booleans produce a warning.
+func evalCondition(cond ast.Expression, env *object.Environment) object.Object {
+	condition := Eval(cond, env)
+	if !isError(condition) && env.Strict() && condition.Type() != object.BOOLEAN_OBJ {
+		warn(cond.Pos(), "non-boolean condition of type %s; use !!x to convert it explicitly", condition.Type())
+	}
+	return condition
+}
+
+// evalLoopBody evaluates one iteration of the body of a loop in its own scope.
+func evalLoopBody(body *ast.BlockStatement, env *object.Environment) object.Object {
+	if err := step(); err != nil {
+		return err
+	}
+	pushFrame(env)
+	defer popFrame()
+	return Eval(body, env)
+}
+
+// evalWhileExpression runs the body of a while loop until its condition is falsy.
+// Each iteration gets a fresh scope. The loop itself evaluates to null, unless
+// the body returns or fails.
+func evalWhileExpression(we *ast.WhileExpression, env *object.Environment) object.Object {
+	for {
+		condition := evalCondition(we.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+		if !isTruthy(condition, env.Strict()) {
+			return NULL
+		}
+		result := evalLoopBody(we.Body, object.NewBlockEnvironment(env))
+		if result != nil {
+			rt := result.Type()
+			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+				return result
+			}
+		}
 	}
-	if ie.Alternative != nil {
-		return Eval(ie.Alternative, env)
+}
+
+// evalForExpression runs a C-style for loop. The init statement runs in a scope
+// of its own, so loop variables are not visible after the loop, and each
+// iteration of the body gets a fresh scope inside it, as in while loops.
+func evalForExpression(fe *ast.ForExpression, env *object.Environment) object.Object {
+	loopEnv := object.NewBlockEnvironment(env)
+	pushFrame(loopEnv)
+	defer popFrame()
+	if fe.Init != nil {
+		if result := Eval(fe.Init, loopEnv); isError(result) {
+			return result
+		}
+	}
+	for {
+		if fe.Condition != nil {
+			condition := evalCondition(fe.Condition, loopEnv)
+			if isError(condition) {
+				return condition
+			}
+			if !isTruthy(condition, loopEnv.Strict()) {
+				return NULL
+			}
+		}
+		result := evalLoopBody(fe.Body, object.NewBlockEnvironment(loopEnv))
+		if result != nil {
+			rt := result.Type()
+			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+				return result
+			}
+		}
+		if fe.Post != nil {
+			if result := Eval(fe.Post, loopEnv); isError(result) {
+				return result
+			}
+		}
+	}
+}
+
+// evalForInExpression runs a loop over the elements of an array, the bytes of
+// bytes as integers, or the pairs of a hash, in the order of their keys. Each iteration gets a fresh scope binding
+// the loop names, so closures created in the body keep their own values.
+func evalForInExpression(fi *ast.ForInExpression, env *object.Environment) object.Object {
+	iterable := Eval(fi.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+
+	var keys, values []object.Object
+	switch iterable := iterable.(type) {
+	case *object.Array:
+		values = iterable.Elements
+		if fi.Key != nil {
+			keys = make([]object.Object, len(values))
+			for i := range values {
+				keys[i] = getIntegerObject(int64(i))
+			}
+		}
+	case *object.Bytes:
+		values = make([]object.Object, len(iterable.Value))
+		for i, b := range iterable.Value {
+			values[i] = getIntegerObject(int64(b))
+		}
+		if fi.Key != nil {
+			keys = make([]object.Object, len(values))
+			for i := range values {
+				keys[i] = getIntegerObject(int64(i))
+			}
+		}
+	case *object.Hash:
+		for _, pair := range iterable.SortedPairs() {
+			keys = append(keys, pair.Key)
+			values = append(values, pair.Value)
+		}
+		if fi.Key == nil {
+			// A single name iterates over the keys
+			values = keys
+		}
+	default:
+		return newError("cannot iterate over %s in `%s`", iterable.Type(), fi.Iterable.String())
+	}
+
+	for i, value := range values {
+		iterEnv := object.NewBlockEnvironment(env)
+		if fi.Key != nil {
+			iterEnv.Set(fi.Key.Value, keys[i])
+		}
+		iterEnv.Set(fi.Value.Value, value)
+
+		result := evalLoopBody(fi.Body, iterEnv)
+		if result != nil {
+			rt := result.Type()
+			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+				return result
+			}
+		}
 	}
 	return NULL
 }
 
-func isTruthy(obj object.Object) bool {
-	switch obj {
-	case TRUE:
-		return true
-	case FALSE:
-		return false
-	case NULL:
+// isTruthy reports whether obj counts as true in a condition. In legacy mode
+// only false and null are falsy, as in the original language; in strict mode
+// 0, 0.0, "" and empty arrays and hashes are falsy too. Everything else is truthy.
+func isTruthy(obj object.Object, strict bool) bool {
+	switch obj := obj.(type) {
+	case *object.Boolean:
+		return obj.Value
+	case *object.Null:
 		return false
+	}
+	if !strict {
+		return true
+	}
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return obj.Value != 0
+	case *object.Float:
+		return obj.Value != 0
+	case *object.String:
+		return obj.Len() != 0
+	case *object.Array:
+		return len(obj.Elements) != 0
+	case *object.Hash:
+		return len(obj.Pairs) != 0
 	default:
 		return true
 	}
 }
 
-func evalInfixExpression(operator string, left, right object.Object) object.Object {
+// evalInfixExpression applies an infix operator to its operands.
+// If checked is set, integer overflow is an error (see checkedIntegerOp).
+func evalInfixExpression(operator string, left, right object.Object, checked bool) object.Object {
 	switch {
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
-		return evalIntegerInfixExpression(operator, left, right)
+		return evalIntegerInfixExpression(operator, left, right, checked)
+	case isNumber(left) && isNumber(right):
+		return evalFloatInfixExpression(operator, toFloat(left), toFloat(right))
+	case left.Type() == object.BYTES_OBJ && right.Type() == object.BYTES_OBJ:
+		return evalBytesInfixExpression(operator, left, right)
+	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
+		return evalStringInfixExpression(operator, left, right)
 	case operator == "==":
 		return nativeBoolToBooleanObject(left == right)
 	case operator == "!=":
@@ -329,8 +772,6 @@ func evalInfixExpression(operator string, left, right object.Object) object.Obje
 	case left.Type() != right.Type():
 		return newError("type mismatch: %s %s %s",
 			left.Type(), operator, right.Type())
-	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
-		return evalStringInfixExpression(operator, left, right)
 	default:
 		return newError("unknown operator: %s %s %s",
 			left.Type(), operator, right.Type())
@@ -345,10 +786,16 @@ func getIntegerObject(value int64) *object.Integer {
 	return &object.Integer{Value: value}
 }
 
-func evalIntegerInfixExpression(operator string, left, right object.Object) object.Object {
+func evalIntegerInfixExpression(operator string, left, right object.Object, checked bool) object.Object {
 	leftVal := left.(*object.Integer).Value
 	rightVal := right.(*object.Integer).Value
 
+	if checked {
+		if result := checkedIntegerOp(operator, leftVal, rightVal); result != nil {
+			return result
+		}
+	}
+
 	switch operator {
 	case "+":
 		return getIntegerObject(leftVal + rightVal)
@@ -357,64 +804,160 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	case "*":
 		return getIntegerObject(leftVal * rightVal)
 	case "/":
+		if rightVal == 0 {
+			return newError("division by zero: %d / %d", leftVal, rightVal)
+		}
 		return getIntegerObject(leftVal / rightVal)
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
 	case ">":
 		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
 	case "==":
 		return nativeBoolToBooleanObject(leftVal == rightVal)
 	case "!=":
 		return nativeBoolToBooleanObject(leftVal != rightVal)
+	case "..", "..=":
+		return evalRange(leftVal, rightVal, operator == "..=")
 	default:
 		return newError("unknown operator: %s %s %s",
 			left.Type(), operator, right.Type())
 	}
 }
 
-func evalPrefixExpression(operator string, right object.Object) object.Object {
+// isNumber reports whether obj is an integer or a float.
+func isNumber(obj object.Object) bool {
+	t := obj.Type()
+	return t == object.INTEGER_OBJ || t == object.FLOAT_OBJ
+}
+
+// toFloat converts a number to a float64.
+func toFloat(obj object.Object) float64 {
+	if i, ok := obj.(*object.Integer); ok {
+		return float64(i.Value)
+	}
+	return obj.(*object.Float).Value
+}
+
+// evalFloatInfixExpression applies an infix operator to two floats.
+// Integer operands of mixed arithmetic are converted to floats first.
+func evalFloatInfixExpression(operator string, leftVal, rightVal float64) object.Object {
+	switch operator {
+	case "+":
+		return &object.Float{Value: leftVal + rightVal}
+	case "-":
+		return &object.Float{Value: leftVal - rightVal}
+	case "*":
+		return &object.Float{Value: leftVal * rightVal}
+	case "/":
+		if rightVal == 0 {
+			return newError("division by zero: %s / %s",
+				(&object.Float{Value: leftVal}).Inspect(), (&object.Float{Value: rightVal}).Inspect())
+		}
+		return &object.Float{Value: leftVal / rightVal}
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return newError("unknown operator: %s %s %s", object.FLOAT_OBJ, operator, object.FLOAT_OBJ)
+	}
+}
+
+func evalPrefixExpression(operator string, right object.Object, checked bool) object.Object {
 	switch operator {
 	case "!":
-		return evalBangOperatorExpression(right)
+		return evalBangOperatorExpression(right, checked)
 	case "-":
-		return evalMinusPrefixOperatorExpression(right)
+		return evalMinusPrefixOperatorExpression(right, checked)
 	default:
 		return newError("unknown operator: %s%s", operator, right.Type())
 	}
 }
 
-func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
+func evalMinusPrefixOperatorExpression(right object.Object, checked bool) object.Object {
+	if f, ok := right.(*object.Float); ok {
+		return &object.Float{Value: -f.Value}
+	}
 	if right.Type() != object.INTEGER_OBJ {
 		return newError("unknown operator: -%s", right.Type())
 	}
 	value := right.(*object.Integer).Value
+	if checked && value == math.MinInt64 {
+		return newError("integer overflow: -(%d)", value)
+	}
 	return getIntegerObject(-value)
 }
 
-func evalBangOperatorExpression(right object.Object) object.Object {
-	switch right {
-	case TRUE:
-		return FALSE
-	case FALSE:
-		return TRUE
-	case NULL:
-		return TRUE
-	default:
-		return FALSE
-	}
+func evalBangOperatorExpression(right object.Object, strict bool) object.Object {
+	return nativeBoolToBooleanObject(!isTruthy(right, strict))
 }
 
 func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	var result object.Object
 
+	for _, pragma := range program.Pragmas {
+		env.SetStrict(pragma == "strict")
+	}
+	pushFrame(env)
+	defer popFrame()
+	defer forgetModules(prefetchModules(program))
+
 	for _, stmt := range program.Statements {
-		result = Eval(stmt, env)
+		result = evalStatement(stmt, env)
 
-		switch result := result.(type) {
-		case *object.ReturnValue:
-			return result.Value
-		case *object.Error:
-			return result
+		if rv, ok := result.(*object.ReturnValue); ok {
+			result = rv.Value
+			break
+		}
+		if isError(result) {
+			setErrorPos(result.(*object.Error), stmt)
+			break
+		}
+	}
+	result = runDeferred(env, result)
+	if err, ok := result.(*object.Error); ok && errors.Is(err.Cause, ErrInterrupted) {
+		_ = CloseResources()
+	}
+	return result
+}
+
+// unusableKeyError reports that key, produced by the expression node, cannot be used as a hash key.
+// The error points at the key expression rather than the enclosing statement.
+func unusableKeyError(key object.Object, node ast.Expression) *object.Error {
+	err := newError("unusable as hash key: %s from `%s`; hash keys must be STRING, INTEGER or BOOLEAN",
+		key.Type(), node.String())
+	err.Pos = node.Pos()
+	return err
+}
+
+// setErrorPos records the position of stmt on err, unless the error was
+// raised by a statement nested inside it and so already has a position.
+func setErrorPos(err *object.Error, stmt ast.Statement) {
+	if err.Pos.Line == 0 {
+		err.Pos = stmt.Pos()
+	}
+}
+
+// runDeferred evaluates the blocks deferred in env, most recent first.
+// An error raised by a deferred block replaces result, unless result is an error already.
+func runDeferred(env *object.Environment, result object.Object) object.Object {
+	for blocks := env.TakeDeferred(); len(blocks) > 0; blocks = env.TakeDeferred() {
+		for i := len(blocks) - 1; i >= 0; i-- {
+			if val := evalScopedBlock(blocks[i].Body, blocks[i].Env); isError(val) && !isError(result) {
+				result = val
+			}
 		}
 	}
 	return result
@@ -446,40 +989,93 @@ func getStringObject(value string) *object.String {
 	return &object.String{Value: value}
 }
 
+// evalStringInfixExpression concatenates strings with "+" and compares them
+// byte-wise with the comparison operators.
 func evalStringInfixExpression(operator string, left, right object.Object) object.Object {
-	if operator != "+" {
+	l, r := left.(*object.String), right.(*object.String)
+
+	switch operator {
+	case "+":
+		// Long strings are joined without copying them, so that appending
+		// to a string in a loop does not copy it on every iteration
+		if l.Len()+r.Len() >= object.RopeThreshold {
+			return allocate(object.Concat(l, r))
+		}
+		return allocate(getStringObject(l.Flat() + r.Flat()))
+	case "<":
+		return nativeBoolToBooleanObject(l.Flat() < r.Flat())
+	case ">":
+		return nativeBoolToBooleanObject(l.Flat() > r.Flat())
+	case "<=":
+		return nativeBoolToBooleanObject(l.Flat() <= r.Flat())
+	case ">=":
+		return nativeBoolToBooleanObject(l.Flat() >= r.Flat())
+	case "==":
+		// Long strings and ropes are not interned, so they are compared by content
+		return nativeBoolToBooleanObject(l == r || l.Flat() == r.Flat())
+	case "!=":
+		return nativeBoolToBooleanObject(l != r && l.Flat() != r.Flat())
+	default:
 		return newError("unknown operator: %s %s %s",
 			left.Type(), operator, right.Type())
 	}
-	leftVal := left.(*object.String).Value
-	rightVal := right.(*object.String).Value
-
-	return getStringObject(leftVal + rightVal)
 }
 
 func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
 	// Pre-allocate the map with the expected size to avoid resizing
 	pairs := make(map[object.HashKey]object.HashPair, len(node.Pairs))
 
-	for keyNode, valueNode := range node.Pairs {
-		key := Eval(keyNode, env)
-		if isError(key) {
-			return key
+	// Literals built by the parser record their source order, so that later
+	// pairs and spreads override earlier ones
+	if node.Order != nil {
+		for _, keyNode := range node.Order {
+			if spread, ok := keyNode.(*ast.SpreadElement); ok {
+				val := Eval(spread.Value, env)
+				if isError(val) {
+					return val
+				}
+				hash, ok := val.(*object.Hash)
+				if !ok {
+					return spreadError(val, spread, object.HASH_OBJ)
+				}
+				maps.Copy(pairs, hash.Pairs)
+				continue
+			}
+			if err := evalHashPair(pairs, keyNode, node.Pairs[keyNode], env); err != nil {
+				return err
+			}
 		}
+		return &object.Hash{Pairs: pairs}
+	}
 
-		hashKey, ok := key.(object.Hashable)
-		if !ok {
-			return newError("unusable as hash key: %s", key.Type())
+	for keyNode, valueNode := range node.Pairs {
+		if err := evalHashPair(pairs, keyNode, valueNode, env); err != nil {
+			return err
 		}
+	}
 
-		value := Eval(valueNode, env)
-		if isError(value) {
-			return value
-		}
+	return &object.Hash{Pairs: pairs}
+}
 
-		hashed := hashKey.HashKey()
-		pairs[hashed] = object.HashPair{Key: key, Value: value}
+// evalHashPair evaluates a key-value pair of a hash literal into pairs.
+// It returns an error object if evaluation fails, or nil.
+func evalHashPair(pairs map[object.HashKey]object.HashPair, keyNode, valueNode ast.Expression, env *object.Environment) object.Object {
+	key := Eval(keyNode, env)
+	if isError(key) {
+		return key
 	}
 
-	return &object.Hash{Pairs: pairs}
+	hashKey, ok := key.(object.Hashable)
+	if !ok {
+		return unusableKeyError(key, keyNode)
+	}
+
+	value := Eval(valueNode, env)
+	if isError(value) {
+		return value
+	}
+
+	hashed := hashKey.HashKey()
+	pairs[hashed] = object.HashPair{Key: key, Value: value}
+	return nil
 }
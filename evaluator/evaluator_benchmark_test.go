@@ -1,6 +1,7 @@
 package evaluator
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/dr8co/monke/lexer"
@@ -132,3 +133,55 @@ func BenchmarkComplexExpression(b *testing.B) {
 	`
 	benchmarkEval(input, b)
 }
+
+// BenchmarkConstantSubexpressions measures a loop whose body repeats constant subexpressions
+func BenchmarkConstantSubexpressions(b *testing.B) {
+	input := `
+	let seconds = 0;
+	for (let day = 0; day < 100; day = day + 1) {
+		seconds = seconds + 24 * 60 * 60 - (2 * 60 + 30);
+	}
+	seconds;
+	`
+	benchmarkEval(input, b)
+}
+
+// BenchmarkStringConcatenationLoop measures building a long string with s = s + piece,
+// which makes a rope once the string is long
+func BenchmarkStringConcatenationLoop(b *testing.B) {
+	input := `
+	let s = "";
+	for (let i = 0; i < 2000; i = i + 1) {
+		s = s + "line of text\n";
+	}
+	len(s);
+	`
+	benchmarkEval(input, b)
+}
+
+// BenchmarkStringBuilder measures building the same string as
+// BenchmarkStringConcatenationLoop with a string builder
+func BenchmarkStringBuilder(b *testing.B) {
+	input := `
+	let sb = builder();
+	for (let i = 0; i < 2000; i = i + 1) {
+		b_write(sb, "line of text\n");
+	}
+	len(b_string(sb));
+	`
+	benchmarkEval(input, b)
+}
+
+// BenchmarkStringIndexLoop measures indexing every character of a string
+// that is not ASCII, which must not take quadratic time
+func BenchmarkStringIndexLoop(b *testing.B) {
+	input := `
+	let s = "` + strings.Repeat("héllo wörld ", 500) + `";
+	let n = 0;
+	for (let i = 0; i < len(s); i = i + 1) {
+		if (s[i] == "ö") { n = n + 1 }
+	}
+	n;
+	`
+	benchmarkEval(input, b)
+}
@@ -1,11 +1,22 @@
 package evaluator
 
 import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/dr8co/monke/ast"
 	"github.com/dr8co/monke/lexer"
+	"github.com/dr8co/monke/modules"
 	"github.com/dr8co/monke/object"
 	"github.com/dr8co/monke/parser"
+	"github.com/dr8co/monke/token"
+	"github.com/dr8co/monke/version"
 )
 
 func TestEvalIntegerExpression(t *testing.T) {
@@ -36,6 +47,52 @@ func TestEvalIntegerExpression(t *testing.T) {
 	}
 }
 
+func TestEvalFloatExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any // float64, bool, or an error message
+	}{
+		{"3.14 * 2", 6.28},
+		{"-1.5", -1.5},
+		{"1 / 2.0", 0.5},
+		{"2.5 + 1", 3.5},
+		{"10 - 0.5 * 4", 8.0},
+		{"7.0 / 2", 3.5},
+		{"1 < 1.5", true},
+		{"2.0 > 3", false},
+		{"1 == 1.0", true},
+		{"0.5 != 0.5", false},
+		{"1.0 / 0", "division by zero: 1.0 / 0.0"},
+		{`1.5 + "a"`, "type mismatch: FLOAT + STRING"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case float64:
+			result, ok := evaluated.(*object.Float)
+			if !ok {
+				t.Errorf("%q: object is not Float. got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if math.Abs(result.Value-expected) > 1e-9 {
+				t.Errorf("%q: object has wrong value. got=%g, want=%g", tt.input, result.Value, expected)
+			}
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("%q: no error object returned. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("%q: wrong error message. expected=%q, got=%q", tt.input, expected, errObj.Message)
+			}
+		}
+	}
+}
+
 func TestEvalBooleanExpression(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -60,6 +117,35 @@ func TestEvalBooleanExpression(t *testing.T) {
 		{"(1 < 2) == false", false},
 		{"(1 > 2) == true", false},
 		{"(1 > 2) == false", true},
+		{"1 <= 2", true},
+		{"1 <= 1", true},
+		{"2 <= 1", false},
+		{"1 >= 2", false},
+		{"1 >= 1", true},
+		{"2 >= 1", true},
+		{"1.5 <= 1", false},
+		{"1 >= 0.5", true},
+		{`"abc" <= "abd"`, true},
+		{`"abc" <= "abc"`, true},
+		{`"b" <= "abc"`, false},
+		{`"abc" < "abd"`, true},
+		{`"abc" < "abc"`, false},
+		{`"b" > "abc"`, true},
+		{`"" < "a"`, true},
+		{`"Z" < "a"`, true},
+		{`"b" >= "abc"`, true},
+		{`"" >= ""`, true},
+		{`"abc" == "abc"`, true},
+		{`"abc" != "abd"`, true},
+		{`"abc" == 1`, false},
+		// Long strings and ropes are not interned, but compare by content
+		{`"` + strings.Repeat("x", 100) + `" == "` + strings.Repeat("x", 100) + `"`, true},
+		{`"` + strings.Repeat("x", 100) + `" != "` + strings.Repeat("x", 100) + `"`, false},
+		{`let half = "` + strings.Repeat("ab", object.RopeThreshold/2) + `"; half + half == "` +
+			strings.Repeat("ab", object.RopeThreshold) + `"`, true},
+		{`let half = "` + strings.Repeat("ab", object.RopeThreshold/2) + `"; half + half != "` +
+			strings.Repeat("ab", object.RopeThreshold) + `"`, false},
+		{`let half = "` + strings.Repeat("ab", object.RopeThreshold/2) + `"; half + half == half + "x"`, false},
 	}
 
 	for _, tt := range tests {
@@ -79,6 +165,17 @@ func TestBangOperator(t *testing.T) {
 		{"!!true", true},
 		{"!!false", false},
 		{"!!5", true},
+		// Only false and null are falsy in legacy mode
+		{"!0", false},
+		{`!""`, false},
+		{"![]", false},
+		{"!{}", false},
+		{"#pragma strict\n!0", true},
+		{"#pragma strict\n!\"\"", true},
+		{"#pragma strict\n!\"a\"", false},
+		{"#pragma strict\n![]", true},
+		{"#pragma strict\n!{}", true},
+		{"#pragma strict\n!!{\"a\": 1}", true},
 	}
 
 	for _, tt := range tests {
@@ -95,10 +192,19 @@ func TestIfElseExpressions(t *testing.T) {
 		{"if (true) { 10 }", 10},
 		{"if (false) { 10 }", nil},
 		{"if (1) { 10 }", 10},
+		{"if (0) { 10 }", 10},
+		{`if ("") { 10 } else { 20 }`, 10},
+		{"#pragma strict\nif (0) { 10 }", nil},
+		{"#pragma strict\nif (\"\") { 10 } else { 20 }", 20},
+		{"if ([0]) { 10 }", 10},
 		{"if (1 < 2) { 10 }", 10},
 		{"if (1 > 2) { 10 }", nil},
 		{"if (1 > 2) { 10 } else { 20 }", 20},
 		{"if (1 < 2) { 10 } else { 20 }", 10},
+		{"if (1 > 2) { 10 } else if (2 > 1) { 20 } else { 30 }", 20},
+		{"if (1 > 2) { 10 } else if (2 > 3) { 20 } else { 30 }", 30},
+		{"if (1 > 2) { 10 } else if (2 > 3) { 20 }", nil},
+		{"if (true) { 10 } else if (missing) { 20 }", 10},
 	}
 
 	for _, tt := range tests {
@@ -112,6 +218,44 @@ func TestIfElseExpressions(t *testing.T) {
 	}
 }
 
+func TestConditionalExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any // int64 result, or an error message
+	}{
+		{"true ? 10 : 20", int64(10)},
+		{"false ? 10 : 20", int64(20)},
+		{"1 < 2 ? 1 + 1 : 3", int64(2)},
+		{`"" ? 10 : 20`, int64(10)},
+		{"#pragma strict\n\"\" ? 10 : 20", int64(20)},
+		{"let x = 5; x > 3 ? x > 4 ? 1 : 2 : 3", int64(1)},
+		{"#pragma strict\nlet x = 0; x ? 1 : x == 0 ? 2 : 3", int64(2)},
+		{"let f = fn(n) { n < 2 ? n : f(n - 1) + f(n - 2) }; f(10)", int64(55)},
+		// Only the selected branch is evaluated
+		{"true ? 1 : missing()", int64(1)},
+		{"let n = 0; let f = fn() { n = 1 }; false ? f() : 2; n", int64(0)},
+		{"missing ? 1 : 2", "identifier not found: missing"},
+		{"false ? 1 : -true", "unknown operator: -BOOLEAN"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int64:
+			testIntegerObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("%q: no error object returned. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("%q: wrong error message. expected=%q, got=%q", tt.input, expected, errObj.Message)
+			}
+		}
+	}
+}
+
 func TestReturnStatements(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -212,9 +356,17 @@ func TestErrorHandling(t *testing.T) {
 			`"Hello" - "World"`,
 			"unknown operator: STRING - STRING",
 		},
+		{
+			`"a" * "b"`,
+			"unknown operator: STRING * STRING",
+		},
+		{
+			`true <= false`,
+			"unknown operator: BOOLEAN <= BOOLEAN",
+		},
 		{
 			`{"name": "Monkey"}[fn(x) { x }];`,
-			"unusable as hash key: FUNCTION",
+			"unusable as hash key: FUNCTION from `fn(x)x`; hash keys must be STRING, INTEGER or BOOLEAN",
 		},
 	}
 
@@ -235,6 +387,31 @@ func TestErrorHandling(t *testing.T) {
 	}
 }
 
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		input        string
+		line, column int
+	}{
+		{"5;\n  true + 1;", 2, 3},
+		{"let f = fn() {\n  1;\n  -true\n};\nf();", 3, 3},
+		{"if (true) {\n  foo\n}", 2, 3},
+		{"let h = {\"a\": 1};\nh[\n  [1]];", 3, 3},
+		{"{1: 2,\n [3]: 4}", 2, 2},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("%q: no error object returned. got=%T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if errObj.Pos.Line != tt.line || errObj.Pos.Column != tt.column {
+			t.Errorf("%q: wrong position. want=%d:%d, got=%s", tt.input, tt.line, tt.column, errObj.Pos)
+		}
+	}
+}
+
 func TestLetStatements(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -275,6 +452,74 @@ func TestFunctionObject(t *testing.T) {
 	}
 }
 
+func TestFunctionNames(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let add = fn(x, y) { x + y }; add", "fn add(x, y) { ... }"},
+		{"let add = fn(x, y) { x + y }; let plus = add; plus", "fn add(x, y) { ... }"},
+		{"let make = fn() { fn(x) { x } }; let id = make(); id", "fn(x) { ... }"},
+		{"fn() { 1 }", "fn() { ... }"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if got := evaluated.Inspect(); got != tt.expected {
+			t.Errorf("%q: wrong Inspect(). expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestSourceBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let add = fn(a, b) { let sum = a + b; sum * 2 }; source(add)",
+			"fn(a, b) {\n    let sum = a + b;\n    sum * 2;\n}"},
+		{"source(fn() {})", "fn() {}"},
+		{"source(len)", "argument to `source` must be FUNCTION, got BUILTIN"},
+		{"source()", "wrong number of arguments. got=0, want=1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		var got string
+		switch obj := evaluated.(type) {
+		case *object.String:
+			got = obj.Value
+		case *object.Error:
+			got = obj.Message
+		default:
+			t.Fatalf("%q: unexpected result %T (%+v)", tt.input, evaluated, evaluated)
+		}
+		if got != tt.expected {
+			t.Errorf("%q: wrong result. expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestFunctionEquality(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"let f = fn(x) { x }; f == f", true},
+		{"let f = fn(x) { x }; let g = f; g == f", true},
+		{"let f = fn(x) { x }; let g = fn(x) { x }; f == g", false},
+		{"let f = fn(x) { x }; let g = fn(x) { x }; f != g", true},
+		{"let make = fn() { fn() { 1 } }; make() == make()", false},
+		{"len == len", true},
+		{"len == first", false},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
 func TestFunctionApplication(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -293,6 +538,107 @@ func TestFunctionApplication(t *testing.T) {
 	}
 }
 
+func TestRestParameters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // Inspect() of the result
+	}{
+		{"let f = fn(...xs) { xs }; f()", "[]"},
+		{"let f = fn(...xs) { xs }; f(1, 2, 3)", "[1, 2, 3]"},
+		{"let f = fn(a, ...xs) { [a, xs] }; f(1)", "[1, []]"},
+		{"let f = fn(a, ...r) { r }; f()", "[]"},
+		{"let f = fn(a, b, ...r) { [a, b, r] }; f(1)", "[1, null, []]"},
+		{"let f = fn(a, ...xs) { [a, xs] }; f(1, 2, 3)", "[1, [2, 3]]"},
+		{"let f = fn(a, ...xs) { xs }; f(...[1, 2], 3)", "[2, 3]"},
+		{"let xs = [1, 2]; let f = fn(...ys) { ys }; f(...xs) == xs", "false"},
+		{"#pragma strict\nlet f = fn(a, ...xs) { len(xs) }; f(1, 2, 3)", "2"},
+		{"let sum = fn(...nums) { let total = 0; for (n in nums) { total = total + n }; total }; sum(1, 2, 3)", "6"},
+		{"let f = fn(a, ...rest) { a }; f", "fn f(a, ...rest) { ... }"},
+		{"source(fn(a, ...rest) { a })", "fn(a, ...rest) {\n    a;\n}"},
+	}
+
+	for _, tt := range tests {
+		if got := testEval(tt.input).Inspect(); got != tt.expected {
+			t.Errorf("%s: wrong result. expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestMultipleValues(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // Inspect() of the result
+	}{
+		{"let f = fn() { return 1, 2; }; f()", "[1, 2]"},
+		{"let f = fn(a, b) { return b, a; }; let x, y = f(1, 2); [x, y]", "[2, 1]"},
+		{"let x, y, z = [1, 2, 3]; x + y + z", "6"},
+		{`let parse = fn(s) { if (s == "") { return false, "empty" } return s, false; }; let v, err = parse(""); err`, "empty"},
+		{"let _, b = [1, 2]; b", "2"},
+		{"let _, _ = [1, 2]; _", "ERROR: identifier not found: _"},
+		{"let x, y = [1]", "ERROR: wrong number of values to destructure. got=1, want=2"},
+		{"let x, y = [1, 2, 3]", "ERROR: wrong number of values to destructure. got=3, want=2"},
+		{"let x, y = 5", "ERROR: cannot destructure INTEGER: not an array"},
+		{"let x, y = [z, 1]", "ERROR: identifier not found: z"},
+		{"#pragma strict\nlet x = 1; let y, x = [1, 2]", "ERROR: identifier already declared: x"},
+		{"#pragma strict\nlet _, x = [1, 2]; let _, y = [3, 4]; x + y", "6"},
+		{"let [a, b, c] = [1, 2, 3]; a + b + c", "6"},
+		{"let [head, ...tail] = [1, 2, 3]; [head, tail]", "[1, [2, 3]]"},
+		{"let [head, ...tail] = [1]; tail", "[]"},
+		{"let [_, ...rest] = [1, 2]; rest", "[2]"},
+		{"let [[a, b], {x}] = [[1, 2], {\"x\": 3}]; a + b + x", "6"},
+		{"let [a, b] = [1]", "ERROR: wrong number of values to destructure. got=1, want=2"},
+		{"let [a, ...rest] = []", "ERROR: wrong number of values to destructure. got=0, want at least 1"},
+		{"let [a] = {}", "ERROR: cannot destructure HASH: not an array"},
+		{"let [[a, b]] = [[1]]", "ERROR: cannot destructure [[1]]: does not match [[a, b]]"},
+		{"let [1, a] = [1, 2]; a", "2"},
+		{"#pragma strict\nlet tail = 1; let [_, ...tail] = [1, 2]", "ERROR: identifier already declared: tail"},
+		{`let person = {"name": "Ann", "age": 30}; let {name, age} = person; [name, age]`, "[Ann, 30]"},
+		{`let {name: n, pos: [x, y]} = {"name": "Ann", "pos": [1, 2]}; [n, x + y]`, "[Ann, 3]"},
+		{`let {1: one, true: yes} = {1: "a", true: "b"}; one + yes`, "ab"},
+		{`let {name, email} = {"name": "Ann"}; email`, "null"},
+		{`let [{x}] = [{}]; x`, "null"},
+		{`let {x: [a]} = {}`, "ERROR: cannot destructure {}: does not match {x: [a]}"},
+		{`let {name} = [1]`, "ERROR: cannot destructure ARRAY: not a hash"},
+		{"#pragma strict\nlet {name, email} = {\"name\": \"Ann\"}", "ERROR: cannot destructure HASH: missing key email"},
+		{"#pragma strict\nlet name = 1; let {name} = {\"name\": 2}", "ERROR: identifier already declared: name"},
+	}
+
+	for _, tt := range tests {
+		if got := testEval(tt.input).Inspect(); got != tt.expected {
+			t.Errorf("%s: wrong result. expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestNamedArguments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // Inspect() of the result
+	}{
+		{"let f = fn(a, b) { a - b }; f(b: 1, a: 3)", "2"},
+		{"let f = fn(a, b, c) { [a, b, c] }; f(1, c: 3, b: 2)", "[1, 2, 3]"},
+		{"let f = fn(a, b, c) { [a, b, c] }; f(c: 3)", "[null, null, 3]"},
+		{"let f = fn(a, b, ...rest) { [a, b, rest] }; f(1, 2, 3, 4)", "[1, 2, [3, 4]]"},
+		{"let f = fn(a, b, ...rest) { [a, b, rest] }; f(1, b: 2)", "[1, 2, []]"},
+		{"let f = fn(a, b) { [a, b] }; f(...[1], b: 2)", "[1, 2]"},
+		{"let log = []; let f = fn(a, b) { log }; let g = fn(x) { log = push(log, x); x }; f(g(1), b: g(2)); log", "[1, 2]"},
+		{"let f = fn(a) { a }; f(b: 1)", "ERROR: unknown parameter: b"},
+		{"let f = fn(a) { a }; f(1, a: 2)", "ERROR: argument given twice for parameter: a"},
+		{"let f = fn(a, ...rest) { a }; f(rest: [1])", "ERROR: rest parameter cannot be passed by name: rest"},
+		{"let f = fn(a) { a }; f(a: x)", "ERROR: identifier not found: x"},
+		{"len(arg: [1])", "ERROR: builtins do not take named arguments"},
+		{"let x = 1; x(a: 1)", "ERROR: not a function: INTEGER"},
+		{"#pragma strict\nlet f = fn(a, b) { a }; f(b: 1)", "ERROR: missing argument for parameter: a"},
+		{"#pragma strict\nlet f = fn(a, b) { a }; f(b: 1, a: 2)", "2"},
+	}
+
+	for _, tt := range tests {
+		if got := testEval(tt.input).Inspect(); got != tt.expected {
+			t.Errorf("%s: wrong result. expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
 func TestClosures(t *testing.T) {
 	input := `
     let newAdder = fn(x) {
@@ -322,128 +668,1403 @@ func TestEnclosingEnvironments(t *testing.T) {
 	testIntegerObject(t, testEval(input), 70)
 }
 
-func TestStringLiteral(t *testing.T) {
-	input := `"Hello World!"`
-
-	evaluated := testEval(input)
-	str, ok := evaluated.(*object.String)
-	if !ok {
-		t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+func TestBlockScopes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // Inspect() of the result
+	}{
+		{"if (true) { let x = 1 }; x", "ERROR: identifier not found: x"},
+		{"if (false) { 1 } else { let x = 1 }; x", "ERROR: identifier not found: x"},
+		{"if (false) { 1 } else if (true) { let x = 1 }; x", "ERROR: identifier not found: x"},
+		{"let x = 1; if (true) { let x = 2; x }", "2"},
+		{"let x = 1; if (true) { let x = 2 }; x", "1"},
+		{"let x = 1; if (true) { x = 2 }; x", "2"},
+		{"let x = 1; if (true) { let x = 2; x = 3 }; x", "1"},
+		{"let f = fn() { if (true) { let y = 1 } y }; f()", "ERROR: identifier not found: y"},
+		{"let f = fn() { defer { let z = 1 } 2 }; f(); z", "ERROR: identifier not found: z"},
+		{"#pragma strict\nlet x = 1; if (true) { let x = 2; x }", "2"},
+		{"#pragma strict\nif (true) { let x = 1; let x = 2 }", "ERROR: identifier already declared: x"},
+		// Closures keep the bindings of the block they were created in.
+		{"let f = if (true) { let n = 5; fn() { n } }; f()", "5"},
+		{"let c = if (true) { let n = 0; fn() { n = n + 1; n } }; c(); c()", "2"},
+		{"let n = 1; let f = if (true) { let n = 2; fn() { n } }; [f(), n]", "[2, 1]"},
+		{"let x = 1; let f = fn() { x }; f(); if (true) { let x = 2; f() }", "1"},
+		{"let fs = []; for (let i = 0; i < 3; i = i + 1) { if (true) { let j = i * 10; fs = push(fs, fn() { j }) } }; [fs[0](), fs[2]()]", "[0, 20]"},
 	}
 
-	if str.Value != "Hello World!" {
-		t.Errorf("String has wrong value. got=%q", str.Value)
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%q: got %s, want %s", tt.input, evaluated.Inspect(), tt.expected)
+		}
 	}
 }
 
-func TestStringConcatenation(t *testing.T) {
-	input := `"Hello" + " " + "World!"`
-
-	evaluated := testEval(input)
-	str, ok := evaluated.(*object.String)
-	if !ok {
-		t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+func TestIdentifierLookupCache(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		// Rebinding a global invalidates the cached value.
+		{"let x = 1; let f = fn() { x }; f(); let x = 2; f();", 2},
+		// A parameter shadows a global that was cached by an earlier call.
+		{"let x = 1; let f = fn() { x }; let g = fn(x) { f() + x }; f(); g(10);", 11},
+		{"let x = 1; let f = fn(x) { x }; let y = x; f(5) + y;", 6},
+		// A binding shadows a builtin that was cached by an earlier call.
+		{"let f = fn() { len([1, 2]) }; f(); let len = fn(a) { 40 }; f();", 40},
+		{"let f = fn(len) { len(1) }; len([1]) + f(fn(a) { a + 1 });", 3},
 	}
 
-	if str.Value != "Hello World!" {
-		t.Errorf("String has wrong value. got=%q", str.Value)
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
 	}
 }
 
-func TestBuiltinFunctions(t *testing.T) {
+func TestDeferStatements(t *testing.T) {
 	tests := []struct {
 		input    string
-		expected interface{}
+		expected any // int64 result or error message
 	}{
-		{`len("")`, 0},
-		{`len("four")`, 4},
-		{`len("hello world")`, 11},
-		{`len(1)`, "argument to `len` not supported, got INTEGER"},
-		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
-		{`len([1, 2, 3])`, 3},
-		{`len([])`, 0},
-		{`puts("hello", "world!")`, nil},
-		{`first([1, 2, 3])`, 1},
-		{`first([])`, nil},
-		{`first(1)`, "argument to `first` not supported, got INTEGER"},
-		{`last([1, 2, 3])`, 3},
-		{`last([])`, nil},
-		{`last(1)`, "argument to `last` not supported, got INTEGER"},
-		{`rest([1, 2, 3])`, []int{2, 3}},
-		{`rest([])`, nil},
-		{`push([], 1)`, []int{1}},
-		{`push(1, 1)`, "argument to `push` not supported, got INTEGER"},
+		{"let f = fn() { defer { 1 }; return 5; 6 }; f();", int64(5)},
+		{"let f = fn() { defer { a }; 1 }; 2;", int64(2)},
+		{"let f = fn(x) { defer { x + true }; x }; f(1);", "type mismatch: INTEGER + BOOLEAN"},
+		{"let f = fn() { defer { a }; defer { b }; 1 }; f();", "identifier not found: b"},
+		{"let f = fn() { defer { a }; c }; f();", "identifier not found: c"},
+		{"let f = fn() { if (true) { defer { a } }; 1 }; f();", "identifier not found: a"},
+		{"defer { a }; 5;", "identifier not found: a"},
+		{"let f = fn() { defer { defer { a } }; 1 }; f();", "identifier not found: a"},
 	}
 
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
-
 		switch expected := tt.expected.(type) {
-		case int:
-			testIntegerObject(t, evaluated, int64(expected))
+		case int64:
+			testIntegerObject(t, evaluated, expected)
 		case string:
 			errObj, ok := evaluated.(*object.Error)
 			if !ok {
-				t.Errorf("object is not Error. got=%T (%+v)",
-					evaluated, evaluated)
+				t.Errorf("%s: no error object returned. got=%T(%+v)", tt.input, evaluated, evaluated)
 				continue
 			}
 			if errObj.Message != expected {
-				t.Errorf("wrong error message. expected=%q, got=%q",
-					expected, errObj.Message)
+				t.Errorf("%s: wrong error message. expected=%q, got=%q", tt.input, expected, errObj.Message)
 			}
+		}
+	}
+}
 
-		case []int:
-			array, ok := evaluated.(*object.Array)
+func TestImportStatements(t *testing.T) {
+	resolver := modules.Memory{
+		"math":      "let double = fn(x) { x * 2 }; let _half = fn(x) { x / 2 }; let two = _half(4);",
+		"lib/a":     `import "./b"; let a = b + 1;`,
+		"lib/b":     "let b = 1;",
+		"cycle/a":   `import "./b";`,
+		"cycle/b":   `import "./a";`,
+		"broken":    "let x = 1;\nlet y = x + true;",
+		"malformed": "let = 1;",
+	}
+	tests := []struct {
+		input    string
+		expected any // int64 result, bool, or an error message
+	}{
+		{`import "math"; double(two)`, int64(4)},
+		{`import "math"; _half(4)`, "identifier not found: _half"},
+		{`import "math"; let f = double; import "math"; f == double`, true},
+		{`let f = fn() { import "math"; double(3) }; f()`, int64(6)},
+		{`let f = fn() { import "math"; 1 }; f(); double`, "identifier not found: double"},
+		{`import "lib/a"; a`, int64(2)},
+		{`#pragma strict
+import "math"; import "math"; two`, int64(2)},
+		{`#pragma strict
+let two = 1; import "math";`, "identifier already declared: two"},
+		{`import "missing";`, `cannot import "missing": module not found: missing`},
+		{`import "../math";`, `cannot import "../math": invalid module path "../math"`},
+		{`import "cycle/a";`, "cycle/a:1:1: cycle/b:1:1: import cycle: cycle/a -> cycle/b -> cycle/a"},
+		{`import "broken";`, "broken:2:1: type mismatch: INTEGER + BOOLEAN"},
+		{`import "malformed";`, "malformed:1:5: Expected next token to be IDENT, got = instead"},
+		{`import "math"; import "lib/a"; import "lib/b"; double(a + b)`, int64(6)},
+		{`import "missing"; import "math";`, `cannot import "missing": module not found: missing`},
+		{`import "math"; import "malformed";`, "malformed:1:5: Expected next token to be IDENT, got = instead"},
+	}
+
+	for _, tt := range tests {
+		SetModuleResolver(resolver)
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int64:
+			testIntegerObject(t, evaluated, expected)
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
 			if !ok {
-				t.Errorf("obj not Array. got=%T (%+v)", evaluated, evaluated)
+				t.Errorf("%s: no error object returned. got=%T(%+v)", tt.input, evaluated, evaluated)
 				continue
 			}
-
-			if len(array.Elements) != len(expected) {
-				t.Errorf("wrong num of elements. want=%d, got=%d",
-					len(expected), len(array.Elements))
-				continue
+			if errObj.Message != expected {
+				t.Errorf("%s: wrong error message. expected=%q, got=%q", tt.input, expected, errObj.Message)
 			}
+		}
+	}
 
-			for i, expectedElem := range expected {
-				testIntegerObject(t, array.Elements[i], int64(expectedElem))
-			}
+	SetModuleResolver(nil)
+	if got := testEval(`import "math";`); got.Inspect() != `ERROR: cannot import "math": no module resolver is set` {
+		t.Errorf("wrong result without a resolver. got=%s", got.Inspect())
+	}
+}
+
+// countingResolver counts the modules loaded from its Memory resolver.
+type countingResolver struct {
+	modules.Memory
+	loads map[string]int
+}
+
+func (r countingResolver) Load(name string) (string, error) {
+	r.loads[name]++
+	return r.Memory.Load(name)
+}
+
+func TestPrefetchedModules(t *testing.T) {
+	resolver := countingResolver{
+		Memory: modules.Memory{
+			"a":      `import "./b"; import "./c"; let a = b + c;`,
+			"b":      "let b = 1;",
+			"c":      "let c = 2;",
+			"d":      "let d = 3;",
+			"broken": "let x = ;",
+		},
+		loads: make(map[string]int),
+	}
+	SetModuleResolver(resolver)
+	defer SetModuleResolver(nil)
+
+	env := object.NewEnvironment()
+	eval := func(input string) string {
+		return Eval(parser.New(lexer.New(input)).ParseProgram(), env).Inspect()
+	}
+	if got := eval(`import "a"; import "d"; import "b"; a + d`); got != "6" {
+		t.Errorf("wrong result of prefetched imports. got=%s", got)
+	}
+	for _, name := range []string{"a", "b", "c", "d"} {
+		if resolver.loads[name] != 1 {
+			t.Errorf("module %s loaded %d times, want once", name, resolver.loads[name])
 		}
 	}
+
+	// A module parsed ahead of an import that never ran is read again later
+	eval(`import "broken"; let e = 1; import "e";`)
+	resolver.Memory["e"] = "let e = 5;"
+	if got := eval(`import "broken"; import "e";`); got != "ERROR: broken:1:9: no prefix parse function for ; found" {
+		t.Errorf("wrong error of an import before a prefetched one. got=%s", got)
+	}
+	resolver.Memory["e"] = "let e = 7;"
+	if got := eval(`import "e"; e`); got != "7" {
+		t.Errorf("a prefetched module was not read again. e=%s", got)
+	}
 }
 
-func TestArrayLiterals(t *testing.T) {
-	input := `[1, 2 * 2, 3 + 3]`
+func TestReloadModule(t *testing.T) {
+	resolver := modules.Memory{
+		"shapes": "let area = fn(r) { 3 * r * r }; let unit = 1;",
+		"user":   `import "shapes"; let big = fn() { area(10) };`,
+	}
+	SetModuleResolver(resolver)
+	defer SetModuleResolver(nil)
 
-	evaluated := testEval(input)
-	arr, ok := evaluated.(*object.Array)
-	if !ok {
-		t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+	env := object.NewEnvironment()
+	eval := func(input string) string {
+		return Eval(parser.New(lexer.New(input)).ParseProgram(), env).Inspect()
 	}
+	eval(`import "shapes"; import "user"; let small = fn() { area(1) }; unit = 5; unit`)
 
-	if len(arr.Elements) != 3 {
-		t.Fatalf("array has wrong number of elements. got=%d", len(arr.Elements))
+	resolver["shapes"] = "let area = fn(r) { 4 * r * r }; let unit = 2; let perimeter = fn(r) { 8 * r };"
+	name, rebound, err := ReloadModule("shapes", env)
+	if err != nil || name != "shapes" {
+		t.Fatalf("ReloadModule failed. got=%q, %v", name, err)
+	}
+	if want := []string{"area", "perimeter"}; !slices.Equal(rebound, want) {
+		t.Errorf("wrong bindings rebound. expected=%v, got=%v", want, rebound)
+	}
+	// Functions defined in the session and in other modules see the new
+	// version, while bindings changed since the import are kept
+	for input, expected := range map[string]string{
+		"small()": "4", "big()": "400", "perimeter(1)": "8", "unit": "5",
+	} {
+		if got := eval(input); got != expected {
+			t.Errorf("%s: wrong result after reload. expected=%q, got=%q", input, expected, got)
+		}
 	}
 
-	testIntegerObject(t, arr.Elements[0], 1)
-	testIntegerObject(t, arr.Elements[1], 4)
-	testIntegerObject(t, arr.Elements[2], 6)
+	resolver["shapes"] = "let area = ;"
+	if _, _, err := ReloadModule("shapes", env); err == nil || err.Error() != "shapes:1:12: no prefix parse function for ; found" {
+		t.Errorf("wrong error for a broken module. got=%v", err)
+	}
+	if got := eval("small()"); got != "4" {
+		t.Errorf("a failed reload replaced the module. small()=%s", got)
+	}
+	if _, _, err := ReloadModule("user2", env); err == nil {
+		t.Errorf("expected an error for a module that does not exist")
+	}
+	resolver["other"] = ""
+	if _, _, err := ReloadModule("other", env); err == nil || err.Error() != "module other has not been imported" {
+		t.Errorf("wrong error for a module that was not imported. got=%v", err)
+	}
 }
 
-func TestArrayIndexExpressions(t *testing.T) {
+func TestForExpressions(t *testing.T) {
 	tests := []struct {
 		input    string
-		expected interface{}
+		expected any // int64 result, nil for null, or an error message
 	}{
-		{
-			"[1, 2, 3][0]",
-			1,
-		},
-		{
-			"[1, 2, 3][1]",
-			2,
-		},
+		{"let s = 0; for (let i = 1; i < 5; i = i + 1) { s = s + i }; s", int64(10)},
+		{"for (let i = 0; i < 3; i = i + 1) { i }", nil},
+		{"let s = 0; for (let i = 0; i < 3; i = i + 1) { }; i", "identifier not found: i"},
+		{"let i = 10; for (let i = 0; i < 3; i = i + 1) { }; i", int64(10)},
+		{"let i = 0; for (; i < 3;) { i = i + 1 }; i", int64(3)},
+		{"let i = 0; for (i = 5; i < 7; i = i + 1) { }; i", int64(7)},
+		{"let f = fn() { for (;;) { return 42 } }; f()", int64(42)},
+		{"#pragma strict\nlet n = 0; for (let i = 0; i < 3; i = i + 1) { let sq = i * i; n = n + sq }; n", int64(5)},
+		{"for (let i = 0; i < 3; j = 1) { }", "cannot assign to undeclared identifier: j"},
+		{"for (let i = missing; i < 3; i = i + 1) { }", "identifier not found: missing"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int64:
+			testIntegerObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("%q: no error object returned. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("%q: wrong error message. expected=%q, got=%q", tt.input, expected, errObj.Message)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestForInExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any // int64 result, nil for null, or an error message
+	}{
+		{"let s = 0; for (x in [1, 2, 3]) { s = s + x }; s", int64(6)},
+		{"let s = 0; for (i, x in [5, 6, 7]) { s = s + i * x }; s", int64(20)},
+		{"for (x in []) { x }", nil},
+		{"for (x in [1]) { x }", nil},
+		{"let s = 0; for (k in {1: 10, 2: 20}) { s = s + k }; s", int64(3)},
+		{"let s = 0; for (k, v in {1: 10, 2: 20}) { s = s + v }; s", int64(30)},
+		{"let f = fn(xs) { for (x in xs) { if (x > 1) { return x } } }; f([1, 5, 9])", int64(5)},
+		{"for (x in [1]) { }; x", "identifier not found: x"},
+		{"let x = 7; for (x in [1, 2]) { }; x", int64(7)},
+		{"#pragma strict\nlet n = 0; for (x in [1, 2]) { let sq = x * x; n = n + sq }; n", int64(5)},
+		{"for (x in 5) { }", "cannot iterate over INTEGER in `5`"},
+		{"for (x in [1, missing]) { }", "identifier not found: missing"},
+		{"for (x in [1]) { x + true }", "type mismatch: INTEGER + BOOLEAN"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int64:
+			testIntegerObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("%q: no error object returned. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("%q: wrong error message. expected=%q, got=%q", tt.input, expected, errObj.Message)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestForInOrder(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let out = []; for (k in {10: 1, 2: 1, 3: 1}) { out = push(out, k) }; out`, "[2, 3, 10]"},
+		{`let out = []; for (k, v in {"b": 1, "a": 2}) { out = push(out, [k, v]) }; out`, "[[a, 2], [b, 1]]"},
+		{`let fs = []; for (x in [1, 2]) { fs = push(fs, fn() { x }) }; [fs[0](), fs[1]()]`, "[1, 2]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%q: wrong result. expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestMatchExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`match (2) { 1 => "one", 2 => "two", _ => "many" }`, "two"},
+		{`match (7) { 1 => "one", n => n * 2 }`, "14"},
+		{`match (2.0) { 2 => "int matches float", _ => "no" }`, "int matches float"},
+		{`match (-3) { -3 => "negative", _ => "no" }`, "negative"},
+		{`match ("b") { "a" => 1, "b" => 2 }`, "2"},
+		{`match (5) { 1 => 1 }`, "null"},
+		{`match ([]) { [] => "empty", [x] => x }`, "empty"},
+		{`match ([1, 2]) { [x] => x, [x, y] => x + y }`, "3"},
+		{`match ([1, 2, 3]) { [x, y] => 0, [first, ...rest] => rest }`, "[2, 3]"},
+		{`match ([1]) { [_, ...rest] => rest }`, "[]"},
+		{`match ([[1, 2], 3]) { [[a, b], c] => a + b + c }`, "6"},
+		{`match ({"name": "Ann", "age": 30, "x": 1}) { {name: n, "age": a} => [n, a] }`, "[Ann, 30]"},
+		{`match ({1: "one", true: "yes"}) { {1: one, true: yes} => [one, yes] }`, "[one, yes]"},
+		{`match ({"a": 1}) { {b} => b, {a} => a }`, "1"},
+		{`match ({"kind": "circle", "r": 2}) { {kind: "square", side} => side, {kind: "circle", r} => r }`, "2"},
+		{`match ({"x": 1}) { Point {x} => "point", {x} => "plain" }`, "plain"},
+		{`match (tag({"x": 1, "y": 0}, "Point")) { Point {x, y: 0} => x, Point {} => "other" }`, "1"},
+		{`match (tag({"x": 1}, "Point")) { Vector {x} => "vector", {x} => x }`, "1"},
+		{`match ([1, 2]) { {a} => a, [x, ...r] => x }`, "1"},
+		{`match (true) { true => { let y = 2; y * 3 } }`, "6"},
+		{`let f = fn(xs) { match (xs) { [x, ...rest] => { return x; } }; 0 }; f([4, 5])`, "4"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%q: wrong result. expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		// The names bound by a pattern, and by the body of its arm, stay in the arm
+		{"match ([1]) { [x] => x }; x", "identifier not found: x"},
+		{"match (1) { _ => { let y = 1; y } }; y", "identifier not found: y"},
+		{"match (1) { _ => _ }", "identifier not found: _"},
+		// A failed arm binds nothing, even if part of its pattern matched
+		{"match ([1, 2]) { [x, 3] => x, [y, z] => x }", "identifier not found: x"},
+		{"match (missing) { _ => 1 }", "identifier not found: missing"},
+		{"match (1) { n => n + true }", "type mismatch: INTEGER + BOOLEAN"},
+	}
+
+	for _, tt := range errorTests {
+		errObj, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("%q: no error object returned", tt.input)
+			continue
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("%q: wrong error message. expected=%q, got=%q", tt.input, tt.expected, errObj.Message)
+		}
+	}
+}
+
+func TestDeferInLoop(t *testing.T) {
+	input := `
+let log = [];
+let f = fn() {
+    let i = 0;
+    while (i < 3) {
+        let n = i;
+        defer { log = push(log, n) }
+        i = i + 1;
+    }
+    log = push(log, "end");
+};
+f();
+log`
+
+	evaluated := testEval(input)
+	if got := evaluated.Inspect(); got != "[end, 2, 1, 0]" {
+		t.Errorf("deferred blocks ran in the wrong order. got=%s", got)
+	}
+}
+
+func TestStrictMode(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any // int64 result or error message
+	}{
+		{"let x = 1; let x = 2; x", int64(2)},
+		{"#pragma strict\nlet x = 1; let x = 2; x", "identifier already declared: x"},
+		{"#pragma strict\nlet x = 1; let f = fn() { let x = 2; x }; f()", int64(2)},
+		{"let f = fn(a) { a }; f(1, 2)", int64(1)},
+		{"let f = fn(a, b, c) { a }; f(1, 2)", int64(1)},
+		{"let f = fn(a, b) { b ?? 3 }; f(1)", int64(3)},
+		{"let f = fn(a) { a ?? 4 }; f()", int64(4)},
+		{"#pragma strict\nlet f = fn(a) { a }; f(1, 2)", "wrong number of arguments. got=2, want=1"},
+		{"#pragma strict\nlet f = fn(a) { fn(b) { b } }; f(1)()", "wrong number of arguments. got=0, want=1"},
+		{"#pragma strict\nlet f = fn(a, b, ...c) { c }; f(1)", "wrong number of arguments. got=1, want at least 2"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int64:
+			testIntegerObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("%q: no error object returned. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("%q: wrong error message. expected=%q, got=%q", tt.input, expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestNullAndEmptyBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"is_null(if (false) { 1 })", true},
+		{"is_null(0)", false},
+		{"is_null(false)", false},
+		{`is_empty("")`, true},
+		{`is_empty("a")`, false},
+		{"is_empty([])", true},
+		{"is_empty([1])", false},
+		{"is_empty({})", true},
+		{`is_empty({"a": 1})`, false},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestNonBooleanConditionWarnings(t *testing.T) {
+	var warnings []string
+	SetWarningHandler(func(pos token.Position, message string) {
+		warnings = append(warnings, pos.String()+": "+message)
+	})
+	defer SetWarningHandler(nil)
+
+	testEval("let f = fn(x) { if (x) { x } }; f(3); if (true) { 1 }")
+	if len(warnings) != 0 {
+		t.Errorf("expected no warnings outside strict mode. got=%q", warnings)
+	}
+
+	testEval("#pragma strict\nlet f = fn(x) { if (x) { f(x - 1) } }; f(3); if (1 < 2) { 1 }")
+	expected := []string{"2:21: non-boolean condition of type INTEGER; use !!x to convert it explicitly"}
+	if !slices.Equal(warnings, expected) {
+		t.Errorf("wrong warnings. expected=%q, got=%q", expected, warnings)
+	}
+}
+
+func TestEvalDetailed(t *testing.T) {
+	var handled []string
+	SetWarningHandler(func(pos token.Position, message string) {
+		handled = append(handled, pos.String())
+	})
+	defer SetWarningHandler(nil)
+
+	input := "#pragma strict\nlet xs = [];\nfor (i in 0..3) { if (i) { xs = push(xs, i) } }; len(xs)"
+	program := parser.New(lexer.New(input)).ParseProgram()
+	for run := range 2 {
+		SetStepLimit(0)
+		result := EvalDetailed(program, object.NewEnvironment())
+		testIntegerObject(t, result.Value, 2)
+		if result.Steps != Steps() || result.Steps == 0 {
+			t.Errorf("run %d: wrong steps. expected=%d, got=%d", run, Steps(), result.Steps)
+		}
+		// The range, the empty array and the results of the builtins
+		if result.AllocatedObjects != 5 {
+			t.Errorf("run %d: wrong allocated objects. expected=5, got=%d", run, result.AllocatedObjects)
+		}
+		if len(result.Warnings) != 1 || result.Warnings[0].Pos.String() != "3:23" {
+			t.Errorf("run %d: wrong warnings. got=%v", run, result.Warnings)
+		}
+	}
+	// The handler only hears of the warning once
+	if want := []string{"3:23"}; !slices.Equal(handled, want) {
+		t.Errorf("wrong handled warnings. expected=%q, got=%q", want, handled)
+	}
+}
+
+func TestEvalExpression(t *testing.T) {
+	env := object.NewEnvironment()
+	Eval(parser.New(lexer.New("let x = 20; let double = fn(n) { n * 2 };")).ParseProgram(), env)
+
+	value, err := EvalExpression("double(x) + 2", env)
+	if err != nil {
+		t.Fatalf("EvalExpression returned an error: %v", err)
+	}
+	testIntegerObject(t, value, 42)
+
+	value, err = EvalExpression("1 + 1", nil)
+	if err != nil {
+		t.Fatalf("EvalExpression returned an error: %v", err)
+	}
+	testIntegerObject(t, value, 2)
+
+	for _, input := range []string{"let y = 1", "x; x", "", "return x"} {
+		if _, err := EvalExpression(input, env); !errors.Is(err, ErrNotExpression) {
+			t.Errorf("%q: expected ErrNotExpression, got %v", input, err)
+		}
+	}
+
+	var list *parser.ErrorList
+	if _, err := EvalExpression("x +", env); !errors.As(err, &list) {
+		t.Errorf("expected a parser.ErrorList, got %v", err)
+	}
+
+	var runtimeErr *RuntimeError
+	if _, err := EvalExpression("missing", env); !errors.As(err, &runtimeErr) {
+		t.Errorf("expected a RuntimeError, got %v", err)
+	} else if runtimeErr.Err.Message != "identifier not found: missing" || runtimeErr.Err.Pos.String() != "1:1" {
+		t.Errorf("wrong error. got=%v", runtimeErr)
+	}
+}
+
+func TestCheckedArithmetic(t *testing.T) {
+	const maxInt = "9223372036854775807"
+	tests := []struct {
+		input    string
+		expected any // int64 result or error message
+	}{
+		{"10 / 0", "division by zero: 10 / 0"},
+		{"#pragma strict\n10 / 0", "division by zero: 10 / 0"},
+		{maxInt + " + 1", int64(-9223372036854775808)},
+		{"#pragma strict\n" + maxInt + " + 1", "integer overflow: " + maxInt + " + 1"},
+		{"#pragma strict\n-" + maxInt + " - 2", "integer overflow: -" + maxInt + " - 2"},
+		{"#pragma strict\n" + maxInt + " * 2", "integer overflow: " + maxInt + " * 2"},
+		{"#pragma strict\nlet min = -" + maxInt + " - 1; min / -1", "integer overflow: -9223372036854775808 / -1"},
+		{"#pragma strict\nlet min = -" + maxInt + " - 1; -min", "integer overflow: -(-9223372036854775808)"},
+		{"#pragma strict\nlet min = -" + maxInt + " - 1; min * -1", "integer overflow: -9223372036854775808 * -1"},
+		{"#pragma strict\n" + maxInt + " - 1 + 1", int64(9223372036854775807)},
+		{"#pragma strict\n-3037000499 * 3037000499", int64(-9223372030926249001)},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int64:
+			testIntegerObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("%q: no error object returned. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("%q: wrong error message. expected=%q, got=%q", tt.input, expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestWhileExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any // int64 result, nil for null, or an error message
+	}{
+		{"let i = 0; while (i < 5) { i = i + 1 }; i", int64(5)},
+		{"let i = 0; while (i < 5) { i = i + 1 }", nil},
+		{"while (false) { 1 }", nil},
+		{`let hash = {"one": 1, "two": 2, "three": 3};
+		  let keys = ["one", "two", "three"];
+		  let sum = 0;
+		  let i = 0;
+		  while (i < len(keys)) {
+		      let key = keys[i];
+		      sum = sum + hash[key];
+		      i = i + 1;
+		  }
+		  sum`, int64(6)},
+		{"let f = fn() { let i = 0; while (true) { if (i == 3) { return i * 10 }; i = i + 1 } }; f()", int64(30)},
+		{"#pragma strict\nlet n = 3; while (n) { n = n - 1 }; n", int64(0)},
+		{"#pragma strict\nlet i = 0; while (i < 2) { let x = i; i = i + 1 }; i", int64(2)},
+		{"let i = 0; while (i < 2) { let x = i; i = i + 1 }; x", "identifier not found: x"},
+		{"while (true) { 1 + true }", "type mismatch: INTEGER + BOOLEAN"},
+		{"while (missing) { 1 }", "identifier not found: missing"},
+		{"x = 1", "cannot assign to undeclared identifier: x"},
+		{"let x = 1; let f = fn() { x = 2 }; f(); x", int64(2)},
+		{"let x = 1; let f = fn(x) { x = 2; x }; [f(0), x][0] + x", int64(3)},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int64:
+			testIntegerObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("%q: no error object returned. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("%q: wrong error message. expected=%q, got=%q", tt.input, expected, errObj.Message)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestStringLiteral(t *testing.T) {
+	input := `"Hello World!"`
+
+	evaluated := testEval(input)
+	str, ok := evaluated.(*object.String)
+	if !ok {
+		t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if str.Value != "Hello World!" {
+		t.Errorf("String has wrong value. got=%q", str.Value)
+	}
+}
+
+func TestStringConcatenation(t *testing.T) {
+	input := `"Hello" + " " + "World!"`
+
+	evaluated := testEval(input)
+	str, ok := evaluated.(*object.String)
+	if !ok {
+		t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if str.Value != "Hello World!" {
+		t.Errorf("String has wrong value. got=%q", str.Value)
+	}
+}
+
+func TestStringCaseBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`upper("Hello, World!")`, "HELLO, WORLD!"},
+		{`lower("Hello, World!")`, "hello, world!"},
+		{`upper("")`, ""},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if str.Value != tt.expected {
+			t.Errorf("String has wrong value. want=%q, got=%q", tt.expected, str.Value)
+		}
+	}
+}
+
+func TestVersionBuiltin(t *testing.T) {
+	evaluated := testEval(`let v = version(); [v["version"], v["engine"], len(v["features"])]`)
+	want := fmt.Sprintf("[%s, %s, %d]", version.Version, version.Engine, len(version.Features))
+	if got := evaluated.Inspect(); got != want {
+		t.Errorf("version() wrong. want=%q, got=%q", want, got)
+	}
+}
+
+func TestBuiltinFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`len("")`, 0},
+		{`len("four")`, 4},
+		{`len("hello world")`, 11},
+		{`len("héllo")`, 5},
+		{`len("🐒")`, 1},
+		{`len(bytes("héllo"))`, 6},
+		{`len(1)`, "argument to `len` not supported, got INTEGER"},
+		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
+		{`len([1, 2, 3])`, 3},
+		{`len([])`, 0},
+		{`puts("hello", "world!")`, nil},
+		{`first([1, 2, 3])`, 1},
+		{`first([])`, nil},
+		{`first(1)`, "argument to `first` not supported, got INTEGER"},
+		{`last([1, 2, 3])`, 3},
+		{`last([])`, nil},
+		{`last(1)`, "argument to `last` not supported, got INTEGER"},
+		{`rest([1, 2, 3])`, []int{2, 3}},
+		{`rest([])`, nil},
+		{`push([], 1)`, []int{1}},
+		{`push(1, 1)`, "argument to `push` not supported, got INTEGER"},
+		{`upper(1)`, "argument to `upper` must be STRING, got INTEGER"},
+		{`lower("a", "b")`, "wrong number of arguments. got=2, want=1"},
+		{`is_empty(1)`, "argument to `is_empty` not supported, got INTEGER"},
+		{`is_null()`, "wrong number of arguments. got=0, want=1"},
+		{`div(7, 2)`, 3},
+		{`div(-7, 2)`, -4},
+		{`div(7, -2)`, -4},
+		{`div(-7, -2)`, 3},
+		{`div(-8, 2)`, -4},
+		{`mod(7, 2)`, 1},
+		{`mod(-7, 2)`, 1},
+		{`mod(7, -2)`, -1},
+		{`mod(-7, -2)`, -1},
+		{`mod(-8, 2)`, 0},
+		{`div(1, 0)`, "division by zero: div(1, 0)"},
+		{`mod(1, 0)`, "division by zero: mod(1, 0)"},
+		{`div("1", 2)`, "first argument to `div` must be INTEGER, got STRING"},
+		{`mod(1)`, "wrong number of arguments. got=1, want=2"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)",
+					evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q",
+					expected, errObj.Message)
+			}
+
+		case []int:
+			array, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Errorf("obj not Array. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+
+			if len(array.Elements) != len(expected) {
+				t.Errorf("wrong num of elements. want=%d, got=%d",
+					len(expected), len(array.Elements))
+				continue
+			}
+
+			for i, expectedElem := range expected {
+				testIntegerObject(t, array.Elements[i], int64(expectedElem))
+			}
+		}
+	}
+}
+
+func TestForall(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`forall(gen_int(-100, 100), fn(x) { x + 0 == x })`, true},
+		{`forall(gen_string(10), fn(s) { len(s) < 11 }, 50)`, true},
+		{`forall(gen_array(gen_int(0, 9), 5), fn(a) { len(a) < 6 })`, true},
+		// Failing cases shrink to the minimal counterexample.
+		{`forall(gen_int(0, 1000), fn(x) { x < 10 }, 500)`, "counterexample 10: property returned false"},
+		{`forall(gen_int(-1000, -1), fn(x) { x > -5 }, 500)`, "counterexample -5: property returned false"},
+		{`forall(gen_string(20), fn(s) { len(s) < 3 }, 500)`, "counterexample aaa: property returned false"},
+		{`forall(gen_array(gen_int(0, 100), 10), fn(a) { len(a) < 2 }, 500)`, "counterexample [0, 0]: property returned false"},
+		{`forall(gen_int(1, 5), fn(x) { x + true }, 5)`, "counterexample 1: type mismatch: INTEGER + BOOLEAN"},
+		{`forall(1, fn(x) { true })`, "first argument to `forall` must be GENERATOR, got INTEGER"},
+		{`forall(gen_int(0, 1), fn(x, y) { true })`, "property passed to `forall` must take 1 argument, got 2"},
+		{`gen_int(5, 1)`, "invalid range for `gen_int`: 5 > 1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if !strings.HasSuffix(errObj.Message, expected) {
+				t.Errorf("wrong error message. expected suffix %q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestRuntimeStats(t *testing.T) {
+	ResetBuiltinStats()
+	input := `
+let arr = push(push([], 1), 2);
+len(arr);
+runtime_stats();
+`
+	evaluated := testEval(input)
+	stats, ok := evaluated.(*object.Hash)
+	if !ok {
+		t.Fatalf("object is not Hash. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	tests := map[string]int64{"push": 2, "len": 1}
+	for name, calls := range tests {
+		key := &object.String{Value: name}
+		pair, ok := stats.Pairs[key.HashKey()]
+		if !ok {
+			t.Errorf("no stats for %q", name)
+			continue
+		}
+		entry, ok := pair.Value.(*object.Hash)
+		if !ok {
+			t.Fatalf("stats for %q is not Hash. got=%T", name, pair.Value)
+		}
+		callsKey := &object.String{Value: "calls"}
+		testIntegerObject(t, entry.Pairs[callsKey.HashKey()].Value, calls)
+	}
+
+	if got := BuiltinStats(); len(got) != 3 {
+		t.Errorf("expected stats for 3 builtins, got %d: %+v", len(got), got)
+	}
+	ResetBuiltinStats()
+	if got := BuiltinStats(); len(got) != 0 {
+		t.Errorf("expected no stats after reset, got %+v", got)
+	}
+}
+
+func TestFunctionStats(t *testing.T) {
+	ResetFunctionStats()
+	defer ResetFunctionStats()
+	input := `
+let double = fn(x) { x * 2 };
+let twice = fn(f, x) { f(f(x)) };
+twice(double, 1);
+for (x in [1, 2, 3]) { (fn(y) { y })(x) };
+`
+	testEval(input)
+
+	expected := []FunctionStat{
+		{Name: Anonymous, Pos: token.Position{Line: 5, Column: 31}, Calls: 3},
+		{Name: "double", Pos: token.Position{Line: 2, Column: 20}, Calls: 2},
+		{Name: "twice", Pos: token.Position{Line: 3, Column: 22}, Calls: 1},
+	}
+	if got := FunctionStats(); !slices.Equal(got, expected) {
+		t.Errorf("wrong stats.\nexpected=%+v\ngot=%+v", expected, got)
+	}
+	ResetFunctionStats()
+	if got := FunctionStats(); len(got) != 0 {
+		t.Errorf("expected no stats after reset, got %+v", got)
+	}
+}
+
+func TestPeakMemory(t *testing.T) {
+	TrackPeakMemory(true)
+	defer TrackPeakMemory(false)
+
+	testEval(`let f = fn() { let xs = []; for (let i = 0; i < 10000; i = i + 1) { xs = push(xs, i) }; len(xs) }; f(); f()`)
+	first := PeakMemory()
+	if first < 10000*8 {
+		t.Errorf("peak too low for an array of 10000 integers: %d bytes", first)
+	}
+	// The arrays of the calls are garbage once they return, so a second
+	// call does not raise the peak much
+	if first > 4*10000*8*4 {
+		t.Errorf("peak too high: %d bytes", first)
+	}
+	if len(frames) != 0 {
+		t.Errorf("frames left after the run: %d", len(frames))
+	}
+
+	TrackPeakMemory(false)
+	testEval(`let xs = 1..100; len(xs)`)
+	if PeakMemory() != 0 {
+		t.Errorf("peak measured with tracking off: %d", PeakMemory())
+	}
+}
+
+func TestMemoryLimit(t *testing.T) {
+	SetMemoryLimit(64 << 10)
+	defer SetMemoryLimit(0)
+
+	tests := []struct {
+		input    string
+		expected string // The prefix of the error message, or "" for none
+	}{
+		{`let arr = []; while (true) { arr = push(arr, 1) }`, "memory limit exceeded: "},
+		{`let s = "x"; while (true) { s = s + s }`, "memory limit exceeded: "},
+		{`let b = builder(); while (true) { b_write(b, "0123456789") }`, "memory limit exceeded: "},
+		{`len(0..1000000)`, "memory limit exceeded: 0..1000000 has 1000000 integers"},
+		{`let grow = fn(h, n) { if (n == 0) { h } else { grow({...h, n: [n]}, n - 1) } }; grow({}, 100000)`, "memory limit exceeded: "},
+		// Garbage does not count, however much of it is created
+		{`let i = 0; while (i < 20000) { let tmp = [i, i, i, i]; i = i + 1 }; i`, ""},
+		{`let f = fn(n) { let xs = []; for (let i = 0; i < n; i = i + 1) { xs = push(xs, i) }; len(xs) }; f(100) + f(100)`, ""},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		err, isErr := evaluated.(*object.Error)
+		if tt.expected == "" {
+			if isErr {
+				t.Errorf("%s: unexpected error: %s", tt.input, err.Message)
+			}
+			continue
+		}
+		if !isErr || !strings.HasPrefix(err.Message, tt.expected) {
+			t.Errorf("%s: expected an error starting with %q, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+
+	if len(frames) != 0 {
+		t.Errorf("frames left after the runs: %d", len(frames))
+	}
+}
+
+func TestDeterministic(t *testing.T) {
+	t.Cleanup(func() {
+		SetRandSource(NewRandSource())
+		object.SetSortedHashes(false)
+		now = time.Now
+	})
+
+	input := `
+let seen = [];
+forall(gen_int(0, 1000), fn(x) { seen = push(seen, x); true }, 5);
+len(seen);
+[{"b": 1, "a": 2, 2: 3, 1: 4}, seen, runtime_stats()["len"]["time_ns"]]
+`
+	ResetBuiltinStats()
+	SetDeterministic(42)
+	first := testEval(input).Inspect()
+	SetDeterministic(42)
+	if second := testEval(input).Inspect(); second != first {
+		t.Errorf("runs with the same seed differ:\n%s\n%s", first, second)
+	}
+	if !strings.HasPrefix(first, "[{1: 4, 2: 3, a: 2, b: 1}, [") || !strings.HasSuffix(first, "], 0]") {
+		t.Errorf("hash not sorted or clock not stopped. got=%s", first)
+	}
+
+	SetDeterministic(43)
+	if other := testEval(input).Inspect(); other == first {
+		t.Errorf("runs with different seeds drew the same numbers: %s", other)
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	spec := `{"count": {"type": "int", "default": 1, "help": "how many"},
+		"name": {"type": "string"}, "verbose": {"type": "bool"}}`
+
+	tests := []struct {
+		argv     string
+		expected string // key=Inspect pairs (commas unspaced), or an error prefix
+	}{
+		{`["--name", "bob"]`, `count=1 name=bob verbose=false _=[]`},
+		{`["--name=bob", "-count", "3", "--verbose", "x", "y"]`, `count=3 name=bob verbose=true _=[x,y]`},
+		{`["--name", "bob", "--verbose=false", "--", "--count"]`, `count=1 name=bob verbose=false _=[--count]`},
+		{`[]`, "ERROR: missing required flag: --name"},
+		{`["--name", "bob", "--count", "many"]`, `ERROR: invalid value "many" for flag --count: expected int`},
+		{`["--name", "bob", "--size", "2"]`, "ERROR: unknown flag: --size"},
+		{`["--name"]`, "ERROR: flag needs a value: --name"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval("parse_flags(" + spec + ", " + tt.argv + ")")
+		if strings.HasPrefix(tt.expected, "ERROR: ") {
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("%s: expected error, got=%T (%+v)", tt.argv, evaluated, evaluated)
+				continue
+			}
+			if !strings.HasPrefix(errObj.Inspect(), tt.expected) || !strings.Contains(errObj.Message, "Usage:") {
+				t.Errorf("%s: wrong error message. expected prefix %q, got=%q", tt.argv, tt.expected, errObj.Inspect())
+			}
+			continue
+		}
+
+		hash, ok := evaluated.(*object.Hash)
+		if !ok {
+			t.Errorf("%s: object is not Hash. got=%T (%+v)", tt.argv, evaluated, evaluated)
+			continue
+		}
+		for _, field := range strings.Fields(tt.expected) {
+			key, want, _ := strings.Cut(field, "=")
+			k := &object.String{Value: key}
+			pair, ok := hash.Pairs[k.HashKey()]
+			if !ok {
+				t.Errorf("%s: no value for %q", tt.argv, key)
+				continue
+			}
+			if got := pair.Value.Inspect(); got != strings.ReplaceAll(want, ",", ", ") {
+				t.Errorf("%s: wrong value for %q. want=%q, got=%q", tt.argv, key, want, got)
+			}
+		}
+	}
+}
+
+func TestArgs(t *testing.T) {
+	SetArgs([]string{"a", "--b"})
+	defer SetArgs(nil)
+
+	evaluated := testEval(`let f = parse_flags({"b": {"type": "bool"}}); [args(), f["b"], f["_"]]`)
+	if got := evaluated.Inspect(); got != "[[a, --b], true, [a]]" {
+		t.Errorf("wrong result. got=%q", got)
+	}
+}
+
+func TestArrayLiterals(t *testing.T) {
+	input := `[1, 2 * 2, 3 + 3]`
+
+	evaluated := testEval(input)
+	arr, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if len(arr.Elements) != 3 {
+		t.Fatalf("array has wrong number of elements. got=%d", len(arr.Elements))
+	}
+
+	testIntegerObject(t, arr.Elements[0], 1)
+	testIntegerObject(t, arr.Elements[1], 4)
+	testIntegerObject(t, arr.Elements[2], 6)
+}
+
+func TestSpreadElements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // Inspect() of the result, or an error message
+	}{
+		{"let xs = [2, 3]; [1, ...xs, 4]", "[1, 2, 3, 4]"},
+		{"[...[], ...[1], ...[]]", "[1]"},
+		{"let add = fn(a, b, c) { a + b * c }; let xs = [2, 3]; add(1, ...xs)", "7"},
+		{`let d = {"a": 1, "b": 2}; let h = {"a": 0, ...d, "b": 3}; [h["a"], h["b"]]`, "[1, 3]"},
+		{`let h = {...{"x": 1}}; h["x"]`, "1"},
+		{"[1, ...2]", "cannot spread INTEGER from `2`, want ARRAY"},
+		{`let h = {"a": 1}; [...h]`, "cannot spread HASH from `h`, want ARRAY"},
+		{`{...[1]}`, "cannot spread ARRAY from `[1]`, want HASH"},
+		{"[...missing]", "identifier not found: missing"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if errObj, ok := evaluated.(*object.Error); ok {
+			if errObj.Message != tt.expected {
+				t.Errorf("%q: wrong error message. expected=%q, got=%q", tt.input, tt.expected, errObj.Message)
+			}
+			continue
+		}
+		if got := evaluated.Inspect(); got != tt.expected {
+			t.Errorf("%q: wrong result. expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestFieldExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any // int64 result, nil for null, or an error message
+	}{
+		{`let person = {"name": "Ada", "age": 36}; person.age`, int64(36)},
+		{`let h = {"a": {"b": 2}}; h.a.b * 3`, int64(6)},
+		{`let h = {"a": 1}; h.b`, nil},
+		{`let h = {"f": fn(x) { x + 1 }}; h.f(1)`, int64(2)},
+		{`let h = {"a": 1}; h.b.c`, "index operator not supported: NULL"},
+		{`let h = {"a": 1}; h.b?.c ?? 4`, int64(4)},
+		{"let xs = [1]; xs.len", "index operator not supported: ARRAY"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int64:
+			testIntegerObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("%q: no error object returned. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("%q: wrong error message. expected=%q, got=%q", tt.input, expected, errObj.Message)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestMethodCalls(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any // int64, bool or string result, nil for null, or an error message
+	}{
+		{`"monke".upper()`, "MONKE"},
+		{"[1, 2, 3].len()", int64(3)},
+		{"[1, 2].push(3).last()", int64(3)},
+		{"let add = fn(a, b) { a + b }; 2.add(3)", int64(5)},
+		{"let add = fn(a, b) { a - b }; 10.add(b: 3)", int64(7)},
+		{"let map = fn(xs, f) { let out = []; for (x in xs) { out = out.push(f(x)) }; out }; [1, 2, 3].map(fn(x) { x * x }).last()", int64(9)},
+		{`let h = {"size": fn() { 42 }}; h.size()`, int64(42)},
+		{`let h = {"a": 1, "b": 2}; h.is_empty()`, false},
+		{`let n = if (false) { 1 }; n?.upper()`, nil},
+		{"5.missing()", "unknown method: INTEGER.missing"},
+		{"3.upper()", "argument to `upper` must be STRING, got INTEGER"},
+		{`let h = {"n": 1}; h.n()`, "not a function: INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int64:
+			testIntegerObject(t, evaluated, expected)
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			if errObj, ok := evaluated.(*object.Error); ok {
+				if errObj.Message != expected {
+					t.Errorf("%q: wrong error message. expected=%q, got=%q", tt.input, expected, errObj.Message)
+				}
+				continue
+			}
+			str, ok := evaluated.(*object.String)
+			if !ok || str.Value != expected {
+				t.Errorf("%q: expected %q. got=%T(%+v)", tt.input, expected, evaluated, evaluated)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestClasses(t *testing.T) {
+	const point = `class Point(x, y) {
+  fn norm() { self.x * self.x + self.y * self.y }
+  fn add(other) { Point(self.x + other.x, self.y + other.y) }
+  fn moved(dx) { merge(self, {"x": self.x + dx}) }
+  fn double() { self.add(self) }
+};
+`
+	tests := []struct {
+		input    string
+		expected any // int64 or string result, or an error message
+	}{
+		{point + "Point(3, 4).norm()", int64(25)},
+		{point + "Point(1, 2).add(Point(2, 2)).norm()", int64(25)},
+		{point + "Point(1, 2).double().y", int64(4)},
+		{point + "Point(1, 0).moved(2).moved(1).norm()", int64(16)},
+		{point + "let p = Point(y: 4, x: 3); p.x * 10 + p.y", int64(34)},
+		{point + "type(Point(1, 2))", "Point"},
+		{point + "type(Point)", "CLASS"},
+		{point + "match (Point(3, 4)) { Point {x, y} => x + y, _ => 0 }", int64(7)},
+		{point + "let norm = fn(p) { 0 }; Point(1, 1).norm()", int64(2)},
+		{point + "let add = fn(a, b) { a - b }; 5.add(3)", int64(2)},
+		{"class Empty() { fn answer() { 42 } }; Empty().answer()", int64(42)},
+		{"let n = 10; class Box(v) { fn add() { self.v + n } }; Box(1).add()", int64(11)},
+		{point + "Point(1)", "wrong number of arguments to Point. got=1, want=2"},
+		{point + "Point(1, 2).missing()", "unknown method: Point.missing"},
+		{point + "Point(1, y: 2, z: 3)", "unknown parameter: z"},
+		{point + "Point(x: 1)", "missing argument for parameter: y"},
+		{"#pragma strict\nlet Point = 1; class Point() {}", "identifier already declared: Point"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int64:
+			testIntegerObject(t, evaluated, expected)
+		case string:
+			if errObj, ok := evaluated.(*object.Error); ok {
+				if errObj.Message != expected {
+					t.Errorf("%q: wrong error message. expected=%q, got=%q", tt.input, expected, errObj.Message)
+				}
+				continue
+			}
+			str, ok := evaluated.(*object.String)
+			if !ok || str.Value != expected {
+				t.Errorf("%q: expected %q. got=%T(%+v)", tt.input, expected, evaluated, evaluated)
+			}
+		}
+	}
+
+	class, ok := testEval("class Point(x, y) {}; Point").(*object.Class)
+	if !ok {
+		t.Fatalf("class statement did not bind a Class")
+	}
+	if got := class.Inspect(); got != "class Point(x, y)" {
+		t.Errorf("wrong Inspect. got=%q", got)
+	}
+}
+
+func TestOptionalIndexAndNullish(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any // int64 result, nil for null, or an error message
+	}{
+		{`let h = {"a": {"b": 1}}; h?.a?.b`, int64(1)},
+		{`let h = {"a": {"b": 1}}; h?.["a"]?.["b"]`, int64(1)},
+		{`let h = {"a": {"b": 1}}; h?.x?.b`, nil},
+		{`let h = {"a": 1}; h?.x ?? 5`, int64(5)},
+		{`let xs = [1, 2]; xs?.[1]`, int64(2)},
+		{`let n = if (false) { 1 }; n?.[0]`, nil},
+		{"0 ?? 5", int64(0)},
+		{"let f = fn() { puts_missing() }; 1 ?? f()", int64(1)},
+		{"if (false) { 1 } ?? 2 * 3", int64(6)},
+		{`let h = {"a": 1}; h["x"]["y"]`, "index operator not supported: NULL"},
+		{`let h = {"a": 1}; h?.x["y"]`, "index operator not supported: NULL"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int64:
+			testIntegerObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("%q: no error object returned. got=%T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("%q: wrong error message. expected=%q, got=%q", tt.input, expected, errObj.Message)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestLongStringConcatenation(t *testing.T) {
+	// s is a rope, and ropes are the same hash key as flat strings with the same contents
+	input := `
+	let s = "";
+	for (let i = 0; i < 500; i = i + 1) { s = s + "ab" + "cd" }
+	let t = upper(s);
+	[len(s), is_empty(s), !!s, len(t), {s: 1}[t] ?? 2, {s: 1}[lower(t)]]
+	`
+	if got := testEval(input).Inspect(); got != "[2000, false, true, 2000, 2, 1]" {
+		t.Errorf("wrong result. got=%s", got)
+	}
+
+	evaluated := testEval(`let s = ""; for (let i = 0; i < 400; i = i + 1) { s = s + "ab" }; s`)
+	str, ok := evaluated.(*object.String)
+	if !ok {
+		t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+	}
+	if got := str.Flat(); got != strings.Repeat("ab", 400) {
+		t.Errorf("wrong value of the concatenation, of length %d", len(got))
+	}
+}
+
+func TestStringBuilder(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`b_string(builder())`, ""},
+		{`let b = builder(); b_write(b, "Hello"); b_write(b, ", "); b_write(b, "World"); b_string(b)`, "Hello, World"},
+		{`b_string(b_write(b_write(builder(), "a"), "b"))`, "ab"},
+		{`let b = builder(); b_write(b, "abc"); b`, "builder(3 bytes)"},
+		{`let b = builder(); let s = b_string(b_write(b, "a")); b_write(b, "b"); [s, b_string(b)]`, "[a, ab]"},
+		{`let b = builder(); for (x in ["x", "y", "z"]) { b_write(b, x) }; len(b_string(b))`, "3"},
+		{`type(builder())`, "BUILDER"},
+		{`builder(1)`, "ERROR: wrong number of arguments. got=1, want=0"},
+		{`b_write("a", "b")`, "ERROR: first argument to `b_write` must be BUILDER, got STRING"},
+		{`b_write(builder(), 1)`, "ERROR: second argument to `b_write` must be STRING, got INTEGER"},
+		{`b_string("a")`, "ERROR: argument to `b_string` must be BUILDER, got STRING"},
+	}
+
+	for _, tt := range tests {
+		if got := testEval(tt.input).Inspect(); got != tt.expected {
+			t.Errorf("%q: expected=%s, got=%s", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestSliceAndSplice(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"slice([1, 2, 3, 4], 1, 3)", "[2, 3]"},
+		{"slice([1, 2, 3, 4], 2)", "[3, 4]"},
+		{"slice([1, 2, 3, 4], -2)", "[3, 4]"},
+		{"slice([1, 2, 3, 4], 0, -1)", "[1, 2, 3]"},
+		{"slice([1, 2, 3, 4], -10, 10)", "[1, 2, 3, 4]"},
+		{"slice([1, 2, 3, 4], 3, 1)", "[]"},
+		{"slice([], 0)", "[]"},
+		{"let xs = [1, 2, 3]; let ys = slice(xs, 0, 2); [xs, ys]", "[[1, 2, 3], [1, 2]]"},
+		{"splice([1, 2, 3, 4], 1, 2)", "[1, 4]"},
+		{"splice([1, 2, 3, 4], 1, 2, \"a\", \"b\", \"c\")", "[1, a, b, c, 4]"},
+		{"splice([1, 2, 3], 1, 0, 9)", "[1, 9, 2, 3]"},
+		{"splice([1, 2, 3], -1, 5)", "[1, 2]"},
+		{"splice([1, 2, 3], 10, 1, 4)", "[1, 2, 3, 4]"},
+		{"let xs = [1, 2, 3]; let ys = splice(xs, 0, 1); [xs, ys]", "[[1, 2, 3], [2, 3]]"},
+		{"slice(\"abc\", 1)", "ERROR: first argument to `slice` must be ARRAY, got STRING"},
+		{"slice([1], \"a\")", "ERROR: second argument to `slice` must be INTEGER, got STRING"},
+		{"slice([1], 0, true)", "ERROR: third argument to `slice` must be INTEGER, got BOOLEAN"},
+		{"slice([1])", "ERROR: wrong number of arguments. got=1, want=2 or 3"},
+		{"splice([1], 0)", "ERROR: wrong number of arguments. got=2, want at least 3"},
+		{"splice([1], 0, -1)", "ERROR: third argument to `splice` must not be negative, got -1"},
+	}
+
+	for _, tt := range tests {
+		if got := testEval(tt.input).Inspect(); got != tt.expected {
+			t.Errorf("%q: expected=%s, got=%s", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestTypeTags(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // Inspect() of the result, or an error message
+	}{
+		{`type(1)`, "INTEGER"},
+		{`type("a")`, "STRING"},
+		{`type(fn() {})`, "FUNCTION"},
+		{`type({})`, "HASH"},
+		{`let p = tag({"x": 1}, "Point"); type(p)`, "Point"},
+		{`let p = tag({"x": 1}, "Point"); p`, "Point{x: 1}"},
+		{`let p = tag({"x": 1}, "Point"); p["x"]`, "1"},
+		{`let h = {"x": 1}; let p = tag(h, "Point"); type(h)`, "HASH"},
+		{`let p = tag({"x": 1}, "Point"); type(tag(p, ""))`, "HASH"},
+		{`let p = tag({"x": 1}, "Point"); type({...p})`, "HASH"},
+		{`tag([1], "List")`, "first argument to `tag` must be HASH, got ARRAY"},
+		{`tag({}, 1)`, "second argument to `tag` must be STRING, got INTEGER"},
+		{`type()`, "wrong number of arguments. got=0, want=1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		var got string
+		switch obj := evaluated.(type) {
+		case *object.String:
+			got = obj.Value
+		case *object.Error:
+			got = obj.Message
+		default:
+			got = evaluated.Inspect()
+		}
+		if got != tt.expected {
+			t.Errorf("%q: wrong result. expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestArrayIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{
+			"[1, 2, 3][0]",
+			1,
+		},
+		{
+			"[1, 2, 3][1]",
+			2,
+		},
 		{
 			"[1, 2, 3][2]",
 			3,
@@ -489,6 +2110,88 @@ func TestArrayIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestStringIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // Inspect() of the result
+	}{
+		{`"hello"[0]`, "h"},
+		{`"hello"[1]`, "e"},
+		{`let s = "hello"; s[len(s) - 1]`, "o"},
+		{`"hello"[1] + "hello"[4]`, "eo"},
+		{`"hello"[5]`, "null"},
+		{`"hello"[-1]`, "null"},
+		{`""[0]`, "null"},
+		{`let s = "abc"; let out = ""; for (let i = len(s) - 1; i >= 0; i = i - 1) { out = out + s[i] }; out`, "cba"},
+		{`let long = "` + strings.Repeat("x", 100) + `"; (long + long + "!")[200]`, "!"},
+		{`let s = "abc"; s?.[1]`, "b"},
+		{`"héllo"[1]`, "é"},
+		{`"héllo"[2]`, "l"},
+		{`"héllo"[4]`, "o"},
+		{`"héllo"[5]`, "null"},
+		{`"日本語"[0] + "日本語"[2]`, "日語"},
+		{`"日本語"[3]`, "null"},
+		{`"añ"[1]`, "ñ"},
+		{`"añ"[2]`, "null"},
+		{`"🐒!"[0]`, "🐒"},
+		{`"🐒!"[1]`, "!"},
+		{`bytes_string(bytes([97, 255, 98]))[1]`, "\xff"},
+		{`bytes_string(bytes([97, 255, 98]))[2]`, "b"},
+		{`let long = "` + strings.Repeat("é", 600) + `"; (long + long + "!")[1200]`, "!"},
+		{`let s = "` + strings.Repeat("é", 600) + `!"; s[len(s) - 1]`, "!"},
+		{`"abc"["a"]`, "ERROR: index operator not supported: STRING"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: got %s, want %s", tt.input, evaluated.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // Inspect() of the result
+	}{
+		{"[1, 2, 3, 4, 5][1:3]", "[2, 3]"},
+		{"[1, 2, 3, 4, 5][:2]", "[1, 2]"},
+		{"[1, 2, 3, 4, 5][2:]", "[3, 4, 5]"},
+		{"[1, 2, 3][:]", "[1, 2, 3]"},
+		{"[1, 2, 3, 4, 5][-2:]", "[4, 5]"},
+		{"[1, 2, 3, 4, 5][:-1]", "[1, 2, 3, 4]"},
+		{"[1, 2, 3][1:100]", "[2, 3]"},
+		{"[1, 2, 3][-100:1]", "[1]"},
+		{"[1, 2, 3][2:1]", "[]"},
+		{"[][0:1]", "[]"},
+		{`"hello"[1:3]`, "el"},
+		{`"hello"[:-1]`, "hell"},
+		{`"hello"[3:]`, "lo"},
+		{`"hello"[10:]`, ""},
+		// Slices count characters, as indexing and len do
+		{`"héllo"[0:2]`, "hé"},
+		{`"héllo"[-3:]`, "llo"},
+		{`"日本語"[1:]`, "本語"},
+		{`"🐒!"[:1]`, "🐒"},
+		{`let s = "` + strings.Repeat("é", 100) + `xyz"; s[len(s) - 3:]`, "xyz"},
+		{`let h = {"xs": [1, 2, 3]}; h?.xs?.[1:]`, "[2, 3]"},
+		{`let h = {}; h?.xs?.[1:]`, "null"},
+		{"let n = 3; [1, 2, 3, 4][n - 2:n]", "[2, 3]"},
+		{"5[1:2]", "ERROR: slice operator not supported: INTEGER"},
+		{`{"a": 1}[0:]`, "ERROR: slice operator not supported: HASH"},
+		{`[1, 2][:"x"]`, "ERROR: slice index must be INTEGER, got STRING"},
+		{"[1, 2][missing:]", "ERROR: identifier not found: missing"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: got %s, want %s", tt.input, evaluated.Inspect(), tt.expected)
+		}
+	}
+}
+
 func TestHashLiterals(t *testing.T) {
 	input := `let two = "two";
     {
@@ -527,6 +2230,140 @@ func TestHashLiterals(t *testing.T) {
 	}
 }
 
+func TestMerge(t *testing.T) {
+	input := `let a = {"one": 1, 2: 2, true: 3};
+    let b = {"one": 10, false: 4};
+    let merged = merge(a, b);
+    [merged, a, b]`
+
+	evaluated := testEval(input)
+	results, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf("Eval didn't return Array. got=%T (%+v)", evaluated, evaluated)
+	}
+	expected := []map[object.HashKey]int64{
+		{
+			(&object.String{Value: "one"}).HashKey(): 10,
+			(&object.Integer{Value: 2}).HashKey():    2,
+			TRUE.HashKey():                           3,
+			FALSE.HashKey():                          4,
+		},
+		// The arguments are left as they were
+		{
+			(&object.String{Value: "one"}).HashKey(): 1,
+			(&object.Integer{Value: 2}).HashKey():    2,
+			TRUE.HashKey():                           3,
+		},
+		{
+			(&object.String{Value: "one"}).HashKey(): 10,
+			FALSE.HashKey():                          4,
+		},
+	}
+	for i, want := range expected {
+		hash, ok := results.Elements[i].(*object.Hash)
+		if !ok {
+			t.Fatalf("results[%d] is not Hash. got=%T", i, results.Elements[i])
+		}
+		if len(hash.Pairs) != len(want) {
+			t.Fatalf("results[%d] has wrong num of pairs. got=%d", i, len(hash.Pairs))
+		}
+		for key, value := range want {
+			pair, ok := hash.Pairs[key]
+			if !ok {
+				t.Errorf("results[%d]: no pair for given key in Pairs", i)
+				continue
+			}
+			if pair.Key.(object.Hashable).HashKey() != key {
+				t.Errorf("results[%d]: pair stored under the wrong key: %s", i, pair.Key.Inspect())
+			}
+			testIntegerObject(t, pair.Value, value)
+		}
+	}
+
+	tests := []struct {
+		input    string
+		expected string // Inspect() of the result, or an error message
+	}{
+		{`let p = merge(tag({"x": 1}, "Point"), {"x": 2}); [type(p), p.x]`, "[Point, 2]"},
+		{`type(merge({}, tag({}, "Point")))`, "HASH"},
+		{`merge([1], {})`, "first argument to `merge` must be HASH, got ARRAY"},
+		{`merge({}, 1)`, "second argument to `merge` must be HASH, got INTEGER"},
+		{`merge({})`, "wrong number of arguments. got=1, want=2"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		got := evaluated.Inspect()
+		if err, ok := evaluated.(*object.Error); ok {
+			got = err.Message
+		}
+		if got != tt.expected {
+			t.Errorf("%q: wrong result. expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestRanges(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // Inspect() of the result, or an error message
+	}{
+		{`1..5`, "[1, 2, 3, 4]"},
+		{`1..=5`, "[1, 2, 3, 4, 5]"},
+		{`-2..1`, "[-2, -1, 0]"},
+		{`3..3`, "[]"},
+		{`3..=3`, "[3]"},
+		{`5..1`, "[]"},
+		{`let n = 3; 0..n + 1`, "[0, 1, 2, 3]"},
+		{`[len(1..=10), (0..10)[9], (0..10)[2:4]]`, "[10, 9, [2, 3]]"},
+		{`let total = 0; for (i in 1..=100) { total = total + i }; total`, "5050"},
+		{`0..=9223372036854775807`, "range is too long: 0..=9223372036854775807"},
+		{`1.5..3`, "unknown operator: FLOAT .. FLOAT"},
+		{`"a"..="z"`, "unknown operator: STRING ..= STRING"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		got := evaluated.Inspect()
+		if err, ok := evaluated.(*object.Error); ok {
+			got = err.Message
+		}
+		if got != tt.expected {
+			t.Errorf("%q: wrong result. expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestBytes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // Inspect() of the result, or an error message
+	}{
+		{`bytes("hi")`, `bytes("hi")`},
+		{`bytes([0, 127, 255])`, `bytes("\x00\x7f\xff")`},
+		{`let b = bytes("monke"); [len(b), b[0], b[-1], b[5], type(b)]`, "[5, 109, null, null, BYTES]"},
+		{`let b = bytes("monke"); [b[1:3], b[:-1], b[3:1]]`, `[bytes("on"), bytes("monk"), bytes("")]`},
+		{`bytes_string(bytes([104, 105]))`, "hi"},
+		{`let b = bytes("ab"); [b + bytes("c"), b == bytes("ab"), b != bytes("ab"), b == bytes("a")]`,
+			`[bytes("abc"), true, false, false]`},
+		{`let s = 0; for (i, x in bytes([1, 2, 3])) { s = s + i * x }; s`, "8"},
+		{`bytes("a") < bytes("b")`, "unknown operator: BYTES < BYTES"},
+		{`bytes("a") == "a"`, "false"},
+		{`bytes([1, "2"])`, "element 1 of the argument to `bytes` must be an INTEGER from 0 to 255, got 2"},
+		{`bytes([256])`, "element 0 of the argument to `bytes` must be an INTEGER from 0 to 255, got 256"},
+		{`bytes(1)`, "argument to `bytes` must be STRING or ARRAY, got INTEGER"},
+		{`bytes_string("a")`, "argument to `bytes_string` must be BYTES, got STRING"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		got := evaluated.Inspect()
+		if err, ok := evaluated.(*object.Error); ok {
+			got = err.Message
+		}
+		if got != tt.expected {
+			t.Errorf("%q: wrong result. expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
 func TestHashIndexExpressions(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -582,6 +2419,50 @@ func testNullObject(t *testing.T, obj object.Object) bool {
 	return true
 }
 
+func TestErrorCauses(t *testing.T) {
+	defer SetStepLimit(0)
+	defer SetDepthLimit(0)
+	defer SetMemoryLimit(0)
+	defer SetModuleResolver(nil)
+
+	tests := []struct {
+		input string
+		setup func()
+		cause error
+	}{
+		{`while (true) {}`, func() { SetStepLimit(100) }, ErrStepLimit},
+		{`let f = fn(n) { f(n + 1) }; f(0)`, func() { SetDepthLimit(10) }, ErrDepthLimit},
+		{`let s = "x"; while (true) { s = s + s }`, func() { SetMemoryLimit(1 << 16) }, ErrMemoryLimit},
+		{`1`, Interrupt, ErrInterrupted},
+		{`import "missing";`, func() { SetModuleResolver(modules.Memory{}) }, modules.ErrNotFound},
+		{`1 + true`, func() {}, nil},
+	}
+
+	for _, tt := range tests {
+		SetStepLimit(0)
+		SetDepthLimit(0)
+		SetMemoryLimit(0)
+		tt.setup()
+		err := AsError(testEval(tt.input))
+		var runtimeErr *RuntimeError
+		if !errors.As(err, &runtimeErr) {
+			t.Errorf("%s: expected a *RuntimeError, got=%v", tt.input, err)
+			continue
+		}
+		if tt.cause == nil && errors.Unwrap(err) != nil || tt.cause != nil && !errors.Is(err, tt.cause) {
+			t.Errorf("%s: wrong cause. expected=%v, got=%v", tt.input, tt.cause, errors.Unwrap(err))
+		}
+	}
+
+	if err := AsError(testEval("1 + 1")); err != nil {
+		t.Errorf("AsError of a value wrong. expected nil, got=%v", err)
+	}
+	err := AsError(&object.Error{Message: "boom", Pos: token.Position{Line: 2, Column: 3}})
+	if err.Error() != "2:3: boom" {
+		t.Errorf("Error wrong. expected=%q, got=%q", "2:3: boom", err.Error())
+	}
+}
+
 func testEval(input string) object.Object {
 	l := lexer.New(input)
 	p := parser.New(l)
@@ -618,3 +2499,182 @@ func testBooleanObject(t *testing.T, obj object.Object, expected bool) bool {
 	}
 	return true
 }
+
+func TestConstantMemo(t *testing.T) {
+	program := parser.New(lexer.New("let f = fn(x) { x * (60 * 60) }; f(1) + f(2) + (9223372036854775807 + 1)")).ParseProgram()
+	hour := program.Statements[0].(*ast.LetStatement).Value.(*ast.FunctionLiteral).
+		Body.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.InfixExpression).Right.(*ast.InfixExpression)
+
+	// Overflow wraps around in legacy mode, and is an error in strict mode,
+	// which memoized values must not hide
+	for i, strict := range []bool{false, true, false} {
+		env := object.NewEnvironment()
+		env.SetStrict(strict)
+		evaluated := Eval(program, env)
+		if strict {
+			if !isError(evaluated) {
+				t.Errorf("run %d: expected an overflow error in strict mode, got=%s", i, evaluated.Inspect())
+			}
+			continue
+		}
+		testIntegerObject(t, evaluated, 10800+math.MinInt64)
+	}
+
+	m, ok := hour.Cache.(*constMemo)
+	if !ok || !m.constant || m.value == nil || m.value.Inspect() != "3600" {
+		t.Errorf("expected 60 * 60 to be memoized as 3600, got=%+v", hour.Cache)
+	}
+	if m := program.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.InfixExpression).Cache; m != notConstant {
+		t.Errorf("expected an expression using f not to be memoized, got=%+v", m)
+	}
+}
+
+func TestStepAndDepthLimits(t *testing.T) {
+	defer SetStepLimit(0)
+	defer SetDepthLimit(0)
+
+	tests := []struct {
+		input    string
+		expected string // The prefix of the error message, or "" for none
+	}{
+		{`while (true) {}`, "step limit exceeded: "},
+		{`for (let i = 0; true; i = i + 1) { i }`, "step limit exceeded: "},
+		{`let f = fn(n) { f(n + 1) }; f(0)`, "call depth limit exceeded: "},
+		{`let f = fn(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } }; f(50)`, ""},
+		{`let i = 0; while (i < 100) { i = i + 1 }; i`, ""},
+	}
+
+	for _, tt := range tests {
+		SetStepLimit(1000)
+		SetDepthLimit(100)
+		evaluated := testEval(tt.input)
+		err, isErr := evaluated.(*object.Error)
+		if tt.expected == "" {
+			if isErr {
+				t.Errorf("%s: unexpected error: %s", tt.input, err.Message)
+			}
+			continue
+		}
+		if !isErr || !strings.HasPrefix(err.Message, tt.expected) {
+			t.Errorf("%s: expected an error starting with %q, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+
+	if len(calls) != 0 {
+		t.Errorf("calls left after the runs: %d", len(calls))
+	}
+
+	SetStepLimit(0)
+	Interrupt()
+	if evaluated := testEval(`while (true) {}`); evaluated.Inspect() != "ERROR: interrupted" {
+		t.Errorf("expected the run to be interrupted, got=%s", evaluated.Inspect())
+	}
+	SetStepLimit(0)
+	if evaluated := testEval(`1 + 1`); evaluated.Inspect() != "2" {
+		t.Errorf("expected SetStepLimit to clear the interrupt, got=%s", evaluated.Inspect())
+	}
+
+	// Steps are counted without a limit too
+	SetStepLimit(0)
+	testEval(`let i = 0; while (i < 10) { i = i + 1 }`)
+	if got := Steps(); got < 10 {
+		t.Errorf("Steps wrong. expected at least 10, got=%d", got)
+	}
+}
+
+// requestTracer calls request when the statement "1" is entered.
+type requestTracer struct{ request func() }
+
+func (r requestTracer) EnterStatement(stmt ast.Statement) {
+	if stmt.String() == "1" {
+		r.request()
+	}
+}
+
+func (requestTracer) ExitStatement(ast.Statement, object.Object) {}
+
+func TestBacktrace(t *testing.T) {
+	var frames []Frame
+	SetBacktraceHandler(func(f []Frame) { frames = f })
+	defer SetBacktraceHandler(nil)
+	SetTracer(requestTracer{RequestBacktrace})
+	defer SetTracer(nil)
+
+	input := "let inner = fn() { 1; 2 };\nlet outer = fn() {\n  fn(x) { inner() }(0)\n};\nouter();"
+	if evaluated := testEval(input); isError(evaluated) {
+		t.Fatalf("unexpected error: %s", evaluated.Inspect())
+	}
+
+	// The backtrace is taken at the step after the request
+	expected := []Frame{
+		{"inner", token.Position{Line: 1, Column: 23}},
+		{Anonymous, token.Position{Line: 3, Column: 11}},
+		{"outer", token.Position{Line: 3, Column: 3}},
+		{"", token.Position{Line: 5, Column: 1}},
+	}
+	if !slices.Equal(frames, expected) {
+		t.Errorf("wrong backtrace.\nexpected=%v\ngot=     %v", expected, frames)
+	}
+	if len(calls) != 0 || len(Backtrace()) != 1 {
+		t.Errorf("calls left after the run: %d", len(calls))
+	}
+}
+
+func TestInterruptRunsDeferred(t *testing.T) {
+	var out strings.Builder
+	SetOutput(&out)
+	defer SetOutput(os.Stdout)
+	SetTracer(requestTracer{Interrupt})
+	defer SetTracer(nil)
+
+	evaluated := testEval(`let f = fn() { defer { puts("inner") }; 1; 2 }; defer { puts("outer") }; f(); 3`)
+	if evaluated.Inspect() != "ERROR: interrupted" {
+		t.Errorf("expected the run to be interrupted, got=%s", evaluated.Inspect())
+	}
+	if out.String() != "inner\nouter\n" {
+		t.Errorf("expected the deferred blocks to run, got output %q", out.String())
+	}
+}
+
+func TestResources(t *testing.T) {
+	closed := map[string]int{}
+	open := func(name string, err error) *object.Resource {
+		return OpenResource("file", name, func() error {
+			closed[name]++
+			return err
+		})
+	}
+	eval := func(input string, r *object.Resource) object.Object {
+		env := object.NewEnvironment()
+		env.Set("r", r)
+		return Eval(parser.New(lexer.New(input)).ParseProgram(), env)
+	}
+
+	if got := eval(`[type(r), close(r), close(r), r]`, open("b", nil)).Inspect(); got != `[RESOURCE, null, null, closed file("b")]` {
+		t.Errorf("wrong result of closing a resource twice. got=%s", got)
+	}
+	if closed["b"] != 1 {
+		t.Errorf("expected the resource to be closed once, got %d", closed["b"])
+	}
+	if got := eval(`close(r)`, open("c", os.ErrClosed)).Inspect(); got != `ERROR: closing closed file("c"): `+os.ErrClosed.Error() {
+		t.Errorf("wrong error of closing a resource. got=%s", got)
+	}
+	if got := testEval(`close(1)`).Inspect(); got != "ERROR: argument to `close` must be RESOURCE, got INTEGER" {
+		t.Errorf("wrong error of closing a non-resource. got=%s", got)
+	}
+
+	// Interrupted runs close the resources left open, after the deferred blocks
+	var out strings.Builder
+	SetOutput(&out)
+	defer SetOutput(os.Stdout)
+	SetTracer(requestTracer{Interrupt})
+	defer SetTracer(nil)
+
+	r := open("d", nil)
+	if got := eval(`defer { puts(r) }; 1; 2`, r).Inspect(); got != "ERROR: interrupted" {
+		t.Errorf("expected the run to be interrupted, got=%s", got)
+	}
+	if out.String() != "file(\"d\")\n" || !r.Closed() {
+		t.Errorf("expected the resource to be closed after the deferred blocks, got output %q, closed=%t", out.String(), r.Closed())
+	}
+}
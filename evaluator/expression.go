@@ -0,0 +1,51 @@
+package evaluator
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dr8co/monke/ast"
+	"github.com/dr8co/monke/lexer"
+	"github.com/dr8co/monke/object"
+	"github.com/dr8co/monke/parser"
+)
+
+// ErrNotExpression is the error of EvalExpression for a source that parses
+// but is not a single expression, such as a let statement.
+var ErrNotExpression = errors.New("not an expression")
+
+// EvalExpression parses src, which must hold a single expression, and
+// evaluates it in env, or a new environment if env is nil, e.g. for a watch expression or a snippet of a
+// configuration file. It returns a *parser.ErrorList if src has syntax
+// errors, an error wrapping ErrNotExpression if it is something else, such
+// as a statement or several expressions, and an *evaluator.RuntimeError if
+// the expression fails.
+//
+// Unlike evaluating a program, it leaves the language mode of env as it is
+// and does not run the blocks deferred in env.
+func EvalExpression(src string, env *object.Environment) (object.Object, error) {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if err := p.Err(); err != nil {
+		return nil, err
+	}
+	if len(program.Statements) != 1 {
+		return nil, fmt.Errorf("%w: %q has %d statements", ErrNotExpression, src, len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotExpression, program.Statements[0].String())
+	}
+
+	if env == nil {
+		env = object.NewEnvironment()
+	}
+	pushFrame(env)
+	defer popFrame()
+	value := Eval(stmt.Expression, env)
+	if err, ok := value.(*object.Error); ok {
+		setErrorPos(err, stmt)
+		return nil, &RuntimeError{Err: err}
+	}
+	return value, nil
+}
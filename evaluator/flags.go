@@ -0,0 +1,266 @@
+package evaluator
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dr8co/monke/object"
+)
+
+// scriptArgs holds the command-line arguments passed to the running script.
+var scriptArgs []string
+
+// SetArgs sets the command-line arguments returned by the `args` builtin.
+func SetArgs(args []string) {
+	scriptArgs = args
+}
+
+func init() {
+	builtins["args"] = &object.Builtin{Fn: argsBuiltin}
+	builtins["parse_flags"] = &object.Builtin{Fn: parseFlagsBuiltin}
+}
+
+// argsBuiltin implements args(), returning the script arguments as an array of strings.
+func argsBuiltin(args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return newError("wrong number of arguments. got=%d, want=0", len(args))
+	}
+	elements := make([]object.Object, len(scriptArgs))
+	for i, arg := range scriptArgs {
+		elements[i] = &object.String{Value: arg}
+	}
+	return &object.Array{Elements: elements}
+}
+
+// flagSpec describes a single flag accepted by parse_flags.
+type flagSpec struct {
+	name     string
+	kind     string // "int", "string" or "bool"
+	def      object.Object
+	help     string
+	required bool
+}
+
+// parseFlagsBuiltin implements parse_flags(spec[, argv]).
+// spec maps flag names to hashes with a "type" ("int", "string" or "bool"),
+// an optional "default" and an optional "help" text. Flags without a default
+// are required, except for booleans, which default to false.
+//
+// The result maps each flag name to its value, "_" to an array of the
+// positional arguments, and "_usage" to a usage message generated from spec.
+// Invalid arguments produce an error that includes the usage message.
+func parseFlagsBuiltin(args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+	}
+	specHash, ok := args[0].(*object.Hash)
+	if !ok {
+		return newError("first argument to `parse_flags` must be HASH, got %s", args[0].Type())
+	}
+	argv := scriptArgs
+	if len(args) == 2 {
+		arr, ok := args[1].(*object.Array)
+		if !ok {
+			return newError("second argument to `parse_flags` must be ARRAY, got %s", args[1].Type())
+		}
+		argv = make([]string, len(arr.Elements))
+		for i, el := range arr.Elements {
+			s, ok := el.(*object.String)
+			if !ok {
+				return newError("arguments to `parse_flags` must be STRING, got %s", el.Type())
+			}
+			argv[i] = s.Flat()
+		}
+	}
+
+	specs, errObj := parseFlagSpecs(specHash)
+	if errObj != nil {
+		return errObj
+	}
+	usage := flagUsage(specs)
+
+	values := make(map[string]object.Object, len(specs))
+	var positional []object.Object
+	for i := 0; i < len(argv); i++ {
+		arg := argv[i]
+		if arg == "--" {
+			for _, rest := range argv[i+1:] {
+				positional = append(positional, &object.String{Value: rest})
+			}
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			positional = append(positional, &object.String{Value: arg})
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(arg[1:], "-"), "=")
+		if name == "h" || name == "help" {
+			return newError("%s", usage)
+		}
+		spec, ok := specs[name]
+		if !ok {
+			return newError("unknown flag: %s\n%s", arg, usage)
+		}
+		if !hasValue {
+			if spec.kind == "bool" {
+				value = "true"
+			} else {
+				if i+1 == len(argv) {
+					return newError("flag needs a value: --%s\n%s", name, usage)
+				}
+				i++
+				value = argv[i]
+			}
+		}
+		parsed, err := parseFlagValue(spec.kind, value)
+		if err != nil {
+			return newError("invalid value %q for flag --%s: expected %s\n%s", value, name, spec.kind, usage)
+		}
+		values[name] = parsed
+	}
+
+	result := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair, len(specs)+2)}
+	for _, name := range sortedFlagNames(specs) {
+		spec := specs[name]
+		value, ok := values[name]
+		if !ok {
+			if spec.required {
+				return newError("missing required flag: --%s\n%s", name, usage)
+			}
+			value = spec.def
+		}
+		setHashPair(result, name, value)
+	}
+	if positional == nil {
+		positional = []object.Object{}
+	}
+	setHashPair(result, "_", &object.Array{Elements: positional})
+	setHashPair(result, "_usage", &object.String{Value: usage})
+	return result
+}
+
+// parseFlagSpecs validates the spec hash passed to parse_flags.
+func parseFlagSpecs(hash *object.Hash) (map[string]*flagSpec, *object.Error) {
+	specs := make(map[string]*flagSpec, len(hash.Pairs))
+	// Sorted, so that the same spec always reports the same error
+	for _, pair := range hash.SortedPairs() {
+		name, ok := pair.Key.(*object.String)
+		if !ok || name.Len() == 0 || strings.HasPrefix(name.Flat(), "_") {
+			return nil, newError("invalid flag name: %s", pair.Key.Inspect())
+		}
+		desc, ok := pair.Value.(*object.Hash)
+		if !ok {
+			return nil, newError("spec for flag %q must be HASH, got %s", name.Value, pair.Value.Type())
+		}
+
+		spec := &flagSpec{name: name.Value, kind: "string"}
+		if typ, ok := hashString(desc, "type"); ok {
+			spec.kind = typ
+		}
+		if help, ok := hashString(desc, "help"); ok {
+			spec.help = help
+		}
+
+		var want object.Type
+		switch spec.kind {
+		case "int":
+			want = object.INTEGER_OBJ
+		case "string":
+			want = object.STRING_OBJ
+		case "bool":
+			want = object.BOOLEAN_OBJ
+		default:
+			return nil, newError("unknown type %q for flag %q, want int, string or bool", spec.kind, name.Value)
+		}
+
+		key := &object.String{Value: "default"}
+		if def, ok := desc.Pairs[key.HashKey()]; ok {
+			if def.Value.Type() != want {
+				return nil, newError("default for flag %q must be %s, got %s", name.Value, want, def.Value.Type())
+			}
+			spec.def = def.Value
+		} else if spec.kind == "bool" {
+			spec.def = FALSE
+		} else {
+			spec.required = true
+		}
+		specs[spec.name] = spec
+	}
+	return specs, nil
+}
+
+// hashString returns the string stored under key in hash, if any.
+func hashString(hash *object.Hash, key string) (string, bool) {
+	k := &object.String{Value: key}
+	pair, ok := hash.Pairs[k.HashKey()]
+	if !ok {
+		return "", false
+	}
+	s, ok := pair.Value.(*object.String)
+	if !ok {
+		return "", false
+	}
+	return s.Flat(), true
+}
+
+func parseFlagValue(kind, value string) (object.Object, error) {
+	switch kind {
+	case "int":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return getIntegerObject(n), nil
+	case "bool":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, err
+		}
+		return nativeBoolToBooleanObject(b), nil
+	default:
+		return &object.String{Value: value}, nil
+	}
+}
+
+func sortedFlagNames(specs map[string]*flagSpec) []string {
+	names := make([]string, 0, len(specs))
+	for name := range specs {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// flagUsage returns a usage message listing the flags in specs,
+// in the style of Go's flag package.
+func flagUsage(specs map[string]*flagSpec) string {
+	var b strings.Builder
+	b.WriteString("Usage:")
+	for _, name := range sortedFlagNames(specs) {
+		spec := specs[name]
+		fmt.Fprintf(&b, "\n  --%s", name)
+		if spec.kind != "bool" {
+			fmt.Fprintf(&b, " %s", spec.kind)
+		}
+		var details []string
+		if spec.help != "" {
+			details = append(details, spec.help)
+		}
+		switch {
+		case spec.required:
+			details = append(details, "(required)")
+		case spec.kind == "string":
+			details = append(details, fmt.Sprintf("(default %q)", spec.def.Inspect()))
+		case spec.kind == "int":
+			details = append(details, fmt.Sprintf("(default %s)", spec.def.Inspect()))
+		}
+		if len(details) > 0 {
+			b.WriteString("\n    \t")
+			b.WriteString(strings.Join(details, " "))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,33 @@
+package evaluator
+
+import (
+	"maps"
+
+	"github.com/dr8co/monke/object"
+)
+
+func init() {
+	builtins["merge"] = &object.Builtin{Fn: mergeBuiltin}
+}
+
+// mergeBuiltin implements merge(a, b), returning a new hash with the pairs of
+// both hashes. The pairs of b replace those of a with an equal key, and the
+// result keeps the tag and class of a, so merging updates into a tagged hash
+// or an instance keeps its type and methods.
+func mergeBuiltin(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	a, ok := args[0].(*object.Hash)
+	if !ok {
+		return newError("first argument to `merge` must be HASH, got %s", args[0].Type())
+	}
+	b, ok := args[1].(*object.Hash)
+	if !ok {
+		return newError("second argument to `merge` must be HASH, got %s", args[1].Type())
+	}
+	pairs := make(map[object.HashKey]object.HashPair, len(a.Pairs)+len(b.Pairs))
+	maps.Copy(pairs, a.Pairs)
+	maps.Copy(pairs, b.Pairs)
+	return &object.Hash{Pairs: pairs, Tag: a.Tag, Class: a.Class}
+}
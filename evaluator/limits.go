@@ -0,0 +1,79 @@
+package evaluator
+
+import (
+	"sync/atomic"
+
+	"github.com/dr8co/monke/object"
+)
+
+// stepLimit caps the number of steps, statements and loop iterations, a run
+// may take; 0 means no limit. steps counts the steps taken so far.
+var stepLimit, steps int
+
+// depthLimit caps the number of nested function calls; 0 means no limit.
+var depthLimit int
+
+// The requests other goroutines make to the running evaluation, which it
+// handles at its next step.
+const (
+	interruptRequest uint32 = 1 << iota
+	backtraceRequest
+)
+
+// requests holds the pending requests.
+var requests atomic.Uint32
+
+// SetStepLimit caps the number of statements and loop iterations the following
+// evaluation may run before failing with a "step limit exceeded" error, which
+// stops infinite loops. A limit of 0, the default, removes the cap. It also
+// restarts the count and clears a pending Interrupt, so it is meant to be
+// called before each evaluation, e.g. each input of a REPL.
+func SetStepLimit(limit int) {
+	stepLimit = max(limit, 0)
+	steps = 0
+	requests.And(^interruptRequest)
+}
+
+// Steps returns the number of steps, statements and loop iterations, run
+// since the last call of SetStepLimit, e.g. to compare the work two versions
+// of a program do independently of the speed of the machine.
+func Steps() int {
+	return steps
+}
+
+// SetDepthLimit caps the number of nested function calls before failing with a
+// "call depth limit exceeded" error, which stops runaway recursion before it
+// exhausts the stack. A limit of 0, the default, removes the cap.
+func SetDepthLimit(limit int) {
+	depthLimit = max(limit, 0)
+}
+
+// Interrupt makes the running evaluation fail with an "interrupted" error at
+// its next step. It is safe to call from another goroutine, e.g. when the user
+// presses Ctrl+C while the REPL evaluates an input. The error unwinds the
+// evaluation like any other, so deferred blocks still run, and only the step
+// that handles the interrupt fails: the deferred blocks are not interrupted
+// too unless Interrupt is called again. Once they have run, the resources
+// still open are closed (see CloseResources).
+func Interrupt() {
+	requests.Or(interruptRequest)
+}
+
+// step counts a step of the evaluation. It returns an error if the evaluation
+// was interrupted or ran out of steps, or nil.
+func step() *object.Error {
+	if requests.Load() != 0 {
+		pending := requests.Swap(0)
+		if pending&backtraceRequest != 0 && backtraceHandler != nil {
+			backtraceHandler(Backtrace())
+		}
+		if pending&interruptRequest != 0 {
+			return wrapError(ErrInterrupted, "interrupted")
+		}
+	}
+	steps++
+	if stepLimit != 0 && steps > stepLimit {
+		return wrapError(ErrStepLimit, "step limit exceeded: the program ran %d statements and loop iterations", stepLimit)
+	}
+	return nil
+}
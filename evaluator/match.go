@@ -0,0 +1,132 @@
+package evaluator
+
+import (
+	"slices"
+
+	"github.com/dr8co/monke/ast"
+	"github.com/dr8co/monke/object"
+)
+
+// evalMatchExpression evaluates the body of the first arm of me whose pattern
+// matches the value, in a scope of its own binding the names of the pattern.
+// If no arm matches, the result is null.
+func evalMatchExpression(me *ast.MatchExpression, env *object.Environment) object.Object {
+	value := Eval(me.Value, env)
+	if isError(value) {
+		return value
+	}
+
+	m := matcher{env: env}
+	for _, arm := range me.Arms {
+		m.bindings = m.bindings[:0]
+		matched := m.match(arm.Pattern, value)
+		if m.err != nil {
+			return m.err
+		}
+		if !matched {
+			continue
+		}
+		// Bindings are only made once the whole pattern matches, so a
+		// failed arm leaves nothing behind for the next one
+		armEnv := object.NewBlockEnvironment(env)
+		for _, b := range m.bindings {
+			armEnv.Set(b.name, b.value)
+		}
+		return Eval(arm.Body, armEnv)
+	}
+	return NULL
+}
+
+// matcher matches values against patterns, collecting the names they bind.
+type matcher struct {
+	env      *object.Environment
+	bindings []patternBinding
+	err      object.Object // An error raised while matching, such as running out of memory
+
+	// missingKeys makes the keys of hash patterns missing from a hash match
+	// null, as let statements bind them, rather than fail; in strict mode, a
+	// missing key is an error.
+	missingKeys bool
+}
+
+// patternBinding is a name bound by a pattern, with its value.
+type patternBinding struct {
+	name  string
+	value object.Object
+}
+
+// match reports whether value matches pattern.
+func (m *matcher) match(pattern ast.Pattern, value object.Object) bool {
+	switch pattern := pattern.(type) {
+	case *ast.BindingPattern:
+		m.bind(pattern, value)
+		return true
+	case *ast.LiteralPattern:
+		return literalMatches(Eval(pattern.Value, m.env), value)
+	case *ast.ArrayPattern:
+		array, ok := value.(*object.Array)
+		if !ok || len(array.Elements) < len(pattern.Elements) ||
+			pattern.Rest == nil && len(array.Elements) != len(pattern.Elements) {
+			return false
+		}
+		for i, element := range pattern.Elements {
+			if !m.match(element, array.Elements[i]) || m.err != nil {
+				return false
+			}
+		}
+		if pattern.Rest != nil && !pattern.Rest.Wildcard() {
+			rest := allocate(&object.Array{Elements: slices.Clone(array.Elements[len(pattern.Elements):])})
+			if isError(rest) {
+				m.err = rest
+				return false
+			}
+			m.bind(pattern.Rest, rest)
+		}
+		return true
+	case *ast.HashPattern:
+		hash, ok := value.(*object.Hash)
+		if !ok || pattern.Tag != "" && hash.Tag != pattern.Tag {
+			return false
+		}
+		for i, key := range pattern.Keys {
+			hashable, ok := Eval(key, m.env).(object.Hashable)
+			if !ok {
+				return false
+			}
+			pair, ok := hash.Pairs[hashable.HashKey()]
+			switch {
+			case ok:
+			case !m.missingKeys:
+				return false
+			case m.env.Strict():
+				m.err = newError("cannot destructure %s: missing key %s", hash.Type(), key.String())
+				return false
+			default:
+				pair.Value = NULL
+			}
+			if !m.match(pattern.Values[i], pair.Value) || m.err != nil {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// bind records that pattern binds value, unless it is the wildcard.
+func (m *matcher) bind(pattern *ast.BindingPattern, value object.Object) {
+	if !pattern.Wildcard() {
+		m.bindings = append(m.bindings, patternBinding{pattern.Name.Value, value})
+	}
+}
+
+// literalMatches reports whether value equals the value of a literal pattern.
+// Numbers match numbers of the same value, whether integers or floats.
+func literalMatches(literal, value object.Object) bool {
+	if isNumber(literal) && isNumber(value) {
+		return evalInfixExpression("==", literal, value, false) == TRUE
+	}
+	l, ok := literal.(object.Hashable)
+	v, ok2 := value.(object.Hashable)
+	return ok && ok2 && l.HashKey() == v.HashKey()
+}
@@ -0,0 +1,69 @@
+package evaluator
+
+import (
+	"github.com/dr8co/monke/ast"
+	"github.com/dr8co/monke/object"
+)
+
+// constMemo is the memo attached to the Cache of a prefix or infix expression
+// after its first evaluation. Expressions whose operands are all literals,
+// like "60 * 60 * 24", are pure and read nothing from the environment but its
+// language mode, so their value is kept and reused whenever the same node is
+// evaluated again: in loops, in function bodies, or when a program is run
+// again as by benchmarks. A value computed in the other mode is recomputed.
+type constMemo struct {
+	constant bool          // The expression only has literal operands
+	strict   bool          // The language mode value was computed in
+	value    object.Object // The memoized value, or nil
+}
+
+// notConstant is the memo shared by the expressions that are not constant.
+var notConstant = &constMemo{}
+
+// memo returns the memo kept in cache for node, creating it on first use.
+func memo(cache *any, node ast.Expression) *constMemo {
+	if m, ok := (*cache).(*constMemo); ok {
+		return m
+	}
+	m := notConstant
+	if isConstant(node) {
+		m = &constMemo{constant: true}
+	}
+	*cache = m
+	return m
+}
+
+// lookup returns the memoized value of the expression in env, or nil.
+func (m *constMemo) lookup(env *object.Environment) object.Object {
+	if m.value != nil && m.strict == env.Strict() {
+		return m.value
+	}
+	return nil
+}
+
+// keep memoizes value, the value of the expression in env, if the expression
+// is constant, and returns it. Only immutable values are kept, so reusing them
+// cannot be told apart from computing them again; errors are not kept either.
+func (m *constMemo) keep(value object.Object, env *object.Environment) object.Object {
+	if !m.constant {
+		return value
+	}
+	switch value.(type) {
+	case *object.Integer, *object.Float, *object.Boolean, *object.String:
+		m.value, m.strict = value, env.Strict()
+	}
+	return value
+}
+
+// isConstant reports whether exp is made of literals and operators only.
+func isConstant(exp ast.Expression) bool {
+	switch exp := exp.(type) {
+	case *ast.IntegerLiteral, *ast.FloatLiteral, *ast.Boolean, *ast.StringLiteral:
+		return true
+	case *ast.PrefixExpression:
+		return isConstant(exp.Right)
+	case *ast.InfixExpression:
+		return isConstant(exp.Left) && isConstant(exp.Right)
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package evaluator
+
+import "github.com/dr8co/monke/object"
+
+// memoryLimit caps the estimated bytes of live objects; 0 means no limit.
+var memoryLimit int
+
+// allocated counts the bytes of the arrays, strings and hashes created since
+// the live objects were last measured, which took live bytes.
+var allocated, live int
+
+// allocations counts the objects accounted for, with or without a limit, for
+// EvalDetailed.
+var allocations int
+
+// trackPeak is set by TrackPeakMemory, and peak is the largest live size measured since.
+var (
+	trackPeak bool
+	peak      int
+)
+
+// frames holds the environments of the running programs, function calls and
+// loop bodies, from which the live objects are reachable. It is only kept
+// while a memory limit is set or the peak is tracked.
+var frames []*object.Environment
+
+// SetMemoryLimit caps the estimated size of the objects a program keeps alive,
+// in bytes. Creating an array, string or hash that takes the live objects past
+// the limit fails with a "memory limit exceeded" error, which ends the program
+// like any other runtime error and leaves a REPL usable. A limit of 0, the
+// default, removes the cap. It is meant to be called before evaluation starts.
+func SetMemoryLimit(bytes int) {
+	memoryLimit = max(bytes, 0)
+	allocated, live = 0, 0
+	frames = nil
+}
+
+// TrackPeakMemory turns measuring the largest estimated size of the live
+// objects on or off, and resets the peak reported by PeakMemory. The live
+// objects are measured again once the bytes created since could have doubled
+// the peak, which keeps the cost in proportion to what the program allocates,
+// so the peak reported may be up to half the real one. It is meant to be
+// called before evaluation starts.
+func TrackPeakMemory(enabled bool) {
+	trackPeak, peak = enabled, 0
+	allocated, live = 0, 0
+	frames = nil
+}
+
+// PeakMemory returns the largest estimated size of the live objects, in
+// bytes, measured since TrackPeakMemory turned tracking on.
+func PeakMemory() int {
+	return peak
+}
+
+// accounting reports whether the bytes of created objects are counted, which
+// they are while a memory limit is set or the peak is tracked.
+func accounting() bool {
+	return memoryLimit > 0 || trackPeak
+}
+
+// pushFrame records env as a root of the live objects until the matching popFrame.
+func pushFrame(env *object.Environment) {
+	if accounting() {
+		frames = append(frames, env)
+	}
+}
+
+func popFrame() {
+	if accounting() {
+		frames = frames[:len(frames)-1]
+	}
+}
+
+// allocate records the creation of obj and returns it, or an error if the
+// live objects, including obj, exceed the memory limit.
+func allocate(obj object.Object) object.Object {
+	if err := account(obj); err != nil {
+		return err
+	}
+	return obj
+}
+
+// account records the creation of obj. It returns an error if the live
+// objects, including obj, exceed the memory limit, or nil.
+//
+// Objects are not freed explicitly, so the live objects are only measured,
+// by walking everything reachable from the frames, once the bytes created
+// since the last measurement could have taken them past the limit.
+func account(obj object.Object) *object.Error {
+	allocations++
+	if !accounting() {
+		return nil
+	}
+	return grow(obj, object.Size(obj))
+}
+
+// grow records that obj took n more bytes, as when it was created or when a
+// builder grew. It returns an error if the live objects, including obj,
+// exceed the memory limit, or nil.
+func grow(obj object.Object, n int) *object.Error {
+	if !accounting() {
+		return nil
+	}
+	allocated += n
+	overLimit := memoryLimit > 0 && live+allocated > memoryLimit
+	if !overLimit && !(trackPeak && live+allocated > 2*peak) {
+		return nil
+	}
+	live, allocated = object.LiveSize(frames, obj), 0
+	peak = max(peak, live)
+	if memoryLimit > 0 && live > memoryLimit {
+		return wrapError(ErrMemoryLimit, "memory limit exceeded: %d bytes live, the limit is %d", live, memoryLimit)
+	}
+	return nil
+}
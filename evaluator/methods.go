@@ -0,0 +1,72 @@
+package evaluator
+
+import (
+	"github.com/dr8co/monke/ast"
+	"github.com/dr8co/monke/object"
+)
+
+// evalMethodCall evaluates a call of a field, "recv.name(args)" or
+// "recv?.name(args)". A hash with the key name calls the value at the key, as
+// recv["name"](args) does, and an instance of a class with a method name calls
+// the method with the instance as self. Any other receiver, or a hash without
+// the key, calls the function bound to name, in the environment or as a
+// builtin, with the receiver as its first argument, so xs.map(f) is map(xs, f)
+// and s.upper() is upper(s). The optional form evaluates to null when the
+// receiver is null.
+func evalMethodCall(call *ast.CallExpression, field *ast.IndexExpression, name string, env *object.Environment) object.Object {
+	recv := Eval(field.Left, env)
+	if isError(recv) {
+		return recv
+	}
+	if field.Optional && recv == NULL {
+		return NULL
+	}
+
+	var receiver []object.Object
+	function := methodField(recv, name)
+	if function == nil {
+		function = classMethod(recv, name)
+		if function == nil {
+			function = methodFunction(name, env)
+		}
+		if function == nil {
+			return newError("unknown method: %s.%s", typeName(recv), name)
+		}
+		receiver = []object.Object{recv}
+	}
+
+	if hasNamedArguments(call.Arguments) {
+		return evalNamedCall(function, call.Arguments, env, receiver...)
+	}
+	args := evalExpressions(call.Arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+	return applyFunction(function, append(receiver, args...))
+}
+
+// methodField returns the value at the key name of recv, if it is a hash
+// that has the key, or nil.
+func methodField(recv object.Object, name string) object.Object {
+	hash, ok := recv.(*object.Hash)
+	if !ok {
+		return nil
+	}
+	pair, ok := hash.Pairs[(&object.String{Value: name}).HashKey()]
+	if !ok {
+		return nil
+	}
+	return pair.Value
+}
+
+// methodFunction returns the function a method call of name dispatches to:
+// the value bound to name in env, or else the builtin of that name, or nil.
+func methodFunction(name string, env *object.Environment) object.Object {
+	if fn, ok := env.Get(name); ok {
+		return fn
+	}
+	if builtin, ok := builtins[name]; ok {
+		return builtin
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+package evaluator
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/dr8co/monke/ast"
+	"github.com/dr8co/monke/modules"
+	"github.com/dr8co/monke/object"
+	"github.com/dr8co/monke/parser"
+)
+
+// moduleResolver finds the modules imported by programs; nil disables imports.
+var moduleResolver modules.Resolver
+
+// loadedModules holds the environments of the modules evaluated so far, by
+// name, so that a module imported again is not evaluated twice. loading is the
+// chain of modules being evaluated, innermost last, for finding import cycles.
+var (
+	loadedModules = make(map[string]*object.Environment)
+	loading       []string
+)
+
+// parsedModules holds the modules parsed by prefetchModules ahead of their
+// import, by name, until loadModule evaluates them.
+var parsedModules = make(map[string]parser.File)
+
+// SetModuleResolver sets the resolver that finds the modules import
+// statements name (see package modules). It also forgets the modules
+// imported so far. A nil resolver, the default, makes every import fail.
+func SetModuleResolver(r modules.Resolver) {
+	moduleResolver = r
+	clear(loadedModules)
+	clear(parsedModules)
+	loading = nil
+}
+
+// prefetchModules loads the modules imported by the top-level import
+// statements of program that are not loaded yet, and parses them all at once,
+// in parallel, for loadModule to evaluate when the imports run. The resolver
+// is only called from the goroutine of the evaluator. Failures are left for
+// the imports to report. It returns the names of the modules it parsed.
+func prefetchModules(program *ast.Program) []string {
+	if moduleResolver == nil {
+		return nil
+	}
+	from := ""
+	if len(loading) != 0 {
+		from = loading[len(loading)-1]
+	}
+	var names, sources []string
+	for _, stmt := range program.Statements {
+		is, ok := stmt.(*ast.ImportStatement)
+		if !ok {
+			continue
+		}
+		name, err := moduleResolver.ResolvePath(from, is.Path.Value)
+		if err != nil || slices.Contains(names, name) || slices.Contains(loading, name) {
+			continue
+		}
+		if _, ok := loadedModules[name]; ok {
+			continue
+		}
+		if _, ok := parsedModules[name]; ok {
+			continue
+		}
+		source, err := moduleResolver.Load(name)
+		if err != nil {
+			continue
+		}
+		names = append(names, name)
+		sources = append(sources, source)
+	}
+	for i, file := range parser.ParseAll(sources) {
+		parsedModules[names[i]] = file
+	}
+	return names
+}
+
+// forgetModules drops the modules of names that prefetchModules parsed but
+// that were not imported, so a later import reads their current source.
+func forgetModules(names []string) {
+	for _, name := range names {
+		delete(parsedModules, name)
+	}
+}
+
+// evalImportStatement evaluates the module imported by is, unless it was
+// imported before, and binds its top-level names in env. Names starting with
+// "_" are private to the module and are not bound.
+func evalImportStatement(is *ast.ImportStatement, env *object.Environment) object.Object {
+	path := is.Path.Value
+	if moduleResolver == nil {
+		return newError("cannot import %q: no module resolver is set", path)
+	}
+	from := ""
+	if len(loading) != 0 {
+		from = loading[len(loading)-1]
+	}
+	name, err := moduleResolver.ResolvePath(from, path)
+	if err != nil {
+		return wrapError(err, "cannot import %q: %s", path, err)
+	}
+
+	module, errObj := loadModule(name)
+	if errObj != nil {
+		return errObj
+	}
+	for _, n := range exported(module) {
+		val, _ := module.Get(n)
+		// Importing a module twice binds the same values again, which strict mode allows
+		if env.Strict() && env.Defined(n) {
+			if old, _ := env.Get(n); old != val {
+				return newError("identifier already declared: %s", n)
+			}
+		}
+		env.Set(n, val)
+	}
+	return nil
+}
+
+// loadModule returns the environment of the module name, evaluating the
+// module in an environment of its own the first time it is imported.
+func loadModule(name string) (*object.Environment, *object.Error) {
+	if module, ok := loadedModules[name]; ok {
+		return module, nil
+	}
+	for i, n := range loading {
+		if n == name {
+			return nil, newError("import cycle: %s -> %s", strings.Join(loading[i:], " -> "), name)
+		}
+	}
+
+	file, ok := parsedModules[name]
+	delete(parsedModules, name)
+	if !ok {
+		source, err := moduleResolver.Load(name)
+		if err != nil {
+			return nil, wrapError(err, "cannot load module %s: %s", name, err)
+		}
+		file = parser.ParseAll([]string{source})[0]
+	}
+	if errs := file.Errors; len(errs) != 0 {
+		return nil, newError("%s:%s: %s", name, errs[0].Pos, errs[0].Message)
+	}
+	program := file.Program
+
+	loading = append(loading, name)
+	module := object.NewEnvironment()
+	result := Eval(program, module)
+	loading = loading[:len(loading)-1]
+	// The errors of a module are reported where they happened, in the
+	// module, prefixed the way compilers report errors in other files
+	if err, ok := result.(*object.Error); ok {
+		if err.Pos.Line != 0 {
+			return nil, newError("%s:%s: %s", name, err.Pos, err.Message)
+		}
+		return nil, newError("%s: %s", name, err.Message)
+	}
+	loadedModules[name] = module
+	return module, nil
+}
+
+// ReloadModule evaluates the module imported as path again, from its current
+// source, so that changes to it take effect without starting over, e.g. in a
+// REPL. The module must have been imported already. Wherever a binding of one
+// of envs or of another module still holds the value the module bound before,
+// it is replaced by the new value; bindings changed since the import are kept.
+// Names the module binds for the first time are bound where its old values
+// were found. It returns the name of the module and the names rebound in envs.
+// If the module fails to load, the old one stays in place.
+func ReloadModule(path string, envs ...*object.Environment) (string, []string, error) {
+	if moduleResolver == nil {
+		return "", nil, errors.New("no module resolver is set")
+	}
+	name, err := moduleResolver.ResolvePath("", path)
+	if err != nil {
+		return "", nil, err
+	}
+	old, ok := loadedModules[name]
+	if !ok {
+		return name, nil, fmt.Errorf("module %s has not been imported", name)
+	}
+
+	delete(loadedModules, name)
+	module, errObj := loadModule(name)
+	if errObj != nil {
+		loadedModules[name] = old
+		return name, nil, errors.New(errObj.Message)
+	}
+	for other, e := range loadedModules {
+		if other != name {
+			rebind(e, old, module)
+		}
+	}
+	var rebound []string
+	for _, env := range envs {
+		rebound = append(rebound, rebind(env, old, module)...)
+	}
+	slices.Sort(rebound)
+	return name, slices.Compact(rebound), nil
+}
+
+// rebind replaces the bindings of env that hold a value exported by the
+// module old with the value module exports under the same name, and returns
+// the names of the bindings it changed. If any binding matched, the names
+// only module exports are bound too.
+func rebind(env, old, module *object.Environment) []string {
+	var changed []string
+	for _, n := range exported(old) {
+		before, _ := old.Get(n)
+		after, ok := module.Get(n)
+		if current, _ := env.Get(n); !ok || !env.Defined(n) || current != before {
+			continue
+		}
+		env.Set(n, after)
+		changed = append(changed, n)
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	for _, n := range exported(module) {
+		if !old.Defined(n) && !env.Defined(n) {
+			val, _ := module.Get(n)
+			env.Set(n, val)
+			changed = append(changed, n)
+		}
+	}
+	return changed
+}
+
+// exported returns the names a module binds for its importers, in sorted
+// order: those that do not start with "_".
+func exported(module *object.Environment) []string {
+	return slices.DeleteFunc(module.Names(), func(n string) bool {
+		return strings.HasPrefix(n, "_")
+	})
+}
@@ -0,0 +1,42 @@
+package evaluator
+
+import (
+	"io"
+	"os"
+
+	"github.com/dr8co/monke/modules"
+)
+
+// Options holds the settings of the evaluator that hosts embedding it usually
+// choose together, each otherwise set by a function of its own. The zero value
+// is the default of every setting.
+type Options struct {
+	Output      io.Writer        // Where `puts` prints; nil for os.Stdout
+	Args        []string         // What the `args` builtin returns
+	Modules     modules.Resolver // Finds the modules programs import; nil makes imports fail
+	Warnings    WarningHandler   // Receives runtime warnings; nil discards them
+	Tracer      Tracer           // Observes the statements evaluated, or nil
+	StepLimit   int              // Statements and loop iterations a run may take; 0 for no limit
+	DepthLimit  int              // Nested function calls; 0 for no limit
+	MemoryLimit int              // Estimated bytes of live objects; 0 for no limit
+}
+
+// Configure applies every setting of opts, as SetOutput, SetArgs,
+// SetModuleResolver, SetWarningHandler, SetTracer, SetStepLimit, SetDepthLimit
+// and SetMemoryLimit would. Like them, it is meant to be called before an
+// evaluation starts, and it resets what they reset: the step count, the
+// imported modules and the warnings already reported.
+func Configure(opts Options) {
+	output := opts.Output
+	if output == nil {
+		output = os.Stdout
+	}
+	SetOutput(output)
+	SetArgs(opts.Args)
+	SetModuleResolver(opts.Modules)
+	SetWarningHandler(opts.Warnings)
+	SetTracer(opts.Tracer)
+	SetStepLimit(opts.StepLimit)
+	SetDepthLimit(opts.DepthLimit)
+	SetMemoryLimit(opts.MemoryLimit)
+}
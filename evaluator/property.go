@@ -0,0 +1,281 @@
+package evaluator
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+
+	"github.com/dr8co/monke/object"
+)
+
+const (
+	// defaultIterations is the number of cases `forall` checks when no count is given.
+	defaultIterations = 100
+
+	// maxShrinkSteps bounds the number of successful shrinks applied to a counterexample.
+	maxShrinkSteps = 1000
+)
+
+// rng is the random source used by generators.
+var rng = rand.New(NewRandSource())
+
+// NewRandSource returns a randomly seeded source of the kind the evaluator uses by default.
+func NewRandSource() rand.Source {
+	return rand.NewPCG(rand.Uint64(), rand.Uint64())
+}
+
+// SetRandSource replaces the random source used by builtins such as the generators.
+// It is meant to be called before evaluation starts, e.g. to record or replay a run.
+func SetRandSource(src rand.Source) {
+	rng = rand.New(src)
+}
+
+func init() {
+	builtins["forall"] = &object.Builtin{Fn: forallBuiltin}
+	builtins["gen_int"] = &object.Builtin{Fn: genIntBuiltin}
+	builtins["gen_string"] = &object.Builtin{Fn: genStringBuiltin}
+	builtins["gen_array"] = &object.Builtin{Fn: genArrayBuiltin}
+}
+
+// forallBuiltin implements forall(gen, property_fn[, iterations]).
+// It checks the property against values drawn from gen and, on failure,
+// shrinks the counterexample before reporting it as an error.
+func forallBuiltin(args ...object.Object) object.Object {
+	if len(args) != 2 && len(args) != 3 {
+		return newError("wrong number of arguments. got=%d, want=2 or 3", len(args))
+	}
+	gen, ok := args[0].(*object.Generator)
+	if !ok {
+		return newError("first argument to `forall` must be GENERATOR, got %s", args[0].Type())
+	}
+	switch fn := args[1].(type) {
+	case *object.Function:
+		if len(fn.Parameters) != 1 {
+			return newError("property passed to `forall` must take 1 argument, got %d", len(fn.Parameters))
+		}
+	case *object.Builtin:
+	default:
+		return newError("second argument to `forall` must be FUNCTION, got %s", args[1].Type())
+	}
+	iterations := int64(defaultIterations)
+	if len(args) == 3 {
+		n, ok := args[2].(*object.Integer)
+		if !ok || n.Value < 1 {
+			return newError("third argument to `forall` must be a positive INTEGER, got %s", args[2].Inspect())
+		}
+		iterations = n.Value
+	}
+
+	for i := int64(1); i <= iterations; i++ {
+		value := gen.Generate(rng)
+		reason, failed := checkProperty(args[1], value)
+		if !failed {
+			continue
+		}
+		value, reason, steps := shrinkCounterexample(gen, args[1], value, reason)
+		return newError("property failed after %d tests (shrunk %d times): counterexample %s: %s",
+			i, steps, value.Inspect(), reason)
+	}
+	return TRUE
+}
+
+// checkProperty applies fn to value and reports whether the property failed, and why.
+func checkProperty(fn, value object.Object) (string, bool) {
+	result := applyFunction(fn, []object.Object{value})
+	if errObj, ok := result.(*object.Error); ok {
+		return errObj.Message, true
+	}
+	// The property is truthy by the rules of the mode it was written in
+	strict := false
+	if f, ok := fn.(*object.Function); ok {
+		strict = f.Env.Strict()
+	}
+	if result == nil || !isTruthy(result, strict) {
+		return "property returned " + inspectOrNil(result), true
+	}
+	return "", false
+}
+
+// shrinkCounterexample greedily replaces value with the first simpler candidate
+// that still fails, until no candidate fails or maxShrinkSteps is reached.
+func shrinkCounterexample(gen *object.Generator, fn, value object.Object, reason string) (object.Object, string, int) {
+	steps := 0
+	for steps < maxShrinkSteps {
+		shrunk := false
+		for _, candidate := range gen.Shrink(value) {
+			if r, failed := checkProperty(fn, candidate); failed {
+				value, reason = candidate, r
+				shrunk = true
+				steps++
+				break
+			}
+		}
+		if !shrunk {
+			break
+		}
+	}
+	return value, reason, steps
+}
+
+func inspectOrNil(obj object.Object) string {
+	if obj == nil {
+		return "nil"
+	}
+	return obj.Inspect()
+}
+
+// genIntBuiltin implements gen_int(lo, hi), producing integers in [lo, hi]
+// that shrink towards zero (or the bound nearest to it).
+func genIntBuiltin(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	lo, ok1 := args[0].(*object.Integer)
+	hi, ok2 := args[1].(*object.Integer)
+	if !ok1 || !ok2 {
+		return newError("arguments to `gen_int` must be INTEGER, got %s and %s", args[0].Type(), args[1].Type())
+	}
+	if lo.Value > hi.Value {
+		return newError("invalid range for `gen_int`: %d > %d", lo.Value, hi.Value)
+	}
+	low, high := lo.Value, hi.Value
+	target := min(max(0, low), high)
+
+	return &object.Generator{
+		Name: fmt.Sprintf("gen_int(%d, %d)", low, high),
+		Generate: func(r *rand.Rand) object.Object {
+			//nolint:gosec // the range is validated above
+			span := uint64(high - low)
+			if span == ^uint64(0) {
+				return getIntegerObject(int64(r.Uint64()))
+			}
+			//nolint:gosec
+			return getIntegerObject(low + int64(r.Uint64N(span+1)))
+		},
+		Shrink: func(v object.Object) []object.Object {
+			n := v.(*object.Integer).Value
+			if n == target {
+				return nil
+			}
+			var candidates []object.Object
+			seen := map[int64]bool{n: true}
+			for _, c := range []int64{target, n - (n-target)/2, n - sign(n-target)} {
+				if !seen[c] {
+					seen[c] = true
+					candidates = append(candidates, getIntegerObject(c))
+				}
+			}
+			return candidates
+		},
+	}
+}
+
+func sign(n int64) int64 {
+	switch {
+	case n > 0:
+		return 1
+	case n < 0:
+		return -1
+	default:
+		return 0
+	}
+}
+
+// genStringBuiltin implements gen_string(n), producing lowercase strings of
+// length 0 to n that shrink towards shorter strings made of 'a's.
+func genStringBuiltin(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	n, ok := args[0].(*object.Integer)
+	if !ok || n.Value < 0 {
+		return newError("argument to `gen_string` must be a non-negative INTEGER, got %s", args[0].Inspect())
+	}
+	maxLen := n.Value
+
+	return &object.Generator{
+		Name: fmt.Sprintf("gen_string(%d)", maxLen),
+		Generate: func(r *rand.Rand) object.Object {
+			length := r.Int64N(maxLen + 1)
+			var b strings.Builder
+			b.Grow(int(length))
+			for range length {
+				b.WriteByte(byte('a' + r.IntN(26)))
+			}
+			return &object.String{Value: b.String()}
+		},
+		Shrink: func(v object.Object) []object.Object {
+			s := v.(*object.String).Flat()
+			if s == "" {
+				return nil
+			}
+			candidates := []object.Object{&object.String{Value: ""}}
+			if len(s) > 1 {
+				candidates = append(candidates, &object.String{Value: s[:len(s)/2]})
+			}
+			for i := range len(s) {
+				candidates = append(candidates, &object.String{Value: s[:i] + s[i+1:]})
+			}
+			for i := range len(s) {
+				if s[i] != 'a' {
+					candidates = append(candidates, &object.String{Value: s[:i] + "a" + s[i+1:]})
+				}
+			}
+			return candidates
+		},
+	}
+}
+
+// genArrayBuiltin implements gen_array(gen, n), producing arrays of 0 to n
+// elements drawn from gen that shrink by dropping and then shrinking elements.
+func genArrayBuiltin(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	elem, ok := args[0].(*object.Generator)
+	if !ok {
+		return newError("first argument to `gen_array` must be GENERATOR, got %s", args[0].Type())
+	}
+	n, ok := args[1].(*object.Integer)
+	if !ok || n.Value < 0 {
+		return newError("second argument to `gen_array` must be a non-negative INTEGER, got %s", args[1].Inspect())
+	}
+	maxLen := n.Value
+
+	return &object.Generator{
+		Name: fmt.Sprintf("gen_array(%s, %d)", elem.Name, maxLen),
+		Generate: func(r *rand.Rand) object.Object {
+			length := r.Int64N(maxLen + 1)
+			elements := make([]object.Object, length)
+			for i := range elements {
+				elements[i] = elem.Generate(r)
+			}
+			return &object.Array{Elements: elements}
+		},
+		Shrink: func(v object.Object) []object.Object {
+			elements := v.(*object.Array).Elements
+			if len(elements) == 0 {
+				return nil
+			}
+			candidates := []object.Object{&object.Array{Elements: []object.Object{}}}
+			if len(elements) > 1 {
+				candidates = append(candidates, &object.Array{Elements: elements[:len(elements)/2]})
+			}
+			for i := range elements {
+				shorter := make([]object.Object, 0, len(elements)-1)
+				shorter = append(shorter, elements[:i]...)
+				shorter = append(shorter, elements[i+1:]...)
+				candidates = append(candidates, &object.Array{Elements: shorter})
+			}
+			for i, e := range elements {
+				for _, s := range elem.Shrink(e) {
+					replaced := make([]object.Object, len(elements))
+					copy(replaced, elements)
+					replaced[i] = s
+					candidates = append(candidates, &object.Array{Elements: replaced})
+				}
+			}
+			return candidates
+		},
+	}
+}
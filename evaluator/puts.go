@@ -0,0 +1,25 @@
+//go:build !monke_noio
+
+package evaluator
+
+import (
+	"fmt"
+
+	"github.com/dr8co/monke/object"
+)
+
+// `puts`, the only builtin that writes anywhere, is left out of builds with the
+// monke_noio tag, for hosts embedding the interpreter whose programs must not
+// produce output of their own.
+func init() {
+	builtins["puts"] = &object.Builtin{Fn: putsBuiltin}
+}
+
+// putsBuiltin implements puts(values...), printing each value to output on a
+// line of its own.
+func putsBuiltin(args ...object.Object) object.Object {
+	for _, arg := range args {
+		fmt.Fprintln(output, arg.Inspect())
+	}
+	return NULL
+}
@@ -0,0 +1,43 @@
+package evaluator
+
+import (
+	"math"
+	"unsafe"
+
+	"github.com/dr8co/monke/object"
+)
+
+// maxRangeLength caps the number of integers in a range, whose array would
+// otherwise take more memory than a machine has before any limit applies.
+const maxRangeLength = math.MaxInt32
+
+// evalRange evaluates start..end, or start..=end if inclusive, to an array of
+// the integers from start up to end. The array is empty if end comes first.
+func evalRange(start, end int64, inclusive bool) object.Object {
+	operator := ".."
+	if inclusive {
+		operator = "..="
+	}
+	if end < start || end == start && !inclusive {
+		return &object.Array{Elements: []object.Object{}}
+	}
+
+	n := uint64(end) - uint64(start)
+	if inclusive {
+		n++
+	}
+	if n == 0 || n > maxRangeLength {
+		return newError("range is too long: %d%s%d", start, operator, end)
+	}
+	// Check the limit before making the elements, which may not fit in memory
+	if memoryLimit > 0 && n > uint64(memoryLimit)/uint64(unsafe.Sizeof(object.Object(nil))) {
+		return wrapError(ErrMemoryLimit, "memory limit exceeded: %d%s%d has %d integers, the limit is %d bytes",
+			start, operator, end, n, memoryLimit)
+	}
+
+	elements := make([]object.Object, n)
+	for i := range elements {
+		elements[i] = getIntegerObject(start + int64(i))
+	}
+	return allocate(&object.Array{Elements: elements})
+}
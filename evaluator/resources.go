@@ -0,0 +1,78 @@
+package evaluator
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"weak"
+
+	"github.com/dr8co/monke/object"
+)
+
+func init() {
+	builtins["close"] = &object.Builtin{Fn: closeBuiltin}
+}
+
+// resources holds weak pointers to the resources opened with OpenResource, so
+// that CloseResources can reach the open ones without keeping them alive.
+// Resources are opened by builtins and closed from other goroutines when a
+// run is cancelled, so the list is guarded by resourcesMu.
+var (
+	resourcesMu sync.Mutex
+	resources   []weak.Pointer[object.Resource]
+)
+
+// OpenResource returns a new resource released by close, for the builtins of
+// a host that give programs files, connections and the like. The resource is
+// closed by the close builtin, by CloseResources, or once it is unreachable.
+func OpenResource(kind, name string, close func() error) *object.Resource {
+	r := object.NewResource(kind, name, close)
+
+	resourcesMu.Lock()
+	defer resourcesMu.Unlock()
+	// Forget the resources collected or closed since, before the list grows,
+	// so that it stays proportional to the open ones
+	if len(resources) == cap(resources) {
+		resources = slices.DeleteFunc(resources, func(p weak.Pointer[object.Resource]) bool {
+			r := p.Value()
+			return r == nil || r.Closed()
+		})
+	}
+	resources = append(resources, weak.Make(r))
+	return r
+}
+
+// CloseResources closes every resource opened with OpenResource that is still
+// open, and returns the errors of closing them joined. It is safe to call from
+// another goroutine. An evaluation that is interrupted calls it once its
+// deferred blocks have run, so that a cancelled run leaves nothing open.
+func CloseResources() error {
+	resourcesMu.Lock()
+	open := resources
+	resources = nil
+	resourcesMu.Unlock()
+
+	var errs []error
+	for _, p := range open {
+		if r := p.Value(); r != nil {
+			errs = append(errs, r.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// closeBuiltin implements close(resource), closing the resource. Closing a
+// resource again does nothing.
+func closeBuiltin(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	r, ok := args[0].(*object.Resource)
+	if !ok {
+		return newError("argument to `close` must be RESOURCE, got %s", args[0].Type())
+	}
+	if err := r.Close(); err != nil {
+		return wrapError(err, "closing %s: %s", r.Inspect(), err)
+	}
+	return NULL
+}
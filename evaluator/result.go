@@ -0,0 +1,42 @@
+package evaluator
+
+import (
+	"time"
+
+	"github.com/dr8co/monke/ast"
+	"github.com/dr8co/monke/object"
+	"github.com/dr8co/monke/parser"
+	"github.com/dr8co/monke/token"
+)
+
+// EvalResult is the outcome of EvalDetailed: the value Eval would return,
+// with what the evaluation took.
+type EvalResult struct {
+	Value            object.Object
+	Steps            int            // Statements and loop iterations run, as counted for SetStepLimit
+	Duration         time.Duration  // Wall time, as measured by Now, so 0 in deterministic runs
+	AllocatedObjects int            // Arrays, strings, hashes and builtin results created, as counted for SetMemoryLimit
+	Warnings         []parser.Error // The runtime warnings, each position once, in the order reported
+}
+
+// EvalDetailed evaluates node in env like Eval, and reports what the
+// evaluation took, so that hosts showing or comparing those numbers get the
+// same ones without measuring around the call themselves. The warnings are
+// collected whether or not a WarningHandler is installed, which still
+// receives those it has not been told of before.
+func EvalDetailed(node ast.Node, env *object.Environment) EvalResult {
+	outer := collected
+	collected = &collection{seen: make(map[token.Position]bool)}
+	defer func() { collected = outer }()
+
+	startSteps, startAllocations := steps, allocations
+	start := now()
+	value := Eval(node, env)
+	return EvalResult{
+		Value:            value,
+		Steps:            steps - startSteps,
+		Duration:         now().Sub(start),
+		AllocatedObjects: allocations - startAllocations,
+		Warnings:         collected.warnings,
+	}
+}
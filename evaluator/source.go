@@ -0,0 +1,30 @@
+package evaluator
+
+import (
+	"github.com/dr8co/monke/ast"
+	"github.com/dr8co/monke/format"
+	"github.com/dr8co/monke/object"
+	"github.com/dr8co/monke/token"
+)
+
+func init() {
+	builtins["source"] = &object.Builtin{Fn: sourceBuiltin}
+}
+
+// sourceBuiltin implements source(fn), returning the formatted source of a function literal.
+func sourceBuiltin(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	fn, ok := args[0].(*object.Function)
+	if !ok {
+		return newError("argument to `source` must be FUNCTION, got %s", args[0].Type())
+	}
+	literal := &ast.FunctionLiteral{
+		Token:      token.Token{Type: token.FUNCTION, Literal: "fn"},
+		Parameters: fn.Parameters,
+		Rest:       fn.Rest,
+		Body:       fn.Body,
+	}
+	return &object.String{Value: format.Node(literal)}
+}
@@ -0,0 +1,152 @@
+package evaluator
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/dr8co/monke/ast"
+	"github.com/dr8co/monke/object"
+	"github.com/dr8co/monke/token"
+)
+
+// BuiltinStat holds the accumulated resource usage of a single builtin.
+type BuiltinStat struct {
+	Name  string
+	Calls int64
+	Time  time.Duration // Inclusive wall time, including nested calls
+}
+
+// builtinStats accumulates the usage of every builtin called since the last reset.
+var builtinStats = make(map[*object.Builtin]*BuiltinStat)
+
+// FunctionStat holds the number of calls to the functions of a single function
+// literal, which all the closures it evaluated to count towards.
+type FunctionStat struct {
+	Name  string         // The name of the first function called, or Anonymous
+	Pos   token.Position // The position of the body of the literal
+	Calls int64
+}
+
+// functionStats counts the calls to each function literal since the last reset.
+var functionStats = make(map[*ast.BlockStatement]*FunctionStat)
+
+func init() {
+	builtins["runtime_stats"] = &object.Builtin{Fn: runtimeStatsBuiltin}
+}
+
+// callBuiltin calls fn with args, accounting the call in builtinStats.
+func callBuiltin(fn *object.Builtin, args []object.Object) object.Object {
+	start := now()
+	result := fn.Fn(args...)
+	elapsed := now().Sub(start)
+
+	stat, ok := builtinStats[fn]
+	if !ok {
+		stat = &BuiltinStat{Name: builtinName(fn)}
+		builtinStats[fn] = stat
+	}
+	stat.Calls++
+	stat.Time += elapsed
+	if isError(result) {
+		return result
+	}
+	return allocate(result)
+}
+
+// countCall accounts a call to fn in functionStats.
+func countCall(fn *object.Function) {
+	stat, ok := functionStats[fn.Body]
+	if !ok {
+		name := fn.Name
+		if name == "" {
+			name = Anonymous
+		}
+		stat = &FunctionStat{Name: name, Pos: fn.Body.Pos()}
+		functionStats[fn.Body] = stat
+	}
+	stat.Calls++
+}
+
+// builtinName returns the name fn is registered under, or "builtin" if it has none.
+func builtinName(fn *object.Builtin) string {
+	for name, b := range builtins {
+		if b == fn {
+			return name
+		}
+	}
+	return "builtin"
+}
+
+// BuiltinStats returns the usage of every builtin called so far,
+// most time-consuming first.
+func BuiltinStats() []BuiltinStat {
+	stats := make([]BuiltinStat, 0, len(builtinStats))
+	for _, s := range builtinStats {
+		stats = append(stats, *s)
+	}
+	slices.SortFunc(stats, func(a, b BuiltinStat) int {
+		if c := cmp.Compare(b.Time, a.Time); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return stats
+}
+
+// ResetBuiltinStats clears the accumulated builtin usage.
+func ResetBuiltinStats() {
+	clear(builtinStats)
+}
+
+// FunctionStats returns the number of calls to every function called so
+// far, most called first.
+func FunctionStats() []FunctionStat {
+	stats := make([]FunctionStat, 0, len(functionStats))
+	for _, s := range functionStats {
+		stats = append(stats, *s)
+	}
+	slices.SortFunc(stats, func(a, b FunctionStat) int {
+		if c := cmp.Compare(b.Calls, a.Calls); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.Name, b.Name); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.Pos.Line, b.Pos.Line); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Pos.Column, b.Pos.Column)
+	})
+	return stats
+}
+
+// ResetFunctionStats clears the accumulated function call counts.
+func ResetFunctionStats() {
+	clear(functionStats)
+}
+
+// runtimeStatsBuiltin implements runtime_stats(), returning a hash from builtin
+// names to hashes holding their call counts and cumulative time in nanoseconds.
+func runtimeStatsBuiltin(args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return newError("wrong number of arguments. got=%d, want=0", len(args))
+	}
+	stats := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair)}
+	// The durations are inputs of the program, read in an order that does not depend on them
+	sorted := BuiltinStats()
+	slices.SortFunc(sorted, func(a, b BuiltinStat) int { return cmp.Compare(a.Name, b.Name) })
+	for _, s := range sorted {
+		entry := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair, 2)}
+		setHashPair(entry, "calls", getIntegerObject(s.Calls))
+		setHashPair(entry, "time_ns", getIntegerObject(timeInput(s.Time).Nanoseconds()))
+		setHashPair(stats, s.Name, entry)
+	}
+	return stats
+}
+
+// setHashPair binds value to the string key in hash.
+func setHashPair(hash *object.Hash, key string, value object.Object) {
+	k := &object.String{Value: key}
+	hash.Pairs[k.HashKey()] = object.HashPair{Key: k, Value: value}
+}
@@ -0,0 +1,46 @@
+package evaluator
+
+import (
+	"maps"
+
+	"github.com/dr8co/monke/object"
+)
+
+func init() {
+	builtins["tag"] = &object.Builtin{Fn: tagBuiltin}
+	builtins["type"] = &object.Builtin{Fn: typeBuiltin}
+}
+
+// tagBuiltin implements tag(hash, name), returning a copy of hash tagged with
+// the user-defined type name. An empty name returns an untagged copy.
+func tagBuiltin(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	hash, ok := args[0].(*object.Hash)
+	if !ok {
+		return newError("first argument to `tag` must be HASH, got %s", args[0].Type())
+	}
+	name, ok := args[1].(*object.String)
+	if !ok {
+		return newError("second argument to `tag` must be STRING, got %s", args[1].Type())
+	}
+	return &object.Hash{Pairs: maps.Clone(hash.Pairs), Tag: name.Flat()}
+}
+
+// typeBuiltin implements type(value), returning the name of the value's type:
+// the tag of a tagged hash, or the built-in type name such as "INTEGER".
+func typeBuiltin(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	return &object.String{Value: typeName(args[0])}
+}
+
+// typeName returns the type name reported by `type` for obj.
+func typeName(obj object.Object) string {
+	if hash, ok := obj.(*object.Hash); ok && hash.Tag != "" {
+		return hash.Tag
+	}
+	return string(obj.Type())
+}
@@ -0,0 +1,39 @@
+package evaluator
+
+import (
+	"github.com/dr8co/monke/ast"
+	"github.com/dr8co/monke/object"
+)
+
+// Tracer observes the evaluation of statements.
+// EnterStatement is called before a statement is evaluated and ExitStatement
+// after it completes, with its result (nil for statements such as let that
+// produce no value). Calls nest: the statements of a block are entered and
+// exited between the Enter and Exit calls of the statement containing it.
+type Tracer interface {
+	EnterStatement(stmt ast.Statement)
+	ExitStatement(stmt ast.Statement, result object.Object)
+}
+
+// tracer is the Tracer notified during evaluation, if any.
+var tracer Tracer
+
+// SetTracer installs t to observe all subsequent evaluation; nil removes the current tracer.
+func SetTracer(t Tracer) {
+	tracer = t
+}
+
+// evalStatement evaluates a single statement of a program or block, notifying the tracer.
+func evalStatement(stmt ast.Statement, env *object.Environment) object.Object {
+	current = stmt
+	if err := step(); err != nil {
+		return err
+	}
+	if tracer == nil {
+		return Eval(stmt, env)
+	}
+	tracer.EnterStatement(stmt)
+	result := Eval(stmt, env)
+	tracer.ExitStatement(stmt, result)
+	return result
+}
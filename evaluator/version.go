@@ -0,0 +1,28 @@
+package evaluator
+
+import (
+	"github.com/dr8co/monke/object"
+	"github.com/dr8co/monke/version"
+)
+
+func init() {
+	builtins["version"] = &object.Builtin{Fn: versionBuiltin}
+}
+
+// versionBuiltin implements version(), returning a hash with the interpreter
+// "version", its "engine" and the array of supported "features".
+func versionBuiltin(args ...object.Object) object.Object {
+	if len(args) != 0 {
+		return newError("wrong number of arguments. got=%d, want=0", len(args))
+	}
+	features := make([]object.Object, len(version.Features))
+	for i, f := range version.Features {
+		features[i] = &object.String{Value: f}
+	}
+
+	info := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair, 3)}
+	setHashPair(info, "version", &object.String{Value: version.Version})
+	setHashPair(info, "engine", &object.String{Value: version.Engine})
+	setHashPair(info, "features", &object.Array{Elements: features})
+	return info
+}
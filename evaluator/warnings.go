@@ -0,0 +1,54 @@
+package evaluator
+
+import (
+	"fmt"
+
+	"github.com/dr8co/monke/parser"
+	"github.com/dr8co/monke/token"
+)
+
+// WarningHandler receives the warnings reported during evaluation.
+type WarningHandler func(pos token.Position, message string)
+
+var (
+	// warningHandler is notified of runtime warnings, if set.
+	warningHandler WarningHandler
+
+	// warned records the positions already warned about, so that a warning
+	// inside a loop or a recursive function is only reported once.
+	warned map[token.Position]bool
+
+	// collected gathers the warnings of the running EvalDetailed, if any.
+	collected *collection
+)
+
+// collection holds the warnings reported during an EvalDetailed.
+type collection struct {
+	warnings []parser.Error
+	seen     map[token.Position]bool
+}
+
+// SetWarningHandler installs h to receive subsequent runtime warnings; nil discards them.
+// Each position is reported at most once per handler.
+func SetWarningHandler(h WarningHandler) {
+	warningHandler = h
+	warned = make(map[token.Position]bool)
+}
+
+// warn reports a runtime warning at pos to the installed handler.
+func warn(pos token.Position, format string, a ...any) {
+	handle := warningHandler != nil && !warned[pos]
+	collect := collected != nil && !collected.seen[pos]
+	if !handle && !collect {
+		return
+	}
+	message := fmt.Sprintf(format, a...)
+	if handle {
+		warned[pos] = true
+		warningHandler(pos, message)
+	}
+	if collect {
+		collected.seen[pos] = true
+		collected.warnings = append(collected.warnings, parser.Error{Pos: pos, Message: message})
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/dr8co/monke/evaluator"
+	"github.com/dr8co/monke/examples"
+	"github.com/dr8co/monke/object"
+	"github.com/dr8co/monke/pipeline"
+)
+
+// runExamples implements "monke examples", which lists, shows and runs the embedded sample programs.
+func runExamples(args []string) int {
+	fs := flag.NewFlagSet("examples", flag.ExitOnError)
+	noColor := fs.Bool("no-color", false, "Disable colored output")
+	fs.Usage = func() {
+		out := fs.Output()
+		name := filepath.Base(os.Args[0])
+		fmt.Fprintf(out, "Usage: %s examples [flags]              List the sample programs\n", name)
+		fmt.Fprintf(out, "       %s examples [flags] show <name>  Print the source of a sample\n", name)
+		fmt.Fprintf(out, "       %s examples [flags] run <name>   Run a sample and print its result\n", name)
+		fmt.Fprintln(out, "\nFlags:")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, ex := range examples.All() {
+			fmt.Fprintf(w, "%s\t%s\n", ex.Name, ex.Description)
+		}
+		_ = w.Flush()
+		return 0
+	}
+
+	if fs.NArg() != 2 || fs.Arg(0) != "show" && fs.Arg(0) != "run" {
+		fs.Usage()
+		return 2
+	}
+	ex, ok := examples.Lookup(fs.Arg(1))
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: unknown example %q (see %s examples)\n", fs.Arg(1), filepath.Base(os.Args[0]))
+		return 1
+	}
+
+	if fs.Arg(0) == "show" {
+		fmt.Print(ex.Source)
+		return 0
+	}
+	return runExample(ex, !colorStderr(*noColor))
+}
+
+// runExample evaluates a sample program and prints its result, unless it is null.
+func runExample(ex examples.Example, noColor bool) int {
+	evaluator.SetWarningHandler(stderrWarnings(ex.Name))
+	result := pipeline.Run(ex.Source, nil)
+	if len(result.Errors) != 0 {
+		fmt.Fprint(os.Stderr, formatParseErrors(ex.Source, result.Errors, noColor))
+		return 1
+	}
+	if errObj := result.Error(); errObj != nil {
+		fmt.Fprint(os.Stderr, formatRuntimeError(ex.Source, errObj, noColor))
+		return 1
+	}
+	if result.Value != nil && result.Value.Type() != object.NULL_OBJ {
+		fmt.Println(result.Value.Inspect())
+	}
+	return 0
+}
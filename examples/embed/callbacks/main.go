@@ -0,0 +1,62 @@
+// Callbacks calls Monke functions from Go.
+//
+// A Go function registered with RegisterFunc can take a func parameter, which
+// runs a Monke function while the program runs. Once a program has finished,
+// Interpreter.Call calls the functions it defined.
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/dr8co/monke/interp"
+)
+
+func main() {
+	in := interp.New(interp.Options{})
+
+	// sortBy sorts the strings by the keys the program computes for them
+	err := in.RegisterFunc("sortBy", func(items []string, key func(string) int) []string {
+		sorted := slices.Clone(items)
+		slices.SortStableFunc(sorted, func(a, b string) int { return key(a) - key(b) })
+		return sorted
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	value, err := in.Run(`sortBy(["banana", "fig", "cherry", "kiwi"], fn(s) { len(s) })`)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(value.Inspect())
+
+	// A program defines handlers that the host calls later
+	if _, err := in.Run(`
+		let handlers = {
+			"greet": fn(name) { "hello, " + name },
+			"area": fn(w, h) { w * h },
+		};
+	`); err != nil {
+		log.Fatal(err)
+	}
+	for _, call := range []struct {
+		name string
+		args []any
+	}{
+		{"greet", []any{"monke"}},
+		{"area", []any{3, 4.5}},
+		{"area", []any{3, "wide"}},
+	} {
+		handler, err := in.Run(fmt.Sprintf("handlers[%q]", call.name))
+		if err != nil {
+			log.Fatal(err)
+		}
+		result, err := in.Call(handler, call.args...)
+		if err != nil {
+			fmt.Printf("%s%v: error: %v\n", call.name, call.args, err)
+			continue
+		}
+		fmt.Printf("%s%v: %s\n", call.name, call.args, result.Inspect())
+	}
+}
@@ -0,0 +1,10 @@
+package main
+
+func Example() {
+	main()
+	// Output:
+	// [fig, kiwi, banana, cherry]
+	// greet[monke]: hello, monke
+	// area[3 4.5]: 13.5
+	// area[3 wide]: error: 4:23: type mismatch: INTEGER * STRING
+}
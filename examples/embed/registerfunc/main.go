@@ -0,0 +1,56 @@
+// Registerfunc gives a Monke program Go functions to call.
+//
+// RegisterFunc converts the arguments of each call to the parameter types of
+// the Go function and its result back to a Monke value, and turns a non-nil
+// error result into a runtime error of the program.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/dr8co/monke/interp"
+)
+
+func main() {
+	in := interp.New(interp.Options{})
+	funcs := map[string]any{
+		"repeat": strings.Repeat,
+		"words":  strings.Fields,
+		"sum": func(nums ...float64) float64 {
+			total := 0.0
+			for _, n := range nums {
+				total += n
+			}
+			return total
+		},
+		"divide": func(a, b int) (int, error) {
+			if b == 0 {
+				return 0, errors.New("division by zero")
+			}
+			return a / b, nil
+		},
+	}
+	for name, fn := range funcs {
+		if err := in.RegisterFunc(name, fn); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	for _, src := range []string{
+		`repeat("ab", 3)`,
+		`len(words("the quick brown fox"))`,
+		`sum(1, 2.5, 3)`,
+		`divide(7, 2)`,
+		`divide(1, 0)`,
+	} {
+		value, err := in.Run(src)
+		if err != nil {
+			fmt.Printf("%s: error: %v\n", src, err)
+			continue
+		}
+		fmt.Printf("%s: %s\n", src, value.Inspect())
+	}
+}
@@ -0,0 +1,11 @@
+package main
+
+func Example() {
+	main()
+	// Output:
+	// repeat("ab", 3): ababab
+	// len(words("the quick brown fox")): 4
+	// sum(1, 2.5, 3): 6.5
+	// divide(7, 2): 3
+	// divide(1, 0): error: 1:1: division by zero
+}
@@ -0,0 +1,52 @@
+// Sandbox runs untrusted programs with limits on what they may do.
+//
+// The step, call depth and memory limits stop programs that run too long,
+// recurse too deep or allocate too much; imports only find the modules the
+// host provides; and the output of puts goes to a buffer the host reads.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dr8co/monke/interp"
+	"github.com/dr8co/monke/modules"
+)
+
+func main() {
+	var out strings.Builder
+	in := interp.New(interp.Options{
+		Output:      &out,
+		Modules:     modules.Memory{"greeting": `let greet = fn(name) { "hello, " + name };`},
+		StepLimit:   10_000,
+		DepthLimit:  100,
+		MemoryLimit: 1 << 20,
+	})
+
+	programs := []struct{ name, src string }{
+		{"greet", `import "greeting"; puts(greet("sandbox")); 42`},
+		{"loop", `while (true) {}`},
+		{"recursion", `let f = fn(n) { f(n + 1) }; f(0)`},
+		{"allocation", `let grow = fn(s) { grow(s + s) }; grow("monke")`},
+		{"import", `import "std/list";`},
+	}
+	for _, p := range programs {
+		out.Reset()
+		value, err := in.Run(p.src)
+		switch {
+		case errors.Is(err, interp.ErrStepLimit):
+			fmt.Printf("%s: stopped: too many steps\n", p.name)
+		case errors.Is(err, interp.ErrDepthLimit):
+			fmt.Printf("%s: stopped: calls nested too deep\n", p.name)
+		case errors.Is(err, interp.ErrMemoryLimit):
+			fmt.Printf("%s: stopped: too much memory\n", p.name)
+		case errors.Is(err, modules.ErrNotFound):
+			fmt.Printf("%s: stopped: module not found\n", p.name)
+		case err != nil:
+			fmt.Printf("%s: error: %v\n", p.name, err)
+		default:
+			fmt.Printf("%s: printed %q, returned %s\n", p.name, out.String(), value.Inspect())
+		}
+	}
+}
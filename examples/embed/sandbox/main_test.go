@@ -0,0 +1,11 @@
+package main
+
+func Example() {
+	main()
+	// Output:
+	// greet: printed "hello, sandbox\n", returned 42
+	// loop: stopped: too many steps
+	// recursion: stopped: calls nested too deep
+	// allocation: stopped: too much memory
+	// import: stopped: module not found
+}
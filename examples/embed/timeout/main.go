@@ -0,0 +1,35 @@
+// Timeout stops a program that runs past a deadline.
+//
+// RunContext interrupts the program once its context is done. The program
+// stops at its next step after running its deferred blocks, and the error
+// unwraps to both interp.ErrInterrupted and the error of the context.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dr8co/monke/interp"
+)
+
+func main() {
+	in := interp.New(interp.Options{Output: os.Stdout})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := in.RunContext(ctx, `
+		defer { puts("cleaning up"); }
+		let n = 0;
+		while (true) { n = n + 1; }
+	`)
+	fmt.Println("interrupted:", errors.Is(err, interp.ErrInterrupted))
+	fmt.Println("deadline exceeded:", errors.Is(err, context.DeadlineExceeded))
+
+	// The environment is still usable after an interrupted run
+	n, _ := in.Lookup("n")
+	value, err := in.Run(`n > 0`)
+	fmt.Println("counted:", n.Type(), value.Inspect(), err)
+}
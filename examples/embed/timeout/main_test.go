@@ -0,0 +1,10 @@
+package main
+
+func Example() {
+	main()
+	// Output:
+	// cleaning up
+	// interrupted: true
+	// deadline exceeded: true
+	// counted: INTEGER true <nil>
+}
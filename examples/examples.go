@@ -0,0 +1,75 @@
+// Package examples holds the sample Monke programs embedded in the interpreter.
+//
+// The samples are shown and run by "monke examples" and measured by the
+// profiling tool in cmd/profile. Each one ends with an expression whose value
+// is its result.
+//
+// Key components:
+//   - Example: A named sample program
+//   - All: Lists the samples
+//   - Lookup: Finds a sample by name
+package examples
+
+import (
+	"embed"
+	"path"
+	"strings"
+)
+
+//go:embed programs/*.monkey
+var programs embed.FS
+
+// Example is a sample program.
+type Example struct {
+	Name        string
+	Description string
+	Source      string
+}
+
+// descriptions maps the name of each sample to a one-line description.
+var descriptions = map[string]string{
+	"fibonacci": "Compute the 20th Fibonacci number recursively",
+	"factorial": "Compute the factorial of 10 recursively",
+	"array":     "Double and sum an array with recursive map and reduce",
+	"hash":      "Sum the values of a hash by looking up each key",
+	"complex":   "Sum Fibonacci numbers with map, reduce and recursion",
+	"closures":  "Keep private state in counters built from closures",
+	"primes":    "Find the primes below 50 with while and for loops",
+	"shapes":    "Dispatch on type tags to add up the areas of shapes",
+	"wordcount": "Count repeated words with for-in loops and hash spreads",
+}
+
+// All returns the samples, sorted by name.
+func All() []Example {
+	entries, err := programs.ReadDir("programs")
+	if err != nil {
+		panic(err) // the directory is embedded, so it always exists
+	}
+	all := make([]Example, 0, len(entries))
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), path.Ext(entry.Name()))
+		if ex, ok := Lookup(name); ok {
+			all = append(all, ex)
+		}
+	}
+	return all
+}
+
+// Lookup returns the sample with the given name.
+func Lookup(name string) (Example, bool) {
+	src, err := programs.ReadFile("programs/" + name + ".monkey")
+	if err != nil {
+		return Example{}, false
+	}
+	return Example{Name: name, Description: descriptions[name], Source: string(src)}, true
+}
+
+// Names returns the names of the samples, sorted.
+func Names() []string {
+	all := All()
+	names := make([]string, len(all))
+	for i, ex := range all {
+		names[i] = ex.Name
+	}
+	return names
+}
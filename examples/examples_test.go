@@ -0,0 +1,57 @@
+package examples
+
+import (
+	"testing"
+
+	"github.com/dr8co/monke/evaluator"
+	"github.com/dr8co/monke/lexer"
+	"github.com/dr8co/monke/object"
+	"github.com/dr8co/monke/parser"
+)
+
+func TestExamples(t *testing.T) {
+	results := map[string]string{
+		"fibonacci": "6765",
+		"factorial": "3628800",
+		"array":     "110",
+		"hash":      "15",
+		"complex":   "143",
+		"closures":  "[3, 1]",
+		"primes":    "[2, 3, 5, 7, 11, 13, 17, 19, 23, 29, 31, 37, 41, 43, 47]",
+		"shapes":    "21.70795",
+		"wordcount": "[[the, 3]]",
+	}
+
+	all := All()
+	if len(all) != len(results) {
+		t.Errorf("wrong number of examples. expected=%d, got=%d (%v)", len(results), len(all), Names())
+	}
+	for _, ex := range all {
+		if ex.Description == "" {
+			t.Errorf("%s: missing description", ex.Name)
+		}
+
+		p := parser.New(lexer.New(ex.Source))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Errorf("%s: parser errors: %v", ex.Name, p.Errors())
+			continue
+		}
+		result := evaluator.Eval(program, object.NewEnvironment())
+		if result == nil || result.Inspect() != results[ex.Name] {
+			t.Errorf("%s: wrong result. expected=%s, got=%v", ex.Name, results[ex.Name], result)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	ex, ok := Lookup("fibonacci")
+	if !ok || ex.Name != "fibonacci" || ex.Source == "" {
+		t.Errorf("Lookup(fibonacci) = %+v, %t", ex, ok)
+	}
+	for _, name := range []string{"missing", "", "../examples"} {
+		if _, ok := Lookup(name); ok {
+			t.Errorf("Lookup(%q) found an example", name)
+		}
+	}
+}
@@ -0,0 +1,579 @@
+// Package format prints Monke syntax trees as canonical source code.
+//
+// The output uses one statement per line, four-space indentation inside
+// blocks, and only the parentheses required by operator precedence, so
+// formatting the result again yields the same text.
+//
+// Key components:
+//   - Node: Formats a single AST node
+//   - Source: Parses and formats a complete program
+//   - Minify: Prints a program in as few characters as possible
+package format
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/dr8co/monke/ast"
+	"github.com/dr8co/monke/lexer"
+	"github.com/dr8co/monke/parser"
+	"github.com/dr8co/monke/token"
+)
+
+// indent is the text inserted for each level of nesting.
+const indent = "    "
+
+// primary is the precedence of expressions that never need parentheses.
+const primary = parser.INDEX + 1
+
+// Source parses src and returns it formatted.
+// If src has syntax errors, the parser error messages are returned instead.
+func Source(src string) (string, error) {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) != 0 {
+		return "", errors.New(strings.Join(errs, "\n"))
+	}
+	return Node(program), nil
+}
+
+// Node returns the canonical source of node.
+// Programs end with a newline; other nodes do not.
+func Node(node ast.Node) string {
+	var pr printer
+	pr.node(node)
+	return pr.out.String()
+}
+
+// printer accumulates formatted source.
+type printer struct {
+	out   strings.Builder
+	depth int
+
+	compact bool                       // Leave out optional spaces and line breaks
+	names   map[*ast.Identifier]string // Replacement names of identifiers, if any
+	suffix  byte                       // The '?' or '!' ending the name just written, if any
+}
+
+func (pr *printer) write(s string) {
+	if s == "" {
+		return
+	}
+	if pr.suffix != 0 && !keepsSuffix(pr.suffix, s[0]) {
+		pr.out.WriteByte(' ')
+	}
+	pr.suffix = 0
+	pr.out.WriteString(s)
+}
+
+// pad writes s, a token surrounded by optional spaces, e.g. " = ".
+func (pr *printer) pad(s string) {
+	if pr.compact {
+		s = strings.TrimSpace(s)
+	}
+	pr.write(s)
+}
+
+func (pr *printer) newline() {
+	if pr.compact {
+		return
+	}
+	pr.write("\n")
+	pr.write(strings.Repeat(indent, pr.depth))
+}
+
+// ident writes name, an identifier, and remembers a '?' or '!' ending it so
+// that write can keep the lexer from reading it as part of the next token.
+func (pr *printer) ident(name string) {
+	pr.write(name)
+	if end := name[len(name)-1]; end == '?' || end == '!' {
+		pr.suffix = end
+	}
+}
+
+// keepsSuffix reports whether the lexer reads suffix, the '?' or '!' ending a
+// name, as part of the name when next follows it. A space keeps a '?' since
+// what the printer writes after a name and a space is never an operand.
+func keepsSuffix(suffix, next byte) bool {
+	if suffix == '!' {
+		return next != '='
+	}
+	switch next {
+	case ' ', '\n', '(', ')', ']', '}', ',', ';', '=', ':':
+		return true
+	}
+	return false
+}
+
+// name returns the name to print for ident.
+func (pr *printer) name(ident *ast.Identifier) string {
+	if name, ok := pr.names[ident]; ok {
+		return name
+	}
+	return ident.Value
+}
+
+func (pr *printer) node(node ast.Node) {
+	switch node := node.(type) {
+	case *ast.Program:
+		for _, pragma := range node.Pragmas {
+			pr.write("#pragma " + pragma + "\n")
+		}
+		for i, stmt := range node.Statements {
+			if i > 0 && pr.compact {
+				pr.write(";")
+			}
+			pr.statement(stmt)
+			if !pr.compact {
+				pr.write("\n")
+			}
+		}
+	case ast.Statement:
+		pr.statement(node)
+	case ast.Expression:
+		pr.expression(node)
+	}
+}
+
+func (pr *printer) statement(stmt ast.Statement) {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		pr.write("let ")
+		switch {
+		case stmt.Pattern != nil:
+			pr.pattern(stmt.Pattern)
+		case stmt.Names != nil:
+			for i, name := range stmt.Names {
+				if i > 0 {
+					pr.pad(", ")
+				}
+				pr.ident(pr.name(name))
+			}
+		default:
+			pr.ident(pr.name(stmt.Name))
+		}
+		if stmt.Type != nil {
+			pr.pad(": ")
+			pr.write(stmt.Type.Value)
+		}
+		pr.pad(" = ")
+		pr.expression(stmt.Value)
+		pr.terminate()
+	case *ast.AssignStatement:
+		pr.ident(pr.name(stmt.Name))
+		pr.pad(" = ")
+		pr.expression(stmt.Value)
+		pr.terminate()
+	case *ast.ReturnStatement:
+		pr.write("return")
+		if values, ok := stmt.ReturnValue.(*ast.ArrayLiteral); ok && stmt.Multiple {
+			pr.write(" ")
+			pr.expressions(values.Elements)
+		} else if stmt.ReturnValue != nil {
+			pr.write(" ")
+			pr.expression(stmt.ReturnValue)
+		}
+		pr.terminate()
+	case *ast.DeferStatement:
+		pr.pad("defer ")
+		pr.block(stmt.Body)
+	case *ast.ImportStatement:
+		pr.write("import ")
+		pr.expression(stmt.Path)
+		pr.terminate()
+	case *ast.ClassStatement:
+		pr.class(stmt)
+	case *ast.BlockStatement:
+		pr.block(stmt)
+	case *ast.ExpressionStatement:
+		if stmt.Expression == nil {
+			return
+		}
+		pr.expression(stmt.Expression)
+		if !endsWithBlock(stmt.Expression) {
+			pr.terminate()
+		}
+	default:
+		pr.write(stmt.String())
+	}
+}
+
+// class formats a class statement with one method per line.
+func (pr *printer) class(stmt *ast.ClassStatement) {
+	pr.write("class ")
+	pr.ident(pr.name(stmt.Name))
+	pr.write("(")
+	for i, field := range stmt.Fields {
+		if i > 0 {
+			pr.pad(", ")
+		}
+		// Fields are keys of the instances, so they are never renamed
+		pr.ident(field.Value)
+	}
+	pr.pad(") ")
+	if len(stmt.Methods) == 0 {
+		pr.write("{}")
+		return
+	}
+	pr.write("{")
+	pr.depth++
+	for _, method := range stmt.Methods {
+		pr.newline()
+		pr.write("fn ")
+		pr.ident(method.Name.Value)
+		pr.function(method.Function)
+	}
+	pr.depth--
+	pr.newline()
+	pr.write("}")
+}
+
+// function formats the parameters, return type and body of fn, which follow
+// the 'fn' keyword or the name of a method.
+func (pr *printer) function(fn *ast.FunctionLiteral) {
+	pr.write("(")
+	for i, param := range fn.Parameters {
+		if i > 0 {
+			pr.pad(", ")
+		}
+		if fn.Rest && i == len(fn.Parameters)-1 {
+			pr.write("...")
+		}
+		pr.ident(pr.name(param))
+		if typ := fn.ParamType(i); typ != nil {
+			pr.pad(": ")
+			pr.write(typ.Value)
+		}
+	}
+	pr.pad(") ")
+	if fn.ReturnType != nil {
+		pr.pad("-> ")
+		pr.pad(fn.ReturnType.Value + " ")
+	}
+	pr.block(fn.Body)
+}
+
+// terminate ends a statement with a semicolon. In compact source, semicolons
+// only separate statements, so the enclosing list writes them instead.
+func (pr *printer) terminate() {
+	if !pr.compact {
+		pr.write(";")
+	}
+}
+
+// endsWithBlock reports whether an expression statement reads as a block
+// statement, which is not followed by a semicolon.
+func endsWithBlock(exp ast.Expression) bool {
+	switch exp.(type) {
+	case *ast.IfExpression, *ast.WhileExpression, *ast.ForExpression, *ast.ForInExpression, *ast.MatchExpression:
+		return true
+	}
+	return false
+}
+
+// clause formats a statement of a for loop header, without its semicolon.
+func (pr *printer) clause(stmt ast.Statement) {
+	sub := printer{compact: pr.compact, names: pr.names}
+	sub.statement(stmt)
+	pr.write(strings.TrimSuffix(sub.out.String(), ";"))
+}
+
+func (pr *printer) block(block *ast.BlockStatement) {
+	if len(block.Statements) == 0 {
+		pr.write("{}")
+		return
+	}
+	pr.write("{")
+	pr.depth++
+	for i, stmt := range block.Statements {
+		if i > 0 && pr.compact {
+			pr.write(";")
+		}
+		pr.newline()
+		pr.statement(stmt)
+	}
+	pr.depth--
+	pr.newline()
+	pr.write("}")
+}
+
+// precedence returns how tightly exp binds, using the parser's precedences.
+func precedence(exp ast.Expression) int {
+	switch exp := exp.(type) {
+	case *ast.InfixExpression:
+		return parser.Precedence(exp.Token.Type)
+	case *ast.ConditionalExpression:
+		return parser.TERNARY
+	case *ast.PrefixExpression:
+		return parser.PREFIX
+	case *ast.CallExpression:
+		return parser.CALL
+	case *ast.IndexExpression, *ast.SliceExpression:
+		return parser.INDEX
+	default:
+		return primary
+	}
+}
+
+// operand formats exp, parenthesized if it binds less tightly than minPrec.
+func (pr *printer) operand(exp ast.Expression, minPrec int) {
+	if precedence(exp) < minPrec {
+		pr.write("(")
+		pr.expression(exp)
+		pr.write(")")
+		return
+	}
+	pr.expression(exp)
+}
+
+func (pr *printer) expressions(exps []ast.Expression) {
+	for i, exp := range exps {
+		if i > 0 {
+			pr.pad(", ")
+		}
+		pr.expression(exp)
+	}
+}
+
+func (pr *printer) expression(exp ast.Expression) {
+	switch exp := exp.(type) {
+	case *ast.Identifier:
+		pr.ident(pr.name(exp))
+	case *ast.StringLiteral:
+		if exp.Token.Type == token.RAW_STRING {
+			pr.write("`" + exp.Value + "`")
+		} else {
+			pr.write(`"` + exp.Value + `"`)
+		}
+	case *ast.PrefixExpression:
+		pr.write(exp.Operator)
+		pr.operand(exp.Right, parser.PREFIX)
+	case *ast.InfixExpression:
+		// Operators are left-associative, so only the right operand
+		// needs parentheses at the same precedence
+		prec := precedence(exp)
+		pr.operand(exp.Left, prec)
+		if prec == parser.RANGE {
+			// Ranges read as one value, so they are written without spaces
+			pr.write(exp.Operator)
+		} else {
+			pr.pad(" " + exp.Operator + " ")
+		}
+		pr.operand(exp.Right, prec+1)
+	case *ast.ConditionalExpression:
+		// The operator is right-associative, so only the condition needs
+		// parentheses when it is itself a conditional expression
+		pr.operand(exp.Condition, parser.TERNARY+1)
+		pr.pad(" ? ")
+		pr.expression(exp.Consequence)
+		pr.pad(" : ")
+		pr.operand(exp.Alternative, parser.TERNARY)
+	case *ast.IfExpression:
+		pr.write("if")
+		pr.pad(" (")
+		pr.expression(exp.Condition)
+		pr.pad(") ")
+		pr.block(exp.Consequence)
+		switch alternative := exp.Alternative.(type) {
+		case *ast.BlockStatement:
+			pr.pad(" else ")
+			pr.block(alternative)
+		case *ast.IfExpression:
+			pr.pad(" else")
+			pr.write(" ")
+			pr.expression(alternative)
+		}
+	case *ast.WhileExpression:
+		pr.write("while")
+		pr.pad(" (")
+		pr.expression(exp.Condition)
+		pr.pad(") ")
+		pr.block(exp.Body)
+	case *ast.ForExpression:
+		pr.write("for")
+		pr.pad(" (")
+		if exp.Init != nil {
+			pr.clause(exp.Init)
+		}
+		pr.pad("; ")
+		if exp.Condition != nil {
+			pr.expression(exp.Condition)
+		}
+		pr.pad("; ")
+		if exp.Post != nil {
+			pr.clause(exp.Post)
+		}
+		pr.pad(") ")
+		pr.block(exp.Body)
+	case *ast.ForInExpression:
+		pr.write("for")
+		pr.pad(" (")
+		if exp.Key != nil {
+			pr.ident(pr.name(exp.Key))
+			pr.pad(", ")
+		}
+		pr.ident(pr.name(exp.Value))
+		pr.write(" in ")
+		pr.expression(exp.Iterable)
+		pr.pad(") ")
+		pr.block(exp.Body)
+	case *ast.FunctionLiteral:
+		pr.write("fn")
+		pr.function(exp)
+	case *ast.CallExpression:
+		pr.operand(exp.Function, parser.CALL)
+		pr.write("(")
+		pr.expressions(exp.Arguments)
+		pr.write(")")
+	case *ast.ArrayLiteral:
+		pr.write("[")
+		pr.expressions(exp.Elements)
+		pr.write("]")
+	case *ast.SpreadElement:
+		pr.write("...")
+		pr.expression(exp.Value)
+	case *ast.NamedArgument:
+		// The name is the callee's, so it is never renamed
+		pr.write(exp.Name.Value)
+		pr.pad(": ")
+		pr.expression(exp.Value)
+	case *ast.IndexExpression:
+		// Calls and index expressions chain from left to right
+		pr.operand(exp.Left, parser.CALL)
+		if exp.Optional {
+			pr.write("?.")
+		}
+		if field, ok := exp.Field(); ok {
+			if !exp.Optional {
+				pr.write(".")
+			}
+			pr.ident(field)
+			return
+		}
+		pr.write("[")
+		pr.expression(exp.Index)
+		pr.write("]")
+	case *ast.SliceExpression:
+		pr.operand(exp.Left, parser.CALL)
+		if exp.Optional {
+			pr.write("?.")
+		}
+		pr.write("[")
+		if exp.Start != nil {
+			pr.expression(exp.Start)
+		}
+		pr.write(":")
+		if exp.End != nil {
+			pr.expression(exp.End)
+		}
+		pr.write("]")
+	case *ast.HashLiteral:
+		pr.hash(exp)
+	case *ast.MatchExpression:
+		pr.match(exp)
+	default:
+		pr.write(exp.String())
+	}
+}
+
+func (pr *printer) hash(hash *ast.HashLiteral) {
+	keys := hash.Order
+	if keys == nil {
+		for key := range hash.Pairs {
+			keys = append(keys, key)
+		}
+	}
+	pr.write("{")
+	for i, key := range keys {
+		if i > 0 {
+			pr.pad(", ")
+		}
+		pr.expression(key)
+		if _, ok := key.(*ast.SpreadElement); !ok {
+			pr.pad(": ")
+			pr.expression(hash.Pairs[key])
+		}
+	}
+	pr.write("}")
+}
+
+// match formats a match expression with one arm per line. Arms with an
+// expression body end with a comma; arms with a block need none.
+func (pr *printer) match(match *ast.MatchExpression) {
+	pr.write("match")
+	pr.pad(" (")
+	pr.expression(match.Value)
+	pr.pad(") ")
+	if len(match.Arms) == 0 {
+		pr.write("{}")
+		return
+	}
+	pr.write("{")
+	pr.depth++
+	for i, arm := range match.Arms {
+		pr.newline()
+		pr.pattern(arm.Pattern)
+		pr.pad(" => ")
+		if block, ok := arm.Body.(*ast.BlockStatement); ok {
+			pr.block(block)
+			continue
+		}
+		pr.expression(arm.Body.(*ast.ExpressionStatement).Expression)
+		if !pr.compact || i < len(match.Arms)-1 {
+			pr.write(",")
+		}
+	}
+	pr.depth--
+	pr.newline()
+	pr.write("}")
+}
+
+func (pr *printer) pattern(pattern ast.Pattern) {
+	switch pattern := pattern.(type) {
+	case *ast.BindingPattern:
+		pr.ident(pr.name(pattern.Name))
+	case *ast.LiteralPattern:
+		pr.expression(pattern.Value)
+	case *ast.ArrayPattern:
+		pr.write("[")
+		for i, el := range pattern.Elements {
+			if i > 0 {
+				pr.pad(", ")
+			}
+			pr.pattern(el)
+		}
+		if pattern.Rest != nil {
+			if len(pattern.Elements) > 0 {
+				pr.pad(", ")
+			}
+			pr.write("...")
+			pr.ident(pr.name(pattern.Rest.Name))
+		}
+		pr.write("]")
+	case *ast.HashPattern:
+		if pattern.Tag != "" {
+			pr.write(pattern.Tag)
+			pr.pad(" ")
+		}
+		pr.write("{")
+		for i, key := range pattern.Keys {
+			if i > 0 {
+				pr.pad(", ")
+			}
+			field, bare := key.(*ast.StringLiteral)
+			bare = bare && field.Token.Type == token.IDENT
+			if binding, ok := pattern.Values[i].(*ast.BindingPattern); ok && bare && pr.name(binding.Name) == field.Value {
+				pr.write(field.Value)
+				continue
+			}
+			if bare {
+				pr.write(field.Value)
+			} else {
+				pr.expression(key)
+			}
+			pr.pad(": ")
+			pr.pattern(pattern.Values[i])
+		}
+		pr.write("}")
+	}
+}
@@ -0,0 +1,78 @@
+package format
+
+import "testing"
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x=1+2*3", "let x = 1 + 2 * 3;\n"},
+		{"(1 + 2) * 3; 1 - (2 - 3); (1 - 2) - 3", "(1 + 2) * 3;\n1 - (2 - 3);\n1 - 2 - 3;\n"},
+		{"-(a + b); !f(x); -(-5)", "-(a + b);\n!f(x);\n--5;\n"},
+		{
+			"let max = fn(a, b) { if (a > b) { return a; } else { b } };",
+			"let max = fn(a, b) {\n    if (a > b) {\n        return a;\n    } else {\n        b;\n    }\n};\n",
+		},
+		{"if (x) { }", "if (x) {}\n"},
+		{"if(a){1}else if(b){2}else{3}", "if (a) {\n    1;\n} else if (b) {\n    2;\n} else {\n    3;\n}\n"},
+		{"a<b?a:(c?d:e); (a?b:c)?d:e; (a ?? b) ? c : d", "a < b ? a : c ? d : e;\n(a ? b : c) ? d : e;\na ?? b ? c : d;\n"},
+		{`let h = {"b": [1, ...xs], "a": h?.k ?? h?.["k k"]}; h["a"]`,
+			"let h = {\"b\": [1, ...xs], \"a\": h?.k ?? h?.[\"k k\"]};\nh[\"a\"];\n"},
+		{"person . name; h.a?.b.c; (a + b).x", "person.name;\nh.a?.b.c;\n(a + b).x;\n"},
+		{"xs[1:n-1]; s[ : 2]+s[2 :]; (a + b)[:]; h?.[1:]", "xs[1:n - 1];\ns[:2] + s[2:];\n(a + b)[:];\nh?.[1:];\n"},
+		{"fn(x) { x }(5); (a + b)(c); f(1)[0]", "fn(x) {\n    x;\n}(5);\n(a + b)(c);\nf(1)[0];\n"},
+		{"#pragma strict\ndefer { puts(1) }", "#pragma strict\ndefer {\n    puts(1);\n}\n"},
+		{"3.50 * 2", "3.50 * 2;\n"},
+		{"0 .. n+1; (a..b)[0]; 1..=f(x)", "0..n + 1;\n(a..b)[0];\n1..=f(x);\n"},
+		{"f(1,by:x?1:2)", "f(1, by: x ? 1 : 2);\n"},
+		{"let empty?=fn(x1){x1==[]}; empty?(xs) ? done! : h2", "let empty? = fn(x1) {\n    x1 == [];\n};\nempty?(xs) ? done! : h2;\n"},
+		{"fn(a,...rest:array){rest}", "fn(a, ...rest: array) {\n    rest;\n};\n"},
+		{"let q,r=fn(){return 1,a+b}()", "let q, r = fn() {\n    return 1, a + b;\n}();\n"},
+		{"let [h,...t]=xs", "let [h, ...t] = xs;\n"},
+		{"let {name,\"age\":a}=p", "let {name, \"age\": a} = p;\n"},
+		{"import \"std/list\" import `./util`", "import \"std/list\";\nimport `./util`;\n"},
+		{"while (i < 3) { i = i + 1 }", "while (i < 3) {\n    i = i + 1;\n}\n"},
+		{"for (let i = 0; i < 3; i = i + 1) { puts(i) }", "for (let i = 0; i < 3; i = i + 1) {\n    puts(i);\n}\n"},
+		{"for (;;) {}", "for (; ; ) {}\n"},
+		{"for(k,v in h){puts(k)}", "for (k, v in h) {\n    puts(k);\n}\n"},
+		{
+			"match(v){0=>a,[x,..._]=>{x},Point{x,\"y\":-1,z:[]}=>x}",
+			"match (v) {\n    0 => a,\n    [x, ..._] => {\n        x;\n    }\n    Point {x, \"y\": -1, z: []} => x,\n}\n",
+		},
+		{"let add:fn=fn(a:int,b)->int{a+b}", "let add: fn = fn(a: int, b) -> int {\n    a + b;\n};\n"},
+		{"if (x) { `say \"hi\"\n  twice` }", "if (x) {\n    `say \"hi\"\n  twice`;\n}\n"},
+		{
+			"class Point(x,y){fn norm()->int{self.x*self.x+self.y*self.y};fn add(o){Point(self.x+o.x,self.y+o.y)}} class Unit(){}",
+			"class Point(x, y) {\n    fn norm() -> int {\n        self.x * self.x + self.y * self.y;\n    }\n" +
+				"    fn add(o) {\n        Point(self.x + o.x, self.y + o.y);\n    }\n}\nclass Unit() {}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := Source(tt.input)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.input, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("%q: wrong output.\nexpected=%q\ngot=     %q", tt.input, tt.expected, got)
+		}
+
+		// Formatting is idempotent
+		again, err := Source(got)
+		if err != nil {
+			t.Errorf("%q: formatted source does not parse: %v", got, err)
+			continue
+		}
+		if again != got {
+			t.Errorf("formatting %q again changed it to %q", got, again)
+		}
+	}
+}
+
+func TestSourceErrors(t *testing.T) {
+	if _, err := Source("let = 5;"); err == nil {
+		t.Errorf("expected an error for invalid source")
+	}
+}
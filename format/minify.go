@@ -0,0 +1,443 @@
+package format
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/dr8co/monke/ast"
+	"github.com/dr8co/monke/lexer"
+	"github.com/dr8co/monke/parser"
+	"github.com/dr8co/monke/token"
+)
+
+// Minify parses src and prints it in as few characters as possible: without
+// comments, and with spaces and line breaks only where tokens would merge.
+// With rename, the names that are local to a function are also replaced by
+// short ones. Global names are kept, since other code such as a REPL session
+// or the interpreter's own bindings may refer to them.
+// If src has syntax errors, the parser error messages are returned instead.
+func Minify(src string, rename bool) (string, error) {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) != 0 {
+		return "", errors.New(strings.Join(errs, "\n"))
+	}
+
+	pr := printer{compact: true}
+	if rename {
+		pr.names = localNames(program)
+	}
+	pr.node(program)
+	return pr.out.String(), nil
+}
+
+// scope holds the names bound by a function literal: its parameters and the
+// names bound in its body by let and for-in. Loop bodies and the branches of
+// if expressions get environments of their own too, but every binding of a
+// name in a function gets the same replacement, so shadowing between them is
+// unaffected.
+type scope struct {
+	parent  *scope
+	params  map[string]bool
+	bound   map[string]bool
+	settled map[string]bool // Names known to be bound at the current point of the walk
+	unsafe  map[string]bool // Names that may also refer to a binding outside the scope
+	uses    map[string][]*ast.Identifier
+	first   []string // The bound names in the order they first occur
+	kids    []*scope
+	next    int // The index of the first short name given to this scope
+}
+
+func newScope(parent *scope) *scope {
+	sc := &scope{
+		parent:  parent,
+		params:  make(map[string]bool),
+		bound:   make(map[string]bool),
+		settled: make(map[string]bool),
+		unsafe:  make(map[string]bool),
+		uses:    make(map[string][]*ast.Identifier),
+	}
+	if parent != nil {
+		parent.kids = append(parent.kids, sc)
+	}
+	return sc
+}
+
+// bind records that the scope binds name.
+func (sc *scope) bind(name string) {
+	if !sc.bound[name] {
+		sc.bound[name] = true
+		sc.first = append(sc.first, name)
+	}
+}
+
+// resolve returns the innermost scope from sc outwards that binds name, or
+// nil if it is a global.
+func (sc *scope) resolve(name string) *scope {
+	for ; sc != nil; sc = sc.parent {
+		if sc.bound[name] {
+			return sc
+		}
+	}
+	return nil
+}
+
+// localNames returns short replacement names for the identifiers bound by
+// function literals. A name is only replaced when every use of it is known to
+// refer to the local binding: uses that may run before the binding exists, and
+// would then see an outer or global binding of the same name, keep the names
+// of all the bindings involved. So do parameters that share their name with a
+// named argument anywhere in the program, as the call may be to them.
+func localNames(program *ast.Program) map[*ast.Identifier]string {
+	// Type annotations are identifiers too, but name types rather than bindings
+	annotations := make(map[*ast.Identifier]bool)
+	taken := make(map[string]bool)
+	named := make(map[string]bool)
+	ast.Inspect(program, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.Identifier:
+			taken[n.Value] = true
+		case *ast.NamedArgument:
+			named[n.Name.Value] = true
+		case *ast.CallExpression:
+			if name, ok := methodName(n); ok {
+				taken[name] = true
+			}
+		case *ast.LetStatement:
+			annotations[n.Type] = true
+		case *ast.FunctionLiteral:
+			for _, typ := range n.ParamTypes {
+				annotations[typ] = true
+			}
+			annotations[n.ReturnType] = true
+		}
+		return true
+	})
+
+	scopes := make(map[*ast.FunctionLiteral]*scope)
+	collectBindings(program, nil, scopes)
+
+	root := newScope(nil)
+	w := walker{scopes: scopes, annotations: annotations, named: named}
+	w.walk(program, root)
+
+	var short shortNames
+	short.taken = taken
+	names := make(map[*ast.Identifier]string)
+	var assign func(sc *scope)
+	assign = func(sc *scope) {
+		// The most used names get the shortest replacements
+		var renamed []string
+		for _, name := range sc.first {
+			if !sc.unsafe[name] {
+				renamed = append(renamed, name)
+			}
+		}
+		slices.SortStableFunc(renamed, func(a, b string) int {
+			return cmp.Compare(len(sc.uses[b]), len(sc.uses[a]))
+		})
+		for i, name := range renamed {
+			for _, ident := range sc.uses[name] {
+				names[ident] = short.get(sc.next + i)
+			}
+		}
+		// Nested functions start after this one's names, so they never
+		// shadow them, while sibling functions reuse the same names
+		for _, kid := range sc.kids {
+			kid.next = sc.next + len(renamed)
+			assign(kid)
+		}
+	}
+	for _, kid := range root.kids {
+		assign(kid)
+	}
+	return names
+}
+
+// collectBindings records the names bound by each function literal under node.
+func collectBindings(node ast.Node, sc *scope, scopes map[*ast.FunctionLiteral]*scope) {
+	ast.Inspect(node, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.FunctionLiteral:
+			fn := &scope{bound: make(map[string]bool), params: make(map[string]bool)}
+			for _, param := range n.Parameters {
+				fn.bind(param.Value)
+				fn.params[param.Value] = true
+			}
+			scopes[n] = fn
+			collectBindings(n.Body, fn, scopes)
+			return false
+		case *ast.LetStatement:
+			if sc != nil {
+				for _, name := range n.Identifiers() {
+					sc.bind(name.Value)
+				}
+			}
+		case *ast.ClassStatement:
+			if sc != nil {
+				sc.bind(n.Name.Value)
+			}
+		case *ast.ForInExpression:
+			if sc != nil {
+				if n.Key != nil {
+					sc.bind(n.Key.Value)
+				}
+				sc.bind(n.Value.Value)
+			}
+		case *ast.BindingPattern:
+			if sc != nil && !n.Wildcard() {
+				sc.bind(n.Name.Value)
+			}
+		}
+		return true
+	})
+}
+
+// walker resolves every identifier to the scope binding it, in source order.
+type walker struct {
+	scopes      map[*ast.FunctionLiteral]*scope
+	annotations map[*ast.Identifier]bool
+	named       map[string]bool // The names passed as named arguments, which callers depend on
+	loops       []loop          // The loops whose bodies enclose the current node
+}
+
+// loop holds the names a loop binds for its body, and the scope they are bound in.
+type loop struct {
+	names []string
+	sc    *scope
+}
+
+// walk resolves the identifiers under node, which is in the scope sc. The root
+// scope stands for the globals and renames nothing.
+func (w *walker) walk(node ast.Node, sc *scope) {
+	if node == nil {
+		return
+	}
+	switch n := node.(type) {
+	case *ast.FunctionLiteral:
+		collected := w.scopes[n]
+		fn := newScope(sc)
+		fn.params, fn.bound, fn.first = collected.params, collected.bound, collected.first
+		for _, param := range n.Parameters {
+			fn.uses[param.Value] = append(fn.uses[param.Value], param)
+			// Any call may pass the parameter by its name
+			if w.named[param.Value] {
+				fn.unsafe[param.Value] = true
+			}
+		}
+		for _, stmt := range n.Body.Statements {
+			w.statement(stmt, fn, true)
+		}
+		// A name this function cannot rename may refer to any enclosing binding
+		for name := range fn.unsafe {
+			w.unsafe(sc, name)
+		}
+		return
+	case *ast.Identifier:
+		if w.annotations[n] {
+			return
+		}
+		w.use(n, sc)
+		return
+	}
+
+	// Statements below the top level of a function body may not run
+	if stmt, ok := node.(ast.Statement); ok {
+		w.statement(stmt, sc, false)
+		return
+	}
+	w.children(node, sc)
+}
+
+// statement resolves the identifiers of stmt. Top-level statements of a
+// function body always run before the statements that follow them.
+func (w *walker) statement(stmt ast.Statement, sc *scope, top bool) {
+	switch s := stmt.(type) {
+	case *ast.LetStatement:
+		for _, name := range s.Identifiers() {
+			w.own(name, sc)
+		}
+		// A function literal can only be called once it is bound, so its
+		// uses of the name are settled already
+		if _, ok := s.Value.(*ast.FunctionLiteral); ok && top && !s.Destructures() {
+			sc.settled[s.Name.Value] = true
+		}
+		w.walk(s.Value, sc)
+		if top {
+			for _, name := range s.Identifiers() {
+				sc.settled[name.Value] = true
+			}
+		}
+	case *ast.ClassStatement:
+		// Like a function literal, a method can only be called once its class
+		// is bound. Fields and method names are keys of the instances, and
+		// self is the instance, so none of them refers to a binding in scope.
+		w.own(s.Name, sc)
+		if top {
+			sc.settled[s.Name.Value] = true
+		}
+		w.unsafe(sc, "self")
+		for _, method := range s.Methods {
+			w.walk(method.Function, sc)
+		}
+	case *ast.ExpressionStatement:
+		w.walk(s.Expression, sc)
+	case *ast.ImportStatement:
+		// The names a module binds are unknown, and may be the replacement of
+		// any name visible here, so none of them is renamed
+		for ; sc != nil; sc = sc.parent {
+			for _, name := range sc.first {
+				w.unsafe(sc, name)
+			}
+		}
+	default:
+		w.children(stmt, sc)
+	}
+}
+
+// children resolves the identifiers of the children of node.
+func (w *walker) children(node ast.Node, sc *scope) {
+	switch expr := node.(type) {
+	case *ast.ForInExpression:
+		w.walk(expr.Iterable, sc)
+		names := []string{expr.Value.Value}
+		if expr.Key != nil {
+			w.own(expr.Key, sc)
+			names = append(names, expr.Key.Value)
+		}
+		w.own(expr.Value, sc)
+		w.loops = append(w.loops, loop{names, sc})
+		w.walk(expr.Body, sc)
+		w.loops = w.loops[:len(w.loops)-1]
+		return
+	case *ast.MatchExpression:
+		// The names of a pattern are bound whenever the body of its arm runs
+		w.walk(expr.Value, sc)
+		for _, arm := range expr.Arms {
+			var names []string
+			ast.Inspect(arm.Pattern, func(node ast.Node) bool {
+				if binding, ok := node.(*ast.BindingPattern); ok && !binding.Wildcard() {
+					w.own(binding.Name, sc)
+					names = append(names, binding.Name.Value)
+				}
+				return true
+			})
+			w.loops = append(w.loops, loop{names, sc})
+			w.walk(arm.Body, sc)
+			w.loops = w.loops[:len(w.loops)-1]
+		}
+		return
+	case *ast.NamedArgument:
+		// The name is that of a parameter of the callee, not a use
+		w.walk(expr.Value, sc)
+		return
+	case *ast.CallExpression:
+		// A method call may be to any function of its name in scope
+		if name, ok := methodName(expr); ok {
+			w.unsafe(sc, name)
+		}
+	case *ast.ForExpression:
+		// The initialization binds its name in the environment of the loop
+		if init, ok := expr.Init.(*ast.LetStatement); ok {
+			var names []string
+			for _, name := range init.Identifiers() {
+				w.own(name, sc)
+				names = append(names, name.Value)
+			}
+			w.walk(init.Value, sc)
+			w.loops = append(w.loops, loop{names, sc})
+			w.walk(expr.Condition, sc)
+			w.walk(expr.Post, sc)
+			w.walk(expr.Body, sc)
+			w.loops = w.loops[:len(w.loops)-1]
+			return
+		}
+	}
+	ast.Inspect(node, func(child ast.Node) bool {
+		if child == nil || child == node {
+			return child == node
+		}
+		w.walk(child, sc)
+		return false
+	})
+}
+
+// methodName returns the name of the method a call of a field, "recv.name()",
+// may dispatch to, and whether call is one.
+func methodName(call *ast.CallExpression) (string, bool) {
+	field, ok := call.Function.(*ast.IndexExpression)
+	if !ok {
+		return "", false
+	}
+	return field.Field()
+}
+
+// own records a binding occurrence of ident in sc.
+func (w *walker) own(ident *ast.Identifier, sc *scope) {
+	if sc.parent == nil {
+		return // A global
+	}
+	sc.uses[ident.Value] = append(sc.uses[ident.Value], ident)
+}
+
+// use records a use of ident in sc, and whether it is sure to refer to the
+// binding it resolves to.
+func (w *walker) use(ident *ast.Identifier, sc *scope) {
+	name := ident.Value
+	owner := sc.resolve(name)
+	if owner == nil || owner.parent == nil {
+		return
+	}
+	owner.uses[name] = append(owner.uses[name], ident)
+	if owner.params[name] || owner.settled[name] {
+		return
+	}
+	// The names of a loop are bound whenever its body runs
+	for _, l := range w.loops {
+		if l.sc == owner && slices.Contains(l.names, name) {
+			return
+		}
+	}
+	w.unsafe(owner, name)
+}
+
+// unsafe records that name cannot be renamed in sc or any scope enclosing it.
+func (w *walker) unsafe(sc *scope, name string) {
+	for ; sc != nil && sc.parent != nil; sc = sc.parent {
+		if sc.bound[name] {
+			sc.unsafe[name] = true
+		}
+	}
+}
+
+// shortNames generates the replacement names a, b, ..., z, aa, ab, ...,
+// skipping keywords and the names used in the program.
+type shortNames struct {
+	taken map[string]bool
+	names []string
+	n     int // The number of candidates generated so far
+}
+
+// get returns the i-th replacement name.
+func (s *shortNames) get(i int) string {
+	for len(s.names) <= i {
+		name := candidate(s.n)
+		s.n++
+		if !s.taken[name] && token.LookupIdent(name) == token.IDENT {
+			s.names = append(s.names, name)
+		}
+	}
+	return s.names[i]
+}
+
+// candidate returns the n-th name in the sequence a, ..., z, aa, ..., zz, aaa, ...
+func candidate(n int) string {
+	var b []byte
+	for n++; n > 0; n = (n - 1) / 26 {
+		b = append(b, byte('a'+(n-1)%26))
+	}
+	slices.Reverse(b)
+	return string(b)
+}
@@ -0,0 +1,144 @@
+package format_test
+
+import (
+	"testing"
+
+	"github.com/dr8co/monke/evaluator"
+	"github.com/dr8co/monke/examples"
+	"github.com/dr8co/monke/format"
+	"github.com/dr8co/monke/lexer"
+	"github.com/dr8co/monke/object"
+	"github.com/dr8co/monke/parser"
+)
+
+func TestMinify(t *testing.T) {
+	tests := []struct {
+		input    string
+		rename   bool
+		expected string
+	}{
+		{"let x = 1 + 2; /* sum */\n/* done */ x", false, "let x=1+2;x"},
+		{"if (x) { 1 } else { 2 }; while (y) { y = y - 1 }", false, "if(x){1}else{2};while(y){y=y-1}"},
+		{"if (x) { 1 } else if (y) { 2 }", false, "if(x){1}else if(y){2}"},
+		{"for (k, v in h) { puts(k) }; a - -b; -(-5)", false, "for(k,v in h){puts(k)};a--b;--5"},
+		{"let x = a < b ? -1 : (c ? d : e)", false, "let x=a<b?-1:c?d:e"},
+		{"#pragma strict\nlet f = fn(a: int) -> int { return a; };", false, "#pragma strict\nlet f=fn(a:int)->int{return a}"},
+		{`{"k": [1, ...xs]}?.k ?? h?.["a b"]`, false, `{"k":[1,...xs]}?.k??h?.["a b"]`},
+		{"xs[1 : n - 1] + xs[:2] + h?.[1:]", false, "xs[1:n-1]+xs[:2]+h?.[1:]"},
+		{"for (i in 0 .. n) { ok? ..= i }", false, "for(i in 0..n){ok? ..=i}"},
+		{"let done! = empty? ?? ok?; done! == (empty?)[0] ? ok? .x : 1", false, "let done! =empty? ??ok?;done! ==empty? [0]?ok? .x:1"},
+		{"for (x? in xs) { (x?) - 1 ? x? : [x?] }", false, "for(x? in xs){x? -1?x?:[x?]}"},
+		{
+			// A method call may be to a local function, which keeps its name
+			"let f = fn(list) { let double = fn(x) { x * 2 }; let total = 0; total + list.double() }",
+			true, "let f=fn(a){let double=fn(c){c*2};let b=0;b+a.double()}",
+		},
+		{
+			"let add = fn(first, second) { let total = first + second; total }; add(1, 2)",
+			true, "let add=fn(a,b){let c=a+b;c};add(1,2)",
+		},
+		{
+			// Uses may not run, or run before the binding, so those names are kept
+			"let f = fn(list) { puts(n); let n = 1; for (item in list) { n = n + item }; item }",
+			true, "let f=fn(a){puts(n);let n=1;for(item in a){n=n+item};item}",
+		},
+		{
+			// Nested functions do not reuse the names of enclosing ones
+			"let g = fn(x) { fn(y) { x + y } }; let h = fn(z) { let loop = fn(i) { if (i == 0) { z } else { loop(i - 1) } }; loop(3) }",
+			true, "let g=fn(a){fn(b){a+b}};let h=fn(b){let a=fn(c){if(c==0){b}else{a(c-1)}};a(3)}",
+		},
+		{
+			// A let in an if branch is local to the branch
+			"let x = 1; let f = fn(n) { let x = n; if (n) { let x = 2; x }; x }; x",
+			true, "let x=1;let f=fn(b){let a=b;if(b){let a=2;a};a};x",
+		},
+		{
+			"let f = fn(n) { if (n) { let y = 2 }; y }",
+			true, "let f=fn(a){if(a){let y=2};y}",
+		},
+		{
+			// Loop counters are local to the loop
+			"let f = fn(n) { for (let i = 0; i < n; i = i + 1) { n }; i }",
+			true, "let f=fn(a){for(let i=0;i<a;i=i+1){a};i}",
+		},
+		{
+			// Replacements skip the names already used by the program
+			"let a = 1; let f = fn(count) { for (let i = 0; i < count; i = i + 1) { puts(a) } }",
+			true, "let a=1;let f=fn(c){for(let b=0;b<c;b=b+1){puts(a)}}",
+		},
+		{"let f = fn(n: int) { let m: int = n; m }", true, "let f=fn(a:int){let b:int=a;b}"},
+		{"let f = fn(first, ...others) { others }", true, "let f=fn(b,...a){a}"},
+		{
+			// Destructuring binds every name but "_"
+			"let f = fn(pair) { let _, second = pair; let first, last = [1, 2]; return first + _, last + second; }",
+			true, "let f=fn(a){let _,b=a;let c,d=[1,2];return c+_,d+b}",
+		},
+		{
+			"let f = fn(list) { let [head, ...tail] = list; let [[x, _]] = [tail]; head + x }",
+			true, "let f=fn(a){let [b,...c]=a;let [[d,_]]=[c];b+d}",
+		},
+		{
+			"let f = fn(person) { let {name, age: years} = person; name + years }",
+			true, "let f=fn(a){let {name:b,age:c}=a;b+c}",
+		},
+		{
+			// Parameters that calls may name keep their names
+			"let f = fn(first, second) { first - second }; f(second: 1, first: 2)",
+			true, "let f=fn(first,second){first-second};f(second:1,first:2)",
+		},
+		{
+			// A module imported in a function may bind any name in it
+			"import \"std/list\"; let f = fn(xs) { import \"util\"; let total = 0; fn(n) { total + n } }",
+			true, "import \"std/list\";let f=fn(xs){import \"util\";let total=0;fn(a){total+a}}",
+		},
+		{
+			// Pattern names are bound in their arm; shorthand keys keep their name
+			"let f = fn(v) { match (v) { [first, ...rest] => { first } {name} => name, _ => v } }",
+			true, "let f=fn(a){match(a){[b,...d]=>{b}{name:c}=>c,_=>a}}",
+		},
+		{
+			// Fields and self are not bindings of the function, which keeps x as a field name
+			"let f = fn(x, self) { class Pair(x, y) { fn sum(other) { self.x + other.y } }; Pair(x, x).sum(self) }",
+			true, "let f=fn(a,self){class b(x,y){fn sum(c){self.x+c.y}};b(a,a).sum(self)}",
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := format.Minify(tt.input, tt.rename)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.input, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("%q: wrong output.\nexpected=%q\ngot=     %q", tt.input, tt.expected, got)
+		}
+	}
+
+	if _, err := format.Minify("let = 5;", false); err == nil {
+		t.Errorf("expected an error for invalid source")
+	}
+}
+
+func TestMinifyKeepsResults(t *testing.T) {
+	run := func(src string) string {
+		p := parser.New(lexer.New(src))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			return "parser errors: " + p.Errors()[0]
+		}
+		return evaluator.Eval(program, object.NewEnvironment()).Inspect()
+	}
+
+	for _, ex := range examples.All() {
+		expected := run(ex.Source)
+		for _, rename := range []bool{false, true} {
+			minified, err := format.Minify(ex.Source, rename)
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", ex.Name, err)
+			}
+			if got := run(minified); got != expected {
+				t.Errorf("%s (rename=%t): expected=%s, got=%s\n%s", ex.Name, rename, expected, got, minified)
+			}
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dr8co/monke/deps"
+)
+
+// runGet implements "monke get", which vendors packages into a project.
+func runGet(args []string) int {
+	fs := flag.NewFlagSet("get", flag.ExitOnError)
+	dir := fs.String("dir", ".", "Root directory of the project to vendor the packages into")
+	verify := fs.Bool("verify", false, "Check the vendored packages against "+deps.LockFile+" instead of fetching")
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "Usage: %s get [flags] path@version...\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(out, "       %s get -verify [-dir dir]\n\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(out, "Fetches each package, e.g. github.com/user/lib@v1, from its git repository and copies\n")
+		fmt.Fprintf(out, "its .monkey files into %s/<path>, recording the commit and checksum in %s.\n",
+			deps.VendorDir, deps.LockFile)
+		fmt.Fprintf(out, "Getting a locked version again fails if its commit or files changed.\n")
+		fmt.Fprintln(out, "\nFlags:")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if *verify {
+		if fs.NArg() != 0 {
+			fs.Usage()
+			return 2
+		}
+		if err := deps.Verify(*dir); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			return 1
+		}
+		fmt.Printf("Vendored packages match %s\n", deps.LockFile)
+		return 0
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	refs := make([]deps.Ref, fs.NArg())
+	for i, arg := range fs.Args() {
+		ref, err := deps.ParseRef(arg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			return 2
+		}
+		refs[i] = ref
+	}
+
+	status := 0
+	for _, ref := range refs {
+		pkg, err := deps.Get(*dir, ref, deps.GitFetch)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			status = 1
+			continue
+		}
+		fmt.Printf("Vendored %s (commit %.12s)\n", ref, pkg.Commit)
+	}
+	return status
+}
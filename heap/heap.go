@@ -0,0 +1,209 @@
+// Package heap takes snapshots of the object graph reachable from a Monke environment.
+//
+// A snapshot lists every object reachable from the root environment along
+// with an estimate of its size and the references between objects. It helps
+// find out what keeps large values alive, e.g. in a long REPL session.
+//
+// Key components:
+//   - Take: Walks the object graph and builds a Snapshot
+//   - Snapshot.WriteJSON: Writes the snapshot as JSON
+//   - Snapshot.WriteDOT: Writes the snapshot as a Graphviz DOT graph
+package heap
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/dr8co/monke/object"
+)
+
+// EnvironmentType is the node type used for environments, which are not objects themselves.
+const EnvironmentType = "ENVIRONMENT"
+
+// maxLabelLen bounds the length of the value preview stored in a node label.
+const maxLabelLen = 40
+
+// Node is a single object (or environment) in the graph.
+type Node struct {
+	ID    int    `json:"id"`
+	Type  string `json:"type"`
+	Size  int    `json:"size"`
+	Label string `json:"label"`
+}
+
+// Edge is a reference from one node to another.
+type Edge struct {
+	From  int    `json:"from"`
+	To    int    `json:"to"`
+	Label string `json:"label"`
+}
+
+// Snapshot is the object graph reachable from an environment.
+// The root environment is always node 0.
+type Snapshot struct {
+	Nodes []Node `json:"nodes"`
+	Edges []Edge `json:"edges"`
+}
+
+// TotalSize returns the sum of the sizes of all nodes in the snapshot.
+func (s *Snapshot) TotalSize() int {
+	total := 0
+	for _, n := range s.Nodes {
+		total += n.Size
+	}
+	return total
+}
+
+// walker builds a Snapshot, visiting each object once.
+type walker struct {
+	snap *Snapshot
+	ids  map[any]int
+}
+
+// Take returns a snapshot of the object graph reachable from env.
+func Take(env *object.Environment) *Snapshot {
+	w := &walker{snap: &Snapshot{}, ids: make(map[any]int)}
+	w.visitEnv(env, "global")
+	return w.snap
+}
+
+// add registers a node for key, returning its ID and whether it is new.
+func (w *walker) add(key any, typ string, size int, label string) (int, bool) {
+	if id, ok := w.ids[key]; ok {
+		return id, false
+	}
+	id := len(w.snap.Nodes)
+	w.ids[key] = id
+	w.snap.Nodes = append(w.snap.Nodes, Node{ID: id, Type: typ, Size: size, Label: label})
+	return id, true
+}
+
+func (w *walker) edge(from, to int, label string) {
+	w.snap.Edges = append(w.snap.Edges, Edge{From: from, To: to, Label: label})
+}
+
+func (w *walker) visitEnv(env *object.Environment, label string) int {
+	names := env.Names()
+	id, isNew := w.add(env, EnvironmentType, env.Size(), label)
+	if !isNew {
+		return id
+	}
+	for _, name := range names {
+		val, _ := env.Get(name)
+		w.edge(id, w.visit(val), name)
+	}
+	if outer := env.Outer(); outer != nil {
+		w.edge(id, w.visitEnv(outer, "closure"), "outer")
+	}
+	return id
+}
+
+func (w *walker) visit(obj object.Object) int {
+	id, isNew := w.add(obj, string(obj.Type()), object.Size(obj), label(obj))
+	if !isNew {
+		return id
+	}
+
+	switch obj := obj.(type) {
+	case *object.Array:
+		for i, el := range obj.Elements {
+			w.edge(id, w.visit(el), fmt.Sprintf("[%d]", i))
+		}
+	case *object.Hash:
+		pairs := make([]object.HashPair, 0, len(obj.Pairs))
+		for _, pair := range obj.Pairs {
+			pairs = append(pairs, pair)
+		}
+		slices.SortFunc(pairs, func(a, b object.HashPair) int {
+			return strings.Compare(a.Key.Inspect(), b.Key.Inspect())
+		})
+		for _, pair := range pairs {
+			key := truncate(pair.Key.Inspect())
+			w.edge(id, w.visit(pair.Key), "key "+key)
+			w.edge(id, w.visit(pair.Value), "["+key+"]")
+		}
+		if obj.Class != nil {
+			w.edge(id, w.visit(obj.Class), "class")
+		}
+	case *object.Function:
+		if obj.Env != nil {
+			w.edge(id, w.visitEnv(obj.Env, "closure"), "env")
+		}
+	case *object.Class:
+		names := slices.Sorted(maps.Keys(obj.Methods))
+		for _, name := range names {
+			w.edge(id, w.visit(obj.Methods[name]), "method "+name)
+		}
+	case *object.ReturnValue:
+		w.edge(id, w.visit(obj.Value), "value")
+	}
+	return id
+}
+
+// label returns a short preview of an object's value.
+func label(obj object.Object) string {
+	switch obj := obj.(type) {
+	case *object.Array:
+		return fmt.Sprintf("len=%d", len(obj.Elements))
+	case *object.Bytes:
+		return fmt.Sprintf("len=%d", len(obj.Value))
+	case *object.Hash:
+		if obj.Tag != "" {
+			return fmt.Sprintf("%s len=%d", obj.Tag, len(obj.Pairs))
+		}
+		return fmt.Sprintf("len=%d", len(obj.Pairs))
+	case *object.Function:
+		if obj.Name != "" {
+			return fmt.Sprintf("fn %s/%d", obj.Name, len(obj.Parameters))
+		}
+		return fmt.Sprintf("fn/%d", len(obj.Parameters))
+	case *object.ReturnValue:
+		return ""
+	default:
+		return truncate(obj.Inspect())
+	}
+}
+
+func truncate(s string) string {
+	if len(s) <= maxLabelLen {
+		return s
+	}
+	return s[:maxLabelLen-3] + "..."
+}
+
+// WriteJSON writes the snapshot to w as an indented JSON document.
+func (s *Snapshot) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// WriteDOT writes the snapshot to w as a Graphviz digraph.
+func (s *Snapshot) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph heap {\n")
+	b.WriteString("\tnode [shape=box];\n")
+	for _, n := range s.Nodes {
+		text := fmt.Sprintf("%s\n%d B", n.Type, n.Size)
+		if n.Label != "" {
+			text = fmt.Sprintf("%s\n%s\n%d B", n.Type, n.Label, n.Size)
+		}
+		fmt.Fprintf(&b, "\tn%d [label=%s];\n", n.ID, quoteDOT(text))
+	}
+	for _, e := range s.Edges {
+		fmt.Fprintf(&b, "\tn%d -> n%d [label=%s];\n", e.From, e.To, quoteDOT(e.Label))
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// quoteDOT returns s as a quoted DOT string.
+func quoteDOT(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + r.Replace(s) + `"`
+}
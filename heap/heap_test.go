@@ -0,0 +1,112 @@
+package heap
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/dr8co/monke/evaluator"
+	"github.com/dr8co/monke/lexer"
+	"github.com/dr8co/monke/object"
+	"github.com/dr8co/monke/parser"
+)
+
+func snapshotOf(t *testing.T, input string) *Snapshot {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	env := object.NewEnvironment()
+	evaluator.Eval(program, env)
+	return Take(env)
+}
+
+// edgesFrom returns the labels of the edges leaving node id, mapped to their targets.
+func edgesFrom(s *Snapshot, id int) map[string]Node {
+	out := make(map[string]Node)
+	for _, e := range s.Edges {
+		if e.From == id {
+			out[e.Label] = s.Nodes[e.To]
+		}
+	}
+	return out
+}
+
+func TestTake(t *testing.T) {
+	snap := snapshotOf(t, `
+let big = [1, 2, 3];
+let alias = big;
+let make = fn(x) { fn() { x } };
+let keep = make(big);
+`)
+
+	root := snap.Nodes[0]
+	if root.Type != EnvironmentType || root.Label != "global" {
+		t.Fatalf("root node wrong. got=%+v", root)
+	}
+
+	globals := edgesFrom(snap, 0)
+	for _, name := range []string{"big", "alias", "make", "keep"} {
+		if _, ok := globals[name]; !ok {
+			t.Errorf("missing edge from global environment to %q", name)
+		}
+	}
+	if globals["big"].ID != globals["alias"].ID {
+		t.Errorf("aliased array should be a single node, got %d and %d", globals["big"].ID, globals["alias"].ID)
+	}
+	if got := globals["big"].Label; got != "len=3" {
+		t.Errorf("array label wrong. want=%q, got=%q", "len=3", got)
+	}
+
+	// keep -> closure env -> x is the same array as big.
+	closure, ok := edgesFrom(snap, globals["keep"].ID)["env"]
+	if !ok || closure.Type != EnvironmentType {
+		t.Fatalf("closure environment missing, got %+v", closure)
+	}
+	captured := edgesFrom(snap, closure.ID)
+	if captured["x"].ID != globals["big"].ID {
+		t.Errorf("closure should capture the array bound to big")
+	}
+	if captured["outer"].ID != 0 {
+		t.Errorf("closure environment should point back at the global environment")
+	}
+
+	if snap.TotalSize() <= 0 {
+		t.Errorf("expected a positive total size, got %d", snap.TotalSize())
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	snap := snapshotOf(t, `let h = {"a": [1]};`)
+
+	var buf bytes.Buffer
+	if err := snap.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	var decoded Snapshot
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(decoded.Nodes) != len(snap.Nodes) || len(decoded.Edges) != len(snap.Edges) {
+		t.Errorf("decoded snapshot differs. want %d nodes and %d edges, got %d and %d",
+			len(snap.Nodes), len(snap.Edges), len(decoded.Nodes), len(decoded.Edges))
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	snap := snapshotOf(t, `let s = "C:\dir";`)
+
+	var buf bytes.Buffer
+	if err := snap.WriteDOT(&buf); err != nil {
+		t.Fatalf("WriteDOT: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"digraph heap {", `n0 -> n1 [label="s"];`, `C:\\dir`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("DOT output missing %q:\n%s", want, out)
+		}
+	}
+}
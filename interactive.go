@@ -0,0 +1,46 @@
+//go:build !monke_minimal
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/dr8co/monke/object"
+	"github.com/dr8co/monke/parser"
+	"github.com/dr8co/monke/repl"
+)
+
+// startREPL starts the REPL in env, appending its events to the
+// file logPath if one is given. Builds with the monke_minimal tag, which leave
+// out the REPL and its terminal UI, use the version in minimal.go instead.
+func startREPL(env *object.Environment, noColor, debug bool, maxMemory int, logPath string) {
+	options := repl.Options{
+		NoColor: noColor,
+		Debug:   debug,
+		Env:     env,
+
+		MaxMemory: maxMemory,
+	}
+	if logPath != "" {
+		//nolint:gosec // The path is supplied by the user on purpose
+		f, err := os.OpenFile(logPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening REPL log: %s\n", err)
+			os.Exit(1)
+		}
+		defer func() { _ = f.Close() }()
+		options.Log = f
+	}
+	repl.Start(options)
+}
+
+// formatParseErrors formats the syntax errors of source as the REPL shows them.
+func formatParseErrors(source string, errors []parser.Error, noColor bool) string {
+	return repl.FormatParseErrors(source, errors, noColor)
+}
+
+// formatRuntimeError formats a runtime error raised by source as the REPL shows it.
+func formatRuntimeError(source string, err *object.Error, noColor bool) string {
+	return repl.FormatRuntimeError(source, err, noColor)
+}
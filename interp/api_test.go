@@ -0,0 +1,167 @@
+package interp
+
+import (
+	"bytes"
+	"flag"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "write the stable API to testdata/api.txt instead of comparing it")
+
+// stablePackages are the directories of the packages whose exported API is
+// covered by the compatibility promise of docs/api.md.
+var stablePackages = []string{"interp", "ast", "lexer", "modules", "object", "parser", "token"}
+
+// TestAPI compares the exported API of the stable packages with the one
+// recorded in testdata/api.txt. Additions are recorded with -update; removing
+// or changing a line of the file breaks the programs using it.
+func TestAPI(t *testing.T) {
+	var lines []string
+	for _, dir := range stablePackages {
+		lines = append(lines, packageAPI(t, filepath.Join("..", dir))...)
+	}
+	got := strings.Join(lines, "\n") + "\n"
+
+	file := filepath.Join("testdata", "api.txt")
+	if *update {
+		if err := os.WriteFile(file, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	recorded, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Split(strings.TrimSuffix(string(recorded), "\n"), "\n")
+	for _, line := range want {
+		if !slices.Contains(lines, line) {
+			t.Errorf("removed from the stable API: %s", line)
+		}
+	}
+	for _, line := range lines {
+		if !slices.Contains(want, line) {
+			t.Errorf("added to the stable API, record it with -update: %s", line)
+		}
+	}
+}
+
+// packageAPI returns the exported declarations of the package in dir, one
+// per line and sorted, leaving out its tests.
+func packageAPI(t *testing.T, dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".go") || strings.HasSuffix(entry.Name(), "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, filepath.Join(dir, entry.Name()), nil, parser.SkipObjectResolution)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file)
+	}
+	name := filepath.Base(dir)
+
+	expr := func(e ast.Expr) string {
+		var b bytes.Buffer
+		if err := printer.Fprint(&b, fset, e); err != nil {
+			t.Fatal(err)
+		}
+		return b.String()
+	}
+	var lines []string
+	add := func(format string, a ...string) {
+		lines = append(lines, "pkg "+name+", "+strings.ReplaceAll(strings.Join(append([]string{format}, a...), " "), "\n", " "))
+	}
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if !decl.Name.IsExported() {
+					continue
+				}
+				signature := strings.TrimPrefix(expr(decl.Type), "func")
+				if decl.Recv == nil {
+					add("func", decl.Name.Name+signature)
+					continue
+				}
+				recv := expr(decl.Recv.List[0].Type)
+				if !ast.IsExported(strings.TrimPrefix(recv, "*")) {
+					continue
+				}
+				add("method", "("+recv+")", decl.Name.Name+signature)
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						if spec.Name.IsExported() {
+							typeAPI(spec, expr, add)
+						}
+					case *ast.ValueSpec:
+						for _, n := range spec.Names {
+							if !n.IsExported() {
+								continue
+							}
+							if spec.Type != nil {
+								add(decl.Tok.String(), n.Name, expr(spec.Type))
+							} else {
+								add(decl.Tok.String(), n.Name)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	slices.Sort(lines)
+	return lines
+}
+
+// typeAPI adds the lines of the exported type spec: the type, and the
+// exported fields of a struct or the methods of an interface.
+func typeAPI(spec *ast.TypeSpec, expr func(ast.Expr) string, add func(string, ...string)) {
+	name := spec.Name.Name
+	switch typ := spec.Type.(type) {
+	case *ast.StructType:
+		add("type", name, "struct")
+		for _, field := range typ.Fields.List {
+			if len(field.Names) == 0 {
+				add("type", name, "struct, embedded", expr(field.Type))
+			}
+			for _, n := range field.Names {
+				if n.IsExported() {
+					add("type", name, "struct,", n.Name, expr(field.Type))
+				}
+			}
+		}
+	case *ast.InterfaceType:
+		add("type", name, "interface")
+		for _, method := range typ.Methods.List {
+			for _, n := range method.Names {
+				add("type", name, "interface,", n.Name+strings.TrimPrefix(expr(method.Type), "func"))
+			}
+			if len(method.Names) == 0 {
+				add("type", name, "interface, embedded", expr(method.Type))
+			}
+		}
+	default:
+		assign := ""
+		if spec.Assign.IsValid() {
+			assign = "= "
+		}
+		add("type", name, assign+expr(spec.Type))
+	}
+}
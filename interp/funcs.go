@@ -0,0 +1,430 @@
+package interp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+
+	"github.com/dr8co/monke/evaluator"
+	"github.com/dr8co/monke/object"
+)
+
+var (
+	objectType = reflect.TypeFor[object.Object]()
+	errorType  = reflect.TypeFor[error]()
+)
+
+// RegisterFunc binds name in the global environment to a builtin that calls
+// fn, a Go function. The arguments of a call are converted to the types of
+// the parameters of fn, and its result back to a Monke value:
+//   - booleans, integers, floats and strings to and from bool, the integer and
+//     floating-point types and string; an integer is also accepted for a float
+//   - arrays to and from slices, and hashes to and from maps with string keys
+//   - functions to func types, which fn may call while the program runs; a
+//     callback that fails makes the call fail, or returns the error if the
+//     func type has an error result
+//   - any value to and from object.Object as is, and to any as int64,
+//     float64, bool, string, nil, []any, map[string]any, or the object itself
+//
+// fn may return nothing, a value, an error, or a value and an error. A non-nil
+// error makes the call fail with its message. RegisterFunc returns an error
+// if fn is not a function, or if it has parameters or results of other types.
+func (in *Interpreter) RegisterFunc(name string, fn any) error {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func {
+		return fmt.Errorf("interp: RegisterFunc(%q): %T is not a function", name, fn)
+	}
+	t := v.Type()
+	for i := range t.NumIn() {
+		if !convertible(t.In(i), true) {
+			return fmt.Errorf("interp: RegisterFunc(%q): unsupported parameter type %s", name, t.In(i))
+		}
+	}
+	if err := checkResults(t); err != nil {
+		return fmt.Errorf("interp: RegisterFunc(%q): %w", name, err)
+	}
+
+	in.env.Set(name, &object.Builtin{Fn: func(args ...object.Object) (result object.Object) {
+		// A callback without an error result fails the whole call
+		defer func() {
+			if r := recover(); r != nil {
+				failure, ok := r.(callbackFailure)
+				if !ok {
+					panic(r)
+				}
+				result = failure.err
+			}
+		}()
+		return callGo(name, v, args)
+	}})
+	return nil
+}
+
+// Call calls fn, a function of a program or a builtin, with args, converted
+// to Monke values as the results of a function registered with RegisterFunc
+// are, and returns its result. A function that fails returns a *RuntimeError.
+// Like Run, it applies the options of the interpreter, so the limits count
+// the steps of the call alone. It must not be called from a function the
+// program is running, such as one registered with RegisterFunc, which gets
+// its callbacks as Go funcs instead.
+func (in *Interpreter) Call(fn object.Object, args ...any) (object.Object, error) {
+	objs := make([]object.Object, len(args))
+	for i, arg := range args {
+		obj, ok := toObject(reflect.ValueOf(arg))
+		if !ok {
+			return nil, fmt.Errorf("interp: cannot convert argument %d of type %T", i+1, arg)
+		}
+		objs[i] = obj
+	}
+
+	running.Lock()
+	defer running.Unlock()
+	in.configure()
+	result := evaluator.Call(fn, objs...)
+	if err, ok := result.(*object.Error); ok {
+		return nil, newRuntimeError("", err, err.Cause)
+	}
+	object.Flatten(result)
+	return result, nil
+}
+
+// callbackFailure is the panic that unwinds a call of a registered function
+// when a callback without an error result fails.
+type callbackFailure struct {
+	err *object.Error
+}
+
+// callGo calls fn, registered as name, with args and returns its result.
+func callGo(name string, fn reflect.Value, args []object.Object) object.Object {
+	t := fn.Type()
+	fixed := t.NumIn()
+	if t.IsVariadic() {
+		fixed--
+		if len(args) < fixed {
+			return &object.Error{Message: fmt.Sprintf("wrong number of arguments. got=%d, want at least %d", len(args), fixed)}
+		}
+	} else if len(args) != fixed {
+		return &object.Error{Message: fmt.Sprintf("wrong number of arguments. got=%d, want=%d", len(args), fixed)}
+	}
+
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		pt := t.In(min(i, t.NumIn()-1))
+		if t.IsVariadic() && i >= fixed {
+			pt = pt.Elem()
+		}
+		v, ok := fromObject(arg, pt)
+		if !ok {
+			return &object.Error{Message: fmt.Sprintf("argument %d to `%s` must be %s, got %s", i+1, name, pt, arg.Type())}
+		}
+		in[i] = v
+	}
+
+	out := fn.Call(in)
+	if n := len(out); n > 0 && t.Out(n-1) == errorType {
+		if err, _ := out[n-1].Interface().(error); err != nil {
+			return errorObject(err)
+		}
+		out = out[:n-1]
+	}
+	if len(out) == 0 {
+		return evaluator.NULL
+	}
+	result, ok := toObject(out[0])
+	if !ok {
+		return &object.Error{Message: fmt.Sprintf("cannot convert the result of `%s`, of type %s", name, out[0].Type())}
+	}
+	return result
+}
+
+// errorObject returns the Monke error for err, returned by a registered
+// function. The error of a failed callback is passed on as it was raised.
+func errorObject(err error) *object.Error {
+	var runtimeErr *RuntimeError
+	if errors.As(err, &runtimeErr) && err == error(runtimeErr) {
+		return &object.Error{Message: runtimeErr.Message, Pos: runtimeErr.Pos, Cause: runtimeErr.Cause}
+	}
+	return &object.Error{Message: err.Error(), Cause: err}
+}
+
+// checkResults returns an error unless the results of the func type t are
+// nothing, a value, an error, or a value and an error.
+func checkResults(t reflect.Type) error {
+	n := t.NumOut()
+	if n > 0 && t.Out(n-1) == errorType {
+		n--
+	}
+	switch {
+	case n > 1 || (n == 1 && t.NumOut() == 2 && t.Out(0) == errorType):
+		return fmt.Errorf("%s must return at most a value and an error", t)
+	case n == 1 && !convertible(t.Out(0), false):
+		return fmt.Errorf("unsupported result type %s", t.Out(0))
+	}
+	return nil
+}
+
+// convertible reports whether values of t can be converted from Monke
+// values, for a parameter, or to them, for a result.
+func convertible(t reflect.Type, param bool) bool {
+	if t == objectType || (t.Kind() == reflect.Interface && t.NumMethod() == 0) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	case reflect.Slice:
+		return convertible(t.Elem(), param)
+	case reflect.Map:
+		return t.Key().Kind() == reflect.String && convertible(t.Elem(), param)
+	case reflect.Func:
+		if !param {
+			return false
+		}
+		for i := range t.NumIn() {
+			if !convertible(t.In(i), false) {
+				return false
+			}
+		}
+		return checkResults(t) == nil
+	}
+	return t.Implements(objectType) && !param
+}
+
+// fromObject converts obj to a Go value of type t, reporting whether it could.
+func fromObject(obj object.Object, t reflect.Type) (reflect.Value, bool) {
+	if t == objectType {
+		object.Flatten(obj)
+		v := reflect.New(t).Elem()
+		v.Set(reflect.ValueOf(obj))
+		return v, true
+	}
+	v := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.Interface:
+		if t.NumMethod() != 0 {
+			return v, false
+		}
+		if natural := naturalValue(obj); natural != nil {
+			v.Set(reflect.ValueOf(natural))
+		}
+		return v, true
+	case reflect.Bool:
+		b, ok := obj.(*object.Boolean)
+		if ok {
+			v.SetBool(b.Value)
+		}
+		return v, ok
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ok := obj.(*object.Integer)
+		if !ok || v.OverflowInt(i.Value) {
+			return v, false
+		}
+		v.SetInt(i.Value)
+		return v, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, ok := obj.(*object.Integer)
+		if !ok || i.Value < 0 || v.OverflowUint(uint64(i.Value)) {
+			return v, false
+		}
+		v.SetUint(uint64(i.Value))
+		return v, true
+	case reflect.Float32, reflect.Float64:
+		switch n := obj.(type) {
+		case *object.Float:
+			v.SetFloat(n.Value)
+		case *object.Integer:
+			v.SetFloat(float64(n.Value))
+		default:
+			return v, false
+		}
+		return v, true
+	case reflect.String:
+		s, ok := obj.(*object.String)
+		if ok {
+			v.SetString(s.Flat())
+		}
+		return v, ok
+	case reflect.Slice:
+		if b, ok := obj.(*object.Bytes); ok && t.Elem().Kind() == reflect.Uint8 {
+			v.Set(reflect.ValueOf(slices.Clone(b.Value)).Convert(t))
+			return v, true
+		}
+		array, ok := obj.(*object.Array)
+		if !ok {
+			return v, false
+		}
+		v.Set(reflect.MakeSlice(t, len(array.Elements), len(array.Elements)))
+		for i, el := range array.Elements {
+			ev, ok := fromObject(el, t.Elem())
+			if !ok {
+				return v, false
+			}
+			v.Index(i).Set(ev)
+		}
+		return v, true
+	case reflect.Map:
+		hash, ok := obj.(*object.Hash)
+		if !ok {
+			return v, false
+		}
+		v.Set(reflect.MakeMapWithSize(t, len(hash.Pairs)))
+		for _, pair := range hash.Pairs {
+			key, ok := pair.Key.(*object.String)
+			if !ok {
+				return v, false
+			}
+			ev, ok := fromObject(pair.Value, t.Elem())
+			if !ok {
+				return v, false
+			}
+			v.SetMapIndex(reflect.ValueOf(key.Flat()).Convert(t.Key()), ev)
+		}
+		return v, true
+	case reflect.Func:
+		switch obj.(type) {
+		case *object.Function, *object.Builtin:
+			return reflect.MakeFunc(t, callback(obj, t)), true
+		}
+	}
+	return v, false
+}
+
+// callback returns the implementation of a Go func of type t that calls fn,
+// a function of the running program.
+func callback(fn object.Object, t reflect.Type) func(args []reflect.Value) []reflect.Value {
+	hasErr := t.NumOut() > 0 && t.Out(t.NumOut()-1) == errorType
+	return func(args []reflect.Value) []reflect.Value {
+		objs := make([]object.Object, len(args))
+		for i, arg := range args {
+			objs[i], _ = toObject(arg)
+		}
+		result := evaluator.Call(fn, objs...)
+
+		out := make([]reflect.Value, t.NumOut())
+		for i := range out {
+			out[i] = reflect.New(t.Out(i)).Elem()
+		}
+		fail := func(err *object.Error) []reflect.Value {
+			if !hasErr {
+				panic(callbackFailure{err})
+			}
+			out[len(out)-1].Set(reflect.ValueOf(newRuntimeError("", err, err.Cause)))
+			return out
+		}
+		if err, ok := result.(*object.Error); ok {
+			return fail(err)
+		}
+		if len(out) > 0 && t.Out(0) != errorType {
+			v, ok := fromObject(result, t.Out(0))
+			if !ok {
+				return fail(&object.Error{Message: fmt.Sprintf("callback returned %s, want %s", result.Type(), t.Out(0))})
+			}
+			out[0] = v
+		}
+		return out
+	}
+}
+
+// naturalValue returns the Go value closest to obj, for a parameter of type any.
+func naturalValue(obj object.Object) any {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return obj.Value
+	case *object.Float:
+		return obj.Value
+	case *object.Boolean:
+		return obj.Value
+	case *object.String:
+		return obj.Flat()
+	case *object.Bytes:
+		return slices.Clone(obj.Value)
+	case *object.Null:
+		return nil
+	case *object.Array:
+		values := make([]any, len(obj.Elements))
+		for i, el := range obj.Elements {
+			values[i] = naturalValue(el)
+		}
+		return values
+	case *object.Hash:
+		values := make(map[string]any, len(obj.Pairs))
+		for _, pair := range obj.Pairs {
+			key, ok := pair.Key.(*object.String)
+			if !ok {
+				return obj
+			}
+			values[key.Flat()] = naturalValue(pair.Value)
+		}
+		return values
+	}
+	return obj
+}
+
+// toObject converts the Go value v to a Monke value, reporting whether it could.
+func toObject(v reflect.Value) (object.Object, bool) {
+	if !v.IsValid() {
+		return evaluator.NULL, true
+	}
+	if v.Kind() == reflect.Interface || (v.Kind() == reflect.Pointer && v.Type().Implements(objectType)) {
+		if v.IsNil() {
+			return evaluator.NULL, true
+		}
+	}
+	if v.Kind() == reflect.Interface {
+		return toObject(v.Elem())
+	}
+	if obj, ok := v.Interface().(object.Object); ok {
+		return obj, true
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return evaluator.TRUE, true
+		}
+		return evaluator.FALSE, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &object.Integer{Value: v.Int()}, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v.Uint() > math.MaxInt64 {
+			return nil, false
+		}
+		return &object.Integer{Value: int64(v.Uint())}, true
+	case reflect.Float32, reflect.Float64:
+		return &object.Float{Value: v.Float()}, true
+	case reflect.String:
+		return &object.String{Value: v.String()}, true
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			return &object.Bytes{Value: slices.Clone(v.Bytes())}, true
+		}
+		elements := make([]object.Object, v.Len())
+		for i := range elements {
+			el, ok := toObject(v.Index(i))
+			if !ok {
+				return nil, false
+			}
+			elements[i] = el
+		}
+		return &object.Array{Elements: elements}, true
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		hash := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair, v.Len())}
+		for iter := v.MapRange(); iter.Next(); {
+			key := &object.String{Value: iter.Key().String()}
+			value, ok := toObject(iter.Value())
+			if !ok {
+				return nil, false
+			}
+			hash.Pairs[key.HashKey()] = object.HashPair{Key: key, Value: value}
+		}
+		return hash, true
+	}
+	return nil, false
+}
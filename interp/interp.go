@@ -0,0 +1,225 @@
+// Package interp embeds the Monke interpreter in Go programs.
+//
+// It is the stable entry point for embedders: an Interpreter runs source in a
+// global environment of its own, with the settings of its Options, and
+// reports syntax and runtime errors as Go errors. The API is covered by the
+// compatibility promise of docs/api.md, along with the values of package
+// object and the syntax tree of package ast it hands out.
+//
+//	in := interp.New(interp.Options{Output: &buf, StepLimit: 1_000_000})
+//	in.Define("name", &object.String{Value: "monke"})
+//	value, err := in.Run(`"hello, " + name`)
+//
+// The evaluator keeps its settings in package variables, so Run applies the
+// options of its interpreter before each run, and runs of all interpreters
+// take turns: an Interpreter may be used from any goroutine, but only one
+// program runs at a time.
+//
+// Key components:
+//   - Interpreter: Runs programs in an environment that persists between runs
+//   - Options: The language mode, output, imports and limits of the runs
+//   - SyntaxError, RuntimeError: The errors of programs that fail
+package interp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/dr8co/monke/evaluator"
+	"github.com/dr8co/monke/modules"
+	"github.com/dr8co/monke/object"
+	"github.com/dr8co/monke/parser"
+	"github.com/dr8co/monke/pipeline"
+	"github.com/dr8co/monke/token"
+)
+
+// Options configures an Interpreter. The zero value runs legacy-mode programs
+// without limits, printing to os.Stdout and importing only the standard library.
+type Options struct {
+	Strict      bool                                     // Run in strict mode, as "#pragma strict" does
+	Output      io.Writer                                // Where `puts` prints; nil for os.Stdout
+	Args        []string                                 // What the `args` builtin returns
+	Modules     modules.Resolver                         // Finds imported modules; nil for modules.Stdlib
+	Warnings    func(pos token.Position, message string) // Receives runtime warnings; nil discards them
+	StepLimit   int                                      // Statements and loop iterations per run; 0 for no limit
+	DepthLimit  int                                      // Nested function calls; 0 for no limit
+	MemoryLimit int                                      // Estimated bytes of live objects; 0 for no limit
+}
+
+// Interpreter runs Monke programs. The names a program binds at the top level
+// stay bound for the programs run after it.
+type Interpreter struct {
+	opts Options
+	env  *object.Environment
+}
+
+// running serializes the runs of all interpreters, which share the settings
+// of the evaluator.
+var running sync.Mutex
+
+// New returns an Interpreter with opts and an empty global environment.
+func New(opts Options) *Interpreter {
+	if opts.Modules == nil {
+		opts.Modules = modules.Stdlib
+	}
+	env := object.NewEnvironment()
+	env.SetStrict(opts.Strict)
+	return &Interpreter{opts: opts, env: env}
+}
+
+// Run lexes, parses and evaluates src. It returns the value of the program,
+// or nil if it has none, e.g. when it ends with a let statement. A program
+// with syntax errors fails with a *SyntaxError before it runs; one that fails
+// at runtime, with a *RuntimeError.
+func (in *Interpreter) Run(src string) (object.Object, error) {
+	return in.RunContext(context.Background(), src)
+}
+
+// RunContext runs src as Run does, interrupting the program once ctx is done.
+// The program stops at its next step, running its deferred blocks, and fails
+// with a *RuntimeError that unwraps to both ErrInterrupted and ctx.Err().
+func (in *Interpreter) RunContext(ctx context.Context, src string) (object.Object, error) {
+	running.Lock()
+	defer running.Unlock()
+	in.configure()
+
+	if ctx.Done() != nil {
+		done := make(chan struct{})
+		var wg sync.WaitGroup
+		wg.Go(func() {
+			select {
+			case <-ctx.Done():
+				evaluator.Interrupt()
+			case <-done:
+			}
+		})
+		// The watcher must be gone before the next run clears its interrupt
+		defer wg.Wait()
+		defer close(done)
+	}
+
+	result := pipeline.Run(src, in.env)
+	if len(result.Errors) != 0 {
+		return nil, &SyntaxError{Source: src, Errors: result.Errors}
+	}
+	if err := result.Error(); err != nil {
+		cause := err.Cause
+		if errors.Is(cause, ErrInterrupted) && ctx.Err() != nil {
+			cause = errors.Join(cause, ctx.Err())
+		}
+		return nil, newRuntimeError(src, err, cause)
+	}
+	object.Flatten(result.Value)
+	return result.Value, nil
+}
+
+// configure applies the options of the interpreter to the evaluator.
+func (in *Interpreter) configure() {
+	evaluator.Configure(evaluator.Options{
+		Output:      in.opts.Output,
+		Args:        in.opts.Args,
+		Modules:     in.opts.Modules,
+		Warnings:    in.opts.Warnings,
+		StepLimit:   in.opts.StepLimit,
+		DepthLimit:  in.opts.DepthLimit,
+		MemoryLimit: in.opts.MemoryLimit,
+	})
+}
+
+// Define binds name to value in the global environment, e.g. to give programs
+// a builtin of the host as an *object.Builtin.
+func (in *Interpreter) Define(name string, value object.Object) {
+	in.env.Set(name, value)
+}
+
+// Lookup returns the value bound to name in the global environment.
+func (in *Interpreter) Lookup(name string) (object.Object, bool) {
+	value, ok := in.env.Get(name)
+	object.Flatten(value)
+	return value, ok
+}
+
+// Env returns the global environment of the interpreter.
+func (in *Interpreter) Env() *object.Environment {
+	return in.env
+}
+
+// The causes of the runtime errors of programs stopped by the limits of
+// Options or by an interrupt, for errors.Is.
+var (
+	ErrInterrupted = evaluator.ErrInterrupted
+	ErrStepLimit   = evaluator.ErrStepLimit
+	ErrDepthLimit  = evaluator.ErrDepthLimit
+	ErrMemoryLimit = evaluator.ErrMemoryLimit
+)
+
+// SyntaxError is the error of a program that does not parse. errors.As finds
+// each of its errors as a parser.Error.
+type SyntaxError struct {
+	Source string
+	Errors []parser.Error // In the order they were found; there is at least one
+}
+
+// Error returns the first syntax error and its position, and how many more there are.
+func (e *SyntaxError) Error() string {
+	return (&parser.ErrorList{Errors: e.Errors}).Error()
+}
+
+// Unwrap returns the syntax errors.
+func (e *SyntaxError) Unwrap() []error {
+	return (&parser.ErrorList{Errors: e.Errors}).Unwrap()
+}
+
+// Format formats the errors as the command line reports them, each pointing at the offending code.
+func (e *SyntaxError) Format() string {
+	return pipeline.FormatParseErrors(e.Source, e.Errors)
+}
+
+// RuntimeError is the error of a program that fails while it runs. It
+// unwraps to its cause, e.g. ErrStepLimit or modules.ErrNotFound. It wraps
+// the *evaluator.RuntimeError the evaluator raised, which errors.As finds too,
+// so hosts that use the evaluator directly handle both the same way.
+type RuntimeError struct {
+	Source  string
+	Pos     token.Position // The innermost statement that raised the error, if known
+	Message string
+	Cause   error // The Go error behind the error, or nil
+}
+
+// Error returns the message of the error, with its position if it is known.
+func (e *RuntimeError) Error() string {
+	return (&evaluator.RuntimeError{Err: e.object()}).Error()
+}
+
+// Unwrap returns the cause of the error, or nil.
+func (e *RuntimeError) Unwrap() error {
+	return e.Cause
+}
+
+// Format formats the error as the command line reports it, pointing at the offending code.
+func (e *RuntimeError) Format() string {
+	return pipeline.FormatRuntimeError(e.Source, e.object())
+}
+
+// As sets target to the error as the evaluator raised it, if target is an
+// **evaluator.RuntimeError, for errors.As.
+func (e *RuntimeError) As(target any) bool {
+	t, ok := target.(**evaluator.RuntimeError)
+	if ok {
+		*t = &evaluator.RuntimeError{Err: e.object()}
+	}
+	return ok
+}
+
+// object returns the error as a Monke error.
+func (e *RuntimeError) object() *object.Error {
+	return &object.Error{Message: e.Message, Pos: e.Pos, Cause: e.Cause}
+}
+
+// newRuntimeError returns the RuntimeError of err, raised by src, which
+// unwraps to cause.
+func newRuntimeError(src string, err *object.Error, cause error) *RuntimeError {
+	return &RuntimeError{Source: src, Pos: err.Pos, Message: err.Message, Cause: cause}
+}
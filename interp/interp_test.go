@@ -0,0 +1,320 @@
+package interp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dr8co/monke/evaluator"
+	"github.com/dr8co/monke/modules"
+	"github.com/dr8co/monke/object"
+	"github.com/dr8co/monke/parser"
+	"github.com/dr8co/monke/token"
+)
+
+func TestRun(t *testing.T) {
+	var out strings.Builder
+	in := New(Options{Output: &out, Args: []string{"-v"}})
+	in.Define("greet", &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		return &object.String{Value: "hello, " + args[0].Inspect()}
+	}})
+
+	if value, err := in.Run(`puts(args()[0]); let name = "monke";`); err != nil || value != nil {
+		t.Fatalf("Run wrong. expected no value and no error, got=%v, %v", value, err)
+	}
+	value, err := in.Run(`greet(name)`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value.Inspect() != "hello, monke" {
+		t.Errorf("value wrong. expected=%q, got=%q", "hello, monke", value.Inspect())
+	}
+	if got := out.String(); got != "-v\n" {
+		t.Errorf("output wrong. expected=%q, got=%q", "-v\n", got)
+	}
+	if name, ok := in.Lookup("name"); !ok || name.Inspect() != "monke" {
+		t.Errorf("Lookup wrong. got=%v, %t", name, ok)
+	}
+	if _, err := in.Run(`import "std/list"; map([1], fn(x) { x })`); err != nil {
+		t.Errorf("the standard library is not importable by default: %v", err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	in := New(Options{})
+	_, err := in.Run("let = 5;")
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("expected a *SyntaxError, got=%T (%v)", err, err)
+	}
+	if expected := "1:5: Expected next token to be IDENT, got = instead (and 1 more)"; err.Error() != expected {
+		t.Errorf("Error wrong. expected=%q, got=%q", expected, err.Error())
+	}
+	if !strings.HasPrefix(syntaxErr.Format(), "Parser Errors:\n") {
+		t.Errorf("Format wrong. got=%q", syntaxErr.Format())
+	}
+
+	_, err = in.Run("let x = 1;\nx + true")
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		t.Fatalf("expected a *RuntimeError, got=%T (%v)", err, err)
+	}
+	if runtimeErr.Pos != (token.Position{Line: 2, Column: 1}) {
+		t.Errorf("position wrong. got=%s", runtimeErr.Pos)
+	}
+	if expected := "2:1: type mismatch: INTEGER + BOOLEAN"; err.Error() != expected {
+		t.Errorf("Error wrong. expected=%q, got=%q", expected, err.Error())
+	}
+	if errors.Unwrap(err) != nil {
+		t.Errorf("expected no cause, got=%v", errors.Unwrap(err))
+	}
+	// Hosts that handle the errors of the evaluator find the same error
+	var evalErr *evaluator.RuntimeError
+	if !errors.As(err, &evalErr) || evalErr.Error() != err.Error() {
+		t.Errorf("errors.As does not find the *evaluator.RuntimeError. got=%v", evalErr)
+	}
+
+	// The errors of the parser, the limits and the resolvers stay visible to errors.Is and errors.As
+	var first parser.Error
+	if _, err := in.Run("let = 5;"); !errors.As(err, &first) || first.Pos != (token.Position{Line: 1, Column: 5}) {
+		t.Errorf("errors.As does not find the first parser.Error. got=%v", first)
+	}
+	_, err = New(Options{StepLimit: 10}).Run("while (true) {}")
+	if !errors.Is(err, ErrStepLimit) {
+		t.Errorf("expected ErrStepLimit, got=%v", err)
+	}
+	if !errors.As(err, &evalErr) || !errors.Is(evalErr, ErrStepLimit) {
+		t.Errorf("expected an *evaluator.RuntimeError caused by ErrStepLimit, got=%v", evalErr)
+	}
+	if _, err := in.Run(`import "std/missing";`); !errors.Is(err, modules.ErrNotFound) {
+		t.Errorf("expected modules.ErrNotFound, got=%v", err)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	strict := New(Options{Strict: true})
+	if _, err := strict.Run("let x = 1; let x = 2;"); err == nil || err.Error() != "1:12: identifier already declared: x" {
+		t.Errorf("expected a strict-mode error, got=%v", err)
+	}
+
+	limited := New(Options{StepLimit: 100})
+	if _, err := limited.Run("while (true) {}"); err == nil || !strings.Contains(err.Error(), "step limit exceeded") {
+		t.Errorf("expected the step limit to stop the loop, got=%v", err)
+	}
+	// The limits of one interpreter do not leak into the runs of another
+	if _, err := New(Options{}).Run("let i = 0; while (i < 1000) { i = i + 1 }"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	custom := New(Options{Modules: modules.Memory{"util": "let double = fn(x) { x * 2 };"}})
+	if value, err := custom.Run(`import "util"; double(21)`); err != nil || value.Inspect() != "42" {
+		t.Errorf("import from the resolver wrong. got=%v, %v", value, err)
+	}
+
+	var warnings []string
+	warned := New(Options{Strict: true, Warnings: func(pos token.Position, message string) {
+		warnings = append(warnings, pos.String()+": "+message)
+	}})
+	if _, err := warned.Run("if (1) { 2 }"); err != nil || len(warnings) != 1 {
+		t.Errorf("expected a warning, got=%q, %v", warnings, err)
+	}
+}
+
+func TestConcurrentRuns(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := range 4 {
+		in := New(Options{StepLimit: 1000 * (i + 1)})
+		wg.Go(func() {
+			for range 10 {
+				if _, err := in.Run("let n = 0; while (n < 100) { n = n + 1 }; n"); err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			}
+		})
+	}
+	wg.Wait()
+}
+
+func TestRunContext(t *testing.T) {
+	in := New(Options{})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := in.RunContext(ctx, "let n = 0; defer { n = -1; }; while (true) { n = n + 1; }")
+	if !errors.Is(err, ErrInterrupted) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected ErrInterrupted and context.DeadlineExceeded, got=%v", err)
+	}
+
+	// The interrupt does not outlive the run it stopped
+	if value, err := in.RunContext(context.Background(), "1 + 1"); err != nil || value.Inspect() != "2" {
+		t.Errorf("RunContext wrong. expected 2, got=%v, %v", value, err)
+	}
+}
+
+func TestRegisterFunc(t *testing.T) {
+	in := New(Options{})
+	funcs := map[string]any{
+		"add":     func(a, b int) int { return a + b },
+		"half":    func(x float64) float64 { return x / 2 },
+		"join":    func(sep string, parts ...string) string { return strings.Join(parts, sep) },
+		"keys":    func(h map[string]any) []string { return slices.Sorted(maps.Keys(h)) },
+		"even":    func(n int64) bool { return n%2 == 0 },
+		"fail":    func() error { return errors.New("no way") },
+		"small":   func(n int8) int8 { return n },
+		"nothing": func() {},
+		"apply":   func(f func(int) int, x int) int { return f(x) },
+		"try":     func(f func() (int, error)) string { _, err := f(); return fmt.Sprint(err) },
+		"kind":    func(v any) string { return fmt.Sprintf("%T", v) },
+		"object":  func(o object.Object) object.Type { return o.Type() },
+		"reverse": func(b []byte) []byte { slices.Reverse(b); return b },
+	}
+	for name, fn := range funcs {
+		if err := in.RegisterFunc(name, fn); err != nil {
+			t.Fatalf("RegisterFunc(%q): %v", name, err)
+		}
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"add(2, 3)", "5"},
+		{"half(3)", "1.5"},
+		{`join("-", "a", "b", "c")`, "a-b-c"},
+		{`join(",")`, ""},
+		{`keys({"b": 1, "a": [2]})`, "[a, b]"},
+		{"even(4) == true", "true"},
+		{"nothing()", "null"},
+		{"apply(fn(x) { x * 10 }, 4)", "40"},
+		{`try(fn() { 1 + true })`, "1:12: type mismatch: INTEGER + BOOLEAN"},
+		{`kind([1, "a"])`, "[]interface {}"},
+		{`kind(2.5)`, "float64"},
+		{`object({})`, "HASH"},
+		{`let b = bytes("abc"); [reverse(b), b]`, `[bytes("cba"), bytes("abc")]`},
+		{`kind(bytes("a"))`, "[]uint8"},
+	}
+	for _, tt := range tests {
+		value, err := in.Run(tt.input)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.input, err)
+			continue
+		}
+		if value.Inspect() != tt.expected {
+			t.Errorf("%s: expected=%q, got=%q", tt.input, tt.expected, value.Inspect())
+		}
+	}
+
+	failures := []struct {
+		input    string
+		expected string
+	}{
+		{"add(1)", "wrong number of arguments. got=1, want=2"},
+		{"join()", "wrong number of arguments. got=0, want at least 1"},
+		{`add(1, "2")`, "argument 2 to `add` must be int, got STRING"},
+		{"small(300)", "argument 1 to `small` must be int8, got INTEGER"},
+		{"fail()", "no way"},
+		{"apply(fn(x) { x + true }, 1)", "type mismatch: INTEGER + BOOLEAN"},
+	}
+	for _, tt := range failures {
+		_, err := in.Run(tt.input)
+		var runtimeErr *RuntimeError
+		if !errors.As(err, &runtimeErr) || runtimeErr.Message != tt.expected {
+			t.Errorf("%s: expected a runtime error %q, got=%v", tt.input, tt.expected, err)
+		}
+	}
+
+	// The limits stop a program inside a callback too
+	limited := New(Options{StepLimit: 100})
+	if err := limited.RegisterFunc("apply", funcs["apply"]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := limited.Run("apply(fn(x) { while (true) {} }, 1)"); !errors.Is(err, ErrStepLimit) {
+		t.Errorf("expected ErrStepLimit, got=%v", err)
+	}
+
+	for _, fn := range []any{42, func(chan int) {}, func() (int, int) { return 0, 0 }, func() func() { return nil }} {
+		if err := in.RegisterFunc("bad", fn); err == nil {
+			t.Errorf("RegisterFunc(%T) expected an error", fn)
+		}
+	}
+}
+
+func TestCall(t *testing.T) {
+	in := New(Options{})
+	if _, err := in.Run(`let greet = fn(name, times) { let s = ""; let i = 0; while (i < times) { s = s + name; i = i + 1; }; s };`); err != nil {
+		t.Fatal(err)
+	}
+	greet, _ := in.Lookup("greet")
+	value, err := in.Call(greet, "ab", 3)
+	if err != nil || value.Inspect() != "ababab" {
+		t.Errorf("Call wrong. expected ababab, got=%v, %v", value, err)
+	}
+
+	_, err = in.Call(greet, true, 1)
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) || runtimeErr.Message != "type mismatch: STRING + BOOLEAN" {
+		t.Errorf("expected a runtime error, got=%v", err)
+	}
+	var evalErr *evaluator.RuntimeError
+	if !errors.As(err, &evalErr) || evalErr.Err.Message != runtimeErr.Message {
+		t.Errorf("errors.As does not find the *evaluator.RuntimeError. got=%v", evalErr)
+	}
+	if _, err := in.Call(greet, make(chan int), 1); err == nil {
+		t.Error("expected an error for an argument that does not convert")
+	}
+}
+
+func TestRopeValues(t *testing.T) {
+	in := New(Options{})
+	var got []string
+	if err := in.RegisterFunc("keep", func(s object.Object) { got = append(got, s.(*object.String).Value) }); err != nil {
+		t.Fatal(err)
+	}
+	// Strings this long are concatenated as ropes, whose Value is only set once flattened
+	long := strings.Repeat("a", object.RopeThreshold)
+	in.Define("long", &object.String{Value: long})
+	expected := long + long + "b"
+
+	value, err := in.Run(`let s = long + long + "b"; keep(s); let join = fn(x) { x + long }; s`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, ok := value.(*object.String); !ok || s.Value != expected {
+		t.Errorf("Run returned a string without its Value: %T", value)
+	}
+	if len(got) != 1 || got[0] != expected {
+		t.Errorf("registered function got a string without its Value")
+	}
+
+	value, err = in.Run(`[long + long, {"key": long + long}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	array := value.(*object.Array)
+	if s := array.Elements[0].(*object.String); s.Value != long+long {
+		t.Error("string in the returned array has no Value")
+	}
+	for _, pair := range array.Elements[1].(*object.Hash).Pairs {
+		if s := pair.Value.(*object.String); s.Value != long+long {
+			t.Error("string in the returned hash has no Value")
+		}
+	}
+
+	if _, err := in.Run(`let t = long + long;`); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := in.Lookup("t"); value.(*object.String).Value != long+long {
+		t.Error("Lookup returned a string without its Value")
+	}
+
+	join, _ := in.Lookup("join")
+	value, err = in.Call(join, long)
+	if err != nil || value.(*object.String).Value != long+long {
+		t.Errorf("Call returned a string without its Value (%v)", err)
+	}
+}
@@ -0,0 +1,93 @@
+//go:build !monke_minimal
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dr8co/monke/learn"
+	"github.com/dr8co/monke/object"
+	"github.com/dr8co/monke/repl"
+)
+
+// runLearn implements "monke learn", which runs the REPL through the embedded lessons.
+func runLearn(args []string) int {
+	fs := flag.NewFlagSet("learn", flag.ExitOnError)
+	list := fs.Bool("list", false, "List the lessons and the progress made in each")
+	reset := fs.Bool("reset", false, "Forget the progress made so far")
+	noColor := fs.Bool("no-color", false, "Disable syntax highlighting and colored output")
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "Usage: %s learn [flags] [lesson]\n\n", filepath.Base(os.Args[0]))
+		fmt.Fprintln(out, "Starts the REPL with guided exercises, resuming where the last session stopped.")
+		fmt.Fprintln(out, "Naming a lesson starts at that lesson instead.")
+		fmt.Fprintln(out, "\nFlags:")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	lessons, err := learn.Lessons()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading lessons: %s\n", err)
+		return 1
+	}
+
+	file := progressFile()
+	progress := &learn.Progress{Solved: map[string]int{}}
+	if file != "" && !*reset {
+		if progress, err = learn.LoadProgress(file); err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading progress: %s\n", err)
+			return 1
+		}
+	}
+	if *reset && file != "" {
+		if err := progress.Save(file); err != nil {
+			fmt.Fprintf(os.Stderr, "Error saving progress: %s\n", err)
+			return 1
+		}
+	}
+
+	if *list {
+		for i, lesson := range lessons {
+			fmt.Printf("%d. %-12s %-24s %d/%d\n", i+1, lesson.Name, lesson.Title,
+				min(progress.Solved[lesson.Name], len(lesson.Exercises)), len(lesson.Exercises))
+		}
+		return 0
+	}
+
+	start := 0
+	if fs.NArg() > 0 {
+		start = -1
+		for i, lesson := range lessons {
+			if lesson.Name == fs.Arg(0) {
+				start = i
+				// Starting a lesson explicitly goes through it again
+				progress.Solved[lesson.Name] = 0
+			}
+		}
+		if start < 0 {
+			fmt.Fprintf(os.Stderr, "Error: unknown lesson %q (see %s learn -list)\n", fs.Arg(0), filepath.Base(os.Args[0]))
+			return 2
+		}
+	}
+
+	repl.Start(repl.Options{
+		NoColor: *noColor,
+		Env:     object.NewEnvironment(),
+		Guide:   learn.NewTutor(lessons, progress, file, start),
+	})
+	return 0
+}
+
+// progressFile returns where the tutorial progress is stored,
+// or "" if there is no configuration directory to store it in.
+func progressFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "monke", "learn.json")
+}
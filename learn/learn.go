@@ -0,0 +1,157 @@
+// Package learn implements the guided lessons of "monke learn".
+//
+// Lessons are text files embedded in the binary. Each one introduces a part of
+// the language and asks the user to evaluate expressions in the REPL; the Tutor
+// compares every evaluated value with the one the exercise expects and moves on
+// once it matches, saving the progress so that the next session resumes there.
+//
+// Lesson format: sections separated by "---" lines. The first section holds a
+// "# Title" line and the introduction. Each later section is an exercise: its
+// text, a "=> value" line with the expected result as the REPL prints it, and
+// optional "hint: " and "solution: " lines.
+//
+// Key components:
+//   - Lessons: Loads the embedded lessons
+//   - Progress: The number of exercises solved in each lesson, stored as JSON
+//   - Tutor: Leads a REPL session through the lessons
+package learn
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+//go:embed lessons/*.txt
+var lessonFiles embed.FS
+
+// Lesson is a titled introduction followed by exercises.
+type Lesson struct {
+	Name      string // The file name without its extension and number prefix, e.g. "values"
+	Title     string
+	Intro     string
+	Exercises []Exercise
+}
+
+// Exercise asks for an expression that evaluates to Expect.
+type Exercise struct {
+	Text     string
+	Expect   string // The expected value, as printed by the REPL
+	Hint     string // Shown after the first wrong answer
+	Solution string // Shown after a few wrong answers
+}
+
+// Lessons returns the embedded lessons in order.
+func Lessons() ([]Lesson, error) {
+	names, err := fs.Glob(lessonFiles, "lessons/*.txt")
+	if err != nil {
+		return nil, err
+	}
+	lessons := make([]Lesson, 0, len(names))
+	for _, name := range names {
+		data, err := lessonFiles.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		lesson, err := parseLesson(lessonName(name), string(data))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		lessons = append(lessons, lesson)
+	}
+	return lessons, nil
+}
+
+// lessonName turns "lessons/01_values.txt" into "values".
+func lessonName(file string) string {
+	name := strings.TrimSuffix(path.Base(file), ".txt")
+	if _, rest, ok := strings.Cut(name, "_"); ok {
+		return rest
+	}
+	return name
+}
+
+// parseLesson parses the text of a lesson file.
+func parseLesson(name, text string) (Lesson, error) {
+	sections := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n---\n")
+	title, intro, _ := strings.Cut(strings.TrimSpace(sections[0]), "\n")
+	if !strings.HasPrefix(title, "# ") {
+		return Lesson{}, errors.New("lesson must start with a \"# Title\" line")
+	}
+
+	lesson := Lesson{Name: name, Title: strings.TrimPrefix(title, "# "), Intro: strings.TrimSpace(intro)}
+	for i, section := range sections[1:] {
+		var ex Exercise
+		var text []string
+		for line := range strings.SplitSeq(strings.TrimSpace(section), "\n") {
+			switch {
+			case strings.HasPrefix(line, "=> "):
+				ex.Expect = strings.TrimPrefix(line, "=> ")
+			case strings.HasPrefix(line, "hint: "):
+				ex.Hint = strings.TrimPrefix(line, "hint: ")
+			case strings.HasPrefix(line, "solution: "):
+				ex.Solution = strings.TrimPrefix(line, "solution: ")
+			default:
+				text = append(text, line)
+			}
+		}
+		if ex.Expect == "" {
+			return Lesson{}, fmt.Errorf("exercise %d has no \"=> value\" line", i+1)
+		}
+		ex.Text = strings.Join(text, "\n")
+		lesson.Exercises = append(lesson.Exercises, ex)
+	}
+	if len(lesson.Exercises) == 0 {
+		return Lesson{}, errors.New("lesson has no exercises")
+	}
+	return lesson, nil
+}
+
+// Progress records the number of exercises solved in each lesson, by name.
+type Progress struct {
+	Solved map[string]int `json:"solved"`
+}
+
+// LoadProgress reads the progress stored in file.
+// A missing file means that nothing has been solved yet.
+func LoadProgress(file string) (*Progress, error) {
+	p := &Progress{Solved: map[string]int{}}
+	//nolint:gosec // The path is chosen by the interpreter, not read from input
+	data, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("reading progress: %w", err)
+	}
+	if p.Solved == nil {
+		p.Solved = map[string]int{}
+	}
+	return p, nil
+}
+
+// Save writes the progress to file, creating its directory if needed.
+func (p *Progress) Save(file string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o750); err != nil {
+		return err
+	}
+	return os.WriteFile(file, append(data, '\n'), 0o600)
+}
+
+// Complete reports whether every exercise of lesson has been solved.
+func (p *Progress) Complete(lesson Lesson) bool {
+	return p.Solved[lesson.Name] >= len(lesson.Exercises)
+}
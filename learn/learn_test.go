@@ -0,0 +1,127 @@
+package learn
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dr8co/monke/evaluator"
+	"github.com/dr8co/monke/lexer"
+	"github.com/dr8co/monke/object"
+	"github.com/dr8co/monke/parser"
+)
+
+func eval(t *testing.T, input string, env *object.Environment) object.Object {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("%q: parser errors: %v", input, p.Errors())
+	}
+	return evaluator.Eval(program, env)
+}
+
+func TestLessonSolutions(t *testing.T) {
+	lessons, err := Lessons()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lessons) == 0 {
+		t.Fatal("no lessons embedded")
+	}
+
+	for _, lesson := range lessons {
+		// Exercises of a lesson may use the bindings of earlier ones
+		env := object.NewEnvironment()
+		for i, ex := range lesson.Exercises {
+			if ex.Solution == "" {
+				t.Errorf("%s: exercise %d has no solution", lesson.Name, i+1)
+				continue
+			}
+			got := eval(t, ex.Solution, env)
+			if got == nil || got.Inspect() != ex.Expect {
+				t.Errorf("%s: exercise %d: solution %q gives %v, want %s", lesson.Name, i+1, ex.Solution, got, ex.Expect)
+			}
+		}
+	}
+}
+
+func TestParseLesson(t *testing.T) {
+	lesson, err := parseLesson("demo", "# Demo\n\nIntro.\n---\nAdd them.\n=> 3\nhint: Use +.\nsolution: 1 + 2\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Exercise{Text: "Add them.", Expect: "3", Hint: "Use +.", Solution: "1 + 2"}
+	if lesson.Title != "Demo" || lesson.Intro != "Intro." || len(lesson.Exercises) != 1 || lesson.Exercises[0] != want {
+		t.Errorf("wrong lesson. got=%+v", lesson)
+	}
+
+	for _, text := range []string{"Demo\n---\n=> 1", "# Demo\n", "# Demo\n---\nNo answer.\n"} {
+		if _, err := parseLesson("demo", text); err == nil {
+			t.Errorf("%q: expected an error", text)
+		}
+	}
+}
+
+func TestTutor(t *testing.T) {
+	lessons := []Lesson{
+		{Name: "one", Title: "One", Intro: "First.", Exercises: []Exercise{
+			{Text: "Give 1.", Expect: "1", Hint: "Type 1.", Solution: "1"},
+			{Text: "Give 2.", Expect: "2"},
+		}},
+		{Name: "two", Title: "Two", Exercises: []Exercise{{Text: "Give 3.", Expect: "3"}}},
+	}
+	file := filepath.Join(t.TempDir(), "monke", "learn.json")
+	progress, err := LoadProgress(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tutor := NewTutor(lessons, progress, file, 0)
+	env := object.NewEnvironment()
+
+	if got := tutor.Prompt(); !strings.Contains(got, "First.") || !strings.Contains(got, "Exercise 1/2: Give 1.") {
+		t.Errorf("wrong prompt. got=%q", got)
+	}
+
+	steps := []struct {
+		input    string
+		feedback string
+	}{
+		{"5", "Not quite: expected 1, got 5.\nHint: Type 1."},
+		{"let x = 1;", "That has no value to check.\nHint: Type 1."},
+		{"x", "Correct!"},
+		{"1 + 1", `Correct! You finished the lesson "One".`},
+	}
+	for _, step := range steps {
+		if got := tutor.Evaluated(eval(t, step.input, env)); got != step.feedback {
+			t.Errorf("%q: wrong feedback. expected=%q, got=%q", step.input, step.feedback, got)
+		}
+	}
+	if got := tutor.Prompt(); !strings.HasPrefix(got, "Lesson 2/2: Two") {
+		t.Errorf("wrong prompt after the first lesson. got=%q", got)
+	}
+
+	// A new session resumes after the solved lesson
+	saved, err := LoadProgress(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resumed := NewTutor(lessons, saved, "", 0)
+	resumed.Evaluated(&object.Integer{Value: 3})
+	if !resumed.Done() {
+		t.Errorf("tutor not done after solving every exercise. progress=%v", saved.Solved)
+	}
+}
+
+func TestTutorShowsSolution(t *testing.T) {
+	lessons := []Lesson{{Name: "one", Exercises: []Exercise{{Expect: "1", Solution: "1"}}}}
+	tutor := NewTutor(lessons, &Progress{Solved: map[string]int{}}, "", 0)
+
+	var feedback string
+	for range solutionAfter {
+		feedback = tutor.Evaluated(&object.Integer{Value: 2})
+	}
+	if !strings.HasSuffix(feedback, "Solution: 1") {
+		t.Errorf("solution not shown after %d wrong answers. got=%q", solutionAfter, feedback)
+	}
+}
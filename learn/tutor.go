@@ -0,0 +1,109 @@
+package learn
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dr8co/monke/object"
+)
+
+// solutionAfter is the number of wrong answers after which the solution is shown.
+const solutionAfter = 3
+
+// Tutor leads a REPL session through the lessons, one exercise at a time.
+// It implements repl.Guide.
+type Tutor struct {
+	lessons  []Lesson
+	progress *Progress
+	file     string // where progress is saved; empty to not save it
+	lesson   int    // index of the current lesson, len(lessons) when all are done
+	failures int    // wrong answers to the current exercise
+}
+
+// NewTutor returns a Tutor that starts at the first unsolved exercise of
+// lessons[start:], saving the progress to file after every solved exercise.
+func NewTutor(lessons []Lesson, progress *Progress, file string, start int) *Tutor {
+	t := &Tutor{lessons: lessons, progress: progress, file: file, lesson: start}
+	t.skipComplete()
+	return t
+}
+
+// skipComplete advances past the lessons whose exercises are all solved.
+func (t *Tutor) skipComplete() {
+	for t.lesson < len(t.lessons) && t.progress.Complete(t.lessons[t.lesson]) {
+		t.lesson++
+	}
+}
+
+// Done reports whether every lesson has been completed.
+func (t *Tutor) Done() bool {
+	return t.lesson >= len(t.lessons)
+}
+
+// current returns the current lesson and the index of its current exercise.
+func (t *Tutor) current() (Lesson, int) {
+	lesson := t.lessons[t.lesson]
+	return lesson, t.progress.Solved[lesson.Name]
+}
+
+// Prompt returns the current exercise, preceded by the lesson introduction
+// while no exercise of the lesson is solved.
+func (t *Tutor) Prompt() string {
+	if t.Done() {
+		return "You have completed every lesson. Keep experimenting, or press Esc to exit."
+	}
+	lesson, n := t.current()
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "Lesson %d/%d: %s\n", t.lesson+1, len(t.lessons), lesson.Title)
+	if n == 0 {
+		out.WriteString("\n" + lesson.Intro + "\n")
+	}
+	fmt.Fprintf(&out, "\nExercise %d/%d: %s", n+1, len(lesson.Exercises), lesson.Exercises[n].Text)
+	return out.String()
+}
+
+// Evaluated checks the value of an evaluation against the current exercise and
+// returns feedback for the user. The value is nil if the input had none, as
+// for a let statement or a syntax error.
+func (t *Tutor) Evaluated(value object.Object) string {
+	if t.Done() {
+		return ""
+	}
+	lesson, n := t.current()
+	ex := lesson.Exercises[n]
+
+	if value != nil && value.Inspect() == ex.Expect {
+		t.failures = 0
+		t.progress.Solved[lesson.Name] = n + 1
+		feedback := "Correct!"
+		if t.progress.Complete(lesson) {
+			feedback += fmt.Sprintf(" You finished the lesson %q.", lesson.Title)
+			t.skipComplete()
+		}
+		if t.file != "" {
+			if err := t.progress.Save(t.file); err != nil {
+				feedback += fmt.Sprintf("\n(could not save your progress: %s)", err)
+			}
+		}
+		return feedback
+	}
+
+	t.failures++
+	var feedback string
+	switch {
+	case value == nil:
+		feedback = "That has no value to check."
+	case value.Type() == object.ERROR_OBJ:
+		feedback = "That failed with an error."
+	default:
+		feedback = fmt.Sprintf("Not quite: expected %s, got %s.", ex.Expect, value.Inspect())
+	}
+	if ex.Hint != "" {
+		feedback += "\nHint: " + ex.Hint
+	}
+	if t.failures >= solutionAfter && ex.Solution != "" {
+		feedback += "\nSolution: " + ex.Solution
+	}
+	return feedback
+}
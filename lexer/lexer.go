@@ -16,7 +16,12 @@
 // and the NextToken method, which returns the next token from the input.
 package lexer
 
-import "github.com/dr8co/monke/token"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dr8co/monke/token"
+)
 
 // Common tokens that are reused to reduce allocations
 var (
@@ -28,6 +33,7 @@ var (
 	tokenGT        = token.Token{Type: token.GT, Literal: ">"}
 	tokenSemicolon = token.Token{Type: token.SEMICOLON, Literal: ";"}
 	tokenColon     = token.Token{Type: token.COLON, Literal: ":"}
+	tokenDot       = token.Token{Type: token.DOT, Literal: "."}
 	tokenComma     = token.Token{Type: token.COMMA, Literal: ","}
 	tokenLParen    = token.Token{Type: token.LPAREN, Literal: "("}
 	tokenRParen    = token.Token{Type: token.RPAREN, Literal: ")"}
@@ -38,12 +44,22 @@ var (
 	tokenEOF       = token.Token{Type: token.EOF, Literal: ""}
 )
 
+// Error is a problem found while reading the input, such as an unterminated comment.
+type Error struct {
+	Pos     token.Position
+	Message string
+}
+
 // Lexer represents the lexer for the Monke programming language.
 type Lexer struct {
 	input        string
 	position     int
 	readPosition int
 	ch           byte
+	line         int // line of ch
+	column       int // column of ch
+	pragmas      []string
+	errors       []Error
 	// Pre-allocates a token to reuse for single-character tokens
 	singleCharToken token.Token
 }
@@ -51,6 +67,12 @@ type Lexer struct {
 // readChar reads the next character from the input and advances the position.
 // It's optimized to minimize checks and operations.
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line++
+		l.column = 1
+	} else {
+		l.column++
+	}
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
@@ -65,18 +87,65 @@ func (l *Lexer) readChar() {
 func New(input string) *Lexer {
 	l := &Lexer{
 		input:           input,
+		line:            1,
 		singleCharToken: token.Token{}, // Initialize the token buffer
 	}
 	l.readChar()
+
+	// Skip a "#!" line so scripts can be run directly as executables
+	if strings.HasPrefix(input, "#!") {
+		l.skipLine()
+	}
+	l.readPragmas()
 	return l
 }
 
+// skipLine advances to the newline ending the current line, or to the end of input.
+func (l *Lexer) skipLine() {
+	for l.ch != '\n' && l.ch != 0 {
+		l.readChar()
+	}
+}
+
+// readPragmas consumes the "#pragma <name>" lines at the start of the input.
+func (l *Lexer) readPragmas() {
+	for {
+		l.skipWhitespace()
+		if !strings.HasPrefix(l.input[l.position:], "#pragma") {
+			return
+		}
+		start := l.position + len("#pragma")
+		l.skipLine()
+		l.pragmas = append(l.pragmas, strings.TrimSpace(l.input[start:l.position]))
+	}
+}
+
+// Pragmas returns the names given by the "#pragma" lines at the start of the input.
+func (l *Lexer) Pragmas() []string {
+	return l.pragmas
+}
+
+// Errors returns the problems found in the input read so far, in order.
+func (l *Lexer) Errors() []Error {
+	return l.errors
+}
+
 // NextToken reads the next token from the input.
 // It skips whitespace, identifies the token type based on the current character,
-// and returns a token with the appropriate type and literal value.
+// and returns a token with the appropriate type, literal value, and position.
 func (l *Lexer) NextToken() token.Token {
+	end := l.line // The line the previous token ends on
 	l.skipWhitespace()
 
+	line, column := l.line, l.column
+	tok := l.scanToken()
+	tok.Line, tok.Column = line, column
+	tok.Newline = line > end
+	return tok
+}
+
+// scanToken reads the token starting at the current character.
+func (l *Lexer) scanToken() token.Token {
 	switch l.ch {
 	case '=':
 		if l.peekChar() == '=' {
@@ -86,6 +155,11 @@ func (l *Lexer) NextToken() token.Token {
 			l.readChar() // Advance to the next character after '=='
 			return token.Token{Type: token.EQ, Literal: string(ch) + string('=')}
 		}
+		if l.peekChar() == '>' {
+			l.readChar()
+			l.readChar() // Advance to the next character after '=>'
+			return token.Token{Type: token.FAT_ARROW, Literal: "=>"}
+		}
 		l.readChar() // Advance to the next character after '='
 		return token.Token{Type: token.ASSIGN, Literal: "="}
 	case '!':
@@ -102,6 +176,11 @@ func (l *Lexer) NextToken() token.Token {
 		l.readChar() // Advance to the next character after '+'
 		return tokenPlus
 	case '-':
+		if l.peekChar() == '>' {
+			l.readChar()
+			l.readChar() // Advance to the next character after '->'
+			return token.Token{Type: token.ARROW, Literal: "->"}
+		}
 		l.readChar() // Advance to the next character after '-'
 		return tokenMinus
 	case '/':
@@ -111,9 +190,19 @@ func (l *Lexer) NextToken() token.Token {
 		l.readChar() // Advance to the next character after '*'
 		return tokenAsterisk
 	case '<':
+		if l.peekChar() == '=' {
+			l.readChar()
+			l.readChar() // Advance to the next character after '<='
+			return token.Token{Type: token.LT_EQ, Literal: "<="}
+		}
 		l.readChar() // Advance to the next character after '<'
 		return tokenLT
 	case '>':
+		if l.peekChar() == '=' {
+			l.readChar()
+			l.readChar() // Advance to the next character after '>='
+			return token.Token{Type: token.GT_EQ, Literal: ">="}
+		}
 		l.readChar() // Advance to the next character after '>'
 		return tokenGT
 	case ';':
@@ -143,11 +232,56 @@ func (l *Lexer) NextToken() token.Token {
 	case ']':
 		l.readChar() // Advance to the next character after ']'
 		return tokenRBracket
+	case '.':
+		if strings.HasPrefix(l.input[l.position:], "...") {
+			l.readChar()
+			l.readChar()
+			l.readChar() // Advance to the next character after '...'
+			return token.Token{Type: token.SPREAD, Literal: "..."}
+		}
+		if l.peekChar() == '.' {
+			l.readChar()
+			if l.peekChar() == '=' {
+				l.readChar()
+				l.readChar() // Advance to the next character after '..='
+				return token.Token{Type: token.RANGE_EQ, Literal: "..="}
+			}
+			l.readChar() // Advance to the next character after '..'
+			return token.Token{Type: token.RANGE, Literal: ".."}
+		}
+		l.readChar() // Advance to the next character after '.'
+		return tokenDot
+	case '?':
+		switch l.peekChar() {
+		case '.':
+			l.readChar()
+			l.readChar() // Advance to the next character after '?.'
+			return token.Token{Type: token.OPTIONAL, Literal: "?."}
+		case '?':
+			l.readChar()
+			l.readChar() // Advance to the next character after '??'
+			return token.Token{Type: token.NULLISH, Literal: "??"}
+		}
+		l.singleCharToken.Type = token.QUESTION
+		l.singleCharToken.Literal = string(l.ch)
+		l.readChar()
+		return l.singleCharToken
 	case '"':
 		tok := token.Token{Type: token.STRING}
-		tok.Literal = l.readString()
+		tok.Literal = l.readString('"')
 		l.readChar() // Advance to the next character after the closing quote
 		return tok
+	case '`':
+		tok := token.Token{Type: token.RAW_STRING}
+		start := token.Position{Line: l.line, Column: l.column}
+		tok.Literal = l.readString('`')
+		// Raw strings span lines, so a missing backtick would silently
+		// swallow the rest of the file
+		if l.ch == 0 {
+			l.errors = append(l.errors, Error{Pos: start, Message: "Unterminated raw string; it needs a closing `"})
+		}
+		l.readChar() // Advance to the next character after the closing backtick
+		return tok
 	case 0:
 		return tokenEOF
 	default:
@@ -159,9 +293,17 @@ func (l *Lexer) NextToken() token.Token {
 			}
 		}
 		if isDigit(l.ch) {
+			literal := l.readNumber()
+			// A '.' followed by a digit continues the number as a float
+			if l.ch == '.' && isDigit(l.peekChar()) {
+				start := l.position - len(literal)
+				l.readChar()
+				l.readNumber()
+				return token.Token{Type: token.FLOAT, Literal: l.input[start:l.position]}
+			}
 			return token.Token{
 				Type:    token.INT,
-				Literal: l.readNumber(),
+				Literal: literal,
 			}
 		}
 		// For illegal characters, reuse the single char token
@@ -181,34 +323,140 @@ func isDigit(ch byte) bool {
 }
 
 // readNumber reads a number from the input and returns it as a string.
+// Underscores may separate the digits, as in "1_000_000"; the ones that do not
+// sit between two digits are reported as errors.
 // It's optimized to avoid unnecessary allocations.
 func (l *Lexer) readNumber() string {
 	position := l.position
+	start := token.Position{Line: l.line, Column: l.column}
 	// Fast-forward through digits
-	for isDigit(l.ch) {
+	for isDigit(l.ch) || l.ch == '_' {
 		l.readChar()
 	}
-	return l.input[position:l.position]
+	literal := l.input[position:l.position]
+	if literal[len(literal)-1] == '_' || strings.Contains(literal, "__") {
+		l.errors = append(l.errors, Error{
+			Pos:     start,
+			Message: fmt.Sprintf("Misplaced _ in number %s; underscores may only separate digits", literal),
+		})
+	}
+	return literal
 }
 
 // readIdentifier reads an identifier from the input and returns it as a string.
+// Digits may follow the first letter, and the name may end with one '?' or '!',
+// as in "empty?", unless that would split an operator: '!' is left out before
+// '=', and '?' only ends a name as questionEndsName decides, so "a?b:c" and
+// "c? a : b" are still conditional expressions.
 // It's optimized to avoid unnecessary allocations.
 func (l *Lexer) readIdentifier() string {
 	position := l.position
-	// Fast-forward through letters
-	for isLetter(l.ch) {
+	// Fast-forward through letters and digits
+	for isLetter(l.ch) || isDigit(l.ch) {
 		l.readChar()
 	}
+	switch l.ch {
+	case '!':
+		if l.peekChar() != '=' {
+			l.readChar()
+		}
+	case '?':
+		if l.questionEndsName() {
+			l.readChar()
+		}
+	}
 	return l.input[position:l.position]
 }
 
-// skipWhitespace skips any whitespace characters in the input.
+// questionEndsName reports whether the '?' at the current character ends the
+// name before it. It does before a line break, '(', a closing bracket, '=', a
+// separator or the end of the input. Before spaces, it does unless an operand
+// follows them, which makes the '?' a conditional operator.
+func (l *Lexer) questionEndsName() bool {
+	switch l.peekChar() {
+	case 0, '\n', '\r', '(', ')', ']', '}', ',', ';', '=', ':':
+		return true
+	case ' ', '\t':
+		i := l.readPosition
+		for i < len(l.input) && (l.input[i] == ' ' || l.input[i] == '\t') {
+			i++
+		}
+		return i == len(l.input) || !startsOperand(l.input[i:])
+	}
+	return false
+}
+
+// startsOperand reports whether s, which is not empty, starts with something
+// that can only be an operand after a name: a name other than "in", a number,
+// a string, or a '!' that does not start "!=". A '(', '[' or '-' continues the
+// name as a call, an index or a subtraction instead.
+func startsOperand(s string) bool {
+	switch ch := s[0]; {
+	case isLetter(ch):
+		end := 1
+		for end < len(s) && (isLetter(s[end]) || isDigit(s[end])) {
+			end++
+		}
+		return s[:end] != "in"
+	case isDigit(ch) || ch == '"' || ch == '`':
+		return true
+	case ch == '!':
+		return len(s) == 1 || s[1] != '='
+	}
+	return false
+}
+
+// skipWhitespace skips any whitespace characters and comments in the input.
 // It's optimized to use a single loop.
 func (l *Lexer) skipWhitespace() {
-	// Fast-forward through whitespace
-	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
+	for {
+		// Fast-forward through whitespace
+		for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
+			l.readChar()
+		}
+		switch {
+		case l.ch == '/' && l.peekChar() == '*':
+			l.skipBlockComment()
+		case l.ch == '*' && l.peekChar() == '/':
+			// A "*/" is never valid outside a comment, so report it here
+			// rather than as two confusing operator errors
+			l.errors = append(l.errors, Error{
+				Pos:     token.Position{Line: l.line, Column: l.column},
+				Message: "Unexpected */ without a matching /*",
+			})
+			l.readChar()
+			l.readChar()
+		default:
+			return
+		}
+	}
+}
+
+// skipBlockComment skips a "/* ... */" comment, starting at its '/'.
+// Comments nest, so every "/*" inside a comment needs its own "*/".
+func (l *Lexer) skipBlockComment() {
+	start := token.Position{Line: l.line, Column: l.column}
+	depth := 0
+	for l.ch != 0 {
+		switch {
+		case l.ch == '/' && l.peekChar() == '*':
+			depth++
+			l.readChar()
+		case l.ch == '*' && l.peekChar() == '/':
+			depth--
+			l.readChar()
+			if depth == 0 {
+				l.readChar()
+				return
+			}
+		}
 		l.readChar()
 	}
+	msg := "Unterminated block comment; it needs a closing */"
+	if depth > 1 {
+		msg = fmt.Sprintf("Unterminated block comment; comments nest, so it needs %d closing */", depth)
+	}
+	l.errors = append(l.errors, Error{Pos: start, Message: msg})
 }
 
 // peekChar returns the next character in the input without advancing the position.
@@ -220,14 +468,14 @@ func (l *Lexer) peekChar() byte {
 	return l.input[l.readPosition]
 }
 
-// readString reads a string from the input and returns it as a string.
+// readString reads a string enclosed in quote from the input and returns it as a string.
 // It's optimized to avoid unnecessary allocations.
-func (l *Lexer) readString() string {
+func (l *Lexer) readString(quote byte) string {
 	position := l.position + 1
 	// Fast-forward through string characters
 	for {
 		l.readChar()
-		if l.ch == '"' || l.ch == 0 {
+		if l.ch == quote || l.ch == 0 {
 			break
 		}
 	}
@@ -1,6 +1,7 @@
 package lexer
 
 import (
+	"slices"
 	"testing"
 
 	"github.com/dr8co/monke/token"
@@ -13,7 +14,7 @@ let add = fn(x, y) {
     x + y;
 };
 let result = add(five, ten);
-!-/*5;
+!-/ *5;
 5 < 10 > 5;
 
 if (5 < 10) {
@@ -24,11 +25,19 @@ if (5 < 10) {
 
 10 == 10;
 10 != 9;
+1 <= 2 >= 1;
 
 "foobar"
 "foo bar"
 [1, 2];
 {"foo": "bar"}
+[...xs];
+h?.a ?? b.c;
+c ? 1 : 2;
+3.14 [1...]
+fn(a: int) -> int
+match (x) { _ => 1 }
+class P(x) { fn m() { self } }
 `
 	tests := []struct {
 		expectedType    token.Type
@@ -107,6 +116,12 @@ if (5 < 10) {
 		{token.NOT_EQ, "!="},
 		{token.INT, "9"},
 		{token.SEMICOLON, ";"},
+		{token.INT, "1"},
+		{token.LT_EQ, "<="},
+		{token.INT, "2"},
+		{token.GT_EQ, ">="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
 		{token.STRING, "foobar"},
 		{token.STRING, "foo bar"},
 		{token.LBRACKET, "["},
@@ -120,6 +135,61 @@ if (5 < 10) {
 		{token.COLON, ":"},
 		{token.STRING, "bar"},
 		{token.RBRACE, "}"},
+		{token.LBRACKET, "["},
+		{token.SPREAD, "..."},
+		{token.IDENT, "xs"},
+		{token.RBRACKET, "]"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "h"},
+		{token.OPTIONAL, "?."},
+		{token.IDENT, "a"},
+		{token.NULLISH, "??"},
+		{token.IDENT, "b"},
+		{token.DOT, "."},
+		{token.IDENT, "c"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "c"},
+		{token.QUESTION, "?"},
+		{token.INT, "1"},
+		{token.COLON, ":"},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.FLOAT, "3.14"},
+		{token.LBRACKET, "["},
+		{token.INT, "1"},
+		{token.SPREAD, "..."},
+		{token.RBRACKET, "]"},
+		{token.FUNCTION, "fn"},
+		{token.LPAREN, "("},
+		{token.IDENT, "a"},
+		{token.COLON, ":"},
+		{token.IDENT, "int"},
+		{token.RPAREN, ")"},
+		{token.ARROW, "->"},
+		{token.IDENT, "int"},
+		{token.MATCH, "match"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.IDENT, "_"},
+		{token.FAT_ARROW, "=>"},
+		{token.INT, "1"},
+		{token.RBRACE, "}"},
+		{token.CLASS, "class"},
+		{token.IDENT, "P"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.FUNCTION, "fn"},
+		{token.IDENT, "m"},
+		{token.LPAREN, "("},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.IDENT, "self"},
+		{token.RBRACE, "}"},
+		{token.RBRACE, "}"},
 		{token.EOF, ""},
 	}
 
@@ -139,3 +209,292 @@ if (5 < 10) {
 		}
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	input := "let x = 5;\n  x + 10;\n\"a b\" == y"
+
+	tests := []struct {
+		expectedType   token.Type
+		expectedLine   int
+		expectedColumn int
+	}{
+		{token.LET, 1, 1},
+		{token.IDENT, 1, 5},
+		{token.ASSIGN, 1, 7},
+		{token.INT, 1, 9},
+		{token.SEMICOLON, 1, 10},
+		{token.IDENT, 2, 3},
+		{token.PLUS, 2, 5},
+		{token.INT, 2, 7},
+		{token.SEMICOLON, 2, 9},
+		{token.STRING, 3, 1},
+		{token.EQ, 3, 7},
+		{token.IDENT, 3, 10},
+		{token.EOF, 3, 11},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Line != tt.expectedLine || tok.Column != tt.expectedColumn {
+			t.Errorf("tests[%d] - position wrong. expected=%d:%d, got=%d:%d",
+				i, tt.expectedLine, tt.expectedColumn, tok.Line, tok.Column)
+		}
+	}
+}
+
+func TestNewlineTokens(t *testing.T) {
+	input := "a b\n  c /* x\n */ d `raw\nstring` e\n\nf"
+	expected := map[string]bool{"a": false, "b": false, "c": true, "d": true, "raw\nstring": false, "e": false, "f": true}
+
+	l := New(input)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		if tok.Newline != expected[tok.Literal] {
+			t.Errorf("%q: Newline wrong. expected=%t, got=%t", tok.Literal, expected[tok.Literal], tok.Newline)
+		}
+	}
+}
+
+func TestShebangLine(t *testing.T) {
+	l := New("#!/usr/bin/env monke\nlet x = 1;")
+
+	tok := l.NextToken()
+	if tok.Type != token.LET {
+		t.Fatalf("tokentype wrong. expected=%q, got=%q", token.LET, tok.Type)
+	}
+	if tok.Line != 2 || tok.Column != 1 {
+		t.Errorf("position wrong. expected=2:1, got=%d:%d", tok.Line, tok.Column)
+	}
+}
+
+func TestPragmas(t *testing.T) {
+	l := New("#!/usr/bin/env monke\n#pragma strict\n\n#pragma  legacy \nlet x = 1;")
+
+	tok := l.NextToken()
+	if tok.Type != token.LET || tok.Line != 5 {
+		t.Fatalf("first token wrong. expected LET at line 5, got=%q at line %d", tok.Type, tok.Line)
+	}
+	pragmas := l.Pragmas()
+	if len(pragmas) != 2 || pragmas[0] != "strict" || pragmas[1] != "legacy" {
+		t.Errorf("pragmas wrong. got=%q", pragmas)
+	}
+}
+
+func TestBlockComments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.Type
+		errors   []string // "line:column: message" of each error
+	}{
+		{"1 /* one */ + 2", []token.Type{token.INT, token.PLUS, token.INT}, nil},
+		{"/* a\nmulti-line\ncomment */ x", []token.Type{token.IDENT}, nil},
+		{"x /* outer /* inner */ still outer */ y", []token.Type{token.IDENT, token.IDENT}, nil},
+		{"a/**/b /***/", []token.Type{token.IDENT, token.IDENT}, nil},
+		{"x / *y", []token.Type{token.IDENT, token.SLASH, token.ASTERISK, token.IDENT}, nil},
+		{"x\n  /* never closed", []token.Type{token.IDENT},
+			[]string{"2:3: Unterminated block comment; it needs a closing */"}},
+		{"/* a /* b */", nil,
+			[]string{"1:1: Unterminated block comment; it needs a closing */"}},
+		{"/* a /* b /* c", nil,
+			[]string{"1:1: Unterminated block comment; comments nest, so it needs 3 closing */"}},
+		{"x */ y", []token.Type{token.IDENT, token.IDENT},
+			[]string{"1:3: Unexpected */ without a matching /*"}},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		var got []token.Type
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+			got = append(got, tok.Type)
+		}
+		if !slices.Equal(got, tt.expected) {
+			t.Errorf("%q: wrong tokens. expected=%v, got=%v", tt.input, tt.expected, got)
+		}
+
+		var errs []string
+		for _, err := range l.Errors() {
+			errs = append(errs, err.Pos.String()+": "+err.Message)
+		}
+		if !slices.Equal(errs, tt.errors) {
+			t.Errorf("%q: wrong errors. expected=%q, got=%q", tt.input, tt.errors, errs)
+		}
+	}
+}
+
+func TestRawStrings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		errors   []string // "line:column: message" of each error
+	}{
+		{"`plain`", "plain", nil},
+		{"`say \"hi\"`", `say "hi"`, nil},
+		{"`line one\n  line two\\n`", "line one\n  line two\\n", nil},
+		{"``", "", nil},
+		{"x = `never closed\n", "never closed\n", []string{"1:5: Unterminated raw string; it needs a closing `"}},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		var tok token.Token
+		for tok = l.NextToken(); tok.Type != token.RAW_STRING && tok.Type != token.EOF; tok = l.NextToken() {
+		}
+		if tok.Type != token.RAW_STRING || tok.Literal != tt.expected {
+			t.Errorf("%q: wrong token. expected=RAW_STRING %q, got=%s %q", tt.input, tt.expected, tok.Type, tok.Literal)
+		}
+		if next := l.NextToken(); next.Type != token.EOF {
+			t.Errorf("%q: expected EOF after the string, got=%s", tt.input, next.Type)
+		}
+
+		var errs []string
+		for _, err := range l.Errors() {
+			errs = append(errs, err.Pos.String()+": "+err.Message)
+		}
+		if !slices.Equal(errs, tt.errors) {
+			t.Errorf("%q: wrong errors. expected=%q, got=%q", tt.input, tt.errors, errs)
+		}
+	}
+
+	// Lines inside a raw string count towards the positions of later tokens
+	l := New("`a\nb` x")
+	l.NextToken()
+	if tok := l.NextToken(); tok.Line != 2 || tok.Column != 4 {
+		t.Errorf("position after a multi-line string wrong. expected=2:4, got=%d:%d", tok.Line, tok.Column)
+	}
+}
+
+func TestDigitSeparators(t *testing.T) {
+	l := New("1_000 + 2_5.0_1;\n  3__0")
+	expected := []token.Token{
+		{Type: token.INT, Literal: "1_000"},
+		{Type: token.PLUS, Literal: "+"},
+		{Type: token.FLOAT, Literal: "2_5.0_1"},
+		{Type: token.SEMICOLON, Literal: ";"},
+		{Type: token.INT, Literal: "3__0"},
+	}
+	for i, want := range expected {
+		tok := l.NextToken()
+		if tok.Type != want.Type || tok.Literal != want.Literal {
+			t.Errorf("tests[%d] - wrong token. expected=%s %q, got=%s %q", i, want.Type, want.Literal, tok.Type, tok.Literal)
+		}
+	}
+
+	errs := l.Errors()
+	if len(errs) != 1 || errs[0].Pos.String() != "2:3" {
+		t.Fatalf("expected one error at 2:3, got=%v", errs)
+	}
+	if msg := "Misplaced _ in number 3__0; underscores may only separate digits"; errs[0].Message != msg {
+		t.Errorf("wrong error. expected=%q, got=%q", msg, errs[0].Message)
+	}
+}
+
+func TestIdentifierSuffixes(t *testing.T) {
+	l := New("x1 empty?(xs) done! a!=b c?d:e f?.g h??i j?: k?")
+	expected := []token.Token{
+		{Type: token.IDENT, Literal: "x1"},
+		{Type: token.IDENT, Literal: "empty?"},
+		{Type: token.LPAREN, Literal: "("},
+		{Type: token.IDENT, Literal: "xs"},
+		{Type: token.RPAREN, Literal: ")"},
+		{Type: token.IDENT, Literal: "done!"},
+		{Type: token.IDENT, Literal: "a"},
+		{Type: token.NOT_EQ, Literal: "!="},
+		{Type: token.IDENT, Literal: "b"},
+		{Type: token.IDENT, Literal: "c"},
+		{Type: token.QUESTION, Literal: "?"},
+		{Type: token.IDENT, Literal: "d"},
+		{Type: token.COLON, Literal: ":"},
+		{Type: token.IDENT, Literal: "e"},
+		{Type: token.IDENT, Literal: "f"},
+		{Type: token.OPTIONAL, Literal: "?."},
+		{Type: token.IDENT, Literal: "g"},
+		{Type: token.IDENT, Literal: "h"},
+		{Type: token.NULLISH, Literal: "??"},
+		{Type: token.IDENT, Literal: "i"},
+		{Type: token.IDENT, Literal: "j?"},
+		{Type: token.COLON, Literal: ":"},
+		{Type: token.IDENT, Literal: "k?"},
+		{Type: token.EOF, Literal: ""},
+	}
+	for i, want := range expected {
+		tok := l.NextToken()
+		if tok.Type != want.Type || tok.Literal != want.Literal {
+			t.Errorf("tests[%d] - wrong token. expected=%s %q, got=%s %q", i, want.Type, want.Literal, tok.Type, tok.Literal)
+		}
+	}
+
+	// After spaces, a '?' followed by an operand is the conditional operator
+	tests := []struct {
+		input    string
+		literals []string
+	}{
+		{"c? a : b", []string{"c", "?", "a", ":", "b"}},
+		{"c?  1 : 2", []string{"c", "?", "1", ":", "2"}},
+		{`c?	"x" : (y)`, []string{"c", "?", "x", ":", "(", "y", ")"}},
+		{"c? !d : [e]", []string{"c", "?", "!", "d", ":", "[", "e", "]"}},
+		{"c? index : 0", []string{"c", "?", "index", ":", "0"}},
+		{"for (x? in xs)", []string{"for", "(", "x?", "in", "xs", ")"}},
+		{"empty? [0] - 1", []string{"empty?", "[", "0", "]", "-", "1"}},
+		{"empty? (xs)", []string{"empty?", "(", "xs", ")"}},
+		{"empty? == true", []string{"empty?", "==", "true"}},
+		{"empty? != done!", []string{"empty?", "!=", "done!"}},
+		{"let empty? = 1", []string{"let", "empty?", "=", "1"}},
+		{"empty? /* note */ )", []string{"empty?", ")"}},
+		{"empty?\n(x)", []string{"empty?", "(", "x", ")"}},
+		{"empty?  ", []string{"empty?"}},
+	}
+	for _, tt := range tests {
+		l := New(tt.input)
+		var literals []string
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+			literals = append(literals, tok.Literal)
+		}
+		if !slices.Equal(literals, tt.literals) {
+			t.Errorf("%q: wrong tokens. expected=%q, got=%q", tt.input, tt.literals, literals)
+		}
+	}
+}
+
+func TestRanges(t *testing.T) {
+	l := New("1..10 0..=n [...xs] a.b 1.5")
+	expected := []token.Token{
+		{Type: token.INT, Literal: "1"},
+		{Type: token.RANGE, Literal: ".."},
+		{Type: token.INT, Literal: "10"},
+		{Type: token.INT, Literal: "0"},
+		{Type: token.RANGE_EQ, Literal: "..="},
+		{Type: token.IDENT, Literal: "n"},
+		{Type: token.LBRACKET, Literal: "["},
+		{Type: token.SPREAD, Literal: "..."},
+		{Type: token.IDENT, Literal: "xs"},
+		{Type: token.RBRACKET, Literal: "]"},
+		{Type: token.IDENT, Literal: "a"},
+		{Type: token.DOT, Literal: "."},
+		{Type: token.IDENT, Literal: "b"},
+		{Type: token.FLOAT, Literal: "1.5"},
+		{Type: token.EOF, Literal: ""},
+	}
+	for i, want := range expected {
+		tok := l.NextToken()
+		if tok.Type != want.Type || tok.Literal != want.Literal {
+			t.Errorf("tests[%d] - wrong token. expected=%s %q, got=%s %q", i, want.Type, want.Literal, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestCommentBeforePragma(t *testing.T) {
+	l := New("/* strict mode */\n#pragma strict\nx")
+
+	if tok := l.NextToken(); tok.Type != token.IDENT {
+		t.Fatalf("first token wrong. expected IDENT, got=%q", tok.Type)
+	}
+	if pragmas := l.Pragmas(); len(pragmas) != 1 || pragmas[0] != "strict" {
+		t.Errorf("pragmas wrong. got=%q", pragmas)
+	}
+}
@@ -2,145 +2,533 @@
 package main
 
 import (
+	"bufio"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"math"
 	"os"
-	"os/user"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
 
+	"github.com/dr8co/monke/ast"
 	"github.com/dr8co/monke/evaluator"
-	"github.com/dr8co/monke/lexer"
+	"github.com/dr8co/monke/heap"
+	"github.com/dr8co/monke/modules"
 	"github.com/dr8co/monke/object"
-	"github.com/dr8co/monke/parser"
-	"github.com/dr8co/monke/repl"
+	"github.com/dr8co/monke/pipeline"
+	"github.com/dr8co/monke/replay"
+	"github.com/dr8co/monke/token"
+	"github.com/dr8co/monke/trace"
+	"github.com/dr8co/monke/version"
 )
 
-const VERSION = "0.9.0"
+// maxLineLength is the longest stdin line accepted by the line-processing mode.
+const maxLineLength = 16 * 1024 * 1024
+
+// stdin is the standard input of -n and -p, recorded and replayed with traces.
+var stdin io.Reader = os.Stdin
+
+// command is a subcommand, such as "monke check".
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) int // receives the arguments after the name, returns the exit status
+}
+
+var commands = []command{
+	{"check", "Report errors in scripts without running them (--types checks annotations)", runCheck},
+	{"learn", "Learn the language with guided exercises in the REPL", runLearn},
+	{"examples", "List, show and run the sample programs", runExamples},
+	{"doc", "Show the reference of a builtin or keyword", runDoc},
+	{"get", "Vendor packages from git repositories into the project", runGet},
+	{"stats", "Report size and complexity metrics of scripts", runStats},
+	{"min", "Print scripts without comments and whitespace (--rename shortens local names)", runMin},
+	{"bench", "Compare benchmark results of the profiler (bench diff old.json new.json)", runBench},
+	{"selfcheck", "Verify the embedded stdlib, examples and lessons, and run a smoke test", runSelfcheck},
+}
 
 func main() {
+	// A subcommand takes over the rest of the command line
+	if len(os.Args) > 1 {
+		for _, cmd := range commands {
+			if os.Args[1] == cmd.name {
+				os.Exit(cmd.run(os.Args[2:]))
+			}
+		}
+	}
+
 	// Define command-line flags
 	noColor := flag.Bool("no-color", false, "Disable syntax highlighting and colored output")
 	fileFlag := flag.String("file", "", "Execute a Monkey script file")
-	evalFlag := flag.String("eval", "", "Evaluate a Monkey expression and print the result")
+	var evalFlags stringList
+	flag.Var(&evalFlags, "eval", "Evaluate a Monkey expression and print the result (repeatable)")
+	printFlag := flag.Bool("print", false, "Evaluate the -e expressions once per stdin line, bound to \"line\", and print each result")
+	loopFlag := flag.Bool("loop", false, "Evaluate the -e expressions once per stdin line, bound to \"line\" and \"line_number\"")
+	langFlag := flag.String("lang", "legacy", "Language mode: \"legacy\" or \"strict\" (scripts can also start with #pragma strict)")
 	debugFlag := flag.Bool("debug", false, "Enable debug mode with more verbose output")
 	versionFlag := flag.Bool("version", false, "Show version information")
+	recordFlag := flag.String("record", "", "Record nondeterministic inputs of the run to a trace file")
+	replayFlag := flag.String("replay", "", "Replay the nondeterministic inputs stored in a trace file")
+	traceEvalFlag := flag.String("trace-eval", "", "Write one JSON line per evaluated statement to a file")
+	statsFlag := flag.Bool("vm-stats", false, "Print call counts and cumulative time per builtin after the run")
+	reportFlag := flag.Bool("report", false, "Print a summary of the time, memory and calls a script took after it runs")
+	deterministicFlag := flag.Bool("deterministic", false, "Produce the same output on every run: seeded random numbers, sorted hashes and a stopped clock")
+	seedFlag := flag.Uint64("seed", 0, "Seed of the random numbers in -deterministic runs")
+	var memoryFlag byteSize
+	flag.Var(&memoryFlag, "max-memory", "Abort the run when its live objects take more than this many bytes, e.g. 64M (0 for no limit)")
+	heapFlag := flag.String("heap-snapshot", "", "Write the object graph reachable from the global environment to a file (.dot for DOT, JSON otherwise)")
+	replLogFlag := flag.String("repl-log", "", "Append one JSON line per REPL event (inputs, results, commands) to a file")
 
 	// Define short flag aliases
-	flag.BoolVar(noColor, "n", false, "Disable syntax highlighting and colored output")
 	flag.StringVar(fileFlag, "f", "", "Execute a Monkey script file")
-	flag.StringVar(evalFlag, "e", "", "Evaluate a Monkey expression and print the result")
+	flag.Var(&evalFlags, "e", "Evaluate a Monkey expression and print the result (repeatable)")
+	flag.BoolVar(printFlag, "p", false, "Evaluate the -e expressions once per stdin line, bound to \"line\", and print each result")
+	flag.BoolVar(loopFlag, "n", false, "Evaluate the -e expressions once per stdin line, bound to \"line\" and \"line_number\"")
 	flag.BoolVar(debugFlag, "d", false, "Enable debug mode with more verbose output")
 	flag.BoolVar(versionFlag, "v", false, "Show version information")
 
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+		fmt.Fprintf(out, "Usage: %s [flags] [script [args...]]\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(out, "       %s <command> [args...]\n\n", filepath.Base(os.Args[0]))
+		fmt.Fprintln(out, "Arguments after the script, or after --, are passed to the script as args().")
+		fmt.Fprintln(out, "\nCommands:")
+		for _, cmd := range commands {
+			fmt.Fprintf(out, "  %-10s %s\n", cmd.name, cmd.summary)
+		}
+		fmt.Fprintln(out, "\nFlags:")
+		flag.PrintDefaults()
+	}
+
 	// Parse command-line flags
 	flag.Parse()
 
 	// Show version information if requested
 	if *versionFlag {
-		fmt.Printf("Monkey Programming Language v%s\n", VERSION)
+		fmt.Printf("Monkey Programming Language v%s\n", version.Version)
 		return
 	}
 
-	// Get current user
-	usr, err := user.Current()
-	if err != nil {
-		panic(err)
+	// Create the global environment shared by all modes
+	env := object.NewEnvironment()
+	switch *langFlag {
+	case "legacy":
+	case "strict":
+		env.SetStrict(true)
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown language mode %q, want \"legacy\" or \"strict\"\n", *langFlag)
+		os.Exit(1)
+	}
+
+	// Remove or record the sources of nondeterminism if requested
+	if *deterministicFlag {
+		if *recordFlag != "" || *replayFlag != "" {
+			fmt.Fprintln(os.Stderr, "Error: --deterministic cannot be used with --record or --replay")
+			os.Exit(1)
+		}
+		evaluator.SetDeterministic(*seedFlag)
 	}
+	evaluator.SetMemoryLimit(int(memoryFlag))
+	// Scripts import modules relative to their own directory (see executeFile)
+	evaluator.SetModuleResolver(modules.Chain{modules.Stdlib, modules.Dir(".")})
+	finishTrace := setupTrace(*recordFlag, *replayFlag)
 
-	// Create options struct for REPL
-	options := repl.Options{
-		NoColor: *noColor,
-		Debug:   *debugFlag,
+	// The first positional argument names the script unless -f or -e is given,
+	// which lets scripts start with a "#!/usr/bin/env monke" line.
+	// Everything after the script (or after --) is passed to it.
+	scriptArgs := flag.Args()
+	scriptFile := *fileFlag
+	if scriptFile == "" && len(evalFlags) == 0 && len(scriptArgs) > 0 {
+		scriptFile, scriptArgs = scriptArgs[0], scriptArgs[1:]
 	}
+	evaluator.SetArgs(scriptArgs)
+
+	// Trace statement evaluation if requested
+	finishEvalTrace := setupEvalTrace(*traceEvalFlag)
 
 	// Execute a file if specified
-	if *fileFlag != "" {
-		executeFile(*fileFlag, *debugFlag)
-		return
+	if scriptFile != "" {
+		stopSignals := watchSignals(scriptFile)
+		status := executeFile(env, scriptFile, *debugFlag, !colorStderr(*noColor), *reportFlag)
+		stopSignals()
+		printBuiltinStats(*statsFlag)
+		// The outputs of the run are written whether or not it failed
+		status = max(status, writeHeapSnapshot(env, *heapFlag), finishEvalTrace(), finishTrace())
+		os.Exit(exitStatus(status))
 	}
 
-	// Evaluate an expression if specified
-	if *evalFlag != "" {
-		evaluateExpression(*evalFlag)
-		return
+	if (*printFlag || *loopFlag) && len(evalFlags) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: -n and -p require at least one -e expression")
+		os.Exit(1)
+	}
+
+	// Evaluate expressions if specified
+	if len(evalFlags) != 0 {
+		evaluator.SetWarningHandler(stderrWarnings("-e"))
+		plain := !colorStderr(*noColor)
+		status := 1
+		if programs, ok := parseExpressions(evalFlags, plain); ok {
+			stopSignals := watchSignals("-e")
+			if *printFlag || *loopFlag {
+				status = processLines(env, evalFlags, programs, *printFlag, plain)
+			} else {
+				status = evaluateExpressions(env, evalFlags, programs, plain)
+			}
+			stopSignals()
+		}
+		printBuiltinStats(*statsFlag)
+		status = max(status, writeHeapSnapshot(env, *heapFlag), finishEvalTrace(), finishTrace())
+		os.Exit(exitStatus(status))
 	}
 
 	// Start the REPL
-	repl.Start(usr.Username, options)
+	startREPL(env, *noColor, *debugFlag, int(memoryFlag), *replLogFlag)
+	printBuiltinStats(*statsFlag)
+	if status := max(writeHeapSnapshot(env, *heapFlag), finishEvalTrace(), finishTrace()); status != 0 {
+		os.Exit(status)
+	}
 }
 
-// executeFile reads and executes a Monkey script file
-func executeFile(filename string, debug bool) {
+// executeFile reads and executes a Monkey script file, writing a summary of
+// the run to stderr afterwards if report is set, whether or not it failed.
+// Parse and runtime errors are reported on stderr. It returns the status to
+// exit with, 1 if the script could not be read or failed.
+func executeFile(env *object.Environment, filename string, debug, noColor, report bool) int {
 	cleaned := filepath.Clean(filename)
 	absolute, err := filepath.Abs(cleaned)
 	if err != nil {
 		fmt.Printf("Error getting absolute path: %s\n", err)
-		os.Exit(1)
+		return 1
+	}
+	if debug {
+		fmt.Printf("Executing file: %s\n", absolute)
 	}
-	fmt.Printf("Executing file: %s\n", absolute)
 
 	// Read the file
 	//nolint:gosec // We're not reading user input here
 	content, err := os.ReadFile(absolute)
 	if err != nil {
 		fmt.Printf("Error reading file: %s\n", err)
-		os.Exit(1)
+		return 1
 	}
 
-	// Create environment
-	env := object.NewEnvironment()
+	// Parse and evaluate the file, reporting the parser's warnings before it runs
+	evaluator.SetModuleResolver(modules.Chain{modules.Stdlib, modules.Dir(filepath.Dir(absolute))})
+	source := string(content)
+	warnf := stderrWarnings(filename)
+	evaluator.SetWarningHandler(warnf)
+	var times runTimes
+	if report {
+		times = startReport()
+	}
+	result := pipeline.Run(source, env, pipeline.Middleware{
+		Program: func(result *pipeline.Result) bool {
+			times.markParsed()
+			for _, w := range result.Warnings {
+				warnf(w.Pos, w.Message)
+			}
+			return true
+		},
+	})
 
-	// Parse and evaluate the file
-	l := lexer.New(string(content))
-	p := parser.New(l)
-	program := p.ParseProgram()
+	status := 0
+	switch {
+	case len(result.Errors) != 0:
+		fmt.Fprint(os.Stderr, formatParseErrors(source, result.Errors, noColor))
+		status = 1
+	case result.Error() != nil:
+		fmt.Fprint(os.Stderr, formatRuntimeError(source, result.Error(), noColor))
+		status = 1
+	case debug && result.Value != nil:
+		// Print the result if in debug mode
+		fmt.Println(result.Value.Inspect())
+	}
+	if report {
+		writeReport(os.Stderr, times, result)
+	}
+	return status
+}
 
-	if len(p.Errors()) != 0 {
-		printParserErrors(p.Errors())
-		os.Exit(1)
+// stderrWarnings returns a warning handler that prints warnings about the named source to stderr.
+func stderrWarnings(name string) evaluator.WarningHandler {
+	return func(pos token.Position, message string) {
+		fmt.Fprintf(os.Stderr, "%s:%s: warning: %s\n", name, pos, message)
 	}
+}
 
-	evaluated := evaluator.Eval(program, env)
+// stringList is a flag.Value that collects every occurrence of a repeatable flag.
+type stringList []string
 
-	// Print the result if in debug mode
-	if debug && evaluated != nil {
-		fmt.Println(evaluated.Inspect())
-	}
+func (s *stringList) String() string { return strings.Join(*s, "; ") }
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
 }
 
-// evaluateExpression evaluates a single Monkey expression
-func evaluateExpression(expr string) {
-	// Create environment
-	env := object.NewEnvironment()
+// byteSize is a flag.Value holding a number of bytes, written with an optional
+// K, M or G suffix for binary kilo-, mega- and gigabytes, e.g. "64M".
+type byteSize int
 
-	// Parse and evaluate the expression
-	l := lexer.New(expr)
-	p := parser.New(l)
-	program := p.ParseProgram()
+func (b *byteSize) String() string { return strconv.Itoa(int(*b)) }
 
-	if len(p.Errors()) != 0 {
-		printParserErrors(p.Errors())
-		os.Exit(1)
+func (b *byteSize) Set(value string) error {
+	shift := 0
+	switch {
+	case strings.HasSuffix(value, "K"):
+		shift = 10
+	case strings.HasSuffix(value, "M"):
+		shift = 20
+	case strings.HasSuffix(value, "G"):
+		shift = 30
 	}
+	if shift != 0 {
+		value = value[:len(value)-1]
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 || n > math.MaxInt>>shift {
+		return errors.New("want a number of bytes, e.g. 1048576 or 64M")
+	}
+	*b = byteSize(n << shift)
+	return nil
+}
 
-	evaluated := evaluator.Eval(program, env)
+// parseExpressions parses each -e expression, reporting the parser's warnings
+// on stderr. Syntax errors are reported as they are for scripts, and reported
+// as false; no expression runs unless all of them parse.
+func parseExpressions(exprs []string, noColor bool) ([]*ast.Program, bool) {
+	warnf := stderrWarnings("-e")
+	programs := make([]*ast.Program, 0, len(exprs))
+	for _, expr := range exprs {
+		result := pipeline.Run(expr, nil, pipeline.Middleware{
+			Program: func(result *pipeline.Result) bool {
+				for _, w := range result.Warnings {
+					warnf(w.Pos, w.Message)
+				}
+				return false
+			},
+		})
+		if len(result.Errors) != 0 {
+			fmt.Fprint(os.Stderr, formatParseErrors(expr, result.Errors, noColor))
+			return nil, false
+		}
+		programs = append(programs, result.Program)
+	}
+	return programs, true
+}
+
+// evalPrograms runs sources in order in env and returns the value of the last
+// one. Each run evaluates the program parsed from its source beforehand, so
+// the caches in its syntax tree last from one line of stdin to the next. A
+// runtime error is reported on stderr as it is for scripts, and reported as false.
+func evalPrograms(env *object.Environment, sources []string, programs []*ast.Program, noColor bool) (object.Object, bool) {
+	var evaluated object.Object
+	for i, program := range programs {
+		result := pipeline.Run(sources[i], env, pipeline.Middleware{
+			Program: func(result *pipeline.Result) bool {
+				result.Program = program
+				return true
+			},
+		})
+		if errObj := result.Error(); errObj != nil {
+			fmt.Fprint(os.Stderr, formatRuntimeError(sources[i], errObj, noColor))
+			return nil, false
+		}
+		evaluated = result.Value
+	}
+	return evaluated, true
+}
+
+// evaluateExpressions evaluates the parsed -e expressions in a shared environment
+// and prints the result of the last one. It returns the status to exit with.
+func evaluateExpressions(env *object.Environment, sources []string, programs []*ast.Program, noColor bool) int {
+	evaluated, ok := evalPrograms(env, sources, programs, noColor)
+	if !ok {
+		return 1
+	}
 
 	// Print the result
 	if evaluated != nil {
 		fmt.Println(evaluated.Inspect())
 	}
+	return 0
+}
+
+// processLines evaluates the parsed -e expressions once per line of stdin,
+// with the line bound to `line` and its 1-based number to `line_number`.
+// If print is set, every result that is not null is printed. It returns the
+// status to exit with, stopping at the first line that fails.
+func processLines(env *object.Environment, sources []string, programs []*ast.Program, print, noColor bool) int {
+	scanner := bufio.NewScanner(stdin)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineLength)
+
+	for n := int64(1); scanner.Scan(); n++ {
+		env.Set("line", &object.String{Value: scanner.Text()})
+		env.Set("line_number", &object.Integer{Value: n})
+
+		evaluated, ok := evalPrograms(env, sources, programs, noColor)
+		if !ok {
+			return 1
+		}
+		if evaluated == nil || evaluated.Type() == object.NULL_OBJ {
+			continue
+		}
+		if print {
+			fmt.Println(evaluated.Inspect())
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading stdin: %s\n", err)
+		return 1
+	}
+	return 0
+}
+
+// setupTrace installs a recording or replaying random source in the evaluator.
+// The returned function must be called once the run is over; it flushes the
+// recorded trace, or reports whether the replayed run diverged from its trace,
+// and returns 1 if that failed.
+func setupTrace(recordPath, replayPath string) func() int {
+	switch {
+	case recordPath != "" && replayPath != "":
+		fmt.Fprintln(os.Stderr, "Error: --record and --replay cannot be used together")
+		os.Exit(1)
+
+	case recordPath != "":
+		//nolint:gosec // The path is supplied by the user on purpose
+		f, err := os.Create(recordPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating trace file: %s\n", err)
+			os.Exit(1)
+		}
+		rec, err := replay.NewRecorder(f)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing trace file: %s\n", err)
+			os.Exit(1)
+		}
+		evaluator.SetRandSource(replay.RecordSource(evaluator.NewRandSource(), rec))
+		evaluator.SetTimeInput(replay.RecordTime(rec))
+		stdin = replay.RecordReader(os.Stdin, rec)
+		return func() int {
+			err := rec.Flush()
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing trace file: %s\n", err)
+				return 1
+			}
+			return 0
+		}
+
+	case replayPath != "":
+		//nolint:gosec // The path is supplied by the user on purpose
+		f, err := os.Open(replayPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening trace file: %s\n", err)
+			os.Exit(1)
+		}
+		p, err := replay.NewPlayer(f)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading trace file: %s\n", err)
+			os.Exit(1)
+		}
+		evaluator.SetRandSource(p.Source())
+		evaluator.SetTimeInput(p.Time())
+		stdin = p.Reader()
+		return func() int {
+			_ = f.Close()
+			if p.Err() != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", p.Err())
+				return 1
+			}
+			return 0
+		}
+	}
+	return func() int { return 0 }
 }
 
-// printParserErrors prints parser errors to stderr
-func printParserErrors(errors []string) {
-	_, err := fmt.Fprintln(os.Stderr, "Parser errors:")
+// setupEvalTrace installs a JSONL statement tracer in the evaluator.
+// The returned function must be called once the run is over to flush the trace;
+// it returns 1 if that failed.
+func setupEvalTrace(path string) func() int {
+	if path == "" {
+		return func() int { return 0 }
+	}
+	//nolint:gosec // The path is supplied by the user on purpose
+	f, err := os.Create(path)
 	if err != nil {
-		panic(err)
+		fmt.Fprintf(os.Stderr, "Error creating trace file: %s\n", err)
+		os.Exit(1)
 	}
-	for _, msg := range errors {
-		_, err := fmt.Fprintln(os.Stderr, "\t"+msg)
+	tracer := trace.NewJSONL(f)
+	evaluator.SetTracer(tracer)
+	return func() int {
+		evaluator.SetTracer(nil)
+		err := tracer.Flush()
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
 		if err != nil {
-			panic(err)
+			fmt.Fprintf(os.Stderr, "Error writing trace file: %s\n", err)
+			return 1
 		}
+		return 0
+	}
+}
+
+// writeHeapSnapshot writes the object graph reachable from env to path, if one is given.
+// Files ending in .dot get a Graphviz graph; anything else gets JSON.
+// It returns 1 if the snapshot could not be written.
+func writeHeapSnapshot(env *object.Environment, path string) int {
+	if path == "" {
+		return 0
+	}
+	//nolint:gosec // The path is supplied by the user on purpose
+	f, err := os.Create(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating heap snapshot: %s\n", err)
+		return 1
+	}
+	snap := heap.Take(env)
+	if strings.EqualFold(filepath.Ext(path), ".dot") {
+		err = snap.WriteDOT(f)
+	} else {
+		err = snap.WriteJSON(f)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing heap snapshot: %s\n", err)
+		return 1
+	}
+	return 0
+}
+
+// printBuiltinStats prints the resource usage of each builtin to stderr, if enabled.
+func printBuiltinStats(enabled bool) {
+	if !enabled {
+		return
+	}
+	w := tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "BUILTIN\tCALLS\tTIME")
+	for _, s := range evaluator.BuiltinStats() {
+		fmt.Fprintf(w, "%s\t%d\t%v\n", s.Name, s.Calls, s.Time)
+	}
+	_ = w.Flush()
+}
+
+// colorStderr reports whether errors written to stderr should be colored:
+// stderr must be a terminal, and neither -no-color nor NO_COLOR may be set.
+func colorStderr(noColor bool) bool {
+	if noColor || os.Getenv("NO_COLOR") != "" {
+		return false
 	}
+	info, err := os.Stderr.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
 }
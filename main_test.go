@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMain runs the command itself instead of the tests when the test binary
+// is started by runMonke, so that exit statuses and output can be checked.
+func TestMain(m *testing.M) {
+	if os.Getenv("MONKE_TEST_MAIN") == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runMonke runs the command with args and stdin, returning its stdout,
+// stderr and exit status.
+func runMonke(t *testing.T, stdin string, args ...string) (string, string, int) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), "MONKE_TEST_MAIN=1", "NO_COLOR=1")
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		t.Fatalf("running %q: %v", args, err)
+	}
+	return stdout.String(), stderr.String(), cmd.ProcessState.ExitCode()
+}
+
+func TestEvalFlagErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		stdin string
+		args  []string
+	}{
+		{"expression", "", []string{"-e", `1 + "a"`}},
+		{"later expression", "", []string{"-e", "let x = 1", "-e", `x + "a"`}},
+		{"line loop", "a\nb\n", []string{"-n", "-e", `line + 1`}},
+		{"print loop", "a\n", []string{"-p", "-e", `line - 1`}},
+	}
+
+	for _, tt := range tests {
+		stdout, stderr, status := runMonke(t, tt.stdin, tt.args...)
+		if status != 1 {
+			t.Errorf("%s: expected exit status 1, got=%d", tt.name, status)
+		}
+		if stdout != "" {
+			t.Errorf("%s: expected nothing on stdout, got=%q", tt.name, stdout)
+		}
+		if !strings.Contains(stderr, "type mismatch") {
+			t.Errorf("%s: expected the error on stderr, got=%q", tt.name, stderr)
+		}
+	}
+}
+
+func TestEvalFlagParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"expression", []string{"-e", "let x = ;"}},
+		{"later expression", []string{"-e", "puts(1)", "-e", "let x = ;"}},
+		{"line loop", []string{"-n", "-e", "puts(line)", "-e", "let x = ;"}},
+	}
+
+	for _, tt := range tests {
+		stdout, stderr, status := runMonke(t, "a\n", tt.args...)
+		if status != 1 {
+			t.Errorf("%s: expected exit status 1, got=%d", tt.name, status)
+		}
+		if stdout != "" {
+			t.Errorf("%s: expected no expression to run, got stdout=%q", tt.name, stdout)
+		}
+		if !strings.Contains(stderr, "1. no prefix parse function for ; found (at 1:9)\n     let x = ;\n") {
+			t.Errorf("%s: expected the error with its source on stderr, got=%q", tt.name, stderr)
+		}
+	}
+}
+
+func TestEvalFlagResult(t *testing.T) {
+	stdout, stderr, status := runMonke(t, "", "-e", "let x = 2", "-e", "x * 21")
+	if status != 0 || stdout != "42\n" || stderr != "" {
+		t.Errorf("expected 42 and exit status 0, got stdout=%q stderr=%q status=%d", stdout, stderr, status)
+	}
+}
+
+func TestRecordFailingScript(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "fail.monkey")
+	source := `forall(gen_int(0, 1000), fn(x) { puts(x); true }, 5); 1 + "a";`
+	if err := os.WriteFile(script, []byte(source), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tracePath := filepath.Join(dir, "trace.bin")
+	evalTrace := filepath.Join(dir, "eval.jsonl")
+
+	recorded, stderr, status := runMonke(t, "", "-record", tracePath, "-trace-eval", evalTrace, script)
+	if status != 1 || !strings.Contains(stderr, "type mismatch") {
+		t.Fatalf("expected the script to fail, got status=%d stderr=%q", status, stderr)
+	}
+	for _, file := range []string{tracePath, evalTrace} {
+		if info, err := os.Stat(file); err != nil || info.Size() <= int64(len("MONKETR1")) {
+			t.Errorf("%s was not written by the failing run: %v", filepath.Base(file), err)
+		}
+	}
+
+	replayed, stderr, status := runMonke(t, "", "-replay", tracePath, script)
+	if status != 1 || !strings.Contains(stderr, "type mismatch") || strings.Contains(stderr, "diverged") {
+		t.Errorf("expected the replay to fail the same way, got status=%d stderr=%q", status, stderr)
+	}
+	if strings.Count(recorded, "\n") != 5 || replayed != recorded {
+		t.Errorf("replay printed other values. recorded=%q, replayed=%q", recorded, replayed)
+	}
+}
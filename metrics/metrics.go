@@ -0,0 +1,184 @@
+// Package metrics measures the size and complexity of Monke programs.
+//
+// A report counts the tokens of a program and the nodes of its AST by type,
+// and records how deeply its blocks nest and the cyclomatic complexity of each
+// function literal, so that the parts of a script worth splitting up stand out.
+//
+// Key components:
+//   - Measure: Parses a program and builds its Report
+//   - Report.WriteTo: Writes the report as aligned text
+package metrics
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dr8co/monke/ast"
+	"github.com/dr8co/monke/lexer"
+	"github.com/dr8co/monke/parser"
+	"github.com/dr8co/monke/token"
+)
+
+// Anonymous is the name given to function literals that are not bound with let or assignment.
+const Anonymous = "(anonymous)"
+
+// Function holds the metrics of a function literal.
+type Function struct {
+	Name string         // The name the literal is bound to, or Anonymous
+	Pos  token.Position // The position of the 'fn' token
+	// Complexity is one plus the number of decision points in the body: if,
+	// while, for, for-in and conditional expressions, the arms of match
+	// expressions and the ?? and ?. operators. Those of nested function
+	// literals count towards the nested functions only.
+	Complexity int
+}
+
+// Report holds the metrics of a program.
+type Report struct {
+	Tokens    int            // The number of tokens, not counting the end of input
+	Nodes     map[string]int // The number of AST nodes by type name, e.g. "Identifier"
+	MaxDepth  int            // The deepest nesting of blocks; top-level statements are at depth 0
+	Functions []Function     // The function literals in source order
+}
+
+// Measure parses source and computes its metrics. If the program does not
+// parse, it returns the parser errors instead.
+func Measure(source string) (*Report, []parser.Error) {
+	p := parser.New(lexer.New(source))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, p.DetailedErrors()
+	}
+
+	r := &Report{Tokens: countTokens(source), Nodes: make(map[string]int)}
+
+	// The stack holds the visited nodes that have not been left yet, and
+	// functions the indices in r.Functions of the enclosing function literals
+	var stack []ast.Node
+	var functions []int
+	depth := 0
+	ast.Inspect(program, func(node ast.Node) bool {
+		if node == nil {
+			switch stack[len(stack)-1].(type) {
+			case *ast.BlockStatement:
+				depth--
+			case *ast.FunctionLiteral:
+				functions = functions[:len(functions)-1]
+			}
+			stack = stack[:len(stack)-1]
+			return false
+		}
+
+		r.Nodes[strings.TrimPrefix(fmt.Sprintf("%T", node), "*ast.")]++
+		switch n := node.(type) {
+		case *ast.BlockStatement:
+			depth++
+			r.MaxDepth = max(r.MaxDepth, depth)
+		case *ast.FunctionLiteral:
+			functions = append(functions, len(r.Functions))
+			r.Functions = append(r.Functions, Function{Name: functionName(stack, n), Pos: n.Pos(), Complexity: 1})
+		case *ast.IfExpression, *ast.ConditionalExpression, *ast.WhileExpression, *ast.ForExpression,
+			*ast.ForInExpression, *ast.MatchArm:
+			addDecision(r, functions)
+		case *ast.InfixExpression:
+			if n.Operator == token.NULLISH {
+				addDecision(r, functions)
+			}
+		case *ast.IndexExpression:
+			if n.Optional {
+				addDecision(r, functions)
+			}
+		case *ast.SliceExpression:
+			if n.Optional {
+				addDecision(r, functions)
+			}
+		}
+		stack = append(stack, node)
+		return true
+	})
+	return r, nil
+}
+
+// addDecision counts a decision point towards the innermost enclosing function, if any.
+func addDecision(r *Report, functions []int) {
+	if len(functions) > 0 {
+		r.Functions[functions[len(functions)-1]].Complexity++
+	}
+}
+
+// functionName returns the name fn is bound to, given the nodes enclosing it.
+func functionName(stack []ast.Node, fn *ast.FunctionLiteral) string {
+	if len(stack) == 0 {
+		return Anonymous
+	}
+	switch parent := stack[len(stack)-1].(type) {
+	case *ast.LetStatement:
+		if parent.Value == fn && !parent.Destructures() {
+			return parent.Name.Value
+		}
+	case *ast.AssignStatement:
+		if parent.Value == fn {
+			return parent.Name.Value
+		}
+	case *ast.ClassStatement:
+		for _, method := range parent.Methods {
+			if method.Function == fn {
+				return parent.Name.Value + "." + method.Name.Value
+			}
+		}
+	}
+	return Anonymous
+}
+
+// countTokens returns the number of tokens in source, not counting the end of input.
+func countTokens(source string) int {
+	l := lexer.New(source)
+	n := 0
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		n++
+	}
+	return n
+}
+
+// WriteTo writes the report to w as aligned text: the totals, the node counts
+// from the most to the least frequent, and the functions.
+func (r *Report) WriteTo(w io.Writer) (int64, error) {
+	var s strings.Builder
+	tw := tabwriter.NewWriter(&s, 0, 0, 2, ' ', 0)
+
+	total := 0
+	for _, count := range r.Nodes {
+		total += count
+	}
+	fmt.Fprintf(tw, "Tokens:\t%d\n", r.Tokens)
+	fmt.Fprintf(tw, "Nodes:\t%d\n", total)
+	fmt.Fprintf(tw, "Max depth:\t%d\n", r.MaxDepth)
+	fmt.Fprintf(tw, "Functions:\t%d\n", len(r.Functions))
+
+	names := make([]string, 0, len(r.Nodes))
+	for name := range r.Nodes {
+		names = append(names, name)
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		return cmp.Or(cmp.Compare(r.Nodes[b], r.Nodes[a]), cmp.Compare(a, b))
+	})
+	fmt.Fprintln(tw, "\nNodes by type:")
+	for _, name := range names {
+		fmt.Fprintf(tw, "  %s\t%d\n", name, r.Nodes[name])
+	}
+
+	if len(r.Functions) > 0 {
+		fmt.Fprintln(tw, "\nComplexity by function:")
+		for _, f := range r.Functions {
+			fmt.Fprintf(tw, "  %s\t%s\t%d\n", f.Name, f.Pos, f.Complexity)
+		}
+	}
+	_ = tw.Flush()
+
+	n, err := io.WriteString(w, s.String())
+	return int64(n), err
+}
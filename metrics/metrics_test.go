@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMeasure(t *testing.T) {
+	input := `let fib = fn(n) {
+  if (n <= 1) { return n; }
+  fib(n - 1) + fib(n - 2)
+};
+let total = 0;
+for (x in [1, 2]) {
+  total = total + (fn(y) { y ?? 0 })(x);
+}`
+
+	r, errs := Measure(input)
+	if len(errs) != 0 {
+		t.Fatalf("unexpected parser errors: %v", errs)
+	}
+
+	if r.Tokens != 70 {
+		t.Errorf("wrong number of tokens. expected=70, got=%d", r.Tokens)
+	}
+	if r.MaxDepth != 2 {
+		t.Errorf("wrong max depth. expected=2, got=%d", r.MaxDepth)
+	}
+	counts := map[string]int{"LetStatement": 2, "FunctionLiteral": 2, "CallExpression": 3, "ForInExpression": 1}
+	for name, expected := range counts {
+		if r.Nodes[name] != expected {
+			t.Errorf("wrong number of %s nodes. expected=%d, got=%d", name, expected, r.Nodes[name])
+		}
+	}
+
+	expected := []Function{{Name: "fib", Complexity: 2}, {Name: Anonymous, Complexity: 2}}
+	if len(r.Functions) != len(expected) {
+		t.Fatalf("wrong number of functions. expected=%d, got=%d", len(expected), len(r.Functions))
+	}
+	for i, f := range r.Functions {
+		if f.Name != expected[i].Name || f.Complexity != expected[i].Complexity {
+			t.Errorf("functions[%d]: expected %s with complexity %d, got %s with %d",
+				i, expected[i].Name, expected[i].Complexity, f.Name, f.Complexity)
+		}
+	}
+	if pos := r.Functions[1].Pos.String(); pos != "7:20" {
+		t.Errorf("wrong position of the anonymous function. expected=7:20, got=%s", pos)
+	}
+}
+
+func TestMeasureParseError(t *testing.T) {
+	r, errs := Measure("let = 5;")
+	if r != nil || len(errs) == 0 {
+		t.Errorf("expected parser errors and no report, got report=%v errors=%v", r, errs)
+	}
+}
+
+func TestWriteTo(t *testing.T) {
+	r, _ := Measure("let id = fn(x) { x };")
+	var s strings.Builder
+	if _, err := r.WriteTo(&s); err != nil {
+		t.Fatal(err)
+	}
+	expected := `Tokens:     11
+Nodes:      8
+Max depth:  1
+Functions:  1
+
+Nodes by type:
+  Identifier           3
+  BlockStatement       1
+  ExpressionStatement  1
+  FunctionLiteral      1
+  LetStatement         1
+  Program              1
+
+Complexity by function:
+  id  1:10  1
+`
+	if s.String() != expected {
+		t.Errorf("wrong report.\nexpected:\n%s\ngot:\n%s", expected, s.String())
+	}
+}
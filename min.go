@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dr8co/monke/format"
+)
+
+// runMin implements "monke min", which prints a script in as few characters as possible.
+func runMin(args []string) int {
+	fs := flag.NewFlagSet("min", flag.ExitOnError)
+	rename := fs.Bool("rename", false, "Also replace the names local to functions with short ones")
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "Usage: %s min [flags] script\n\n", filepath.Base(os.Args[0]))
+		fmt.Fprintln(out, "Prints the script without comments and optional whitespace.")
+		fmt.Fprintln(out, "\nFlags:")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	//nolint:gosec // The path is supplied by the user on purpose
+	content, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading file: %s\n", err)
+		return 1
+	}
+	minified, err := format.Minify(string(content), *rename)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", fs.Arg(0), err)
+		return 1
+	}
+	fmt.Println(minified)
+	return 0
+}
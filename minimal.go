@@ -0,0 +1,39 @@
+//go:build monke_minimal
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/dr8co/monke/object"
+	"github.com/dr8co/monke/parser"
+	"github.com/dr8co/monke/pipeline"
+)
+
+// The monke_minimal build tag leaves the REPL, its terminal UI and "monke learn"
+// out of the binary, so that it builds without the Charm libraries, e.g. for
+// WebAssembly (GOOS=wasip1 GOARCH=wasm). Scripts, -e expressions and the other
+// commands work as usual; errors are reported without colors.
+
+// startREPL reports that the REPL is not part of this build.
+func startREPL(*object.Environment, bool, bool, int, string) {
+	fmt.Fprintln(os.Stderr, "Error: this build of monke has no REPL; run a script or use -e")
+	os.Exit(1)
+}
+
+// runLearn reports that "monke learn", which runs in the REPL, is not part of this build.
+func runLearn([]string) int {
+	fmt.Fprintln(os.Stderr, "Error: this build of monke has no REPL to run the lessons in")
+	return 1
+}
+
+// formatParseErrors formats the syntax errors of source, without colors.
+func formatParseErrors(source string, errors []parser.Error, _ bool) string {
+	return pipeline.FormatParseErrors(source, errors)
+}
+
+// formatRuntimeError formats a runtime error raised by source, without colors.
+func formatRuntimeError(source string, err *object.Error, _ bool) string {
+	return pipeline.FormatRuntimeError(source, err)
+}
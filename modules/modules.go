@@ -0,0 +1,228 @@
+// Package modules finds the source of the modules Monke programs import.
+//
+// An import statement names a module by a path, e.g. import "std/list" or
+// import "./util". A Resolver turns the path into the name of a module, which
+// identifies it across imports, and loads the source of the module by its
+// name. The evaluator asks the resolver set with evaluator.SetModuleResolver,
+// so hosts can serve modules from wherever they keep them, e.g. a database
+// or an HTTP server, by implementing Resolver.
+//
+// Key components:
+//   - Resolver: Resolves import paths and loads modules
+//   - FS: Modules stored as ".monkey" files, including vendored packages
+//   - Stdlib: The standard library embedded in the interpreter
+//   - Memory: Modules held in memory, for tests and embedding
+//   - Chain: Tries several resolvers in order
+package modules
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+// ErrNotFound is the error of resolvers that have no module for a path or a name.
+var ErrNotFound = errors.New("module not found")
+
+// Resolver resolves import paths to modules and loads their source.
+type Resolver interface {
+	// ResolvePath returns the name of the module imported as path by the
+	// module named from, which is "" for the program itself. Paths starting
+	// with "./" or "../" are relative to the importing module. It returns an
+	// error wrapping ErrNotFound if the resolver has no such module.
+	ResolvePath(from, path string) (string, error)
+
+	// Load returns the source of the module with the given name, as returned
+	// by ResolvePath. It returns an error wrapping ErrNotFound if the resolver
+	// has no such module.
+	Load(name string) (string, error)
+}
+
+// Extension is the file name extension of modules stored as files.
+const Extension = ".monkey"
+
+// VendorDir is the directory of a project holding the packages vendored by
+// "monke get", which FS searches for imports that are not relative.
+const VendorDir = "vendor"
+
+// StdPrefix starts the import paths of the modules of the standard library.
+const StdPrefix = "std/"
+
+// FS resolves modules stored as ".monkey" files in a file system. The module
+// imported as "lib/util" is "lib/util.monkey", or else "lib/util/util.monkey".
+// Paths that are not relative are also looked up in the vendor directory,
+// where "monke get" keeps the packages a project depends on. Module names are
+// the paths of the files, and relative paths may not leave the file system.
+type FS struct {
+	fsys fs.FS
+}
+
+// NewFS returns a resolver for the modules in fsys.
+func NewFS(fsys fs.FS) *FS {
+	return &FS{fsys: fsys}
+}
+
+// Dir returns a resolver for the modules in the directory root, usually the
+// directory of the program being run.
+func Dir(root string) *FS {
+	return NewFS(os.DirFS(root))
+}
+
+// ResolvePath implements Resolver.
+func (f *FS) ResolvePath(from, p string) (string, error) {
+	name, relative, err := join(from, p)
+	if err != nil {
+		return "", err
+	}
+	candidates := []string{name}
+	if !relative {
+		candidates = append(candidates, path.Join(VendorDir, name))
+	}
+	for _, c := range candidates {
+		for _, file := range []string{c + Extension, path.Join(c, path.Base(c)+Extension)} {
+			if info, err := fs.Stat(f.fsys, file); err == nil && info.Mode().IsRegular() {
+				return file, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrNotFound, p)
+}
+
+// Load implements Resolver.
+func (f *FS) Load(name string) (string, error) {
+	content, err := fs.ReadFile(f.fsys, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+//go:embed stdlib/*.monkey
+var stdlibFiles embed.FS
+
+// Stdlib resolves the modules of the standard library, which are embedded in
+// the interpreter and imported as "std/name" (e.g. import "std/list").
+// Their names are their import paths.
+var Stdlib Resolver = stdlib{}
+
+type stdlib struct{}
+
+// ResolvePath implements Resolver.
+func (stdlib) ResolvePath(from, p string) (string, error) {
+	name, _, err := join(from, p)
+	if err != nil {
+		return "", err
+	}
+	if _, err := stdlibFiles.Open(stdlibFile(name)); err != nil {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, p)
+	}
+	return name, nil
+}
+
+// Load implements Resolver.
+func (stdlib) Load(name string) (string, error) {
+	content, err := stdlibFiles.ReadFile(stdlibFile(name))
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return string(content), nil
+}
+
+// StdlibModules returns the names of the modules of the standard library,
+// sorted, e.g. to check that each of them loads.
+func StdlibModules() []string {
+	entries, err := stdlibFiles.ReadDir("stdlib")
+	if err != nil {
+		panic(err) // the directory is embedded, so it always exists
+	}
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = StdPrefix + strings.TrimSuffix(entry.Name(), Extension)
+	}
+	return names
+}
+
+// stdlibFile returns the embedded file holding the standard module name, or
+// "" if name is not in the standard library.
+func stdlibFile(name string) string {
+	rest, ok := strings.CutPrefix(name, StdPrefix)
+	if !ok || strings.Contains(rest, "/") {
+		return ""
+	}
+	return "stdlib/" + rest + Extension
+}
+
+// Memory resolves modules held in memory, mapping their names to their
+// source. Paths are resolved to the names as they are, or relative to the
+// importing module, so Memory{"util": "let double = fn(x) { x * 2 };"} serves
+// import "util". It is meant for tests and for hosts embedding the interpreter.
+type Memory map[string]string
+
+// ResolvePath implements Resolver.
+func (m Memory) ResolvePath(from, p string) (string, error) {
+	name, _, err := join(from, p)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := m[name]; !ok {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, p)
+	}
+	return name, nil
+}
+
+// Load implements Resolver.
+func (m Memory) Load(name string) (string, error) {
+	source, ok := m[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return source, nil
+}
+
+// Chain tries its resolvers in order, using the first that has the module.
+// Resolvers should name their modules differently, as a module is loaded by
+// the first resolver that has a module of its name.
+type Chain []Resolver
+
+// ResolvePath implements Resolver.
+func (c Chain) ResolvePath(from, p string) (string, error) {
+	for _, r := range c {
+		name, err := r.ResolvePath(from, p)
+		if !errors.Is(err, ErrNotFound) {
+			return name, err
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrNotFound, p)
+}
+
+// Load implements Resolver.
+func (c Chain) Load(name string) (string, error) {
+	for _, r := range c {
+		source, err := r.Load(name)
+		if !errors.Is(err, ErrNotFound) {
+			return source, err
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+}
+
+// join returns the module path p imported by the module named from, made
+// relative to the root of the modules, and whether p is a relative path.
+func join(from, p string) (string, bool, error) {
+	relative := strings.HasPrefix(p, "./") || strings.HasPrefix(p, "../")
+	name := path.Clean(p)
+	if relative {
+		name = path.Join(path.Dir(from), p)
+	}
+	if !fs.ValidPath(name) || name == "." {
+		return "", relative, fmt.Errorf("invalid module path %q", p)
+	}
+	return name, relative, nil
+}
@@ -0,0 +1,98 @@
+package modules
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.monkey":                               {Data: []byte(`import "lib/util";`)},
+		"lib/util.monkey":                           {Data: []byte(`import "./shapes";`)},
+		"lib/shapes/shapes.monkey":                  {Data: []byte("let area = 1;")},
+		"vendor/github.com/user/lib/lib.monkey":     {Data: []byte("let lib = 1;")},
+		"vendor/github.com/user/lib/extra.monkey":   {Data: []byte("let extra = 1;")},
+		"vendor/github.com/user/lib/README.md":      {Data: []byte("not a module")},
+		"github.com/user/local/local.monkey":        {Data: []byte("let local = 1;")},
+		"vendor/github.com/user/local/local.monkey": {Data: []byte("let vendored = 1;")},
+	}
+	r := NewFS(fsys)
+
+	tests := []struct {
+		from, path string
+		expected   string // The name of the module, or the error message
+	}{
+		{"", "lib/util", "lib/util.monkey"},
+		{"", "./lib/util", "lib/util.monkey"},
+		{"lib/util.monkey", "./shapes", "lib/shapes/shapes.monkey"},
+		{"lib/shapes/shapes.monkey", "../util", "lib/util.monkey"},
+		{"", "github.com/user/lib", "vendor/github.com/user/lib/lib.monkey"},
+		{"", "github.com/user/lib/extra", "vendor/github.com/user/lib/extra.monkey"},
+		{"", "github.com/user/local", "github.com/user/local/local.monkey"},
+		{"", "github.com/user/lib/README", "module not found: github.com/user/lib/README"},
+		{"", "./github.com/user/lib", "module not found: ./github.com/user/lib"},
+		{"lib/util.monkey", "../../main", `invalid module path "../../main"`},
+		{"", "/main", `invalid module path "/main"`},
+	}
+	for _, tt := range tests {
+		got, err := r.ResolvePath(tt.from, tt.path)
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tt.expected {
+			t.Errorf("ResolvePath(%q, %q) wrong. expected=%q, got=%q", tt.from, tt.path, tt.expected, got)
+		}
+	}
+
+	if source, err := r.Load("lib/shapes/shapes.monkey"); err != nil || source != "let area = 1;" {
+		t.Errorf("Load wrong. got=%q, %v", source, err)
+	}
+	if _, err := r.Load("lib/missing.monkey"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Load of a missing module does not fail with ErrNotFound. got=%v", err)
+	}
+}
+
+func TestStdlib(t *testing.T) {
+	if got := StdlibModules(); !slices.Equal(got, []string{"std/list", "std/strings"}) {
+		t.Errorf("StdlibModules() wrong. got=%q", got)
+	}
+	for _, path := range StdlibModules() {
+		name, err := Stdlib.ResolvePath("", path)
+		if err != nil || name != path {
+			t.Errorf("ResolvePath(%q) wrong. got=%q, %v", path, name, err)
+			continue
+		}
+		if source, err := Stdlib.Load(name); err != nil || source == "" {
+			t.Errorf("Load(%q) wrong. got=%q, %v", name, source, err)
+		}
+	}
+	if name, err := Stdlib.ResolvePath("std/list", "./strings"); err != nil || name != "std/strings" {
+		t.Errorf("relative import in the standard library wrong. got=%q, %v", name, err)
+	}
+	for _, path := range []string{"std/missing", "list", "std/stdlib/list"} {
+		if _, err := Stdlib.ResolvePath("", path); !errors.Is(err, ErrNotFound) {
+			t.Errorf("ResolvePath(%q) does not fail with ErrNotFound. got=%v", path, err)
+		}
+	}
+}
+
+func TestChain(t *testing.T) {
+	r := Chain{Stdlib, Memory{"util": "let u = 1;", "std/list": "let shadowed = 1;"}}
+
+	if name, err := r.ResolvePath("", "util"); err != nil || name != "util" {
+		t.Errorf("ResolvePath(%q) wrong. got=%q, %v", "util", name, err)
+	}
+	// The first resolver with the module wins
+	if source, err := r.Load("std/list"); err != nil || source == "let shadowed = 1;" {
+		t.Errorf("Load(%q) wrong. got=%q, %v", "std/list", source, err)
+	}
+	if _, err := r.ResolvePath("", "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ResolvePath of a missing module does not fail with ErrNotFound. got=%v", err)
+	}
+	// Errors other than a missing module stop the search
+	if _, err := r.ResolvePath("", "../util"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("ResolvePath of an invalid path wrong. got=%v", err)
+	}
+}
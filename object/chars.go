@@ -0,0 +1,89 @@
+package object
+
+import "unicode/utf8"
+
+// charStride is how many characters apart the offsets of a charIndex are.
+const charStride = 64
+
+// charIndex locates the characters of a string that is not ASCII, so that
+// finding one takes time independent of its position. It holds the offset in
+// bytes of every charStride-th character; the ones between are decoded from
+// the nearest offset before them.
+type charIndex struct {
+	count   int   // The number of characters
+	offsets []int // The offset of character i*charStride, for each i
+}
+
+// chars returns the index of the characters of s, building it on first use.
+// It returns nil for an ASCII string, whose characters are its bytes.
+func (s *String) chars() *charIndex {
+	if s.charsDone {
+		return s.charIndex
+	}
+	value := s.Flat()
+	s.charsDone = true
+
+	ascii := 0
+	for ascii < len(value) && value[ascii] < utf8.RuneSelf {
+		ascii++
+	}
+	if ascii == len(value) {
+		return nil
+	}
+
+	index := &charIndex{}
+	for offset := 0; offset < len(value); index.count++ {
+		if index.count%charStride == 0 {
+			index.offsets = append(index.offsets, offset)
+		}
+		if value[offset] < utf8.RuneSelf {
+			offset++
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(value[offset:])
+		offset += size
+	}
+	s.charIndex = index
+	return index
+}
+
+// CharLen returns the number of characters of the UTF-8 encoded string. A
+// byte that is not valid UTF-8 counts as one character.
+func (s *String) CharLen() int {
+	if index := s.chars(); index != nil {
+		return index.count
+	}
+	return len(s.Value)
+}
+
+// charOffset returns the offset in bytes of character i of s, or the length
+// of s in bytes if i is its number of characters.
+func (s *String) charOffset(i int) int {
+	index := s.chars()
+	if index == nil {
+		return i
+	}
+	if i >= index.count {
+		return len(s.Value)
+	}
+	offset := index.offsets[i/charStride]
+	for range i % charStride {
+		_, size := utf8.DecodeRuneInString(s.Value[offset:])
+		offset += size
+	}
+	return offset
+}
+
+// CharAt returns character i of the string, as a string of its own, for
+// 0 <= i < CharLen().
+func (s *String) CharAt(i int) string {
+	start := s.charOffset(i)
+	_, size := utf8.DecodeRuneInString(s.Value[start:])
+	return s.Value[start : start+size]
+}
+
+// CharSlice returns the characters of the string from start up to but not
+// including end, for 0 <= start <= end <= CharLen().
+func (s *String) CharSlice(start, end int) string {
+	return s.Value[s.charOffset(start):s.charOffset(end)]
+}
@@ -1,9 +1,50 @@
 package object
 
+import (
+	"slices"
+
+	"github.com/dr8co/monke/ast"
+)
+
 // Environment represents a scope in a program.
 type Environment struct {
 	store map[string]Object
 	outer *Environment
+
+	// mask summarizes the names bound in store (one bit per name, see nameBit),
+	// letting lookups skip scopes that cannot contain a name without hashing it.
+	mask uint64
+
+	// version is bumped on every Set and invalidates any LookupCache
+	// that points at this environment.
+	version uint64
+
+	// deferred holds the blocks registered with `defer` in this scope, in order.
+	deferred []Deferred
+
+	// strict enables the checks of the strict language mode; enclosed
+	// environments inherit it from their outer environment.
+	strict bool
+
+	// block marks the scope of a loop body, which hands deferred blocks
+	// to the enclosing function scope.
+	block bool
+}
+
+// Deferred is a block registered with `defer`, along with the environment
+// it was registered in and must be evaluated in.
+type Deferred struct {
+	Body *ast.BlockStatement
+	Env  *Environment
+}
+
+// LookupCache remembers the environment a name was last resolved in, so that
+// repeated lookups of the same identifier can skip the map access.
+// The zero value is an empty cache.
+type LookupCache struct {
+	env     *Environment
+	version uint64
+	value   Object
 }
 
 // NewEnvironment creates a new Environment with an empty store and no outer environment.
@@ -19,21 +60,139 @@ func NewEnvironment() *Environment {
 func NewEnclosedEnvironment(outer *Environment) *Environment {
 	env := NewEnvironment()
 	env.outer = outer
+	env.strict = outer.strict
 	return env
 }
 
+// NewBlockEnvironment creates an enclosed Environment for a block, such as the
+// body of a loop or a branch of an if expression.
+// Bindings made with let stay local to the block, while deferred blocks are
+// registered with the enclosing function or program scope.
+func NewBlockEnvironment(outer *Environment) *Environment {
+	env := NewEnclosedEnvironment(outer)
+	env.block = true
+	return env
+}
+
+// nameBit maps a name to one of 64 bits using its length and first and last bytes.
+// It is deliberately cheap; collisions only cost a map lookup.
+func nameBit(name string) uint64 {
+	if name == "" {
+		return 1
+	}
+	return 1 << ((uint(name[0]) + uint(name[len(name)-1]) + uint(len(name))) & 63)
+}
+
 // Get returns the value of the given variable name in the environment.
 // If the variable is not found, it looks in the outer environment, if any.
 func (e *Environment) Get(name string) (Object, bool) {
-	obj, ok := e.store[name]
-	if !ok && e.outer != nil {
-		obj, ok = e.outer.Get(name)
+	bit := nameBit(name)
+	for env := e; env != nil; env = env.outer {
+		if env.mask&bit == 0 {
+			continue
+		}
+		if obj, ok := env.store[name]; ok {
+			return obj, true
+		}
+	}
+	return nil, false
+}
+
+// GetCached behaves like Get, but consults and refreshes c.
+// Only bindings found in the outermost (global) environment are cached:
+// if that environment is reached and has not been modified since, the cached
+// value is returned without touching its store. Enclosed environments are
+// usually short-lived function scopes, so caching them would rarely pay off.
+func (e *Environment) GetCached(name string, c *LookupCache) (Object, bool) {
+	bit := nameBit(name)
+	for env := e; env != nil; env = env.outer {
+		if env == c.env && env.version == c.version {
+			return c.value, true
+		}
+		if env.mask&bit == 0 {
+			continue
+		}
+		if obj, ok := env.store[name]; ok {
+			if env.outer == nil {
+				c.env, c.version, c.value = env, env.version, obj
+			}
+			return obj, true
+		}
 	}
-	return obj, ok
+	return nil, false
 }
 
 // Set sets the value of the given variable name in the environment.
 func (e *Environment) Set(name string, val Object) Object {
 	e.store[name] = val
+	e.mask |= nameBit(name)
+	e.version++
 	return val
 }
+
+// Assign updates the value of an existing variable, in the innermost environment
+// that binds it. It reports false if the variable is not bound anywhere.
+func (e *Environment) Assign(name string, val Object) bool {
+	bit := nameBit(name)
+	for env := e; env != nil; env = env.outer {
+		if env.mask&bit == 0 {
+			continue
+		}
+		if _, ok := env.store[name]; ok {
+			env.Set(name, val)
+			return true
+		}
+	}
+	return false
+}
+
+// Names returns the names bound directly in this environment, in sorted order.
+// Bindings of outer environments are not included.
+func (e *Environment) Names() []string {
+	names := make([]string, 0, len(e.store))
+	for name := range e.store {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Outer returns the enclosing environment, or nil for the global environment.
+func (e *Environment) Outer() *Environment {
+	return e.outer
+}
+
+// Defer registers a block to run when the scope owning this environment exits.
+func (e *Environment) Defer(block *ast.BlockStatement) {
+	d := Deferred{Body: block, Env: e}
+	for e.block {
+		e = e.outer
+	}
+	e.deferred = append(e.deferred, d)
+}
+
+// TakeDeferred returns the blocks registered with Defer, in registration order,
+// and forgets them.
+func (e *Environment) TakeDeferred() []Deferred {
+	blocks := e.deferred
+	e.deferred = nil
+	return blocks
+}
+
+// Defined reports whether name is bound directly in this environment,
+// ignoring outer environments.
+func (e *Environment) Defined(name string) bool {
+	_, ok := e.store[name]
+	return ok
+}
+
+// SetStrict selects the strict (true) or legacy (false) language mode for code
+// evaluated in this environment and the environments created from it later.
+func (e *Environment) SetStrict(strict bool) {
+	e.strict = strict
+}
+
+// Strict reports whether code evaluated in this environment uses the strict language mode.
+func (e *Environment) Strict() bool {
+	return e.strict
+}
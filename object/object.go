@@ -16,17 +16,23 @@
 package object
 
 import (
+	"cmp"
 	"fmt"
 	"hash/fnv"
+	"maps"
+	"math/rand/v2"
+	"slices"
 	"strconv"
 	"strings"
 
 	"github.com/dr8co/monke/ast"
+	"github.com/dr8co/monke/token"
 )
 
 //nolint:revive
 const (
 	INTEGER_OBJ      = "INTEGER"
+	FLOAT_OBJ        = "FLOAT"
 	BOOLEAN_OBJ      = "BOOLEAN"
 	STRING_OBJ       = "STRING"
 	NULL_OBJ         = "NULL"
@@ -36,6 +42,11 @@ const (
 	BUILTIN_OBJ      = "BUILTIN"
 	ARRAY_OBJ        = "ARRAY"
 	HASH_OBJ         = "HASH"
+	GENERATOR_OBJ    = "GENERATOR"
+	BUILDER_OBJ      = "BUILDER"
+	RESOURCE_OBJ     = "RESOURCE"
+	BYTES_OBJ        = "BYTES"
+	CLASS_OBJ        = "CLASS"
 )
 
 // Type represents the type of object.
@@ -59,6 +70,24 @@ func (i *Integer) Type() Type { return INTEGER_OBJ }
 // Inspect returns a string representation of the object.
 func (i *Integer) Inspect() string { return strconv.FormatInt(i.Value, 10) }
 
+// Float represents a Monke floating-point value.
+type Float struct {
+	Value float64
+}
+
+// Type returns the type of the object.
+func (f *Float) Type() Type { return FLOAT_OBJ }
+
+// Inspect returns a string representation of the object.
+// Whole numbers keep a ".0" suffix, so floats are never shown as integers.
+func (f *Float) Inspect() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eIN") {
+		s += ".0"
+	}
+	return s
+}
+
 // Boolean represents a Monke boolean value.
 type Boolean struct {
 	Value bool
@@ -71,17 +100,25 @@ func (b *Boolean) Type() Type { return BOOLEAN_OBJ }
 func (b *Boolean) Inspect() string { return strconv.FormatBool(b.Value) }
 
 // String represents a Monke string value.
+// A string made by concatenating long strings is a rope until it is first
+// read, and its Value is only set then, so code that may see such strings
+// reads them with Flat and Len. The interp package flattens the values it
+// returns and passes to Go functions, so embedders can read Value.
 type String struct {
 	Value string
+	rope  *rope // The pieces of the string while it is a rope, or nil
 	// Cache for the hash key to avoid recalculating it
 	hashKey *HashKey
+	// Cache for the offsets of the characters, built on first use; nil if ASCII
+	charIndex *charIndex
+	charsDone bool
 }
 
 // Type returns the type of the object.
 func (s *String) Type() Type { return STRING_OBJ }
 
 // Inspect returns a string representation of the object.
-func (s *String) Inspect() string { return s.Value }
+func (s *String) Inspect() string { return s.Flat() }
 
 // Null represents a Monke null value.
 type Null struct{}
@@ -106,6 +143,8 @@ func (rv *ReturnValue) Inspect() string { return rv.Value.Inspect() }
 // Error represents a Monke error.
 type Error struct {
 	Message string
+	Pos     token.Position // Position of the innermost statement that raised the error, if known
+	Cause   error          // The Go error behind the error, such as a limit of the evaluator being hit, or nil
 }
 
 // Type returns the type of the object.
@@ -116,7 +155,9 @@ func (e *Error) Inspect() string { return "ERROR: " + e.Message }
 
 // Function represents a Monke function.
 type Function struct {
+	Name       string // Name the function was first bound to with let, or "" if anonymous
 	Parameters []*ast.Identifier
+	Rest       bool // The last parameter collects the remaining arguments into an array
 	Body       *ast.BlockStatement
 	Env        *Environment
 }
@@ -124,25 +165,53 @@ type Function struct {
 // Type returns the type of the object.
 func (f *Function) Type() Type { return FUNCTION_OBJ }
 
-// Inspect returns a string representation of the object.
+// Inspect returns a one-line summary of the function's signature,
+// e.g. "fn add(x, y) { ... }". The body is left out to keep closures readable.
 func (f *Function) Inspect() string {
 	var out strings.Builder
 	params := make([]string, 0, len(f.Parameters))
 
-	for _, p := range f.Parameters {
+	for i, p := range f.Parameters {
+		if f.Rest && i == len(f.Parameters)-1 {
+			params = append(params, "..."+p.String())
+			continue
+		}
 		params = append(params, p.String())
 	}
 
 	out.WriteString("fn")
+	if f.Name != "" {
+		out.WriteString(" ")
+		out.WriteString(f.Name)
+	}
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
-	out.WriteString(") {\n")
-	out.WriteString(f.Body.String())
-	out.WriteString("\n}")
+	out.WriteString(") { ... }")
 
 	return out.String()
 }
 
+// Class represents a class defined by a class statement. Calling it makes an
+// instance: a hash tagged with the name of the class, holding the values of
+// its fields.
+type Class struct {
+	Name    string
+	Fields  []*ast.Identifier
+	Methods map[string]*Function // Each takes the instance, self, before its own parameters
+}
+
+// Type returns the type of the object.
+func (c *Class) Type() Type { return CLASS_OBJ }
+
+// Inspect returns the name and fields of the class, e.g. "class Point(x, y)".
+func (c *Class) Inspect() string {
+	fields := make([]string, 0, len(c.Fields))
+	for _, field := range c.Fields {
+		fields = append(fields, field.Value)
+	}
+	return "class " + c.Name + "(" + strings.Join(fields, ", ") + ")"
+}
+
 // BuiltinFunction represents a Monke builtin function.
 type BuiltinFunction func(args ...Object) Object
 
@@ -181,6 +250,44 @@ func (a *Array) Inspect() string {
 	return out.String()
 }
 
+// Generator produces random values for property-based testing.
+type Generator struct {
+	Name     string                    // Description shown by Inspect, e.g. "gen_int(0, 10)"
+	Generate func(r *rand.Rand) Object // Produces a random value
+	Shrink   func(v Object) []Object   // Returns simpler candidates for a failing value, simplest first
+}
+
+// Type returns the type of the object.
+func (g *Generator) Type() Type { return GENERATOR_OBJ }
+
+// Inspect returns a string representation of the object.
+func (g *Generator) Inspect() string { return g.Name }
+
+// Builder accumulates a string piece by piece, so building a long string
+// takes time linear in its length rather than in the square of it.
+// Unlike other objects, a builder is changed in place by writes.
+type Builder struct {
+	strings.Builder
+}
+
+// Type returns the type of the object.
+func (b *Builder) Type() Type { return BUILDER_OBJ }
+
+// Inspect returns a string representation of the object, e.g. "builder(12 bytes)".
+func (b *Builder) Inspect() string { return fmt.Sprintf("builder(%d bytes)", b.Len()) }
+
+// Bytes is a sequence of bytes, such as the contents of a binary file. Like
+// strings, bytes are never changed in place.
+type Bytes struct {
+	Value []byte
+}
+
+// Type returns the type of the object.
+func (b *Bytes) Type() Type { return BYTES_OBJ }
+
+// Inspect returns a string representation of the object, e.g. `bytes("GIF\x01")`.
+func (b *Bytes) Inspect() string { return fmt.Sprintf("bytes(%q)", b.Value) }
+
 // HashKey represents a hash key.
 type HashKey struct {
 	Type  Type
@@ -214,7 +321,7 @@ func (s *String) HashKey() HashKey {
 
 	// Calculate the hash key
 	h := fnv.New64a()
-	_, err := h.Write([]byte(s.Value))
+	_, err := h.Write([]byte(s.Flat()))
 	if err != nil {
 		return HashKey{Type: ERROR_OBJ, Value: 0}
 	}
@@ -234,20 +341,55 @@ type HashPair struct {
 // Hash represents a Monke hash.
 type Hash struct {
 	Pairs map[HashKey]HashPair
+	Tag   string // User-defined type name attached with the `tag` builtin, or ""
+	Class *Class // The class the hash is an instance of, or nil
+}
+
+// sortedHashes makes Inspect list the pairs of hashes in key order.
+var sortedHashes bool
+
+// SetSortedHashes makes Inspect list the pairs of every hash in the order of
+// SortedPairs, rather than in the unspecified order of the underlying map.
+// It is meant to be called before evaluation starts, for reproducible output.
+func SetSortedHashes(sorted bool) {
+	sortedHashes = sorted
 }
 
 // Type returns the type of the object.
 func (h *Hash) Type() Type { return HASH_OBJ }
 
+// SortedPairs returns the pairs of the hash ordered by key: integers by value,
+// strings alphabetically, and keys of different types by type name.
+func (h *Hash) SortedPairs() []HashPair {
+	pairs := slices.Collect(maps.Values(h.Pairs))
+	slices.SortFunc(pairs, func(a, b HashPair) int {
+		if a.Key.Type() != b.Key.Type() {
+			return cmp.Compare(a.Key.Type(), b.Key.Type())
+		}
+		if x, ok := a.Key.(*Integer); ok {
+			return cmp.Compare(x.Value, b.Key.(*Integer).Value)
+		}
+		return strings.Compare(a.Key.Inspect(), b.Key.Inspect())
+	})
+	return pairs
+}
+
 // Inspect returns a string representation of the object.
 func (h *Hash) Inspect() string {
 	var out strings.Builder
 
 	pairs := make([]string, 0, len(h.Pairs))
-	for _, pair := range h.Pairs {
-		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
+	if sortedHashes {
+		for _, pair := range h.SortedPairs() {
+			pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
+		}
+	} else {
+		for _, pair := range h.Pairs {
+			pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
+		}
 	}
 
+	out.WriteString(h.Tag)
 	out.WriteString("{")
 	out.WriteString(strings.Join(pairs, ", "))
 	out.WriteString("}")
@@ -1,6 +1,12 @@
 package object
 
-import "testing"
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
 
 func TestStringHashKey(t *testing.T) {
 	hello1 := &String{Value: "Hello World"}
@@ -21,3 +27,153 @@ func TestStringHashKey(t *testing.T) {
 		t.Errorf("strings with different content have same hash keys")
 	}
 }
+
+func TestRope(t *testing.T) {
+	abc := Concat(Concat(&String{Value: "a"}, &String{Value: "b"}), &String{Value: "c"})
+	if abc.Len() != 3 {
+		t.Errorf("wrong length of the rope. expected=3, got=%d", abc.Len())
+	}
+	if abc.Value != "" {
+		t.Errorf("expected the rope not to be flattened before it is read, got Value=%q", abc.Value)
+	}
+	if abc.HashKey() != (&String{Value: "abc"}).HashKey() {
+		t.Errorf("rope and flat string with the same content have different hash keys")
+	}
+	if abc.Value != "abc" || abc.Len() != 3 {
+		t.Errorf("expected the rope to be flattened to %q, got %q", "abc", abc.Value)
+	}
+
+	// Ropes appended to in a loop are as deep as the number of pieces
+	s := &String{}
+	for range 100000 {
+		s = Concat(s, &String{Value: "xy"})
+	}
+	if got := s.Inspect(); len(got) != 200000 || got != strings.Repeat("xy", 100000) {
+		t.Errorf("wrong value of the deep rope, of length %d", len(got))
+	}
+}
+
+func TestStringChars(t *testing.T) {
+	inputs := []string{
+		"",
+		"hello",
+		"héllo",
+		"日本語",
+		"a\xffb",
+		strings.Repeat("é", charStride*2) + "xyz",
+		strings.Repeat("a", charStride+1) + strings.Repeat("🐒", charStride+1),
+	}
+
+	for _, input := range inputs {
+		// A byte that is not valid UTF-8 is one character, as it is to a range loop
+		var chars []string
+		for rest := input; rest != ""; {
+			_, size := utf8.DecodeRuneInString(rest)
+			chars, rest = append(chars, rest[:size]), rest[size:]
+		}
+
+		s := &String{Value: input}
+		if s.CharLen() != len(chars) {
+			t.Errorf("%q: wrong number of characters. expected=%d, got=%d", input, len(chars), s.CharLen())
+			continue
+		}
+		for i, char := range chars {
+			if got := s.CharAt(i); got != char {
+				t.Errorf("%q: wrong character %d. expected=%q, got=%q", input, i, char, got)
+			}
+		}
+		for _, bounds := range [][2]int{{0, len(chars)}, {len(chars) / 2, len(chars)}, {1, len(chars) - 1}} {
+			start, end := bounds[0], bounds[1]
+			if start > end {
+				continue
+			}
+			if got, expected := s.CharSlice(start, end), strings.Join(chars[start:end], ""); got != expected {
+				t.Errorf("%q: wrong slice [%d:%d]. expected=%q, got=%q", input, start, end, expected, got)
+			}
+		}
+	}
+
+	// The characters of a rope are counted once it is flattened
+	rope := Concat(&String{Value: "h\u00e9"}, &String{Value: "llo"})
+	if rope.CharLen() != 5 || rope.CharAt(1) != "é" || rope.CharSlice(1, 3) != "él" {
+		t.Errorf("wrong characters of the rope %q", rope.Flat())
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	element := Concat(&String{Value: "a"}, &String{Value: "b"})
+	value := Concat(&String{Value: "c"}, &String{Value: "d"})
+	hash := &Hash{Pairs: map[HashKey]HashPair{{Type: STRING_OBJ, Value: 1}: {Key: &String{Value: "k"}, Value: value}}}
+	array := &Array{Elements: []Object{element, hash}}
+	// Arrays may contain themselves
+	array.Elements = append(array.Elements, array)
+
+	Flatten(array)
+	if element.Value != "ab" || value.Value != "cd" {
+		t.Errorf("ropes not flattened. got=%q, %q", element.Value, value.Value)
+	}
+	Flatten(nil)
+}
+
+func TestFloatInspect(t *testing.T) {
+	tests := []struct {
+		value    float64
+		expected string
+	}{
+		{3.14, "3.14"},
+		{2, "2.0"},
+		{-0.5, "-0.5"},
+		{1e21, "1e+21"},
+	}
+
+	for _, tt := range tests {
+		f := &Float{Value: tt.value}
+		if got := f.Inspect(); got != tt.expected {
+			t.Errorf("Inspect() wrong for %v. expected=%q, got=%q", tt.value, tt.expected, got)
+		}
+	}
+}
+
+func TestSortedPairs(t *testing.T) {
+	hash := &Hash{Pairs: make(map[HashKey]HashPair)}
+	for _, key := range []Object{&String{Value: "b"}, &Integer{Value: 10}, &String{Value: "a"}, &Integer{Value: 9}, &Boolean{Value: true}} {
+		hash.Pairs[key.(Hashable).HashKey()] = HashPair{Key: key, Value: &Integer{Value: 0}}
+	}
+
+	var keys []string
+	for _, pair := range hash.SortedPairs() {
+		keys = append(keys, pair.Key.Inspect())
+	}
+	if got := strings.Join(keys, " "); got != "true 9 10 a b" {
+		t.Errorf("wrong order of keys. expected=%q, got=%q", "true 9 10 a b", got)
+	}
+
+	SetSortedHashes(true)
+	defer SetSortedHashes(false)
+	if got := hash.Inspect(); got != "{true: 0, 9: 0, 10: 0, a: 0, b: 0}" {
+		t.Errorf("Inspect() not sorted. got=%q", got)
+	}
+}
+
+func TestResourceCleanup(t *testing.T) {
+	closed := make(chan string, 1)
+	r := NewResource("file", "data.txt", func() error {
+		closed <- "data.txt"
+		return nil
+	})
+	if got := r.Inspect(); got != `file("data.txt")` {
+		t.Errorf("wrong inspection of an open resource. got=%s", got)
+	}
+
+	// Resources dropped while open are closed once the garbage collector notices
+	r = nil
+	runtime.GC()
+	select {
+	case name := <-closed:
+		if name != "data.txt" {
+			t.Errorf("wrong resource closed: %s", name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("expected the unreachable resource to be closed")
+	}
+}
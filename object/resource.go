@@ -0,0 +1,70 @@
+package object
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// Resource is a handle to a host resource, such as a file, a socket or a
+// database connection, which builtins provided by the host give to programs.
+// It is closed explicitly, or at the latest once the program drops the last
+// reference to it and the garbage collector notices.
+type Resource struct {
+	Kind  string // The kind of resource, e.g. "file"
+	Name  string // What the resource refers to, e.g. the path of the file
+	state *resourceState
+}
+
+// resourceState is the part of a resource that closing it needs. The cleanup
+// of a resource holds only this, since a cleanup that referred to the
+// resource itself would keep it reachable.
+type resourceState struct {
+	mu     sync.Mutex
+	close  func() error
+	closed bool
+}
+
+// NewResource returns an open resource that close releases. close is called
+// at most once: by Close, or by the garbage collector if the resource becomes
+// unreachable while it is still open, so that forgotten resources do not leak.
+// Closing explicitly releases it at a known point, and reports its error.
+func NewResource(kind, name string, close func() error) *Resource {
+	r := &Resource{Kind: kind, Name: name, state: &resourceState{close: close}}
+	runtime.AddCleanup(r, func(s *resourceState) { _ = s.closeOnce() }, r.state)
+	return r
+}
+
+// Type returns the type of the object.
+func (r *Resource) Type() Type { return RESOURCE_OBJ }
+
+// Inspect returns a string representation of the object, e.g. `file("data.txt")`,
+// or `closed file("data.txt")` once it is closed.
+func (r *Resource) Inspect() string {
+	if r.Closed() {
+		return fmt.Sprintf("closed %s(%q)", r.Kind, r.Name)
+	}
+	return fmt.Sprintf("%s(%q)", r.Kind, r.Name)
+}
+
+// Close closes the resource. Closing it again does nothing and returns nil.
+func (r *Resource) Close() error {
+	return r.state.closeOnce()
+}
+
+// Closed reports whether the resource has been closed.
+func (r *Resource) Closed() bool {
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+	return r.state.closed
+}
+
+func (s *resourceState) closeOnce() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	return s.close()
+}
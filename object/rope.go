@@ -0,0 +1,89 @@
+package object
+
+import "strings"
+
+// RopeThreshold is the length in bytes from which concatenating two strings
+// makes a rope rather than copying both into a new string.
+const RopeThreshold = 1024
+
+// rope is the value of a string made by concatenation, held as its two
+// pieces until it is read. The pieces may be ropes themselves.
+type rope struct {
+	left, right *String
+	length      int // The length of the concatenation in bytes
+}
+
+// Concat returns the concatenation of left and right as a rope, which refers
+// to both instead of copying their bytes. Repeatedly appending to a string
+// this way takes time linear in the number of pieces, and the bytes are only
+// copied once, when the value is first read with Flat.
+func Concat(left, right *String) *String {
+	return &String{rope: &rope{left: left, right: right, length: left.Len() + right.Len()}}
+}
+
+// Len returns the length of the string in bytes, without flattening it.
+func (s *String) Len() int {
+	if s.rope != nil {
+		return s.rope.length
+	}
+	return len(s.Value)
+}
+
+// Flat returns the value of the string. A rope is flattened first: its pieces
+// are copied into Value, and the string no longer refers to them.
+func (s *String) Flat() string {
+	if s.rope == nil {
+		return s.Value
+	}
+
+	var b strings.Builder
+	b.Grow(s.rope.length)
+	// Ropes built by appending in a loop are as deep as the number of
+	// pieces, so they are walked with a stack of their own
+	stack := []*String{s}
+	for len(stack) > 0 {
+		top := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if top.rope == nil {
+			b.WriteString(top.Value)
+			continue
+		}
+		stack = append(stack, top.rope.right, top.rope.left)
+	}
+
+	s.Value, s.rope = b.String(), nil
+	return s.Value
+}
+
+// Flatten flattens every rope in obj, including the strings held by its arrays
+// and hashes, so that the Value of each of its strings is set. It is meant for
+// values handed to code outside the interpreter, which may read Value directly.
+func Flatten(obj Object) {
+	flatten(obj, make(map[Object]bool))
+}
+
+// flatten flattens the ropes in obj, skipping the arrays and hashes in seen,
+// which may contain themselves.
+func flatten(obj Object, seen map[Object]bool) {
+	switch obj := obj.(type) {
+	case *String:
+		obj.Flat()
+	case *Array:
+		if seen[obj] {
+			return
+		}
+		seen[obj] = true
+		for _, el := range obj.Elements {
+			flatten(el, seen)
+		}
+	case *Hash:
+		if seen[obj] {
+			return
+		}
+		seen[obj] = true
+		for _, pair := range obj.Pairs {
+			flatten(pair.Key, seen)
+			flatten(pair.Value, seen)
+		}
+	}
+}
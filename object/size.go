@@ -0,0 +1,106 @@
+package object
+
+import "unsafe"
+
+// Size estimates the number of bytes obj occupies, excluding the objects it refers to.
+func Size(obj Object) int {
+	iface := int(unsafe.Sizeof(Object(nil)))
+	switch obj := obj.(type) {
+	case *Integer:
+		return int(unsafe.Sizeof(*obj))
+	case *Float:
+		return int(unsafe.Sizeof(*obj))
+	case *Boolean:
+		return int(unsafe.Sizeof(*obj))
+	case *String:
+		// The length of a rope counts the bytes of its pieces, which it would
+		// take once flattened
+		if obj.rope != nil {
+			return int(unsafe.Sizeof(*obj)+unsafe.Sizeof(*obj.rope)) + obj.Len()
+		}
+		return int(unsafe.Sizeof(*obj)) + len(obj.Value)
+	case *Error:
+		return int(unsafe.Sizeof(*obj)) + len(obj.Message)
+	case *Array:
+		return int(unsafe.Sizeof(*obj)) + cap(obj.Elements)*iface
+	case *Hash:
+		entry := int(unsafe.Sizeof(HashKey{}) + unsafe.Sizeof(HashPair{}))
+		return int(unsafe.Sizeof(*obj)) + len(obj.Pairs)*entry
+	case *Function:
+		return int(unsafe.Sizeof(*obj)) + cap(obj.Parameters)*int(unsafe.Sizeof(uintptr(0)))
+	case *ReturnValue:
+		return int(unsafe.Sizeof(*obj))
+	case *Builder:
+		return int(unsafe.Sizeof(*obj)) + obj.Cap()
+	case *Bytes:
+		return int(unsafe.Sizeof(*obj)) + cap(obj.Value)
+	default:
+		return iface
+	}
+}
+
+// Size estimates the number of bytes the environment occupies, excluding the
+// objects bound in it and its outer environment.
+func (e *Environment) Size() int {
+	size := int(unsafe.Sizeof(*e))
+	for name := range e.store {
+		size += len(name) + int(unsafe.Sizeof("")+unsafe.Sizeof(Object(nil)))
+	}
+	return size
+}
+
+// LiveSize estimates the number of bytes of the environments in roots, the
+// objects in objs, and everything reachable from them, counting each once.
+func LiveSize(roots []*Environment, objs ...Object) int {
+	seen := make(map[any]bool)
+	size := 0
+
+	var visitEnv func(env *Environment)
+	var visit func(obj Object)
+	visitEnv = func(env *Environment) {
+		for ; env != nil && !seen[env]; env = env.outer {
+			seen[env] = true
+			size += env.Size()
+			for _, val := range env.store {
+				visit(val)
+			}
+		}
+	}
+	visit = func(obj Object) {
+		if obj == nil || seen[obj] {
+			return
+		}
+		seen[obj] = true
+		size += Size(obj)
+		switch obj := obj.(type) {
+		case *Array:
+			for _, el := range obj.Elements {
+				visit(el)
+			}
+		case *Hash:
+			for _, pair := range obj.Pairs {
+				visit(pair.Key)
+				visit(pair.Value)
+			}
+			if obj.Class != nil {
+				visit(obj.Class)
+			}
+		case *Function:
+			visitEnv(obj.Env)
+		case *Class:
+			for _, method := range obj.Methods {
+				visit(method)
+			}
+		case *ReturnValue:
+			visit(obj.Value)
+		}
+	}
+
+	for _, env := range roots {
+		visitEnv(env)
+	}
+	for _, obj := range objs {
+		visit(obj)
+	}
+	return size
+}
@@ -0,0 +1,29 @@
+package parser_test
+
+import (
+	"testing"
+
+	"github.com/dr8co/monke/parsertest"
+)
+
+func TestGolden(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"let", "let x = 5;\nlet y: int = x;"},
+		{"precedence", "-a * b + c <= d == !e"},
+		{"function", "let add = fn(a: int, b) -> int { return a + b; };\nadd(1, ...rest);"},
+		{"if_else", "if (x < y) { x } else { y }"},
+		{"loops", "while (i < 3) { i = i + 1; }\nfor (let j = 0; j < 3; j = j + 1) { j }\nfor (k, v in h) { defer { k } }"},
+		{"collections", "[1, 2.5, \"three\", ...xs][0];\n{\"a\": 1, ...h, true: 2}?.a"},
+		{"pragma", "#pragma strict\nlet x = 1;"},
+		{"errors", "let = 1;\nlet y 2;"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsertest.Golden(t, tt.name, tt.input)
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/dr8co/monke/ast"
+	"github.com/dr8co/monke/token"
+)
+
+// parseMatchExpression parses "match (value) { pattern => body, ... }".
+// Arms whose body is an expression are separated by commas, which are
+// optional after a block.
+func (p *Parser) parseMatchExpression() ast.Expression {
+	expression := &ast.MatchExpression{Token: p.currentToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+	expression.Value = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.RPAREN) || !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		arm := p.parseMatchArm()
+		if arm == nil {
+			return nil
+		}
+		expression.Arms = append(expression.Arms, arm)
+
+		_, block := arm.Body.(*ast.BlockStatement)
+		if p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+		} else if !block && !p.peekTokenIs(token.RBRACE) {
+			p.peekError(token.COMMA)
+			return nil
+		}
+	}
+	p.nextToken()
+	return expression
+}
+
+// parseMatchArm parses "pattern => expression" or "pattern => { statements }".
+func (p *Parser) parseMatchArm() *ast.MatchArm {
+	pattern := p.parsePattern(make(map[string]bool))
+	if pattern == nil || !p.expectPeek(token.FAT_ARROW) {
+		return nil
+	}
+	arm := &ast.MatchArm{Token: p.currentToken, Pattern: pattern}
+
+	p.nextToken()
+	if p.currentTokenIs(token.LBRACE) {
+		arm.Body = p.parseBlockStatement()
+		return arm
+	}
+	stmt := &ast.ExpressionStatement{Token: p.currentToken}
+	if stmt.Expression = p.parseExpression(LOWEST); stmt.Expression == nil {
+		return nil
+	}
+	arm.Body = stmt
+	return arm
+}
+
+// parsePattern parses the pattern starting at the current token. bound holds
+// the names bound by the pattern so far, which may only be bound once.
+func (p *Parser) parsePattern(bound map[string]bool) ast.Pattern {
+	switch p.currentToken.Type {
+	case token.IDENT:
+		if p.peekTokenIs(token.LBRACE) {
+			tag := p.currentToken.Literal
+			p.nextToken()
+			return p.parseHashPattern(tag, bound)
+		}
+		if binding := p.parseBindingPattern(bound); binding != nil {
+			return binding
+		}
+		return nil
+	case token.INT, token.FLOAT, token.STRING, token.RAW_STRING, token.TRUE, token.FALSE:
+		if value := p.prefixParseFns[p.currentToken.Type](); value != nil {
+			return &ast.LiteralPattern{Value: value}
+		}
+		return nil
+	case token.MINUS:
+		if !p.peekTokenIs(token.INT) && !p.peekTokenIs(token.FLOAT) {
+			break
+		}
+		if value := p.parsePrefixExpression(); value != nil {
+			return &ast.LiteralPattern{Value: value}
+		}
+		return nil
+	case token.LBRACKET:
+		return p.parseArrayPattern(bound)
+	case token.LBRACE:
+		return p.parseHashPattern("", bound)
+	}
+	p.addError(p.currentToken.Position, fmt.Sprintf("Expected a pattern, got %s instead", p.currentToken.Type))
+	return nil
+}
+
+// parseBindingPattern parses the name at the current token as a pattern
+// binding it, reporting names the pattern binds already.
+func (p *Parser) parseBindingPattern(bound map[string]bool) *ast.BindingPattern {
+	binding := &ast.BindingPattern{Name: &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}}
+	if binding.Wildcard() {
+		return binding
+	}
+	if bound[binding.Name.Value] {
+		p.addError(binding.Pos(), fmt.Sprintf("%s is bound more than once in the pattern", binding.Name.Value))
+		return nil
+	}
+	bound[binding.Name.Value] = true
+	return binding
+}
+
+// parseArrayPattern parses "[pattern, ..., ...rest]", where the rest is optional.
+func (p *Parser) parseArrayPattern(bound map[string]bool) ast.Pattern {
+	pattern := &ast.ArrayPattern{Token: p.currentToken}
+
+	for !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+		if p.currentTokenIs(token.SPREAD) {
+			// The rest binds what is left, so it comes last
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			if pattern.Rest = p.parseBindingPattern(bound); pattern.Rest == nil || !p.expectPeek(token.RBRACKET) {
+				return nil
+			}
+			return pattern
+		}
+
+		element := p.parsePattern(bound)
+		if element == nil {
+			return nil
+		}
+		pattern.Elements = append(pattern.Elements, element)
+		if !p.peekTokenIs(token.RBRACKET) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+	return pattern
+}
+
+// parseHashPattern parses "{key: pattern, name, ...}", tagged with tag unless it is "".
+// Keys are string, integer or boolean literals, or names standing for strings.
+func (p *Parser) parseHashPattern(tag string, bound map[string]bool) ast.Pattern {
+	pattern := &ast.HashPattern{Token: p.currentToken, Tag: tag}
+	seen := make(map[string]bool)
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		var key ast.Expression
+		var value ast.Pattern
+		switch p.currentToken.Type {
+		case token.IDENT:
+			key = &ast.StringLiteral{Token: p.currentToken, Value: p.currentToken.Literal}
+			if p.peekTokenIs(token.COMMA) || p.peekTokenIs(token.RBRACE) {
+				// "{name}" binds the value of "name" to name
+				binding := p.parseBindingPattern(bound)
+				if binding == nil {
+					return nil
+				}
+				value = binding
+			}
+		case token.STRING, token.RAW_STRING, token.INT, token.TRUE, token.FALSE:
+			if key = p.prefixParseFns[p.currentToken.Type](); key == nil {
+				return nil
+			}
+		default:
+			p.addError(p.currentToken.Position, fmt.Sprintf("Expected a hash key, got %s instead", p.currentToken.Type))
+			return nil
+		}
+
+		id, display, _ := literalKey(key)
+		if seen[id] {
+			p.addError(key.Pos(), fmt.Sprintf("duplicate key %s in pattern", display))
+			return nil
+		}
+		seen[id] = true
+
+		if value == nil {
+			if !p.expectPeek(token.COLON) {
+				return nil
+			}
+			p.nextToken()
+			if value = p.parsePattern(bound); value == nil {
+				return nil
+			}
+		}
+		pattern.Keys = append(pattern.Keys, key)
+		pattern.Values = append(pattern.Values, value)
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+	return pattern
+}
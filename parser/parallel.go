@@ -0,0 +1,54 @@
+package parser
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/dr8co/monke/ast"
+	"github.com/dr8co/monke/lexer"
+)
+
+// File is a source parsed by ParseAll.
+type File struct {
+	Program  *ast.Program
+	Errors   []Error // The syntax errors, in the order they were found
+	Warnings []Error
+}
+
+// ParseAll lexes and parses each of sources with a parser of its own, and
+// returns the results in the order of sources. The sources are independent of
+// each other, so they are parsed on a pool of goroutines, one per CPU, and
+// the results are the same whatever order the parsers finish in.
+func ParseAll(sources []string) []File {
+	files := make([]File, len(sources))
+	workers := min(runtime.GOMAXPROCS(0), len(sources))
+	if workers <= 1 {
+		for i, source := range sources {
+			files[i] = parseFile(source)
+		}
+		return files
+	}
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Go(func() {
+			for i := range next {
+				files[i] = parseFile(sources[i])
+			}
+		})
+	}
+	for i := range sources {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return files
+}
+
+// parseFile parses source as ParseAll does.
+func parseFile(source string) File {
+	p := New(lexer.New(source))
+	program := p.ParseProgram()
+	return File{Program: program, Errors: p.DetailedErrors(), Warnings: p.Warnings()}
+}
@@ -16,8 +16,12 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"slices"
 	"strconv"
+	"strings"
 
 	"github.com/dr8co/monke/ast"
 	"github.com/dr8co/monke/lexer"
@@ -30,12 +34,21 @@ const (
 	// LOWEST represents the lowest possible precedence for parsing expressions in the syntax tree.
 	LOWEST
 
+	// TERNARY is the precedence for the conditional operator.
+	TERNARY // x ? y : z
+
+	// NULLISH is the precedence for the null-coalescing operator.
+	NULLISH // x ?? y
+
 	// EQUALS is the precedence for the equality operator.
 	EQUALS // ==
 
 	// LESSGREATER is the precedence for the less-than and greater-than operators.
 	LESSGREATER // > or <
 
+	// RANGE is the precedence for the range operators.
+	RANGE // 1..10 or 1..=10
+
 	// SUM is the precedence for the sum operator.
 	SUM // +
 
@@ -52,17 +65,41 @@ const (
 	INDEX // array[index]
 )
 
+// minInt64Magnitude is the digits of the smallest int64, without its sign.
+const minInt64Magnitude = "9223372036854775808"
+
+// digits returns the digits of a number literal without the underscores that
+// may separate them.
+func digits(literal string) string {
+	return strings.ReplaceAll(literal, "_", "")
+}
+
+// knownPragmas holds the names accepted in "#pragma" lines.
+var knownPragmas = map[string]bool{
+	"strict":          true,
+	"legacy":          true,
+	"ignore_newlines": true,
+}
+
 var precedences = map[token.Type]int{
 	token.EQ:       EQUALS,
 	token.NOT_EQ:   EQUALS,
 	token.LT:       LESSGREATER,
 	token.GT:       LESSGREATER,
+	token.LT_EQ:    LESSGREATER,
+	token.GT_EQ:    LESSGREATER,
+	token.RANGE:    RANGE,
+	token.RANGE_EQ: RANGE,
 	token.PLUS:     SUM,
 	token.MINUS:    SUM,
 	token.SLASH:    PRODUCT,
 	token.ASTERISK: PRODUCT,
 	token.LPAREN:   CALL,
 	token.LBRACKET: INDEX,
+	token.OPTIONAL: INDEX,
+	token.DOT:      INDEX,
+	token.NULLISH:  NULLISH,
+	token.QUESTION: TERNARY,
 }
 
 type (
@@ -70,10 +107,33 @@ type (
 	infixParseFn  func(ast.Expression) ast.Expression
 )
 
+// TokenSource supplies the tokens a Parser parses. *lexer.Lexer implements it;
+// other implementations can, for instance, replay a token stream rewritten by
+// a tool.
+type TokenSource interface {
+	// NextToken returns the next token, or EOF tokens once the input is over.
+	NextToken() token.Token
+	// Errors returns the errors found in the input so far.
+	Errors() []lexer.Error
+	// Pragmas returns the pragmas at the start of the input.
+	Pragmas() []string
+}
+
 // Parser represents a Monke parser.
 type Parser struct {
-	l      *lexer.Lexer
-	errors []string
+	l       TokenSource
+	errors  []string
+	details []Error // errors with their positions, parallel to errors
+	warns   []Error
+
+	lexErrors int // number of lexer errors already reported
+
+	// Line breaks end an expression before a "(" or "[" that starts a line,
+	// unless "#pragma ignore_newlines" turns the rule off. Between parentheses
+	// and square brackets the expression goes on; within the braces of blocks
+	// and hashes, marked true, the rule applies again.
+	newlines bool
+	brackets []bool
 
 	currentToken token.Token
 	peekToken    token.Token
@@ -82,26 +142,32 @@ type Parser struct {
 	infixParseFns  map[token.Type]infixParseFn
 }
 
-// New creates a new Parser with the given lexer.
+// New creates a new Parser with the given lexer, usually a *lexer.Lexer.
 // It initializes the parser, registers prefix and infix parsing functions,
 // and reads the first two tokens to set up currentToken and peekToken.
-func New(l *lexer.Lexer) *Parser {
+func New(l TokenSource) *Parser {
 	p := &Parser{
-		l:      l,
-		errors: []string{},
+		l:        l,
+		errors:   []string{},
+		newlines: !slices.Contains(l.Pragmas(), "ignore_newlines"),
 	}
 
 	p.prefixParseFns = make(map[token.Type]prefixParseFn)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.IF, p.parseIfExpression)
+	p.registerPrefix(token.WHILE, p.parseWhileExpression)
+	p.registerPrefix(token.FOR, p.parseForExpression)
+	p.registerPrefix(token.MATCH, p.parseMatchExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
+	p.registerPrefix(token.RAW_STRING, p.parseStringLiteral)
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
 	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
 
@@ -114,8 +180,16 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.registerInfix(token.LT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.GT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.RANGE, p.parseInfixExpression)
+	p.registerInfix(token.RANGE_EQ, p.parseInfixExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.OPTIONAL, p.parseOptionalIndexExpression)
+	p.registerInfix(token.DOT, p.parseFieldExpression)
+	p.registerInfix(token.NULLISH, p.parseInfixExpression)
+	p.registerInfix(token.QUESTION, p.parseConditionalExpression)
 
 	// Read two tokens, so curToken and peekToken are both set
 	p.nextToken()
@@ -140,37 +214,129 @@ func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{Token: p.currentToken, Value: p.currentTokenIs(token.TRUE)}
 }
 
+// Error is a parse error along with the position of the token it was reported at.
+type Error struct {
+	Pos     token.Position
+	Message string
+}
+
+// Error returns the message of the error, after its position if it is known.
+func (e Error) Error() string {
+	if e.Pos.Line == 0 {
+		return e.Message
+	}
+	return e.Pos.String() + ": " + e.Message
+}
+
+// ErrorList is the error of a program that does not parse, holding its syntax
+// errors in the order they were found. errors.As finds each of them as an Error.
+type ErrorList struct {
+	Errors []Error
+}
+
+// Error returns the first syntax error, and how many more there are.
+func (l *ErrorList) Error() string {
+	if len(l.Errors) == 0 {
+		return "no syntax errors"
+	}
+	msg := l.Errors[0].Error()
+	if n := len(l.Errors) - 1; n > 0 {
+		msg += fmt.Sprintf(" (and %d more)", n)
+	}
+	return msg
+}
+
+// Unwrap returns the syntax errors.
+func (l *ErrorList) Unwrap() []error {
+	errs := make([]error, len(l.Errors))
+	for i, err := range l.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Err returns the errors encountered during parsing as an *ErrorList, or nil
+// if parsing was successful.
+func (p *Parser) Err() error {
+	if len(p.details) == 0 {
+		return nil
+	}
+	return &ErrorList{Errors: p.details}
+}
+
 // Errors returns the list of errors encountered during parsing.
 // If the list is empty, parsing was successful.
 func (p *Parser) Errors() []string {
 	return p.errors
 }
 
+// DetailedErrors returns the errors encountered during parsing, with their positions.
+// It holds the same errors as Errors, in the same order.
+func (p *Parser) DetailedErrors() []Error {
+	return p.details
+}
+
+// Warnings returns the problems found during parsing that do not prevent the
+// program from running, such as duplicate keys in a hash literal.
+func (p *Parser) Warnings() []Error {
+	return p.warns
+}
+
+// addWarning records a parse warning reported at pos.
+func (p *Parser) addWarning(pos token.Position, msg string) {
+	p.warns = append(p.warns, Error{Pos: pos, Message: msg})
+}
+
+// addError records a parse error reported at pos.
+func (p *Parser) addError(pos token.Position, msg string) {
+	p.errors = append(p.errors, msg)
+	p.details = append(p.details, Error{Pos: pos, Message: msg})
+}
+
 func (p *Parser) peekError(t token.Type) {
 	msg := fmt.Sprintf("Expected next token to be %s, got %s instead",
 		t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	p.addError(p.peekToken.Position, msg)
 }
 
-func (p *Parser) peekPrecedence() int {
-	if p, ok := precedences[p.peekToken.Type]; ok {
+// Precedence returns the binding power of the infix operator t,
+// or LOWEST if t is not an infix operator.
+func Precedence(t token.Type) int {
+	if p, ok := precedences[t]; ok {
 		return p
 	}
 
 	return LOWEST
 }
 
-func (p *Parser) curPrecedence() int {
-	if p, ok := precedences[p.currentToken.Type]; ok {
-		return p
-	}
+func (p *Parser) peekPrecedence() int {
+	return Precedence(p.peekToken.Type)
+}
 
-	return LOWEST
+func (p *Parser) curPrecedence() int {
+	return Precedence(p.currentToken.Type)
 }
 
 func (p *Parser) nextToken() {
 	p.currentToken = p.peekToken
 	p.peekToken = p.l.NextToken()
+
+	switch p.currentToken.Type {
+	case token.LPAREN, token.LBRACKET:
+		p.brackets = append(p.brackets, false)
+	case token.LBRACE:
+		p.brackets = append(p.brackets, true)
+	case token.RPAREN, token.RBRACKET, token.RBRACE:
+		if len(p.brackets) > 0 {
+			p.brackets = p.brackets[:len(p.brackets)-1]
+		}
+	}
+
+	// Report the lexer's errors in order with the parser's own
+	for _, err := range p.l.Errors()[p.lexErrors:] {
+		p.addError(err.Pos, err.Message)
+	}
+	p.lexErrors = len(p.l.Errors())
 }
 
 // ParseProgram parses a complete Monke program and returns its AST representation.
@@ -179,6 +345,13 @@ func (p *Parser) nextToken() {
 func (p *Parser) ParseProgram() *ast.Program {
 	program := &ast.Program{}
 	program.Statements = []ast.Statement{}
+	program.Pragmas = p.l.Pragmas()
+
+	for _, pragma := range program.Pragmas {
+		if !knownPragmas[pragma] {
+			p.addError(token.Position{}, fmt.Sprintf("unknown pragma %q", pragma))
+		}
+	}
 
 	for !p.currentTokenIs(token.EOF) {
 		//nolint:staticcheck
@@ -198,6 +371,17 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.DEFER:
+		return p.parseDeferStatement()
+	case token.IMPORT:
+		return p.parseImportStatement()
+	case token.CLASS:
+		return p.parseClassStatement()
+	case token.IDENT:
+		if p.peekTokenIs(token.ASSIGN) {
+			return p.parseAssignStatement()
+		}
+		return p.parseExpressionStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -206,10 +390,43 @@ func (p *Parser) parseStatement() ast.Statement {
 func (p *Parser) parseLetStatement() *ast.LetStatement {
 	stmt := &ast.LetStatement{Token: p.currentToken}
 
+	switch {
+	case p.peekTokenIs(token.LBRACKET):
+		p.nextToken()
+		pattern, ok := p.parseArrayPattern(make(map[string]bool)).(*ast.ArrayPattern)
+		if !ok {
+			return nil
+		}
+		stmt.Pattern = pattern
+		return p.parseLetValue(stmt)
+	case p.peekTokenIs(token.LBRACE):
+		p.nextToken()
+		pattern, ok := p.parseHashPattern("", make(map[string]bool)).(*ast.HashPattern)
+		if !ok {
+			return nil
+		}
+		stmt.Pattern = pattern
+		return p.parseLetValue(stmt)
+	}
 	if !p.expectPeek(token.IDENT) {
 		return nil
 	}
 	stmt.Name = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		if stmt.Type = p.parseTypeName(); stmt.Type == nil {
+			return nil
+		}
+	} else if p.peekTokenIs(token.COMMA) {
+		if stmt.Names = p.parseLetNames(stmt.Name); stmt.Names == nil {
+			return nil
+		}
+	}
+	return p.parseLetValue(stmt)
+}
+
+// parseLetValue parses "= expression;", the end of the let statement stmt.
+func (p *Parser) parseLetValue(stmt *ast.LetStatement) *ast.LetStatement {
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
 	}
@@ -223,6 +440,42 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 	return stmt
 }
 
+// parseLetNames parses the names after the first of a destructuring let
+// statement, e.g. ", y" in "let x, y = f();", and returns all the names.
+// A name other than "_" may be bound only once.
+func (p *Parser) parseLetNames(first *ast.Identifier) []*ast.Identifier {
+	names := []*ast.Identifier{first}
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		name := &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+		for _, n := range names {
+			if n.Value == name.Value && name.Value != "_" {
+				p.addError(name.Pos(), "duplicate name "+name.Value+" in let statement")
+				return nil
+			}
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func (p *Parser) parseAssignStatement() *ast.AssignStatement {
+	stmt := &ast.AssignStatement{Token: p.currentToken}
+	stmt.Name = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+
+	p.nextToken() // the '=' token
+	p.nextToken()
+	stmt.Value = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
 func (p *Parser) expectPeek(t token.Type) bool {
 	if p.peekTokenIs(t) {
 		p.nextToken()
@@ -244,7 +497,139 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	stmt := &ast.ReturnStatement{Token: p.currentToken}
 	p.nextToken()
 
+	first := p.currentToken
 	stmt.ReturnValue = p.parseExpression(LOWEST)
+	// Several values are returned as an array of them
+	if p.peekTokenIs(token.COMMA) {
+		values := &ast.ArrayLiteral{Token: first, Elements: []ast.Expression{stmt.ReturnValue}}
+		for p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+			p.nextToken()
+			values.Elements = append(values.Elements, p.parseExpression(LOWEST))
+		}
+		stmt.ReturnValue = values
+		stmt.Multiple = true
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+func (p *Parser) parseDeferStatement() ast.Statement {
+	stmt := &ast.DeferStatement{Token: p.currentToken}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	stmt.Body = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+// parseImportStatement parses "import "path";". The path is a string literal,
+// so that imports can be found without evaluating the program.
+func (p *Parser) parseImportStatement() ast.Statement {
+	stmt := &ast.ImportStatement{Token: p.currentToken}
+
+	if !p.peekTokenIs(token.STRING) && !p.peekTokenIs(token.RAW_STRING) {
+		p.peekError(token.STRING)
+		return nil
+	}
+	p.nextToken()
+	stmt.Path = &ast.StringLiteral{Token: p.currentToken, Value: p.currentToken.Literal}
+	if stmt.Path.Value == "" {
+		p.addError(stmt.Path.Pos(), "empty import path")
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+// parseClassStatement parses "class Name(field, ...) { fn method(params) { body } ... }",
+// whose methods may be separated by semicolons. Fields and methods share their
+// names as keys of an instance, so a name may be given once, and none may be
+// self, which methods bind to the instance.
+func (p *Parser) parseClassStatement() ast.Statement {
+	stmt := &ast.ClassStatement{Token: p.currentToken}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+	names := make(map[string]bool)
+	checkName := func(ident *ast.Identifier) bool {
+		switch {
+		case ident.Value == "self":
+			p.addError(ident.Pos(), "self cannot be a field or parameter, since methods bind it to the instance")
+			return false
+		case names[ident.Value]:
+			p.addError(ident.Pos(), "duplicate name "+ident.Value+" in class "+stmt.Name.Value)
+			return false
+		}
+		names[ident.Value] = true
+		return true
+	}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	for !p.peekTokenIs(token.RPAREN) {
+		if len(stmt.Fields) > 0 && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		field := &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+		if !checkName(field) {
+			return nil
+		}
+		stmt.Fields = append(stmt.Fields, field)
+	}
+	p.nextToken()
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	for !p.peekTokenIs(token.RBRACE) {
+		if p.peekTokenIs(token.SEMICOLON) {
+			p.nextToken()
+			continue
+		}
+		if !p.expectPeek(token.FUNCTION) {
+			return nil
+		}
+		tok := p.currentToken
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		method := &ast.Method{Name: &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}}
+		if !checkName(method.Name) {
+			return nil
+		}
+		fn, ok := p.parseFunctionLiteral().(*ast.FunctionLiteral)
+		if !ok {
+			return nil
+		}
+		fn.Token = tok
+		for _, param := range fn.Parameters {
+			if param.Value == "self" {
+				checkName(param)
+				return nil
+			}
+		}
+		method.Function = fn
+		stmt.Methods = append(stmt.Methods, method)
+	}
+	p.nextToken()
 
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
@@ -270,7 +655,7 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 		return nil
 	}
 	leftExp := prefix()
-	for !p.peekTokenIs(token.SEMICOLON) && precedence < p.peekPrecedence() {
+	for !p.peekTokenIs(token.SEMICOLON) && precedence < p.peekPrecedence() && !p.lineEnds() {
 		infix := p.infixParseFns[p.peekToken.Type]
 		if infix == nil {
 			return leftExp
@@ -282,12 +667,40 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 	return leftExp
 }
 
+// lineEnds reports whether a line break before the peek token ends the
+// expression being parsed: a call or index must start on the line of its
+// operand, so that a line starting with "(" or "[" starts a new statement.
+func (p *Parser) lineEnds() bool {
+	if !p.newlines || !p.peekToken.Newline || !p.peekTokenIs(token.LPAREN) && !p.peekTokenIs(token.LBRACKET) {
+		return false
+	}
+	return len(p.brackets) == 0 || p.brackets[len(p.brackets)-1]
+}
+
 func (p *Parser) parseIntegerLiteral() ast.Expression {
 	lit := &ast.IntegerLiteral{Token: p.currentToken}
-	value, err := strconv.ParseInt(p.currentToken.Literal, 0, 64)
+	value, err := strconv.ParseInt(digits(p.currentToken.Literal), 0, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		msg := fmt.Sprintf("Integer literal %s overflows int64 (max %d); big integers are not supported yet",
+			p.currentToken.Literal, int64(math.MaxInt64))
+		p.addError(p.currentToken.Position, msg)
+		return nil
+	}
 	if err != nil {
 		msg := fmt.Sprintf("Could not parse %q as integer", p.currentToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.addError(p.currentToken.Position, msg)
+		return nil
+	}
+	lit.Value = value
+	return lit
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{Token: p.currentToken}
+	value, err := strconv.ParseFloat(digits(p.currentToken.Literal), 64)
+	if err != nil {
+		msg := fmt.Sprintf("Could not parse %q as float", p.currentToken.Literal)
+		p.addError(p.currentToken.Position, msg)
 		return nil
 	}
 	lit.Value = value
@@ -300,6 +713,16 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 		Operator: p.currentToken.Literal,
 	}
 
+	// The magnitude of the smallest int64 does not fit in an int64 itself,
+	// so "-9223372036854775808" is parsed as a single literal.
+	if expression.Operator == "-" && p.peekTokenIs(token.INT) && digits(p.peekToken.Literal) == minInt64Magnitude {
+		p.nextToken()
+		return &ast.IntegerLiteral{
+			Token: token.Token{Type: token.INT, Literal: "-" + p.currentToken.Literal, Position: expression.Token.Position},
+			Value: math.MinInt64,
+		}
+	}
+
 	p.nextToken()
 	expression.Right = p.parseExpression(PREFIX)
 
@@ -308,7 +731,7 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 
 func (p *Parser) noPrefixParseFnError(t token.Type) {
 	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	p.addError(p.currentToken.Position, msg)
 }
 
 func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
@@ -325,6 +748,26 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	return expression
 }
 
+// parseConditionalExpression parses "condition ? consequence : alternative".
+// The operator is right-associative, so "a ? b : c ? d : e" is
+// "a ? b : (c ? d : e)".
+func (p *Parser) parseConditionalExpression(condition ast.Expression) ast.Expression {
+	expression := &ast.ConditionalExpression{Token: p.currentToken, Condition: condition}
+
+	p.nextToken()
+	if expression.Consequence = p.parseExpression(LOWEST); expression.Consequence == nil {
+		return nil
+	}
+	if !p.expectPeek(token.COLON) {
+		return nil
+	}
+	p.nextToken()
+	if expression.Alternative = p.parseExpression(LOWEST); expression.Alternative == nil {
+		return nil
+	}
+	return expression
+}
+
 func (p *Parser) parseGroupedExpression() ast.Expression {
 	p.nextToken()
 	exp := p.parseExpression(LOWEST)
@@ -358,6 +801,15 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	if p.peekTokenIs(token.ELSE) {
 		p.nextToken()
 
+		if p.peekTokenIs(token.IF) {
+			p.nextToken()
+			alternative := p.parseIfExpression()
+			if alternative == nil {
+				return nil
+			}
+			expression.Alternative = alternative
+			return expression
+		}
 		if !p.expectPeek(token.LBRACE) {
 			return nil
 		}
@@ -366,6 +818,106 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	return expression
 }
 
+func (p *Parser) parseWhileExpression() ast.Expression {
+	expression := &ast.WhileExpression{Token: p.currentToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	expression.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	expression.Body = p.parseBlockStatement()
+	return expression
+}
+
+// parseForExpression parses "for (init; condition; post) { body }",
+// where each of the three clauses may be empty.
+func (p *Parser) parseForExpression() ast.Expression {
+	expression := &ast.ForExpression{Token: p.currentToken}
+	errCount := len(p.errors)
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	if p.currentTokenIs(token.IDENT) && (p.peekTokenIs(token.IN) || p.peekTokenIs(token.COMMA)) {
+		return p.parseForInExpression(expression.Token)
+	}
+	if !p.currentTokenIs(token.SEMICOLON) {
+		expression.Init = p.parseStatement()
+		if len(p.errors) > errCount {
+			return nil
+		}
+		// Let, assignment and expression statements consume their semicolon
+		if !p.currentTokenIs(token.SEMICOLON) {
+			p.addError(p.peekToken.Position, fmt.Sprintf("Expected next token to be %s, got %s instead",
+				token.SEMICOLON, p.peekToken.Type))
+			return nil
+		}
+	}
+
+	p.nextToken()
+	if !p.currentTokenIs(token.SEMICOLON) {
+		expression.Condition = p.parseExpression(LOWEST)
+		if !p.expectPeek(token.SEMICOLON) {
+			return nil
+		}
+	}
+
+	p.nextToken()
+	if !p.currentTokenIs(token.RPAREN) {
+		expression.Post = p.parseStatement()
+		if len(p.errors) > errCount || !p.expectPeek(token.RPAREN) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	expression.Body = p.parseBlockStatement()
+	return expression
+}
+
+// parseForInExpression parses the rest of a for-in loop, starting at its first name.
+func (p *Parser) parseForInExpression(tok token.Token) ast.Expression {
+	expression := &ast.ForInExpression{Token: tok}
+	expression.Value = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+
+	if p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		expression.Key = expression.Value
+		expression.Value = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+	}
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+
+	p.nextToken()
+	expression.Iterable = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.RPAREN) || !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	expression.Body = p.parseBlockStatement()
+	return expression
+}
+
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: p.currentToken}
 	block.Statements = []ast.Statement{}
@@ -389,7 +941,14 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 		return nil
 	}
 
-	lit.Parameters = p.parseFunctionParameters()
+	lit.Parameters, lit.ParamTypes, lit.Rest = p.parseFunctionParameters()
+
+	if p.peekTokenIs(token.ARROW) {
+		p.nextToken()
+		if lit.ReturnType = p.parseTypeName(); lit.ReturnType == nil {
+			return nil
+		}
+	}
 
 	if !p.expectPeek(token.LBRACE) {
 		return nil
@@ -399,49 +958,138 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 	return lit
 }
 
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
-	var identifiers []*ast.Identifier
+// parseFunctionParameters parses a parameter list and the optional type
+// annotation of each parameter. The types are nil if no parameter is annotated.
+// rest reports whether the last parameter is a rest parameter, "...name",
+// which may only come last.
+func (p *Parser) parseFunctionParameters() (identifiers, types []*ast.Identifier, rest bool) {
+	annotated := false
 
 	if p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
-		return identifiers
+		return identifiers, nil, false
 	}
-	p.nextToken()
 
-	ident := &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
-	identifiers = append(identifiers, ident)
-
-	for p.peekTokenIs(token.COMMA) {
-		p.nextToken()
+	for {
 		p.nextToken()
+		if p.currentTokenIs(token.SPREAD) {
+			if !p.expectPeek(token.IDENT) {
+				return nil, nil, false
+			}
+			rest = true
+		}
 		ident := &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
 		identifiers = append(identifiers, ident)
+
+		var typ *ast.Identifier
+		if p.peekTokenIs(token.COLON) {
+			p.nextToken()
+			if typ = p.parseTypeName(); typ == nil {
+				return nil, nil, false
+			}
+			annotated = true
+		}
+		types = append(types, typ)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		if rest {
+			p.addError(p.peekToken.Position, "rest parameter "+ident.Value+" must be the last parameter")
+			return nil, nil, false
+		}
+		p.nextToken()
 	}
 
 	if !p.expectPeek(token.RPAREN) {
+		return nil, nil, false
+	}
+	if !annotated {
+		types = nil
+	}
+	return identifiers, types, rest
+}
+
+// parseTypeName parses the type name of an annotation, after its ':' or '->'.
+// Type names are identifiers, or the 'fn' keyword for functions.
+func (p *Parser) parseTypeName() *ast.Identifier {
+	if p.peekTokenIs(token.FUNCTION) {
+		p.nextToken()
+	} else if !p.expectPeek(token.IDENT) {
 		return nil
 	}
-	return identifiers
+	return &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
 }
 
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	exp := &ast.CallExpression{Token: p.currentToken, Function: function}
-	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	exp.Arguments = p.parseExpressionList(token.RPAREN, p.parseArgument)
+
+	// Named arguments come last, and each parameter is named at most once
+	named := make(map[string]bool)
+	for _, arg := range exp.Arguments {
+		na, ok := arg.(*ast.NamedArgument)
+		switch {
+		case arg == nil:
+			return nil // The error is reported already
+		case !ok && len(named) != 0:
+			p.addError(arg.Pos(), "positional argument after named arguments")
+			return nil
+		case ok && named[na.Name.Value]:
+			p.addError(na.Pos(), "duplicate named argument "+na.Name.Value)
+			return nil
+		case ok:
+			named[na.Name.Value] = true
+		}
+	}
 	return exp
 }
 
+// parseArgument parses an argument of a call: an element, or a named
+// argument "name: value".
+func (p *Parser) parseArgument() ast.Expression {
+	if !p.currentTokenIs(token.IDENT) || !p.peekTokenIs(token.COLON) {
+		return p.parseElement()
+	}
+	arg := &ast.NamedArgument{Token: p.currentToken, Name: &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}}
+	p.nextToken()
+	p.nextToken()
+	if arg.Value = p.parseExpression(LOWEST); arg.Value == nil {
+		return nil
+	}
+	return arg
+}
+
 func (p *Parser) parseStringLiteral() ast.Expression {
 	return &ast.StringLiteral{Token: p.currentToken, Value: p.currentToken.Literal}
 }
 
 func (p *Parser) parseArrayLiteral() ast.Expression {
 	array := &ast.ArrayLiteral{Token: p.currentToken}
-	array.Elements = p.parseExpressionList(token.RBRACKET)
+	array.Elements = p.parseExpressionList(token.RBRACKET, p.parseElement)
 
 	return array
 }
 
-func (p *Parser) parseExpressionList(end token.Type) []ast.Expression {
+// parseElement parses an element of an array literal or argument list,
+// which may be a spread element.
+func (p *Parser) parseElement() ast.Expression {
+	if p.currentTokenIs(token.SPREAD) {
+		return p.parseSpreadElement()
+	}
+	return p.parseExpression(LOWEST)
+}
+
+func (p *Parser) parseSpreadElement() *ast.SpreadElement {
+	spread := &ast.SpreadElement{Token: p.currentToken}
+	p.nextToken()
+	spread.Value = p.parseExpression(LOWEST)
+	return spread
+}
+
+// parseExpressionList parses a comma-separated list of the items parsed by
+// item, closed by end.
+func (p *Parser) parseExpressionList(end token.Type, item func() ast.Expression) []ast.Expression {
 	var list []ast.Expression
 
 	if p.peekTokenIs(end) {
@@ -450,12 +1098,12 @@ func (p *Parser) parseExpressionList(end token.Type) []ast.Expression {
 	}
 
 	p.nextToken()
-	list = append(list, p.parseExpression(LOWEST))
+	list = append(list, item())
 
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		list = append(list, p.parseExpression(LOWEST))
+		list = append(list, item())
 	}
 
 	if !p.expectPeek(end) {
@@ -466,25 +1114,110 @@ func (p *Parser) parseExpressionList(end token.Type) []ast.Expression {
 }
 
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
-	exp := &ast.IndexExpression{Token: p.currentToken, Left: left}
-
+	tok := p.currentToken
 	p.nextToken()
-	exp.Index = p.parseExpression(LOWEST)
+	return p.parseIndexOrSlice(tok, left, false)
+}
 
+// parseIndexOrSlice parses what follows the '[' of an index expression, from
+// the current token up to the closing ']': an index as in "left[i]", or the
+// bounds of a slice as in "left[i:j]", either of which may be left out.
+func (p *Parser) parseIndexOrSlice(tok token.Token, left ast.Expression, optional bool) ast.Expression {
+	var start ast.Expression
+	if !p.currentTokenIs(token.COLON) {
+		start = p.parseExpression(LOWEST)
+		if !p.peekTokenIs(token.COLON) {
+			if !p.expectPeek(token.RBRACKET) {
+				return nil
+			}
+			return &ast.IndexExpression{Token: tok, Left: left, Index: start, Optional: optional}
+		}
+		p.nextToken()
+	}
+
+	exp := &ast.SliceExpression{Token: tok, Left: left, Start: start, Optional: optional}
+	if !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+		exp.End = p.parseExpression(LOWEST)
+	}
 	if !p.expectPeek(token.RBRACKET) {
 		return nil
 	}
 	return exp
 }
 
+// parseOptionalIndexExpression parses "left?.[index]", "left?.[start:end]"
+// and "left?.field", where the last is shorthand for "left?.["field"]".
+func (p *Parser) parseOptionalIndexExpression(left ast.Expression) ast.Expression {
+	exp := &ast.IndexExpression{Token: p.currentToken, Left: left, Optional: true}
+
+	switch p.peekToken.Type {
+	case token.IDENT:
+		p.nextToken()
+		exp.Index = &ast.StringLiteral{Token: p.currentToken, Value: p.currentToken.Literal}
+	case token.LBRACKET:
+		p.nextToken()
+		p.nextToken()
+		return p.parseIndexOrSlice(exp.Token, left, true)
+	default:
+		p.addError(p.peekToken.Position, fmt.Sprintf("Expected a field name or [ after ?., got %s instead", p.peekToken.Type))
+		return nil
+	}
+	return exp
+}
+
+// parseFieldExpression parses "left.field", shorthand for "left["field"]".
+func (p *Parser) parseFieldExpression(left ast.Expression) ast.Expression {
+	exp := &ast.IndexExpression{Token: p.currentToken, Left: left}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	exp.Index = &ast.StringLiteral{Token: p.currentToken, Value: p.currentToken.Literal}
+	return exp
+}
+
+// literalKey returns a string identifying the value of a literal hash key,
+// and how to show it in messages, or false if key is not a literal.
+func literalKey(key ast.Expression) (id, display string, ok bool) {
+	switch key := key.(type) {
+	case *ast.StringLiteral:
+		return "s" + key.Value, strconv.Quote(key.Value), true
+	case *ast.IntegerLiteral:
+		return "i" + strconv.FormatInt(key.Value, 10), key.String(), true
+	case *ast.Boolean:
+		return "b" + strconv.FormatBool(key.Value), key.String(), true
+	default:
+		return "", "", false
+	}
+}
+
 func (p *Parser) parseHashLiteral() ast.Expression {
 	hash := &ast.HashLiteral{Token: p.currentToken}
 	hash.Pairs = make(map[ast.Expression]ast.Expression)
+	var seen map[string]token.Position
 
 	for !p.peekTokenIs(token.RBRACE) {
 		p.nextToken()
+		if p.currentTokenIs(token.SPREAD) {
+			hash.Order = append(hash.Order, p.parseSpreadElement())
+			if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+				return nil
+			}
+			continue
+		}
 		key := p.parseExpression(LOWEST)
 
+		if id, display, ok := literalKey(key); ok {
+			if first, dup := seen[id]; dup {
+				p.addWarning(key.Pos(), fmt.Sprintf("duplicate key %s in hash literal (first used at %s)", display, first))
+			} else {
+				if seen == nil {
+					seen = make(map[string]token.Position)
+				}
+				seen[id] = key.Pos()
+			}
+		}
+
 		if !p.expectPeek(token.COLON) {
 			return nil
 		}
@@ -492,6 +1225,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		p.nextToken()
 		value := p.parseExpression(LOWEST)
 		hash.Pairs[key] = value
+		hash.Order = append(hash.Order, key)
 		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
 			return nil
 		}
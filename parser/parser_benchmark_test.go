@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/dr8co/monke/lexer"
@@ -114,3 +115,15 @@ func BenchmarkParseLargeProgram(b *testing.B) {
 	`
 	benchmarkParser(input, b)
 }
+
+// BenchmarkParseAll measures the performance of parsing many files at once
+func BenchmarkParseAll(b *testing.B) {
+	sources := make([]string, 64)
+	for i := range sources {
+		sources[i] = strings.Repeat("let f = fn(x) { if (x < 2) { x } else { f(x - 1) + f(x - 2) } };\n", 200)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ParseAll(sources)
+	}
+}
@@ -1,7 +1,10 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"slices"
 	"testing"
 
 	"github.com/dr8co/monke/ast"
@@ -78,6 +81,483 @@ func TestReturnStatements(t *testing.T) {
 	}
 }
 
+func TestDeferStatement(t *testing.T) {
+	input := `defer { puts(x); close(f) };`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statements. got=%d",
+			len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.DeferStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.DeferStatement. got=%T", program.Statements[0])
+	}
+	if len(stmt.Body.Statements) != 2 {
+		t.Fatalf("defer body does not contain 2 statements. got=%d", len(stmt.Body.Statements))
+	}
+	if got := stmt.String(); got != "defer { puts(x)close(f) }" {
+		t.Errorf("stmt.String() wrong. got=%q", got)
+	}
+
+	p = New(lexer.New("defer puts(x);"))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 {
+		t.Errorf("expected an error for defer without a block")
+	}
+}
+
+func TestImportStatement(t *testing.T) {
+	input := `import "std/list"; import ` + "`./util`"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain 2 statements. got=%d",
+			len(program.Statements))
+	}
+	for i, path := range []string{"std/list", "./util"} {
+		stmt, ok := program.Statements[i].(*ast.ImportStatement)
+		if !ok {
+			t.Fatalf("stmt not *ast.ImportStatement. got=%T", program.Statements[i])
+		}
+		if stmt.Path.Value != path {
+			t.Errorf("stmt.Path.Value not %q. got=%q", path, stmt.Path.Value)
+		}
+	}
+	if got := program.String(); got != `import "std/list";import "./util";` {
+		t.Errorf("program.String() wrong. got=%q", got)
+	}
+
+	for _, input := range []string{`import std;`, `import "";`, `import;`} {
+		p = New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("%q: expected a parser error", input)
+		}
+	}
+}
+
+func TestClassStatement(t *testing.T) {
+	input := "class Point(x, y) {\n  fn norm() { self.x * self.x + self.y * self.y }\n  fn add(other) { Point(self.x + other.x, self.y) };\n}\nclass Unit() {}"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain 2 statements. got=%d",
+			len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.ClassStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.ClassStatement. got=%T", program.Statements[0])
+	}
+	if stmt.Name.Value != "Point" || len(stmt.Fields) != 2 || len(stmt.Methods) != 2 {
+		t.Fatalf("wrong class. got name=%s, %d fields, %d methods", stmt.Name.Value, len(stmt.Fields), len(stmt.Methods))
+	}
+	want := "class Point(x, y) { fn norm()(((self.x) * (self.x)) + ((self.y) * (self.y)))" +
+		" fn add(other)Point(((self.x) + (other.x)), (self.y)) }"
+	if got := stmt.String(); got != want {
+		t.Errorf("stmt.String() wrong.\nexpected=%q\ngot=     %q", want, got)
+	}
+	if got := program.Statements[1].String(); got != "class Unit() { }" {
+		t.Errorf("empty class String() wrong. got=%q", got)
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"class Point(x, x) {}", "duplicate name x in class Point"},
+		{"class Point(x) { fn x() { 1 } }", "duplicate name x in class Point"},
+		{"class Point(self) {}", "self cannot be a field or parameter, since methods bind it to the instance"},
+		{"class Point(x) { fn add(self) { 1 } }", "self cannot be a field or parameter, since methods bind it to the instance"},
+		{"class Point(x) { let y = 1; }", "Expected next token to be FUNCTION, got LET instead"},
+		{"class (x) {}", "Expected next token to be IDENT, got ( instead"},
+	}
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if errs := p.Errors(); len(errs) == 0 || errs[0] != tt.expected {
+			t.Errorf("%q: wrong errors. expected first=%q, got=%q", tt.input, tt.expected, errs)
+		}
+	}
+}
+
+func TestIntegerLiteralRange(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // String() of the program, or the error message
+	}{
+		{"9223372036854775807", "9223372036854775807"},
+		{"-9223372036854775808", "-9223372036854775808"},
+		{"-9223372036854775808 - 1", "(-9223372036854775808 - 1)"},
+		{"9223372036854775808", "Integer literal 9223372036854775808 overflows int64 (max 9223372036854775807); big integers are not supported yet"},
+		{"-9223372036854775809", "Integer literal 9223372036854775809 overflows int64 (max 9223372036854775807); big integers are not supported yet"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			if got := program.String(); got != tt.expected {
+				t.Errorf("%q: program.String() wrong. expected=%q, got=%q", tt.input, tt.expected, got)
+			}
+			continue
+		}
+		if p.Errors()[0] != tt.expected {
+			t.Errorf("%q: wrong error. expected=%q, got=%q", tt.input, tt.expected, p.Errors()[0])
+		}
+	}
+}
+
+func TestDigitSeparators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any // The value of the literal, or the error message
+	}{
+		{"1_000_000", int64(1000000)},
+		{"3_141.592_65", 3141.59265},
+		{"-9_223_372_036_854_775_808", int64(math.MinInt64)},
+		{"1__000", "Misplaced _ in number 1__000; underscores may only separate digits"},
+		{"100_", "Misplaced _ in number 100_; underscores may only separate digits"},
+		{"1_.5", "Misplaced _ in number 1_; underscores may only separate digits"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if msg, ok := tt.expected.(string); ok {
+			if len(p.Errors()) == 0 || p.Errors()[0] != msg {
+				t.Errorf("%q: wrong errors. expected %q first, got=%q", tt.input, msg, p.Errors())
+			}
+			continue
+		}
+		checkParserErrors(t, p)
+
+		var value any
+		switch lit := program.Statements[0].(*ast.ExpressionStatement).Expression.(type) {
+		case *ast.IntegerLiteral:
+			value = lit.Value
+		case *ast.FloatLiteral:
+			value = lit.Value
+		}
+		if value != tt.expected {
+			t.Errorf("%q: wrong value. expected=%v, got=%v", tt.input, tt.expected, value)
+		}
+		if got := program.String(); got != tt.input {
+			t.Errorf("%q: the literal should keep its separators, got=%q", tt.input, got)
+		}
+	}
+}
+
+func TestTypeAnnotations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // String() of the program, or the first error message
+	}{
+		{"let x: int = 5;", "let x: int = 5;"},
+		{"let f: fn = fn(x) { x };", "let f: fn = fn(x)x;"},
+		{"fn(a: int, b) -> string { b }", "fn(a: int, b) -> string b"},
+		{"fn(a, b) -> Point { a }", "fn(a, b) -> Point a"},
+		{"fn() -> int { 1 }", "fn() -> int 1"},
+		{"let x: = 5;", "Expected next token to be IDENT, got = instead"},
+		{"fn(a: 1) { a }", "Expected next token to be IDENT, got INT instead"},
+		{"fn(a) -> { a }", "Expected next token to be IDENT, got { instead"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			if got := program.String(); got != tt.expected {
+				t.Errorf("%q: program.String() wrong. expected=%q, got=%q", tt.input, tt.expected, got)
+			}
+			continue
+		}
+		if p.Errors()[0] != tt.expected {
+			t.Errorf("%q: wrong error. expected=%q, got=%q", tt.input, tt.expected, p.Errors()[0])
+		}
+	}
+
+	fn := New(lexer.New("fn(a, b: int) {}")).ParseProgram().Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	if fn.ParamType(0) != nil || fn.ParamType(1).Value != "int" || fn.ReturnType != nil {
+		t.Errorf("wrong annotations. got=%v, return=%v", fn.ParamTypes, fn.ReturnType)
+	}
+}
+
+func TestAssignStatement(t *testing.T) {
+	input := "x = x + 1; y = 2"
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain 2 statements. got=%d", len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.AssignStatement)
+	if !ok {
+		t.Fatalf("stmt not *ast.AssignStatement. got=%T", program.Statements[0])
+	}
+	if stmt.Name.Value != "x" {
+		t.Errorf("stmt.Name.Value not 'x'. got=%s", stmt.Name.Value)
+	}
+	testInfixExpression(t, stmt.Value, "x", "+", 1)
+	if got := program.String(); got != "x = (x + 1);y = 2;" {
+		t.Errorf("program.String() wrong. got=%q", got)
+	}
+}
+
+func TestWhileExpression(t *testing.T) {
+	input := "while (i < 10) { i = i + 1 }"
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+	exp, ok := stmt.Expression.(*ast.WhileExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.WhileExpression. got=%T", stmt.Expression)
+	}
+	if !testInfixExpression(t, exp.Condition, "i", "<", 10) {
+		return
+	}
+	if len(exp.Body.Statements) != 1 {
+		t.Fatalf("body is not 1 statement. got=%d", len(exp.Body.Statements))
+	}
+	if _, ok := exp.Body.Statements[0].(*ast.AssignStatement); !ok {
+		t.Errorf("body statement is not ast.AssignStatement. got=%T", exp.Body.Statements[0])
+	}
+
+	for _, input := range []string{"while i < 10 { }", "while (true) i"} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("expected parser errors for %q", input)
+		}
+	}
+}
+
+func TestForInExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"for (x in xs) { puts(x) }", "for (x in xs) puts(x)"},
+		{"for (k, v in {\"a\": 1}) { k }", "for (k, v in {a:1}) k"},
+		{"for (x in rest(xs)) { }", "for (x in rest(xs)) "},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		if _, ok := stmt.Expression.(*ast.ForInExpression); !ok {
+			t.Fatalf("%q: expression is not ast.ForInExpression. got=%T", tt.input, stmt.Expression)
+		}
+		if got := program.String(); got != tt.expected {
+			t.Errorf("program.String() wrong. expected=%q, got=%q", tt.expected, got)
+		}
+	}
+
+	for _, input := range []string{"for (x in) { }", "for (k, in xs) { }", "for (k, v, w in xs) { }", "for (x in xs { }", "for (x in xs)"} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("expected parser errors for %q", input)
+		}
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"match (x) { 0 => a, -1.5 => b, \"s\" => c, true => { d } }", "match (x) { 0 => a, (-1.5) => b, s => c, true => d }"},
+		{"match (xs) { [] => 0, [x, _] => x, [first, ...rest] => first, }", "match (xs) { [] => 0, [x, _] => x, [first, ...rest] => first }"},
+		{"match (h) { {name: n, \"age\": 3} => n, Point {x, y} => x }", "match (h) { {name: n, age: 3} => n, Point {x: x, y: y} => x }"},
+		{"match (v) { [{id}, ..._] => { id } _ => null }", "match (v) { [{id: id}, ..._] => id, _ => null }"},
+		{"match (v) {}", "match (v) {  }"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		if _, ok := stmt.Expression.(*ast.MatchExpression); !ok {
+			t.Fatalf("%q: expression is not ast.MatchExpression. got=%T", tt.input, stmt.Expression)
+		}
+		if got := program.String(); got != tt.expected {
+			t.Errorf("program.String() wrong. expected=%q, got=%q", tt.expected, got)
+		}
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"match (v) { [x, x] => x }", "x is bound more than once in the pattern"},
+		{"match (v) { {a, a: b} => b }", "duplicate key \"a\" in pattern"},
+		{"match (v) { [...rest, x] => x }", "Expected next token to be ], got , instead"},
+		{"match (v) { x + 1 => x }", "Expected next token to be =>, got + instead"},
+		{"match (v) { 1 => a 2 => b }", "Expected next token to be ,, got INT instead"},
+		{"match (v) { f(x) => x }", "Expected next token to be =>, got ( instead"},
+		{"match (v) { {[1]: y} => y }", "Expected a hash key, got [ instead"},
+		{"match (v) { - => 1 }", "Expected a pattern, got - instead"},
+	}
+
+	for _, tt := range errorTests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("%q: expected first error %q, got=%q", tt.input, tt.expected, p.Errors())
+		}
+	}
+}
+
+func TestForExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"for (let i = 0; i < 10; i = i + 1) { puts(i) }", "for (let i = 0; (i < 10); i = (i + 1)) puts(i)"},
+		{"for (;;) { x }", "for (; ; ) x"},
+		{"for (i = 0; i < n;) { }", "for (i = 0; (i < n); ) "},
+		{"for (; f(); g()) { }", "for (; f(); g()) "},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		if _, ok := stmt.Expression.(*ast.ForExpression); !ok {
+			t.Fatalf("%q: expression is not ast.ForExpression. got=%T", tt.input, stmt.Expression)
+		}
+		if got := program.String(); got != tt.expected {
+			t.Errorf("program.String() wrong. expected=%q, got=%q", tt.expected, got)
+		}
+	}
+
+	for _, input := range []string{"for (let i = 0) { }", "for (;; i = 1 { }", "for (let = 0;;) { }", "for (;;)"} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("expected parser errors for %q", input)
+		}
+	}
+}
+
+func TestDetailedErrors(t *testing.T) {
+	input := "let x = 1;\nlet = 2;\n99999999999999999999;"
+
+	p := New(lexer.New(input))
+	p.ParseProgram()
+
+	details := p.DetailedErrors()
+	if len(details) != len(p.Errors()) {
+		t.Fatalf("DetailedErrors and Errors differ in length: %d vs %d", len(details), len(p.Errors()))
+	}
+	expected := []struct {
+		line, column int
+	}{
+		{2, 5}, // Expected next token to be IDENT
+		{2, 5}, // no prefix parse function for =
+		{3, 1}, // Integer literal overflows int64
+	}
+	if len(details) != len(expected) {
+		t.Fatalf("wrong number of errors. want=%d, got=%d: %v", len(expected), len(details), p.Errors())
+	}
+	for i, want := range expected {
+		if details[i].Message != p.Errors()[i] {
+			t.Errorf("errors[%d] message differs: %q vs %q", i, details[i].Message, p.Errors()[i])
+		}
+		if details[i].Pos.Line != want.line || details[i].Pos.Column != want.column {
+			t.Errorf("errors[%d] position wrong. want=%d:%d, got=%s", i, want.line, want.column, details[i].Pos)
+		}
+	}
+}
+
+func TestCommentErrors(t *testing.T) {
+	p := New(lexer.New("let x = 1; /* note */\nlet y = x; /* unfinished\nlet z = y;"))
+	program := p.ParseProgram()
+
+	if len(program.Statements) != 2 {
+		t.Errorf("wrong number of statements. want=2, got=%d", len(program.Statements))
+	}
+	details := p.DetailedErrors()
+	if len(details) != 1 {
+		t.Fatalf("wrong number of errors. want=1, got=%d: %v", len(details), p.Errors())
+	}
+	if details[0].Message != "Unterminated block comment; it needs a closing */" || details[0].Pos.String() != "2:12" {
+		t.Errorf("wrong error. got=%s: %s", details[0].Pos, details[0].Message)
+	}
+}
+
+func TestPragmas(t *testing.T) {
+	p := New(lexer.New("#pragma strict\nlet x = 1;"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	if len(program.Pragmas) != 1 || program.Pragmas[0] != "strict" {
+		t.Errorf("program.Pragmas wrong. got=%q", program.Pragmas)
+	}
+
+	p = New(lexer.New("#pragma fast\nlet x = 1;"))
+	p.ParseProgram()
+	if len(p.Errors()) != 1 || p.Errors()[0] != `unknown pragma "fast"` {
+		t.Errorf("expected an unknown pragma error, got=%q", p.Errors())
+	}
+}
+
+func TestHashLiteralDuplicateKeyWarnings(t *testing.T) {
+	input := `{"a": 1, "b": 2,
+  "a": 3, 1: 4, true: 5, 1: 6, x: 7, x: 8}`
+
+	p := New(lexer.New(input))
+	p.ParseProgram()
+	checkParserErrors(t, p)
+
+	expected := []struct {
+		message      string
+		line, column int
+	}{
+		{`duplicate key "a" in hash literal (first used at 1:2)`, 2, 3},
+		{`duplicate key 1 in hash literal (first used at 2:11)`, 2, 26},
+	}
+	warnings := p.Warnings()
+	if len(warnings) != len(expected) {
+		t.Fatalf("wrong number of warnings. want=%d, got=%d: %+v", len(expected), len(warnings), warnings)
+	}
+	for i, want := range expected {
+		if warnings[i].Message != want.message {
+			t.Errorf("warnings[%d] message wrong. want=%q, got=%q", i, want.message, warnings[i].Message)
+		}
+		if warnings[i].Pos.Line != want.line || warnings[i].Pos.Column != want.column {
+			t.Errorf("warnings[%d] position wrong. want=%d:%d, got=%s", i, want.line, want.column, warnings[i].Pos)
+		}
+	}
+}
+
 func checkParserErrors(t *testing.T, p *Parser) {
 	errors := p.Errors()
 	if len(errors) == 0 {
@@ -180,6 +660,36 @@ func TestIntegerLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestFloatLiteralExpression(t *testing.T) {
+	input := "3.25;"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	if len(program.Statements) != 1 {
+		t.Fatalf("program does not have enough statements. got=%d",
+			len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+
+	literal, ok := stmt.Expression.(*ast.FloatLiteral)
+	if !ok {
+		t.Fatalf("exp is not ast.FloatLiteral. got=%T", stmt.Expression)
+	}
+
+	if literal.Value != 3.25 {
+		t.Errorf("literal.Value not %g. got=%g", 3.25, literal.Value)
+	}
+	if literal.TokenLiteral() != "3.25" {
+		t.Errorf("literal.TokenLiteral got %s, want %s", literal.TokenLiteral(), "3.25")
+	}
+}
+
 func TestParsingPrefixExpressions(t *testing.T) {
 	prefixTests := []struct {
 		input    string
@@ -244,6 +754,8 @@ func TestParsingInfixExpressions(t *testing.T) {
 		{"5 < 5;", 5, "<", 5},
 		{"5 == 5;", 5, "==", 5},
 		{"5 != 5;", 5, "!=", 5},
+		{"5 <= 5;", 5, "<=", 5},
+		{"5 >= 5;", 5, ">=", 5},
 		{"foobar + barfoo;", "foobar", "+", "barfoo"},
 		{"foobar - barfoo;", "foobar", "-", "barfoo"},
 		{"foobar * barfoo;", "foobar", "*", "barfoo"},
@@ -330,6 +842,10 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"5 < 4 != 3 > 4",
 			"((5 < 4) != (3 > 4))",
 		},
+		{
+			"1 + 2 <= 3 == 4 >= 5 * 6",
+			"(((1 + 2) <= 3) == (4 >= (5 * 6)))",
+		},
 		{
 			"3 + 4 * 5 == 3 * 1 + 4 * 5",
 			"((3 + (4 * 5)) == ((3 * 1) + (4 * 5)))",
@@ -390,6 +906,70 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a * b[2], b[1], 2 * [1, 2][1])",
 			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
 		},
+		{
+			"a?.b?.[\"c\"] ?? d + 1 == 2",
+			"(((a?.b)?.[c]) ?? ((d + 1) == 2))",
+		},
+		{
+			"f(x)?.y[0]",
+			"((f(x)?.y)[0])",
+		},
+		{
+			"-person.address.city + f(x).n",
+			"((-((person.address).city)) + (f(x).n))",
+		},
+		{
+			"h.a?.b.c",
+			"(((h.a)?.b).c)",
+		},
+		{
+			"a[1:n - 1][0] + s[:2]",
+			"(((a[1:(n - 1)])[0]) + (s[:2]))",
+		},
+		{
+			"a?.[x ? 1 : 2:]",
+			"(a?.[(x ? 1 : 2):])",
+		},
+		{
+			"a ?? b ?? c",
+			"((a ?? b) ?? c)",
+		},
+		{
+			"a < b ? a + 1 : b * 2",
+			"((a < b) ? (a + 1) : (b * 2))",
+		},
+		{
+			"a ? b : c ? d : e",
+			"(a ? b : (c ? d : e))",
+		},
+		{
+			"a ? b ? c : d : e",
+			"(a ? (b ? c : d) : e)",
+		},
+		{
+			"a ?? b ? c : d ?? e",
+			"((a ?? b) ? c : (d ?? e))",
+		},
+		{
+			"f(a ? b : c, d)",
+			"f((a ? b : c), d)",
+		},
+		{
+			"h ? [a] : h?.[a]",
+			"(h ? [a] : (h?.[a]))",
+		},
+		{
+			"0..n + 1",
+			"(0 .. (n + 1))",
+		},
+		{
+			"a..=b == c..d",
+			"((a ..= b) == (c .. d))",
+		},
+		{
+			"-1..x[0]",
+			"((-1) .. (x[0]))",
+		},
 	}
 
 	for _, tt := range tests {
@@ -639,15 +1219,20 @@ func TestIfElseExpression(t *testing.T) {
 		return
 	}
 
-	if len(exp.Alternative.Statements) != 1 {
+	block, ok := exp.Alternative.(*ast.BlockStatement)
+	if !ok {
+		t.Fatalf("exp.Alternative is not ast.BlockStatement. got=%T", exp.Alternative)
+	}
+
+	if len(block.Statements) != 1 {
 		t.Errorf("exp.Alternative.Statements does not contain enough statements. got=%d\n",
-			len(exp.Alternative.Statements))
+			len(block.Statements))
 	}
 
-	alternative, ok := exp.Alternative.Statements[0].(*ast.ExpressionStatement)
+	alternative, ok := block.Statements[0].(*ast.ExpressionStatement)
 	if !ok {
 		t.Fatalf("Statements[0] is not ast.ExpressionStatement. got=%T",
-			exp.Alternative.Statements[0])
+			block.Statements[0])
 	}
 
 	if !testIdentifier(t, alternative.Expression, "y") {
@@ -655,6 +1240,106 @@ func TestIfElseExpression(t *testing.T) {
 	}
 }
 
+func TestElseIfExpression(t *testing.T) {
+	input := `if (x < y) { x } else if (x > y) { y } else { 0 }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.IfExpression. got=%T", stmt.Expression)
+	}
+
+	elseIf, ok := exp.Alternative.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("exp.Alternative is not ast.IfExpression. got=%T", exp.Alternative)
+	}
+	if !testInfixExpression(t, elseIf.Condition, "x", ">", "y") {
+		return
+	}
+	if _, ok := elseIf.Alternative.(*ast.BlockStatement); !ok {
+		t.Fatalf("elseIf.Alternative is not ast.BlockStatement. got=%T", elseIf.Alternative)
+	}
+
+	// The else-if branch needs a condition of its own
+	p = New(lexer.New("if (x) { 1 } else if { 2 }"))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 {
+		t.Errorf("expected an error for an else-if without a condition")
+	}
+}
+
+func TestConditionalExpression(t *testing.T) {
+	input := `x < y ? x : y`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements has not enough statements. got=%d\n",
+			len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+			program.Statements[0])
+	}
+
+	exp, ok := stmt.Expression.(*ast.ConditionalExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.ConditionalExpression. got=%T", stmt.Expression)
+	}
+
+	if !testInfixExpression(t, exp.Condition, "x", "<", "y") {
+		return
+	}
+	if !testIdentifier(t, exp.Consequence, "x") {
+		return
+	}
+	if !testIdentifier(t, exp.Alternative, "y") {
+		return
+	}
+
+	// The alternative is required
+	p = New(lexer.New("x ? y"))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 {
+		t.Errorf("expected an error for a conditional without an alternative")
+	}
+}
+
+func TestQuestionSuffix(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // String() of the program
+	}{
+		// A '?' after a name and spaces is the operator when an operand follows
+		{"c? a : b", "(c ? a : b)"},
+		{"c?a:b", "(c ? a : b)"},
+		{"c? 1 : empty?(xs)", "(c ? 1 : empty?(xs))"},
+		// Otherwise it ends the name
+		{"empty?(xs)", "empty?(xs)"},
+		{"let empty? = fn(xs) { len(xs) == 0 }; empty?([])", "let empty? = fn(xs)(len(xs) == 0);empty?([])"},
+		{"empty? == true ? 1 : 2", "((empty? == true) ? 1 : 2)"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if got := program.String(); got != tt.expected {
+			t.Errorf("%q: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
 func TestFunctionLiteralParsing(t *testing.T) {
 	input := `fn(x, y) { x + y; }`
 
@@ -706,10 +1391,13 @@ func TestFunctionParameterParsing(t *testing.T) {
 	tests := []struct {
 		input          string
 		expectedParams []string
+		rest           bool
 	}{
 		{input: "fn() {};", expectedParams: []string{}},
 		{input: "fn(x) {};", expectedParams: []string{"x"}},
 		{input: "fn(x, y, z) {};", expectedParams: []string{"x", "y", "z"}},
+		{input: "fn(...xs) {};", expectedParams: []string{"xs"}, rest: true},
+		{input: "fn(x, ...xs: array) {};", expectedParams: []string{"x", "xs"}, rest: true},
 	}
 
 	for _, tt := range tests {
@@ -729,6 +1417,171 @@ func TestFunctionParameterParsing(t *testing.T) {
 		for i, ident := range tt.expectedParams {
 			testLiteralExpression(t, function.Parameters[i], ident)
 		}
+		if function.Rest != tt.rest {
+			t.Errorf("%q: function.Rest wrong. want %t, got=%t", tt.input, tt.rest, function.Rest)
+		}
+	}
+
+	for _, input := range []string{"fn(...xs, y) {}", "fn(...) {}", "fn(... 1) {}"} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("%q: expected a parser error", input)
+		}
+	}
+}
+
+func TestNamedArguments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // String() of the program, or the error message
+	}{
+		{`makeUser(name: "a", age: 3)`, `makeUser(name: a, age: 3)`},
+		{`f(1, ...xs, by: x ? 1 : 2)`, `f(1, ...xs, by: (x ? 1 : 2))`},
+		{`f(a ? b : c)`, `f((a ? b : c))`},
+		{`f(x: 1, 2)`, "positional argument after named arguments"},
+		{`f(x: 1, ...xs)`, "positional argument after named arguments"},
+		{`f(x: 1, x: 2)`, "duplicate named argument x"},
+		{`[x: 1]`, "Expected next token to be ], got : instead"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		got := program.String()
+		if errs := p.Errors(); len(errs) != 0 {
+			got = errs[0]
+		}
+		if got != tt.expected {
+			t.Errorf("%q: wrong result. expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestMultipleValues(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string // String() of the program, or the error message
+	}{
+		{`return a, b + 1;`, `return a, (b + 1);`},
+		{`return f(a, b), [c];`, `return f(a, b), [c];`},
+		{`let x, y = f();`, `let x, y = f();`},
+		{`let _, err = f(); let _, y = g();`, `let _, err = f();let _, y = g();`},
+		{`let x, x = f();`, "duplicate name x in let statement"},
+		{`let x, 1 = f();`, "Expected next token to be IDENT, got INT instead"},
+		{`let x, y: int = f();`, "Expected next token to be =, got : instead"},
+		{`let [head, ...tail] = xs;`, `let [head, ...tail] = xs;`},
+		{`let [a, [b, _], {name}] = xs;`, `let [a, [b, _], {name: name}] = xs;`},
+		{`let [a, a] = xs;`, "a is bound more than once in the pattern"},
+		{`let [a, ...b, c] = xs;`, "Expected next token to be ], got , instead"},
+		{`let [a]: array = xs;`, "Expected next token to be =, got : instead"},
+		{`let {name, "age": a, pos: [x, y]} = p;`, `let {name: name, age: a, pos: [x, y]} = p;`},
+		{`let {} = p;`, `let {} = p;`},
+		{`let {a, b: a} = p;`, "a is bound more than once in the pattern"},
+		{`let {name, name: n} = p;`, `duplicate key "name" in pattern`},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		var got string
+		if errs := p.Errors(); len(errs) != 0 {
+			got = errs[0]
+		} else {
+			got = program.String()
+		}
+		if got != tt.expected {
+			t.Errorf("%q: wrong result. expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestLineBreaks(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string // String() of each statement
+	}{
+		{"let x = f\n(1)", []string{"let x = f;", "1"}},
+		{"f\n[1, 2]", []string{"f", "[1, 2]"}},
+		{"f(1)\n[0]", []string{"f(1)", "[0]"}},
+		{"xs[0]\n(a + b) * 2", []string{"(xs[0])", "((a + b) * 2)"}},
+		{"f /* a\ncomment */ (1)", []string{"f", "1"}},
+		{"let g = fn() { x\n(y) }", []string{"let g = fn()xy;"}}, // The body has two statements
+		// Other operators continue the expression on the next line
+		{"a\n- b\n+ c", []string{"((a - b) + c)"}},
+		{"person\n.name", []string{"(person.name)"}},
+		// Between parentheses and brackets the expression goes on
+		{"f(a\n(b),\n[1]\n[0])", []string{"f(a(b), ([1][0]))"}},
+		{"(f\n(1))", []string{"f(1)"}},
+		{"let s = `raw\nstring`[0]", []string{"let s = (raw\nstring[0]);"}},
+		{"#pragma ignore_newlines\nf\n(1)", []string{"f(1)"}},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		var got []string
+		for _, stmt := range program.Statements {
+			got = append(got, stmt.String())
+		}
+		if !slices.Equal(got, tt.expected) {
+			t.Errorf("%q: wrong statements. expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestErrorList(t *testing.T) {
+	p := New(lexer.New("let x = 1;"))
+	p.ParseProgram()
+	if err := p.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	p = New(lexer.New("let = 5;\nlet y 1;"))
+	p.ParseProgram()
+	err := p.Err()
+	var list *ErrorList
+	if !errors.As(err, &list) || len(list.Errors) != len(p.DetailedErrors()) {
+		t.Fatalf("expected an *ErrorList of every error, got=%v", err)
+	}
+	expected := fmt.Sprintf("1:5: Expected next token to be IDENT, got = instead (and %d more)", len(list.Errors)-1)
+	if err.Error() != expected {
+		t.Errorf("Error wrong. expected=%q, got=%q", expected, err.Error())
+	}
+	var first Error
+	if !errors.As(err, &first) || first != list.Errors[0] {
+		t.Errorf("errors.As does not find the first error. got=%v", first)
+	}
+}
+
+func TestParseAll(t *testing.T) {
+	sources := make([]string, 50)
+	for i := range sources {
+		sources[i] = fmt.Sprintf("let x%d = %d * 2;", i, i)
+	}
+	sources[7] = "let = 5;"
+	sources[9] = `{"a": 1, "a": 2}`
+
+	files := ParseAll(sources)
+	if len(files) != len(sources) {
+		t.Fatalf("wrong number of files. expected=%d, got=%d", len(sources), len(files))
+	}
+	for i, file := range files {
+		p := New(lexer.New(sources[i]))
+		program := p.ParseProgram()
+		if len(file.Errors) == 0 && file.Program.String() != program.String() {
+			t.Errorf("file %d: wrong program. expected=%q, got=%q", i, program.String(), file.Program.String())
+		}
+		if !slices.Equal(file.Errors, p.DetailedErrors()) || !slices.Equal(file.Warnings, p.Warnings()) {
+			t.Errorf("file %d: wrong errors or warnings. got=%v, %v", i, file.Errors, file.Warnings)
+		}
+	}
+	if len(files[7].Errors) == 0 || len(files[9].Warnings) == 0 {
+		t.Errorf("errors and warnings of the files were lost")
+	}
+	if files := ParseAll(nil); len(files) != 0 {
+		t.Errorf("expected no files, got=%d", len(files))
 	}
 }
 
@@ -825,21 +1678,29 @@ func TestCallExpressionParameterParsing(t *testing.T) {
 }
 
 func TestStringLiteralExpression(t *testing.T) {
-	input := `"hello world";`
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"hello world";`, "hello world"},
+		{"`a \"raw\"\nstring`;", "a \"raw\"\nstring"},
+	}
 
-	l := lexer.New(input)
-	p := New(l)
-	program := p.ParseProgram()
-	checkParserErrors(t, p)
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
 
-	stmt := program.Statements[0].(*ast.ExpressionStatement)
-	literal, ok := stmt.Expression.(*ast.StringLiteral)
-	if !ok {
-		t.Fatalf("exp not *ast.StringLiteral. got=%T", stmt.Expression)
-	}
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.StringLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.StringLiteral. got=%T", stmt.Expression)
+		}
 
-	if literal.Value != "hello world" {
-		t.Errorf("literal.Value not %q. got=%q", "hello world", literal.Value)
+		if literal.Value != tt.expected {
+			t.Errorf("literal.Value not %q. got=%q", tt.expected, literal.Value)
+		}
 	}
 }
 
@@ -885,6 +1746,36 @@ func TestParsingEmptyArrayLiterals(t *testing.T) {
 	}
 }
 
+func TestParsingSpreadElements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1, ...xs, 5]", "[1, ...xs, 5]"},
+		{"f(...args, 1)", "f(...args, 1)"},
+		{`{"a": 1, ...defaults, "b": 2}`, "{a:1, ...defaults, b:2}"},
+		{"[...f(x)[0]]", "[...(f(x)[0])]"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if got := program.String(); got != tt.expected {
+			t.Errorf("program.String() wrong. expected=%q, got=%q", tt.expected, got)
+		}
+	}
+
+	for _, input := range []string{"...xs", "[...]", `{...}`, `{...a: 1}`} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("expected parser errors for %q", input)
+		}
+	}
+}
+
 func TestParsingIndexExpressions(t *testing.T) {
 	input := "myArray[1 + 1]"
 
@@ -908,6 +1799,59 @@ func TestParsingIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestParsingSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		start    any // int64 bound, or nil if it is left out
+		end      any
+		optional bool
+	}{
+		{"myArray[1:3]", int64(1), int64(3), false},
+		{"myArray[:2]", nil, int64(2), false},
+		{"myArray[2:]", int64(2), nil, false},
+		{"myArray[:]", nil, nil, false},
+		{"myArray?.[1:]", int64(1), nil, true},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		slice, ok := stmt.Expression.(*ast.SliceExpression)
+		if !ok {
+			t.Fatalf("%s: exp not *ast.SliceExpression. got=%T", tt.input, stmt.Expression)
+		}
+		if !testIdentifier(t, slice.Left, "myArray") {
+			return
+		}
+		for _, bound := range []struct {
+			exp      ast.Expression
+			expected any
+		}{{slice.Start, tt.start}, {slice.End, tt.end}} {
+			if bound.expected == nil {
+				if bound.exp != nil {
+					t.Errorf("%s: expected a missing bound, got=%s", tt.input, bound.exp)
+				}
+				continue
+			}
+			testIntegerLiteral(t, bound.exp, bound.expected.(int64))
+		}
+		if slice.Optional != tt.optional {
+			t.Errorf("%s: slice.Optional is not %t", tt.input, tt.optional)
+		}
+	}
+
+	for _, input := range []string{"a[1:2:3]", "a[1:", "a[:"} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("%s: expected parser errors", input)
+		}
+	}
+}
+
 func TestParsingHashLiteralsStringKeys(t *testing.T) {
 	input := `{"one": 1, "two": 2, "three": 3}`
 
@@ -0,0 +1,175 @@
+// Package parsertest provides snapshot testing for the parser.
+//
+// Golden parses a program and compares a canonical dump of its AST with a file
+// under the testdata directory of the calling test, so covering a new piece of
+// syntax only takes an input and a reviewed snapshot instead of a hand-written
+// walk over the tree. Running the tests with -update writes the snapshots
+// instead of comparing them.
+//
+// Key components:
+//   - Golden: Compares the AST of a program with its snapshot
+//   - Dump: Renders the canonical dump of a node
+package parsertest
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/dr8co/monke/ast"
+	"github.com/dr8co/monke/lexer"
+	"github.com/dr8co/monke/parser"
+)
+
+var update = flag.Bool("update", false, "write the parser snapshots in testdata instead of comparing them")
+
+// Golden parses input and compares the dump of its AST, followed by any parser
+// errors, with the snapshot in testdata/<name>.golden.
+func Golden(t *testing.T, name, input string) {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+
+	var s strings.Builder
+	s.WriteString(Dump(program))
+	for _, err := range p.DetailedErrors() {
+		fmt.Fprintf(&s, "error %s: %s\n", err.Pos, err.Message)
+	}
+	got := s.String()
+
+	file := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.MkdirAll("testdata", 0o750); err != nil {
+			t.Fatal(err)
+		}
+		//nolint:gosec // Snapshots are committed with the tests
+		if err := os.WriteFile(file, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	//nolint:gosec // The path is built from the test's own snapshot name
+	want, err := os.ReadFile(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("%s: no snapshot; run the tests with -update to create it", file)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("%s: AST differs from the snapshot (run with -update after reviewing):\n--- want\n%s--- got\n%s", file, want, got)
+	}
+}
+
+// Dump renders node and its children with one node per line, indented by depth.
+// Each line holds the field the node is stored in, its type, its position and
+// its scalar fields, e.g. `Name: Identifier @1:5 Value="x"`. Nil children and
+// empty lists are left out.
+func Dump(node ast.Node) string {
+	var s strings.Builder
+	dump(&s, 0, "", reflect.ValueOf(node))
+	return s.String()
+}
+
+// nodeType is the type of the ast.Node interface.
+var nodeType = reflect.TypeFor[ast.Node]()
+
+// dump writes the node v under the given label, followed by its children.
+func dump(s *strings.Builder, depth int, label string, v reflect.Value) {
+	s.WriteString(strings.Repeat("  ", depth))
+	if label != "" {
+		s.WriteString(label + ": ")
+	}
+	node := v.Interface().(ast.Node)
+	elem := reflect.ValueOf(node).Elem() // v may hold an interface
+	s.WriteString(elem.Type().Name())
+	if _, ok := node.(*ast.Program); ok {
+		// A program has no token of its own
+	} else if pos := node.Pos(); pos.Line != 0 {
+		s.WriteString(" @" + pos.String())
+	}
+
+	// Scalars go on the node's line, children on the following lines
+	var children []func()
+	for i := range elem.NumField() {
+		field, value := elem.Type().Field(i), elem.Field(i)
+		if field.Name == "Token" || field.Name == "Cache" || !field.IsExported() {
+			continue
+		}
+		if scalar, ok := formatScalar(value); ok {
+			s.WriteString(" " + field.Name + "=" + scalar)
+			continue
+		}
+		children = append(children, func() { dumpField(s, depth+1, field.Name, value) })
+	}
+	s.WriteString("\n")
+
+	if hash, ok := node.(*ast.HashLiteral); ok {
+		// Pairs are dumped in source order rather than map order
+		for _, key := range hash.Order {
+			if _, ok := key.(*ast.SpreadElement); ok {
+				dump(s, depth+1, "Spread", reflect.ValueOf(key))
+				continue
+			}
+			dump(s, depth+1, "Key", reflect.ValueOf(key))
+			dump(s, depth+2, "Value", reflect.ValueOf(hash.Pairs[key]))
+		}
+		return
+	}
+	for _, child := range children {
+		child()
+	}
+}
+
+// dumpField writes a field holding a node, a list of nodes or a list of strings.
+func dumpField(s *strings.Builder, depth int, name string, v reflect.Value) {
+	switch {
+	case v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer:
+		if !isNil(v) && v.Type().Implements(nodeType) {
+			dump(s, depth, name, v)
+		}
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		if v.Len() > 0 {
+			fmt.Fprintf(s, "%s%s: %q\n", strings.Repeat("  ", depth), name, v.Interface())
+		}
+	case v.Kind() == reflect.Slice:
+		for i := range v.Len() {
+			if el := v.Index(i); !isNil(el) {
+				dump(s, depth, fmt.Sprintf("%s[%d]", name, i), el)
+			}
+		}
+	}
+}
+
+// isNil reports whether v is nil, or an interface holding a nil pointer,
+// which the parser leaves in the lists of statements after an error.
+func isNil(v reflect.Value) bool {
+	if v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v.IsNil()
+}
+
+// formatScalar formats a string, number or boolean field.
+func formatScalar(v reflect.Value) (string, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return strconv.Quote(v.String()), true
+	case reflect.Int, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64), true
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,157 @@
+// Package pipeline runs Monke source through the lexer, the parser and the
+// evaluator in one call, with middleware hooking into each stage.
+//
+// The REPL, the command line, the profiler and the conformance runner all run
+// code through Run, so a Middleware sees the same stages whichever of them
+// runs the code:
+//   - Tokens observes or rewrites the token stream before it is parsed
+//   - Program observes or replaces the syntax tree before it is evaluated,
+//     or stops the run before evaluation
+//   - Result observes or changes the outcome of the run
+//
+// Hooks of the same stage are called in the order the middleware is passed.
+package pipeline
+
+import (
+	"github.com/dr8co/monke/ast"
+	"github.com/dr8co/monke/evaluator"
+	"github.com/dr8co/monke/lexer"
+	"github.com/dr8co/monke/object"
+	"github.com/dr8co/monke/parser"
+	"github.com/dr8co/monke/token"
+)
+
+// Middleware hooks into the stages of Run. Any of its hooks may be nil.
+type Middleware struct {
+	// Tokens is called with the tokens of the source up to and including EOF,
+	// and returns the tokens to parse.
+	Tokens func(tokens []token.Token) []token.Token
+
+	// Program is called once the source is parsed without syntax errors, with
+	// the result so far. It may replace result.Program with the program to
+	// evaluate, or return false to stop before evaluation.
+	Program func(result *Result) bool
+
+	// Result is called with the outcome of the run, and may change it.
+	Result func(result *Result)
+}
+
+// Result is the outcome of Run.
+type Result struct {
+	Source   string
+	Program  *ast.Program   // The parsed program, after the Program hooks
+	Errors   []parser.Error // The syntax errors; the program is not evaluated if there are any
+	Warnings []parser.Error // The parser's warnings and ShadowWarnings; runtime warnings go to the evaluator's handler
+	Value    object.Object  // The value of the program, or nil if it was not evaluated or had none
+
+	// Eval is what the evaluation took, with the runtime warnings; it is the
+	// zero EvalResult if the program was not evaluated.
+	Eval evaluator.EvalResult
+}
+
+// Failed reports whether the source had syntax errors or failed at runtime.
+func (r *Result) Failed() bool {
+	return len(r.Errors) != 0 || r.Error() != nil
+}
+
+// Error returns the runtime error the program failed with, or nil.
+func (r *Result) Error() *object.Error {
+	err, _ := r.Value.(*object.Error)
+	return err
+}
+
+// Err returns the failure of the run as a Go error: a *parser.ErrorList if
+// the source had syntax errors, an *evaluator.RuntimeError if it failed at
+// runtime, or nil.
+func (r *Result) Err() error {
+	if len(r.Errors) != 0 {
+		return &parser.ErrorList{Errors: r.Errors}
+	}
+	return evaluator.AsError(r.Value)
+}
+
+// Run lexes, parses and evaluates src in env, with the hooks of middleware
+// called at each stage. A nil env is replaced by a new environment.
+func Run(src string, env *object.Environment, middleware ...Middleware) *Result {
+	result := &Result{Source: src}
+	defer func() {
+		for _, m := range middleware {
+			if m.Result != nil {
+				m.Result(result)
+			}
+		}
+	}()
+
+	p := parser.New(tokenSource(src, middleware))
+	result.Program = p.ParseProgram()
+	result.Warnings = p.Warnings()
+	if len(p.Errors()) != 0 {
+		result.Errors = p.DetailedErrors()
+		return result
+	}
+	result.Warnings = append(result.Warnings, ShadowWarnings(result.Program, src)...)
+
+	for _, m := range middleware {
+		if m.Program != nil && !m.Program(result) {
+			return result
+		}
+	}
+
+	if env == nil {
+		env = object.NewEnvironment()
+	}
+	result.Eval = evaluator.EvalDetailed(result.Program, env)
+	result.Value = result.Eval.Value
+	return result
+}
+
+// tokenSource returns the source of the tokens of src to parse: the lexer
+// itself, unless some middleware hooks into the tokens.
+func tokenSource(src string, middleware []Middleware) parser.TokenSource {
+	l := lexer.New(src)
+	hooked := false
+	for _, m := range middleware {
+		hooked = hooked || m.Tokens != nil
+	}
+	if !hooked {
+		return l
+	}
+
+	var tokens []token.Token
+	for {
+		tok := l.NextToken()
+		tokens = append(tokens, tok)
+		if tok.Type == token.EOF {
+			break
+		}
+	}
+	for _, m := range middleware {
+		if m.Tokens != nil {
+			tokens = m.Tokens(tokens)
+		}
+	}
+	return &replay{tokens: tokens, errors: l.Errors(), pragmas: l.Pragmas()}
+}
+
+// replay is a parser.TokenSource that replays a list of tokens.
+type replay struct {
+	tokens  []token.Token
+	errors  []lexer.Error
+	pragmas []string
+}
+
+// NextToken returns the next token of the list, and EOF once it is over.
+func (r *replay) NextToken() token.Token {
+	if len(r.tokens) == 0 {
+		return token.Token{Type: token.EOF}
+	}
+	tok := r.tokens[0]
+	r.tokens = r.tokens[1:]
+	return tok
+}
+
+// Errors returns the errors of the lexer that produced the tokens.
+func (r *replay) Errors() []lexer.Error { return r.errors }
+
+// Pragmas returns the pragmas of the source of the tokens.
+func (r *replay) Pragmas() []string { return r.pragmas }
@@ -0,0 +1,180 @@
+package pipeline
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/dr8co/monke/ast"
+	"github.com/dr8co/monke/evaluator"
+	"github.com/dr8co/monke/object"
+	"github.com/dr8co/monke/parser"
+	"github.com/dr8co/monke/token"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		failed   bool
+	}{
+		{"let x = 2; x * 21", "42", false},
+		{"1 + true", "ERROR: type mismatch: INTEGER + BOOLEAN", true},
+		{"let = 5;", "", true},
+	}
+
+	for _, tt := range tests {
+		result := Run(tt.input, nil)
+		got := ""
+		if result.Value != nil {
+			got = result.Value.Inspect()
+		}
+		if got != tt.expected {
+			t.Errorf("%q: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+		if result.Failed() != tt.failed {
+			t.Errorf("%q: expected Failed()=%t, got=%t", tt.input, tt.failed, result.Failed())
+		}
+	}
+
+	// Syntax errors stop the run before evaluation
+	env := object.NewEnvironment()
+	result := Run("let x = 1; let = 5;", env)
+	if len(result.Errors) == 0 {
+		t.Fatalf("expected syntax errors")
+	}
+	if _, ok := env.Get("x"); ok {
+		t.Errorf("expected the program not to be evaluated")
+	}
+
+	var list *parser.ErrorList
+	if err := result.Err(); !errors.As(err, &list) || len(list.Errors) != len(result.Errors) {
+		t.Errorf("Err of syntax errors wrong. got=%v", err)
+	}
+	var runtimeErr *evaluator.RuntimeError
+	if err := Run("1 + true", nil).Err(); !errors.As(err, &runtimeErr) {
+		t.Errorf("Err of a runtime error wrong. got=%v", err)
+	}
+	if err := Run("1", nil).Err(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var stages []string
+	trace := func(name string) Middleware {
+		return Middleware{
+			Tokens: func(tokens []token.Token) []token.Token {
+				stages = append(stages, name+" tokens")
+				return tokens
+			},
+			Program: func(*Result) bool {
+				stages = append(stages, name+" program")
+				return true
+			},
+			Result: func(*Result) {
+				stages = append(stages, name+" result")
+			},
+		}
+	}
+
+	Run("1", nil, trace("a"), trace("b"))
+	expected := []string{"a tokens", "b tokens", "a program", "b program", "a result", "b result"}
+	if !slices.Equal(stages, expected) {
+		t.Errorf("wrong hook order: expected=%v, got=%v", expected, stages)
+	}
+
+	// Tokens hooks can rewrite the source
+	plus := Middleware{Tokens: func(tokens []token.Token) []token.Token {
+		for i, tok := range tokens {
+			if tok.Type == token.ASTERISK {
+				tokens[i] = token.Token{Type: token.PLUS, Literal: "+", Position: tok.Position}
+			}
+		}
+		return tokens
+	}}
+	if got := Run("6 * 7", nil, plus).Value.Inspect(); got != "13" {
+		t.Errorf("expected rewritten tokens to give 13, got=%s", got)
+	}
+
+	// Program hooks can replace the program or stop the run
+	replace := Middleware{Program: func(result *Result) bool {
+		result.Program = &ast.Program{Statements: result.Program.Statements[1:]}
+		return true
+	}}
+	if got := Run("1; 2", nil, replace).Value.Inspect(); got != "2" {
+		t.Errorf("expected the replaced program to give 2, got=%s", got)
+	}
+
+	env := object.NewEnvironment()
+	stop := Middleware{Program: func(*Result) bool { return false }}
+	result := Run("let x = 1; x", env, stop, trace("after"))
+	if result.Value != nil {
+		t.Errorf("expected no value after stopping, got=%s", result.Value.Inspect())
+	}
+	if _, ok := env.Get("x"); ok {
+		t.Errorf("expected the program not to be evaluated")
+	}
+
+	// Result hooks can change the outcome
+	double := Middleware{Result: func(result *Result) {
+		if n, ok := result.Value.(*object.Integer); ok {
+			result.Value = &object.Integer{Value: n.Value * 2}
+		}
+	}}
+	if got := Run("21", nil, double).Value.Inspect(); got != "42" {
+		t.Errorf("expected the changed result 42, got=%s", got)
+	}
+}
+
+func TestShadowWarnings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let len = fn(x) { 0 }; len([1])", []string{"1:5: len shadows the builtin of the same name"}},
+		{"let a, type = [1, 2];\nlet [first, ...rest] = [1];", []string{
+			"1:8: type shadows the builtin of the same name",
+			"2:6: first shadows the builtin of the same name",
+			"2:16: rest shadows the builtin of the same name",
+		}},
+		{"let f = fn() { let upper = 1; upper }", []string{"1:20: upper shadows the builtin of the same name"}},
+		{"let len = 1; /* monke:shadow */\nlet last = 2; /*monke:shadow because */", nil},
+		{"let f = fn(first) { first }; match ([1]) { [last] => last }; let lengths = 1", nil},
+	}
+
+	for _, tt := range tests {
+		result := Run(tt.input, nil)
+		var got []string
+		for _, w := range result.Warnings {
+			got = append(got, w.Pos.String()+": "+w.Message)
+		}
+		if !slices.Equal(got, tt.expected) {
+			t.Errorf("%q: wrong warnings.\nexpected=%q\ngot=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestFormatErrors(t *testing.T) {
+	result := Run("let = 5;", nil)
+	expected := "Parser Errors:\n" +
+		"  1. Expected next token to be IDENT, got = instead (at 1:5)\n" +
+		"     let = 5;\n" +
+		"         ^\n"
+	if got := FormatParseErrors(result.Source, result.Errors[:1]); !strings.HasPrefix(got, expected+"\nTips:\n") {
+		t.Errorf("FormatParseErrors wrong.\nexpected prefix=%q\ngot=%q", expected, got)
+	}
+
+	// The caret keeps the tabs of the line, so it lines up under the code
+	result = Run("let x = 1;\n\tx + true", nil)
+	expected = "Runtime Error:\n" +
+		"  ERROR: type mismatch: INTEGER + BOOLEAN (at 2:2)\n" +
+		"  \tx + true\n" +
+		"  \t^\n" +
+		"\nTips:\n" +
+		"  • Ensure operands are of compatible types\n"
+	if got := FormatRuntimeError(result.Source, result.Error()); !strings.HasPrefix(got, expected) {
+		t.Errorf("FormatRuntimeError wrong.\nexpected prefix=%q\ngot=%q", expected, got)
+	}
+}
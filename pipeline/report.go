@@ -0,0 +1,97 @@
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dr8co/monke/object"
+	"github.com/dr8co/monke/parser"
+	"github.com/dr8co/monke/token"
+)
+
+// FormatParseErrors formats the syntax errors of source as the REPL and the
+// command line report them, each pointing at the offending code, followed by tips.
+func FormatParseErrors(source string, errors []parser.Error) string {
+	var s strings.Builder
+	s.WriteString("Parser Errors:\n")
+
+	for i, err := range errors {
+		if err.Pos.Line > 0 {
+			fmt.Fprintf(&s, "  %d. %s (at %s)\n", i+1, err.Message, err.Pos)
+		} else {
+			fmt.Fprintf(&s, "  %d. %s\n", i+1, err.Message)
+		}
+		s.WriteString(sourceCaret(source, err.Pos, "     "))
+	}
+
+	s.WriteString("\nTips:\n")
+	s.WriteString("  • Check for missing parentheses, braces, or semicolons\n")
+	s.WriteString("  • Verify that all expressions are properly terminated\n")
+	s.WriteString("  • Ensure variable names are valid identifiers\n")
+
+	return s.String()
+}
+
+// FormatRuntimeError formats a runtime error raised by source as the REPL and
+// the command line report it, pointing at the offending code, followed by tips
+// for the kind of error.
+func FormatRuntimeError(source string, err *object.Error) string {
+	errorMsg := err.Inspect()
+
+	var s strings.Builder
+	s.WriteString("Runtime Error:\n")
+	if err.Pos.Line > 0 {
+		s.WriteString("  " + errorMsg + " (at " + err.Pos.String() + ")\n")
+	} else {
+		s.WriteString("  " + errorMsg + "\n")
+	}
+	s.WriteString(sourceCaret(source, err.Pos, "  "))
+
+	s.WriteString("\nTips:\n")
+
+	// Add specific tips based on common error patterns
+	//nolint:gocritic
+	if strings.Contains(errorMsg, "identifier not found") {
+		s.WriteString("  • Check if the variable is defined before use\n")
+		s.WriteString("  • Verify the variable name is spelled correctly\n")
+		s.WriteString("  • Make sure the variable is in scope\n")
+	} else if strings.Contains(errorMsg, "wrong number of arguments") {
+		s.WriteString("  • Check the function call has the correct number of arguments\n")
+		s.WriteString("  • Verify the function definition matches its usage\n")
+	} else if strings.Contains(errorMsg, "type mismatch") {
+		s.WriteString("  • Ensure operands are of compatible types\n")
+		s.WriteString("  • Check if you need to convert types before operation\n")
+	} else if strings.Contains(errorMsg, "index") {
+		s.WriteString("  • Verify array indices are within bounds\n")
+		s.WriteString("  • Ensure you're indexing an array, string or hash\n")
+	} else {
+		s.WriteString("  • Review your code logic\n")
+		s.WriteString("  • Check for type mismatches or undefined variables\n")
+		s.WriteString("  • Consider breaking complex expressions into simpler steps\n")
+	}
+
+	return s.String()
+}
+
+// sourceCaret returns the source line at pos followed by a caret under its column,
+// each line prefixed with indent. It returns "" if pos is not within source.
+func sourceCaret(source string, pos token.Position, indent string) string {
+	lines := strings.Split(source, "\n")
+	if pos.Line < 1 || pos.Line > len(lines) || pos.Column < 1 {
+		return ""
+	}
+	line := strings.TrimRight(lines[pos.Line-1], "\r")
+	if pos.Column > len(line)+1 {
+		return ""
+	}
+	// Keep tabs so the caret lines up with the code above it
+	var pad strings.Builder
+	for _, ch := range line[:pos.Column-1] {
+		if ch == '\t' {
+			pad.WriteByte('\t')
+		} else {
+			pad.WriteByte(' ')
+		}
+	}
+	return indent + line + "\n" + indent + pad.String() + "^\n"
+}
@@ -0,0 +1,79 @@
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dr8co/monke/ast"
+	"github.com/dr8co/monke/evaluator"
+	"github.com/dr8co/monke/parser"
+)
+
+// shadowDirective is the comment that keeps ShadowWarnings from reporting
+// the bindings on its line.
+const shadowDirective = "monke:shadow"
+
+// ShadowWarnings returns a warning for every name a let or class statement of
+// program binds that shadows a builtin, such as `let len = 1`, since code in the rest
+// of the scope can no longer call the builtin. source is the source program was
+// parsed from; the bindings on a line with a `/* monke:shadow */` comment are
+// not reported.
+func ShadowWarnings(program *ast.Program, source string) []parser.Error {
+	builtins := make(map[string]bool)
+	for _, name := range evaluator.Builtins() {
+		builtins[name] = true
+	}
+	lines := strings.Split(source, "\n")
+
+	var warnings []parser.Error
+	check := func(name *ast.Identifier) {
+		if name == nil || !builtins[name.Value] {
+			return
+		}
+		pos := name.Pos()
+		if pos.Line > 0 && pos.Line <= len(lines) && allowsShadow(lines[pos.Line-1]) {
+			return
+		}
+		warnings = append(warnings, parser.Error{
+			Pos:     pos,
+			Message: fmt.Sprintf("%s shadows the builtin of the same name", name.Value),
+		})
+	}
+	ast.Inspect(program, func(node ast.Node) bool {
+		if class, ok := node.(*ast.ClassStatement); ok {
+			check(class.Name)
+			return true
+		}
+		let, ok := node.(*ast.LetStatement)
+		if !ok {
+			return true
+		}
+		switch {
+		case let.Pattern != nil:
+			ast.Inspect(let.Pattern, func(node ast.Node) bool {
+				if binding, ok := node.(*ast.BindingPattern); ok {
+					check(binding.Name)
+				}
+				return true
+			})
+		case let.Names != nil:
+			for _, name := range let.Names {
+				check(name)
+			}
+		default:
+			check(let.Name)
+		}
+		return true
+	})
+	return warnings
+}
+
+// allowsShadow reports whether line has a comment starting with the shadow directive.
+func allowsShadow(line string) bool {
+	for _, comment := range strings.Split(line, "/*")[1:] {
+		if strings.HasPrefix(strings.TrimSpace(comment), shadowDirective) {
+			return true
+		}
+	}
+	return false
+}
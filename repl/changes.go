@@ -0,0 +1,96 @@
+package repl
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/dr8co/monke/object"
+)
+
+// maxSummary is the number of characters of a value shown in a change.
+const maxSummary = 40
+
+// binding is the state of a global binding before an evaluation.
+type binding struct {
+	typ     object.Type
+	inspect string
+}
+
+// snapshot records the global bindings of env, so that changes can tell what
+// an evaluation did to them. Values are compared by their Inspect strings,
+// which also catches hashes and arrays modified in place.
+func snapshot(env *object.Environment) map[string]binding {
+	bindings := make(map[string]binding)
+	for _, name := range env.Names() {
+		val, _ := env.Get(name)
+		bindings[name] = binding{typ: val.Type(), inspect: val.Inspect()}
+	}
+	return bindings
+}
+
+// changes describes the bindings of env added, changed or removed since the
+// snapshot before, one per line in the order of their names, or "" if there
+// are none or before is nil because no snapshot was taken.
+func changes(before map[string]binding, env *object.Environment) string {
+	if before == nil {
+		return ""
+	}
+	names := env.Names()
+	var removed []string
+	for name := range before {
+		if _, found := slices.BinarySearch(names, name); !found {
+			removed = append(removed, name)
+		}
+	}
+	names = append(names, removed...)
+	slices.Sort(names)
+
+	var s strings.Builder
+	for _, name := range names {
+		old, ok := before[name]
+		val, defined := env.Get(name)
+		if !defined {
+			fmt.Fprintf(&s, "- %s = %s (%s)\n", name, summarize(old.inspect), old.typ)
+			continue
+		}
+		after := binding{typ: val.Type(), inspect: val.Inspect()}
+		switch {
+		case !ok:
+			fmt.Fprintf(&s, "+ %s = %s (%s)\n", name, summarize(after.inspect), after.typ)
+		case old.typ != after.typ:
+			fmt.Fprintf(&s, "~ %s: %s (%s) → %s (%s)\n", name, summarize(old.inspect), old.typ, summarize(after.inspect), after.typ)
+		case old.inspect != after.inspect:
+			fmt.Fprintf(&s, "~ %s: %s → %s (%s)\n", name, summarize(old.inspect), summarize(after.inspect), after.typ)
+		}
+	}
+	return strings.TrimRight(s.String(), "\n")
+}
+
+// summarize shortens the Inspect string of a value to one line of at most
+// maxSummary characters.
+func summarize(inspect string) string {
+	inspect = strings.Join(strings.Fields(inspect), " ")
+	if runes := []rune(inspect); len(runes) > maxSummary {
+		return string(runes[:maxSummary-1]) + "…"
+	}
+	return inspect
+}
+
+// diffCommand runs ":diff" with args, returning its output and whether it failed.
+func (m *model) diffCommand(args []string) (string, bool) {
+	switch {
+	case len(args) == 0:
+		if m.showChanges {
+			return "Changes to bindings are shown after each evaluation. Type :diff off to hide them.", false
+		}
+		return "Changes to bindings are hidden. Type :diff on to show them after each evaluation.", false
+	case len(args) == 1 && args[0] == "on":
+		m.showChanges = true
+		return "Showing the bindings each evaluation adds (+) or changes (~).", false
+	case len(args) == 1 && args[0] == "off":
+		m.showChanges = false
+		return "Changes to bindings are hidden.", false
+	}
+	return "Usage: :diff [on | off]", true
+}
@@ -0,0 +1,71 @@
+package repl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dr8co/monke/object"
+)
+
+func TestChanges(t *testing.T) {
+	env := object.NewEnvironment()
+	env.Set("kept", &object.Integer{Value: 1})
+	env.Set("changed", &object.Integer{Value: 1})
+	env.Set("retyped", &object.Integer{Value: 1})
+	env.Set("removed", &object.String{Value: "gone"})
+	before := snapshot(env)
+
+	if got := changes(before, env); got != "" {
+		t.Errorf("expected no changes, got=%q", got)
+	}
+
+	after := object.NewEnvironment()
+	after.Set("kept", &object.Integer{Value: 1})
+	after.Set("changed", &object.Integer{Value: 2})
+	after.Set("retyped", &object.String{Value: "one"})
+	after.Set("added", &object.Array{Elements: []object.Object{&object.Integer{Value: 1}}})
+	expected := strings.Join([]string{
+		"+ added = [1] (ARRAY)",
+		"~ changed: 1 → 2 (INTEGER)",
+		"- removed = gone (STRING)",
+		"~ retyped: 1 (INTEGER) → one (STRING)",
+	}, "\n")
+	if got := changes(before, after); got != expected {
+		t.Errorf("wrong changes.\nexpected=%q\ngot=%q", expected, got)
+	}
+
+	if got := changes(nil, after); got != "" {
+		t.Errorf("expected no changes without a snapshot, got=%q", got)
+	}
+}
+
+func TestChangesInPlace(t *testing.T) {
+	env := object.NewEnvironment()
+	xs := &object.Array{}
+	env.Set("xs", xs)
+	before := snapshot(env)
+
+	xs.Elements = append(xs.Elements, &object.Integer{Value: 1})
+	if got, expected := changes(before, env), "~ xs: [] → [1] (ARRAY)"; got != expected {
+		t.Errorf("expected=%q, got=%q", expected, got)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"short", "short"},
+		{"fn(x) {\n    x;\n}", "fn(x) { x; }"},
+		{strings.Repeat("a", maxSummary), strings.Repeat("a", maxSummary)},
+		{strings.Repeat("a", maxSummary+1), strings.Repeat("a", maxSummary-1) + "…"},
+		{strings.Repeat("é", maxSummary+1), strings.Repeat("é", maxSummary-1) + "…"},
+	}
+
+	for _, tt := range tests {
+		if got := summarize(tt.input); got != tt.expected {
+			t.Errorf("summarize(%q): expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
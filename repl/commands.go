@@ -0,0 +1,148 @@
+package repl
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dr8co/monke/object"
+)
+
+// Command is a REPL command added to the session, run when an input is its
+// name after a colon, such as ":env", followed by its arguments. Embedders
+// pass their own in Options.Commands to bring tools into the REPL; a command
+// of the same name as one of the built-in ones, such as ":env", replaces it,
+// while the commands of the REPL itself, such as ":help", cannot be replaced.
+type Command interface {
+	// Name returns the name of the command, without the colon.
+	Name() string
+	// Help returns a one-line description of the command, listed by ":help".
+	Help() string
+	// Run runs the command with the arguments after its name and returns the
+	// output to show, or an error to show in its place.
+	Run(session *Session, args []string) (string, error)
+}
+
+// Session is what the REPL shows a Command of the current workspace.
+type Session struct {
+	Env       *object.Environment // The global environment of the workspace
+	Workspace string              // The name of the workspace
+	History   []HistoryEntry      // The inputs of the workspace, oldest first
+}
+
+// HistoryEntry is an input of the session and what it showed.
+type HistoryEntry struct {
+	Input   string
+	Output  string
+	Failed  bool // The input failed, with a syntax or runtime error or as a command
+	Command bool // The input is a command such as ":help", not code
+}
+
+// builtinCommands are the commands that come with the REPL.
+var builtinCommands = []Command{envCommand{}, historyCommand{}}
+
+// command returns the command called name, looking at the commands of the
+// options before the built-in ones.
+func (m *model) command(name string) (Command, bool) {
+	for _, cmd := range m.options.Commands {
+		if cmd.Name() == name {
+			return cmd, true
+		}
+	}
+	for _, cmd := range builtinCommands {
+		if cmd.Name() == name {
+			return cmd, true
+		}
+	}
+	return nil, false
+}
+
+// commandHelp lists the added commands and their descriptions, one per line,
+// in the order they are looked up in.
+func (m *model) commandHelp() string {
+	var s strings.Builder
+	seen := make(map[string]bool)
+	for _, cmd := range slices.Concat(m.options.Commands, builtinCommands) {
+		if seen[cmd.Name()] {
+			continue
+		}
+		seen[cmd.Name()] = true
+		fmt.Fprintf(&s, "  :%-12s %s\n", cmd.Name(), cmd.Help())
+	}
+	return s.String()
+}
+
+// runPlugin runs cmd with args, returning its output and whether it failed.
+func (m *model) runPlugin(cmd Command, args []string) (string, bool) {
+	session := &Session{Env: m.env, Workspace: m.workspace}
+	for _, entry := range m.history {
+		session.History = append(session.History, HistoryEntry{
+			Input:   entry.input,
+			Output:  entry.output,
+			Failed:  entry.isError,
+			Command: entry.command,
+		})
+	}
+	output, err := cmd.Run(session, args)
+	if err != nil {
+		return fmt.Sprintf(":%s: %s", cmd.Name(), err), true
+	}
+	return output, false
+}
+
+// envCommand lists the global bindings of the workspace.
+type envCommand struct{}
+
+func (envCommand) Name() string { return "env" }
+
+func (envCommand) Help() string { return "List the global bindings with their types and values" }
+
+func (envCommand) Run(session *Session, args []string) (string, error) {
+	if len(args) != 0 {
+		return "", errors.New("takes no arguments")
+	}
+	names := session.Env.Names()
+	if len(names) == 0 {
+		return "No bindings yet.", nil
+	}
+	var s strings.Builder
+	for _, name := range names {
+		val, _ := session.Env.Get(name)
+		fmt.Fprintf(&s, "%s = %s (%s)\n", name, summarize(val.Inspect()), val.Type())
+	}
+	return strings.TrimRight(s.String(), "\n"), nil
+}
+
+// historyCommand lists the inputs of the workspace.
+type historyCommand struct{}
+
+func (historyCommand) Name() string { return "history" }
+
+func (historyCommand) Help() string {
+	return "List the inputs of the workspace, or the last n with :history n"
+}
+
+func (historyCommand) Run(session *Session, args []string) (string, error) {
+	inputs := session.History
+	switch {
+	case len(args) > 1:
+		return "", errors.New("takes at most a count of inputs")
+	case len(args) == 1:
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 0 {
+			return "", fmt.Errorf("%q is not a count of inputs", args[0])
+		}
+		inputs = inputs[max(len(inputs)-n, 0):]
+	}
+	if len(inputs) == 0 {
+		return "No inputs yet.", nil
+	}
+	var s strings.Builder
+	first := len(session.History) - len(inputs) + 1
+	for i, entry := range inputs {
+		fmt.Fprintf(&s, "%4d  %s\n", first+i, strings.ReplaceAll(entry.Input, "\n", "\n      "))
+	}
+	return strings.TrimRight(s.String(), "\n"), nil
+}
@@ -0,0 +1,118 @@
+package repl
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/dr8co/monke/object"
+)
+
+// testCommand is a Command that returns its output, or fails with err.
+type testCommand struct {
+	name, help, output string
+	err                error
+	session            *Session // The session of the last run
+}
+
+func (c *testCommand) Name() string { return c.name }
+
+func (c *testCommand) Help() string { return c.help }
+
+func (c *testCommand) Run(session *Session, args []string) (string, error) {
+	c.session = session
+	return c.output + strings.Join(args, ","), c.err
+}
+
+func TestCommands(t *testing.T) {
+	first := &testCommand{name: "greet", help: "Say hello", output: "hello "}
+	m := initialModel(Options{Commands: []Command{
+		first,
+		&testCommand{name: "greet", help: "Say hello again", output: "hello again"},
+		&testCommand{name: "env", help: "Replace :env", output: "no env"},
+		&testCommand{name: "diff", help: "Replace :diff", output: "not run"},
+		&testCommand{name: "help", help: "Replace :help", output: "not run"},
+		&testCommand{name: "fail", help: "Always fail", err: errors.New("went wrong")},
+	}})
+
+	tests := []struct {
+		input  string
+		output string // A prefix of the output
+		failed bool
+	}{
+		// The first of two commands with the same name wins
+		{":greet a b", "hello a,b", false},
+		// A command replaces the built-in one of its name
+		{":env", "no env", false},
+		// but not the commands of the REPL itself
+		{":diff", "Changes to bindings are hidden.", false},
+		{":help len", "builtin len", false},
+		{":fail", ":fail: went wrong", true},
+		{":missing", "Unknown command :missing.", true},
+		{":help :greet", ":greet: Say hello", false},
+		{":help :env", ":env: Replace :env", false},
+		{":help :history", ":history: List the inputs", false},
+	}
+
+	for _, tt := range tests {
+		output, failed := m.runCommand(tt.input)
+		if !strings.HasPrefix(output, tt.output) || failed != tt.failed {
+			t.Errorf("%s: expected %q (failed=%t), got=%q (failed=%t)", tt.input, tt.output, tt.failed, output, failed)
+		}
+	}
+}
+
+func TestCommandHelp(t *testing.T) {
+	m := initialModel(Options{Commands: []Command{
+		&testCommand{name: "greet", help: "Say hello"},
+		&testCommand{name: "greet", help: "Say hello again"},
+		&testCommand{name: "env", help: "Replace :env"},
+	}})
+
+	expected := "  :greet        Say hello\n" +
+		"  :env          Replace :env\n" +
+		"  :history      List the inputs of the workspace, or the last n with :history n\n"
+	if got := m.commandHelp(); got != expected {
+		t.Errorf("wrong help.\nexpected=%q\ngot=%q", expected, got)
+	}
+	if help, _ := m.runCommand(":help"); !strings.Contains(help, "More commands:\n"+expected) {
+		t.Errorf(":help does not list the commands. got=%q", help)
+	}
+}
+
+func TestCommandSession(t *testing.T) {
+	cmd := &testCommand{name: "show"}
+	m := initialModel(Options{Commands: []Command{cmd}})
+	m.env.Set("x", &object.Integer{Value: 1})
+	m.history = []historyEntry{
+		{input: "let x = 1;", output: "nil"},
+		{input: ":oops", output: "Unknown command :oops.", isError: true, command: true},
+	}
+	m.workspaceCommand([]string{"new", "other"})
+	m.workspaceCommand([]string{"use", "main"})
+
+	m.runCommand(":show")
+	if cmd.session.Workspace != "main" || cmd.session.Env != m.env {
+		t.Errorf("wrong session workspace. got=%s", cmd.session.Workspace)
+	}
+	expected := []HistoryEntry{
+		{Input: "let x = 1;", Output: "nil"},
+		{Input: ":oops", Output: "Unknown command :oops.", Failed: true, Command: true},
+	}
+	if len(cmd.session.History) != len(expected) {
+		t.Fatalf("wrong history. got=%+v", cmd.session.History)
+	}
+	for i, entry := range cmd.session.History {
+		if entry != expected[i] {
+			t.Errorf("history entry %d: expected=%+v, got=%+v", i, expected[i], entry)
+		}
+	}
+
+	// The built-in commands see the same session
+	if output, _ := m.runCommand(":env"); output != "x = 1 (INTEGER)" {
+		t.Errorf(":env wrong. got=%q", output)
+	}
+	if output, _ := m.runCommand(":history 1"); output != "   2  :oops" {
+		t.Errorf(":history wrong. got=%q", output)
+	}
+}
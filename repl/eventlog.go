@@ -0,0 +1,76 @@
+package repl
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/dr8co/monke/object"
+	"github.com/dr8co/monke/version"
+)
+
+// LogEvent is a line of the event log a REPL writes to Options.Log. Events
+// are written as they happen, so an "eval" without a "result" after it shows
+// an evaluation that never finished. The kinds of events are:
+//   - start: The session started, with the interpreter version
+//   - eval: An input was submitted for evaluation
+//   - result: The evaluation finished, with its duration and result type,
+//     or its parse errors or runtime error
+//   - command: A command such as ":help" ran, with whether it failed
+//   - interrupt: Ctrl+C interrupted the running evaluation
+//   - exit: The session ended
+type LogEvent struct {
+	Time       time.Time `json:"time"`
+	Event      string    `json:"event"`
+	Workspace  string    `json:"workspace,omitempty"`
+	Input      string    `json:"input,omitempty"`
+	DurationNs int64     `json:"duration_ns,omitempty"`
+	Result     string    `json:"result,omitempty"` // The type of the value, e.g. "INTEGER"
+	Errors     []string  `json:"errors,omitempty"` // The parse errors or the runtime error
+	Failed     bool      `json:"failed,omitempty"`
+	Version    string    `json:"version,omitempty"`
+}
+
+// eventLog writes LogEvents as JSON lines. A nil eventLog writes nothing.
+type eventLog struct {
+	enc *json.Encoder
+}
+
+// newEventLog returns a log writing to w, or nil if w is nil.
+func newEventLog(w io.Writer) *eventLog {
+	if w == nil {
+		return nil
+	}
+	return &eventLog{enc: json.NewEncoder(w)}
+}
+
+// write writes ev, timestamped now. Failures to write are ignored, since the
+// log must never get in the way of the session.
+func (l *eventLog) write(ev LogEvent) {
+	if l == nil {
+		return
+	}
+	ev.Time = time.Now()
+	_ = l.enc.Encode(ev)
+}
+
+// start logs the start of a session.
+func (l *eventLog) start() {
+	l.write(LogEvent{Event: "start", Version: version.Version})
+}
+
+// result logs the end of an evaluation.
+func (l *eventLog) result(workspace string, msg evalResultMsg) {
+	ev := LogEvent{Event: "result", Workspace: workspace, DurationNs: msg.elapsed.Nanoseconds(), Failed: msg.isError}
+	switch {
+	case msg.errorType == ParseError:
+		ev.Errors = msg.parseErrors
+	case msg.value == nil:
+	case msg.value.Type() == object.ERROR_OBJ:
+		ev.Result = string(object.ERROR_OBJ)
+		ev.Errors = []string{msg.value.(*object.Error).Message}
+	default:
+		ev.Result = string(msg.value.Type())
+	}
+	l.write(ev)
+}
@@ -0,0 +1,90 @@
+package repl
+
+import (
+	"charm.land/bubbles/v2/textinput"
+	tea "charm.land/bubbletea/v2"
+)
+
+// maxKills is the number of kills the kill ring remembers.
+const maxKills = 16
+
+// killRing adds the kill ring of readline to the input. The text input already
+// deletes words and lines with Ctrl+W, Alt+D, Ctrl+U and Ctrl+K; the kill ring
+// keeps what they delete so Ctrl+Y can yank it back at the cursor, and Alt+Y
+// right after a yank replaces it with an older kill.
+type killRing struct {
+	kills   []string // The killed texts, most recent last
+	killing bool     // The previous key was a kill, which the next one extends
+	yank    *yank    // The last yank, while Alt+Y may replace it
+}
+
+// yank is text inserted by Ctrl+Y or Alt+Y.
+type yank struct {
+	start, length int // The runes of the input it occupies
+	index         int // Its index in the kills
+}
+
+// isKill reports whether key deletes text the kill ring should keep.
+func isKill(key string) bool {
+	switch key {
+	case "ctrl+w", "alt+backspace", "alt+d", "alt+delete", "ctrl+u", "ctrl+k":
+		return true
+	}
+	return false
+}
+
+// update passes msg to input, keeping the text it kills and handling yanks.
+func (k *killRing) update(input *textinput.Model, msg tea.KeyPressMsg) tea.Cmd {
+	key := msg.String()
+	switch {
+	case key == "ctrl+y" && len(k.kills) > 0:
+		k.killing = false
+		k.insert(input, len(k.kills)-1)
+		return nil
+	case key == "alt+y" && k.yank != nil:
+		// Replace the yanked text with the kill before it
+		value := []rune(input.Value())
+		end := k.yank.start + k.yank.length
+		input.SetValue(string(value[:k.yank.start]) + string(value[end:]))
+		input.SetCursor(k.yank.start)
+		k.insert(input, (k.yank.index+len(k.kills)-1)%len(k.kills))
+		return nil
+	}
+
+	before, pos := []rune(input.Value()), input.Position()
+	var cmd tea.Cmd
+	*input, cmd = input.Update(msg)
+	k.yank = nil
+	if !isKill(key) {
+		k.killing = false
+		return cmd
+	}
+
+	after, start := []rune(input.Value()), input.Position()
+	if len(after) < len(before) {
+		killed := string(before[start : start+len(before)-len(after)])
+		switch {
+		case !k.killing:
+			k.kills = append(k.kills, killed)
+			if len(k.kills) > maxKills {
+				k.kills = k.kills[1:]
+			}
+		case start < pos:
+			// Kills backwards from the cursor go in front of the text killed before
+			k.kills[len(k.kills)-1] = killed + k.kills[len(k.kills)-1]
+		default:
+			k.kills[len(k.kills)-1] += killed
+		}
+	}
+	k.killing = true
+	return cmd
+}
+
+// insert inserts the kill at index at the cursor of input, as a yank.
+func (k *killRing) insert(input *textinput.Model, index int) {
+	value, pos := []rune(input.Value()), input.Position()
+	text := []rune(k.kills[index])
+	input.SetValue(string(value[:pos]) + string(text) + string(value[pos:]))
+	input.SetCursor(pos + len(text))
+	k.yank = &yank{start: pos, length: len(text), index: index}
+}
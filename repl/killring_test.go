@@ -0,0 +1,103 @@
+package repl
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"charm.land/bubbles/v2/textinput"
+	tea "charm.land/bubbletea/v2"
+)
+
+// press returns the key press of a key in the form KeyPressMsg.String uses,
+// such as "ctrl+w", "alt+backspace" or a single character.
+func press(key string) tea.KeyPressMsg {
+	var msg tea.KeyPressMsg
+	for {
+		if rest, ok := strings.CutPrefix(key, "ctrl+"); ok {
+			msg.Mod |= tea.ModCtrl
+			key = rest
+		} else if rest, ok := strings.CutPrefix(key, "alt+"); ok {
+			msg.Mod |= tea.ModAlt
+			key = rest
+		} else {
+			break
+		}
+	}
+	switch key {
+	case "backspace":
+		msg.Code = tea.KeyBackspace
+	case "delete":
+		msg.Code = tea.KeyDelete
+	case "left":
+		msg.Code = tea.KeyLeft
+	case "end":
+		msg.Code = tea.KeyEnd
+	default:
+		msg.Code = []rune(key)[0]
+		if msg.Mod == 0 {
+			msg.Text = key
+		}
+	}
+	return msg
+}
+
+func TestKillRing(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		cursor int // -1 for the end of the value
+		keys   []string
+		want   string   // The value after the keys
+		kills  []string // The kill ring after the keys
+	}{
+		{"kill word", "let foo bar", -1, []string{"ctrl+w"}, "let foo ", []string{"bar"}},
+		{"kill to start", "let foo", -1, []string{"ctrl+u"}, "", []string{"let foo"}},
+		{"kill to end", "let foo", 4, []string{"ctrl+k"}, "let ", []string{"foo"}},
+		{"append backwards", "let foo bar", -1, []string{"ctrl+w", "ctrl+w"}, "let ", []string{"foo bar"}},
+		{"append forwards", "let foo bar", 4, []string{"alt+d", "ctrl+k"}, "let ", []string{"foo bar"}},
+		{"separate kills", "let foo bar", -1, []string{"ctrl+w", "end", "ctrl+w"}, "let ", []string{"bar", "foo "}},
+		{"typing ends a kill", "foo bar", -1, []string{"ctrl+w", "x", "ctrl+w"}, "foo ", []string{"bar", "x"}},
+		{"yank", "let foo", -1, []string{"ctrl+w", "ctrl+y"}, "let foo", []string{"foo"}},
+		{"yank at cursor", "foo bar", -1, []string{"ctrl+w", "left", "ctrl+y"}, "foobar ", []string{"bar"}},
+		{"yank twice", "foo", -1, []string{"ctrl+w", "ctrl+y", "ctrl+y"}, "foofoo", []string{"foo"}},
+		{"yank empty ring", "foo", -1, []string{"ctrl+y"}, "foo", nil},
+		{"rotate", "one two", -1, []string{"ctrl+w", "end", "ctrl+w", "ctrl+y", "alt+y"}, "two", []string{"two", "one "}},
+		{"rotate wraps", "one two", -1, []string{"ctrl+w", "end", "ctrl+w", "ctrl+y", "alt+y", "alt+y"}, "one ", []string{"two", "one "}},
+		{"rotate without yank", "one two", -1, []string{"ctrl+w", "end", "alt+y"}, "one ", []string{"two"}},
+		{"rotate after typing", "one two", -1, []string{"ctrl+w", "ctrl+y", "x", "alt+y"}, "one twox", []string{"two"}},
+	}
+
+	for _, tt := range tests {
+		input := textinput.New()
+		input.Focus()
+		input.SetValue(tt.value)
+		if tt.cursor >= 0 {
+			input.SetCursor(tt.cursor)
+		}
+		var k killRing
+		for _, key := range tt.keys {
+			k.update(&input, press(key))
+		}
+		if got := input.Value(); got != tt.want {
+			t.Errorf("%s: wrong value. expected=%q, got=%q", tt.name, tt.want, got)
+		}
+		if !slices.Equal(k.kills, tt.kills) {
+			t.Errorf("%s: wrong kills. expected=%q, got=%q", tt.name, tt.kills, k.kills)
+		}
+	}
+}
+
+func TestKillRingLimit(t *testing.T) {
+	input := textinput.New()
+	input.Focus()
+	var k killRing
+	for i := range maxKills + 2 {
+		input.SetValue(string(rune('a' + i)))
+		k.update(&input, press("ctrl+u"))
+		k.update(&input, press("end"))
+	}
+	if len(k.kills) != maxKills || k.kills[0] != "c" || k.kills[maxKills-1] != string(rune('a'+maxKills+1)) {
+		t.Errorf("expected the %d most recent kills, got=%q", maxKills, k.kills)
+	}
+}
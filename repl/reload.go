@@ -0,0 +1,31 @@
+package repl
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dr8co/monke/evaluator"
+	"github.com/dr8co/monke/object"
+)
+
+// reloadCommand runs ":reload" with args, returning its output and whether it
+// failed. The module is evaluated again from its current source, and the
+// bindings of every workspace that still hold its old values are updated
+// (see evaluator.ReloadModule), so the rest of the session is kept.
+func (m *model) reloadCommand(args []string) (string, bool) {
+	if len(args) != 1 {
+		return "Usage: :reload <module>", true
+	}
+	envs := []*object.Environment{m.env}
+	for _, ws := range m.workspaces {
+		envs = append(envs, ws.env)
+	}
+	name, rebound, err := evaluator.ReloadModule(args[0], envs...)
+	if err != nil {
+		return fmt.Sprintf("Cannot reload %s: %s", args[0], err), true
+	}
+	if len(rebound) == 0 {
+		return fmt.Sprintf("Reloaded %s; no bindings refer to it any more.", name), false
+	}
+	return fmt.Sprintf("Reloaded %s, updating %s.", name, strings.Join(rebound, ", ")), false
+}
@@ -8,15 +8,29 @@
 // Key features:
 //   - Interactive command input and execution
 //   - Command history tracking
+//   - Readline-style editing keys, with a kill ring for Ctrl+Y
 //   - Styled output with different colors for results and errors
-//   - Persistent environment across commands
+//   - Persistent environment across commands, with separate workspaces
+//     switched with ":workspace use <name>"
+//   - Reference of builtins and keywords with ":help <name>"
+//   - The bindings each evaluation changes, with ":diff on", and the values
+//     of watch expressions added with ":watch <expression>"
+//   - Imported modules evaluated again after editing them, with ":reload <module>"
+//   - Commands added by embedders through the Command interface, along with
+//     ":env" and ":history"
+//   - Limits on the steps, call depth, memory and output of each evaluation,
+//     so that a runaway program fails instead of freezing the REPL; Ctrl+C
+//     interrupts an evaluation and ":unsafe" turns the limits off
 //
 // The main entry point is the Start function, which initializes and runs the REPL
-// with the given username.
+// with the given options.
 package repl
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"os/user"
 	"strings"
 	"time"
 
@@ -24,10 +38,12 @@ import (
 	"charm.land/bubbles/v2/textinput"
 	tea "charm.land/bubbletea/v2"
 	"charm.land/lipgloss/v2"
+	"github.com/dr8co/monke/doc"
 	"github.com/dr8co/monke/evaluator"
 	"github.com/dr8co/monke/lexer"
 	"github.com/dr8co/monke/object"
 	"github.com/dr8co/monke/parser"
+	"github.com/dr8co/monke/pipeline"
 	"github.com/dr8co/monke/token"
 )
 
@@ -39,22 +55,120 @@ const (
 	ContPrompt = ".. "
 )
 
+// The safety profile applied to every evaluation unless it is turned off with
+// ":unsafe", so that an infinite loop or recursion fails with an error instead
+// of freezing the REPL.
+const (
+	safeSteps  = 10_000_000 // Statements and loop iterations
+	safeDepth  = 10_000     // Nested function calls
+	safeMemory = 256 << 20  // Bytes of live objects
+	safeOutput = 64 << 10   // Bytes printed by puts
+)
+
+// setLimits applies the safety profile to the evaluations that follow, with
+// maxMemory in place of its memory limit if set, or removes its limits if safe
+// is false.
+func setLimits(safe bool, maxMemory int) {
+	if !safe {
+		evaluator.SetStepLimit(0)
+		evaluator.SetDepthLimit(0)
+		evaluator.SetMemoryLimit(0)
+		return
+	}
+	if maxMemory == 0 {
+		maxMemory = safeMemory
+	}
+	evaluator.SetStepLimit(safeSteps)
+	evaluator.SetDepthLimit(safeDepth)
+	evaluator.SetMemoryLimit(maxMemory)
+}
+
+// outputBuffer collects the output of an evaluation, keeping at most limit
+// bytes of it unless limit is 0.
+type outputBuffer struct {
+	strings.Builder
+	limit     int
+	truncated bool
+}
+
+// Write implements io.Writer. Output past the limit is dropped rather than
+// failing, so the program keeps running.
+func (b *outputBuffer) Write(p []byte) (int, error) {
+	if b.limit > 0 && b.Len()+len(p) > b.limit {
+		b.truncated = true
+		b.Builder.Write(p[:b.limit-b.Len()])
+		return len(p), nil
+	}
+	return b.Builder.Write(p)
+}
+
+// String returns the collected output, noting if some of it was dropped.
+func (b *outputBuffer) String() string {
+	if b.truncated {
+		return b.Builder.String() + fmt.Sprintf("\n... output truncated after %d KiB\n", b.limit>>10)
+	}
+	return b.Builder.String()
+}
+
 // Options contains configuration options for the REPL
 type Options struct {
-	NoColor bool // Disable syntax highlighting and colored output
-	Debug   bool // Enable debug mode with more verbose output
+	NoColor bool                // Disable syntax highlighting and colored output
+	Debug   bool                // Enable debug mode with more verbose output
+	Env     *object.Environment // Environment to evaluate in; a new one is created if nil
+	Guide   Guide               // Leads the session through exercises, if set
+
+	// MaxMemory replaces the memory limit of the safety profile, in bytes, if set
+	MaxMemory int
+
+	// Log receives a JSON line per event of the session, such as inputs and
+	// their results, if set; see LogEvent
+	Log io.Writer
+
+	// Commands are added to the commands of the REPL; see Command
+	Commands []Command
+
+	// Username is greeted by the welcome message; if empty, it is the name of
+	// the user running the REPL (see DefaultUsername)
+	Username string
 }
 
-// Start initializes and runs the REPL with the given username and options.
+// Guide leads a REPL session, as the lessons of "monke learn" do.
+type Guide interface {
+	// Prompt returns the text shown above the input, such as the current exercise.
+	Prompt() string
+	// Evaluated is called with the value of every evaluation, or nil if it had
+	// none, and returns feedback to show under the result.
+	Evaluated(value object.Object) string
+}
+
+// DefaultUsername returns the name the REPL greets when the options name no
+// one: the login name of the user, or else $USER or $USERNAME, or "friend" if
+// none is known, as in containers without a passwd entry for the user. The
+// name is only shown, so failing to look it up is not an error.
+func DefaultUsername() string {
+	if usr, err := user.Current(); err == nil && usr.Username != "" {
+		return usr.Username
+	}
+	for _, name := range []string{"USER", "USERNAME"} {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return "friend"
+}
+
+// Start initializes and runs the REPL with the given options.
 // It creates a new bubbletea program with an initial model and runs it.
-// The username is displayed in the welcome message of the REPL.
 // If an error occurs while running the program, it is printed to the console.
-func Start(username string, options Options) {
+func Start(options Options) {
 	// Start the bubbletea program
-	p := tea.NewProgram(initialModel(username, options))
+	m := initialModel(options)
+	m.log.start()
+	p := tea.NewProgram(m)
 	if _, err := p.Run(); err != nil {
 		fmt.Println("Error running program:", err)
 	}
+	m.log.write(LogEvent{Event: "exit"})
 }
 
 // Styling
@@ -90,6 +204,9 @@ var (
 	historyStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("#767676"))
 
+	guideStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#8BE9FD"))
+
 	// Syntax highlighting styles
 	keywordStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("#FF79C6")).
@@ -128,10 +245,15 @@ const (
 
 // Custom messages for async evaluation
 type evalResultMsg struct {
-	output    string
-	isError   bool
-	errorType ErrorType
-	elapsed   time.Duration
+	output      string
+	printed     string        // the output of puts during the evaluation
+	changes     string        // the bindings the evaluation added or changed, if shown
+	watched     string        // the values of the watch expressions, if any
+	parseErrors []string      // the messages of the parse errors, if any
+	value       object.Object // the evaluated value, nil if there is none
+	isError     bool
+	errorType   ErrorType
+	elapsed     time.Duration
 }
 
 // The model represents the state of the application
@@ -146,6 +268,13 @@ type model struct {
 	isMultiline     bool   // Flag to indicate if we're in multiline mode
 	spinner         spinner.Model
 	options         Options
+	unsafe          bool // The safety profile is turned off with ":unsafe"
+	kills           killRing
+	workspace       string               // The name of the current workspace
+	workspaces      map[string]workspace // The other workspaces, by name
+	showChanges     bool                 // Show the bindings each evaluation changes, set with ":diff on"
+	watches         []string             // The watch expressions, added with ":watch"
+	log             *eventLog            // The event log, or nil
 }
 
 // applyStyle applies a lipgloss style to a string, respecting the NoColor option
@@ -160,13 +289,18 @@ func (m model) applyStyle(style lipgloss.Style, text string) string {
 type historyEntry struct {
 	input          string
 	output         string
+	printed        string // Printed by the input, shown before its output
+	changes        string // The bindings the input added or changed, shown after its output
+	watched        string // The values of the watch expressions, shown after the changes
 	isError        bool
 	errorType      ErrorType
 	evaluationTime time.Duration // Time taken to evaluate
+	feedback       string        // Feedback from the guide, if any
+	command        bool          // The input is a REPL command such as ":help", not code
 }
 
 // initialModel creates a new model with default values
-func initialModel(username string, options Options) model {
+func initialModel(options Options) model {
 	ti := textinput.New()
 	ti.Placeholder = "Enter Monkey code"
 	ti.Focus()
@@ -177,16 +311,27 @@ func initialModel(username string, options Options) model {
 	s.Spinner = spinner.Dot
 	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF79C6"))
 
+	env := options.Env
+	if env == nil {
+		env = object.NewEnvironment()
+	}
+	username := options.Username
+	if username == "" {
+		username = DefaultUsername()
+	}
+
 	return model{
 		textInput:       ti,
 		history:         []historyEntry{},
-		env:             object.NewEnvironment(),
+		env:             env,
 		username:        username,
 		evaluating:      false,
 		multilineBuffer: "",
 		isMultiline:     false,
 		spinner:         s,
 		options:         options,
+		workspace:       defaultWorkspace,
+		log:             newEventLog(options.Log),
 	}
 }
 
@@ -224,137 +369,161 @@ func isBalanced(input string) bool {
 	return len(stack) == 0
 }
 
-// evalCmd is a command that evaluates Monkey code asynchronously
-func evalCmd(input string, env *object.Environment, debug bool) tea.Cmd {
+// evalCmd is a command that evaluates Monkey code asynchronously in the
+// current workspace, within the safety profile unless it is turned off
+func (m model) evalCmd(input string) tea.Cmd {
+	m.log.write(LogEvent{Event: "eval", Workspace: m.workspace, Input: input})
+	env, options, safe, showChanges, watches := m.env, m.options, !m.unsafe, m.showChanges, m.watches
+	debug := options.Debug
 	return func() tea.Msg {
 		start := time.Now()
 
-		// Process the input
-		l := lexer.New(input)
-		p := parser.New(l)
-
-		// Debug: Print tokenization time
-		var tokenizeTime time.Duration
-		if debug {
-			tokenizeStart := time.Now()
-			program := p.ParseProgram()
-			tokenizeTime = time.Since(tokenizeStart)
-			var output string
-			isError := false
-			errorType := NoError
-			if len(p.Errors()) != 0 {
-				isError = true
-				errorType = ParseError
-				output = formatParseErrors(p.Errors())
-
-				if debug {
-					fmt.Printf("DEBUG: Parse errors: %v\n", p.Errors())
-				}
-			} else {
-				// Debug: Print evaluation time
-				evalStart := time.Now()
-				evaluated := evaluator.Eval(program, env)
-				evalTime := time.Since(evalStart)
-
-				if debug {
-					fmt.Printf("DEBUG: Tokenize time: %v\n", tokenizeTime)
-					fmt.Printf("DEBUG: Eval time: %v\n", evalTime)
-				}
-
-				if evaluated != nil {
-					// Check if the result is an error object
-					if evaluated.Type() == object.ERROR_OBJ {
-						isError = true
-						errorType = RuntimeError
-						output = formatRuntimeError(evaluated.Inspect())
-
-						if debug {
-							fmt.Printf("DEBUG: Runtime error: %s\n", evaluated.Inspect())
-						}
-					} else {
-						output = evaluated.Inspect()
-
-						if debug {
-							fmt.Printf("DEBUG: Result type: %s\n", evaluated.Type())
-						}
-					}
-				} else {
-					output = "nil"
-				}
-			}
-
-			elapsed := time.Since(start)
+		var before map[string]binding
+		if showChanges {
+			before = snapshot(env)
+		}
 
-			if debug {
-				fmt.Printf("DEBUG: Total execution time: %v\n", elapsed)
-			}
+		// Collect the output of puts, since printing it would garble the screen
+		setLimits(safe, options.MaxMemory)
+		printed := &outputBuffer{}
+		if safe {
+			printed.limit = safeOutput
+		}
+		evaluator.SetOutput(printed)
+		defer evaluator.SetOutput(os.Stdout)
 
-			return evalResultMsg{
-				output:    output,
-				isError:   isError,
-				errorType: errorType,
-				elapsed:   elapsed,
-			}
+		var middleware []pipeline.Middleware
+		if debug {
+			middleware = append(middleware, debugMiddleware(start))
 		}
-		// Non-debug path (original code)
-		program := p.ParseProgram()
+		result := pipeline.Run(input, env, middleware...)
 
 		var output string
+		var parseErrors []string
 		isError := false
 		errorType := NoError
-
-		if len(p.Errors()) != 0 {
+		switch {
+		case len(result.Errors) != 0:
 			isError = true
 			errorType = ParseError
-			output = formatParseErrors(p.Errors())
-		} else {
-			evaluated := evaluator.Eval(program, env)
-			if evaluated != nil {
-				// Check if the result is an error object
-				if evaluated.Type() == object.ERROR_OBJ {
-					isError = true
-					errorType = RuntimeError
-					output = formatRuntimeError(evaluated.Inspect())
-				} else {
-					output = evaluated.Inspect()
-				}
-			} else {
-				output = "nil"
+			output = pipeline.FormatParseErrors(input, result.Errors)
+			for _, err := range result.Errors {
+				parseErrors = append(parseErrors, err.Message)
 			}
+		case result.Error() != nil:
+			isError = true
+			errorType = RuntimeError
+			output = pipeline.FormatRuntimeError(input, result.Error())
+		case result.Value != nil:
+			output = result.Value.Inspect()
+		default:
+			output = "nil"
 		}
 
-		elapsed := time.Since(start)
+		// Runtime warnings are shown with the parser's
+		output = formatWarnings(append(result.Warnings, result.Eval.Warnings...)) + output
 
 		return evalResultMsg{
-			output:    output,
-			isError:   isError,
-			errorType: errorType,
-			elapsed:   elapsed,
+			output:      output,
+			printed:     printed.String(),
+			changes:     changes(before, env),
+			watched:     watchValues(watches, env),
+			parseErrors: parseErrors,
+			value:       result.Value,
+			isError:     isError,
+			errorType:   errorType,
+			elapsed:     result.Eval.Duration,
 		}
 	}
 }
 
-// formatError formats error messages.
-func (m model) formatError(errorStyle *lipgloss.Style, entry *historyEntry, s *strings.Builder) {
-	// Split the output to separate the error message from the tips
-	parts := strings.Split(entry.output, "\nTips:")
-	if len(parts) > 1 {
-		if m.options.NoColor {
-			s.WriteString(parts[0])
-			s.WriteString("\n")
-			s.WriteString("Tips:" + parts[1])
-		} else {
-			s.WriteString(errorStyle.Render(parts[0]))
-			s.WriteString("\n")
-			s.WriteString(errorTipStyle.Render("Tips:" + parts[1]))
-		}
-	} else {
-		if m.options.NoColor {
-			s.WriteString(entry.output)
-		} else {
-			s.WriteString(errorStyle.Render(entry.output))
+// debugMiddleware prints how long each stage of an evaluation takes, along
+// with the builtins it called and its outcome. start is when the evaluation
+// began.
+func debugMiddleware(start time.Time) pipeline.Middleware {
+	return pipeline.Middleware{
+		Program: func(*pipeline.Result) bool {
+			fmt.Printf("DEBUG: Tokenize time: %v\n", time.Since(start))
+			return true
+		},
+		Result: func(result *pipeline.Result) {
+			if len(result.Errors) != 0 {
+				messages := make([]string, len(result.Errors))
+				for i, err := range result.Errors {
+					messages[i] = err.Message
+				}
+				fmt.Printf("DEBUG: Parse errors: %v\n", messages)
+			} else {
+				fmt.Printf("DEBUG: Eval time: %v, %d steps, %d objects allocated\n",
+					result.Eval.Duration, result.Eval.Steps, result.Eval.AllocatedObjects)
+				for _, s := range evaluator.BuiltinStats() {
+					fmt.Printf("DEBUG: Builtin %s: %d calls, %v total\n", s.Name, s.Calls, s.Time)
+				}
+				switch {
+				case result.Error() != nil:
+					fmt.Printf("DEBUG: Runtime error: %s\n", result.Value.Inspect())
+				case result.Value != nil:
+					fmt.Printf("DEBUG: Result type: %s\n", result.Value.Type())
+				}
+			}
+			fmt.Printf("DEBUG: Total execution time: %v\n", time.Since(start))
+		},
+	}
+}
+
+// runCommand runs a REPL command such as ":help len", returning its output
+// and whether it failed.
+func (m *model) runCommand(input string) (string, bool) {
+	fields := strings.Fields(input)
+	switch {
+	case (fields[0] == ":unsafe" || fields[0] == ":safe") && len(fields) > 1:
+		return "Usage: " + fields[0], true
+	case fields[0] == ":unsafe":
+		m.unsafe = true
+		return "Limits turned off: evaluations may now run and grow without bounds. Type :safe to restore them.", false
+	case fields[0] == ":safe":
+		m.unsafe = false
+		return "Limits restored: evaluations fail once they run too long, recurse too deeply or use too much memory," +
+			" and long output is truncated.", false
+	case fields[0] == ":diff":
+		return m.diffCommand(fields[1:])
+	case fields[0] == ":workspace":
+		return m.workspaceCommand(fields[1:])
+	case fields[0] == ":reload":
+		return m.reloadCommand(fields[1:])
+	case fields[0] == ":watch":
+		return m.watchCommand(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(input), ":watch")))
+	case fields[0] != ":help":
+		if cmd, ok := m.command(strings.TrimPrefix(fields[0], ":")); ok {
+			return m.runPlugin(cmd, fields[1:])
 		}
+		return fmt.Sprintf("Unknown command %s. Type :help for the list of builtins and keywords.", fields[0]), true
+	case len(fields) == 1:
+		return "Type :help <name> for the reference of a builtin or keyword, and :unsafe to turn off\n" +
+			"the limits on evaluations (:safe restores them). :workspace new <name> starts a separate\n" +
+			"environment with its own history, :workspace use <name> switches between them, and\n" +
+			":workspace delete <name> discards one that is not in use.\n" +
+			":diff on shows the bindings each evaluation adds or changes under its result,\n" +
+			":watch <expression> shows the value of an expression after each evaluation, and\n" +
+			":reload <module> evaluates an imported module again after its source changed.\n\n" +
+			"More commands:\n" + m.commandHelp() + "\n" +
+			strings.TrimRight(doc.Index(), "\n"), false
+	case len(fields) > 2:
+		return "Usage: :help <name>", true
+	}
+	if cmd, ok := m.command(strings.TrimPrefix(fields[1], ":")); ok && strings.HasPrefix(fields[1], ":") {
+		return fmt.Sprintf(":%s: %s", cmd.Name(), cmd.Help()), false
 	}
+	entry, ok := doc.Lookup(fields[1])
+	if !ok {
+		return fmt.Sprintf("No reference for %q. Type :help for the list of builtins and keywords.", fields[1]), true
+	}
+	return strings.TrimRight(doc.Format(entry), "\n"), false
+}
+
+// formatError formats error messages.
+func (m model) formatError(errorStyle *lipgloss.Style, entry *historyEntry, s *strings.Builder) {
+	s.WriteString(styleError(*errorStyle, entry.output, m.options.NoColor))
 }
 
 // Update handles all the updates to our model
@@ -371,22 +540,32 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case evalResultMsg:
 		// Evaluation completed
 		m.evaluating = false
+		m.log.result(m.workspace, msg)
 
 		// Add to history
-		m.history = append(m.history, historyEntry{
+		entry := historyEntry{
 			input:          m.currentInput,
 			output:         msg.output,
+			printed:        msg.printed,
+			changes:        msg.changes,
+			watched:        msg.watched,
 			isError:        msg.isError,
 			errorType:      msg.errorType,
 			evaluationTime: msg.elapsed,
-		})
+		}
+		if m.options.Guide != nil {
+			entry.feedback = m.options.Guide.Evaluated(msg.value)
+		}
+		m.history = append(m.history, entry)
 
 		m.currentInput = ""
 		return m, nil
 
 	case tea.KeyPressMsg:
-		// If we're evaluating, ignore key presses except for Ctrl+C
+		// If we're evaluating, Ctrl+C interrupts the evaluation instead of exiting
 		if m.evaluating && msg.String() == "ctrl+c" {
+			evaluator.Interrupt()
+			m.log.write(LogEvent{Event: "interrupt", Workspace: m.workspace})
 			return m, m.spinner.Tick
 		}
 
@@ -413,7 +592,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					buffer := m.multilineBuffer
 					m.multilineBuffer = ""
 
-					return m, evalCmd(buffer, m.env, m.options.Debug)
+					return m, m.evalCmd(buffer)
 				}
 				return m, nil
 			}
@@ -434,12 +613,26 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					buffer := m.multilineBuffer
 					m.multilineBuffer = ""
 
-					return m, evalCmd(buffer, m.env, m.options.Debug)
+					return m, m.evalCmd(buffer)
 				}
 
 				return m, nil
 			}
 
+			// Commands are handled by the REPL itself rather than evaluated
+			if strings.HasPrefix(strings.TrimSpace(input), ":") {
+				output, isError := m.runCommand(input)
+				m.log.write(LogEvent{Event: "command", Workspace: m.workspace, Input: strings.TrimSpace(input), Failed: isError})
+				m.history = append(m.history, historyEntry{
+					input:   strings.TrimSpace(input),
+					output:  output,
+					isError: isError,
+					command: true,
+				})
+				m.textInput.SetValue("")
+				return m, nil
+			}
+
 			// Check if the input has balanced brackets
 			if !isBalanced(input) {
 				// Enter multiline mode
@@ -454,13 +647,17 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.currentInput = input
 			m.textInput.SetValue("")
 
-			return m, evalCmd(input, m.env, m.options.Debug)
+			return m, m.evalCmd(input)
 		}
 	}
 
 	// Only update the text input if we're not evaluating
 	if !m.evaluating {
-		m.textInput, cmd = m.textInput.Update(msg)
+		if key, ok := msg.(tea.KeyPressMsg); ok {
+			cmd = m.kills.update(&m.textInput, key)
+		} else {
+			m.textInput, cmd = m.textInput.Update(msg)
+		}
 	}
 
 	// Ensure the spinner keeps ticking while evaluating
@@ -495,10 +692,15 @@ func (m model) View() tea.View {
 			} else {
 				s.WriteString(m.applyStyle(promptStyle, ContPrompt))
 			}
-			s.WriteString(m.highlightCode(line))
+			if entry.command {
+				s.WriteString(line)
+			} else {
+				s.WriteString(m.highlightCode(line))
+			}
 			s.WriteString("\n")
 		}
 
+		s.WriteString(entry.printed)
 		if entry.isError {
 			// Use different styles based on the error type
 			switch entry.errorType {
@@ -531,6 +733,21 @@ func (m model) View() tea.View {
 			}
 		}
 
+		if entry.changes != "" {
+			s.WriteString("\n")
+			s.WriteString(m.applyStyle(historyStyle, entry.changes))
+		}
+
+		if entry.watched != "" {
+			s.WriteString("\n")
+			s.WriteString(m.applyStyle(historyStyle, entry.watched))
+		}
+
+		if entry.feedback != "" {
+			s.WriteString("\n")
+			s.WriteString(m.applyStyle(guideStyle, entry.feedback))
+		}
+
 		s.WriteString("\n\n")
 	}
 
@@ -544,7 +761,7 @@ func (m model) View() tea.View {
 		s.WriteString(m.highlightCode(m.currentInput))
 		s.WriteString("\n")
 		s.WriteString(m.spinner.View())
-		s.WriteString(" Evaluating...")
+		s.WriteString(" Evaluating... (Ctrl+C to interrupt)")
 		s.WriteString("\n\n")
 	}
 
@@ -560,6 +777,12 @@ func (m model) View() tea.View {
 		s.WriteString("\n")
 	}
 
+	// The guide's instructions for the next input
+	if m.options.Guide != nil && !m.evaluating {
+		s.WriteString(m.applyStyle(guideStyle, m.options.Guide.Prompt()))
+		s.WriteString("\n\n")
+	}
+
 	// Input
 	if !m.evaluating {
 		// Set the appropriate prompt based on whether we're in multiline mode
@@ -585,7 +808,13 @@ func (m model) View() tea.View {
 	if m.isMultiline {
 		helpText += " | Multiline mode: Enter empty line to evaluate or continue typing"
 	} else {
-		helpText += " | Multiline input supported for unbalanced brackets"
+		helpText += " | Multiline input supported for unbalanced brackets | :help <name> for reference"
+	}
+	if m.unsafe {
+		helpText += " | Limits off, :safe to restore them"
+	}
+	if len(m.workspaces) > 0 {
+		helpText += " | Workspace " + m.workspace
 	}
 	if m.options.NoColor {
 		s.WriteString(helpText)
@@ -596,52 +825,37 @@ func (m model) View() tea.View {
 	return tea.NewView(s.String())
 }
 
-// formatParseErrors formats parser errors into a string with improved readability
-func formatParseErrors(errors []string) string {
-	var s strings.Builder
-	s.WriteString("Parser Errors:\n")
-
-	for i, msg := range errors {
-		fmt.Fprintf(&s, "  %d. %s\n", i+1, msg)
-	}
+// FormatParseErrors formats the parse errors of source the way the REPL shows them,
+// pointing at the offending code. Colors are used unless noColor is set.
+func FormatParseErrors(source string, errors []parser.Error, noColor bool) string {
+	return styleError(parseErrorStyle, pipeline.FormatParseErrors(source, errors), noColor)
+}
 
-	s.WriteString("\nTips:\n")
-	s.WriteString("  • Check for missing parentheses, braces, or semicolons\n")
-	s.WriteString("  • Verify that all expressions are properly terminated\n")
-	s.WriteString("  • Ensure variable names are valid identifiers\n")
+// FormatRuntimeError formats a runtime error raised by source the way the REPL shows it,
+// pointing at the offending code. Colors are used unless noColor is set.
+func FormatRuntimeError(source string, err *object.Error, noColor bool) string {
+	return styleError(runtimeErrorStyle, pipeline.FormatRuntimeError(source, err), noColor)
+}
 
-	return s.String()
+// styleError renders a formatted error with the given style and its tips with errorTipStyle.
+func styleError(style lipgloss.Style, output string, noColor bool) string {
+	if noColor {
+		return output
+	}
+	// Split the output to separate the error message from the tips
+	parts := strings.SplitN(output, "\nTips:", 2)
+	if len(parts) > 1 {
+		return style.Render(parts[0]) + "\n" + errorTipStyle.Render("Tips:"+parts[1])
+	}
+	return style.Render(output)
 }
 
-// formatRuntimeError formats a runtime error into a string with improved readability
-func formatRuntimeError(errorMsg string) string {
+// formatWarnings formats parser warnings, one per line
+func formatWarnings(warnings []parser.Error) string {
 	var s strings.Builder
-	s.WriteString("Runtime Error:\n")
-	s.WriteString("  " + errorMsg + "\n")
-
-	s.WriteString("\nTips:\n")
-
-	// Add specific tips based on common error patterns
-	//nolint:gocritic
-	if strings.Contains(errorMsg, "identifier not found") {
-		s.WriteString("  • Check if the variable is defined before use\n")
-		s.WriteString("  • Verify the variable name is spelled correctly\n")
-		s.WriteString("  • Make sure the variable is in scope\n")
-	} else if strings.Contains(errorMsg, "wrong number of arguments") {
-		s.WriteString("  • Check the function call has the correct number of arguments\n")
-		s.WriteString("  • Verify the function definition matches its usage\n")
-	} else if strings.Contains(errorMsg, "type mismatch") {
-		s.WriteString("  • Ensure operands are of compatible types\n")
-		s.WriteString("  • Check if you need to convert types before operation\n")
-	} else if strings.Contains(errorMsg, "index") {
-		s.WriteString("  • Verify array indices are within bounds\n")
-		s.WriteString("  • Ensure you're indexing an array or hash\n")
-	} else {
-		s.WriteString("  • Review your code logic\n")
-		s.WriteString("  • Check for type mismatches or undefined variables\n")
-		s.WriteString("  • Consider breaking complex expressions into simpler steps\n")
+	for _, w := range warnings {
+		fmt.Fprintf(&s, "Warning at %s: %s\n", w.Pos, w.Message)
 	}
-
 	return s.String()
 }
 
@@ -663,7 +877,7 @@ func (m model) highlightCode(code string) string {
 
 	isKeyword := func(t token.Token) bool {
 		switch t.Type {
-		case token.FUNCTION, token.LET, token.TRUE, token.FALSE, token.IF, token.ELSE, token.RETURN:
+		case token.FUNCTION, token.LET, token.TRUE, token.FALSE, token.IF, token.ELSE, token.RETURN, token.DEFER, token.WHILE, token.FOR, token.IN, token.MATCH, token.IMPORT:
 			return true
 		}
 		return false
@@ -671,7 +885,7 @@ func (m model) highlightCode(code string) string {
 	isOperator := func(t token.Token) bool {
 		switch t.Type {
 		case token.ASSIGN, token.PLUS, token.MINUS, token.BANG, token.ASTERISK, token.SLASH,
-			token.LT, token.GT, token.EQ, token.NOT_EQ:
+			token.LT, token.GT, token.LT_EQ, token.GT_EQ, token.EQ, token.NOT_EQ:
 			return true
 		}
 		return false
@@ -730,7 +944,7 @@ func (m model) highlightCode(code string) string {
 		// Formatting rules (same as before)
 		if isKeyword(tok) && tok.Type != token.TRUE && tok.Type != token.FALSE {
 			switch tok.Type {
-			case token.LET, token.FUNCTION, token.RETURN, token.IF, token.ELSE:
+			case token.LET, token.FUNCTION, token.RETURN, token.IF, token.ELSE, token.DEFER, token.WHILE, token.FOR, token.IN, token.MATCH, token.IMPORT:
 				if m.options.NoColor {
 					s.WriteString(tok.Literal)
 				} else {
@@ -778,7 +992,7 @@ func (m model) highlightCode(code string) string {
 
 		// Syntax highlighting
 		switch tok.Type {
-		case token.FUNCTION, token.LET, token.TRUE, token.FALSE, token.IF, token.ELSE, token.RETURN:
+		case token.FUNCTION, token.LET, token.TRUE, token.FALSE, token.IF, token.ELSE, token.RETURN, token.DEFER, token.WHILE, token.FOR, token.IN, token.MATCH, token.IMPORT:
 			if m.options.NoColor {
 				s.WriteString(tok.Literal)
 			} else {
@@ -790,7 +1004,7 @@ func (m model) highlightCode(code string) string {
 			} else {
 				s.WriteString(identifierStyle.Render(tok.Literal))
 			}
-		case token.INT:
+		case token.INT, token.FLOAT:
 			if m.options.NoColor {
 				s.WriteString(tok.Literal)
 			} else {
@@ -802,8 +1016,14 @@ func (m model) highlightCode(code string) string {
 			} else {
 				s.WriteString(stringStyle.Render("\"" + tok.Literal + "\""))
 			}
+		case token.RAW_STRING:
+			if m.options.NoColor {
+				s.WriteString("`" + tok.Literal + "`")
+			} else {
+				s.WriteString(stringStyle.Render("`" + tok.Literal + "`"))
+			}
 		case token.ASSIGN, token.PLUS, token.MINUS, token.BANG, token.ASTERISK, token.SLASH,
-			token.LT, token.GT, token.EQ, token.NOT_EQ:
+			token.LT, token.GT, token.LT_EQ, token.GT_EQ, token.EQ, token.NOT_EQ:
 			if m.options.NoColor {
 				s.WriteString(tok.Literal)
 			} else {
@@ -0,0 +1,78 @@
+package repl
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/dr8co/monke/evaluator"
+	"github.com/dr8co/monke/object"
+	"github.com/dr8co/monke/parser"
+)
+
+// watchCommand runs ":watch" with the rest of its input, returning its output
+// and whether it failed. An expression is evaluated once to check it, then
+// again after every evaluation, in the current workspace, and its value is
+// shown under the result.
+func (m *model) watchCommand(arg string) (string, bool) {
+	switch arg {
+	case "":
+		if len(m.watches) == 0 {
+			return "No watch expressions. Type :watch <expression> to add one.", false
+		}
+		m.prepareWatch()
+		defer evaluator.SetOutput(os.Stdout)
+		return watchValues(m.watches, m.env), false
+	case "clear":
+		m.watches = nil
+		return "Removed the watch expressions.", false
+	}
+
+	m.prepareWatch()
+	defer evaluator.SetOutput(os.Stdout)
+	value, err := evaluator.EvalExpression(arg, m.env)
+	var syntax *parser.ErrorList
+	switch {
+	case errors.As(err, &syntax):
+		return fmt.Sprintf("Cannot watch %s: %s", arg, syntax.Errors[0].Message), true
+	case errors.Is(err, evaluator.ErrNotExpression):
+		return fmt.Sprintf("Cannot watch %s: it is not an expression", arg), true
+	}
+	m.watches = append(m.watches, arg)
+	return watchLine(arg, value, err), false
+}
+
+// prepareWatch applies the safety profile, unless it is turned off, to the
+// watch expressions evaluated by a command, and discards what they print.
+// The caller restores the output.
+func (m *model) prepareWatch() {
+	setLimits(!m.unsafe, m.options.MaxMemory)
+	evaluator.SetOutput(io.Discard)
+}
+
+// watchValues evaluates each of watches in env, returning one line per
+// expression with its value or why it failed.
+func watchValues(watches []string, env *object.Environment) string {
+	var s strings.Builder
+	for _, expr := range watches {
+		value, err := evaluator.EvalExpression(expr, env)
+		s.WriteString(watchLine(expr, value, err) + "\n")
+	}
+	return strings.TrimRight(s.String(), "\n")
+}
+
+// watchLine describes the value of the watch expression expr, or why it failed.
+func watchLine(expr string, value object.Object, err error) string {
+	var runtimeErr *evaluator.RuntimeError
+	switch {
+	case errors.As(err, &runtimeErr):
+		return fmt.Sprintf("%s: %s", expr, runtimeErr.Err.Message)
+	case err != nil:
+		return fmt.Sprintf("%s: %s", expr, err)
+	case value == nil:
+		return fmt.Sprintf("%s = nil", expr)
+	}
+	return fmt.Sprintf("%s = %s (%s)", expr, summarize(value.Inspect()), value.Type())
+}
@@ -0,0 +1,89 @@
+package repl
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/dr8co/monke/object"
+)
+
+// defaultWorkspace is the name of the workspace a session starts in.
+const defaultWorkspace = "main"
+
+// workspace is an independent environment of a session, with its own history,
+// e.g. to try two approaches to a problem side by side.
+type workspace struct {
+	env     *object.Environment
+	history []historyEntry
+}
+
+// workspaceCommand runs ":workspace" with args, returning its output and
+// whether it failed. The current workspace lives in the model's env and
+// history; the others are kept in its workspaces until they are used again.
+func (m *model) workspaceCommand(args []string) (string, bool) {
+	const usage = "Usage: :workspace [new NAME | use NAME | delete NAME]"
+	if len(args) == 0 {
+		names := []string{m.workspace}
+		for name := range m.workspaces {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		var s strings.Builder
+		for _, name := range names {
+			marker := "  "
+			if name == m.workspace {
+				marker = "* "
+			}
+			s.WriteString(marker + name + "\n")
+		}
+		return strings.TrimRight(s.String(), "\n"), false
+	}
+	if len(args) != 2 {
+		return usage, true
+	}
+
+	name := args[1]
+	_, exists := m.workspaces[name]
+	switch args[0] {
+	case "new":
+		if exists || name == m.workspace {
+			return fmt.Sprintf("Workspace %s exists already. Type :workspace use %s to switch to it.", name, name), true
+		}
+		previous := m.workspace
+		env := object.NewEnvironment()
+		env.SetStrict(m.env.Strict())
+		m.switchWorkspace(name, workspace{env: env})
+		return fmt.Sprintf("Created workspace %s with no bindings. Type :workspace use %s to go back.", name, previous), false
+	case "use":
+		if name == m.workspace {
+			return "Already in workspace " + name, false
+		}
+		if !exists {
+			return fmt.Sprintf("No workspace %s. Type :workspace new %s to create it.", name, name), true
+		}
+		m.switchWorkspace(name, m.workspaces[name])
+		delete(m.workspaces, name)
+		return "Switched to workspace " + name, false
+	case "delete":
+		if name == m.workspace {
+			return fmt.Sprintf("Cannot delete the current workspace %s. Switch to another one first.", name), true
+		}
+		if !exists {
+			return "No workspace " + name, true
+		}
+		delete(m.workspaces, name)
+		return fmt.Sprintf("Deleted workspace %s and its bindings", name), false
+	default:
+		return usage, true
+	}
+}
+
+// switchWorkspace stores the current workspace and makes ws, named name, current.
+func (m *model) switchWorkspace(name string, ws workspace) {
+	if m.workspaces == nil {
+		m.workspaces = make(map[string]workspace)
+	}
+	m.workspaces[m.workspace] = workspace{env: m.env, history: m.history}
+	m.workspace, m.env, m.history = name, ws.env, ws.history
+}
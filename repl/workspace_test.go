@@ -0,0 +1,79 @@
+package repl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dr8co/monke/object"
+)
+
+func TestWorkspaceCommand(t *testing.T) {
+	m := initialModel(Options{})
+	m.env.Set("x", &object.Integer{Value: 1})
+	m.history = []historyEntry{{input: "let x = 1;"}}
+
+	steps := []struct {
+		command   string // The arguments of :workspace
+		output    string // A prefix of the output
+		failed    bool
+		workspace string // The current workspace after the command
+	}{
+		{"", "* main", false, "main"},
+		{"use main", "Already in workspace main", false, "main"},
+		{"use other", "No workspace other.", true, "main"},
+		{"new other", "Created workspace other", false, "other"},
+		{"new other", "Workspace other exists already.", true, "other"},
+		{"new main", "Workspace main exists already.", true, "other"},
+		{"", "  main\n* other", false, "other"},
+		{"use main", "Switched to workspace main", false, "main"},
+		{"delete main", "Cannot delete the current workspace main.", true, "main"},
+		{"delete missing", "No workspace missing", true, "main"},
+		{"delete other", "Deleted workspace other", false, "main"},
+		{"", "* main", false, "main"},
+		{"use other", "No workspace other.", true, "main"},
+		{"new", "Usage: :workspace", true, "main"},
+		{"rename main other", "Usage: :workspace", true, "main"},
+		{"switch other", "Usage: :workspace", true, "main"},
+	}
+
+	for _, step := range steps {
+		output, failed := m.workspaceCommand(strings.Fields(step.command))
+		if !strings.HasPrefix(output, step.output) || failed != step.failed {
+			t.Errorf(":workspace %s: expected %q (failed=%t), got=%q (failed=%t)",
+				step.command, step.output, step.failed, output, failed)
+		}
+		if m.workspace != step.workspace {
+			t.Errorf(":workspace %s: expected to be in %s, got=%s", step.command, step.workspace, m.workspace)
+		}
+	}
+}
+
+func TestWorkspaceState(t *testing.T) {
+	m := initialModel(Options{})
+	m.env.SetStrict(true)
+	m.env.Set("x", &object.Integer{Value: 1})
+	m.history = []historyEntry{{input: "let x = 1;"}}
+	main := m.env
+
+	m.workspaceCommand([]string{"new", "other"})
+	if m.env == main || len(m.env.Names()) != 0 || len(m.history) != 0 {
+		t.Fatalf("new workspace is not empty: bindings=%q, history=%d", m.env.Names(), len(m.history))
+	}
+	if !m.env.Strict() {
+		t.Error("new workspace did not keep the language mode")
+	}
+	m.env.Set("y", &object.Integer{Value: 2})
+
+	m.workspaceCommand([]string{"use", "main"})
+	if m.env != main || len(m.history) != 1 {
+		t.Fatalf("switching back did not restore main: bindings=%q, history=%d", m.env.Names(), len(m.history))
+	}
+	if _, ok := m.env.Get("y"); ok {
+		t.Error("binding of the other workspace leaked into main")
+	}
+
+	m.workspaceCommand([]string{"use", "other"})
+	if _, ok := m.env.Get("y"); !ok {
+		t.Error("bindings of the other workspace were lost")
+	}
+}
@@ -0,0 +1,265 @@
+// Package replay records and replays the nondeterministic inputs of a Monke run.
+//
+// A trace is a binary file holding every nondeterministic value the interpreter
+// consumed, in order. Recording a run and replaying its trace feeds the program
+// exactly the same inputs again, which makes flaky script bugs reproducible.
+//
+// Trace format:
+//   - An 8-byte header, "MONKETR1"
+//   - A sequence of entries, each a Kind byte, a uvarint payload length,
+//     and the payload itself
+//
+// Key components:
+//   - Recorder: Appends entries to a trace
+//   - Player: Reads entries back, failing if the run diverges from the trace
+//   - RecordSource and Player.Source: rand.Source adapters for random numbers
+//   - RecordTime and Player.Time: Pass on the durations the program reads
+//   - RecordReader and Player.Reader: Readers for standard input
+package replay
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"time"
+)
+
+const header = "MONKETR1"
+
+// maxPayload bounds the size of a single entry to guard against corrupt traces.
+const maxPayload = 1 << 30
+
+// Kind identifies the type of nondeterministic input stored in a trace entry.
+type Kind byte
+
+const (
+	// Random is a 64-bit value drawn from the interpreter's random source.
+	Random Kind = iota + 1
+	// Time is a duration read by the program, in nanoseconds.
+	Time
+	// Input is a chunk of standard input, or the end of it if empty.
+	Input
+)
+
+// String returns the name of the kind.
+func (k Kind) String() string {
+	switch k {
+	case Random:
+		return "random"
+	case Time:
+		return "time"
+	case Input:
+		return "input"
+	default:
+		return fmt.Sprintf("kind(%d)", byte(k))
+	}
+}
+
+// ErrDiverged is returned when a replayed run asks for an input the trace does not hold.
+var ErrDiverged = errors.New("replay diverged from the recorded trace")
+
+// Recorder appends entries to a trace.
+type Recorder struct {
+	w   *bufio.Writer
+	err error
+}
+
+// NewRecorder writes a trace header to w and returns a Recorder for it.
+func NewRecorder(w io.Writer) (*Recorder, error) {
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString(header); err != nil {
+		return nil, err
+	}
+	return &Recorder{w: bw}, nil
+}
+
+// Record appends an entry to the trace.
+// Write errors are sticky and reported by Flush.
+func (r *Recorder) Record(kind Kind, payload []byte) {
+	if r.err != nil {
+		return
+	}
+	var buf [binary.MaxVarintLen64 + 1]byte
+	buf[0] = byte(kind)
+	n := binary.PutUvarint(buf[1:], uint64(len(payload)))
+	if _, r.err = r.w.Write(buf[:n+1]); r.err != nil {
+		return
+	}
+	_, r.err = r.w.Write(payload)
+}
+
+// Flush writes any buffered entries and returns the first error encountered while recording.
+func (r *Recorder) Flush() error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.w.Flush()
+}
+
+// Player reads entries back from a trace.
+type Player struct {
+	r   *bufio.Reader
+	err error
+}
+
+// NewPlayer checks the trace header in r and returns a Player for it.
+func NewPlayer(r io.Reader) (*Player, error) {
+	br := bufio.NewReader(r)
+	got := make([]byte, len(header))
+	if _, err := io.ReadFull(br, got); err != nil || string(got) != header {
+		return nil, errors.New("not a monke trace file")
+	}
+	return &Player{r: br}, nil
+}
+
+// Next returns the payload of the next entry, which must be of the given kind.
+// Once an error occurs, every later call returns it too.
+func (p *Player) Next(kind Kind) ([]byte, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	k, err := p.r.ReadByte()
+	if err != nil {
+		p.err = fmt.Errorf("%w: no more %s inputs", ErrDiverged, kind)
+		return nil, p.err
+	}
+	if Kind(k) != kind {
+		p.err = fmt.Errorf("%w: expected %s input, trace has %s", ErrDiverged, kind, Kind(k))
+		return nil, p.err
+	}
+	n, err := binary.ReadUvarint(p.r)
+	if err != nil || n > maxPayload {
+		p.err = errors.New("corrupt trace entry")
+		return nil, p.err
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(p.r, payload); err != nil {
+		p.err = errors.New("corrupt trace entry")
+		return nil, p.err
+	}
+	return payload, nil
+}
+
+// Err returns the first error encountered while replaying, if any.
+func (p *Player) Err() error {
+	return p.err
+}
+
+type recordingSource struct {
+	src rand.Source
+	rec *Recorder
+}
+
+func (s *recordingSource) Uint64() uint64 {
+	v := s.src.Uint64()
+	s.rec.Record(Random, binary.LittleEndian.AppendUint64(nil, v))
+	return v
+}
+
+// RecordSource returns a rand.Source that draws from src and records every value.
+func RecordSource(src rand.Source, rec *Recorder) rand.Source {
+	return &recordingSource{src: src, rec: rec}
+}
+
+type replayingSource struct {
+	p        *Player
+	fallback rand.Source
+}
+
+func (s *replayingSource) Uint64() uint64 {
+	payload, err := s.p.Next(Random)
+	if err == nil && len(payload) != 8 {
+		s.p.err = errors.New("corrupt trace entry")
+		err = s.p.err
+	}
+	if err != nil {
+		// rand.Source cannot fail; the error is kept in the Player for the caller.
+		// Constant values could stall rejection sampling, so keep producing numbers.
+		return s.fallback.Uint64()
+	}
+	return binary.LittleEndian.Uint64(payload)
+}
+
+// Source returns a rand.Source that yields the random values stored in the trace.
+// If the run asks for more values than were recorded, Err reports ErrDiverged and
+// the source continues with fixed-seed pseudo-random values so the run can finish.
+func (p *Player) Source() rand.Source {
+	return &replayingSource{p: p, fallback: rand.NewPCG(0, 0)}
+}
+
+// RecordTime returns a function that records every duration it is passed and
+// returns it unchanged, for evaluator.SetTimeInput.
+func RecordTime(rec *Recorder) func(time.Duration) time.Duration {
+	return func(d time.Duration) time.Duration {
+		rec.Record(Time, binary.LittleEndian.AppendUint64(nil, uint64(d)))
+		return d
+	}
+}
+
+// Time returns a function that replaces every duration it is passed with the
+// next one stored in the trace, for evaluator.SetTimeInput. If the run reads
+// more durations than were recorded, Err reports ErrDiverged and the measured
+// durations are passed on so the run can finish.
+func (p *Player) Time() func(time.Duration) time.Duration {
+	return func(d time.Duration) time.Duration {
+		payload, err := p.Next(Time)
+		if err == nil && len(payload) != 8 {
+			p.err = errors.New("corrupt trace entry")
+			err = p.err
+		}
+		if err != nil {
+			return d
+		}
+		return time.Duration(binary.LittleEndian.Uint64(payload))
+	}
+}
+
+type recordingReader struct {
+	r   io.Reader
+	rec *Recorder
+}
+
+func (r *recordingReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	if n > 0 {
+		r.rec.Record(Input, b[:n])
+	}
+	if errors.Is(err, io.EOF) {
+		r.rec.Record(Input, nil)
+	}
+	return n, err
+}
+
+// RecordReader returns a reader that reads from r and records what it reads,
+// as well as the end of the input.
+func RecordReader(r io.Reader, rec *Recorder) io.Reader {
+	return &recordingReader{r: r, rec: rec}
+}
+
+type replayingReader struct {
+	p   *Player
+	buf []byte // The rest of the chunk read last
+	eof bool
+}
+
+func (r *replayingReader) Read(b []byte) (int, error) {
+	if len(r.buf) == 0 && !r.eof {
+		payload, err := r.p.Next(Input)
+		// Without a recording the input ends, and Err reports the divergence
+		r.buf, r.eof = payload, err != nil || len(payload) == 0
+	}
+	if len(r.buf) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(b, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// Reader returns a reader that yields the input stored in the trace.
+func (p *Player) Reader() io.Reader {
+	return &replayingReader{p: p}
+}
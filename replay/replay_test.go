@@ -0,0 +1,132 @@
+package replay
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestRecordAndReplayRandom(t *testing.T) {
+	var trace bytes.Buffer
+	rec, err := NewRecorder(&trace)
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+
+	recorded := rand.New(RecordSource(rand.NewPCG(1, 2), rec))
+	want := make([]int64, 10)
+	for i := range want {
+		want[i] = recorded.Int64N(1000)
+	}
+	if err := rec.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	p, err := NewPlayer(bytes.NewReader(trace.Bytes()))
+	if err != nil {
+		t.Fatalf("NewPlayer: %v", err)
+	}
+	replayed := rand.New(p.Source())
+	for i, w := range want {
+		if got := replayed.Int64N(1000); got != w {
+			t.Errorf("value %d: got=%d, want=%d", i, got, w)
+		}
+	}
+	if p.Err() != nil {
+		t.Fatalf("unexpected replay error: %v", p.Err())
+	}
+
+	// Asking for more values than were recorded is a divergence.
+	replayed.Uint64()
+	if !errors.Is(p.Err(), ErrDiverged) {
+		t.Errorf("expected ErrDiverged, got %v", p.Err())
+	}
+}
+
+func TestRecordAndReplayTime(t *testing.T) {
+	var trace bytes.Buffer
+	rec, err := NewRecorder(&trace)
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+
+	// Durations interleave with random values in the order the run read them.
+	recorded := RecordTime(rec)
+	src := RecordSource(rand.NewPCG(1, 2), rec)
+	want := []time.Duration{recorded(3 * time.Millisecond), recorded(5)}
+	wantRandom := src.Uint64()
+	want = append(want, recorded(time.Second))
+	if err := rec.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	p, err := NewPlayer(bytes.NewReader(trace.Bytes()))
+	if err != nil {
+		t.Fatalf("NewPlayer: %v", err)
+	}
+	replayed := p.Time()
+	for i, w := range want[:2] {
+		if got := replayed(0); got != w {
+			t.Errorf("duration %d: got=%v, want=%v", i, got, w)
+		}
+	}
+	if got := p.Source().Uint64(); got != wantRandom {
+		t.Errorf("random: got=%d, want=%d", got, wantRandom)
+	}
+	if got := replayed(0); got != want[2] {
+		t.Errorf("duration 2: got=%v, want=%v", got, want[2])
+	}
+	if p.Err() != nil {
+		t.Fatalf("unexpected replay error: %v", p.Err())
+	}
+
+	if got := replayed(7); got != 7 || !errors.Is(p.Err(), ErrDiverged) {
+		t.Errorf("expected the measured duration and ErrDiverged, got %v, %v", got, p.Err())
+	}
+}
+
+func TestRecordAndReplayReader(t *testing.T) {
+	var trace bytes.Buffer
+	rec, err := NewRecorder(&trace)
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+	const input = "first line\nsecond line\n"
+	got, err := io.ReadAll(iotest.OneByteReader(RecordReader(strings.NewReader(input), rec)))
+	if err != nil || string(got) != input {
+		t.Fatalf("recorded reader: got=%q, %v", got, err)
+	}
+	if err := rec.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	p, err := NewPlayer(bytes.NewReader(trace.Bytes()))
+	if err != nil {
+		t.Fatalf("NewPlayer: %v", err)
+	}
+	// Replayed chunks may be read with smaller buffers than they were recorded with
+	got, err = io.ReadAll(iotest.HalfReader(p.Reader()))
+	if err != nil || string(got) != input {
+		t.Errorf("replayed reader: got=%q, %v", got, err)
+	}
+	if p.Err() != nil {
+		t.Fatalf("unexpected replay error: %v", p.Err())
+	}
+
+	// A trace without the input ends it, reporting the divergence
+	empty, _ := NewPlayer(bytes.NewReader([]byte(header)))
+	if got, err := io.ReadAll(empty.Reader()); err != nil || len(got) != 0 || !errors.Is(empty.Err(), ErrDiverged) {
+		t.Errorf("expected an empty input and ErrDiverged, got %q, %v, %v", got, err, empty.Err())
+	}
+}
+
+func TestNewPlayerRejectsGarbage(t *testing.T) {
+	if _, err := NewPlayer(bytes.NewReader([]byte("not a trace"))); err == nil {
+		t.Errorf("expected an error for a file without a trace header")
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dr8co/monke/evaluator"
+	"github.com/dr8co/monke/pipeline"
+)
+
+// reportTopFunctions is the number of most called functions -report lists.
+const reportTopFunctions = 10
+
+// runTimes holds when a run of a script started and when its source was parsed.
+type runTimes struct {
+	start, parsed time.Time
+}
+
+// startReport prepares the evaluator for a -report of the run about to start.
+func startReport() runTimes {
+	evaluator.TrackPeakMemory(true)
+	return runTimes{start: time.Now()}
+}
+
+// markParsed records that the source has been parsed.
+func (t *runTimes) markParsed() {
+	t.parsed = time.Now()
+}
+
+// writeReport writes a summary of what the run of result took to w: the wall
+// time split into parsing and evaluation, the peak size of the live objects,
+// the most called functions, the builtins called and the work of Go's
+// garbage collector.
+func writeReport(w io.Writer, times runTimes, result *pipeline.Result) {
+	wall := time.Since(times.start)
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\nRUN REPORT")
+	fmt.Fprintf(tw, "Wall time:\t%v\n", wall)
+	if !times.parsed.IsZero() {
+		fmt.Fprintf(tw, "Parse time:\t%v\n", times.parsed.Sub(times.start))
+	}
+	fmt.Fprintf(tw, "Eval time:\t%v\n", result.Eval.Duration)
+	fmt.Fprintf(tw, "Steps:\t%d\n", result.Eval.Steps)
+	fmt.Fprintf(tw, "Objects allocated:\t%d\n", result.Eval.AllocatedObjects)
+	fmt.Fprintf(tw, "Peak live objects:\t~%d bytes\n", evaluator.PeakMemory())
+	fmt.Fprintf(tw, "GC:\t%d cycles, %v paused, %d bytes of Go heap in use, %d allocated in total\n",
+		mem.NumGC, time.Duration(mem.PauseTotalNs), mem.HeapAlloc, mem.TotalAlloc)
+
+	if functions := evaluator.FunctionStats(); len(functions) != 0 {
+		fmt.Fprintln(tw, "\nFUNCTION\tCALLS\tDEFINED AT")
+		for _, s := range functions[:min(len(functions), reportTopFunctions)] {
+			fmt.Fprintf(tw, "%s\t%d\t%s\n", s.Name, s.Calls, s.Pos)
+		}
+		if len(functions) > reportTopFunctions {
+			fmt.Fprintf(tw, "... %d more\t\t\n", len(functions)-reportTopFunctions)
+		}
+	}
+	if builtins := evaluator.BuiltinStats(); len(builtins) != 0 {
+		fmt.Fprintln(tw, "\nBUILTIN\tCALLS\tTIME")
+		for _, s := range builtins {
+			fmt.Fprintf(tw, "%s\t%d\t%v\n", s.Name, s.Calls, s.Time)
+		}
+	}
+	_ = tw.Flush()
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/dr8co/monke/evaluator"
+	"github.com/dr8co/monke/examples"
+	"github.com/dr8co/monke/learn"
+	"github.com/dr8co/monke/modules"
+	"github.com/dr8co/monke/object"
+	"github.com/dr8co/monke/pipeline"
+)
+
+// selfcheckSteps caps the steps of each program run by "monke selfcheck", so
+// that a broken asset fails the check instead of hanging it.
+const selfcheckSteps = 10_000_000
+
+// smokeTest is the program run by "monke selfcheck" once the assets are
+// checked, and smokeResult its value. It imports the standard library and uses
+// closures, ranges, hashes and strings.
+const (
+	smokeTest = `import "std/list";
+import "std/strings";
+let counter = fn() { let n = 0; fn() { n = n + 1; n } };
+let next = counter();
+next();
+let squares = map(1..=4, fn(x) { x * x });
+let h = {"total": reduce(squares, 0, fn(a, b) { a + b }), "name": join(["mon", "ke"], "")};
+[h["total"], upper(h["name"]), next()]`
+	smokeResult = "[30, MONKE, 2]"
+)
+
+// runSelfcheck implements "monke selfcheck", which verifies that the standard
+// library, the sample programs and the lessons embedded in the binary load
+// and run, then runs a smoke test, so a distributed binary can be checked
+// without the source tree.
+func runSelfcheck(args []string) int {
+	fs := flag.NewFlagSet("selfcheck", flag.ExitOnError)
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "Usage: %s selfcheck\n\n", filepath.Base(os.Args[0]))
+		fmt.Fprintln(out, "Checks that the standard library modules, the sample programs and the lessons")
+		fmt.Fprintln(out, "embedded in the binary load and run, then runs a smoke test program.")
+	}
+	_ = fs.Parse(args)
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+
+	evaluator.Configure(evaluator.Options{Output: io.Discard, Modules: modules.Stdlib})
+	checks := []struct {
+		name string
+		run  func() (string, error)
+	}{
+		{"stdlib", checkStdlib},
+		{"examples", checkExamples},
+		{"lessons", checkLessons},
+		{"smoke test", checkSmokeTest},
+	}
+	status := 0
+	for _, check := range checks {
+		summary, err := check.run()
+		if err != nil {
+			fmt.Printf("FAIL  %s: %s\n", check.name, err)
+			status = 1
+			continue
+		}
+		fmt.Printf("ok    %s: %s\n", check.name, summary)
+	}
+	return status
+}
+
+// checkStdlib imports each module of the standard library.
+func checkStdlib() (string, error) {
+	names := modules.StdlibModules()
+	for _, name := range names {
+		if _, err := selfcheckRun(fmt.Sprintf("import %q;", name), nil); err != nil {
+			return "", fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return fmt.Sprintf("%d modules", len(names)), nil
+}
+
+// checkExamples runs each sample program.
+func checkExamples() (string, error) {
+	all := examples.All()
+	if len(all) == 0 {
+		return "", errors.New("no sample programs embedded")
+	}
+	for _, ex := range all {
+		if _, err := selfcheckRun(ex.Source, nil); err != nil {
+			return "", fmt.Errorf("%s: %w", ex.Name, err)
+		}
+	}
+	return fmt.Sprintf("%d programs", len(all)), nil
+}
+
+// checkLessons loads the lessons and checks that the solution of each
+// exercise gives the value it expects.
+func checkLessons() (string, error) {
+	lessons, err := learn.Lessons()
+	if err != nil {
+		return "", err
+	}
+	if len(lessons) == 0 {
+		return "", errors.New("no lessons embedded")
+	}
+	exercises := 0
+	for _, lesson := range lessons {
+		// Exercises of a lesson may use the bindings of earlier ones
+		env := object.NewEnvironment()
+		for i, ex := range lesson.Exercises {
+			value, err := selfcheckRun(ex.Solution, env)
+			if err != nil {
+				return "", fmt.Errorf("%s: exercise %d: %w", lesson.Name, i+1, err)
+			}
+			if value == nil || value.Inspect() != ex.Expect {
+				return "", fmt.Errorf("%s: exercise %d: solution gives %v, want %s", lesson.Name, i+1, value, ex.Expect)
+			}
+			exercises++
+		}
+	}
+	return fmt.Sprintf("%d lessons, %d exercises", len(lessons), exercises), nil
+}
+
+// checkSmokeTest runs the smoke test program.
+func checkSmokeTest() (string, error) {
+	value, err := selfcheckRun(smokeTest, nil)
+	if err != nil {
+		return "", err
+	}
+	if value == nil || value.Inspect() != smokeResult {
+		return "", fmt.Errorf("got %v, want %s", value, smokeResult)
+	}
+	return smokeResult, nil
+}
+
+// selfcheckRun runs source in env, or a new environment if env is nil, within
+// selfcheckSteps, and returns its value or why it failed.
+func selfcheckRun(source string, env *object.Environment) (object.Object, error) {
+	evaluator.SetStepLimit(selfcheckSteps)
+	result := pipeline.Run(source, env)
+	if err := result.Err(); err != nil {
+		return nil, err
+	}
+	return result.Value, nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/dr8co/monke/evaluator"
+)
+
+// stoppedBy holds the number of the signal that interrupted the script, or 0.
+var stoppedBy atomic.Int32
+
+// watchSignals handles signals while the named script runs. SIGINT and SIGTERM
+// interrupt it, so that it fails with an "interrupted" error after running its
+// deferred blocks instead of dying mid-write; a second one exits at once, e.g.
+// if the script is stuck waiting for input. SIGQUIT prints the Monkey
+// backtrace of the script to stderr and lets it carry on. The returned function
+// stops handling the signals.
+func watchSignals(name string) (stop func()) {
+	evaluator.SetBacktraceHandler(func(frames []evaluator.Frame) {
+		fmt.Fprintf(os.Stderr, "SIGQUIT: backtrace of %s, most recent call first:\n", name)
+		for _, f := range frames {
+			if f.Function == "" {
+				fmt.Fprintf(os.Stderr, "    %s:%s at the top level\n", name, f.Pos)
+			} else {
+				fmt.Fprintf(os.Stderr, "    %s:%s in %s\n", name, f.Pos, f.Function)
+			}
+		}
+	})
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				if sig == syscall.SIGQUIT {
+					evaluator.RequestBacktrace()
+					continue
+				}
+				n, _ := sig.(syscall.Signal)
+				if !stoppedBy.CompareAndSwap(0, int32(n)) {
+					os.Exit(128 + int(n))
+				}
+				evaluator.Interrupt()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+		evaluator.SetBacktraceHandler(nil)
+	}
+}
+
+// exitStatus returns the status to exit with after a run: 128 plus the number
+// of the signal that interrupted it, as shells report such runs, or status if
+// no signal did.
+func exitStatus(status int) int {
+	if n := stoppedBy.Load(); n != 0 {
+		return 128 + int(n)
+	}
+	return status
+}
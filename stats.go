@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dr8co/monke/metrics"
+)
+
+// runStats implements "monke stats", which reports size and complexity metrics of scripts.
+func runStats(args []string) int {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	noColor := fs.Bool("no-color", false, "Disable colored output")
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintf(out, "Usage: %s stats [flags] script...\n\n", filepath.Base(os.Args[0]))
+		fmt.Fprintln(out, "Reports the number of tokens and AST nodes by type, the maximum nesting depth of")
+		fmt.Fprintln(out, "blocks and the cyclomatic complexity of each function in the scripts.")
+		fmt.Fprintln(out, "\nFlags:")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	status := 0
+	for i, filename := range fs.Args() {
+		//nolint:gosec // The path is supplied by the user on purpose
+		content, err := os.ReadFile(filename)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading file: %s\n", err)
+			status = 1
+			continue
+		}
+		source := string(content)
+		report, errs := metrics.Measure(source)
+		if errs != nil {
+			fmt.Fprint(os.Stderr, formatParseErrors(source, errs, !colorStderr(*noColor)))
+			status = 1
+			continue
+		}
+
+		if fs.NArg() > 1 {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("%s:\n", filename)
+		}
+		_, _ = report.WriteTo(os.Stdout)
+	}
+	return status
+}
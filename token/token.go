@@ -14,13 +14,33 @@
 // parser to understand the structure of the program.
 package token
 
+import (
+	"maps"
+	"slices"
+	"strconv"
+)
+
 // Type represents the type of token.
 type Type string
 
+// Position is a location in the source code.
+// The zero value means the position is unknown.
+type Position struct {
+	Line   int // 1-based line number
+	Column int // 1-based column, counted in bytes
+}
+
+// String returns the position in "line:column" form.
+func (p Position) String() string {
+	return strconv.Itoa(p.Line) + ":" + strconv.Itoa(p.Column)
+}
+
 // Token represents a single token in the source code.
 type Token struct {
-	Type    Type
-	Literal string
+	Type     Type
+	Literal  string
+	Position      // Position of the token's first character
+	Newline  bool // Whether a line break comes between the token and the one before it
 }
 
 //nolint:revive
@@ -32,8 +52,12 @@ const (
 	// Identifiers & literals
 	IDENT  = "IDENT"
 	INT    = "INT"
+	FLOAT  = "FLOAT"
 	STRING = "STRING"
 
+	// RAW_STRING is a string enclosed in backticks, which may contain double quotes
+	RAW_STRING = "RAW_STRING"
+
 	// Operators
 	ASSIGN   = "="
 	PLUS     = "+"
@@ -43,12 +67,23 @@ const (
 	SLASH    = "/"
 	LT       = "<"
 	GT       = ">"
+	LT_EQ    = "<="
+	GT_EQ    = ">="
 	EQ       = "=="
 	NOT_EQ   = "!="
+	SPREAD   = "..."
+	RANGE    = ".."
+	RANGE_EQ = "..="
+	OPTIONAL = "?."
+	NULLISH  = "??"
+	QUESTION = "?"
 
 	// Delimiters
 	COMMA     = ","
 	COLON     = ":"
+	DOT       = "."
+	ARROW     = "->"
+	FAT_ARROW = "=>"
 	SEMICOLON = ";"
 	LPAREN    = "("
 	RPAREN    = ")"
@@ -65,6 +100,13 @@ const (
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	DEFER    = "DEFER"
+	WHILE    = "WHILE"
+	FOR      = "FOR"
+	IN       = "IN"
+	MATCH    = "MATCH"
+	IMPORT   = "IMPORT"
+	CLASS    = "CLASS"
 )
 
 var keywords = map[string]Type{
@@ -75,6 +117,13 @@ var keywords = map[string]Type{
 	"if":     IF,
 	"else":   ELSE,
 	"return": RETURN,
+	"defer":  DEFER,
+	"while":  WHILE,
+	"for":    FOR,
+	"in":     IN,
+	"match":  MATCH,
+	"import": IMPORT,
+	"class":  CLASS,
 }
 
 // LookupIdent checks if the given identifier is a keyword.
@@ -86,3 +135,8 @@ func LookupIdent(ident string) Type {
 	}
 	return IDENT
 }
+
+// Keywords returns the reserved words of the language, sorted.
+func Keywords() []string {
+	return slices.Sorted(maps.Keys(keywords))
+}
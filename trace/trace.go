@@ -0,0 +1,96 @@
+// Package trace writes execution traces of Monke programs for external analysis.
+//
+// A JSONL tracer emits one JSON object per evaluated statement, in the order
+// statements finish. Each line holds:
+//   - line, column: The position of the statement in the source
+//   - node: The AST node type, e.g. "LetStatement"
+//   - depth: How many statements enclose this one (0 for top-level statements)
+//   - duration_ns: The time taken to evaluate the statement, in nanoseconds
+//   - result: The type of the resulting value, or null if it produced none
+//
+// JSONL implements evaluator.Tracer and is installed with evaluator.SetTracer.
+package trace
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"reflect"
+	"time"
+
+	"github.com/dr8co/monke/ast"
+	"github.com/dr8co/monke/evaluator"
+	"github.com/dr8co/monke/object"
+)
+
+// Event is a single line of a JSONL trace.
+type Event struct {
+	Line       int     `json:"line"`
+	Column     int     `json:"column"`
+	Node       string  `json:"node"`
+	Depth      int     `json:"depth"`
+	DurationNs int64   `json:"duration_ns"`
+	Result     *string `json:"result"`
+}
+
+// JSONL is a tracer that writes one Event per evaluated statement.
+type JSONL struct {
+	w      *bufio.Writer
+	enc    *json.Encoder
+	starts []time.Time
+	err    error
+}
+
+// NewJSONL returns a tracer writing JSON lines to w.
+// Call Flush once evaluation is over.
+func NewJSONL(w io.Writer) *JSONL {
+	bw := bufio.NewWriter(w)
+	return &JSONL{w: bw, enc: json.NewEncoder(bw)}
+}
+
+// EnterStatement records the start time of stmt.
+func (t *JSONL) EnterStatement(_ ast.Statement) {
+	t.starts = append(t.starts, evaluator.Now())
+}
+
+// ExitStatement writes the event for stmt.
+func (t *JSONL) ExitStatement(stmt ast.Statement, result object.Object) {
+	depth := len(t.starts) - 1
+	start := t.starts[depth]
+	t.starts = t.starts[:depth]
+
+	if t.err != nil {
+		return
+	}
+
+	pos := stmt.Pos()
+	ev := Event{
+		Line:       pos.Line,
+		Column:     pos.Column,
+		Node:       nodeName(stmt),
+		Depth:      depth,
+		DurationNs: evaluator.Now().Sub(start).Nanoseconds(),
+	}
+	if result != nil {
+		typ := string(result.Type())
+		ev.Result = &typ
+	}
+	t.err = t.enc.Encode(ev)
+}
+
+// Flush writes any buffered events and returns the first write error, if any.
+func (t *JSONL) Flush() error {
+	if t.err != nil {
+		return t.err
+	}
+	return t.w.Flush()
+}
+
+// nodeName returns the unqualified type name of an AST node, e.g. "LetStatement".
+func nodeName(node ast.Node) string {
+	typ := reflect.TypeOf(node)
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	return typ.Name()
+}
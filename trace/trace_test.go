@@ -0,0 +1,70 @@
+package trace
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/dr8co/monke/evaluator"
+	"github.com/dr8co/monke/lexer"
+	"github.com/dr8co/monke/object"
+	"github.com/dr8co/monke/parser"
+)
+
+func TestJSONLTracer(t *testing.T) {
+	input := "let x = 5;\nif (x > 1) {\n  x * 2;\n}"
+
+	var buf bytes.Buffer
+	tracer := NewJSONL(&buf)
+	evaluator.SetTracer(tracer)
+	defer evaluator.SetTracer(nil)
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	evaluator.Eval(program, object.NewEnvironment())
+	if err := tracer.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	tests := []struct {
+		line, column, depth int
+		node                string
+		result              string
+	}{
+		{1, 1, 0, "LetStatement", ""},
+		{3, 3, 1, "ExpressionStatement", "INTEGER"},
+		{2, 1, 0, "ExpressionStatement", "INTEGER"},
+	}
+
+	var events []Event
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var ev Event
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", scanner.Text(), err)
+		}
+		events = append(events, ev)
+	}
+	if len(events) != len(tests) {
+		t.Fatalf("wrong number of events. want=%d, got=%d", len(tests), len(events))
+	}
+
+	for i, tt := range tests {
+		ev := events[i]
+		if ev.Line != tt.line || ev.Column != tt.column || ev.Depth != tt.depth || ev.Node != tt.node {
+			t.Errorf("events[%d] wrong. want=%d:%d depth %d %s, got=%d:%d depth %d %s",
+				i, tt.line, tt.column, tt.depth, tt.node, ev.Line, ev.Column, ev.Depth, ev.Node)
+		}
+		var result string
+		if ev.Result != nil {
+			result = *ev.Result
+		}
+		if result != tt.result {
+			t.Errorf("events[%d] result wrong. want=%q, got=%q", i, tt.result, result)
+		}
+	}
+}
@@ -0,0 +1,763 @@
+// Package typecheck finds obvious type errors in Monke programs that use the
+// optional type annotations, before they run.
+//
+// Annotations are ignored by the evaluator. The checker infers the types of
+// literals, operators and calls to known functions, and reports values whose
+// type contradicts an annotation or an operator. Anything it cannot infer has
+// type "any", which is compatible with every type, so unannotated code is
+// checked only for mistakes that would fail on every run.
+//
+// The type names are any, int, float, string, bool, array, hash, fn and null.
+// Capitalized names refer to hashes tagged with tag(), such as Point.
+//
+// Key components:
+//   - Check: Checks a parsed program
+//   - Error: A type error and its position
+package typecheck
+
+import (
+	"fmt"
+	"unicode"
+
+	"github.com/dr8co/monke/ast"
+	"github.com/dr8co/monke/token"
+)
+
+// Error is a type error found by the checker.
+type Error struct {
+	Pos     token.Position
+	Message string
+}
+
+// The built-in type names.
+const (
+	anyType    = "any"
+	intType    = "int"
+	floatType  = "float"
+	stringType = "string"
+	boolType   = "bool"
+	arrayType  = "array"
+	bytesType  = "bytes"
+	hashType   = "hash"
+	fnType     = "fn"
+	nullType   = "null"
+)
+
+var typeNames = map[string]bool{
+	anyType: true, intType: true, floatType: true, stringType: true, boolType: true,
+	arrayType: true, bytesType: true, hashType: true, fnType: true, nullType: true,
+}
+
+// builtinResults holds the result types of the builtins that always return the same type.
+var builtinResults = map[string]string{
+	"len":          intType,
+	"upper":        stringType,
+	"lower":        stringType,
+	"is_null":      boolType,
+	"is_empty":     boolType,
+	"type":         stringType,
+	"source":       stringType,
+	"div":          intType,
+	"mod":          intType,
+	"push":         arrayType,
+	"slice":        arrayType,
+	"splice":       arrayType,
+	"merge":        hashType,
+	"b_string":     stringType,
+	"bytes":        bytesType,
+	"bytes_string": stringType,
+}
+
+// Check reports the type errors in program, in source order.
+func Check(program *ast.Program) []Error {
+	c := &checker{assigned: map[string]bool{}}
+	collectAssigned(program, c.assigned)
+	c.statements(program.Statements, newScope(nil))
+	return c.errors
+}
+
+// binding is what the checker knows about a name.
+type binding struct {
+	typ   string
+	fn    *ast.FunctionLiteral // the function bound to the name, if known
+	class *ast.ClassStatement  // the class bound to the name, if known
+}
+
+type scope struct {
+	vars  map[string]binding
+	outer *scope
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{vars: map[string]binding{}, outer: outer}
+}
+
+func (s *scope) lookup(name string) (binding, bool) {
+	for ; s != nil; s = s.outer {
+		if b, ok := s.vars[name]; ok {
+			return b, true
+		}
+	}
+	return binding{}, false
+}
+
+type checker struct {
+	errors []Error
+	// assigned holds the names that are the target of an assignment anywhere in
+	// the program; their inferred types may change, so only annotations are trusted
+	assigned map[string]bool
+	// returns is the stack of return types of the enclosing functions
+	returns []string
+}
+
+func (c *checker) errorf(pos token.Position, format string, a ...any) {
+	c.errors = append(c.errors, Error{Pos: pos, Message: fmt.Sprintf(format, a...)})
+}
+
+// collectAssigned records the names assigned by the assignment statements in node.
+func collectAssigned(node ast.Node, names map[string]bool) {
+	switch node := node.(type) {
+	case *ast.Program:
+		for _, stmt := range node.Statements {
+			collectAssigned(stmt, names)
+		}
+	case *ast.BlockStatement:
+		for _, stmt := range node.Statements {
+			collectAssigned(stmt, names)
+		}
+	case *ast.AssignStatement:
+		names[node.Name.Value] = true
+		collectAssigned(node.Value, names)
+	case *ast.LetStatement:
+		collectAssigned(node.Value, names)
+	case *ast.ReturnStatement:
+		collectAssigned(node.ReturnValue, names)
+	case *ast.DeferStatement:
+		collectAssigned(node.Body, names)
+	case *ast.ExpressionStatement:
+		collectAssigned(node.Expression, names)
+	case *ast.FunctionLiteral:
+		collectAssigned(node.Body, names)
+	case *ast.ClassStatement:
+		for _, method := range node.Methods {
+			collectAssigned(method.Function, names)
+		}
+	case *ast.IfExpression:
+		collectAssigned(node.Condition, names)
+		collectAssigned(node.Consequence, names)
+		if node.Alternative != nil {
+			collectAssigned(node.Alternative, names)
+		}
+	case *ast.ConditionalExpression:
+		collectAssigned(node.Condition, names)
+		collectAssigned(node.Consequence, names)
+		collectAssigned(node.Alternative, names)
+	case *ast.WhileExpression:
+		collectAssigned(node.Condition, names)
+		collectAssigned(node.Body, names)
+	case *ast.ForExpression:
+		if node.Init != nil {
+			collectAssigned(node.Init, names)
+		}
+		if node.Post != nil {
+			collectAssigned(node.Post, names)
+		}
+		collectAssigned(node.Condition, names)
+		collectAssigned(node.Body, names)
+	case *ast.ForInExpression:
+		collectAssigned(node.Iterable, names)
+		collectAssigned(node.Body, names)
+	case *ast.MatchExpression:
+		collectAssigned(node.Value, names)
+		for _, arm := range node.Arms {
+			collectAssigned(arm.Body, names)
+		}
+	case *ast.CallExpression:
+		collectAssigned(node.Function, names)
+		for _, arg := range node.Arguments {
+			collectAssigned(arg, names)
+		}
+	case *ast.ArrayLiteral:
+		for _, elem := range node.Elements {
+			collectAssigned(elem, names)
+		}
+	case *ast.HashLiteral:
+		for key, value := range node.Pairs {
+			collectAssigned(key, names)
+			collectAssigned(value, names)
+		}
+	case *ast.PrefixExpression:
+		collectAssigned(node.Right, names)
+	case *ast.InfixExpression:
+		collectAssigned(node.Left, names)
+		collectAssigned(node.Right, names)
+	case *ast.IndexExpression:
+		collectAssigned(node.Left, names)
+		collectAssigned(node.Index, names)
+	case *ast.SliceExpression:
+		collectAssigned(node.Left, names)
+		collectAssigned(node.Start, names)
+		collectAssigned(node.End, names)
+	case *ast.SpreadElement:
+		collectAssigned(node.Value, names)
+	case *ast.NamedArgument:
+		collectAssigned(node.Value, names)
+	}
+}
+
+// annotation returns the type named by an annotation, reporting unknown names.
+func (c *checker) annotation(typ *ast.Identifier) string {
+	if typ != nil && typeOf(typ) == anyType && typ.Value != anyType {
+		c.errorf(typ.Pos(), "unknown type %s", typ.Value)
+	}
+	return typeOf(typ)
+}
+
+// typeOf returns the type named by an annotation, or any if it is missing or unknown.
+func typeOf(typ *ast.Identifier) string {
+	if typ == nil || !typeNames[typ.Value] && !isTag(typ.Value) {
+		return anyType
+	}
+	return typ.Value
+}
+
+// isTag reports whether typ names a tagged hash type.
+func isTag(typ string) bool {
+	return typ != "" && unicode.IsUpper(rune(typ[0]))
+}
+
+// assignable reports whether a value of type got can be used where want is expected.
+func assignable(want, got string) bool {
+	switch {
+	case want == anyType || got == anyType || want == got:
+		return true
+	case want == floatType && got == intType:
+		return true
+	case want == hashType && isTag(got), isTag(want) && got == hashType:
+		return true
+	}
+	return false
+}
+
+func isNumeric(typ string) bool {
+	return typ == intType || typ == floatType
+}
+
+// statements checks a list of statements and returns the type of the last one,
+// which is the value of a block.
+func (c *checker) statements(stmts []ast.Statement, s *scope) string {
+	typ := nullType
+	for _, stmt := range stmts {
+		typ = c.statement(stmt, s)
+	}
+	return typ
+}
+
+func (c *checker) statement(stmt ast.Statement, s *scope) string {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		if stmt.Destructures() {
+			// The types of the elements of arrays and the values of hashes are not known
+			want, what := arrayType, "an array"
+			if _, ok := stmt.Pattern.(*ast.HashPattern); ok {
+				want, what = hashType, "a hash"
+			}
+			if got := c.expression(stmt.Value, s); !assignable(want, got) {
+				c.errorf(start(stmt.Value), "cannot destructure %s value, not %s", got, what)
+			}
+			for _, name := range stmt.Identifiers() {
+				s.vars[name.Value] = binding{typ: anyType}
+			}
+			return nullType
+		}
+		want := c.annotation(stmt.Type)
+		b := binding{typ: want}
+		fn, isFn := stmt.Value.(*ast.FunctionLiteral)
+		if isFn && !c.assigned[stmt.Name.Value] {
+			// Bind the function first so that it can call itself
+			b.fn = fn
+			if stmt.Type == nil {
+				b.typ = fnType
+			}
+			s.vars[stmt.Name.Value] = b
+		}
+		got := c.expression(stmt.Value, s)
+		if !assignable(want, got) {
+			c.errorf(start(stmt.Value), "cannot use %s value as %s in let %s", got, want, stmt.Name.Value)
+		}
+		if stmt.Type == nil && !c.assigned[stmt.Name.Value] {
+			b.typ = got
+		}
+		s.vars[stmt.Name.Value] = b
+		return nullType
+	case *ast.AssignStatement:
+		got := c.expression(stmt.Value, s)
+		if b, ok := s.lookup(stmt.Name.Value); ok && !assignable(b.typ, got) {
+			c.errorf(start(stmt.Value), "cannot assign %s value to %s of type %s", got, stmt.Name.Value, b.typ)
+		}
+		return nullType
+	case *ast.ReturnStatement:
+		got := nullType
+		if stmt.ReturnValue != nil {
+			got = c.expression(stmt.ReturnValue, s)
+		}
+		c.checkReturn(stmt.Pos(), got)
+		return anyType
+	case *ast.DeferStatement:
+		c.statements(stmt.Body.Statements, newScope(s))
+		return anyType
+	case *ast.ImportStatement:
+		// The names a module binds are not known until it is loaded, so they are any
+		return nullType
+	case *ast.ClassStatement:
+		b := binding{typ: anyType}
+		if !c.assigned[stmt.Name.Value] {
+			b.class = stmt
+		}
+		s.vars[stmt.Name.Value] = b
+		for _, method := range stmt.Methods {
+			methods := newScope(s)
+			methods.vars["self"] = binding{typ: instanceType(stmt)}
+			c.function(method.Function, methods)
+		}
+		return nullType
+	case *ast.BlockStatement:
+		return c.statements(stmt.Statements, s)
+	case *ast.ExpressionStatement:
+		if stmt.Expression == nil {
+			return nullType
+		}
+		return c.expression(stmt.Expression, s)
+	}
+	return anyType
+}
+
+// start returns the position of the first token of exp.
+func start(exp ast.Expression) token.Position {
+	switch exp := exp.(type) {
+	case *ast.InfixExpression:
+		return start(exp.Left)
+	case *ast.ConditionalExpression:
+		return start(exp.Condition)
+	case *ast.CallExpression:
+		return start(exp.Function)
+	case *ast.IndexExpression:
+		return start(exp.Left)
+	case *ast.SliceExpression:
+		return start(exp.Left)
+	}
+	return exp.Pos()
+}
+
+// checkReturn reports a returned value that does not match the return type
+// of the enclosing function.
+func (c *checker) checkReturn(pos token.Position, got string) {
+	if len(c.returns) == 0 {
+		return
+	}
+	if want := c.returns[len(c.returns)-1]; !assignable(want, got) {
+		c.errorf(pos, "cannot return %s value from function returning %s", got, want)
+	}
+}
+
+func (c *checker) expressions(exps []ast.Expression, s *scope) []string {
+	types := make([]string, len(exps))
+	for i, exp := range exps {
+		types[i] = c.expression(exp, s)
+	}
+	return types
+}
+
+func (c *checker) expression(exp ast.Expression, s *scope) string {
+	switch exp := exp.(type) {
+	case *ast.IntegerLiteral:
+		return intType
+	case *ast.FloatLiteral:
+		return floatType
+	case *ast.StringLiteral:
+		return stringType
+	case *ast.Boolean:
+		return boolType
+	case *ast.ArrayLiteral:
+		c.expressions(exp.Elements, s)
+		return arrayType
+	case *ast.HashLiteral:
+		c.hash(exp, s)
+		return hashType
+	case *ast.SpreadElement:
+		c.expression(exp.Value, s)
+		return anyType
+	case *ast.NamedArgument:
+		return c.expression(exp.Value, s)
+	case *ast.Identifier:
+		if b, ok := s.lookup(exp.Value); ok {
+			return b.typ
+		}
+		return anyType
+	case *ast.PrefixExpression:
+		return c.prefix(exp, c.expression(exp.Right, s))
+	case *ast.InfixExpression:
+		return c.infix(exp, c.expression(exp.Left, s), c.expression(exp.Right, s))
+	case *ast.IndexExpression:
+		c.expression(exp.Left, s)
+		c.expression(exp.Index, s)
+		return anyType
+	case *ast.SliceExpression:
+		return c.slice(exp, s)
+	case *ast.IfExpression:
+		c.expression(exp.Condition, s)
+		then := c.statements(exp.Consequence.Statements, newScope(s))
+		var otherwise string
+		switch alternative := exp.Alternative.(type) {
+		case *ast.BlockStatement:
+			otherwise = c.statements(alternative.Statements, newScope(s))
+		case *ast.IfExpression:
+			otherwise = c.expression(alternative, s)
+		default:
+			return anyType
+		}
+		if otherwise != then {
+			return anyType
+		}
+		return then
+	case *ast.ConditionalExpression:
+		c.expression(exp.Condition, s)
+		then := c.expression(exp.Consequence, s)
+		if otherwise := c.expression(exp.Alternative, s); otherwise != then {
+			return anyType
+		}
+		return then
+	case *ast.WhileExpression:
+		c.expression(exp.Condition, s)
+		c.statements(exp.Body.Statements, newScope(s))
+		return anyType
+	case *ast.ForExpression:
+		loop := newScope(s)
+		if exp.Init != nil {
+			c.statement(exp.Init, loop)
+		}
+		if exp.Condition != nil {
+			c.expression(exp.Condition, loop)
+		}
+		if exp.Post != nil {
+			c.statement(exp.Post, loop)
+		}
+		c.statements(exp.Body.Statements, newScope(loop))
+		return anyType
+	case *ast.ForInExpression:
+		iterable := c.expression(exp.Iterable, s)
+		if iterable != anyType && iterable != arrayType && iterable != hashType && !isTag(iterable) {
+			c.errorf(start(exp.Iterable), "cannot iterate over %s", iterable)
+		}
+		body := newScope(s)
+		if exp.Key != nil {
+			key := anyType
+			if iterable == arrayType {
+				key = intType
+			}
+			body.vars[exp.Key.Value] = binding{typ: key}
+		}
+		body.vars[exp.Value.Value] = binding{typ: anyType}
+		c.statements(exp.Body.Statements, body)
+		return anyType
+	case *ast.MatchExpression:
+		return c.match(exp, s)
+	case *ast.FunctionLiteral:
+		c.function(exp, s)
+		return fnType
+	case *ast.CallExpression:
+		return c.call(exp, s)
+	}
+	return anyType
+}
+
+// construct checks a call of class, which makes an instance of it, and
+// returns the type of the instance.
+func (c *checker) construct(class *ast.ClassStatement, exp *ast.CallExpression) string {
+	// Fields passed by name or spread are checked at runtime
+	counted := true
+	for _, arg := range exp.Arguments {
+		switch arg.(type) {
+		case *ast.SpreadElement, *ast.NamedArgument:
+			counted = false
+		}
+	}
+	if counted && len(exp.Arguments) != len(class.Fields) {
+		c.errorf(exp.Pos(), "wrong number of arguments to %s: want %d, got %d", class.Name.Value, len(class.Fields), len(exp.Arguments))
+	}
+	return instanceType(class)
+}
+
+// instanceType returns the type of the instances of class: the name of the
+// class if it names a tagged hash type, or else hash.
+func instanceType(class *ast.ClassStatement) string {
+	if isTag(class.Name.Value) {
+		return class.Name.Value
+	}
+	return hashType
+}
+
+// match checks the arms of a match expression, each in a scope binding the
+// names of its pattern. Its type is the type all the arms share, if the last
+// one matches any value; otherwise no arm may match and the result is null.
+func (c *checker) match(match *ast.MatchExpression, s *scope) string {
+	c.expression(match.Value, s)
+	typ := ""
+	for _, arm := range match.Arms {
+		body := newScope(s)
+		ast.Inspect(arm.Pattern, func(node ast.Node) bool {
+			if pattern, ok := node.(*ast.BindingPattern); ok && !pattern.Wildcard() {
+				body.vars[pattern.Name.Value] = binding{typ: anyType}
+			}
+			return true
+		})
+		got := c.statement(arm.Body, body)
+		if typ == "" || typ == got {
+			typ = got
+		} else {
+			typ = anyType
+		}
+	}
+	if len(match.Arms) == 0 {
+		return nullType
+	}
+	if _, ok := match.Arms[len(match.Arms)-1].Pattern.(*ast.BindingPattern); !ok {
+		return anyType
+	}
+	return typ
+}
+
+// hash checks the keys and values of a hash literal, in source order if it is known.
+func (c *checker) hash(hash *ast.HashLiteral, s *scope) {
+	keys := hash.Order
+	if keys == nil {
+		for key := range hash.Pairs {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range keys {
+		c.expression(key, s)
+		if value, ok := hash.Pairs[key]; ok {
+			c.expression(value, s)
+		}
+	}
+}
+
+// function checks the body of fn against its annotations.
+func (c *checker) function(fn *ast.FunctionLiteral, s *scope) {
+	body := newScope(s)
+	for i, param := range fn.Parameters {
+		typ := c.annotation(fn.ParamType(i))
+		if fn.Rest && i == len(fn.Parameters)-1 {
+			// The rest parameter is always bound to an array
+			if typ != anyType && typ != arrayType {
+				c.errorf(fn.ParamType(i).Pos(), "rest parameter %s must be of type array, not %s", param.Value, typ)
+			}
+			typ = arrayType
+		}
+		body.vars[param.Value] = binding{typ: typ}
+	}
+	ret := c.annotation(fn.ReturnType)
+
+	c.returns = append(c.returns, ret)
+	got := c.statements(fn.Body.Statements, body)
+	c.returns = c.returns[:len(c.returns)-1]
+
+	// The value of the last statement is returned implicitly
+	stmts := fn.Body.Statements
+	if n := len(stmts); n > 0 {
+		if _, ok := stmts[n-1].(*ast.ReturnStatement); !ok && !assignable(ret, got) {
+			c.errorf(stmts[n-1].Pos(), "cannot return %s value from function returning %s", got, ret)
+		}
+	}
+}
+
+func (c *checker) prefix(exp *ast.PrefixExpression, right string) string {
+	switch exp.Operator {
+	case "!":
+		return boolType
+	case "-":
+		if right == anyType || isNumeric(right) {
+			return right
+		}
+		c.errorf(exp.Pos(), "operator - not defined on %s", right)
+	}
+	return anyType
+}
+
+// slice returns the type of a slice expression: that of the array or string
+// it slices. It reports values other than arrays and strings, and bounds that
+// are not integers.
+func (c *checker) slice(exp *ast.SliceExpression, s *scope) string {
+	left := c.expression(exp.Left, s)
+	for _, bound := range []ast.Expression{exp.Start, exp.End} {
+		if bound == nil {
+			continue
+		}
+		if t := c.expression(bound, s); t != anyType && t != intType {
+			c.errorf(start(bound), "cannot use %s value as int in slice index", t)
+		}
+	}
+	switch {
+	case left == arrayType || left == stringType || left == anyType:
+		return left
+	case left == nullType && exp.Optional:
+		return nullType
+	}
+	c.errorf(start(exp.Left), "cannot slice %s", left)
+	return anyType
+}
+
+func (c *checker) infix(exp *ast.InfixExpression, left, right string) string {
+	switch exp.Operator {
+	case "==", "!=":
+		return boolType
+	case "??":
+		if left == right {
+			return left
+		}
+		return anyType
+	}
+	if left == anyType || right == anyType {
+		switch exp.Operator {
+		case "<", ">", "<=", ">=":
+			return boolType
+		case "..", "..=":
+			return arrayType
+		}
+		return anyType
+	}
+
+	switch {
+	case isNumeric(left) && isNumeric(right):
+		switch exp.Operator {
+		case "<", ">", "<=", ">=":
+			return boolType
+		case "+", "-", "*", "/":
+			if left == floatType || right == floatType {
+				return floatType
+			}
+			return intType
+		case "..", "..=":
+			if left == intType && right == intType {
+				return arrayType
+			}
+		}
+	case left == stringType && right == stringType:
+		switch exp.Operator {
+		case "+":
+			return stringType
+		case "<", ">", "<=", ">=":
+			return boolType
+		}
+	case left != right:
+		c.errorf(exp.Pos(), "mismatched types %s and %s for %s", left, right, exp.Operator)
+		return anyType
+	}
+	c.errorf(exp.Pos(), "operator %s not defined on %s", exp.Operator, left)
+	return anyType
+}
+
+// call checks the arguments of a call against the parameters of the callee,
+// when the callee is known, and returns the type of the result. A method call
+// on a value that is not a hash is a call of the function of its name, with
+// the receiver as the first argument.
+func (c *checker) call(exp *ast.CallExpression, s *scope) string {
+	args := c.expressions(exp.Arguments, s)
+	arguments := exp.Arguments
+
+	var fn *ast.FunctionLiteral
+	name := "function"
+	switch callee := exp.Function.(type) {
+	case *ast.Identifier:
+		b, ok := s.lookup(callee.Value)
+		if !ok {
+			return c.builtinCall(callee.Value, exp)
+		}
+		if b.class != nil {
+			return c.construct(b.class, exp)
+		}
+		fn, name = b.fn, callee.Value
+	case *ast.FunctionLiteral:
+		c.function(callee, s)
+		fn = callee
+	case *ast.IndexExpression:
+		recv := c.expression(callee.Left, s)
+		method, ok := callee.Field()
+		// A hash may hold the function it calls
+		if !ok || recv == anyType || recv == hashType || isTag(recv) || (callee.Optional && recv == nullType) {
+			c.expression(callee.Index, s)
+			return anyType
+		}
+		b, ok := s.lookup(method)
+		if !ok {
+			return c.builtinCall(method, exp)
+		}
+		fn, name = b.fn, method
+		args = append([]string{recv}, args...)
+		arguments = append([]ast.Expression{callee.Left}, arguments...)
+	default:
+		c.expression(exp.Function, s)
+	}
+	if fn == nil {
+		return anyType
+	}
+
+	for _, arg := range exp.Arguments {
+		if _, ok := arg.(*ast.SpreadElement); ok {
+			// The number of arguments is only known at runtime
+			return typeOf(fn.ReturnType)
+		}
+	}
+	fixed := len(fn.Parameters)
+	if fn.Rest {
+		fixed--
+		if len(args) < fixed {
+			c.errorf(exp.Pos(), "wrong number of arguments to %s: want at least %d, got %d", name, fixed, len(args))
+		}
+	} else if len(args) != fixed {
+		c.errorf(exp.Pos(), "wrong number of arguments to %s: want %d, got %d", name, fixed, len(args))
+	}
+	for i, got := range args {
+		if named, ok := arguments[i].(*ast.NamedArgument); ok {
+			c.namedArgument(fn, fixed, named, got, name)
+			continue
+		}
+		if i >= fixed {
+			break
+		}
+		if want := typeOf(fn.ParamType(i)); !assignable(want, got) {
+			c.errorf(start(arguments[i]), "cannot use %s value as %s in argument %d to %s", got, want, i+1, name)
+		}
+	}
+	return typeOf(fn.ReturnType)
+}
+
+// namedArgument checks the argument named, of type got, in a call to the
+// function fn called name, whose first fixed parameters can be named.
+func (c *checker) namedArgument(fn *ast.FunctionLiteral, fixed int, named *ast.NamedArgument, got, name string) {
+	for i, param := range fn.Parameters[:fixed] {
+		if param.Value != named.Name.Value {
+			continue
+		}
+		if want := typeOf(fn.ParamType(i)); !assignable(want, got) {
+			c.errorf(start(named.Value), "cannot use %s value as %s in argument %s to %s", got, want, param.Value, name)
+		}
+		return
+	}
+	c.errorf(named.Pos(), "unknown parameter %s in call to %s", named.Name.Value, name)
+}
+
+// builtinCall returns the result type of a call to the named builtin.
+func (c *checker) builtinCall(name string, exp *ast.CallExpression) string {
+	if name == "tag" && len(exp.Arguments) == 2 {
+		if tag, ok := exp.Arguments[1].(*ast.StringLiteral); ok && isTag(tag.Value) {
+			return tag.Value
+		}
+		return hashType
+	}
+	if typ, ok := builtinResults[name]; ok {
+		return typ
+	}
+	return anyType
+}
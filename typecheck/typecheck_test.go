@@ -0,0 +1,140 @@
+package typecheck
+
+import (
+	"testing"
+
+	"github.com/dr8co/monke/lexer"
+	"github.com/dr8co/monke/parser"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string // "line:column: message" of each error
+	}{
+		// Unannotated programs are accepted
+		{"let x = 5; let f = fn(a, b) { a + b }; f(x, 2);", nil},
+		{"let x = 1; x = \"one\"; x + \"!\";", nil},
+		{"let f = fn(x) { x }; f(1) + f(\"a\");", nil},
+
+		// Let annotations
+		{"let x: int = 5; let y: float = x; let z: any = \"a\";", nil},
+		{"let x: int = \"five\";", []string{"1:14: cannot use string value as int in let x"}},
+		{"let x: string = 1 + 2;", []string{"1:17: cannot use int value as string in let x"}},
+		{"let x: integer = 1;", []string{"1:8: unknown type integer"}},
+		{"let x: int = 1; x = true;", []string{"1:21: cannot assign bool value to x of type int"}},
+		{"let n: string = len([1]);", []string{"1:17: cannot use int value as string in let n"}},
+
+		// Operators
+		{"1 + \"a\";", []string{"1:3: mismatched types int and string for +"}},
+		{"\"a\" - \"b\";", []string{"1:5: operator - not defined on string"}},
+		{"-true;", []string{"1:1: operator - not defined on bool"}},
+		{"let a: int = 1; let b: float = a * 2.5; a < b;", nil},
+		{"fn(a: string, b) { a + b }", nil},
+		{"let ok: bool = 1 <= 2.5; let s: bool = \"a\" >= \"b\";", nil},
+		{"let lt: bool = \"a\" < \"b\"; let gt: bool = \"a\" > \"b\";", nil},
+		{"\"a\" * \"b\";", []string{"1:5: operator * not defined on string"}},
+		{"1 >= \"a\";", []string{"1:3: mismatched types int and string for >="}},
+		{"let r: array = 1..=3; let n: int = 0..2;", []string{"1:36: cannot use array value as int in let n"}},
+		{"1.5..2;", []string{"1:4: operator .. not defined on float"}},
+		{"let b: bytes = bytes(\"a\"); let s: string = bytes_string(b); let n: int = b;", []string{"1:74: cannot use bytes value as int in let n"}},
+
+		// Function annotations
+		{"let add = fn(a: int, b: int) -> int { a + b }; add(1, 2);", nil},
+		{"let add = fn(a: int, b: int) -> int { a + b }; add(1, \"2\");", []string{"1:55: cannot use string value as int in argument 2 to add"}},
+		{"let add = fn(a: int, b: int) -> int { a + b }; add(1);", []string{"1:51: wrong number of arguments to add: want 2, got 1"}},
+		{"let f = fn(s: string) -> int { s }", []string{"1:32: cannot return string value from function returning int"}},
+		{"let f = fn(s: string) -> int { return s; }", []string{"1:32: cannot return string value from function returning int"}},
+		{"let f = fn(s) -> string { if (s) { return 1 }; \"a\" }", []string{"1:36: cannot return int value from function returning string"}},
+		{"let f = fn() -> int { 1 }; let s: string = f();", []string{"1:44: cannot use int value as string in let s"}},
+		{"let fact = fn(n: int) -> int { if (n < 2) { 1 } else { n * fact(n - 1) } }", nil},
+		{"fn(x: int) { x }(\"a\")", []string{"1:18: cannot use string value as int in argument 1 to function"}},
+		{"let f = fn(a: int, b: int) { a }; f(...[1, 2]);", nil},
+		{"let f = fn(a, b) { a }; f = fn(a) { a }; f(1);", nil},
+		{"let f = fn(a: int, ...xs) -> int { a + len(xs) }; f(1); f(1, \"b\", true);", nil},
+		{"let f = fn(a: int, ...xs) { a }; f();", []string{"1:35: wrong number of arguments to f: want at least 1, got 0"}},
+		{"let f = fn(...xs) { let n: int = xs; n }", []string{"1:34: cannot use array value as int in let n"}},
+		{"let f = fn(...xs: int) { xs }", []string{"1:19: rest parameter xs must be of type array, not int"}},
+		{"let f = fn(a: int, b: string) { a }; f(b: \"x\", a: 1);", nil},
+		{"let f = fn(a: int, b: string) { a }; f(1, b: 2);", []string{"1:46: cannot use int value as string in argument b to f"}},
+		{"let f = fn(a, b) { a }; f(1, c: 2);", []string{"1:30: unknown parameter c in call to f"}},
+		{"let twice = fn(n: int) -> int { n * 2 }; let s: string = 3.twice();", []string{"1:58: cannot use int value as string in let s"}},
+		{"let twice = fn(n: int) -> int { n * 2 }; \"a\".twice();", []string{"1:42: cannot use string value as int in argument 1 to twice"}},
+		{"let n: int = \"a\".upper();", []string{"1:14: cannot use string value as int in let n"}},
+		{"let twice = fn(n: int) { n }; let h = {\"twice\": fn(s: string) { s }}; h.twice(\"a\");", nil},
+		{"let f = fn(a: int) -> array { return a, a + 1; }; let x, y = f(1); let s: string = x;", nil},
+		{"let f = fn(a: int) -> int { return a, a; }", []string{"1:29: cannot return array value from function returning int"}},
+		{"let x, y = 5;", []string{"1:12: cannot destructure int value, not an array"}},
+		{"let [x, ...rest] = \"ab\";", []string{"1:20: cannot destructure string value, not an array"}},
+		{"let [n, ...rest] = [1, 2]; let s: string = n; let m: int = rest;", nil},
+		{"let {name, age} = {\"name\": 1}; let s: string = name;", nil},
+		{"let p: Point = tag({}, \"Point\"); let {x, y} = p;", nil},
+		{"let {name} = [1];", []string{"1:14: cannot destructure array value, not a hash"}},
+
+		// Loops
+		{"for (i, x in [1, 2]) { let n: int = i; x + 1 }", nil},
+		{"for (x in 5) { x }", []string{"1:11: cannot iterate over int"}},
+
+		// If expressions
+		{"let n: int = if (x) { 1 } else if (y) { 2 } else { 3 };", nil},
+		{"let s: string = if (x) { 1 } else if (y) { 2 } else { 3 };", []string{"1:17: cannot use int value as string in let s"}},
+		{"let s: string = if (x) { 1 } else if (y) { 2 };", nil},
+		{"let s: string = \"a\"; if (x) { let s: int = 1; s + 1 } else { let s = true; !s }; s + \"b\";", nil},
+
+		// Slice expressions
+		{"let xs: array = [1, 2, 3][1:]; let s: string = \"abc\"[:2]; let t: string = xs[0:1];", []string{"1:75: cannot use array value as string in let t"}},
+		{"let f = fn(a, n: int) { a[n:] + a[:n - 1] };", nil},
+		{"5[1:2];", []string{"1:1: cannot slice int"}},
+		{"[1, 2][\"a\":true];", []string{"1:8: cannot use string value as int in slice index", "1:12: cannot use bool value as int in slice index"}},
+
+		// Conditional expressions
+		{"let n: int = x ? 1 : 2;", nil},
+		{"let s: string = x ? 1 : 2;", []string{"1:17: cannot use int value as string in let s"}},
+		{"let s: string = x ? \"a\" : 2;", nil},
+		{"x ? 1 + \"a\" : 2;", []string{"1:7: mismatched types int and string for +"}},
+
+		// Match expressions
+		{"let n: int = match (v) { [x] => 1, _ => 2 };", nil},
+		{"let s: string = match (v) { 1 => 2, n => 3 };", []string{"1:17: cannot use int value as string in let s"}},
+		{"let s: string = match (v) { 1 => \"a\", n => 2 };", nil},
+		{"let s: string = match (v) { [x, ...r] => x, _ => \"\" };", nil},
+		{"let n: int = match (v) { 0 => 1, {x} => 2 };", nil},
+		{"match (v) { [x] => { let n: int = x; n }, _ => 1 + \"a\" }", []string{"1:50: mismatched types int and string for +"}},
+
+		// Tagged hashes
+		{"let p: Point = tag({\"x\": 1}, \"Point\"); let h: hash = p;", nil},
+		{"let norm = fn(p: Point) { p }; norm({\"x\": 1}); norm(1);", []string{"1:53: cannot use int value as Point in argument 1 to norm"}},
+		{"let p: Point = tag({}, \"Line\");", []string{"1:16: cannot use Line value as Point in let p"}},
+
+		// Classes
+		{"class Point(x, y) { fn norm() -> int { self.x } }; let p: Point = Point(1, 2); let q: Point = Point(y: 1, x: 2);", nil},
+		{"class Point(x, y) {}; let p: Line = Point(1);", []string{
+			"1:42: wrong number of arguments to Point: want 2, got 1",
+			"1:37: cannot use Point value as Line in let p",
+		}},
+		{"class Point(x) { fn bad() -> int { \"no\" } }", []string{"1:36: cannot return string value from function returning int"}},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("%q: parser errors: %v", tt.input, p.Errors())
+		}
+
+		errs := Check(program)
+		got := make([]string, len(errs))
+		for i, err := range errs {
+			got[i] = err.Pos.String() + ": " + err.Message
+		}
+		if len(got) != len(tt.expected) {
+			t.Errorf("%q: wrong errors. expected=%q, got=%q", tt.input, tt.expected, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.expected[i] {
+				t.Errorf("%q: wrong error. expected=%q, got=%q", tt.input, tt.expected[i], got[i])
+			}
+		}
+	}
+}
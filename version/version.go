@@ -0,0 +1,65 @@
+// Package version describes the running Monke interpreter.
+//
+// It is the single source of the interpreter version, shared by the command-line
+// interface and the `version` builtin, so scripts can feature-detect capabilities.
+//
+// Key components:
+//   - Version: The interpreter version, overridable at build time with -ldflags "-X"
+//   - Engine: The kind of execution engine
+//   - Features: Optional capabilities scripts can test for
+package version
+
+// Version is the interpreter version.
+// Release builds set it with -ldflags "-X github.com/dr8co/monke/version.Version=...".
+var Version = "0.9.0"
+
+// Engine identifies the execution engine running Monke code.
+const Engine = "tree-walking"
+
+// Features lists the optional capabilities of this interpreter, for feature detection.
+// Names are stable once added.
+var Features = []string{
+	"defer",
+	"property_testing",
+	"script_args",
+	"runtime_stats",
+	"strict_mode",
+	"spread",
+	"optional_chaining",
+	"truthiness",
+	"checked_arithmetic",
+	"floats",
+	"while",
+	"type_tags",
+	"for",
+	"type_annotations",
+	"for_in",
+	"block_comments",
+	"less_or_equal",
+	"raw_strings",
+	"digit_separators",
+	"match",
+	"conditional_operator",
+	"string_builder",
+	"resources",
+	"else_if",
+	"imports",
+	"rest_parameters",
+	"named_arguments",
+	"multiple_values",
+	"let_patterns",
+	"let_hash_patterns",
+	"block_scopes",
+	"string_indexing",
+	"slices",
+	"field_access",
+	"method_calls",
+	"line_breaks",
+	"string_ordering",
+	"identifier_suffixes",
+	"hash_merge",
+	"ranges",
+	"bytes",
+	"classes",
+	"assignment",
+}